@@ -8,12 +8,26 @@ import (
 	"billing-note/pkg/config"
 	"billing-note/pkg/database"
 	"billing-note/pkg/logger"
+	"context"
 	"fmt"
 	"os"
+	"time"
+	_ "time/tzdata" // embed zone data for user time zones
 
 	"github.com/gin-gonic/gin"
 )
 
+// reverificationWindow is how long a re-verification unlocks sensitive actions
+const reverificationWindow = 10 * time.Minute
+
+// Request limits for public auth routes (per client IP) and for sensitive
+// signed-in actions such as pairing (per user)
+const (
+	authRateLimit      = 20
+	sensitiveRateLimit = 10
+	rateLimitWindow    = time.Minute
+)
+
 func main() {
 	// Initialize logger
 	logLevel := os.Getenv("LOG_LEVEL")
@@ -48,8 +62,36 @@ func main() {
 
 	// Initialize services
 	logger.Debug("Initializing services...")
-	authService := services.NewAuthService(userRepo, cfg.JWT.Secret, cfg.JWT.Expiry)
-	transactionService := services.NewTransactionService(transactionRepo)
+	sessionRepo := repository.NewSessionRepository(database.GetDB())
+	twoFactorRepo := repository.NewTwoFactorRepository(database.GetDB())
+	twoFactorService, err := services.NewTwoFactorService(twoFactorRepo, userRepo, sessionRepo, cfg.Encryption.Key)
+	if err != nil {
+		logger.WithError(err).Fatal("Failed to initialize two-factor service")
+	}
+	userTokenRepo := repository.NewUserTokenRepository(database.GetDB())
+	apiTokenRepo := repository.NewAPITokenRepository(database.GetDB())
+	apiTokenService := services.NewAPITokenService(apiTokenRepo)
+	mailer := services.NewMailer(cfg.SMTP)
+	credentialService := services.NewCredentialService(userRepo, userTokenRepo, sessionRepo, apiTokenRepo, mailer, cfg.Server.AppURL)
+	loginThrottle := services.NewLoginThrottle(repository.NewLoginAttemptRepository(database.GetDB()), mailer)
+	authService := services.NewAuthService(userRepo, sessionRepo, twoFactorService, credentialService, loginThrottle, cfg.JWT.Secret, cfg.JWT.Expiry, cfg.JWT.RefreshExpiry)
+
+	// Initialize notification, budget and budget alert services
+	notificationRepo := repository.NewNotificationRepository(database.GetDB())
+	notificationService := services.NewNotificationService(notificationRepo, userRepo,
+		services.NewSMTPChannel(cfg.SMTP),
+		services.NewWebhookChannel(),
+	)
+	accountRepo := repository.NewAccountRepository(database.GetDB())
+	budgetRepo := repository.NewBudgetRepository(database.GetDB())
+	budgetService := services.NewBudgetService(budgetRepo, accountRepo, userRepo)
+	budgetAlertService := services.NewBudgetAlertService(budgetService, budgetRepo, notificationService)
+
+	// Initialize savings goal service
+	goalRepo := repository.NewGoalRepository(database.GetDB())
+	goalService := services.NewGoalService(goalRepo, accountRepo, transactionRepo, userRepo)
+
+	transactionService := services.NewTransactionService(transactionRepo, accountRepo, budgetAlertService, goalService)
 
 	// Initialize PDF password service
 	pdfPasswordService, err := services.NewPDFPasswordService(database.GetDB(), cfg.Encryption.Key)
@@ -59,6 +101,8 @@ func main() {
 
 	// Initialize upload service
 	uploadService := services.NewUploadService(database.GetDB(), pdfPasswordService, cfg.Upload.Dir)
+	uploadService.AddTransactionListener(budgetAlertService)
+	uploadService.AddTransactionListener(goalService)
 
 	// Initialize Gmail service
 	gmailRepo := repository.NewGmailRepository(database.GetDB())
@@ -90,21 +134,63 @@ func main() {
 	logger.Debug("Initializing handlers...")
 	authHandler := handlers.NewAuthHandler(authService)
 	transactionHandler := handlers.NewTransactionHandler(transactionService)
+	transactionHandler.SetGoalService(goalService)
 	categoryHandler := handlers.NewCategoryHandler(categoryRepo)
 	pdfPasswordHandler := handlers.NewPDFPasswordHandler(pdfPasswordService)
+	twoFactorHandler := handlers.NewTwoFactorHandler(twoFactorService)
+	credentialHandler := handlers.NewCredentialHandler(credentialService)
+	apiTokenHandler := handlers.NewAPITokenHandler(apiTokenService)
 	uploadHandler := handlers.NewUploadHandler(uploadService)
 	// Initialize Invoice service
 	invoiceRepo := repository.NewInvoiceRepository(database.GetDB())
 	invoiceService := services.NewInvoiceService(invoiceRepo, cfg.EInvoice.APIURL, cfg.EInvoice.AppID)
 	logger.Info("Invoice service initialized")
 
+	// Start scheduled e-invoice carrier sync
+	if cfg.EInvoice.AutoSync && cfg.EInvoice.AppID != "" {
+		invoiceService.StartScheduler(context.Background(), cfg.EInvoice.SyncInterval)
+		logger.WithField("interval", cfg.EInvoice.SyncInterval.String()).Info("Invoice sync scheduler started")
+	}
+
 	invoiceHandler := handlers.NewInvoiceHandler(invoiceService, database.GetDB())
 
-	// Initialize Budget service
-	budgetRepo := repository.NewBudgetRepository(database.GetDB())
-	budgetService := services.NewBudgetService(budgetRepo, transactionRepo)
+	// Initialize invoice lottery service
+	lotteryRepo := repository.NewLotteryRepository(database.GetDB())
+	lotteryService := services.NewLotteryService(lotteryRepo, invoiceRepo, transactionRepo, categoryRepo, cfg.EInvoice.APIURL, cfg.EInvoice.AppID)
+	lotteryHandler := handlers.NewLotteryHandler(lotteryService)
+
+	// Initialize Account service
+	accountService := services.NewAccountService(accountRepo)
+	accountHandler := handlers.NewAccountHandler(accountService)
+
+	// Initialize bill reminder service
+	billRepo := repository.NewBillRepository(database.GetDB())
+	billService := services.NewBillService(billRepo, accountRepo, transactionRepo, userRepo, notificationService)
+	uploadService.SetBillService(billService)
+	billService.StartScheduler(context.Background(), services.BillReminderInterval)
+	billHandler := handlers.NewBillHandler(billService)
+
+	// Initialize net worth service
+	balanceRepo := repository.NewBalanceRepository(database.GetDB())
+	netWorthService := services.NewNetWorthService(balanceRepo, accountRepo, transactionRepo, userRepo)
+	billService.SetNetWorthService(netWorthService)
+	netWorthHandler := handlers.NewNetWorthHandler(netWorthService)
+
+	// Initialize calendar feed service
+	calendarRepo := repository.NewCalendarRepository(database.GetDB())
+	calendarService := services.NewCalendarService(calendarRepo, billRepo, transactionRepo, userRepo)
+	calendarHandler := handlers.NewCalendarHandler(calendarService)
+
+	// Initialize cash-flow forecast service
+	forecastService := services.NewForecastService(transactionRepo, billRepo, userRepo)
+	forecastHandler := handlers.NewForecastHandler(forecastService)
+
+	goalHandler := handlers.NewGoalHandler(goalService)
+
+	// Initialize Budget, notification and settings handlers
 	budgetHandler := handlers.NewBudgetHandler(budgetService)
-	logger.Info("Budget service initialized")
+	notificationHandler := handlers.NewNotificationHandler(notificationService)
+	settingsHandler := handlers.NewSettingsHandler(userRepo)
 
 	// Initialize Export service
 	exportService := services.NewExportService(transactionRepo)
@@ -115,6 +201,16 @@ func main() {
 	sharingService := services.NewSharingService(sharingRepo)
 	sharingHandler := handlers.NewSharingHandler(sharingService)
 
+	// Initialize household ledger service
+	ledgerRepo := repository.NewLedgerRepository(database.GetDB())
+	ledgerService := services.NewLedgerService(ledgerRepo, userRepo, categoryRepo)
+	ledgerHandler := handlers.NewLedgerHandler(ledgerService)
+
+	// Initialize shared-expense split service
+	splitRepo := repository.NewSplitRepository(database.GetDB())
+	splitService := services.NewSplitService(splitRepo, transactionRepo, sharingRepo, userRepo)
+	splitHandler := handlers.NewSplitHandler(splitService)
+
 	var gmailHandler *handlers.GmailHandler
 	if gmailService != nil {
 		gmailScanService := services.NewGmailScanService(gmailService, uploadService, gmailRepo, cfg.Upload.Dir)
@@ -128,6 +224,11 @@ func main() {
 		gin.SetMode(gin.ReleaseMode)
 	}
 	r := gin.New() // Use gin.New() instead of gin.Default() to have full control over middleware
+	// Only trust X-Forwarded-For from configured proxies, so clients cannot
+	// spoof the IP used for rate limiting and session records
+	if err := r.SetTrustedProxies(cfg.Server.TrustedProxies); err != nil {
+		logger.WithError(err).Fatal("Invalid TRUSTED_PROXIES")
+	}
 
 	// Middleware - Add logging middleware first
 	r.Use(gin.Recovery()) // Panic recovery
@@ -141,40 +242,120 @@ func main() {
 
 	// Auth routes (public)
 	auth := r.Group("/api/auth")
+	authLimited := middleware.RateLimit(middleware.NewRateLimiter(authRateLimit, rateLimitWindow), middleware.ByClientIP)
 	{
-		auth.POST("/register", authHandler.Register)
-		auth.POST("/login", authHandler.Login)
+		auth.POST("/register", authLimited, authHandler.Register)
+		auth.POST("/login", authLimited, authHandler.Login)
+		auth.POST("/login/2fa", authLimited, authHandler.LoginTwoFactor)
+		auth.POST("/refresh", authHandler.Refresh)
+		auth.POST("/password/forgot", authLimited, credentialHandler.ForgotPassword)
+		auth.POST("/password/reset", authLimited, credentialHandler.ResetPassword)
+		auth.POST("/verify-email", authLimited, credentialHandler.VerifyEmail)
 	}
 
+	// iCalendar feed (public, authenticated by the secret token in the URL)
+	r.GET("/calendar/:token", calendarHandler.Feed)
+
 	// Protected routes
 	api := r.Group("/api")
-	api.Use(middleware.AuthMiddleware(cfg.JWT.Secret))
+	api.Use(middleware.AuthMiddleware(cfg.JWT.Secret, sessionRepo, apiTokenRepo))
+
+	// Sensitive actions need the identity re-confirmed within the last few minutes
+	reverified := middleware.RequireReverification(reverificationWindow)
+	// Actions that check a secret (a password, a code) are rate limited per user
+	sensitiveLimited := middleware.RateLimit(middleware.NewRateLimiter(sensitiveRateLimit, rateLimitWindow), middleware.ByUser)
+	admin := middleware.RequireAdmin(cfg.Server.AdminUserIDs)
 	{
 		// Auth
 		api.GET("/auth/me", authHandler.Me)
+		api.POST("/auth/logout", authHandler.Logout)
+		api.GET("/auth/sessions", authHandler.ListSessions)
+		api.DELETE("/auth/sessions", authHandler.RevokeAllSessions)
+		api.DELETE("/auth/sessions/:id", authHandler.RevokeSession)
+		api.POST("/auth/reverify", sensitiveLimited, twoFactorHandler.Reverify)
+		api.POST("/auth/password", sensitiveLimited, credentialHandler.ChangePassword)
+		api.POST("/auth/verify-email/resend", credentialHandler.ResendVerification)
+
+		// Personal API tokens (scoped credentials for scripts)
+		api.GET("/auth/tokens", apiTokenHandler.List)
+		api.POST("/auth/tokens", reverified, apiTokenHandler.Create)
+		api.DELETE("/auth/tokens/:id", apiTokenHandler.Revoke)
+
+		// Two-factor authentication
+		api.GET("/auth/2fa", twoFactorHandler.Status)
+		api.POST("/auth/2fa/setup", reverified, twoFactorHandler.Setup)
+		api.POST("/auth/2fa/enable", twoFactorHandler.Enable)
+		api.POST("/auth/2fa/disable", twoFactorHandler.Disable)
+		api.POST("/auth/2fa/recovery-codes", twoFactorHandler.RegenerateRecoveryCodes)
 
 		// Sharing (no view_as needed)
 		api.GET("/shared/my-code", sharingHandler.GetMyCode)
 		api.POST("/shared/regenerate-code", sharingHandler.RegenerateCode)
-		api.POST("/shared/pair", sharingHandler.Pair)
+		api.POST("/shared/pair", sensitiveLimited, sharingHandler.Pair)
+		api.GET("/shared/requests", sharingHandler.ListRequests)
+		api.POST("/shared/requests/:id/approve", sharingHandler.ApproveRequest)
+		api.POST("/shared/requests/:id/reject", sharingHandler.RejectRequest)
 		api.GET("/shared/connections", sharingHandler.ListConnections)
+		api.PUT("/shared/connections/:uid", sharingHandler.UpdateAccess)
 		api.DELETE("/shared/connections/:uid", sharingHandler.RevokeAccess)
 
-		// PDF Password Settings (user-specific, no view_as)
-		api.GET("/settings/pdf-passwords", pdfPasswordHandler.List)
-		api.POST("/settings/pdf-passwords", pdfPasswordHandler.Set)
-		api.PUT("/settings/pdf-passwords", pdfPasswordHandler.SetMultiple)
-		api.DELETE("/settings/pdf-passwords/:priority", pdfPasswordHandler.Delete)
+		// Household ledgers (no view_as)
+		api.GET("/ledgers", ledgerHandler.List)
+		api.POST("/ledgers", ledgerHandler.Create)
+		api.GET("/ledgers/:id", ledgerHandler.Get)
+		api.PUT("/ledgers/:id", ledgerHandler.Update)
+		api.DELETE("/ledgers/:id", ledgerHandler.Delete)
+		api.POST("/ledgers/:id/members", ledgerHandler.AddMember)
+		api.DELETE("/ledgers/:id/members/:uid", ledgerHandler.RemoveMember)
+		api.POST("/ledgers/:id/categories", ledgerHandler.CreateCategory)
+		api.DELETE("/ledgers/:id/categories/:cid", ledgerHandler.DeleteCategory)
+
+		// Shared-expense splits and settlements (between paired users, no view_as)
+		api.GET("/transactions/:id/split", splitHandler.Get)
+		api.PUT("/transactions/:id/split", splitHandler.Split)
+		api.DELETE("/transactions/:id/split", splitHandler.Delete)
+		api.GET("/splits", splitHandler.List)
+		api.GET("/splits/balances", splitHandler.Balances)
+		api.GET("/settlements", splitHandler.ListSettlements)
+		api.POST("/settlements", splitHandler.RecordSettlement)
+		api.DELETE("/settlements/:id", splitHandler.DeleteSettlement)
+
+		// PDF Password Settings (user-specific, no view_as, re-verification required)
+		api.GET("/settings/pdf-passwords", reverified, pdfPasswordHandler.List)
+		api.POST("/settings/pdf-passwords", reverified, pdfPasswordHandler.Set)
+		api.PUT("/settings/pdf-passwords", reverified, pdfPasswordHandler.SetMultiple)
+		api.DELETE("/settings/pdf-passwords/:priority", reverified, pdfPasswordHandler.Delete)
+
+		// Notifications (user-specific, no view_as)
+		api.GET("/notifications", notificationHandler.List)
+		api.PUT("/notifications/read-all", notificationHandler.MarkAllRead)
+		api.PUT("/notifications/:id/read", notificationHandler.MarkRead)
+		api.GET("/notifications/settings", notificationHandler.GetSettings)
+		api.PUT("/notifications/settings", notificationHandler.UpdateSettings)
+
+		// Calendar feed (user-specific, no view_as)
+		api.GET("/calendar/feed", calendarHandler.GetFeed)
+		api.POST("/calendar/feed/regenerate", calendarHandler.RegenerateFeed)
+		api.DELETE("/calendar/feed", calendarHandler.RevokeFeed)
+
+		// Time zone (user-specific, no view_as)
+		api.GET("/settings/timezone", settingsHandler.GetTimezone)
+		api.PUT("/settings/timezone", settingsHandler.UpdateTimezone)
 
 		// Gmail Integration (user-specific, no view_as)
 		if gmailHandler != nil {
-			api.GET("/gmail/auth", gmailHandler.GetAuthURL)
-			api.POST("/gmail/callback", gmailHandler.HandleCallback)
+			// Connecting an account requires re-verification
+			api.GET("/gmail/auth", reverified, gmailHandler.GetAuthURL)
+			api.POST("/gmail/callback", reverified, gmailHandler.HandleCallback)
 			api.POST("/gmail/scan", gmailHandler.TriggerScan)
 			api.GET("/gmail/status", gmailHandler.GetStatus)
 			api.GET("/gmail/settings", gmailHandler.GetSettings)
 			api.PUT("/gmail/settings", gmailHandler.UpdateSettings)
 			api.DELETE("/gmail/disconnect", gmailHandler.Disconnect)
+			api.GET("/gmail/sender-rules", gmailHandler.ListSenderRules)
+			api.POST("/gmail/sender-rules", gmailHandler.CreateSenderRule)
+			api.PUT("/gmail/sender-rules/:id", gmailHandler.UpdateSenderRule)
+			api.DELETE("/gmail/sender-rules/:id", gmailHandler.DeleteSenderRule)
 		}
 
 		// Category Keyword Rules (user-specific, no view_as)
@@ -184,13 +365,18 @@ func main() {
 		api.DELETE("/category-keywords/:id", catKeywordHandler.Delete)
 		api.POST("/category-keywords/init-defaults", catKeywordHandler.InitDefaults)
 		api.POST("/category-keywords/reclassify", catKeywordHandler.Reclassify)
+
+		// Winning numbers are shared by every user, so only admins may change them
+		api.POST("/invoice/winning-numbers/import", admin, lotteryHandler.ImportWinningNumbers)
+		api.POST("/invoice/winning-numbers/fetch", admin, lotteryHandler.FetchWinningNumbers)
 	}
 
-	// Data routes with view_as support
+	// Data routes with view_as and ledger_id support
 	data := r.Group("/api")
-	data.Use(middleware.AuthMiddleware(cfg.JWT.Secret))
+	data.Use(middleware.AuthMiddleware(cfg.JWT.Secret, sessionRepo, apiTokenRepo))
 	data.Use(middleware.ViewAsMiddleware(sharingRepo))
-	data.Use(middleware.ReadOnlyGuard())
+	data.Use(middleware.SharePermissionGuard())
+	data.Use(middleware.LedgerMiddleware(ledgerRepo))
 	{
 		// Categories
 		data.GET("/categories", categoryHandler.GetAll)
@@ -203,10 +389,32 @@ func main() {
 		data.PUT("/transactions/:id", transactionHandler.Update)
 		data.DELETE("/transactions/:id", transactionHandler.Delete)
 
+		// Accounts
+		data.GET("/accounts", accountHandler.List)
+		data.POST("/accounts", accountHandler.Create)
+		data.PUT("/accounts/:id", accountHandler.Update)
+		data.DELETE("/accounts/:id", accountHandler.Delete)
+		data.GET("/accounts/:id/balances", netWorthHandler.ListBalances)
+		data.POST("/accounts/:id/balances", netWorthHandler.RecordBalance)
+		data.DELETE("/accounts/:id/balances/:bid", netWorthHandler.DeleteBalance)
+		data.GET("/accounts/:id/reconcile", netWorthHandler.ExpectedBalance)
+		data.POST("/accounts/:id/reconcile", netWorthHandler.Reconcile)
+		data.GET("/net-worth", netWorthHandler.Get)
+
+		// Bills
+		data.GET("/bills", billHandler.List)
+		data.GET("/bills/overdue", billHandler.Overdue)
+		data.POST("/bills", billHandler.Create)
+		data.GET("/bills/:id", billHandler.Get)
+		data.PUT("/bills/:id", billHandler.Update)
+		data.DELETE("/bills/:id", billHandler.Delete)
+		data.POST("/bills/:id/pay", billHandler.MarkPaid)
+
 		// Stats
 		data.GET("/stats/monthly", transactionHandler.GetMonthlyStats)
 		data.GET("/stats/category", transactionHandler.GetCategoryStats)
 		data.GET("/stats/trend", transactionHandler.GetTrendStats)
+		data.GET("/forecast", forecastHandler.Get)
 
 		// PDF Upload
 		data.POST("/upload/pdf", uploadHandler.UploadAndParse)
@@ -218,6 +426,17 @@ func main() {
 		data.PUT("/budget/:id", budgetHandler.Update)
 		data.DELETE("/budget/:id", budgetHandler.Delete)
 		data.GET("/budget/compare", budgetHandler.Compare)
+		data.GET("/budget/:id/history", budgetHandler.History)
+
+		// Savings goals
+		data.GET("/goals", goalHandler.List)
+		data.POST("/goals", goalHandler.Create)
+		data.GET("/goals/:id", goalHandler.Get)
+		data.PUT("/goals/:id", goalHandler.Update)
+		data.DELETE("/goals/:id", goalHandler.Delete)
+		data.GET("/goals/:id/contributions", goalHandler.ListContributions)
+		data.POST("/goals/:id/contributions", goalHandler.AddContribution)
+		data.DELETE("/goals/:id/contributions/:cid", goalHandler.DeleteContribution)
 
 		// Export
 		data.GET("/export/csv", exportHandler.ExportCSV)
@@ -228,6 +447,16 @@ func main() {
 		data.POST("/invoice/confirm-duplicate", invoiceHandler.ConfirmDuplicate)
 		data.DELETE("/invoice/:id", invoiceHandler.Delete)
 		data.PUT("/invoice/settings", invoiceHandler.UpdateSettings)
+		data.GET("/invoice/sync/status", invoiceHandler.GetSyncStatus)
+		data.PUT("/invoice/sync/settings", invoiceHandler.UpdateSyncSettings)
+		data.GET("/invoice/sync/history", invoiceHandler.GetSyncHistory)
+
+		// Invoice lottery
+		data.GET("/invoice/winning-numbers", lotteryHandler.ListWinningNumbers)
+		data.GET("/invoice/winning-numbers/:period", lotteryHandler.GetWinningNumbers)
+		data.POST("/invoice/lottery/check", lotteryHandler.Check)
+		data.GET("/invoice/winnings", lotteryHandler.ListWinnings)
+		data.POST("/invoice/winnings/:id/record", lotteryHandler.RecordIncome)
 	}
 
 	// Start server
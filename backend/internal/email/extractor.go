@@ -0,0 +1,160 @@
+package email
+
+import (
+	"errors"
+	"html"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// OrderItem represents a single line item in an order email
+type OrderItem struct {
+	Name     string  `json:"name"`
+	Quantity int     `json:"quantity"`
+	Amount   float64 `json:"amount"`
+}
+
+// Order represents an order extracted from a shopping email body
+type Order struct {
+	OrderNumber string      `json:"order_number"`
+	Merchant    string      `json:"merchant"`
+	Date        time.Time   `json:"date"`
+	Items       []OrderItem `json:"items"`
+	Total       float64     `json:"total"`
+}
+
+// OrderExtractor interface for merchant-specific email body extractors
+type OrderExtractor interface {
+	// Merchant returns the display name of the merchant
+	Merchant() string
+	// Senders returns the sender domains, or full addresses for merchants
+	// sharing a domain with unrelated mail, handled by this extractor
+	Senders() []string
+	// Extract parses orders from the plain-text email body
+	Extract(body string) ([]Order, error)
+}
+
+// ExtractorRegistry maps sender domains and addresses to order extractors
+type ExtractorRegistry struct {
+	extractors map[string]OrderExtractor
+}
+
+// NewExtractorRegistry creates a new extractor registry
+func NewExtractorRegistry() *ExtractorRegistry {
+	return &ExtractorRegistry{
+		extractors: make(map[string]OrderExtractor),
+	}
+}
+
+// RegisterExtractor adds an extractor for each of its senders
+func (r *ExtractorRegistry) RegisterExtractor(extractor OrderExtractor) {
+	for _, sender := range extractor.Senders() {
+		r.extractors[strings.ToLower(sender)] = extractor
+	}
+}
+
+// Senders returns all registered sender domains and addresses
+func (r *ExtractorRegistry) Senders() []string {
+	senders := make([]string, 0, len(r.extractors))
+	for sender := range r.extractors {
+		senders = append(senders, sender)
+	}
+	return senders
+}
+
+// Find returns the extractor for a From header value, or nil if none matches
+func (r *ExtractorRegistry) Find(from string) OrderExtractor {
+	address := senderAddress(from)
+	at := strings.LastIndex(address, "@")
+	if at < 0 {
+		return nil
+	}
+	if extractor, ok := r.extractors[address]; ok {
+		return extractor
+	}
+	domain := address[at+1:]
+	if domain == "" {
+		return nil
+	}
+	// Match the domain itself or any parent domain (e.g. mail.shopee.tw → shopee.tw)
+	for {
+		if extractor, ok := r.extractors[domain]; ok {
+			return extractor
+		}
+		idx := strings.Index(domain, ".")
+		if idx < 0 {
+			return nil
+		}
+		domain = domain[idx+1:]
+	}
+}
+
+// Extract finds the extractor for the sender and parses the (HTML or text) body
+func (r *ExtractorRegistry) Extract(from, body string) ([]Order, string, error) {
+	extractor := r.Find(from)
+	if extractor == nil {
+		return nil, "", errors.New("no extractor registered for sender")
+	}
+
+	orders, err := extractor.Extract(HTMLToText(body))
+	if err != nil {
+		return nil, extractor.Merchant(), err
+	}
+	for i := range orders {
+		if orders[i].Merchant == "" {
+			orders[i].Merchant = extractor.Merchant()
+		}
+	}
+	return orders, extractor.Merchant(), nil
+}
+
+// senderAddress extracts the lower-cased address from a From header
+// such as "Shopee <info@mail.shopee.tw>".
+func senderAddress(from string) string {
+	addr := from
+	if start := strings.LastIndex(from, "<"); start >= 0 {
+		if end := strings.Index(from[start:], ">"); end > 0 {
+			addr = from[start+1 : start+end]
+		}
+	}
+	return strings.ToLower(strings.TrimSpace(addr))
+}
+
+var (
+	scriptStylePattern = regexp.MustCompile(`(?is)<(script|style)[^>]*>.*?</(script|style)>`)
+	lineBreakPattern   = regexp.MustCompile(`(?i)<br\s*/?>|</(p|div|tr|li|h[1-6]|table)>`)
+	cellPattern        = regexp.MustCompile(`(?i)</t[dh]>`)
+	tagPattern         = regexp.MustCompile(`<[^>]+>`)
+	spacePattern       = regexp.MustCompile(`[ \t\x{00A0}]+`)
+)
+
+// HTMLToText converts an HTML email body into plain text lines.
+// Table cells are separated by two spaces so extractors can anchor on columns.
+func HTMLToText(body string) string {
+	if !strings.Contains(body, "<") {
+		return body
+	}
+
+	text := scriptStylePattern.ReplaceAllString(body, "")
+	text = lineBreakPattern.ReplaceAllString(text, "\n")
+	text = cellPattern.ReplaceAllString(text, "  ")
+	text = tagPattern.ReplaceAllString(text, "")
+	text = html.UnescapeString(text)
+
+	lines := strings.Split(text, "\n")
+	result := make([]string, 0, len(lines))
+	for _, line := range lines {
+		line = strings.TrimSpace(spacePattern.ReplaceAllStringFunc(line, func(s string) string {
+			if utf8.RuneCountInString(s) > 1 {
+				return "  "
+			}
+			return " "
+		}))
+		if line != "" {
+			result = append(result, line)
+		}
+	}
+	return strings.Join(result, "\n")
+}
@@ -0,0 +1,56 @@
+package email_test
+
+import (
+	"testing"
+
+	"billing-note/internal/email"
+	"billing-note/internal/email/order_extractors"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHTMLToText(t *testing.T) {
+	html := `<html><style>.a{color:red}</style><body>
+<p>訂單編號：240101ABCDEFGH</p>
+<table><tr><td>手機殼</td><td>x2</td><td>$300</td></tr></table>
+<div>訂單金額：&#36;300</div></body></html>`
+
+	text := email.HTMLToText(html)
+	assert.Contains(t, text, "訂單編號：240101ABCDEFGH")
+	assert.Contains(t, text, "手機殼  x2  $300")
+	assert.Contains(t, text, "訂單金額：$300")
+	assert.NotContains(t, text, "color:red")
+}
+
+func TestExtractorRegistry_Find(t *testing.T) {
+	registry := order_extractors.NewRegistryWithAllExtractors()
+
+	tests := []struct {
+		from string
+		want string
+	}{
+		{from: "Shopee <info@mail.shopee.tw>", want: "蝦皮購物"},
+		{from: "service@momoshop.com.tw", want: "momo購物網"},
+		{from: "\"PChome 24h\" <service@ecmail.pchome.com.tw>", want: "PChome 24h購物"},
+		{from: "Uber Eats <uber.eats@uber.com>", want: "Uber Eats 優食"},
+		{from: "noreply@mail.ubereats.com", want: "Uber Eats 優食"},
+	}
+	for _, tt := range tests {
+		extractor := registry.Find(tt.from)
+		require.NotNil(t, extractor, tt.from)
+		assert.Equal(t, tt.want, extractor.Merchant())
+	}
+
+	assert.Nil(t, registry.Find("Cathay <service@cathaybk.com.tw>"))
+	// Uber ride receipts share the uber.com domain with Uber Eats
+	assert.Nil(t, registry.Find("Uber Receipts <noreply@uber.com>"))
+	assert.Nil(t, registry.Find("not-an-address"))
+}
+
+func TestExtractorRegistry_Extract_UnknownSender(t *testing.T) {
+	registry := order_extractors.NewRegistryWithAllExtractors()
+
+	_, _, err := registry.Extract("someone@example.com", "body")
+	assert.Error(t, err)
+}
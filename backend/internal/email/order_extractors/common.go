@@ -0,0 +1,149 @@
+package order_extractors
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"billing-note/internal/email"
+)
+
+// orderPatterns describes how a merchant lays out its order confirmation email.
+// The item pattern must use the named groups "name", "qty" and "amount".
+type orderPatterns struct {
+	orderNumber *regexp.Regexp
+	date        *regexp.Regexp
+	total       *regexp.Regexp
+	item        *regexp.Regexp
+}
+
+// dateLayouts are tried in order when parsing the order date group
+var dateLayouts = []string{
+	"2006-01-02 15:04:05",
+	"2006/01/02 15:04:05",
+	"2006-01-02 15:04",
+	"2006/01/02 15:04",
+	"2006-01-02",
+	"2006/01/02",
+	"2006年01月02日",
+	"2006年1月2日",
+}
+
+// parseOrders extracts orders from plain-text body using the given patterns
+func parseOrders(body string, p orderPatterns) []email.Order {
+	orders := make([]email.Order, 0)
+	var current *email.Order
+
+	flush := func() {
+		if current == nil {
+			return
+		}
+		if current.Total == 0 {
+			for _, item := range current.Items {
+				current.Total += item.Amount
+			}
+		}
+		if current.OrderNumber != "" && current.Total > 0 {
+			orders = append(orders, *current)
+		}
+		current = nil
+	}
+
+	for _, line := range strings.Split(body, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		if m := p.orderNumber.FindStringSubmatch(line); len(m) >= 2 {
+			// A new order number starts a new order when one email lists several
+			if current != nil && current.OrderNumber != "" && current.OrderNumber != m[1] {
+				flush()
+			}
+			if current == nil {
+				current = &email.Order{}
+			}
+			current.OrderNumber = m[1]
+			continue
+		}
+
+		if current == nil {
+			current = &email.Order{}
+		}
+
+		if current.Date.IsZero() {
+			if m := p.date.FindStringSubmatch(line); len(m) >= 2 {
+				if d, ok := parseDate(m[1]); ok {
+					current.Date = d
+					continue
+				}
+			}
+		}
+
+		if m := p.total.FindStringSubmatch(line); len(m) >= 2 {
+			if amount, ok := parseAmount(m[1]); ok {
+				current.Total = amount
+			}
+			continue
+		}
+
+		if item, ok := parseItem(line, p.item); ok {
+			current.Items = append(current.Items, item)
+		}
+	}
+	flush()
+
+	return orders
+}
+
+// parseItem parses a single item line using named groups
+func parseItem(line string, pattern *regexp.Regexp) (email.OrderItem, bool) {
+	m := pattern.FindStringSubmatch(line)
+	if m == nil {
+		return email.OrderItem{}, false
+	}
+
+	item := email.OrderItem{Quantity: 1}
+	for i, name := range pattern.SubexpNames() {
+		switch name {
+		case "name":
+			item.Name = strings.TrimSpace(m[i])
+		case "qty":
+			if q, err := strconv.Atoi(m[i]); err == nil && q > 0 {
+				item.Quantity = q
+			}
+		case "amount":
+			amount, ok := parseAmount(m[i])
+			if !ok {
+				return email.OrderItem{}, false
+			}
+			item.Amount = amount
+		}
+	}
+	if item.Name == "" || item.Amount == 0 {
+		return email.OrderItem{}, false
+	}
+	return item, true
+}
+
+// parseAmount parses amounts like "1,299" or "NT$1,299"
+func parseAmount(s string) (float64, bool) {
+	s = strings.NewReplacer(",", "", "NT$", "", "$", "", " ", "").Replace(s)
+	amount, err := strconv.ParseFloat(s, 64)
+	if err != nil || amount <= 0 {
+		return 0, false
+	}
+	return amount, true
+}
+
+// parseDate parses a date string using the known layouts in local time
+func parseDate(s string) (time.Time, bool) {
+	s = strings.TrimSpace(s)
+	for _, layout := range dateLayouts {
+		if d, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return d, true
+		}
+	}
+	return time.Time{}, false
+}
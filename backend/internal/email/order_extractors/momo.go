@@ -0,0 +1,46 @@
+package order_extractors
+
+import (
+	"regexp"
+
+	"billing-note/internal/email"
+)
+
+// MomoExtractor parses momo購物網 order confirmation emails
+type MomoExtractor struct {
+	patterns orderPatterns
+}
+
+// NewMomoExtractor creates a new momo extractor
+func NewMomoExtractor() *MomoExtractor {
+	// momo confirmation format (after HTML → text):
+	//   訂單編號：20240101123456
+	//   訂購日期：2024/01/01
+	//   【Dyson】無線吸塵器  1  12,900
+	//   訂單總金額：12,900元
+	return &MomoExtractor{
+		patterns: orderPatterns{
+			orderNumber: regexp.MustCompile(`訂單編號\s*[:：]\s*(\d{8,})`),
+			date:        regexp.MustCompile(`(?:訂購日期|訂購時間)\s*[:：]\s*(\d{4}[-/]\d{2}[-/]\d{2}(?:\s+\d{2}:\d{2}(?::\d{2})?)?)`),
+			total:       regexp.MustCompile(`(?:訂單總金額|應付金額|付款金額)\s*[:：]\s*\$?\s*([\d,]+)\s*元?`),
+			item:        regexp.MustCompile(`^(?P<name>.+?)\s{2,}(?P<qty>\d+)\s{2,}\$?(?P<amount>[\d,]+)\s*元?$`),
+		},
+	}
+}
+
+// Merchant returns the merchant name
+func (e *MomoExtractor) Merchant() string {
+	return "momo購物網"
+}
+
+// Senders returns the sender domains used by momo
+func (e *MomoExtractor) Senders() []string {
+	return []string{"momoshop.com.tw"}
+}
+
+// Extract parses orders from a momo email body
+func (e *MomoExtractor) Extract(body string) ([]email.Order, error) {
+	return parseOrders(body, e.patterns), nil
+}
+
+var _ email.OrderExtractor = (*MomoExtractor)(nil)
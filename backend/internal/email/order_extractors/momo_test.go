@@ -0,0 +1,42 @@
+package order_extractors
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMomoExtractor_Extract(t *testing.T) {
+	extractor := NewMomoExtractor()
+
+	body := `訂單編號：20240101123456
+訂購日期：2024/01/01
+商品名稱  數量  金額
+【Dyson】無線吸塵器  1  12,900
+濾網組  2  1,200元
+訂單總金額：14,100元`
+
+	orders, err := extractor.Extract(body)
+	require.NoError(t, err)
+	require.Len(t, orders, 1)
+
+	assert.Equal(t, "20240101123456", orders[0].OrderNumber)
+	assert.Equal(t, 14100.0, orders[0].Total)
+	require.Len(t, orders[0].Items, 2)
+	assert.Equal(t, "【Dyson】無線吸塵器", orders[0].Items[0].Name)
+	assert.Equal(t, 12900.0, orders[0].Items[0].Amount)
+	assert.Equal(t, 2, orders[0].Items[1].Quantity)
+}
+
+func TestMomoExtractor_Extract_TotalFromItems(t *testing.T) {
+	extractor := NewMomoExtractor()
+
+	body := `訂單編號：20240101123456
+衛生紙  3  597`
+
+	orders, err := extractor.Extract(body)
+	require.NoError(t, err)
+	require.Len(t, orders, 1)
+	assert.Equal(t, 597.0, orders[0].Total)
+}
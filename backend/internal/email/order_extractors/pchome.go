@@ -0,0 +1,46 @@
+package order_extractors
+
+import (
+	"regexp"
+
+	"billing-note/internal/email"
+)
+
+// PChomeExtractor parses PChome 24h購物 order confirmation emails
+type PChomeExtractor struct {
+	patterns orderPatterns
+}
+
+// NewPChomeExtractor creates a new PChome extractor
+func NewPChomeExtractor() *PChomeExtractor {
+	// PChome confirmation format (after HTML → text):
+	//   訂單編號：20240101123456-01
+	//   訂購時間：2024/01/01 10:00:00
+	//   Apple AirPods Pro  1  $7,490
+	//   總計：$7,490
+	return &PChomeExtractor{
+		patterns: orderPatterns{
+			orderNumber: regexp.MustCompile(`訂單編號\s*[:：]\s*([0-9A-Z][0-9A-Z\-]{7,})`),
+			date:        regexp.MustCompile(`(?:訂購時間|訂購日期|訂單日期)\s*[:：]\s*(\d{4}[-/]\d{2}[-/]\d{2}(?:\s+\d{2}:\d{2}(?::\d{2})?)?)`),
+			total:       regexp.MustCompile(`(?:總計|訂單金額|應付總額)\s*[:：]\s*\$?\s*([\d,]+)`),
+			item:        regexp.MustCompile(`^(?P<name>.+?)\s{2,}(?P<qty>\d+)\s{2,}\$(?P<amount>[\d,]+)$`),
+		},
+	}
+}
+
+// Merchant returns the merchant name
+func (e *PChomeExtractor) Merchant() string {
+	return "PChome 24h購物"
+}
+
+// Senders returns the sender domains used by PChome
+func (e *PChomeExtractor) Senders() []string {
+	return []string{"pchome.com.tw"}
+}
+
+// Extract parses orders from a PChome email body
+func (e *PChomeExtractor) Extract(body string) ([]email.Order, error) {
+	return parseOrders(body, e.patterns), nil
+}
+
+var _ email.OrderExtractor = (*PChomeExtractor)(nil)
@@ -0,0 +1,31 @@
+package order_extractors
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPChomeExtractor_Extract_MultipleOrders(t *testing.T) {
+	extractor := NewPChomeExtractor()
+
+	body := `訂單編號：20240101123456-01
+訂購時間：2024/01/01 10:00:00
+Apple AirPods Pro  1  $7,490
+總計：$7,490
+訂單編號：20240101123456-02
+訂購時間：2024/01/01 10:00:00
+USB 隨身碟  2  $598
+總計：$598`
+
+	orders, err := extractor.Extract(body)
+	require.NoError(t, err)
+	require.Len(t, orders, 2)
+
+	assert.Equal(t, "20240101123456-01", orders[0].OrderNumber)
+	assert.Equal(t, 7490.0, orders[0].Total)
+	assert.Equal(t, "20240101123456-02", orders[1].OrderNumber)
+	assert.Equal(t, 598.0, orders[1].Total)
+	assert.Equal(t, 2, orders[1].Items[0].Quantity)
+}
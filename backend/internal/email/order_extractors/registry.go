@@ -0,0 +1,20 @@
+package order_extractors
+
+import (
+	"billing-note/internal/email"
+)
+
+// RegisterAllExtractors registers all order extractors to the registry
+func RegisterAllExtractors(registry *email.ExtractorRegistry) {
+	registry.RegisterExtractor(NewShopeeExtractor())
+	registry.RegisterExtractor(NewMomoExtractor())
+	registry.RegisterExtractor(NewPChomeExtractor())
+	registry.RegisterExtractor(NewUberEatsExtractor())
+}
+
+// NewRegistryWithAllExtractors creates a new registry with all extractors registered
+func NewRegistryWithAllExtractors() *email.ExtractorRegistry {
+	registry := email.NewExtractorRegistry()
+	RegisterAllExtractors(registry)
+	return registry
+}
@@ -0,0 +1,46 @@
+package order_extractors
+
+import (
+	"regexp"
+
+	"billing-note/internal/email"
+)
+
+// ShopeeExtractor parses 蝦皮購物 order confirmation emails
+type ShopeeExtractor struct {
+	patterns orderPatterns
+}
+
+// NewShopeeExtractor creates a new Shopee extractor
+func NewShopeeExtractor() *ShopeeExtractor {
+	// Shopee confirmation format (after HTML → text):
+	//   訂單編號：240101ABCDEFGH
+	//   訂單成立時間：2024-01-01 12:34:56
+	//   USB-C 充電線  x1  $199
+	//   訂單金額：$499
+	return &ShopeeExtractor{
+		patterns: orderPatterns{
+			orderNumber: regexp.MustCompile(`訂單編號\s*[:：]\s*([0-9A-Z]{8,})`),
+			date:        regexp.MustCompile(`(?:訂單成立時間|下單時間|訂單日期)\s*[:：]\s*(\d{4}[-/]\d{2}[-/]\d{2}(?:\s+\d{2}:\d{2}(?::\d{2})?)?)`),
+			total:       regexp.MustCompile(`(?:訂單金額|訂單總金額|實付金額)\s*[:：]\s*((?:NT)?\$?\s*[\d,]+)`),
+			item:        regexp.MustCompile(`^(?P<name>.+?)\s+[xX×]\s*(?P<qty>\d+)\s+(?:NT)?\$\s*(?P<amount>[\d,]+)$`),
+		},
+	}
+}
+
+// Merchant returns the merchant name
+func (e *ShopeeExtractor) Merchant() string {
+	return "蝦皮購物"
+}
+
+// Senders returns the sender domains used by Shopee
+func (e *ShopeeExtractor) Senders() []string {
+	return []string{"shopee.tw", "shopee.com"}
+}
+
+// Extract parses orders from a Shopee email body
+func (e *ShopeeExtractor) Extract(body string) ([]email.Order, error) {
+	return parseOrders(body, e.patterns), nil
+}
+
+var _ email.OrderExtractor = (*ShopeeExtractor)(nil)
@@ -0,0 +1,49 @@
+package order_extractors
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShopeeExtractor_Merchant(t *testing.T) {
+	extractor := NewShopeeExtractor()
+	assert.Equal(t, "蝦皮購物", extractor.Merchant())
+	assert.Contains(t, extractor.Senders(), "shopee.tw")
+}
+
+func TestShopeeExtractor_Extract(t *testing.T) {
+	extractor := NewShopeeExtractor()
+
+	body := `親愛的買家您好，您的訂單已成立
+訂單編號：240101ABCDEFGH
+訂單成立時間：2024-01-01 12:34:56
+USB-C 充電線  x1  $199
+手機殼  x2  $300
+運費  $60
+訂單金額：$559`
+
+	orders, err := extractor.Extract(body)
+	require.NoError(t, err)
+	require.Len(t, orders, 1)
+
+	order := orders[0]
+	assert.Equal(t, "240101ABCDEFGH", order.OrderNumber)
+	assert.Equal(t, 559.0, order.Total)
+	assert.Equal(t, time.Date(2024, 1, 1, 12, 34, 56, 0, time.Local), order.Date)
+	require.Len(t, order.Items, 2)
+	assert.Equal(t, "USB-C 充電線", order.Items[0].Name)
+	assert.Equal(t, 1, order.Items[0].Quantity)
+	assert.Equal(t, 2, order.Items[1].Quantity)
+	assert.Equal(t, 300.0, order.Items[1].Amount)
+}
+
+func TestShopeeExtractor_Extract_NoOrder(t *testing.T) {
+	extractor := NewShopeeExtractor()
+
+	orders, err := extractor.Extract("蝦皮限時優惠，全站免運！")
+	require.NoError(t, err)
+	assert.Empty(t, orders)
+}
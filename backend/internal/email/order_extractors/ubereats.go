@@ -0,0 +1,67 @@
+package order_extractors
+
+import (
+	"regexp"
+	"strings"
+
+	"billing-note/internal/email"
+)
+
+// UberEatsExtractor parses Uber Eats receipt emails
+type UberEatsExtractor struct {
+	patterns   orderPatterns
+	restaurant *regexp.Regexp
+}
+
+// NewUberEatsExtractor creates a new Uber Eats extractor
+func NewUberEatsExtractor() *UberEatsExtractor {
+	// Uber Eats receipt format (after HTML → text):
+	//   2024年1月1日
+	//   訂購來源：麥當勞 台北民生店
+	//   訂單編號：3F2A1B
+	//   1  大麥克套餐  NT$150
+	//   總計  NT$350
+	return &UberEatsExtractor{
+		patterns: orderPatterns{
+			orderNumber: regexp.MustCompile(`(?:訂單編號|Order\s*(?:#|ID|number))\s*[:：]?\s*([0-9A-Za-z\-]{4,})`),
+			date:        regexp.MustCompile(`^(\d{4}年\d{1,2}月\d{1,2}日|\d{4}[-/]\d{2}[-/]\d{2})`),
+			total:       regexp.MustCompile(`^(?:總計|Total)\s*[:：]?\s*(NT\$\s*[\d,]+(?:\.\d+)?)`),
+			item:        regexp.MustCompile(`^(?P<qty>\d+)\s+(?P<name>.+?)\s{2,}NT\$\s*(?P<amount>[\d,]+(?:\.\d+)?)$`),
+		},
+		restaurant: regexp.MustCompile(`(?:訂購來源|You ordered from)\s*[:：]?\s*(.+)$`),
+	}
+}
+
+// Merchant returns the merchant name
+func (e *UberEatsExtractor) Merchant() string {
+	return "Uber Eats 優食"
+}
+
+// Senders returns the addresses Uber Eats receipts come from. uber.com also
+// sends ride receipts, so only the Eats addresses on it are matched.
+func (e *UberEatsExtractor) Senders() []string {
+	return []string{"ubereats.com", "uber.eats@uber.com", "ubereats@uber.com"}
+}
+
+// Extract parses orders from an Uber Eats receipt body.
+// The restaurant name, when present, is appended to the merchant.
+func (e *UberEatsExtractor) Extract(body string) ([]email.Order, error) {
+	orders := parseOrders(body, e.patterns)
+
+	restaurant := ""
+	for _, line := range strings.Split(body, "\n") {
+		if m := e.restaurant.FindStringSubmatch(strings.TrimSpace(line)); len(m) >= 2 {
+			restaurant = strings.TrimSpace(m[1])
+			break
+		}
+	}
+	if restaurant != "" {
+		for i := range orders {
+			orders[i].Merchant = e.Merchant() + " " + restaurant
+		}
+	}
+
+	return orders, nil
+}
+
+var _ email.OrderExtractor = (*UberEatsExtractor)(nil)
@@ -0,0 +1,35 @@
+package order_extractors
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUberEatsExtractor_Extract(t *testing.T) {
+	extractor := NewUberEatsExtractor()
+
+	body := `2024年1月1日
+感謝您訂購 Uber Eats
+訂購來源：麥當勞 台北民生店
+訂單編號：3F2A1B
+1  大麥克套餐  NT$150
+2  蘋果派  NT$80
+小計  NT$230
+總計  NT$265`
+
+	orders, err := extractor.Extract(body)
+	require.NoError(t, err)
+	require.Len(t, orders, 1)
+
+	order := orders[0]
+	assert.Equal(t, "3F2A1B", order.OrderNumber)
+	assert.Equal(t, "Uber Eats 優食 麥當勞 台北民生店", order.Merchant)
+	assert.Equal(t, 265.0, order.Total)
+	assert.Equal(t, time.Date(2024, 1, 1, 0, 0, 0, 0, time.Local), order.Date)
+	require.Len(t, order.Items, 2)
+	assert.Equal(t, "蘋果派", order.Items[1].Name)
+	assert.Equal(t, 2, order.Items[1].Quantity)
+}
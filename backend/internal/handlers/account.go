@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"billing-note/internal/middleware"
+	"billing-note/internal/models"
+	"billing-note/internal/services"
+	"billing-note/pkg/errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AccountHandler handles account endpoints
+type AccountHandler struct {
+	accountService *services.AccountService
+}
+
+// NewAccountHandler creates a new account handler
+func NewAccountHandler(accountService *services.AccountService) *AccountHandler {
+	return &AccountHandler{accountService: accountService}
+}
+
+// List returns the user's accounts
+// GET /api/accounts
+func (h *AccountHandler) List(c *gin.Context) {
+	requestID := c.GetString("request_id")
+
+	userID, exists := middleware.GetDataUserID(c)
+	if !exists {
+		appErr := errors.NewUnauthorizedError("User not authenticated")
+		c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		return
+	}
+
+	accounts, err := h.accountService.List(userID)
+	if err != nil {
+		appErr := errors.NewInternalError("Failed to list accounts", err)
+		c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"accounts": accounts})
+}
+
+// Create adds an account
+// POST /api/accounts
+func (h *AccountHandler) Create(c *gin.Context) {
+	requestID := c.GetString("request_id")
+
+	userID, exists := middleware.GetDataUserID(c)
+	if !exists {
+		appErr := errors.NewUnauthorizedError("User not authenticated")
+		c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		return
+	}
+
+	var input models.AccountInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		appErr := errors.NewValidationError("Invalid request: name is required")
+		c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		return
+	}
+
+	account, err := h.accountService.Create(userID, input)
+	if err != nil {
+		if appErr := errors.GetAppError(err); appErr != nil {
+			c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		} else {
+			appErr := errors.NewInternalError("Failed to create account", err)
+			c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, account)
+}
+
+// Update renames or retypes an account
+// PUT /api/accounts/:id
+func (h *AccountHandler) Update(c *gin.Context) {
+	requestID := c.GetString("request_id")
+
+	userID, exists := middleware.GetDataUserID(c)
+	if !exists {
+		appErr := errors.NewUnauthorizedError("User not authenticated")
+		c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		appErr := errors.NewValidationError("Invalid account ID")
+		c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		return
+	}
+
+	var input models.AccountInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		appErr := errors.NewValidationError("Invalid request: name is required")
+		c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		return
+	}
+
+	account, err := h.accountService.Update(userID, uint(id), input)
+	if err != nil {
+		if appErr := errors.GetAppError(err); appErr != nil {
+			c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		} else {
+			appErr := errors.NewInternalError("Failed to update account", err)
+			c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, account)
+}
+
+// Delete removes an account
+// DELETE /api/accounts/:id
+func (h *AccountHandler) Delete(c *gin.Context) {
+	requestID := c.GetString("request_id")
+
+	userID, exists := middleware.GetDataUserID(c)
+	if !exists {
+		appErr := errors.NewUnauthorizedError("User not authenticated")
+		c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		appErr := errors.NewValidationError("Invalid account ID")
+		c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		return
+	}
+
+	if err := h.accountService.Delete(userID, uint(id)); err != nil {
+		if appErr := errors.GetAppError(err); appErr != nil {
+			c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		} else {
+			appErr := errors.NewInternalError("Failed to delete account", err)
+			c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Account deleted"})
+}
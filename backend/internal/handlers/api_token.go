@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"billing-note/internal/middleware"
+	"billing-note/internal/models"
+	"billing-note/internal/services"
+	"billing-note/pkg/errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// APITokenHandler handles personal API token management
+type APITokenHandler struct {
+	apiTokenService *services.APITokenService
+}
+
+// NewAPITokenHandler creates a new API token handler
+func NewAPITokenHandler(apiTokenService *services.APITokenService) *APITokenHandler {
+	return &APITokenHandler{apiTokenService: apiTokenService}
+}
+
+// List returns the user's API tokens
+// GET /api/auth/tokens
+func (h *APITokenHandler) List(c *gin.Context) {
+	requestID := c.GetString("request_id")
+
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		appErr := errors.NewUnauthorizedError("User not authenticated")
+		c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		return
+	}
+
+	tokens, err := h.apiTokenService.List(userID)
+	if err != nil {
+		if appErr := errors.GetAppError(err); appErr != nil {
+			c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		} else {
+			appErr := errors.NewInternalError("Failed to list API tokens", err)
+			c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"tokens": tokens})
+}
+
+// Create issues an API token; the token is only shown in this response
+// POST /api/auth/tokens
+func (h *APITokenHandler) Create(c *gin.Context) {
+	requestID := c.GetString("request_id")
+
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		appErr := errors.NewUnauthorizedError("User not authenticated")
+		c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		return
+	}
+
+	var input models.APITokenInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		appErr := errors.NewValidationError("Invalid request: name and at least one scope are required, expires_in_days must be 1-365")
+		c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		return
+	}
+
+	created, err := h.apiTokenService.Create(userID, input)
+	if err != nil {
+		if appErr := errors.GetAppError(err); appErr != nil {
+			c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		} else {
+			appErr := errors.NewInternalError("Failed to create API token", err)
+			c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, created)
+}
+
+// Revoke stops an API token from working
+// DELETE /api/auth/tokens/:id
+func (h *APITokenHandler) Revoke(c *gin.Context) {
+	requestID := c.GetString("request_id")
+
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		appErr := errors.NewUnauthorizedError("User not authenticated")
+		c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		appErr := errors.NewValidationError("Invalid API token ID")
+		c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		return
+	}
+
+	if err := h.apiTokenService.Revoke(userID, uint(id)); err != nil {
+		if appErr := errors.GetAppError(err); appErr != nil {
+			c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		} else {
+			appErr := errors.NewInternalError("Failed to revoke API token", err)
+			c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "API token revoked"})
+}
@@ -1,10 +1,13 @@
 package handlers
 
 import (
+	"billing-note/internal/middleware"
+	"billing-note/internal/models"
 	"billing-note/internal/services"
 	"billing-note/pkg/errors"
 	"billing-note/pkg/logger"
 	"net/http"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 )
@@ -38,6 +41,7 @@ func (h *AuthHandler) Register(c *gin.Context) {
 		"email":      req.Email,
 		"name":       req.Name,
 	}).Info("Processing user registration")
+	req.Client = clientInfo(c)
 
 	response, err := h.authService.Register(&req)
 	if err != nil {
@@ -88,6 +92,7 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		"request_id": requestID,
 		"email":      req.Email,
 	}).Info("Processing login attempt")
+	req.Client = clientInfo(c)
 
 	response, err := h.authService.Login(&req)
 	if err != nil {
@@ -106,6 +111,16 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
+	if response.TwoFactorRequired {
+		log.WithFields(logger.Fields{
+			"request_id": requestID,
+			"email":      req.Email,
+		}).Info("Password accepted, two-factor code required")
+
+		c.JSON(http.StatusOK, response)
+		return
+	}
+
 	log.WithFields(logger.Fields{
 		"request_id": requestID,
 		"user_id":    response.User.ID,
@@ -115,6 +130,45 @@ func (h *AuthHandler) Login(c *gin.Context) {
 	c.JSON(http.StatusOK, response)
 }
 
+// LoginTwoFactor completes a login with the challenge token and a TOTP or
+// recovery code
+// POST /api/auth/login/2fa
+func (h *AuthHandler) LoginTwoFactor(c *gin.Context) {
+	log := logger.APILog("AuthHandler", "LoginTwoFactor")
+	requestID := c.GetString("request_id")
+
+	var req services.TwoFactorLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		appErr := errors.NewValidationError("Invalid request: challenge_token and code are required")
+		c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		return
+	}
+	req.Client = clientInfo(c)
+
+	response, err := h.authService.LoginTwoFactor(&req)
+	if err != nil {
+		log.WithFields(logger.Fields{
+			"request_id": requestID,
+			"error":      err.Error(),
+		}).Warn("Two-factor login failed")
+
+		if appErr := errors.GetAppError(err); appErr != nil {
+			c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		} else {
+			appErr := errors.NewInternalError("Failed to complete login", err)
+			c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		}
+		return
+	}
+
+	log.WithFields(logger.Fields{
+		"request_id": requestID,
+		"user_id":    response.User.ID,
+	}).Info("User logged in with two-factor authentication")
+
+	c.JSON(http.StatusOK, response)
+}
+
 func (h *AuthHandler) Me(c *gin.Context) {
 	log := logger.APILog("AuthHandler", "Me")
 	requestID := c.GetString("request_id")
@@ -142,3 +196,135 @@ func (h *AuthHandler) Me(c *gin.Context) {
 		"email":   email,
 	})
 }
+
+// Refresh exchanges a refresh token for a new access and refresh token
+// POST /api/auth/refresh
+func (h *AuthHandler) Refresh(c *gin.Context) {
+	requestID := c.GetString("request_id")
+
+	var req services.RefreshRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		appErr := errors.NewValidationError("Invalid request: refresh_token is required")
+		c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		return
+	}
+
+	response, err := h.authService.Refresh(req.RefreshToken, clientInfo(c))
+	if err != nil {
+		if appErr := errors.GetAppError(err); appErr != nil {
+			c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		} else {
+			appErr := errors.NewInvalidTokenError("Invalid or expired refresh token")
+			c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// Logout revokes the session of the current access token
+// POST /api/auth/logout
+func (h *AuthHandler) Logout(c *gin.Context) {
+	requestID := c.GetString("request_id")
+
+	if err := h.authService.Logout(middleware.GetSessionID(c)); err != nil {
+		if appErr := errors.GetAppError(err); appErr != nil {
+			c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		} else {
+			appErr := errors.NewInternalError("Failed to log out", err)
+			c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "logged out"})
+}
+
+// ListSessions returns the user's signed-in devices
+// GET /api/auth/sessions
+func (h *AuthHandler) ListSessions(c *gin.Context) {
+	requestID := c.GetString("request_id")
+
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		appErr := errors.NewUnauthorizedError("User not authenticated")
+		c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		return
+	}
+
+	sessions, err := h.authService.ListSessions(userID, middleware.GetSessionID(c))
+	if err != nil {
+		if appErr := errors.GetAppError(err); appErr != nil {
+			c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		} else {
+			appErr := errors.NewInternalError("Failed to list sessions", err)
+			c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"sessions": sessions})
+}
+
+// RevokeSession signs out one of the user's devices
+// DELETE /api/auth/sessions/:id
+func (h *AuthHandler) RevokeSession(c *gin.Context) {
+	requestID := c.GetString("request_id")
+
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		appErr := errors.NewUnauthorizedError("User not authenticated")
+		c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		appErr := errors.NewValidationError("Invalid session ID")
+		c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		return
+	}
+
+	if err := h.authService.RevokeSession(userID, uint(id)); err != nil {
+		if appErr := errors.GetAppError(err); appErr != nil {
+			c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		} else {
+			appErr := errors.NewInternalError("Failed to revoke session", err)
+			c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "session revoked"})
+}
+
+// RevokeAllSessions signs out every device except the current one
+// DELETE /api/auth/sessions
+func (h *AuthHandler) RevokeAllSessions(c *gin.Context) {
+	requestID := c.GetString("request_id")
+
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		appErr := errors.NewUnauthorizedError("User not authenticated")
+		c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		return
+	}
+
+	count, err := h.authService.RevokeAllSessions(userID, middleware.GetSessionID(c))
+	if err != nil {
+		if appErr := errors.GetAppError(err); appErr != nil {
+			c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		} else {
+			appErr := errors.NewInternalError("Failed to revoke sessions", err)
+			c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "sessions revoked", "revoked": count})
+}
+
+func clientInfo(c *gin.Context) models.ClientInfo {
+	return models.ClientInfo{UserAgent: c.Request.UserAgent(), IP: c.ClientIP()}
+}
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"billing-note/internal/models"
 	"billing-note/internal/services"
 	"bytes"
 	"encoding/json"
@@ -35,6 +36,42 @@ func (m *MockAuthService) Login(req *services.LoginRequest) (*services.AuthRespo
 	return args.Get(0).(*services.AuthResponse), args.Error(1)
 }
 
+func (m *MockAuthService) LoginTwoFactor(req *services.TwoFactorLoginRequest) (*services.AuthResponse, error) {
+	args := m.Called(req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*services.AuthResponse), args.Error(1)
+}
+
+func (m *MockAuthService) Refresh(refreshToken string, client models.ClientInfo) (*services.AuthResponse, error) {
+	args := m.Called(refreshToken, client)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*services.AuthResponse), args.Error(1)
+}
+
+func (m *MockAuthService) Logout(sessionID uint) error {
+	args := m.Called(sessionID)
+	return args.Error(0)
+}
+
+func (m *MockAuthService) ListSessions(userID, currentSessionID uint) ([]models.Session, error) {
+	args := m.Called(userID, currentSessionID)
+	return args.Get(0).([]models.Session), args.Error(1)
+}
+
+func (m *MockAuthService) RevokeSession(userID, sessionID uint) error {
+	args := m.Called(userID, sessionID)
+	return args.Error(0)
+}
+
+func (m *MockAuthService) RevokeAllSessions(userID, exceptID uint) (int64, error) {
+	args := m.Called(userID, exceptID)
+	return args.Get(0).(int64), args.Error(1)
+}
+
 func setupAuthTest() (*gin.Engine, *MockAuthService) {
 	gin.SetMode(gin.TestMode)
 	router := gin.New()
@@ -44,6 +81,11 @@ func setupAuthTest() (*gin.Engine, *MockAuthService) {
 	router.POST("/auth/register", handler.Register)
 	router.POST("/auth/login", handler.Login)
 	router.GET("/auth/me", handler.Me)
+	router.POST("/auth/refresh", handler.Refresh)
+	router.DELETE("/auth/sessions/:id", func(c *gin.Context) {
+		c.Set("user_id", uint(1))
+		handler.RevokeSession(c)
+	})
 
 	return router, mockService
 }
@@ -258,3 +300,78 @@ func TestAuthHandler_Me_Failure_Unauthenticated(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, "unauthorized", response["error"])
 }
+
+func TestAuthHandler_Refresh_Success(t *testing.T) {
+	router, mockService := setupAuthTest()
+
+	mockService.On("Refresh", "refresh-token", mock.AnythingOfType("models.ClientInfo")).Return(&services.AuthResponse{
+		Token:        "access-token",
+		RefreshToken: "new-refresh-token",
+		ExpiresIn:    900,
+	}, nil)
+
+	req, _ := http.NewRequest(http.MethodPost, "/auth/refresh", bytes.NewBufferString(`{"refresh_token":"refresh-token"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Firefox")
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "new-refresh-token")
+	client := mockService.Calls[0].Arguments.Get(1).(models.ClientInfo)
+	assert.Equal(t, "Firefox", client.UserAgent)
+}
+
+func TestAuthHandler_Refresh_Failure_MissingToken(t *testing.T) {
+	router, mockService := setupAuthTest()
+
+	req, _ := http.NewRequest(http.MethodPost, "/auth/refresh", bytes.NewBufferString(`{}`))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	mockService.AssertNotCalled(t, "Refresh", mock.Anything, mock.Anything)
+}
+
+func TestAuthHandler_RevokeSession(t *testing.T) {
+	router, mockService := setupAuthTest()
+
+	mockService.On("RevokeSession", uint(1), uint(8)).Return(nil)
+
+	req, _ := http.NewRequest(http.MethodDelete, "/auth/sessions/8", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	req, _ = http.NewRequest(http.MethodDelete, "/auth/sessions/abc", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestAuthHandler_Login_TwoFactorRequired(t *testing.T) {
+	router, mockService := setupAuthTest()
+
+	mockService.On("Login", mock.AnythingOfType("*services.LoginRequest")).Return(&services.AuthResponse{
+		TwoFactorRequired: true,
+		ChallengeToken:    "challenge",
+	}, nil)
+
+	req, _ := http.NewRequest(http.MethodPost, "/auth/login", bytes.NewBufferString(`{"email":"test@example.com","password":"password123"}`))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response map[string]interface{}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, true, response["two_factor_required"])
+	assert.Equal(t, "challenge", response["challenge_token"])
+	assert.NotContains(t, response, "token")
+	assert.NotContains(t, response, "user")
+}
@@ -0,0 +1,255 @@
+package handlers
+
+import (
+	"billing-note/internal/middleware"
+	"billing-note/internal/models"
+	"billing-note/internal/services"
+	"billing-note/pkg/errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// BillHandler handles bill reminder endpoints
+type BillHandler struct {
+	billService *services.BillService
+}
+
+// NewBillHandler creates a new bill handler
+func NewBillHandler(billService *services.BillService) *BillHandler {
+	return &BillHandler{billService: billService}
+}
+
+// List returns the user's bills
+// GET /api/bills?status=pending|paid
+func (h *BillHandler) List(c *gin.Context) {
+	requestID := c.GetString("request_id")
+
+	userID, exists := middleware.GetDataUserID(c)
+	if !exists {
+		appErr := errors.NewUnauthorizedError("User not authenticated")
+		c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		return
+	}
+
+	bills, err := h.billService.List(userID, c.Query("status"))
+	if err != nil {
+		if appErr := errors.GetAppError(err); appErr != nil {
+			c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		} else {
+			appErr := errors.NewInternalError("Failed to list bills", err)
+			c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"bills": bills})
+}
+
+// Overdue returns the user's unpaid bills past their due date
+// GET /api/bills/overdue
+func (h *BillHandler) Overdue(c *gin.Context) {
+	requestID := c.GetString("request_id")
+
+	userID, exists := middleware.GetDataUserID(c)
+	if !exists {
+		appErr := errors.NewUnauthorizedError("User not authenticated")
+		c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		return
+	}
+
+	bills, err := h.billService.Overdue(userID)
+	if err != nil {
+		if appErr := errors.GetAppError(err); appErr != nil {
+			c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		} else {
+			appErr := errors.NewInternalError("Failed to list overdue bills", err)
+			c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"bills": bills})
+}
+
+// Get returns a single bill
+// GET /api/bills/:id
+func (h *BillHandler) Get(c *gin.Context) {
+	requestID := c.GetString("request_id")
+
+	userID, exists := middleware.GetDataUserID(c)
+	if !exists {
+		appErr := errors.NewUnauthorizedError("User not authenticated")
+		c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		appErr := errors.NewValidationError("Invalid bill ID")
+		c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		return
+	}
+
+	bill, err := h.billService.Get(userID, uint(id))
+	if err != nil {
+		if appErr := errors.GetAppError(err); appErr != nil {
+			c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		} else {
+			appErr := errors.NewInternalError("Failed to get bill", err)
+			c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, bill)
+}
+
+// Create adds a manually entered bill
+// POST /api/bills
+func (h *BillHandler) Create(c *gin.Context) {
+	requestID := c.GetString("request_id")
+
+	userID, exists := middleware.GetDataUserID(c)
+	if !exists {
+		appErr := errors.NewUnauthorizedError("User not authenticated")
+		c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		return
+	}
+
+	var input models.BillInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		appErr := errors.NewValidationError("Invalid request: due_date is required")
+		c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		return
+	}
+
+	bill, err := h.billService.Create(userID, input)
+	if err != nil {
+		if appErr := errors.GetAppError(err); appErr != nil {
+			c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		} else {
+			appErr := errors.NewInternalError("Failed to create bill", err)
+			c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, bill)
+}
+
+// Update edits a bill
+// PUT /api/bills/:id
+func (h *BillHandler) Update(c *gin.Context) {
+	requestID := c.GetString("request_id")
+
+	userID, exists := middleware.GetDataUserID(c)
+	if !exists {
+		appErr := errors.NewUnauthorizedError("User not authenticated")
+		c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		appErr := errors.NewValidationError("Invalid bill ID")
+		c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		return
+	}
+
+	var input models.BillInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		appErr := errors.NewValidationError("Invalid request: due_date is required")
+		c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		return
+	}
+
+	bill, err := h.billService.Update(userID, uint(id), input)
+	if err != nil {
+		if appErr := errors.GetAppError(err); appErr != nil {
+			c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		} else {
+			appErr := errors.NewInternalError("Failed to update bill", err)
+			c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, bill)
+}
+
+// Delete removes a bill
+// DELETE /api/bills/:id
+func (h *BillHandler) Delete(c *gin.Context) {
+	requestID := c.GetString("request_id")
+
+	userID, exists := middleware.GetDataUserID(c)
+	if !exists {
+		appErr := errors.NewUnauthorizedError("User not authenticated")
+		c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		appErr := errors.NewValidationError("Invalid bill ID")
+		c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		return
+	}
+
+	if err := h.billService.Delete(userID, uint(id)); err != nil {
+		if appErr := errors.GetAppError(err); appErr != nil {
+			c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		} else {
+			appErr := errors.NewInternalError("Failed to delete bill", err)
+			c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Bill deleted"})
+}
+
+// MarkPaid marks a bill as paid, optionally linking the payment transaction
+// POST /api/bills/:id/pay
+func (h *BillHandler) MarkPaid(c *gin.Context) {
+	requestID := c.GetString("request_id")
+
+	userID, exists := middleware.GetDataUserID(c)
+	if !exists {
+		appErr := errors.NewUnauthorizedError("User not authenticated")
+		c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		appErr := errors.NewValidationError("Invalid bill ID")
+		c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		return
+	}
+
+	// The body is optional
+	var req models.MarkBillPaidRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			appErr := errors.NewValidationError("Invalid request body")
+			c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+			return
+		}
+	}
+
+	bill, err := h.billService.MarkPaid(userID, uint(id), req)
+	if err != nil {
+		if appErr := errors.GetAppError(err); appErr != nil {
+			c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		} else {
+			appErr := errors.NewInternalError("Failed to mark bill paid", err)
+			c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, bill)
+}
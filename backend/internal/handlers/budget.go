@@ -23,7 +23,7 @@ func NewBudgetHandler(budgetService *services.BudgetService) *BudgetHandler {
 func (h *BudgetHandler) Create(c *gin.Context) {
 	requestID := c.GetString("request_id")
 
-	userID, exists := middleware.GetUserID(c)
+	userID, exists := middleware.GetDataUserID(c)
 	if !exists {
 		appErr := errors.NewUnauthorizedError("User not authenticated")
 		c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
@@ -37,7 +37,7 @@ func (h *BudgetHandler) Create(c *gin.Context) {
 		return
 	}
 
-	budget, err := h.budgetService.Create(userID, req)
+	budget, err := h.budgetService.ForLedger(middleware.GetLedgerID(c)).Create(userID, req)
 	if err != nil {
 		if appErr := errors.GetAppError(err); appErr != nil {
 			c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
@@ -54,14 +54,14 @@ func (h *BudgetHandler) Create(c *gin.Context) {
 func (h *BudgetHandler) List(c *gin.Context) {
 	requestID := c.GetString("request_id")
 
-	userID, exists := middleware.GetUserID(c)
+	userID, exists := middleware.GetDataUserID(c)
 	if !exists {
 		appErr := errors.NewUnauthorizedError("User not authenticated")
 		c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
 		return
 	}
 
-	budgets, err := h.budgetService.List(userID)
+	budgets, err := h.budgetService.ForLedger(middleware.GetLedgerID(c)).List(userID)
 	if err != nil {
 		appErr := errors.NewInternalError("Failed to list budgets", err)
 		c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
@@ -74,7 +74,7 @@ func (h *BudgetHandler) List(c *gin.Context) {
 func (h *BudgetHandler) Update(c *gin.Context) {
 	requestID := c.GetString("request_id")
 
-	userID, exists := middleware.GetUserID(c)
+	userID, exists := middleware.GetDataUserID(c)
 	if !exists {
 		appErr := errors.NewUnauthorizedError("User not authenticated")
 		c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
@@ -95,7 +95,7 @@ func (h *BudgetHandler) Update(c *gin.Context) {
 		return
 	}
 
-	budget, err := h.budgetService.Update(uint(id), userID, req)
+	budget, err := h.budgetService.ForLedger(middleware.GetLedgerID(c)).Update(uint(id), userID, req)
 	if err != nil {
 		if appErr := errors.GetAppError(err); appErr != nil {
 			c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
@@ -112,7 +112,7 @@ func (h *BudgetHandler) Update(c *gin.Context) {
 func (h *BudgetHandler) Delete(c *gin.Context) {
 	requestID := c.GetString("request_id")
 
-	userID, exists := middleware.GetUserID(c)
+	userID, exists := middleware.GetDataUserID(c)
 	if !exists {
 		appErr := errors.NewUnauthorizedError("User not authenticated")
 		c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
@@ -126,7 +126,7 @@ func (h *BudgetHandler) Delete(c *gin.Context) {
 		return
 	}
 
-	if err := h.budgetService.Delete(uint(id), userID); err != nil {
+	if err := h.budgetService.ForLedger(middleware.GetLedgerID(c)).Delete(uint(id), userID); err != nil {
 		if appErr := errors.GetAppError(err); appErr != nil {
 			c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
 		} else {
@@ -142,20 +142,38 @@ func (h *BudgetHandler) Delete(c *gin.Context) {
 func (h *BudgetHandler) Compare(c *gin.Context) {
 	requestID := c.GetString("request_id")
 
-	userID, exists := middleware.GetUserID(c)
+	userID, exists := middleware.GetDataUserID(c)
 	if !exists {
 		appErr := errors.NewUnauthorizedError("User not authenticated")
 		c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
 		return
 	}
 
+	// An explicit date selects the periods containing that day
+	if dateStr := c.Query("date"); dateStr != "" {
+		ref, err := time.Parse("2006-01-02", dateStr)
+		if err != nil {
+			appErr := errors.NewInvalidInputError("date", "must be YYYY-MM-DD")
+			c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+			return
+		}
+		comparisons, err := h.budgetService.ForLedger(middleware.GetLedgerID(c)).CompareAt(userID, ref)
+		if err != nil {
+			appErr := errors.NewInternalError("Failed to compare budgets", err)
+			c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"comparisons": comparisons})
+		return
+	}
+
 	now := time.Now()
 	yearStr := c.DefaultQuery("year", strconv.Itoa(now.Year()))
 	monthStr := c.DefaultQuery("month", strconv.Itoa(int(now.Month())))
 	year, _ := strconv.Atoi(yearStr)
 	month, _ := strconv.Atoi(monthStr)
 
-	comparisons, err := h.budgetService.Compare(userID, year, month)
+	comparisons, err := h.budgetService.ForLedger(middleware.GetLedgerID(c)).Compare(userID, year, month)
 	if err != nil {
 		appErr := errors.NewInternalError("Failed to compare budgets", err)
 		c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
@@ -164,3 +182,38 @@ func (h *BudgetHandler) Compare(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{"comparisons": comparisons})
 }
+
+// History returns budget vs actual across recent periods
+// GET /api/budget/:id/history?limit=12
+func (h *BudgetHandler) History(c *gin.Context) {
+	requestID := c.GetString("request_id")
+
+	userID, exists := middleware.GetDataUserID(c)
+	if !exists {
+		appErr := errors.NewUnauthorizedError("User not authenticated")
+		c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		appErr := errors.NewValidationError("Invalid budget ID")
+		c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		return
+	}
+
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "12"))
+
+	history, err := h.budgetService.ForLedger(middleware.GetLedgerID(c)).History(uint(id), userID, limit)
+	if err != nil {
+		if appErr := errors.GetAppError(err); appErr != nil {
+			c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		} else {
+			appErr := errors.NewInternalError("Failed to get budget history", err)
+			c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"history": history})
+}
@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"billing-note/internal/middleware"
+	"billing-note/internal/services"
+	"billing-note/pkg/errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CalendarHandler handles the iCalendar feed endpoints
+type CalendarHandler struct {
+	calendarService *services.CalendarService
+}
+
+// NewCalendarHandler creates a new calendar handler
+func NewCalendarHandler(calendarService *services.CalendarService) *CalendarHandler {
+	return &CalendarHandler{calendarService: calendarService}
+}
+
+// GetFeed returns the user's feed URL, if the feed is enabled
+// GET /api/calendar/feed
+func (h *CalendarHandler) GetFeed(c *gin.Context) {
+	requestID := c.GetString("request_id")
+
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		appErr := errors.NewUnauthorizedError("User not authenticated")
+		c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		return
+	}
+
+	token, err := h.calendarService.GetFeedToken(userID)
+	if err != nil {
+		appErr := errors.NewInternalError("Failed to get calendar feed", err)
+		c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		return
+	}
+	if token == nil {
+		c.JSON(http.StatusOK, gin.H{"enabled": false})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"enabled":          true,
+		"token":            token.Token,
+		"url":              feedURL(c, token.Token),
+		"last_accessed_at": token.LastAccessedAt,
+	})
+}
+
+// RegenerateFeed enables the feed with a new token; the old URL stops working
+// POST /api/calendar/feed/regenerate
+func (h *CalendarHandler) RegenerateFeed(c *gin.Context) {
+	requestID := c.GetString("request_id")
+
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		appErr := errors.NewUnauthorizedError("User not authenticated")
+		c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		return
+	}
+
+	token, err := h.calendarService.RegenerateFeedToken(userID)
+	if err != nil {
+		appErr := errors.NewInternalError("Failed to regenerate calendar feed", err)
+		c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"enabled": true,
+		"token":   token.Token,
+		"url":     feedURL(c, token.Token),
+	})
+}
+
+// RevokeFeed disables the feed
+// DELETE /api/calendar/feed
+func (h *CalendarHandler) RevokeFeed(c *gin.Context) {
+	requestID := c.GetString("request_id")
+
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		appErr := errors.NewUnauthorizedError("User not authenticated")
+		c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		return
+	}
+
+	if err := h.calendarService.RevokeFeedToken(userID); err != nil {
+		if appErr := errors.GetAppError(err); appErr != nil {
+			c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		} else {
+			appErr := errors.NewInternalError("Failed to revoke calendar feed", err)
+			c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Calendar feed revoked"})
+}
+
+// Feed serves the iCalendar feed; the secret token is the only credential
+// GET /calendar/:token (".ics" suffix optional)
+func (h *CalendarHandler) Feed(c *gin.Context) {
+	token := strings.TrimSuffix(c.Param("token"), ".ics")
+
+	data, err := h.calendarService.Feed(token)
+	if err != nil {
+		if appErr := errors.GetAppError(err); appErr != nil && appErr.HTTPStatus == http.StatusNotFound {
+			c.String(http.StatusNotFound, "calendar not found")
+			return
+		}
+		c.String(http.StatusInternalServerError, "failed to render calendar")
+		return
+	}
+
+	c.Header("Cache-Control", "private, max-age=900")
+	c.Data(http.StatusOK, "text/calendar; charset=utf-8", data)
+}
+
+// feedURL builds the absolute feed URL for subscribing from calendar apps
+func feedURL(c *gin.Context, token string) string {
+	scheme := "http"
+	if c.Request.TLS != nil {
+		scheme = "https"
+	}
+	if proto := c.GetHeader("X-Forwarded-Proto"); proto != "" {
+		scheme = proto
+	}
+	return scheme + "://" + c.Request.Host + "/calendar/" + token + ".ics"
+}
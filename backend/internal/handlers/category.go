@@ -1,6 +1,8 @@
 package handlers
 
 import (
+	"billing-note/internal/middleware"
+	"billing-note/internal/models"
 	"billing-note/internal/repository"
 	"billing-note/pkg/errors"
 	"billing-note/pkg/logger"
@@ -23,7 +25,7 @@ func (h *CategoryHandler) GetAll(c *gin.Context) {
 
 	log.WithField("request_id", requestID).Debug("Fetching all categories")
 
-	categories, err := h.categoryRepo.GetAll()
+	categories, err := h.list(c, "")
 	if err != nil {
 		log.WithFields(logger.Fields{
 			"request_id": requestID,
@@ -65,7 +67,7 @@ func (h *CategoryHandler) GetByType(c *gin.Context) {
 		return
 	}
 
-	categories, err := h.categoryRepo.GetByType(categoryType)
+	categories, err := h.list(c, categoryType)
 	if err != nil {
 		log.WithFields(logger.Fields{
 			"request_id": requestID,
@@ -86,3 +88,14 @@ func (h *CategoryHandler) GetByType(c *gin.Context) {
 
 	c.JSON(http.StatusOK, categories)
 }
+
+// list returns the global categories, plus the selected household ledger's own
+func (h *CategoryHandler) list(c *gin.Context, categoryType string) ([]models.Category, error) {
+	if ledgerID := middleware.GetLedgerID(c); ledgerID != nil {
+		return h.categoryRepo.ListByLedger(*ledgerID, categoryType)
+	}
+	if categoryType == "" {
+		return h.categoryRepo.GetAll()
+	}
+	return h.categoryRepo.GetByType(categoryType)
+}
@@ -42,6 +42,11 @@ func (m *MockCategoryRepository) GetByID(id uint) (*models.Category, error) {
 	return args.Get(0).(*models.Category), args.Error(1)
 }
 
+func (m *MockCategoryRepository) ListByLedger(ledgerID uint, categoryType string) ([]models.Category, error) {
+	args := m.Called(ledgerID, categoryType)
+	return args.Get(0).([]models.Category), args.Error(1)
+}
+
 func (m *MockCategoryRepository) Create(category *models.Category) error {
 	args := m.Called(category)
 	return args.Error(0)
@@ -0,0 +1,155 @@
+package handlers
+
+import (
+	"billing-note/internal/middleware"
+	"billing-note/internal/models"
+	"billing-note/internal/services"
+	"billing-note/pkg/errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CredentialHandler handles password changes, password resets and email verification
+type CredentialHandler struct {
+	credentialService *services.CredentialService
+}
+
+// NewCredentialHandler creates a new credential handler
+func NewCredentialHandler(credentialService *services.CredentialService) *CredentialHandler {
+	return &CredentialHandler{credentialService: credentialService}
+}
+
+// ChangePassword sets a new password and signs out the user's other devices
+// POST /api/auth/password
+func (h *CredentialHandler) ChangePassword(c *gin.Context) {
+	requestID := c.GetString("request_id")
+
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		appErr := errors.NewUnauthorizedError("User not authenticated")
+		c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		return
+	}
+
+	var input models.ChangePasswordInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		appErr := errors.NewValidationError("Invalid request: current_password and new_password (at least 6 characters) are required")
+		c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		return
+	}
+
+	if err := h.credentialService.ChangePassword(userID, middleware.GetSessionID(c), input); err != nil {
+		if appErr := errors.GetAppError(err); appErr != nil {
+			c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		} else {
+			appErr := errors.NewInternalError("Failed to change password", err)
+			c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "password changed"})
+}
+
+// ForgotPassword emails a password reset link
+// POST /api/auth/password/forgot
+func (h *CredentialHandler) ForgotPassword(c *gin.Context) {
+	requestID := c.GetString("request_id")
+
+	var input models.ForgotPasswordInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		appErr := errors.NewValidationError("Invalid request: a valid email is required")
+		c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		return
+	}
+
+	if err := h.credentialService.RequestPasswordReset(input.Email); err != nil {
+		if appErr := errors.GetAppError(err); appErr != nil {
+			c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		} else {
+			appErr := errors.NewInternalError("Failed to request password reset", err)
+			c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		}
+		return
+	}
+
+	// Same response whether or not the address is registered
+	c.JSON(http.StatusOK, gin.H{"message": "if the address is registered, a reset link has been sent"})
+}
+
+// ResetPassword sets a new password with a reset token
+// POST /api/auth/password/reset
+func (h *CredentialHandler) ResetPassword(c *gin.Context) {
+	requestID := c.GetString("request_id")
+
+	var input models.ResetPasswordInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		appErr := errors.NewValidationError("Invalid request: token and new_password (at least 6 characters) are required")
+		c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		return
+	}
+
+	if err := h.credentialService.ResetPassword(input); err != nil {
+		if appErr := errors.GetAppError(err); appErr != nil {
+			c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		} else {
+			appErr := errors.NewInternalError("Failed to reset password", err)
+			c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "password reset, please log in"})
+}
+
+// VerifyEmail confirms the user's address with a verification token
+// POST /api/auth/verify-email
+func (h *CredentialHandler) VerifyEmail(c *gin.Context) {
+	requestID := c.GetString("request_id")
+
+	var input models.VerifyEmailInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		appErr := errors.NewValidationError("Invalid request: token is required")
+		c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		return
+	}
+
+	user, err := h.credentialService.VerifyEmail(input.Token)
+	if err != nil {
+		if appErr := errors.GetAppError(err); appErr != nil {
+			c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		} else {
+			appErr := errors.NewInternalError("Failed to verify email", err)
+			c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "email verified", "email_verified_at": user.EmailVerifiedAt})
+}
+
+// ResendVerification emails a new verification link
+// POST /api/auth/verify-email/resend
+func (h *CredentialHandler) ResendVerification(c *gin.Context) {
+	requestID := c.GetString("request_id")
+
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		appErr := errors.NewUnauthorizedError("User not authenticated")
+		c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		return
+	}
+
+	if err := h.credentialService.ResendVerification(userID); err != nil {
+		if appErr := errors.GetAppError(err); appErr != nil {
+			c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		} else {
+			appErr := errors.NewInternalError("Failed to send verification email", err)
+			c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "verification email sent"})
+}
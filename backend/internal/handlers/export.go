@@ -22,7 +22,7 @@ func NewExportHandler(exportService *services.ExportService) *ExportHandler {
 func (h *ExportHandler) ExportCSV(c *gin.Context) {
 	requestID := c.GetString("request_id")
 
-	userID, exists := middleware.GetUserID(c)
+	userID, exists := middleware.GetDataUserID(c)
 	if !exists {
 		appErr := errors.NewUnauthorizedError("User not authenticated")
 		c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
@@ -36,7 +36,7 @@ func (h *ExportHandler) ExportCSV(c *gin.Context) {
 		return
 	}
 
-	data, err := h.exportService.ExportCSV(userID, startDate, endDate)
+	data, err := h.exportService.ForLedger(middleware.GetLedgerID(c)).ExportCSV(userID, startDate, endDate)
 	if err != nil {
 		appErr := errors.NewInternalError("Failed to export CSV", err)
 		c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
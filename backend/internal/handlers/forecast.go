@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"billing-note/internal/middleware"
+	"billing-note/internal/services"
+	"billing-note/pkg/errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ForecastHandler handles cash-flow forecast endpoints
+type ForecastHandler struct {
+	forecastService *services.ForecastService
+}
+
+// NewForecastHandler creates a new forecast handler
+func NewForecastHandler(forecastService *services.ForecastService) *ForecastHandler {
+	return &ForecastHandler{forecastService: forecastService}
+}
+
+// Get projects cash flow for the coming months
+// GET /api/forecast?months=3&starting_balance=50000
+func (h *ForecastHandler) Get(c *gin.Context) {
+	requestID := c.GetString("request_id")
+
+	userID, exists := middleware.GetDataUserID(c)
+	if !exists {
+		appErr := errors.NewUnauthorizedError("User not authenticated")
+		c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		return
+	}
+
+	months, err := strconv.Atoi(c.DefaultQuery("months", "3"))
+	if err != nil {
+		appErr := errors.NewValidationError("Invalid months")
+		c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		return
+	}
+
+	startingBalance := 0.0
+	if s := c.Query("starting_balance"); s != "" {
+		startingBalance, err = strconv.ParseFloat(s, 64)
+		if err != nil {
+			appErr := errors.NewValidationError("Invalid starting_balance")
+			c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+			return
+		}
+	}
+
+	forecast, err := h.forecastService.Forecast(userID, months, startingBalance)
+	if err != nil {
+		if appErr := errors.GetAppError(err); appErr != nil {
+			c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		} else {
+			appErr := errors.NewInternalError("Failed to build forecast", err)
+			c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, forecast)
+}
@@ -7,6 +7,7 @@ import (
 	"billing-note/pkg/errors"
 	"billing-note/pkg/logger"
 	"net/http"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 )
@@ -244,3 +245,147 @@ func (h *GmailHandler) TriggerScan(c *gin.Context) {
 
 	c.JSON(http.StatusOK, result)
 }
+
+// ListSenderRules returns the per-sender routing rules
+// GET /api/gmail/sender-rules
+func (h *GmailHandler) ListSenderRules(c *gin.Context) {
+	requestID := c.GetString("request_id")
+
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		appErr := errors.NewUnauthorizedError("User not authenticated")
+		c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		return
+	}
+
+	rules, err := h.gmailService.ListSenderRules(userID)
+	if err != nil {
+		if appErr := errors.GetAppError(err); appErr != nil {
+			c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		} else {
+			appErr := errors.NewInternalError("Failed to list sender rules", err)
+			c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"rules": rules})
+}
+
+// CreateSenderRule creates a per-sender routing rule
+// POST /api/gmail/sender-rules
+func (h *GmailHandler) CreateSenderRule(c *gin.Context) {
+	log := logger.APILog("GmailHandler", "CreateSenderRule")
+	requestID := c.GetString("request_id")
+
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		appErr := errors.NewUnauthorizedError("User not authenticated")
+		c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		return
+	}
+
+	var input models.GmailSenderRuleInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		appErr := errors.NewValidationError("Invalid request body: " + err.Error())
+		c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		return
+	}
+
+	rule, err := h.gmailService.CreateSenderRule(userID, input)
+	if err != nil {
+		log.WithFields(logger.Fields{
+			"request_id": requestID,
+			"user_id":    userID,
+			"error":      err.Error(),
+		}).Error("Failed to create sender rule")
+		if appErr := errors.GetAppError(err); appErr != nil {
+			c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		} else {
+			appErr := errors.NewInternalError("Failed to create sender rule", err)
+			c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, rule)
+}
+
+// UpdateSenderRule updates a per-sender routing rule
+// PUT /api/gmail/sender-rules/:id
+func (h *GmailHandler) UpdateSenderRule(c *gin.Context) {
+	log := logger.APILog("GmailHandler", "UpdateSenderRule")
+	requestID := c.GetString("request_id")
+
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		appErr := errors.NewUnauthorizedError("User not authenticated")
+		c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		appErr := errors.NewValidationError("Invalid sender rule ID")
+		c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		return
+	}
+
+	var input models.GmailSenderRuleInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		appErr := errors.NewValidationError("Invalid request body: " + err.Error())
+		c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		return
+	}
+
+	rule, err := h.gmailService.UpdateSenderRule(userID, uint(id), input)
+	if err != nil {
+		log.WithFields(logger.Fields{
+			"request_id": requestID,
+			"user_id":    userID,
+			"rule_id":    id,
+			"error":      err.Error(),
+		}).Error("Failed to update sender rule")
+		if appErr := errors.GetAppError(err); appErr != nil {
+			c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		} else {
+			appErr := errors.NewInternalError("Failed to update sender rule", err)
+			c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, rule)
+}
+
+// DeleteSenderRule deletes a per-sender routing rule
+// DELETE /api/gmail/sender-rules/:id
+func (h *GmailHandler) DeleteSenderRule(c *gin.Context) {
+	requestID := c.GetString("request_id")
+
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		appErr := errors.NewUnauthorizedError("User not authenticated")
+		c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		appErr := errors.NewValidationError("Invalid sender rule ID")
+		c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		return
+	}
+
+	if err := h.gmailService.DeleteSenderRule(userID, uint(id)); err != nil {
+		if appErr := errors.GetAppError(err); appErr != nil {
+			c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		} else {
+			appErr := errors.NewInternalError("Failed to delete sender rule", err)
+			c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Sender rule deleted"})
+}
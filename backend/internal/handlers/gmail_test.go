@@ -52,6 +52,29 @@ func (m *mockGmailRepoHandler) SaveScanRule(rule *models.GmailScanRule) error {
 	return args.Error(0)
 }
 
+func (m *mockGmailRepoHandler) ListSenderRules(userID uint) ([]models.GmailSenderRule, error) {
+	args := m.Called(userID)
+	return args.Get(0).([]models.GmailSenderRule), args.Error(1)
+}
+
+func (m *mockGmailRepoHandler) GetSenderRule(userID, id uint) (*models.GmailSenderRule, error) {
+	args := m.Called(userID, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.GmailSenderRule), args.Error(1)
+}
+
+func (m *mockGmailRepoHandler) SaveSenderRule(rule *models.GmailSenderRule) error {
+	args := m.Called(rule)
+	return args.Error(0)
+}
+
+func (m *mockGmailRepoHandler) DeleteSenderRule(userID, id uint) error {
+	args := m.Called(userID, id)
+	return args.Error(0)
+}
+
 func (m *mockGmailRepoHandler) CreateScanHistory(history *models.GmailScanHistory) error {
 	args := m.Called(history)
 	return args.Error(0)
@@ -104,6 +127,7 @@ func setupGmailTestRouter() (*gin.Engine, *mockGmailRepoHandler) {
 	api.GET("/gmail/status", handler.GetStatus)
 	api.PUT("/gmail/settings", handler.UpdateSettings)
 	api.DELETE("/gmail/disconnect", handler.Disconnect)
+	api.POST("/gmail/sender-rules", handler.CreateSenderRule)
 
 	return r, repo
 }
@@ -205,3 +229,35 @@ func TestUpdateSettings_InvalidBody(t *testing.T) {
 
 	assert.Equal(t, http.StatusBadRequest, w.Code)
 }
+
+func TestCreateSenderRule_Integration(t *testing.T) {
+	r, repo := setupGmailTestRouter()
+	repo.On("SaveSenderRule", mock.AnythingOfType("*models.GmailSenderRule")).Return(nil)
+
+	w := httptest.NewRecorder()
+	body := `{"sender":"cathaybk.com.tw","bank_hint":"國泰世華","password_priority":2,"label":"Cathay"}`
+	req, _ := http.NewRequest("POST", "/api/gmail/sender-rules", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var resp models.GmailSenderRule
+	err := json.Unmarshal(w.Body.Bytes(), &resp)
+	assert.NoError(t, err)
+	assert.Equal(t, "cathaybk.com.tw", resp.Sender)
+	assert.Equal(t, 2, resp.PasswordPriority)
+	assert.True(t, resp.Enabled)
+}
+
+func TestCreateSenderRule_InvalidPriority(t *testing.T) {
+	r, _ := setupGmailTestRouter()
+
+	w := httptest.NewRecorder()
+	body := `{"sender":"cathaybk.com.tw","password_priority":9}`
+	req, _ := http.NewRequest("POST", "/api/gmail/sender-rules", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
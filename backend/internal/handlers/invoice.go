@@ -31,7 +31,7 @@ func (h *InvoiceHandler) Sync(c *gin.Context) {
 	log := logger.APILog("InvoiceHandler", "Sync")
 	requestID := c.GetString("request_id")
 
-	userID, exists := middleware.GetUserID(c)
+	userID, exists := middleware.GetDataUserID(c)
 	if !exists {
 		appErr := errors.NewUnauthorizedError("User not authenticated")
 		c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
@@ -88,7 +88,7 @@ func (h *InvoiceHandler) Sync(c *gin.Context) {
 func (h *InvoiceHandler) List(c *gin.Context) {
 	requestID := c.GetString("request_id")
 
-	userID, exists := middleware.GetUserID(c)
+	userID, exists := middleware.GetDataUserID(c)
 	if !exists {
 		appErr := errors.NewUnauthorizedError("User not authenticated")
 		c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
@@ -130,7 +130,7 @@ func (h *InvoiceHandler) List(c *gin.Context) {
 func (h *InvoiceHandler) ConfirmDuplicate(c *gin.Context) {
 	requestID := c.GetString("request_id")
 
-	_, exists := middleware.GetUserID(c)
+	userID, exists := middleware.GetDataUserID(c)
 	if !exists {
 		appErr := errors.NewUnauthorizedError("User not authenticated")
 		c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
@@ -144,7 +144,7 @@ func (h *InvoiceHandler) ConfirmDuplicate(c *gin.Context) {
 		return
 	}
 
-	if err := h.invoiceService.ConfirmDuplicate(req.InvoiceID, req.TransactionID); err != nil {
+	if err := h.invoiceService.ConfirmDuplicate(userID, req.InvoiceID, req.TransactionID); err != nil {
 		if appErr := errors.GetAppError(err); appErr != nil {
 			c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
 		} else {
@@ -162,7 +162,7 @@ func (h *InvoiceHandler) ConfirmDuplicate(c *gin.Context) {
 func (h *InvoiceHandler) Delete(c *gin.Context) {
 	requestID := c.GetString("request_id")
 
-	_, exists := middleware.GetUserID(c)
+	userID, exists := middleware.GetDataUserID(c)
 	if !exists {
 		appErr := errors.NewUnauthorizedError("User not authenticated")
 		c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
@@ -176,9 +176,13 @@ func (h *InvoiceHandler) Delete(c *gin.Context) {
 		return
 	}
 
-	if err := h.invoiceService.DeleteInvoice(uint(id)); err != nil {
-		appErr := errors.NewInternalError("Failed to delete invoice", err)
-		c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+	if err := h.invoiceService.DeleteInvoice(userID, uint(id)); err != nil {
+		if appErr := errors.GetAppError(err); appErr != nil {
+			c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		} else {
+			appErr := errors.NewInternalError("Failed to delete invoice", err)
+			c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		}
 		return
 	}
 
@@ -190,7 +194,7 @@ func (h *InvoiceHandler) Delete(c *gin.Context) {
 func (h *InvoiceHandler) UpdateSettings(c *gin.Context) {
 	requestID := c.GetString("request_id")
 
-	userID, exists := middleware.GetUserID(c)
+	userID, exists := middleware.GetDataUserID(c)
 	if !exists {
 		appErr := errors.NewUnauthorizedError("User not authenticated")
 		c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
@@ -212,3 +216,81 @@ func (h *InvoiceHandler) UpdateSettings(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{"message": "Invoice settings updated"})
 }
+
+// GetSyncStatus returns the scheduled sync state
+// GET /api/invoice/sync/status
+func (h *InvoiceHandler) GetSyncStatus(c *gin.Context) {
+	requestID := c.GetString("request_id")
+
+	userID, exists := middleware.GetDataUserID(c)
+	if !exists {
+		appErr := errors.NewUnauthorizedError("User not authenticated")
+		c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		return
+	}
+
+	state, err := h.invoiceService.GetSyncState(userID)
+	if err != nil {
+		appErr := errors.NewInternalError("Failed to get invoice sync status", err)
+		c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		return
+	}
+
+	c.JSON(http.StatusOK, state)
+}
+
+// UpdateSyncSettings enables or disables the scheduled sync
+// PUT /api/invoice/sync/settings
+func (h *InvoiceHandler) UpdateSyncSettings(c *gin.Context) {
+	requestID := c.GetString("request_id")
+
+	userID, exists := middleware.GetDataUserID(c)
+	if !exists {
+		appErr := errors.NewUnauthorizedError("User not authenticated")
+		c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		return
+	}
+
+	var input models.InvoiceSyncSettingsInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		appErr := errors.NewValidationError("Invalid request: enabled is required")
+		c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		return
+	}
+
+	if err := h.invoiceService.SetAutoSync(userID, *input.Enabled); err != nil {
+		if appErr := errors.GetAppError(err); appErr != nil {
+			c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		} else {
+			appErr := errors.NewInternalError("Failed to update invoice sync settings", err)
+			c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Invoice sync settings updated"})
+}
+
+// GetSyncHistory returns recent sync history
+// GET /api/invoice/sync/history
+func (h *InvoiceHandler) GetSyncHistory(c *gin.Context) {
+	requestID := c.GetString("request_id")
+
+	userID, exists := middleware.GetDataUserID(c)
+	if !exists {
+		appErr := errors.NewUnauthorizedError("User not authenticated")
+		c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		return
+	}
+
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+
+	history, err := h.invoiceService.GetSyncHistory(userID, limit)
+	if err != nil {
+		appErr := errors.NewInternalError("Failed to get invoice sync history", err)
+		c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"history": history})
+}
@@ -0,0 +1,255 @@
+package handlers
+
+import (
+	"billing-note/internal/middleware"
+	"billing-note/internal/models"
+	"billing-note/internal/services"
+	"billing-note/pkg/errors"
+	"billing-note/pkg/logger"
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// LotteryHandler handles uniform invoice lottery endpoints
+type LotteryHandler struct {
+	lotteryService *services.LotteryService
+}
+
+// NewLotteryHandler creates a new lottery handler
+func NewLotteryHandler(lotteryService *services.LotteryService) *LotteryHandler {
+	return &LotteryHandler{lotteryService: lotteryService}
+}
+
+// ImportWinningNumbers imports winning numbers from an uploaded JSON file or a JSON body.
+// Both a single period object and an array of periods are accepted.
+// POST /api/invoice/winning-numbers/import
+func (h *LotteryHandler) ImportWinningNumbers(c *gin.Context) {
+	log := logger.APILog("LotteryHandler", "ImportWinningNumbers")
+	requestID := c.GetString("request_id")
+
+	var raw []byte
+	if file, err := c.FormFile("file"); err == nil {
+		f, err := file.Open()
+		if err != nil {
+			appErr := errors.NewValidationError("Failed to read uploaded file")
+			c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+			return
+		}
+		defer f.Close()
+		raw, err = io.ReadAll(f)
+		if err != nil {
+			appErr := errors.NewValidationError("Failed to read uploaded file")
+			c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+			return
+		}
+	} else {
+		raw, err = io.ReadAll(c.Request.Body)
+		if err != nil {
+			appErr := errors.NewValidationError("Failed to read request body")
+			c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+			return
+		}
+	}
+
+	inputs, err := decodeWinningNumbers(raw)
+	if err != nil {
+		appErr := errors.NewValidationError("Invalid winning numbers: expected a JSON object or array")
+		c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		return
+	}
+
+	saved, err := h.lotteryService.ImportWinningNumbers(inputs, "import")
+	if err != nil {
+		log.WithFields(logger.Fields{
+			"request_id": requestID,
+			"error":      err.Error(),
+		}).Error("Failed to import winning numbers")
+		if appErr := errors.GetAppError(err); appErr != nil {
+			c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		} else {
+			appErr := errors.NewInternalError("Failed to import winning numbers", err)
+			c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Winning numbers imported",
+		"periods": saved,
+	})
+}
+
+// FetchWinningNumbers fetches a period's winning numbers from the MOF API
+// POST /api/invoice/winning-numbers/fetch
+func (h *LotteryHandler) FetchWinningNumbers(c *gin.Context) {
+	requestID := c.GetString("request_id")
+
+	var req struct {
+		Period string `json:"period" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		appErr := errors.NewValidationError("Invalid request: period is required")
+		c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		return
+	}
+
+	numbers, err := h.lotteryService.FetchWinningNumbers(req.Period)
+	if err != nil {
+		if appErr := errors.GetAppError(err); appErr != nil {
+			c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		} else {
+			appErr := errors.NewInternalError("Failed to fetch winning numbers", err)
+			c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, numbers)
+}
+
+// ListWinningNumbers returns all stored winning number periods
+// GET /api/invoice/winning-numbers
+func (h *LotteryHandler) ListWinningNumbers(c *gin.Context) {
+	requestID := c.GetString("request_id")
+
+	numbers, err := h.lotteryService.ListWinningNumbers()
+	if err != nil {
+		appErr := errors.NewInternalError("Failed to list winning numbers", err)
+		c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"periods": numbers})
+}
+
+// GetWinningNumbers returns the winning numbers for a period
+// GET /api/invoice/winning-numbers/:period
+func (h *LotteryHandler) GetWinningNumbers(c *gin.Context) {
+	requestID := c.GetString("request_id")
+
+	numbers, err := h.lotteryService.GetWinningNumbers(c.Param("period"))
+	if err != nil {
+		if appErr := errors.GetAppError(err); appErr != nil {
+			c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		} else {
+			appErr := errors.NewInternalError("Failed to get winning numbers", err)
+			c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, numbers)
+}
+
+// Check matches the user's invoices against a period's winning numbers
+// POST /api/invoice/lottery/check
+func (h *LotteryHandler) Check(c *gin.Context) {
+	requestID := c.GetString("request_id")
+
+	userID, exists := middleware.GetDataUserID(c)
+	if !exists {
+		appErr := errors.NewUnauthorizedError("User not authenticated")
+		c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		return
+	}
+
+	var req models.LotteryCheckRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		appErr := errors.NewValidationError("Invalid request: period is required")
+		c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		return
+	}
+
+	result, err := h.lotteryService.CheckPeriod(userID, req.Period, req.RecordIncome)
+	if err != nil {
+		if appErr := errors.GetAppError(err); appErr != nil {
+			c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		} else {
+			appErr := errors.NewInternalError("Failed to check invoice lottery", err)
+			c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// ListWinnings returns the user's winning invoices
+// GET /api/invoice/winnings?period=11410
+func (h *LotteryHandler) ListWinnings(c *gin.Context) {
+	requestID := c.GetString("request_id")
+
+	userID, exists := middleware.GetDataUserID(c)
+	if !exists {
+		appErr := errors.NewUnauthorizedError("User not authenticated")
+		c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		return
+	}
+
+	prizes, total, err := h.lotteryService.ListWinnings(userID, c.Query("period"))
+	if err != nil {
+		appErr := errors.NewInternalError("Failed to list winnings", err)
+		c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"winnings":     prizes,
+		"total_amount": total,
+	})
+}
+
+// RecordIncome records a prize as an income transaction
+// POST /api/invoice/winnings/:id/record
+func (h *LotteryHandler) RecordIncome(c *gin.Context) {
+	requestID := c.GetString("request_id")
+
+	userID, exists := middleware.GetDataUserID(c)
+	if !exists {
+		appErr := errors.NewUnauthorizedError("User not authenticated")
+		c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		appErr := errors.NewInvalidInputError("id", "must be a valid number")
+		c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		return
+	}
+
+	prize, err := h.lotteryService.RecordPrizeIncome(userID, uint(id))
+	if err != nil {
+		if appErr := errors.GetAppError(err); appErr != nil {
+			c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		} else {
+			appErr := errors.NewInternalError("Failed to record prize income", err)
+			c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, prize)
+}
+
+// decodeWinningNumbers accepts either a single period object or an array of them
+func decodeWinningNumbers(raw []byte) ([]models.WinningNumbersInput, error) {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) > 0 && raw[0] == '[' {
+		var inputs []models.WinningNumbersInput
+		if err := json.Unmarshal(raw, &inputs); err != nil {
+			return nil, err
+		}
+		return inputs, nil
+	}
+	var input models.WinningNumbersInput
+	if err := json.Unmarshal(raw, &input); err != nil {
+		return nil, err
+	}
+	return []models.WinningNumbersInput{input}, nil
+}
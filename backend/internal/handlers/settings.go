@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"billing-note/internal/middleware"
+	"billing-note/internal/repository"
+	"billing-note/pkg/errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SettingsHandler handles per-user preference endpoints
+type SettingsHandler struct {
+	userRepo repository.UserRepository
+}
+
+// NewSettingsHandler creates a new settings handler
+func NewSettingsHandler(userRepo repository.UserRepository) *SettingsHandler {
+	return &SettingsHandler{userRepo: userRepo}
+}
+
+// GetTimezone returns the user's time zone
+// GET /api/settings/timezone
+func (h *SettingsHandler) GetTimezone(c *gin.Context) {
+	requestID := c.GetString("request_id")
+
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		appErr := errors.NewUnauthorizedError("User not authenticated")
+		c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		return
+	}
+
+	user, err := h.userRepo.FindByID(userID)
+	if err != nil {
+		appErr := errors.NewNotFoundError("User", userID)
+		c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"timezone": user.Timezone})
+}
+
+// UpdateTimezone sets the user's IANA time zone, used for budget periods
+// PUT /api/settings/timezone
+func (h *SettingsHandler) UpdateTimezone(c *gin.Context) {
+	requestID := c.GetString("request_id")
+
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		appErr := errors.NewUnauthorizedError("User not authenticated")
+		c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		return
+	}
+
+	var req struct {
+		Timezone string `json:"timezone" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		appErr := errors.NewValidationError("Invalid request: timezone is required")
+		c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		return
+	}
+	if _, err := time.LoadLocation(req.Timezone); err != nil {
+		appErr := errors.NewInvalidInputError("timezone", "must be an IANA time zone, e.g. Asia/Taipei")
+		c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		return
+	}
+
+	user, err := h.userRepo.FindByID(userID)
+	if err != nil {
+		appErr := errors.NewNotFoundError("User", userID)
+		c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		return
+	}
+	user.Timezone = req.Timezone
+	if err := h.userRepo.Update(user); err != nil {
+		appErr := errors.NewDBError("update timezone", err)
+		c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Timezone updated", "timezone": user.Timezone})
+}
@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"billing-note/internal/middleware"
+	"billing-note/internal/models"
 	"billing-note/internal/services"
 	"billing-note/pkg/errors"
 	"net/http"
@@ -35,7 +36,16 @@ func (h *SharingHandler) GetMyCode(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"code": code.Code})
+	c.JSON(http.StatusOK, pairingCodeResponse(code))
+}
+
+func pairingCodeResponse(code *models.UserPairingCode) gin.H {
+	return gin.H{
+		"code":       code.Code,
+		"expires_at": code.ExpiresAt,
+		"max_uses":   code.MaxUses,
+		"use_count":  code.UseCount,
+	}
 }
 
 func (h *SharingHandler) RegenerateCode(c *gin.Context) {
@@ -48,14 +58,24 @@ func (h *SharingHandler) RegenerateCode(c *gin.Context) {
 		return
 	}
 
-	code, err := h.sharingService.RegenerateCode(userID)
+	// The body is optional; without one the defaults apply
+	var input models.PairingCodeInput
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&input); err != nil {
+			appErr := errors.NewValidationError("Invalid request: expires_in_hours must be 1-720 and max_uses 1-20")
+			c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+			return
+		}
+	}
+
+	code, err := h.sharingService.RegenerateCode(userID, input)
 	if err != nil {
 		appErr := errors.NewInternalError("Failed to regenerate pairing code", err)
 		c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"code": code.Code})
+	c.JSON(http.StatusOK, pairingCodeResponse(code))
 }
 
 type pairRequest struct {
@@ -79,13 +99,93 @@ func (h *SharingHandler) Pair(c *gin.Context) {
 		return
 	}
 
-	if err := h.sharingService.Pair(userID, req.Code); err != nil {
+	request, err := h.sharingService.Pair(userID, req.Code)
+	if err == services.ErrPairingRateLimited {
+		appErr := errors.NewRateLimitError(err.Error())
+		c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		return
+	}
+	if err != nil {
 		appErr := errors.NewValidationError(err.Error())
 		c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "paired successfully"})
+	c.JSON(http.StatusOK, gin.H{
+		"message": "pairing request sent, waiting for the owner's approval",
+		"request": request,
+	})
+}
+
+// ListRequests returns pending pairing requests sent to and by the user
+// GET /api/shared/requests
+func (h *SharingHandler) ListRequests(c *gin.Context) {
+	requestID := c.GetString("request_id")
+
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		appErr := errors.NewUnauthorizedError("User not authenticated")
+		c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		return
+	}
+
+	incoming, err := h.sharingService.ListIncomingRequests(userID)
+	if err != nil {
+		appErr := errors.NewInternalError("Failed to list pairing requests", err)
+		c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		return
+	}
+
+	outgoing, err := h.sharingService.ListOutgoingRequests(userID)
+	if err != nil {
+		appErr := errors.NewInternalError("Failed to list pairing requests", err)
+		c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"incoming": incoming,
+		"outgoing": outgoing,
+	})
+}
+
+// ApproveRequest grants the requester access to the user's data
+// POST /api/shared/requests/:id/approve
+func (h *SharingHandler) ApproveRequest(c *gin.Context) {
+	h.decideRequest(c, h.sharingService.ApproveRequest)
+}
+
+// RejectRequest declines a pairing request
+// POST /api/shared/requests/:id/reject
+func (h *SharingHandler) RejectRequest(c *gin.Context) {
+	h.decideRequest(c, h.sharingService.RejectRequest)
+}
+
+func (h *SharingHandler) decideRequest(c *gin.Context, decide func(ownerID, requestID uint) (*models.PairingRequest, error)) {
+	requestID := c.GetString("request_id")
+
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		appErr := errors.NewUnauthorizedError("User not authenticated")
+		c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		appErr := errors.NewValidationError("Invalid request ID")
+		c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		return
+	}
+
+	request, err := decide(userID, uint(id))
+	if err != nil {
+		appErr := errors.NewValidationError(err.Error())
+		c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		return
+	}
+
+	c.JSON(http.StatusOK, request)
 }
 
 func (h *SharingHandler) ListConnections(c *gin.Context) {
@@ -118,6 +218,42 @@ func (h *SharingHandler) ListConnections(c *gin.Context) {
 	})
 }
 
+// UpdateAccess sets the role and scope of a viewer's access
+// PUT /api/shared/connections/:uid
+func (h *SharingHandler) UpdateAccess(c *gin.Context) {
+	requestID := c.GetString("request_id")
+
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		appErr := errors.NewUnauthorizedError("User not authenticated")
+		c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		return
+	}
+
+	uid, err := strconv.ParseUint(c.Param("uid"), 10, 32)
+	if err != nil {
+		appErr := errors.NewValidationError("Invalid user ID")
+		c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		return
+	}
+
+	var input models.ShareAccessInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		appErr := errors.NewValidationError("Invalid request: role must be viewer, contributor or editor")
+		c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		return
+	}
+
+	access, err := h.sharingService.UpdateAccess(userID, uint(uid), input)
+	if err != nil {
+		appErr := errors.NewValidationError(err.Error())
+		c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		return
+	}
+
+	c.JSON(http.StatusOK, access)
+}
+
 func (h *SharingHandler) RevokeAccess(c *gin.Context) {
 	requestID := c.GetString("request_id")
 
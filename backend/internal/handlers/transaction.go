@@ -16,17 +16,29 @@ import (
 
 type TransactionHandler struct {
 	transactionService services.TransactionService
+	goalService        *services.GoalService
 }
 
 func NewTransactionHandler(transactionService services.TransactionService) *TransactionHandler {
 	return &TransactionHandler{transactionService: transactionService}
 }
 
+// SetGoalService adds savings goal status to the monthly stats response
+func (h *TransactionHandler) SetGoalService(svc *services.GoalService) {
+	h.goalService = svc
+}
+
+// service returns the transaction service for the selected ledger, limited
+// to the scope of the share being viewed, if any
+func (h *TransactionHandler) service(c *gin.Context) services.TransactionService {
+	return h.transactionService.WithScope(middleware.GetShareScope(c)).ForLedger(middleware.GetLedgerID(c))
+}
+
 func (h *TransactionHandler) Create(c *gin.Context) {
 	log := logger.APILog("TransactionHandler", "Create")
 	requestID := c.GetString("request_id")
 
-	userID, exists := middleware.GetUserID(c)
+	userID, exists := middleware.GetDataUserID(c)
 	if !exists {
 		log.WithField("request_id", requestID).Warn("Unauthorized: user not authenticated")
 		appErr := errors.NewUnauthorizedError("User not authenticated")
@@ -53,7 +65,7 @@ func (h *TransactionHandler) Create(c *gin.Context) {
 		"type":       req.Type,
 	}).Info("Creating new transaction")
 
-	transaction, err := h.transactionService.CreateTransaction(userID, &req)
+	transaction, err := h.service(c).CreateTransaction(userID, &req)
 	if err != nil {
 		log.WithFields(logger.Fields{
 			"request_id": requestID,
@@ -82,7 +94,7 @@ func (h *TransactionHandler) Get(c *gin.Context) {
 	log := logger.APILog("TransactionHandler", "Get")
 	requestID := c.GetString("request_id")
 
-	userID, exists := middleware.GetUserID(c)
+	userID, exists := middleware.GetDataUserID(c)
 	if !exists {
 		log.WithField("request_id", requestID).Warn("Unauthorized: user not authenticated")
 		appErr := errors.NewUnauthorizedError("User not authenticated")
@@ -109,7 +121,7 @@ func (h *TransactionHandler) Get(c *gin.Context) {
 		"transaction_id": id,
 	}).Debug("Fetching transaction")
 
-	transaction, err := h.transactionService.GetTransaction(uint(id), userID)
+	transaction, err := h.service(c).GetTransaction(uint(id), userID)
 	if err != nil {
 		log.WithFields(logger.Fields{
 			"request_id":     requestID,
@@ -140,7 +152,7 @@ func (h *TransactionHandler) List(c *gin.Context) {
 	log := logger.APILog("TransactionHandler", "List")
 	requestID := c.GetString("request_id")
 
-	userID, exists := middleware.GetUserID(c)
+	userID, exists := middleware.GetDataUserID(c)
 	if !exists {
 		log.WithField("request_id", requestID).Warn("Unauthorized: user not authenticated")
 		appErr := errors.NewUnauthorizedError("User not authenticated")
@@ -179,6 +191,14 @@ func (h *TransactionHandler) List(c *gin.Context) {
 		}
 	}
 
+	if accountIDStr := c.Query("account_id"); accountIDStr != "" {
+		accountID, err := strconv.ParseUint(accountIDStr, 10, 32)
+		if err == nil {
+			accID := uint(accountID)
+			filter.AccountID = &accID
+		}
+	}
+
 	if q := c.Query("q"); q != "" {
 		filter.Query = q
 	}
@@ -213,7 +233,7 @@ func (h *TransactionHandler) List(c *gin.Context) {
 		"type":       filter.Type,
 	}).Debug("Listing transactions with filter")
 
-	transactions, total, err := h.transactionService.ListTransactions(userID, filter)
+	transactions, total, err := h.service(c).ListTransactions(userID, filter)
 	if err != nil {
 		log.WithFields(logger.Fields{
 			"request_id": requestID,
@@ -249,7 +269,7 @@ func (h *TransactionHandler) Update(c *gin.Context) {
 	log := logger.APILog("TransactionHandler", "Update")
 	requestID := c.GetString("request_id")
 
-	userID, exists := middleware.GetUserID(c)
+	userID, exists := middleware.GetDataUserID(c)
 	if !exists {
 		log.WithField("request_id", requestID).Warn("Unauthorized: user not authenticated")
 		appErr := errors.NewUnauthorizedError("User not authenticated")
@@ -289,7 +309,7 @@ func (h *TransactionHandler) Update(c *gin.Context) {
 		"transaction_id": id,
 	}).Info("Updating transaction")
 
-	transaction, err := h.transactionService.UpdateTransaction(uint(id), userID, &req)
+	transaction, err := h.service(c).UpdateTransaction(uint(id), userID, &req)
 	if err != nil {
 		log.WithFields(logger.Fields{
 			"request_id":     requestID,
@@ -319,7 +339,7 @@ func (h *TransactionHandler) Delete(c *gin.Context) {
 	log := logger.APILog("TransactionHandler", "Delete")
 	requestID := c.GetString("request_id")
 
-	userID, exists := middleware.GetUserID(c)
+	userID, exists := middleware.GetDataUserID(c)
 	if !exists {
 		log.WithField("request_id", requestID).Warn("Unauthorized: user not authenticated")
 		appErr := errors.NewUnauthorizedError("User not authenticated")
@@ -346,7 +366,7 @@ func (h *TransactionHandler) Delete(c *gin.Context) {
 		"transaction_id": id,
 	}).Info("Deleting transaction")
 
-	if err := h.transactionService.DeleteTransaction(uint(id), userID); err != nil {
+	if err := h.service(c).DeleteTransaction(uint(id), userID); err != nil {
 		log.WithFields(logger.Fields{
 			"request_id":     requestID,
 			"user_id":        userID,
@@ -375,7 +395,7 @@ func (h *TransactionHandler) GetMonthlyStats(c *gin.Context) {
 	log := logger.APILog("TransactionHandler", "GetMonthlyStats")
 	requestID := c.GetString("request_id")
 
-	userID, exists := middleware.GetUserID(c)
+	userID, exists := middleware.GetDataUserID(c)
 	if !exists {
 		log.WithField("request_id", requestID).Warn("Unauthorized: user not authenticated")
 		appErr := errors.NewUnauthorizedError("User not authenticated")
@@ -396,7 +416,7 @@ func (h *TransactionHandler) GetMonthlyStats(c *gin.Context) {
 		"month":      month,
 	}).Debug("Fetching monthly stats")
 
-	stats, err := h.transactionService.GetMonthlyStats(userID, year, month)
+	stats, err := h.service(c).GetMonthlyStats(userID, year, month)
 	if err != nil {
 		log.WithFields(logger.Fields{
 			"request_id": requestID,
@@ -422,14 +442,35 @@ func (h *TransactionHandler) GetMonthlyStats(c *gin.Context) {
 		"month":      month,
 	}).Debug("Monthly stats retrieved successfully")
 
-	c.JSON(http.StatusOK, stats)
+	// Goals are personal and not part of a scoped share
+	if h.goalService == nil || middleware.GetShareScope(c) != nil || middleware.GetLedgerID(c) != nil {
+		c.JSON(http.StatusOK, stats)
+		return
+	}
+
+	goals, err := h.goalService.MonthlyStatus(userID, year, month)
+	if err != nil {
+		if appErr := errors.GetAppError(err); appErr != nil {
+			c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		} else {
+			appErr := errors.NewInternalError("Failed to retrieve goal status", err)
+			c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
+		}
+		return
+	}
+
+	response := gin.H{"goals": goals}
+	for k, v := range stats {
+		response[k] = v
+	}
+	c.JSON(http.StatusOK, response)
 }
 
 func (h *TransactionHandler) GetCategoryStats(c *gin.Context) {
 	log := logger.APILog("TransactionHandler", "GetCategoryStats")
 	requestID := c.GetString("request_id")
 
-	userID, exists := middleware.GetUserID(c)
+	userID, exists := middleware.GetDataUserID(c)
 	if !exists {
 		log.WithField("request_id", requestID).Warn("Unauthorized: user not authenticated")
 		appErr := errors.NewUnauthorizedError("User not authenticated")
@@ -473,7 +514,7 @@ func (h *TransactionHandler) GetCategoryStats(c *gin.Context) {
 		"type":       transactionType,
 	}).Debug("Fetching category stats")
 
-	stats, err := h.transactionService.GetCategoryStats(userID, startDate, endDate, transactionType)
+	stats, err := h.service(c).GetCategoryStats(userID, startDate, endDate, transactionType)
 	if err != nil {
 		log.WithFields(logger.Fields{
 			"request_id": requestID,
@@ -501,7 +542,7 @@ func (h *TransactionHandler) GetCategoryStats(c *gin.Context) {
 func (h *TransactionHandler) GetTrendStats(c *gin.Context) {
 	requestID := c.GetString("request_id")
 
-	userID, exists := middleware.GetUserID(c)
+	userID, exists := middleware.GetDataUserID(c)
 	if !exists {
 		appErr := errors.NewUnauthorizedError("User not authenticated")
 		c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
@@ -511,7 +552,7 @@ func (h *TransactionHandler) GetTrendStats(c *gin.Context) {
 	monthsStr := c.DefaultQuery("months", "12")
 	months, _ := strconv.Atoi(monthsStr)
 
-	trend, err := h.transactionService.GetTrendStats(userID, months)
+	trend, err := h.service(c).GetTrendStats(userID, months)
 	if err != nil {
 		appErr := errors.NewInternalError("Failed to retrieve trend stats", err)
 		c.JSON(appErr.HTTPStatus, appErr.ToResponse(requestID))
@@ -83,6 +83,14 @@ func (m *MockTransactionService) GetTrendStats(userID uint, months int) ([]repos
 	return args.Get(0).([]repository.TrendDataPoint), args.Error(1)
 }
 
+func (m *MockTransactionService) WithScope(scope *models.ShareScope) services.TransactionService {
+	return m
+}
+
+func (m *MockTransactionService) ForLedger(ledgerID *uint) services.TransactionService {
+	return m
+}
+
 func setupTransactionTest() (*gin.Engine, *MockTransactionService) {
 	gin.SetMode(gin.TestMode)
 	router := gin.New()
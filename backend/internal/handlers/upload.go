@@ -28,7 +28,7 @@ func (h *UploadHandler) UploadAndParse(c *gin.Context) {
 	log := logger.APILog("UploadHandler", "UploadAndParse")
 	requestID := c.GetString("request_id")
 
-	userID, exists := middleware.GetUserID(c)
+	userID, exists := middleware.GetDataUserID(c)
 	if !exists {
 		log.WithField("request_id", requestID).Warn("Unauthorized: user not authenticated")
 		appErr := errors.NewUnauthorizedError("User not authenticated")
@@ -161,6 +161,8 @@ func (h *UploadHandler) UploadAndParse(c *gin.Context) {
 // ImportRequest represents request for importing transactions
 type ImportRequest struct {
 	Transactions []services.ParsedTransaction `json:"transactions" binding:"required"`
+	// AccountID assigns the statement's account to lines without one
+	AccountID *uint `json:"account_id"`
 }
 
 // Import handles importing parsed transactions
@@ -169,7 +171,7 @@ func (h *UploadHandler) Import(c *gin.Context) {
 	log := logger.APILog("UploadHandler", "Import")
 	requestID := c.GetString("request_id")
 
-	userID, exists := middleware.GetUserID(c)
+	userID, exists := middleware.GetDataUserID(c)
 	if !exists {
 		log.WithField("request_id", requestID).Warn("Unauthorized: user not authenticated")
 		appErr := errors.NewUnauthorizedError("User not authenticated")
@@ -196,6 +198,14 @@ func (h *UploadHandler) Import(c *gin.Context) {
 		"transaction_count": len(req.Transactions),
 	}).Info("Importing transactions from PDF")
 
+	if req.AccountID != nil {
+		for i := range req.Transactions {
+			if req.Transactions[i].AccountID == nil {
+				req.Transactions[i].AccountID = req.AccountID
+			}
+		}
+	}
+
 	imported, err := h.uploadService.ImportTransactions(userID, req.Transactions)
 	if err != nil {
 		log.WithFields(logger.Fields{
@@ -0,0 +1,27 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequireAdmin guards routes that change data shared by every user, such as
+// the invoice winning numbers. Only the configured admin users pass. Must run
+// after AuthMiddleware.
+func RequireAdmin(adminUserIDs []uint) gin.HandlerFunc {
+	admins := make(map[uint]bool, len(adminUserIDs))
+	for _, id := range adminUserIDs {
+		admins[id] = true
+	}
+	return func(c *gin.Context) {
+		userID, exists := GetUserID(c)
+		if !exists || !admins[userID] {
+			c.JSON(http.StatusForbidden, gin.H{"error": "only administrators can perform this operation"})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
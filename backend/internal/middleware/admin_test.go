@@ -0,0 +1,50 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func setupAdminRouter(userID uint, adminUserIDs []uint) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("user_id", userID)
+		c.Next()
+	})
+	r.POST("/admin", RequireAdmin(adminUserIDs), func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"ok": true})
+	})
+	return r
+}
+
+func TestRequireAdmin_AllowsAdmin(t *testing.T) {
+	r := setupAdminRouter(7, []uint{3, 7})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/admin", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestRequireAdmin_RejectsOtherUsers(t *testing.T) {
+	r := setupAdminRouter(1, []uint{3, 7})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/admin", nil))
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+func TestRequireAdmin_RejectsEveryoneWithoutAdmins(t *testing.T) {
+	r := setupAdminRouter(1, nil)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/admin", nil))
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
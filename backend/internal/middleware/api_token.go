@@ -0,0 +1,86 @@
+package middleware
+
+import (
+	"billing-note/internal/models"
+	"billing-note/internal/repository"
+	"billing-note/pkg/utils"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// apiTokenRoutes maps the routes personal API tokens may call to the scope
+// needed for reads and for writes. Any other route is closed to API tokens.
+var apiTokenRoutes = map[string]struct{ read, write string }{
+	"/api/transactions":          {models.APIScopeTransactionsRead, models.APIScopeTransactionsWrite},
+	"/api/transactions/:id":      {models.APIScopeTransactionsRead, models.APIScopeTransactionsWrite},
+	"/api/categories":            {models.APIScopeTransactionsRead, ""},
+	"/api/categories/type/:type": {models.APIScopeTransactionsRead, ""},
+	"/api/stats/monthly":         {models.APIScopeStats, ""},
+	"/api/stats/category":        {models.APIScopeStats, ""},
+	"/api/stats/trend":           {models.APIScopeStats, ""},
+	"/api/export/csv":            {models.APIScopeExport, ""},
+}
+
+// apiTokenScope returns the scope an API token needs for the request, or ""
+// if API tokens cannot make it
+func apiTokenScope(method, route string) string {
+	scopes, ok := apiTokenRoutes[route]
+	if !ok {
+		return ""
+	}
+	if method == http.MethodGet || method == http.MethodHead {
+		return scopes.read
+	}
+	return scopes.write
+}
+
+// authenticateAPIToken authenticates a request carrying a personal API token
+// and checks the token's scopes allow the route
+func authenticateAPIToken(c *gin.Context, apiTokenRepo repository.APITokenRepository, raw string) {
+	if apiTokenRepo == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid or expired token"})
+		c.Abort()
+		return
+	}
+
+	token, err := apiTokenRepo.FindByHash(utils.HashToken(raw))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to check token"})
+		c.Abort()
+		return
+	}
+	now := time.Now()
+	if token == nil || !token.Active(now) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid or expired token"})
+		c.Abort()
+		return
+	}
+
+	scope := apiTokenScope(c.Request.Method, c.FullPath())
+	if scope == "" || !token.HasScope(scope) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "this API token is not allowed to access this endpoint"})
+		c.Abort()
+		return
+	}
+
+	if token.LastUsedAt == nil || now.Sub(*token.LastUsedAt) > sessionTouchInterval {
+		// Best effort; a failed write should not fail the request
+		_ = apiTokenRepo.Touch(token.ID, now, c.ClientIP())
+	}
+
+	c.Set("user_id", token.UserID)
+	c.Set("api_token", token)
+	c.Next()
+}
+
+// GetAPIToken returns the personal API token that authenticated the request,
+// or nil for requests signed in with a JWT
+func GetAPIToken(c *gin.Context) *models.APIToken {
+	token, exists := c.Get("api_token")
+	if !exists {
+		return nil
+	}
+	return token.(*models.APIToken)
+}
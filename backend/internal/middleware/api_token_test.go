@@ -0,0 +1,150 @@
+package middleware
+
+import (
+	"billing-note/internal/models"
+	"billing-note/pkg/utils"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type mockAPITokenRepo struct {
+	mock.Mock
+}
+
+func (m *mockAPITokenRepo) Create(token *models.APIToken) error {
+	args := m.Called(token)
+	return args.Error(0)
+}
+
+func (m *mockAPITokenRepo) GetByID(id uint) (*models.APIToken, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.APIToken), args.Error(1)
+}
+
+func (m *mockAPITokenRepo) FindByHash(hash string) (*models.APIToken, error) {
+	args := m.Called(hash)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.APIToken), args.Error(1)
+}
+
+func (m *mockAPITokenRepo) ListByUser(userID uint) ([]models.APIToken, error) {
+	args := m.Called(userID)
+	return args.Get(0).([]models.APIToken), args.Error(1)
+}
+
+func (m *mockAPITokenRepo) Touch(id uint, usedAt time.Time, ip string) error {
+	args := m.Called(id, usedAt, ip)
+	return args.Error(0)
+}
+
+func (m *mockAPITokenRepo) Revoke(id uint, at time.Time) error {
+	args := m.Called(id, at)
+	return args.Error(0)
+}
+
+func (m *mockAPITokenRepo) RevokeAllByUser(userID uint, at time.Time) (int64, error) {
+	args := m.Called(userID, at)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+const testAPIToken = models.APITokenPrefix + "secret"
+
+// serveWithAPIToken sends method/path with testAPIToken to a router that
+// mounts the auth middleware on a few API routes
+func serveWithAPIToken(repo *mockAPITokenRepo, method, path string) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	handler := func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"user_id": c.GetUint("user_id")})
+	}
+	var auth gin.HandlerFunc
+	if repo != nil {
+		auth = AuthMiddleware("test-secret-key", nil, repo)
+	} else {
+		auth = AuthMiddleware("test-secret-key", nil, nil)
+	}
+	router.GET("/api/transactions", auth, handler)
+	router.POST("/api/transactions", auth, handler)
+	router.GET("/api/sessions", auth, handler)
+
+	req, _ := http.NewRequest(method, path, nil)
+	req.Header.Set("Authorization", "Bearer "+testAPIToken)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestAuthMiddleware_APIToken(t *testing.T) {
+	repo := new(mockAPITokenRepo)
+	repo.On("FindByHash", utils.HashToken(testAPIToken)).Return(&models.APIToken{
+		ID:     3,
+		UserID: 9,
+		Scopes: pq.StringArray{models.APIScopeTransactionsRead},
+	}, nil)
+	repo.On("Touch", uint(3), mock.Anything, mock.Anything).Return(nil)
+
+	w := serveWithAPIToken(repo, http.MethodGet, "/api/transactions")
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"user_id":9`)
+	repo.AssertCalled(t, "Touch", uint(3), mock.Anything, mock.Anything)
+}
+
+func TestAuthMiddleware_APITokenScopes(t *testing.T) {
+	recentlyUsed := time.Now()
+	repo := new(mockAPITokenRepo)
+	repo.On("FindByHash", utils.HashToken(testAPIToken)).Return(&models.APIToken{
+		ID:         3,
+		UserID:     9,
+		Scopes:     pq.StringArray{models.APIScopeTransactionsRead},
+		LastUsedAt: &recentlyUsed,
+	}, nil)
+
+	// Writing needs transactions:write
+	w := serveWithAPIToken(repo, http.MethodPost, "/api/transactions")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	// Routes outside the scope map are closed to API tokens
+	w = serveWithAPIToken(repo, http.MethodGet, "/api/sessions")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	repo.AssertNotCalled(t, "Touch", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestAuthMiddleware_RejectsInactiveAPITokens(t *testing.T) {
+	past := time.Now().Add(-time.Hour)
+	for name, token := range map[string]*models.APIToken{
+		"unknown": nil,
+		"revoked": {ID: 3, UserID: 9, Scopes: pq.StringArray{models.APIScopeTransactionsRead}, RevokedAt: &past},
+		"expired": {ID: 3, UserID: 9, Scopes: pq.StringArray{models.APIScopeTransactionsRead}, ExpiresAt: &past},
+	} {
+		t.Run(name, func(t *testing.T) {
+			repo := new(mockAPITokenRepo)
+			if token == nil {
+				repo.On("FindByHash", mock.Anything).Return(nil, nil)
+			} else {
+				repo.On("FindByHash", mock.Anything).Return(token, nil)
+			}
+
+			w := serveWithAPIToken(repo, http.MethodGet, "/api/transactions")
+			assert.Equal(t, http.StatusUnauthorized, w.Code)
+		})
+	}
+}
+
+func TestAuthMiddleware_APITokenWithoutRepository(t *testing.T) {
+	w := serveWithAPIToken(nil, http.MethodGet, "/api/transactions")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
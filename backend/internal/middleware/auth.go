@@ -1,14 +1,25 @@
 package middleware
 
 import (
+	"billing-note/internal/models"
+	"billing-note/internal/repository"
 	"billing-note/pkg/utils"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
-func AuthMiddleware(jwtSecret string) gin.HandlerFunc {
+// sessionTouchInterval limits how often a session's last-seen time is written
+const sessionTouchInterval = time.Minute
+
+// AuthMiddleware validates the bearer access token. With a session repository,
+// the token must belong to a session that has not been revoked or expired; the
+// session ID is stored as session_id. Without one, only the token is checked.
+// Personal API tokens are accepted when an API token repository is given, on
+// the routes their scopes allow.
+func AuthMiddleware(jwtSecret string, sessionRepo repository.SessionRepository, apiTokenRepo repository.APITokenRepository) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
@@ -26,6 +37,11 @@ func AuthMiddleware(jwtSecret string) gin.HandlerFunc {
 		}
 
 		tokenString := parts[1]
+		if strings.HasPrefix(tokenString, models.APITokenPrefix) {
+			authenticateAPIToken(c, apiTokenRepo, tokenString)
+			return
+		}
+
 		claims, err := utils.ValidateToken(tokenString, jwtSecret)
 		if err != nil {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid or expired token"})
@@ -33,6 +49,27 @@ func AuthMiddleware(jwtSecret string) gin.HandlerFunc {
 			return
 		}
 
+		if sessionRepo != nil {
+			session, err := sessionRepo.GetByID(claims.SessionID)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to check session"})
+				c.Abort()
+				return
+			}
+			now := time.Now()
+			if session == nil || session.UserID != claims.UserID || !session.Active(now) {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "session has been revoked or expired"})
+				c.Abort()
+				return
+			}
+			if now.Sub(session.LastSeenAt) > sessionTouchInterval {
+				// Best effort; a failed write should not fail the request
+				_ = sessionRepo.Touch(session.ID, now, c.ClientIP())
+			}
+			c.Set("session_id", session.ID)
+			c.Set("session", session)
+		}
+
 		// Set user info in context
 		c.Set("user_id", claims.UserID)
 		c.Set("user_email", claims.Email)
@@ -41,6 +78,36 @@ func AuthMiddleware(jwtSecret string) gin.HandlerFunc {
 	}
 }
 
+// RequireReverification guards sensitive actions: the user must have
+// re-confirmed their identity on this session within the window, by
+// two-factor login or POST /api/auth/reverify. Must run after AuthMiddleware
+// with a session repository.
+func RequireReverification(window time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		value, exists := c.Get("session")
+		session, _ := value.(*models.Session)
+		if !exists || session == nil || !session.VerifiedWithin(time.Now(), window) {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": "please confirm your identity to continue",
+				"code":  "REVERIFICATION_REQUIRED",
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// GetSessionID returns the session of the access token, if any
+func GetSessionID(c *gin.Context) uint {
+	id, exists := c.Get("session_id")
+	if !exists {
+		return 0
+	}
+	return id.(uint)
+}
+
 func GetUserID(c *gin.Context) (uint, bool) {
 	userID, exists := c.Get("user_id")
 	if !exists {
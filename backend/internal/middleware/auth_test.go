@@ -1,6 +1,7 @@
 package middleware
 
 import (
+	"billing-note/internal/models"
 	"billing-note/pkg/utils"
 	"net/http"
 	"net/http/httptest"
@@ -9,6 +10,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 )
 
 func setupAuthMiddlewareTest() (*gin.Engine, string) {
@@ -17,7 +19,7 @@ func setupAuthMiddlewareTest() (*gin.Engine, string) {
 	jwtSecret := "test-secret-key"
 
 	// Protected route
-	router.GET("/protected", AuthMiddleware(jwtSecret), func(c *gin.Context) {
+	router.GET("/protected", AuthMiddleware(jwtSecret, nil, nil), func(c *gin.Context) {
 		userID, exists := c.Get("user_id")
 		if !exists {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "user_id not found in context"})
@@ -203,3 +205,187 @@ func TestAuthMiddleware_ContextValuesSetCorrectly(t *testing.T) {
 	assert.Contains(t, w.Body.String(), "42")
 	assert.Contains(t, w.Body.String(), testEmail)
 }
+
+// --- Mock Session Repository ---
+
+type mockSessionRepo struct {
+	mock.Mock
+}
+
+func (m *mockSessionRepo) Create(session *models.Session) error {
+	args := m.Called(session)
+	return args.Error(0)
+}
+
+func (m *mockSessionRepo) GetByID(id uint) (*models.Session, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Session), args.Error(1)
+}
+
+func (m *mockSessionRepo) FindByRefreshHash(hash string) (*models.Session, error) {
+	args := m.Called(hash)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Session), args.Error(1)
+}
+
+func (m *mockSessionRepo) Update(session *models.Session) error {
+	args := m.Called(session)
+	return args.Error(0)
+}
+
+func (m *mockSessionRepo) Touch(id uint, seenAt time.Time, ip string) error {
+	args := m.Called(id, seenAt, ip)
+	return args.Error(0)
+}
+
+func (m *mockSessionRepo) MarkVerified(id uint, at time.Time) error {
+	args := m.Called(id, at)
+	return args.Error(0)
+}
+
+func (m *mockSessionRepo) ListActiveByUser(userID uint, now time.Time) ([]models.Session, error) {
+	args := m.Called(userID, now)
+	return args.Get(0).([]models.Session), args.Error(1)
+}
+
+func (m *mockSessionRepo) Revoke(id uint, at time.Time) error {
+	args := m.Called(id, at)
+	return args.Error(0)
+}
+
+func (m *mockSessionRepo) RevokeAllByUser(userID, exceptID uint, at time.Time) (int64, error) {
+	args := m.Called(userID, exceptID, at)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func serveWithSession(sessionRepo *mockSessionRepo, sessionID uint) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/protected", AuthMiddleware("test-secret-key", sessionRepo, nil), func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"session_id": GetSessionID(c)})
+	})
+
+	token, _ := utils.GenerateSessionToken(1, "test@example.com", sessionID, "test-secret-key", time.Hour)
+	req, _ := http.NewRequest(http.MethodGet, "/protected", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestAuthMiddleware_ActiveSession(t *testing.T) {
+	sessionRepo := new(mockSessionRepo)
+	sessionRepo.On("GetByID", uint(7)).Return(&models.Session{
+		ID: 7, UserID: 1, LastSeenAt: time.Now().Add(-time.Hour), ExpiresAt: time.Now().Add(time.Hour),
+	}, nil)
+	sessionRepo.On("Touch", uint(7), mock.AnythingOfType("time.Time"), mock.Anything).Return(nil)
+
+	w := serveWithSession(sessionRepo, 7)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"session_id":7`)
+	sessionRepo.AssertExpectations(t)
+}
+
+func TestAuthMiddleware_RecentlySeenSessionNotTouched(t *testing.T) {
+	sessionRepo := new(mockSessionRepo)
+	sessionRepo.On("GetByID", uint(7)).Return(&models.Session{
+		ID: 7, UserID: 1, LastSeenAt: time.Now(), ExpiresAt: time.Now().Add(time.Hour),
+	}, nil)
+
+	w := serveWithSession(sessionRepo, 7)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	sessionRepo.AssertNotCalled(t, "Touch", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestAuthMiddleware_RejectsInactiveSessions(t *testing.T) {
+	revokedAt := time.Now().Add(-time.Minute)
+	sessionRepo := new(mockSessionRepo)
+	sessionRepo.On("GetByID", uint(0)).Return(nil, nil)
+	sessionRepo.On("GetByID", uint(7)).Return(&models.Session{ID: 7, UserID: 1, ExpiresAt: time.Now().Add(time.Hour), RevokedAt: &revokedAt}, nil)
+	sessionRepo.On("GetByID", uint(8)).Return(&models.Session{ID: 8, UserID: 1, ExpiresAt: time.Now().Add(-time.Minute)}, nil)
+	sessionRepo.On("GetByID", uint(9)).Return(&models.Session{ID: 9, UserID: 2, ExpiresAt: time.Now().Add(time.Hour)}, nil)
+
+	// No session, revoked, expired, another user's session
+	for _, id := range []uint{0, 7, 8, 9} {
+		w := serveWithSession(sessionRepo, id)
+		assert.Equal(t, http.StatusUnauthorized, w.Code, id)
+	}
+}
+
+func TestRequireReverification(t *testing.T) {
+	recently := time.Now().Add(-5 * time.Minute)
+	longAgo := time.Now().Add(-time.Hour)
+
+	tests := []struct {
+		name       string
+		verifiedAt *time.Time
+		expected   int
+	}{
+		{"never verified", nil, http.StatusForbidden},
+		{"verified too long ago", &longAgo, http.StatusForbidden},
+		{"verified recently", &recently, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sessionRepo := new(mockSessionRepo)
+			sessionRepo.On("GetByID", uint(7)).Return(&models.Session{
+				ID: 7, UserID: 1, LastSeenAt: time.Now(), ExpiresAt: time.Now().Add(time.Hour), VerifiedAt: tt.verifiedAt,
+			}, nil)
+
+			gin.SetMode(gin.TestMode)
+			router := gin.New()
+			router.GET("/sensitive", AuthMiddleware("test-secret-key", sessionRepo, nil), RequireReverification(10*time.Minute), func(c *gin.Context) {
+				c.Status(http.StatusOK)
+			})
+
+			token, _ := utils.GenerateSessionToken(1, "test@example.com", 7, "test-secret-key", time.Hour)
+			req, _ := http.NewRequest(http.MethodGet, "/sensitive", nil)
+			req.Header.Set("Authorization", "Bearer "+token)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expected, w.Code)
+			if tt.expected == http.StatusForbidden {
+				assert.Contains(t, w.Body.String(), "REVERIFICATION_REQUIRED")
+			}
+		})
+	}
+}
+
+func TestRequireReverification_WithoutSession(t *testing.T) {
+	router, jwtSecret := setupAuthMiddlewareTest()
+	router.GET("/sensitive", AuthMiddleware(jwtSecret, nil, nil), RequireReverification(10*time.Minute), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	token, _ := utils.GenerateToken(1, "test@example.com", jwtSecret, time.Hour)
+	req, _ := http.NewRequest(http.MethodGet, "/sensitive", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+func TestAuthMiddleware_RejectsChallengeToken(t *testing.T) {
+	router, jwtSecret := setupAuthMiddlewareTest()
+
+	token, err := utils.GenerateChallengeToken(1, "test@example.com", jwtSecret, 5*time.Minute)
+	assert.NoError(t, err)
+
+	req, _ := http.NewRequest(http.MethodGet, "/protected", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
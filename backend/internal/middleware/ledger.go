@@ -0,0 +1,95 @@
+package middleware
+
+import (
+	"billing-note/internal/repository"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ledgerRoutes are the data routes that can work on a household ledger. Every
+// other data route is personal only and answers ledger_id with 400 and the
+// LEDGER_NOT_SUPPORTED code: accounts and net worth, bills, goals, the cash-flow
+// forecast, statement upload and import, and e-invoices. Their records belong
+// to one user and have no ledger column, so they cannot be shared yet.
+var ledgerRoutes = map[string]bool{
+	"/api/categories":            true,
+	"/api/categories/type/:type": true,
+	"/api/transactions":          true,
+	"/api/transactions/:id":      true,
+	"/api/stats/monthly":         true,
+	"/api/stats/category":        true,
+	"/api/stats/trend":           true,
+	"/api/budget":                true,
+	"/api/budget/:id":            true,
+	"/api/budget/compare":        true,
+	"/api/budget/:id/history":    true,
+	"/api/export/csv":            true,
+}
+
+// LedgerMiddleware checks the ledger_id query parameter, which switches a data
+// endpoint from the user's personal ledger to a household ledger they belong
+// to. When set, ledger_id is stored in the context.
+func LedgerMiddleware(ledgerRepo repository.LedgerRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ledgerStr := c.Query("ledger_id")
+		if ledgerStr == "" {
+			c.Next()
+			return
+		}
+
+		userID, exists := GetUserID(c)
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+			c.Abort()
+			return
+		}
+
+		ledgerID, err := strconv.ParseUint(ledgerStr, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid ledger_id parameter"})
+			c.Abort()
+			return
+		}
+		if c.Query("view_as") != "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "ledger_id cannot be combined with view_as"})
+			c.Abort()
+			return
+		}
+		if !ledgerRoutes[c.FullPath()] {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "this endpoint is not available for household ledgers",
+				"code":  "LEDGER_NOT_SUPPORTED",
+			})
+			c.Abort()
+			return
+		}
+
+		member, err := ledgerRepo.GetMember(uint(ledgerID), userID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to check ledger membership"})
+			c.Abort()
+			return
+		}
+		if member == nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": "you are not a member of this ledger"})
+			c.Abort()
+			return
+		}
+
+		c.Set("ledger_id", uint(ledgerID))
+		c.Next()
+	}
+}
+
+// GetLedgerID returns the household ledger selected for the request, or nil
+// for the user's personal ledger.
+func GetLedgerID(c *gin.Context) *uint {
+	id, exists := c.Get("ledger_id")
+	if !exists {
+		return nil
+	}
+	ledgerID := id.(uint)
+	return &ledgerID
+}
@@ -0,0 +1,158 @@
+package middleware
+
+import (
+	"billing-note/internal/models"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// --- Mock Ledger Repository ---
+
+type mockLedgerRepo struct {
+	mock.Mock
+}
+
+func (m *mockLedgerRepo) Create(ledger *models.Ledger) error {
+	args := m.Called(ledger)
+	return args.Error(0)
+}
+
+func (m *mockLedgerRepo) GetByID(id uint) (*models.Ledger, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Ledger), args.Error(1)
+}
+
+func (m *mockLedgerRepo) ListByUser(userID uint) ([]models.Ledger, error) {
+	args := m.Called(userID)
+	return args.Get(0).([]models.Ledger), args.Error(1)
+}
+
+func (m *mockLedgerRepo) Update(ledger *models.Ledger) error {
+	args := m.Called(ledger)
+	return args.Error(0)
+}
+
+func (m *mockLedgerRepo) Delete(id uint) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func (m *mockLedgerRepo) GetMember(ledgerID, userID uint) (*models.LedgerMember, error) {
+	args := m.Called(ledgerID, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.LedgerMember), args.Error(1)
+}
+
+func (m *mockLedgerRepo) AddMember(member *models.LedgerMember) error {
+	args := m.Called(member)
+	return args.Error(0)
+}
+
+func (m *mockLedgerRepo) RemoveMember(ledgerID, userID uint) error {
+	args := m.Called(ledgerID, userID)
+	return args.Error(0)
+}
+
+func serveWithLedger(repo *mockLedgerRepo, route, path string) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET(route, func(c *gin.Context) {
+		c.Set("user_id", uint(1))
+		c.Next()
+	}, LedgerMiddleware(repo), func(c *gin.Context) {
+		c.JSON(200, gin.H{"ledger_id": GetLedgerID(c)})
+	})
+
+	req, _ := http.NewRequest("GET", path, nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+// --- Tests ---
+
+func TestLedgerMiddleware_Personal(t *testing.T) {
+	w := serveWithLedger(new(mockLedgerRepo), "/api/transactions", "/api/transactions")
+
+	assert.Equal(t, 200, w.Code)
+	assert.Contains(t, w.Body.String(), `"ledger_id":null`)
+}
+
+func TestLedgerMiddleware_Member(t *testing.T) {
+	repo := new(mockLedgerRepo)
+	repo.On("GetMember", uint(10), uint(1)).Return(&models.LedgerMember{LedgerID: 10, UserID: 1}, nil)
+
+	w := serveWithLedger(repo, "/api/transactions", "/api/transactions?ledger_id=10")
+
+	assert.Equal(t, 200, w.Code)
+	assert.Contains(t, w.Body.String(), `"ledger_id":10`)
+}
+
+func TestLedgerMiddleware_NotMember(t *testing.T) {
+	repo := new(mockLedgerRepo)
+	repo.On("GetMember", uint(11), uint(1)).Return(nil, nil)
+
+	w := serveWithLedger(repo, "/api/budget", "/api/budget?ledger_id=11")
+
+	assert.Equal(t, 403, w.Code)
+}
+
+func TestLedgerMiddleware_Rejected(t *testing.T) {
+	repo := new(mockLedgerRepo)
+
+	w := serveWithLedger(repo, "/api/transactions", "/api/transactions?ledger_id=abc")
+	assert.Equal(t, 400, w.Code)
+
+	w = serveWithLedger(repo, "/api/transactions", "/api/transactions?ledger_id=10&view_as=2")
+	assert.Equal(t, 400, w.Code)
+
+	// Personal-only features cannot be switched to a household ledger
+	w = serveWithLedger(repo, "/api/goals", "/api/goals?ledger_id=10")
+	assert.Equal(t, 400, w.Code)
+	assert.Contains(t, w.Body.String(), "not available for household ledgers")
+
+	repo.AssertNotCalled(t, "GetMember", mock.Anything, mock.Anything)
+}
+
+func TestLedgerMiddleware_PersonalOnlyRoutes(t *testing.T) {
+	repo := new(mockLedgerRepo)
+
+	routes := map[string]string{
+		"/api/accounts":                    "/api/accounts",
+		"/api/accounts/:id/balances":       "/api/accounts/3/balances",
+		"/api/net-worth":                   "/api/net-worth",
+		"/api/bills":                       "/api/bills",
+		"/api/bills/:id":                   "/api/bills/4",
+		"/api/goals":                       "/api/goals",
+		"/api/goals/:id/contributions":     "/api/goals/5/contributions",
+		"/api/forecast":                    "/api/forecast",
+		"/api/upload/pdf":                  "/api/upload/pdf",
+		"/api/transactions/import":         "/api/transactions/import",
+		"/api/invoice/list":                "/api/invoice/list",
+		"/api/invoice/sync/status":         "/api/invoice/sync/status",
+		"/api/invoice/winnings":            "/api/invoice/winnings",
+		"/api/invoice/winning-numbers":     "/api/invoice/winning-numbers",
+		"/api/invoice/winnings/:id/record": "/api/invoice/winnings/6/record",
+	}
+	for route, path := range routes {
+		w := serveWithLedger(repo, route, path+"?ledger_id=10")
+		assert.Equal(t, 400, w.Code, route)
+		assert.Contains(t, w.Body.String(), "LEDGER_NOT_SUPPORTED", route)
+
+		// The same routes work on the personal ledger
+		w = serveWithLedger(repo, route, path)
+		assert.Equal(t, 200, w.Code, route)
+	}
+
+	repo.AssertNotCalled(t, "GetMember", mock.Anything, mock.Anything)
+}
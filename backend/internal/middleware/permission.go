@@ -1,6 +1,7 @@
 package middleware
 
 import (
+	"billing-note/internal/models"
 	"billing-note/internal/repository"
 	"net/http"
 	"strconv"
@@ -11,7 +12,10 @@ import (
 // ViewAsMiddleware checks the view_as query parameter and validates access.
 // If view_as is set, the data_user_id context is set to the target user.
 // Otherwise, data_user_id defaults to the authenticated user.
-// Also sets read_only=true when viewing another user's data.
+// The share (role and scope) is stored as share_access, and read_only=true
+// is set when the share only allows viewing. Responses for another user's
+// data echo the owner and role in the X-View-As-User-ID and X-View-As-Role
+// headers.
 func ViewAsMiddleware(sharingRepo repository.SharingRepository) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		userID, exists := GetUserID(c)
@@ -45,21 +49,28 @@ func ViewAsMiddleware(sharingRepo repository.SharingRepository) gin.HandlerFunc
 			}
 
 			// Check if the authenticated user has access to the target user's data
-			hasAccess, err := sharingRepo.HasAccess(targetUserID, userID)
+			access, err := sharingRepo.GetSharedAccess(targetUserID, userID)
 			if err != nil {
 				c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to check access"})
 				c.Abort()
 				return
 			}
 
-			if !hasAccess {
+			if access == nil {
 				c.JSON(http.StatusForbidden, gin.H{"error": "you do not have access to this user's data"})
 				c.Abort()
 				return
 			}
 
+			role := access.Role
+			if role == "" {
+				role = models.ShareRoleViewer
+			}
 			c.Set("data_user_id", targetUserID)
-			c.Set("read_only", true)
+			c.Set("share_access", access)
+			c.Set("read_only", role == models.ShareRoleViewer)
+			c.Header("X-View-As-User-ID", strconv.FormatUint(uint64(targetUserID), 10))
+			c.Header("X-View-As-Role", role)
 		}
 
 		c.Next()
@@ -82,6 +93,74 @@ func ReadOnlyGuard() gin.HandlerFunc {
 	}
 }
 
+// scopedRoutes are the data routes that respect a share scope. Scoped shares
+// cannot reach any other route.
+var scopedRoutes = map[string]bool{
+	"/api/categories":            true,
+	"/api/categories/type/:type": true,
+	"/api/transactions":          true,
+	"/api/transactions/:id":      true,
+	"/api/stats/monthly":         true,
+	"/api/stats/category":        true,
+	"/api/stats/trend":           true,
+}
+
+// SharePermissionGuard enforces the role and scope of the share when viewing
+// another user's data. Viewers may only read, contributors may also add
+// transactions, and editors may write. Requests for the user's own data pass.
+func SharePermissionGuard() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		access := GetSharedAccess(c)
+		if access == nil {
+			c.Next()
+			return
+		}
+
+		route := c.FullPath()
+		if access.Scope() != nil && !scopedRoutes[route] {
+			c.JSON(http.StatusForbidden, gin.H{"error": "this share is limited to transactions and statistics"})
+			c.Abort()
+			return
+		}
+
+		method := c.Request.Method
+		if method == http.MethodPost || method == http.MethodPut || method == http.MethodDelete || method == http.MethodPatch {
+			allowed := false
+			switch access.Role {
+			case models.ShareRoleEditor:
+				allowed = true
+			case models.ShareRoleContributor:
+				allowed = method == http.MethodPost && route == "/api/transactions"
+			}
+			if !allowed {
+				c.JSON(http.StatusForbidden, gin.H{"error": "your role does not allow this operation on the shared data"})
+				c.Abort()
+				return
+			}
+		}
+		c.Next()
+	}
+}
+
+// GetSharedAccess returns the share being used to view another user's data,
+// or nil when the user is viewing their own data.
+func GetSharedAccess(c *gin.Context) *models.SharedAccess {
+	access, exists := c.Get("share_access")
+	if !exists {
+		return nil
+	}
+	return access.(*models.SharedAccess)
+}
+
+// GetShareScope returns the scope of the share in use, or nil when unrestricted.
+func GetShareScope(c *gin.Context) *models.ShareScope {
+	access := GetSharedAccess(c)
+	if access == nil {
+		return nil
+	}
+	return access.Scope()
+}
+
 // GetDataUserID returns the effective user ID for data access.
 func GetDataUserID(c *gin.Context) (uint, bool) {
 	id, exists := c.Get("data_user_id")
@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
@@ -63,6 +64,70 @@ func (m *mockSharingRepo) HasAccess(ownerID, viewerID uint) (bool, error) {
 	return args.Bool(0), args.Error(1)
 }
 
+func (m *mockSharingRepo) GetSharedAccess(ownerID, viewerID uint) (*models.SharedAccess, error) {
+	args := m.Called(ownerID, viewerID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.SharedAccess), args.Error(1)
+}
+
+func (m *mockSharingRepo) UpdateSharedAccess(access *models.SharedAccess) error {
+	args := m.Called(access)
+	return args.Error(0)
+}
+
+func (m *mockSharingRepo) ConsumePairingCode(id uint, now time.Time) (bool, error) {
+	args := m.Called(id, now)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *mockSharingRepo) CreatePairingRequest(request *models.PairingRequest) error {
+	args := m.Called(request)
+	return args.Error(0)
+}
+
+func (m *mockSharingRepo) GetPairingRequest(id uint) (*models.PairingRequest, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.PairingRequest), args.Error(1)
+}
+
+func (m *mockSharingRepo) FindPendingRequest(ownerID, requesterID uint) (*models.PairingRequest, error) {
+	args := m.Called(ownerID, requesterID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.PairingRequest), args.Error(1)
+}
+
+func (m *mockSharingRepo) ListPendingRequestsByOwner(ownerID uint) ([]models.PairingRequest, error) {
+	args := m.Called(ownerID)
+	return args.Get(0).([]models.PairingRequest), args.Error(1)
+}
+
+func (m *mockSharingRepo) ListPendingRequestsByRequester(requesterID uint) ([]models.PairingRequest, error) {
+	args := m.Called(requesterID)
+	return args.Get(0).([]models.PairingRequest), args.Error(1)
+}
+
+func (m *mockSharingRepo) UpdatePairingRequest(request *models.PairingRequest) error {
+	args := m.Called(request)
+	return args.Error(0)
+}
+
+func (m *mockSharingRepo) RecordPairingFailure(userID uint) error {
+	args := m.Called(userID)
+	return args.Error(0)
+}
+
+func (m *mockSharingRepo) CountPairingFailures(userID uint, since time.Time) (int64, error) {
+	args := m.Called(userID, since)
+	return args.Get(0).(int64), args.Error(1)
+}
+
 // --- Tests ---
 
 func setupTestRouter(repo *mockSharingRepo) *gin.Engine {
@@ -91,6 +156,7 @@ func TestViewAsMiddleware_NoViewAs(t *testing.T) {
 	assert.Equal(t, 200, w.Code)
 	assert.Contains(t, w.Body.String(), `"data_user_id":1`)
 	assert.Contains(t, w.Body.String(), `"read_only":false`)
+	assert.Empty(t, w.Header().Get("X-View-As-User-ID"))
 }
 
 func TestViewAsMiddleware_ViewAsSelf(t *testing.T) {
@@ -120,7 +186,7 @@ func TestViewAsMiddleware_ViewAsWithAccess(t *testing.T) {
 	r := setupTestRouter(repo)
 
 	// User 2 (owner) granted access to User 1 (viewer)
-	repo.On("HasAccess", uint(2), uint(1)).Return(true, nil)
+	repo.On("GetSharedAccess", uint(2), uint(1)).Return(&models.SharedAccess{OwnerID: 2, ViewerID: 1, Role: models.ShareRoleViewer}, nil)
 
 	r.GET("/test", func(c *gin.Context) {
 		c.Set("user_id", uint(1))
@@ -138,6 +204,8 @@ func TestViewAsMiddleware_ViewAsWithAccess(t *testing.T) {
 	assert.Equal(t, 200, w.Code)
 	assert.Contains(t, w.Body.String(), `"data_user_id":2`)
 	assert.Contains(t, w.Body.String(), `"read_only":true`)
+	assert.Equal(t, "2", w.Header().Get("X-View-As-User-ID"))
+	assert.Equal(t, models.ShareRoleViewer, w.Header().Get("X-View-As-Role"))
 }
 
 func TestViewAsMiddleware_ViewAsWithoutAccess(t *testing.T) {
@@ -145,7 +213,7 @@ func TestViewAsMiddleware_ViewAsWithoutAccess(t *testing.T) {
 	r := setupTestRouter(repo)
 
 	// User 3 did NOT grant access to User 1
-	repo.On("HasAccess", uint(3), uint(1)).Return(false, nil)
+	repo.On("GetSharedAccess", uint(3), uint(1)).Return(nil, nil)
 
 	r.GET("/test", func(c *gin.Context) {
 		c.Set("user_id", uint(1))
@@ -287,3 +355,88 @@ func TestViewAsMiddleware_Unauthenticated(t *testing.T) {
 
 	assert.Equal(t, 401, w.Code)
 }
+
+func TestViewAsMiddleware_EditorIsNotReadOnly(t *testing.T) {
+	repo := new(mockSharingRepo)
+	r := setupTestRouter(repo)
+
+	repo.On("GetSharedAccess", uint(2), uint(1)).Return(&models.SharedAccess{OwnerID: 2, ViewerID: 1, Role: models.ShareRoleEditor}, nil)
+
+	r.GET("/test", func(c *gin.Context) {
+		c.Set("user_id", uint(1))
+		c.Next()
+	}, ViewAsMiddleware(repo), func(c *gin.Context) {
+		c.JSON(200, gin.H{"read_only": IsReadOnly(c), "role": GetSharedAccess(c).Role})
+	})
+
+	req, _ := http.NewRequest("GET", "/test?view_as=2", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, 200, w.Code)
+	assert.Contains(t, w.Body.String(), `"read_only":false`)
+	assert.Contains(t, w.Body.String(), `"role":"editor"`)
+}
+
+func serveWithShare(access *models.SharedAccess, method, route, path string) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Handle(method, route, func(c *gin.Context) {
+		if access != nil {
+			c.Set("share_access", access)
+		}
+		c.Next()
+	}, SharePermissionGuard(), func(c *gin.Context) {
+		c.JSON(200, gin.H{"ok": true})
+	})
+
+	req, _ := http.NewRequest(method, path, nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestSharePermissionGuard_Roles(t *testing.T) {
+	viewer := &models.SharedAccess{Role: models.ShareRoleViewer}
+	contributor := &models.SharedAccess{Role: models.ShareRoleContributor}
+	editor := &models.SharedAccess{Role: models.ShareRoleEditor}
+
+	tests := []struct {
+		name   string
+		access *models.SharedAccess
+		method string
+		route  string
+		path   string
+		want   int
+	}{
+		{"own data", nil, "DELETE", "/api/bills/:id", "/api/bills/1", 200},
+		{"viewer reads", viewer, "GET", "/api/bills", "/api/bills", 200},
+		{"viewer cannot add", viewer, "POST", "/api/transactions", "/api/transactions", 403},
+		{"contributor adds transaction", contributor, "POST", "/api/transactions", "/api/transactions", 200},
+		{"contributor cannot edit", contributor, "PUT", "/api/transactions/:id", "/api/transactions/1", 403},
+		{"contributor cannot import", contributor, "POST", "/api/transactions/import", "/api/transactions/import", 403},
+		{"editor edits", editor, "PUT", "/api/transactions/:id", "/api/transactions/1", 200},
+		{"editor deletes bill", editor, "DELETE", "/api/bills/:id", "/api/bills/1", 200},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serveWithShare(tt.access, tt.method, tt.route, tt.path)
+			assert.Equal(t, tt.want, w.Code)
+		})
+	}
+}
+
+func TestSharePermissionGuard_ScopeLimitsRoutes(t *testing.T) {
+	access := &models.SharedAccess{Role: models.ShareRoleEditor, ScopeCategoryIDs: []int64{3}}
+
+	w := serveWithShare(access, "GET", "/api/transactions", "/api/transactions")
+	assert.Equal(t, 200, w.Code)
+	w = serveWithShare(access, "GET", "/api/stats/monthly", "/api/stats/monthly")
+	assert.Equal(t, 200, w.Code)
+
+	w = serveWithShare(access, "GET", "/api/bills", "/api/bills")
+	assert.Equal(t, 403, w.Code)
+	w = serveWithShare(access, "GET", "/api/export/csv", "/api/export/csv")
+	assert.Equal(t, 403, w.Code)
+}
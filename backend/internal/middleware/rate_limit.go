@@ -0,0 +1,106 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RateLimiter allows up to limit requests per key in each fixed window. It is
+// kept in memory, so each server instance counts on its own.
+type RateLimiter struct {
+	limit  int
+	window time.Duration
+	now    func() time.Time
+
+	mu        sync.Mutex
+	windows   map[string]*rateWindow
+	lastSweep time.Time
+}
+
+type rateWindow struct {
+	start time.Time
+	count int
+}
+
+// NewRateLimiter creates a limiter allowing limit requests per key per window
+func NewRateLimiter(limit int, window time.Duration) *RateLimiter {
+	return &RateLimiter{
+		limit:   limit,
+		window:  window,
+		now:     time.Now,
+		windows: make(map[string]*rateWindow),
+	}
+}
+
+// Allow counts a request for the key. When the key is over its limit it
+// returns false and how long until the window resets.
+func (l *RateLimiter) Allow(key string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+
+	w, ok := l.windows[key]
+	if !ok || now.Sub(w.start) >= l.window {
+		w = &rateWindow{start: now}
+		l.windows[key] = w
+	}
+	if w.count >= l.limit {
+		return false, w.start.Add(l.window).Sub(now)
+	}
+	w.count++
+	return true, 0
+}
+
+// sweep drops finished windows, at most once per window so the map does not
+// grow with every client ever seen
+func (l *RateLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < l.window {
+		return
+	}
+	for key, w := range l.windows {
+		if now.Sub(w.start) >= l.window {
+			delete(l.windows, key)
+		}
+	}
+	l.lastSweep = now
+}
+
+// RateLimitKey picks what a rate limit counts requests by
+type RateLimitKey func(c *gin.Context) string
+
+// ByClientIP counts requests per client IP
+func ByClientIP(c *gin.Context) string {
+	return "ip:" + c.ClientIP()
+}
+
+// ByUser counts requests per signed-in user, falling back to the client IP.
+// Use it after AuthMiddleware.
+func ByUser(c *gin.Context) string {
+	if userID, exists := GetUserID(c); exists {
+		return fmt.Sprintf("user:%d", userID)
+	}
+	return ByClientIP(c)
+}
+
+// RateLimit refuses requests over the limiter's limit with 429 and a
+// Retry-After header
+func RateLimit(limiter *RateLimiter, key RateLimitKey) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		allowed, retryAfter := limiter.Allow(key(c))
+		if !allowed {
+			seconds := int((retryAfter + time.Second - 1) / time.Second)
+			c.Header("Retry-After", strconv.Itoa(seconds))
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": "too many requests, please try again later"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,97 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimiter_Allow(t *testing.T) {
+	now := time.Date(2026, 6, 15, 12, 0, 0, 0, time.UTC)
+	limiter := NewRateLimiter(2, time.Minute)
+	limiter.now = func() time.Time { return now }
+
+	allowed, _ := limiter.Allow("a")
+	assert.True(t, allowed)
+	allowed, _ = limiter.Allow("a")
+	assert.True(t, allowed)
+
+	now = now.Add(20 * time.Second)
+	allowed, retryAfter := limiter.Allow("a")
+	assert.False(t, allowed)
+	assert.Equal(t, 40*time.Second, retryAfter)
+
+	// Keys are counted separately
+	allowed, _ = limiter.Allow("b")
+	assert.True(t, allowed)
+
+	// A new window starts once the old one ends
+	now = now.Add(40 * time.Second)
+	allowed, _ = limiter.Allow("a")
+	assert.True(t, allowed)
+}
+
+func TestRateLimiter_SweepsFinishedWindows(t *testing.T) {
+	now := time.Date(2026, 6, 15, 12, 0, 0, 0, time.UTC)
+	limiter := NewRateLimiter(1, time.Minute)
+	limiter.now = func() time.Time { return now }
+
+	limiter.Allow("a")
+	limiter.Allow("b")
+	now = now.Add(2 * time.Minute)
+	limiter.Allow("c")
+
+	assert.Len(t, limiter.windows, 1)
+}
+
+func TestRateLimit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/shared/pair", func(c *gin.Context) {
+		c.Set("user_id", uint(1))
+		c.Next()
+	}, RateLimit(NewRateLimiter(1, time.Minute), ByUser), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	serve := func() *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(http.MethodPost, "/shared/pair", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	assert.Equal(t, http.StatusOK, serve().Code)
+
+	w := serve()
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "60", w.Header().Get("Retry-After"))
+	assert.Contains(t, w.Body.String(), "too many requests")
+}
+
+func TestRateLimit_ByClientIP_IgnoresForwardedForFromUntrustedProxy(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	// As in main with TRUSTED_PROXIES unset
+	assert.NoError(t, router.SetTrustedProxies(nil))
+	router.POST("/auth/login", RateLimit(NewRateLimiter(1, time.Minute), ByClientIP), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	serve := func(forwardedFor string) int {
+		req, _ := http.NewRequest(http.MethodPost, "/auth/login", nil)
+		req.RemoteAddr = "203.0.113.7:40000"
+		req.Header.Set("X-Forwarded-For", forwardedFor)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusOK, serve("198.51.100.1"))
+	// A new forged address does not get a fresh allowance
+	assert.Equal(t, http.StatusTooManyRequests, serve("198.51.100.2"))
+}
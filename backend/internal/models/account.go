@@ -0,0 +1,42 @@
+package models
+
+import "time"
+
+// Account types
+const (
+	AccountTypeCash       = "cash"
+	AccountTypeBank       = "bank"
+	AccountTypeCreditCard = "credit_card"
+	AccountTypeEWallet    = "e_wallet"
+	AccountTypeInvestment = "investment"
+	AccountTypeLoan       = "loan"
+	AccountTypeOther      = "other"
+)
+
+// Account is a place money is held or spent from, e.g. a bank account or credit card
+type Account struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	UserID    uint      `gorm:"not null;index" json:"user_id"`
+	Name      string    `gorm:"not null;size:100" json:"name"`
+	Type      string    `gorm:"not null;size:20;default:other" json:"type"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	User User `gorm:"foreignKey:UserID" json:"-"`
+}
+
+func (Account) TableName() string {
+	return "accounts"
+}
+
+// IsLiability reports whether the account's balance is money owed, such as an
+// outstanding card balance or a loan
+func (a Account) IsLiability() bool {
+	return a.Type == AccountTypeCreditCard || a.Type == AccountTypeLoan
+}
+
+// AccountInput is the request body for creating or updating an account
+type AccountInput struct {
+	Name string `json:"name" binding:"required"`
+	Type string `json:"type"`
+}
@@ -0,0 +1,71 @@
+package models
+
+import (
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// API token scopes
+const (
+	APIScopeTransactionsRead  = "transactions:read"  // list and read transactions and categories
+	APIScopeTransactionsWrite = "transactions:write" // create, update and delete transactions
+	APIScopeExport            = "export"             // CSV export
+	APIScopeStats             = "stats"              // statistics
+)
+
+// APITokenScopes lists every valid scope
+var APITokenScopes = []string{APIScopeTransactionsRead, APIScopeTransactionsWrite, APIScopeExport, APIScopeStats}
+
+// APITokenPrefix starts every personal API token, telling them apart from JWTs
+const APITokenPrefix = "bnpat_"
+
+// APIToken is a personal access token for scripts and integrations. It acts
+// as its user but only on the routes its scopes allow. Only its hash is stored.
+type APIToken struct {
+	ID         uint           `gorm:"primaryKey" json:"id"`
+	UserID     uint           `gorm:"not null;index" json:"-"`
+	Name       string         `gorm:"size:100;not null" json:"name"`
+	TokenHash  string         `gorm:"size:64;not null;uniqueIndex" json:"-"`
+	Hint       string         `gorm:"size:16;not null" json:"hint"` // start of the token, to recognise it
+	Scopes     pq.StringArray `gorm:"type:text[];not null" json:"scopes"`
+	ExpiresAt  *time.Time     `json:"expires_at"`
+	LastUsedAt *time.Time     `json:"last_used_at"`
+	LastUsedIP string         `gorm:"size:45" json:"last_used_ip"`
+	RevokedAt  *time.Time     `json:"-"`
+	CreatedAt  time.Time      `json:"created_at"`
+}
+
+func (APIToken) TableName() string {
+	return "api_tokens"
+}
+
+// Active reports whether the token can be used at the given time
+func (t *APIToken) Active(now time.Time) bool {
+	return t.RevokedAt == nil && (t.ExpiresAt == nil || now.Before(*t.ExpiresAt))
+}
+
+// HasScope reports whether the token was granted the scope
+func (t *APIToken) HasScope(scope string) bool {
+	for _, s := range t.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// APITokenInput creates a personal API token. Tokens without
+// expires_in_days do not expire.
+type APITokenInput struct {
+	Name          string   `json:"name" binding:"required,max=100"`
+	Scopes        []string `json:"scopes" binding:"required,min=1"`
+	ExpiresInDays *int     `json:"expires_in_days" binding:"omitempty,min=1,max=365"`
+}
+
+// APITokenCreated is returned once, when the token is created; the token
+// itself cannot be retrieved later
+type APITokenCreated struct {
+	APIToken
+	Token string `json:"token"`
+}
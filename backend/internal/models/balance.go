@@ -0,0 +1,82 @@
+package models
+
+import "time"
+
+// Balance snapshot sources
+const (
+	BalanceSourceManual         = "manual"
+	BalanceSourceStatement      = "statement"
+	BalanceSourceReconciliation = "reconciliation"
+)
+
+// AccountBalance is an account's balance at the end of a day. Liability
+// balances are the positive amount owed.
+type AccountBalance struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	UserID    uint      `gorm:"not null;index" json:"user_id"`
+	AccountID uint      `gorm:"not null;uniqueIndex:idx_account_balance_date" json:"account_id"`
+	Date      time.Time `gorm:"type:date;not null;uniqueIndex:idx_account_balance_date" json:"date"`
+	Balance   float64   `gorm:"not null" json:"balance"`
+	Source    string    `gorm:"not null;size:20;default:manual" json:"source"`
+	Note      string    `gorm:"size:255" json:"note,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (AccountBalance) TableName() string {
+	return "account_balances"
+}
+
+// BalanceInput is the request body for recording a balance snapshot
+type BalanceInput struct {
+	Balance *float64 `json:"balance" binding:"required"`
+	Date    string   `json:"date"` // YYYY-MM-DD, defaults to today
+	Note    string   `json:"note"`
+}
+
+// ReconcileInput is the request body for reconciling an account
+type ReconcileInput struct {
+	ActualBalance *float64 `json:"actual_balance" binding:"required"`
+	Date          string   `json:"date"` // YYYY-MM-DD, defaults to today
+}
+
+// Reconciliation compares the balance expected from transactions with the
+// actual balance
+type Reconciliation struct {
+	AccountID        uint            `json:"account_id"`
+	Date             time.Time       `json:"date"`
+	ExpectedBalance  float64         `json:"expected_balance"`
+	ActualBalance    *float64        `json:"actual_balance,omitempty"`
+	Difference       *float64        `json:"difference,omitempty"` // actual - expected
+	Reconciled       bool            `json:"reconciled"`
+	BaseSnapshot     *AccountBalance `json:"base_snapshot,omitempty"`
+	TransactionCount int             `json:"transaction_count"` // transactions applied to the base snapshot
+}
+
+// AccountNetWorth is an account's current balance in the net worth report
+type AccountNetWorth struct {
+	AccountID        uint       `json:"account_id"`
+	Name             string     `json:"name"`
+	Type             string     `json:"type"`
+	Liability        bool       `json:"liability"`
+	Balance          float64    `json:"balance"`
+	LastSnapshotDate *time.Time `json:"last_snapshot_date,omitempty"`
+}
+
+// NetWorthPoint is total assets, liabilities and net worth at a date
+type NetWorthPoint struct {
+	Date        time.Time `json:"date"`
+	Assets      float64   `json:"assets"`
+	Liabilities float64   `json:"liabilities"`
+	NetWorth    float64   `json:"net_worth"`
+}
+
+// NetWorth is the current net worth with its month-end history
+type NetWorth struct {
+	Date        time.Time         `json:"date"`
+	Assets      float64           `json:"assets"`
+	Liabilities float64           `json:"liabilities"`
+	NetWorth    float64           `json:"net_worth"`
+	Accounts    []AccountNetWorth `json:"accounts"`
+	Series      []NetWorthPoint   `json:"series"`
+}
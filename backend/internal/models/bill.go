@@ -0,0 +1,67 @@
+package models
+
+import "time"
+
+// Bill statuses
+const (
+	BillStatusPending = "pending"
+	BillStatusPaid    = "paid"
+)
+
+// Bill sources
+const (
+	BillSourceStatement = "statement"
+	BillSourceManual    = "manual"
+)
+
+// DefaultBillRemindDays is how many days before the due date a reminder is sent
+const DefaultBillRemindDays = 3
+
+// Bill is a payment obligation, such as a credit card statement balance
+type Bill struct {
+	ID                   uint       `gorm:"primaryKey" json:"id"`
+	UserID               uint       `gorm:"not null;index" json:"user_id"`
+	AccountID            *uint      `gorm:"index" json:"account_id,omitempty"`
+	Issuer               string     `gorm:"size:100" json:"issuer"` // bank name for statement bills
+	Description          string     `gorm:"size:255" json:"description"`
+	Source               string     `gorm:"not null;size:20;default:manual" json:"source"`
+	StatementDate        *time.Time `gorm:"type:date" json:"statement_date,omitempty"`
+	DueDate              time.Time  `gorm:"type:date;not null;index" json:"due_date"`
+	AmountDue            float64    `gorm:"not null" json:"amount_due"`
+	MinimumDue           float64    `json:"minimum_due"`
+	RemindDays           int        `gorm:"not null" json:"remind_days"` // no gorm default: it would store 0 as 3 on create
+	Status               string     `gorm:"not null;size:20;default:pending;index" json:"status"`
+	PaidAt               *time.Time `json:"paid_at,omitempty"`
+	PaidAmount           float64    `json:"paid_amount"`
+	PaymentTransactionID *uint      `json:"payment_transaction_id,omitempty"`
+	RemindedAt           *time.Time `json:"reminded_at,omitempty"`
+	OverdueNotifiedAt    *time.Time `json:"overdue_notified_at,omitempty"`
+	CreatedAt            time.Time  `json:"created_at"`
+	UpdatedAt            time.Time  `json:"updated_at"`
+
+	User               User         `gorm:"foreignKey:UserID" json:"-"`
+	Account            *Account     `gorm:"foreignKey:AccountID" json:"account,omitempty"`
+	PaymentTransaction *Transaction `gorm:"foreignKey:PaymentTransactionID" json:"payment_transaction,omitempty"`
+}
+
+func (Bill) TableName() string {
+	return "bills"
+}
+
+// BillInput is the request body for creating or updating a bill
+type BillInput struct {
+	AccountID   *uint   `json:"account_id"`
+	Issuer      string  `json:"issuer"`
+	Description string  `json:"description"`
+	DueDate     string  `json:"due_date" binding:"required"` // YYYY-MM-DD
+	AmountDue   float64 `json:"amount_due" binding:"gte=0"`
+	MinimumDue  float64 `json:"minimum_due" binding:"gte=0"`
+	RemindDays  *int    `json:"remind_days"`
+}
+
+// MarkBillPaidRequest is the request body for marking a bill as paid
+type MarkBillPaidRequest struct {
+	TransactionID *uint    `json:"transaction_id"` // the bank payment transaction, optional
+	PaidAt        string   `json:"paid_at"`        // YYYY-MM-DD, defaults to the transaction date or today
+	PaidAmount    *float64 `json:"paid_amount"`    // defaults to the transaction amount or amount due
+}
@@ -1,36 +1,123 @@
 package models
 
-import "time"
+import (
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Budget target types: what spending a budget counts
+const (
+	BudgetTargetCategory   = "category"   // a single category
+	BudgetTargetOverall    = "overall"    // all expenses
+	BudgetTargetCategories = "categories" // a set of categories
+	BudgetTargetTag        = "tag"        // transactions carrying a tag
+	BudgetTargetAccount    = "account"    // transactions from one account
+)
+
+// Budget period types
+const (
+	BudgetPeriodWeekly    = "weekly"
+	BudgetPeriodMonthly   = "monthly"
+	BudgetPeriodQuarterly = "quarterly"
+	BudgetPeriodYearly    = "yearly"
+	BudgetPeriodCustom    = "custom"
+)
 
 type Budget struct {
-	ID            uint      `gorm:"primaryKey" json:"id"`
-	UserID        uint      `gorm:"not null;index" json:"user_id"`
-	CategoryID    uint      `gorm:"not null" json:"category_id"`
-	MonthlyAmount float64   `gorm:"type:decimal(10,2);not null" json:"monthly_amount"`
-	CreatedAt     time.Time `json:"created_at"`
-	UpdatedAt     time.Time `json:"updated_at"`
-
-	User     User     `gorm:"foreignKey:UserID" json:"-"`
-	Category Category `gorm:"foreignKey:CategoryID" json:"category,omitempty"`
+	ID         uint   `gorm:"primaryKey" json:"id"`
+	UserID     uint   `gorm:"not null;index" json:"user_id"`    // owner, or the member who created a ledger budget
+	LedgerID   *uint  `gorm:"index" json:"ledger_id,omitempty"` // household ledger; nil for personal
+	TargetType string `gorm:"size:20;not null;default:category" json:"target_type"`
+	// Target fields; only the one matching TargetType is set
+	CategoryID  *uint         `json:"category_id,omitempty"`
+	CategoryIDs pq.Int64Array `gorm:"type:integer[]" json:"category_ids,omitempty"`
+	Tag         string        `gorm:"size:100" json:"tag,omitempty"`
+	AccountID   *uint         `json:"account_id,omitempty"`
+	PeriodType  string        `gorm:"size:20;not null;default:monthly" json:"period_type"`
+	// StartDay is the weekday (0=Sunday..6) for weekly budgets and the day of
+	// month (1-28) periods begin on for monthly, quarterly and yearly budgets.
+	// No gorm default: it would store Sunday (0) as 1 on create.
+	StartDay int `gorm:"not null" json:"start_day"`
+	// StartDate and EndDate (inclusive) bound custom budgets
+	StartDate *time.Time `gorm:"type:date" json:"start_date,omitempty"`
+	EndDate   *time.Time `gorm:"type:date" json:"end_date,omitempty"`
+	// MonthlyAmount is the amount allowed per period; the name predates non-monthly periods
+	MonthlyAmount float64 `gorm:"type:decimal(10,2);not null" json:"monthly_amount"`
+	// Rollover carries unspent (or overspent) amounts into the next period
+	Rollover bool `gorm:"not null;default:false" json:"rollover"`
+	// AlertThresholds are spending percentages (e.g. 80, 100) that trigger a
+	// notification once per period
+	AlertThresholds pq.Int64Array `gorm:"type:integer[]" json:"alert_thresholds"`
+	CreatedAt       time.Time     `json:"created_at"`
+	UpdatedAt       time.Time     `json:"updated_at"`
+
+	User     User      `gorm:"foreignKey:UserID" json:"-"`
+	Category *Category `gorm:"foreignKey:CategoryID" json:"category,omitempty"`
+	Account  *Account  `gorm:"foreignKey:AccountID" json:"account,omitempty"`
 }
 
 func (Budget) TableName() string {
 	return "budgets"
 }
 
+// BudgetLedgerEntry records one budget period. Amount is snapshotted when the
+// period is recorded so history survives later budget changes.
+type BudgetLedgerEntry struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	BudgetID    uint      `gorm:"not null;uniqueIndex:idx_budget_ledger_period" json:"budget_id"`
+	UserID      uint      `gorm:"not null;index" json:"user_id"`
+	PeriodStart time.Time `gorm:"type:date;not null;uniqueIndex:idx_budget_ledger_period" json:"period_start"`
+	PeriodEnd   time.Time `gorm:"type:date;not null" json:"period_end"` // inclusive
+	Amount      float64   `gorm:"type:decimal(10,2);not null" json:"amount"`
+	CarryIn     float64   `gorm:"type:decimal(10,2);not null;default:0" json:"carry_in"`
+	Actual      float64   `gorm:"type:decimal(10,2);not null;default:0" json:"actual"`
+	CarryOut    float64   `gorm:"type:decimal(10,2);not null;default:0" json:"carry_out"`
+	Closed      bool      `gorm:"not null;default:false" json:"closed"` // finalized; no longer recalculated
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+func (BudgetLedgerEntry) TableName() string {
+	return "budget_ledger_entries"
+}
+
+// CreateBudgetRequest creates a budget. The target is fixed once created.
 type CreateBudgetRequest struct {
-	CategoryID    uint    `json:"category_id" binding:"required"`
+	TargetType    string  `json:"target_type"` // defaults to category
+	CategoryID    *uint   `json:"category_id"`
+	CategoryIDs   []int64 `json:"category_ids"`
+	Tag           string  `json:"tag"`
+	AccountID     *uint   `json:"account_id"`
 	MonthlyAmount float64 `json:"monthly_amount" binding:"required,gt=0"`
+	PeriodType    string  `json:"period_type"` // defaults to monthly
+	StartDay      *int    `json:"start_day"`
+	StartDate     string  `json:"start_date"` // YYYY-MM-DD, custom only
+	EndDate       string  `json:"end_date"`   // YYYY-MM-DD, custom only
+	Rollover      bool    `json:"rollover"`
+	// AlertThresholds are percentages such as [80, 100]; empty disables alerts
+	AlertThresholds []int64 `json:"alert_thresholds"`
 }
 
 type UpdateBudgetRequest struct {
 	MonthlyAmount float64 `json:"monthly_amount" binding:"required,gt=0"`
+	PeriodType    string  `json:"period_type"` // unchanged when empty
+	StartDay      *int    `json:"start_day"`
+	StartDate     string  `json:"start_date"`
+	EndDate       string  `json:"end_date"`
+	Rollover      *bool   `json:"rollover"` // unchanged when omitted
+	// AlertThresholds replaces the thresholds when present; [] disables alerts
+	AlertThresholds []int64 `json:"alert_thresholds"`
 }
 
 type BudgetComparison struct {
-	Budget       Budget  `json:"budget"`
-	ActualAmount float64 `json:"actual_amount"`
-	Remaining    float64 `json:"remaining"`
-	Percentage   float64 `json:"percentage"`
-	IsOverBudget bool    `json:"is_over_budget"`
+	Budget       Budget    `json:"budget"`
+	PeriodStart  time.Time `json:"period_start"`
+	PeriodEnd    time.Time `json:"period_end"` // inclusive
+	CarryIn      float64   `json:"carry_in"`   // rolled over from the previous period
+	Available    float64   `json:"available"`  // budget amount plus carry-in
+	ActualAmount float64   `json:"actual_amount"`
+	Remaining    float64   `json:"remaining"`
+	Percentage   float64   `json:"percentage"`
+	IsOverBudget bool      `json:"is_over_budget"`
 }
@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// CalendarFeedToken is the secret token in a user's iCalendar feed URL.
+// Anyone holding the token can read the feed, so it can be rotated or revoked.
+type CalendarFeedToken struct {
+	ID             uint       `gorm:"primaryKey" json:"id"`
+	UserID         uint       `gorm:"not null;uniqueIndex" json:"user_id"`
+	Token          string     `gorm:"not null;uniqueIndex;size:64" json:"token"`
+	LastAccessedAt *time.Time `json:"last_accessed_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+func (CalendarFeedToken) TableName() string {
+	return "calendar_feed_tokens"
+}
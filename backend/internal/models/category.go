@@ -4,10 +4,11 @@ import "time"
 
 type Category struct {
 	ID        uint      `gorm:"primaryKey" json:"id"`
-	Name      string    `gorm:"uniqueIndex;not null" json:"name"`
+	Name      string    `gorm:"not null" json:"name"` // unique among global categories and within a ledger
 	Type      string    `gorm:"not null" json:"type"` // "income" or "expense"
 	Icon      string    `json:"icon"`
 	Color     string    `json:"color"`
+	LedgerID  *uint     `gorm:"index" json:"ledger_id,omitempty"` // nil for global categories
 	CreatedAt time.Time `json:"created_at"`
 }
 
@@ -0,0 +1,64 @@
+package models
+
+import "time"
+
+// Forecast item kinds
+const (
+	ForecastRecurringIncome = "recurring_income"
+	ForecastSubscription    = "subscription"
+	ForecastInstallment     = "installment"
+	ForecastBill            = "bill"
+)
+
+// CashFlowForecast projects income, expenses and balance over the coming months.
+// Low and High bound the confidence band given by Confidence (e.g. 0.8).
+type CashFlowForecast struct {
+	StartDate       time.Time               `json:"start_date"`
+	EndDate         time.Time               `json:"end_date"`
+	StartingBalance float64                 `json:"starting_balance"`
+	Confidence      float64                 `json:"confidence"`
+	Days            []ForecastDay           `json:"days"`
+	Months          []ForecastMonth         `json:"months"`
+	Scheduled       []ForecastItem          `json:"scheduled"`
+	Discretionary   []DiscretionaryForecast `json:"discretionary"`
+}
+
+// ForecastDay is the projected cash flow for a single day
+type ForecastDay struct {
+	Date    time.Time `json:"date"`
+	Income  float64   `json:"income"`
+	Expense float64   `json:"expense"`
+	Balance float64   `json:"balance"`
+	Low     float64   `json:"low"`
+	High    float64   `json:"high"`
+}
+
+// ForecastMonth is the projected cash flow for a calendar month (partial for
+// the first and last month)
+type ForecastMonth struct {
+	Month       string  `json:"month"` // YYYY-MM
+	Income      float64 `json:"income"`
+	Expense     float64 `json:"expense"`
+	Net         float64 `json:"net"`
+	ExpenseLow  float64 `json:"expense_low"`
+	ExpenseHigh float64 `json:"expense_high"`
+	EndBalance  float64 `json:"end_balance"`
+}
+
+// ForecastItem is a known future income or expense
+type ForecastItem struct {
+	Date        time.Time `json:"date"`
+	Kind        string    `json:"kind"`
+	Type        string    `json:"type"` // "income" or "expense"
+	Description string    `json:"description"`
+	Amount      float64   `json:"amount"`
+}
+
+// DiscretionaryForecast is the average monthly spend of a category, excluding
+// subscriptions and installments
+type DiscretionaryForecast struct {
+	CategoryID     *uint   `json:"category_id"`
+	CategoryName   string  `json:"category_name"`
+	MonthlyAverage float64 `json:"monthly_average"`
+	MonthlyStdDev  float64 `json:"monthly_std_dev"`
+}
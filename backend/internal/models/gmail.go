@@ -31,7 +31,12 @@ type GmailScanRule struct {
 	Enabled           bool           `gorm:"default:false" json:"enabled"`
 	SenderKeywords    pq.StringArray `gorm:"type:text[];default:'{\"credit\",\"信用卡\",\"帳單\",\"statement\"}'" json:"sender_keywords"`
 	SubjectKeywords   pq.StringArray `gorm:"type:text[];default:'{\"帳單\",\"電子帳單\",\"statement\"}'" json:"subject_keywords"`
-	RequireAttachment bool           `gorm:"default:true" json:"require_attachment"`
+	RequireAttachment bool           `json:"require_attachment"`                // no gorm default: it would store false as true on create
+	ScanOrders        bool           `json:"scan_orders"`                       // also scan shopping order emails (no attachment); no gorm default, as above
+	ApplyLabels       bool           `gorm:"default:false" json:"apply_labels"` // label processed messages (needs modify scope)
+	ImportedLabel     string         `gorm:"size:100;default:'BillingNote/Imported'" json:"imported_label"`
+	FailedLabel       string         `gorm:"size:100;default:'BillingNote/Failed'" json:"failed_label"`
+	ArchiveProcessed  bool           `gorm:"default:false" json:"archive_processed"` // remove imported messages from the inbox
 	LastScanAt        *time.Time     `json:"last_scan_at,omitempty"`
 	CreatedAt         time.Time      `json:"created_at"`
 	UpdatedAt         time.Time      `json:"updated_at"`
@@ -43,6 +48,25 @@ func (GmailScanRule) TableName() string {
 	return "gmail_scan_rules"
 }
 
+// GmailSenderRule routes mail from a specific sender to a bank parser and PDF password
+type GmailSenderRule struct {
+	ID               uint      `gorm:"primaryKey" json:"id"`
+	UserID           uint      `gorm:"not null;index" json:"user_id"`
+	Sender           string    `gorm:"not null;size:255" json:"sender"`    // address or domain, e.g. "cathaybk.com.tw"
+	BankHint         string    `gorm:"size:50" json:"bank_hint,omitempty"` // forced parser bank name, empty = auto-detect
+	PasswordPriority int       `gorm:"default:0" json:"password_priority"` // PDF password priority to try first, 0 = default order
+	Label            string    `gorm:"size:100" json:"label,omitempty"`    // Gmail label to apply after processing
+	Enabled          bool      `json:"enabled"`                            // no gorm default: it would store false as true on create
+	CreatedAt        time.Time `json:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at"`
+
+	User User `gorm:"foreignKey:UserID" json:"-"`
+}
+
+func (GmailSenderRule) TableName() string {
+	return "gmail_sender_rules"
+}
+
 // GmailScanHistory records each scan attempt
 type GmailScanHistory struct {
 	ID             uint      `gorm:"primaryKey" json:"id"`
@@ -65,6 +89,7 @@ type GmailStatusResponse struct {
 	Connected  bool       `json:"connected"`
 	Email      string     `json:"email,omitempty"`
 	Scopes     string     `json:"scopes,omitempty"`
+	CanModify  bool       `json:"can_modify"`
 	LastScanAt *time.Time `json:"last_scan_at,omitempty"`
 	ConnectedAt *time.Time `json:"connected_at,omitempty"`
 }
@@ -75,4 +100,18 @@ type GmailSettingsInput struct {
 	SenderKeywords    []string `json:"sender_keywords"`
 	SubjectKeywords   []string `json:"subject_keywords"`
 	RequireAttachment *bool    `json:"require_attachment"`
+	ScanOrders        *bool    `json:"scan_orders"`
+	ApplyLabels       *bool    `json:"apply_labels"`
+	ImportedLabel     *string  `json:"imported_label"`
+	FailedLabel       *string  `json:"failed_label"`
+	ArchiveProcessed  *bool    `json:"archive_processed"`
+}
+
+// GmailSenderRuleInput represents input for creating/updating a sender rule
+type GmailSenderRuleInput struct {
+	Sender           string `json:"sender" binding:"required"`
+	BankHint         string `json:"bank_hint"`
+	PasswordPriority int    `json:"password_priority" binding:"min=0,max=4"`
+	Label            string `json:"label"`
+	Enabled          *bool  `json:"enabled"`
 }
@@ -0,0 +1,95 @@
+package models
+
+import "time"
+
+// Goal statuses
+const (
+	GoalStatusActive   = "active"
+	GoalStatusArchived = "archived"
+)
+
+// Goal progress, derived from contributions and the deadline
+const (
+	GoalProgressAchieved   = "achieved"
+	GoalProgressOnTrack    = "on_track"
+	GoalProgressBehind     = "behind"
+	GoalProgressOverdue    = "overdue"
+	GoalProgressNoDeadline = "no_deadline"
+)
+
+// Contribution sources
+const (
+	ContributionSourceManual = "manual"
+	ContributionSourceRule   = "rule"
+)
+
+// Goal is a savings target. Transactions on the linked account, or with the
+// linked tag, are recorded as contributions automatically.
+type Goal struct {
+	ID           uint       `gorm:"primaryKey" json:"id"`
+	UserID       uint       `gorm:"not null;index" json:"user_id"`
+	Name         string     `gorm:"not null;size:100" json:"name"`
+	TargetAmount float64    `gorm:"not null" json:"target_amount"`
+	StartDate    time.Time  `gorm:"type:date;not null" json:"start_date"`
+	Deadline     *time.Time `gorm:"type:date" json:"deadline,omitempty"`
+	AccountID    *uint      `gorm:"index" json:"account_id,omitempty"`
+	Tag          string     `gorm:"size:50" json:"tag,omitempty"`
+	Status       string     `gorm:"not null;size:20;default:active" json:"status"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+
+	User    User     `gorm:"foreignKey:UserID" json:"-"`
+	Account *Account `gorm:"foreignKey:AccountID" json:"account,omitempty"`
+}
+
+func (Goal) TableName() string {
+	return "goals"
+}
+
+// GoalContribution is money put toward (or, if negative, taken from) a goal
+type GoalContribution struct {
+	ID            uint      `gorm:"primaryKey" json:"id"`
+	GoalID        uint      `gorm:"not null;index;uniqueIndex:idx_goal_contribution_txn" json:"goal_id"`
+	UserID        uint      `gorm:"not null;index" json:"user_id"`
+	Amount        float64   `gorm:"not null" json:"amount"`
+	Date          time.Time `gorm:"type:date;not null" json:"date"`
+	Note          string    `gorm:"size:255" json:"note,omitempty"`
+	Source        string    `gorm:"not null;size:20;default:manual" json:"source"`
+	TransactionID *uint     `gorm:"uniqueIndex:idx_goal_contribution_txn" json:"transaction_id,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+func (GoalContribution) TableName() string {
+	return "goal_contributions"
+}
+
+// GoalProgress reports a goal's progress as of a date
+type GoalProgress struct {
+	Goal                  Goal    `json:"goal"`
+	Saved                 float64 `json:"saved"`
+	Remaining             float64 `json:"remaining"`
+	Percentage            float64 `json:"percentage"`
+	MonthsLeft            int     `json:"months_left"`
+	RequiredMonthlySaving float64 `json:"required_monthly_saving"`
+	AverageMonthlySaving  float64 `json:"average_monthly_saving"` // over the last 3 months
+	ContributedThisMonth  float64 `json:"contributed_this_month"`
+	Status                string  `json:"status"`
+}
+
+// GoalInput is the request body for creating or updating a goal
+type GoalInput struct {
+	Name         string  `json:"name" binding:"required"`
+	TargetAmount float64 `json:"target_amount" binding:"required,gt=0"`
+	StartDate    string  `json:"start_date"` // YYYY-MM-DD, defaults to today
+	Deadline     string  `json:"deadline"`   // YYYY-MM-DD, optional
+	AccountID    *uint   `json:"account_id"`
+	Tag          string  `json:"tag"`
+	Status       string  `json:"status"` // update only: active or archived
+}
+
+// ContributionInput is the request body for a manual contribution
+type ContributionInput struct {
+	Amount float64 `json:"amount" binding:"required,ne=0"`
+	Date   string  `json:"date"` // YYYY-MM-DD, defaults to today
+	Note   string  `json:"note"`
+}
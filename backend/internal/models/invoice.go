@@ -29,6 +29,49 @@ func (Invoice) TableName() string {
 	return "invoices"
 }
 
+// InvoiceSyncState tracks the scheduled carrier sync per user
+type InvoiceSyncState struct {
+	ID             uint       `gorm:"primaryKey" json:"id"`
+	UserID         uint       `gorm:"not null;uniqueIndex" json:"user_id"`
+	Enabled        bool       `json:"enabled"`                    // no gorm default: it would store false as true on create
+	LastSyncedDate *time.Time `json:"last_synced_date,omitempty"` // invoices are synced up to this date
+	LastSyncAt     *time.Time `json:"last_sync_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+
+	User User `gorm:"foreignKey:UserID" json:"-"`
+}
+
+func (InvoiceSyncState) TableName() string {
+	return "invoice_sync_states"
+}
+
+// InvoiceSyncHistory records each sync attempt
+type InvoiceSyncHistory struct {
+	ID              uint      `gorm:"primaryKey" json:"id"`
+	UserID          uint      `gorm:"not null;index" json:"user_id"`
+	SyncAt          time.Time `gorm:"default:now()" json:"sync_at"`
+	StartDate       time.Time `json:"start_date"`
+	EndDate         time.Time `json:"end_date"`
+	InvoicesFetched int       `gorm:"default:0" json:"invoices_fetched"`
+	InvoicesCreated int       `gorm:"default:0" json:"invoices_created"`
+	Source          string    `gorm:"default:'scheduled';size:20" json:"source"` // "scheduled" or "manual"
+	Status          string    `gorm:"default:'completed';size:20" json:"status"` // "completed", "partial", "error"
+	ErrorMessage    string    `json:"error_message,omitempty"`
+
+	User User `gorm:"foreignKey:UserID" json:"-"`
+}
+
+func (InvoiceSyncHistory) TableName() string {
+	return "invoice_sync_history"
+}
+
+// InvoiceCarrierUser is a user with an invoice carrier configured
+type InvoiceCarrierUser struct {
+	UserID         uint
+	InvoiceCarrier string
+}
+
 // InvoiceItem represents an item in an invoice
 type InvoiceItem struct {
 	Description string  `json:"description"`
@@ -48,6 +91,11 @@ type InvoiceSettingsInput struct {
 	InvoiceCarrier string `json:"invoice_carrier" binding:"required"`
 }
 
+// InvoiceSyncSettingsInput represents input for updating scheduled sync settings
+type InvoiceSyncSettingsInput struct {
+	Enabled *bool `json:"enabled" binding:"required"`
+}
+
 // ConfirmDuplicateRequest represents input for confirming a duplicate
 type ConfirmDuplicateRequest struct {
 	InvoiceID     uint `json:"invoice_id" binding:"required"`
@@ -0,0 +1,59 @@
+package models
+
+import "time"
+
+// Ledger member roles
+const (
+	LedgerRoleOwner  = "owner"  // manages the ledger and its members
+	LedgerRoleMember = "member" // reads and writes the ledger's data
+)
+
+// Ledger is a household ledger shared by its members. Transactions, budgets
+// and categories with a LedgerID belong to the ledger rather than to the user
+// who created them; their UserID records the member who created the entry.
+type Ledger struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	Name      string    `gorm:"not null;size:100" json:"name"`
+	OwnerID   uint      `gorm:"not null;index" json:"owner_id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	Members []LedgerMember `gorm:"foreignKey:LedgerID" json:"members,omitempty"`
+}
+
+func (Ledger) TableName() string {
+	return "ledgers"
+}
+
+// LedgerMember is a user's membership in a ledger
+type LedgerMember struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	LedgerID  uint      `gorm:"not null;uniqueIndex:idx_ledger_member" json:"ledger_id"`
+	UserID    uint      `gorm:"not null;uniqueIndex:idx_ledger_member;index" json:"user_id"`
+	Role      string    `gorm:"not null;size:20;default:member" json:"role"`
+	CreatedAt time.Time `json:"created_at"`
+
+	User User `gorm:"foreignKey:UserID" json:"user"`
+}
+
+func (LedgerMember) TableName() string {
+	return "ledger_members"
+}
+
+// LedgerInput is the request body for creating or renaming a ledger
+type LedgerInput struct {
+	Name string `json:"name" binding:"required,max=100"`
+}
+
+// LedgerMemberInput is the request body for adding a member by email
+type LedgerMemberInput struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// LedgerCategoryInput is the request body for a ledger's own category
+type LedgerCategoryInput struct {
+	Name  string `json:"name" binding:"required,max=50"`
+	Type  string `json:"type" binding:"required,oneof=income expense"`
+	Icon  string `json:"icon"`
+	Color string `json:"color"`
+}
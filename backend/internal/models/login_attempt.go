@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// LoginAttempt records a failed sign-in. Failures are kept per email, whether
+// or not it belongs to a user, and per client IP.
+type LoginAttempt struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	Email     string    `gorm:"size:255;not null;index" json:"email"`
+	IP        string    `gorm:"size:45;not null;index" json:"ip"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (LoginAttempt) TableName() string {
+	return "login_attempts"
+}
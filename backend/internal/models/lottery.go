@@ -0,0 +1,90 @@
+package models
+
+import (
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Prize tiers of the uniform invoice lottery (統一發票)
+const (
+	PrizeSpecial = "special" // 特別獎: all 8 digits match the special prize number
+	PrizeGrand   = "grand"   // 特獎: all 8 digits match the grand prize number
+	PrizeFirst   = "first"   // 頭獎: all 8 digits match a first prize number
+	PrizeSecond  = "second"  // 二獎: last 7 digits
+	PrizeThird   = "third"   // 三獎: last 6 digits
+	PrizeFourth  = "fourth"  // 四獎: last 5 digits
+	PrizeFifth   = "fifth"   // 五獎: last 4 digits
+	PrizeSixth   = "sixth"   // 六獎: last 3 digits (first prize or additional sixth prize)
+)
+
+// PrizeAmounts maps each tier to its prize in TWD
+var PrizeAmounts = map[string]float64{
+	PrizeSpecial: 10000000,
+	PrizeGrand:   2000000,
+	PrizeFirst:   200000,
+	PrizeSecond:  40000,
+	PrizeThird:   10000,
+	PrizeFourth:  4000,
+	PrizeFifth:   1000,
+	PrizeSixth:   200,
+}
+
+// InvoiceWinningNumbers stores the winning numbers of one bi-monthly period
+type InvoiceWinningNumbers struct {
+	ID                    uint           `gorm:"primaryKey" json:"id"`
+	Period                string         `gorm:"not null;uniqueIndex;size:5" json:"period"` // ROC year + even month, e.g. "11410" for Sep-Oct 2025
+	SpecialPrize          string         `gorm:"size:8" json:"special_prize"`
+	GrandPrize            string         `gorm:"size:8" json:"grand_prize"`
+	FirstPrizes           pq.StringArray `gorm:"type:text[]" json:"first_prizes"`
+	AdditionalSixthPrizes pq.StringArray `gorm:"type:text[]" json:"additional_sixth_prizes"`
+	Source                string         `gorm:"size:20" json:"source"` // "import" or "api"
+	CreatedAt             time.Time      `json:"created_at"`
+	UpdatedAt             time.Time      `json:"updated_at"`
+}
+
+func (InvoiceWinningNumbers) TableName() string {
+	return "invoice_winning_numbers"
+}
+
+// InvoicePrize records a winning invoice
+type InvoicePrize struct {
+	ID            uint      `gorm:"primaryKey" json:"id"`
+	UserID        uint      `gorm:"not null;index" json:"user_id"`
+	InvoiceID     uint      `gorm:"not null;uniqueIndex" json:"invoice_id"`
+	Period        string    `gorm:"not null;size:5" json:"period"`
+	Tier          string    `gorm:"not null;size:20" json:"tier"`
+	Amount        float64   `gorm:"type:decimal(12,2);not null" json:"amount"`
+	TransactionID *uint     `json:"transaction_id,omitempty"` // income transaction, if recorded
+	CreatedAt     time.Time `json:"created_at"`
+
+	User    User    `gorm:"foreignKey:UserID" json:"-"`
+	Invoice Invoice `gorm:"foreignKey:InvoiceID" json:"invoice,omitempty"`
+}
+
+func (InvoicePrize) TableName() string {
+	return "invoice_prizes"
+}
+
+// WinningNumbersInput represents winning numbers imported from a file or request body
+type WinningNumbersInput struct {
+	Period                string   `json:"period" binding:"required"`
+	SpecialPrize          string   `json:"special_prize"`
+	GrandPrize            string   `json:"grand_prize"`
+	FirstPrizes           []string `json:"first_prizes"`
+	AdditionalSixthPrizes []string `json:"additional_sixth_prizes"`
+}
+
+// LotteryCheckRequest represents input for checking a period
+type LotteryCheckRequest struct {
+	Period       string `json:"period" binding:"required"`
+	RecordIncome bool   `json:"record_income"`
+}
+
+// LotteryCheckResult represents the outcome of checking a period
+type LotteryCheckResult struct {
+	Period      string         `json:"period"`
+	Checked     int            `json:"checked"`
+	Prizes      []InvoicePrize `json:"prizes"`
+	TotalAmount float64        `json:"total_amount"`
+}
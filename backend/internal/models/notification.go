@@ -0,0 +1,68 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Notification types
+const (
+	NotificationBudgetAlert  = "budget_alert"
+	NotificationBillReminder = "bill_reminder"
+	NotificationBillOverdue  = "bill_overdue"
+)
+
+// Notification is an in-app notification; it may also be delivered through
+// external channels such as email or a webhook
+type Notification struct {
+	ID        uint            `gorm:"primaryKey" json:"id"`
+	UserID    uint            `gorm:"not null;index" json:"user_id"`
+	Type      string          `gorm:"not null;size:50" json:"type"`
+	Title     string          `gorm:"not null;size:255" json:"title"`
+	Message   string          `gorm:"type:text" json:"message"`
+	Data      json.RawMessage `gorm:"type:jsonb" json:"data,omitempty"`
+	ReadAt    *time.Time      `json:"read_at,omitempty"`
+	CreatedAt time.Time       `json:"created_at"`
+
+	User User `gorm:"foreignKey:UserID" json:"-"`
+}
+
+func (Notification) TableName() string {
+	return "notifications"
+}
+
+// NotificationSettings stores a user's external delivery channel preferences
+type NotificationSettings struct {
+	UserID         uint      `gorm:"primaryKey" json:"user_id"`
+	EmailEnabled   bool      `gorm:"not null;default:false" json:"email_enabled"`
+	WebhookEnabled bool      `gorm:"not null;default:false" json:"webhook_enabled"`
+	WebhookURL     string    `gorm:"size:500" json:"webhook_url"`
+	WebhookSecret  string    `gorm:"size:255" json:"-"` // signs webhook payloads when set
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+func (NotificationSettings) TableName() string {
+	return "notification_settings"
+}
+
+// NotificationSettingsInput is the request body for updating notification settings
+type NotificationSettingsInput struct {
+	EmailEnabled   *bool   `json:"email_enabled"`
+	WebhookEnabled *bool   `json:"webhook_enabled"`
+	WebhookURL     *string `json:"webhook_url"`
+	WebhookSecret  *string `json:"webhook_secret"`
+}
+
+// BudgetAlert records a threshold alert already sent for a budget period
+type BudgetAlert struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	BudgetID    uint      `gorm:"not null;uniqueIndex:idx_budget_alert_period" json:"budget_id"`
+	PeriodStart time.Time `gorm:"type:date;not null;uniqueIndex:idx_budget_alert_period" json:"period_start"`
+	Threshold   int       `gorm:"not null;uniqueIndex:idx_budget_alert_period" json:"threshold"` // percent
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+func (BudgetAlert) TableName() string {
+	return "budget_alerts"
+}
@@ -0,0 +1,44 @@
+package models
+
+import "time"
+
+// Session is a signed-in device. It holds the hash of the current refresh
+// token, which is rotated on every refresh; the previous hash is kept so a
+// replayed token can be detected.
+type Session struct {
+	ID                uint       `gorm:"primaryKey" json:"id"`
+	UserID            uint       `gorm:"not null;index" json:"user_id"`
+	RefreshTokenHash  string     `gorm:"not null;uniqueIndex;size:64" json:"-"`
+	PreviousTokenHash string     `gorm:"size:64;index" json:"-"`
+	UserAgent         string     `gorm:"size:255" json:"user_agent"`
+	IP                string     `gorm:"size:45" json:"ip"`
+	LastSeenAt        time.Time  `json:"last_seen_at"`
+	ExpiresAt         time.Time  `gorm:"not null" json:"expires_at"`
+	RevokedAt         *time.Time `json:"revoked_at,omitempty"`
+	VerifiedAt        *time.Time `json:"-"` // last two-factor login or re-verification
+	CreatedAt         time.Time  `json:"created_at"`
+
+	// Current marks the session making the request when listing sessions
+	Current bool `gorm:"-" json:"current"`
+}
+
+func (Session) TableName() string {
+	return "sessions"
+}
+
+// Active reports whether the session can still be used at the given time
+func (s *Session) Active(now time.Time) bool {
+	return s.RevokedAt == nil && now.Before(s.ExpiresAt)
+}
+
+// VerifiedWithin reports whether the user re-confirmed their identity on
+// this session within the window before now
+func (s *Session) VerifiedWithin(now time.Time, window time.Duration) bool {
+	return s.VerifiedAt != nil && now.Sub(*s.VerifiedAt) <= window
+}
+
+// ClientInfo describes the device and address a request came from
+type ClientInfo struct {
+	UserAgent string
+	IP        string
+}
@@ -1,11 +1,34 @@
 package models
 
-import "time"
+import (
+	"time"
 
+	"github.com/lib/pq"
+)
+
+// Sharing roles, from least to most privileged
+const (
+	ShareRoleViewer      = "viewer"      // read only
+	ShareRoleContributor = "contributor" // read and add transactions
+	ShareRoleEditor      = "editor"      // read and write everything shared
+)
+
+// Pairing request statuses
+const (
+	PairingRequestPending  = "pending"
+	PairingRequestApproved = "approved"
+	PairingRequestRejected = "rejected"
+)
+
+// UserPairingCode is the user's current invitation; it stops working once it
+// expires or has been used MaxUses times
 type UserPairingCode struct {
 	ID        uint      `gorm:"primaryKey" json:"id"`
 	UserID    uint      `gorm:"not null;uniqueIndex" json:"user_id"`
 	Code      string    `gorm:"not null;uniqueIndex;size:9" json:"code"`
+	ExpiresAt time.Time `gorm:"not null" json:"expires_at"`
+	MaxUses   int       `gorm:"not null;default:1" json:"max_uses"`
+	UseCount  int       `gorm:"not null;default:0" json:"use_count"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 }
@@ -14,11 +37,61 @@ func (UserPairingCode) TableName() string {
 	return "user_pairing_codes"
 }
 
-type SharedAccess struct {
+// Usable reports whether the code can still be redeemed at the given time
+func (c *UserPairingCode) Usable(now time.Time) bool {
+	return now.Before(c.ExpiresAt) && c.UseCount < c.MaxUses
+}
+
+// PairingCodeInput configures a newly generated pairing code; zero values
+// select the defaults
+type PairingCodeInput struct {
+	ExpiresInHours int `json:"expires_in_hours" binding:"omitempty,min=1,max=720"`
+	MaxUses        int `json:"max_uses" binding:"omitempty,min=1,max=20"`
+}
+
+// PairingRequest is a redeemed pairing code waiting for the owner's approval
+type PairingRequest struct {
+	ID          uint       `gorm:"primaryKey" json:"id"`
+	OwnerID     uint       `gorm:"not null;index" json:"owner_id"`
+	RequesterID uint       `gorm:"not null;index" json:"requester_id"`
+	Status      string     `gorm:"not null;size:20;default:pending" json:"status"`
+	DecidedAt   *time.Time `json:"decided_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+
+	Owner     User `gorm:"foreignKey:OwnerID" json:"owner,omitempty"`
+	Requester User `gorm:"foreignKey:RequesterID" json:"requester,omitempty"`
+}
+
+func (PairingRequest) TableName() string {
+	return "pairing_requests"
+}
+
+// PairingAttempt records a failed attempt to redeem a pairing code
+type PairingAttempt struct {
 	ID        uint      `gorm:"primaryKey" json:"id"`
-	OwnerID   uint      `gorm:"not null;index" json:"owner_id"`
-	ViewerID  uint      `gorm:"not null;index" json:"viewer_id"`
+	UserID    uint      `gorm:"not null;index" json:"user_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (PairingAttempt) TableName() string {
+	return "pairing_attempts"
+}
+
+type SharedAccess struct {
+	ID       uint   `gorm:"primaryKey" json:"id"`
+	OwnerID  uint   `gorm:"not null;index" json:"owner_id"`
+	ViewerID uint   `gorm:"not null;index" json:"viewer_id"`
+	Role     string `gorm:"not null;size:20;default:viewer" json:"role"`
+
+	// Optional scope; empty fields do not restrict
+	ScopeStartDate   *time.Time    `gorm:"type:date" json:"scope_start_date,omitempty"`
+	ScopeEndDate     *time.Time    `gorm:"type:date" json:"scope_end_date,omitempty"`
+	ScopeCategoryIDs pq.Int64Array `gorm:"type:integer[]" json:"scope_category_ids,omitempty"`
+	ScopeAccountIDs  pq.Int64Array `gorm:"type:integer[]" json:"scope_account_ids,omitempty"`
+	HideDescriptions bool          `gorm:"not null;default:false" json:"hide_descriptions"`
+
 	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
 
 	Owner  User `gorm:"foreignKey:OwnerID" json:"owner,omitempty"`
 	Viewer User `gorm:"foreignKey:ViewerID" json:"viewer,omitempty"`
@@ -27,3 +100,87 @@ type SharedAccess struct {
 func (SharedAccess) TableName() string {
 	return "shared_access"
 }
+
+// Scope returns the restrictions on the shared data, or nil if everything is shared
+func (a *SharedAccess) Scope() *ShareScope {
+	scope := &ShareScope{
+		StartDate:        a.ScopeStartDate,
+		EndDate:          a.ScopeEndDate,
+		HideDescriptions: a.HideDescriptions,
+	}
+	for _, id := range a.ScopeCategoryIDs {
+		scope.CategoryIDs = append(scope.CategoryIDs, uint(id))
+	}
+	for _, id := range a.ScopeAccountIDs {
+		scope.AccountIDs = append(scope.AccountIDs, uint(id))
+	}
+	if scope.StartDate == nil && scope.EndDate == nil && len(scope.CategoryIDs) == 0 &&
+		len(scope.AccountIDs) == 0 && !scope.HideDescriptions {
+		return nil
+	}
+	return scope
+}
+
+// ShareScope restricts which transactions a shared user sees
+type ShareScope struct {
+	StartDate        *time.Time
+	EndDate          *time.Time
+	CategoryIDs      []uint
+	AccountIDs       []uint
+	HideDescriptions bool
+}
+
+// AllowsTransaction reports whether the transaction falls within the scope
+func (s *ShareScope) AllowsTransaction(t *Transaction) bool {
+	if s == nil {
+		return true
+	}
+	date := t.TransactionDate
+	if s.StartDate != nil && date.Before(*s.StartDate) {
+		return false
+	}
+	if s.EndDate != nil && !date.Before(s.EndDate.AddDate(0, 0, 1)) {
+		return false
+	}
+	if len(s.CategoryIDs) > 0 && (t.CategoryID == nil || !containsUint(s.CategoryIDs, *t.CategoryID)) {
+		return false
+	}
+	if len(s.AccountIDs) > 0 {
+		from := t.AccountID != nil && containsUint(s.AccountIDs, *t.AccountID)
+		to := t.ToAccountID != nil && containsUint(s.AccountIDs, *t.ToAccountID)
+		if !from && !to {
+			return false
+		}
+	}
+	return true
+}
+
+// Redact removes the free-text details of a transaction when the scope hides them
+func (s *ShareScope) Redact(t *Transaction) {
+	if s == nil || !s.HideDescriptions {
+		return
+	}
+	t.Description = ""
+	t.Merchant = ""
+	t.OrderNumber = ""
+	t.Items = nil
+}
+
+func containsUint(ids []uint, id uint) bool {
+	for _, v := range ids {
+		if v == id {
+			return true
+		}
+	}
+	return false
+}
+
+// ShareAccessInput is the request body for changing a viewer's role and scope
+type ShareAccessInput struct {
+	Role             string  `json:"role" binding:"required,oneof=viewer contributor editor"`
+	ScopeStartDate   string  `json:"scope_start_date"` // YYYY-MM-DD
+	ScopeEndDate     string  `json:"scope_end_date"`   // YYYY-MM-DD
+	ScopeCategoryIDs []int64 `json:"scope_category_ids"`
+	ScopeAccountIDs  []int64 `json:"scope_account_ids"`
+	HideDescriptions bool    `json:"hide_descriptions"`
+}
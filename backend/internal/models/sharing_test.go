@@ -0,0 +1,68 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSharedAccess_ScopeUnrestricted(t *testing.T) {
+	access := SharedAccess{Role: ShareRoleEditor}
+	assert.Nil(t, access.Scope())
+}
+
+func TestShareScope_AllowsTransaction(t *testing.T) {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2026, 3, 31, 0, 0, 0, 0, time.UTC)
+	access := SharedAccess{
+		ScopeStartDate:   &start,
+		ScopeEndDate:     &end,
+		ScopeCategoryIDs: []int64{3},
+		ScopeAccountIDs:  []int64{7},
+	}
+	scope := access.Scope()
+
+	food, other := uint(3), uint(4)
+	card, bank := uint(7), uint(8)
+	inScope := Transaction{CategoryID: &food, AccountID: &card, TransactionDate: time.Date(2026, 3, 31, 18, 0, 0, 0, time.UTC)}
+	assert.True(t, scope.AllowsTransaction(&inScope))
+
+	tooLate := inScope
+	tooLate.TransactionDate = time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC)
+	assert.False(t, scope.AllowsTransaction(&tooLate))
+
+	otherCategory := inScope
+	otherCategory.CategoryID = &other
+	assert.False(t, scope.AllowsTransaction(&otherCategory))
+
+	uncategorized := inScope
+	uncategorized.CategoryID = nil
+	assert.False(t, scope.AllowsTransaction(&uncategorized))
+
+	// Transfers match on either account
+	transfer := inScope
+	transfer.AccountID = &bank
+	transfer.ToAccountID = &card
+	assert.True(t, scope.AllowsTransaction(&transfer))
+	transfer.ToAccountID = nil
+	assert.False(t, scope.AllowsTransaction(&transfer))
+}
+
+func TestShareScope_Redact(t *testing.T) {
+	scope := (&SharedAccess{HideDescriptions: true}).Scope()
+	txn := Transaction{Description: "Dinner", Merchant: "Bistro", OrderNumber: "A1", Amount: 500}
+
+	scope.Redact(&txn)
+
+	assert.Empty(t, txn.Description)
+	assert.Empty(t, txn.Merchant)
+	assert.Empty(t, txn.OrderNumber)
+	assert.Equal(t, 500.0, txn.Amount)
+
+	// A nil scope leaves the transaction alone
+	txn.Description = "Dinner"
+	var unrestricted *ShareScope
+	unrestricted.Redact(&txn)
+	assert.Equal(t, "Dinner", txn.Description)
+}
@@ -0,0 +1,108 @@
+package models
+
+import "time"
+
+// Split methods
+const (
+	SplitMethodEqual   = "equal"   // the amount is divided evenly
+	SplitMethodPercent = "percent" // each share is a percentage of the amount
+	SplitMethodExact   = "exact"   // each share is an exact amount
+)
+
+// TransactionSplit divides an expense among paired users. The transaction's
+// owner paid the whole amount; every other share is owed to them.
+type TransactionSplit struct {
+	ID            uint      `gorm:"primaryKey" json:"id"`
+	TransactionID uint      `gorm:"not null;uniqueIndex" json:"transaction_id"`
+	PayerID       uint      `gorm:"not null;index" json:"payer_id"`
+	Method        string    `gorm:"not null;size:20" json:"method"`
+	Amount        float64   `gorm:"not null" json:"amount"`
+	Date          time.Time `gorm:"type:date;not null" json:"date"`
+	Description   string    `gorm:"size:255" json:"description"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+
+	Shares []SplitShare `gorm:"foreignKey:SplitID" json:"shares"`
+}
+
+func (TransactionSplit) TableName() string {
+	return "transaction_splits"
+}
+
+// SplitShare is one participant's part of a split
+type SplitShare struct {
+	ID      uint     `gorm:"primaryKey" json:"id"`
+	SplitID uint     `gorm:"not null;uniqueIndex:idx_split_share_user" json:"split_id"`
+	UserID  uint     `gorm:"not null;uniqueIndex:idx_split_share_user;index" json:"user_id"`
+	Amount  float64  `gorm:"not null" json:"amount"`
+	Percent *float64 `json:"percent,omitempty"` // percent splits only
+}
+
+func (SplitShare) TableName() string {
+	return "split_shares"
+}
+
+// Settlement records money paid from one user to another to clear what they owe
+type Settlement struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	FromUserID  uint      `gorm:"not null;index" json:"from_user_id"`
+	ToUserID    uint      `gorm:"not null;index" json:"to_user_id"`
+	Amount      float64   `gorm:"not null" json:"amount"`
+	Date        time.Time `gorm:"type:date;not null" json:"date"`
+	Note        string    `gorm:"size:255" json:"note,omitempty"`
+	CreatedByID uint      `gorm:"not null" json:"created_by_id"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+func (Settlement) TableName() string {
+	return "settlements"
+}
+
+// SplitInput is the request body for splitting a transaction
+type SplitInput struct {
+	Method string            `json:"method" binding:"required,oneof=equal percent exact"`
+	Shares []SplitShareInput `json:"shares" binding:"required,min=1,dive"`
+}
+
+// SplitShareInput is one participant in a split. Percent is required for
+// percent splits and Amount for exact splits; equal splits use neither.
+type SplitShareInput struct {
+	UserID  uint     `json:"user_id" binding:"required"`
+	Percent *float64 `json:"percent"`
+	Amount  *float64 `json:"amount"`
+}
+
+// SettlementInput is the request body for recording a settlement. The current
+// user must be the payer or the recipient.
+type SettlementInput struct {
+	FromUserID uint     `json:"from_user_id" binding:"required"`
+	ToUserID   uint     `json:"to_user_id" binding:"required"`
+	Amount     *float64 `json:"amount" binding:"required"`
+	Date       string   `json:"date"` // YYYY-MM-DD, defaults to today
+	Note       string   `json:"note"`
+}
+
+// SplitBalance is what another user owes the current user (negative when the
+// current user owes them)
+type SplitBalance struct {
+	UserID uint    `json:"user_id"`
+	Name   string  `json:"name"`
+	Email  string  `json:"email"`
+	Amount float64 `json:"amount"`
+}
+
+// SettleUpSuggestion is a payment that, with the others suggested, clears all
+// balances among the users involved with the fewest transfers
+type SettleUpSuggestion struct {
+	FromUserID uint    `json:"from_user_id"`
+	ToUserID   uint    `json:"to_user_id"`
+	Amount     float64 `json:"amount"`
+}
+
+// SplitSummary is the current user's balances with settle-up suggestions
+type SplitSummary struct {
+	Balances    []SplitBalance       `json:"balances"`
+	Suggestions []SettleUpSuggestion `json:"suggestions"`
+	// Net is what the current user is owed overall (negative when they owe)
+	Net float64 `json:"net"`
+}
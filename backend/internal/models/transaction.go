@@ -1,6 +1,7 @@
 package models
 
 import (
+	"encoding/json"
 	"time"
 
 	"github.com/lib/pq"
@@ -8,14 +9,20 @@ import (
 
 type Transaction struct {
 	ID              uint      `gorm:"primaryKey" json:"id"`
-	UserID          uint      `gorm:"not null;index" json:"user_id"`
+	UserID          uint      `gorm:"not null;index" json:"user_id"` // owner, or the member who created a ledger entry
+	LedgerID        *uint     `gorm:"index" json:"ledger_id,omitempty"` // household ledger; nil for personal
 	CategoryID      *uint     `gorm:"index" json:"category_id"`
+	AccountID       *uint     `gorm:"index" json:"account_id,omitempty"` // source account for transfers
+	ToAccountID     *uint     `gorm:"index" json:"to_account_id,omitempty"` // destination account for transfers
 	Amount          float64   `gorm:"not null" json:"amount"`
-	Type            string    `gorm:"not null;index" json:"type"` // "income" or "expense"
+	Type            string    `gorm:"not null;index" json:"type"` // "income", "expense" or "transfer"
 	Description     string    `json:"description"`
 	TransactionDate time.Time `gorm:"not null;index" json:"transaction_date"`
 	Source          string         `gorm:"default:manual" json:"source"` // "manual", "pdf", "gmail", "invoice"
 	Tags            pq.StringArray `gorm:"type:text[];default:'{}'" json:"tags"`
+	Merchant        string          `gorm:"size:255" json:"merchant,omitempty"`
+	OrderNumber     string          `gorm:"size:64;index" json:"order_number,omitempty"`
+	Items           json.RawMessage `gorm:"type:jsonb" json:"items,omitempty"`
 	CreatedAt       time.Time      `json:"created_at"`
 	UpdatedAt       time.Time `json:"updated_at"`
 
@@ -0,0 +1,68 @@
+package models
+
+import "time"
+
+// UserTwoFactor holds a user's TOTP enrollment. The secret is AES encrypted.
+// Until EnabledAt is set the enrollment is pending confirmation and does not
+// affect login.
+type UserTwoFactor struct {
+	UserID          uint       `gorm:"primaryKey" json:"-"`
+	SecretEncrypted string     `gorm:"not null" json:"-"`
+	EnabledAt       *time.Time `json:"enabled_at"`
+	// LastUsedStep is the TOTP time step of the last accepted code, so a code
+	// cannot be replayed
+	LastUsedStep   int64      `gorm:"not null;default:0" json:"-"`
+	FailedAttempts int        `gorm:"not null;default:0" json:"-"`
+	LastFailedAt   *time.Time `json:"-"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+func (UserTwoFactor) TableName() string {
+	return "user_two_factor"
+}
+
+// Enabled reports whether login requires the second factor
+func (t *UserTwoFactor) Enabled() bool {
+	return t.EnabledAt != nil
+}
+
+// RecoveryCode is a single-use code that stands in for a TOTP code when the
+// authenticator is lost. Only its hash is stored.
+type RecoveryCode struct {
+	ID        uint   `gorm:"primaryKey"`
+	UserID    uint   `gorm:"not null;index"`
+	CodeHash  string `gorm:"not null;size:64"`
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
+
+func (RecoveryCode) TableName() string {
+	return "recovery_codes"
+}
+
+// TwoFactorStatus is the user's two-factor state
+type TwoFactorStatus struct {
+	Enabled                bool       `json:"enabled"`
+	EnabledAt              *time.Time `json:"enabled_at,omitempty"`
+	RecoveryCodesRemaining int64      `json:"recovery_codes_remaining"`
+}
+
+// TwoFactorSetup is returned when enrollment starts, for the user to add to
+// an authenticator app
+type TwoFactorSetup struct {
+	Secret     string `json:"secret"`
+	OTPAuthURL string `json:"otpauth_url"`
+}
+
+// TwoFactorCodeInput carries a TOTP or recovery code
+type TwoFactorCodeInput struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// ReverifyInput re-confirms the user before a sensitive action: a TOTP or
+// recovery code when two-factor is enabled, the password otherwise
+type ReverifyInput struct {
+	Code     string `json:"code"`
+	Password string `json:"password"`
+}
@@ -8,12 +8,14 @@ import (
 )
 
 type User struct {
-	ID           uint      `gorm:"primaryKey" json:"id"`
-	Email        string    `gorm:"uniqueIndex;not null" json:"email"`
-	PasswordHash string    `gorm:"not null" json:"-"`
-	Name         string    `json:"name"`
-	CreatedAt    time.Time `json:"created_at"`
-	UpdatedAt    time.Time `json:"updated_at"`
+	ID              uint       `gorm:"primaryKey" json:"id"`
+	Email           string     `gorm:"uniqueIndex;not null" json:"email"`
+	PasswordHash    string     `gorm:"not null" json:"-"`
+	Name            string     `json:"name"`
+	Timezone        string     `gorm:"size:64;not null;default:Asia/Taipei" json:"timezone"`
+	EmailVerifiedAt *time.Time `json:"email_verified_at"` // set once the verification link is followed
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
 }
 
 // BeforeCreate hook to hash password
@@ -0,0 +1,47 @@
+package models
+
+import "time"
+
+// User token purposes
+const (
+	UserTokenPasswordReset     = "password_reset"
+	UserTokenEmailVerification = "email_verification"
+)
+
+// UserToken is a time-limited, single-use token emailed to the user, for a
+// password reset or to verify their address. Only its hash is stored.
+type UserToken struct {
+	ID        uint      `gorm:"primaryKey"`
+	UserID    uint      `gorm:"not null;index"`
+	Purpose   string    `gorm:"size:32;not null"`
+	TokenHash string    `gorm:"size:64;not null;uniqueIndex"`
+	ExpiresAt time.Time `gorm:"not null"`
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
+
+func (UserToken) TableName() string {
+	return "user_tokens"
+}
+
+// ChangePasswordInput changes the password of a signed-in user
+type ChangePasswordInput struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	NewPassword     string `json:"new_password" binding:"required,min=6"`
+}
+
+// ForgotPasswordInput requests a password reset email
+type ForgotPasswordInput struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// ResetPasswordInput sets a new password with an emailed reset token
+type ResetPasswordInput struct {
+	Token       string `json:"token" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required,min=6"`
+}
+
+// VerifyEmailInput carries an emailed verification token
+type VerifyEmailInput struct {
+	Token string `json:"token" binding:"required"`
+}
@@ -167,6 +167,95 @@ func (r *ParserRegistry) Parse(pdfPath string, passwords []string) ([]Transactio
 	return nil, "", errors.New("no suitable parser found for this PDF")
 }
 
+// BankNames returns the names of all registered parsers
+func (r *ParserRegistry) BankNames() []string {
+	names := make([]string, 0, len(r.parsers))
+	for _, parser := range r.parsers {
+		names = append(names, parser.BankName())
+	}
+	return names
+}
+
+// FindParser returns the parser registered under the given bank name, or nil
+func (r *ParserRegistry) FindParser(bankName string) BankParser {
+	for _, parser := range r.parsers {
+		if strings.EqualFold(parser.BankName(), bankName) {
+			return parser
+		}
+	}
+	return nil
+}
+
+// ParseWithBank parses a PDF file with a specific bank parser, skipping
+// content detection. An empty bank name falls back to Parse.
+func (r *ParserRegistry) ParseWithBank(pdfPath string, passwords []string, bankName string) ([]Transaction, string, error) {
+	if bankName == "" {
+		return r.Parse(pdfPath, passwords)
+	}
+
+	parser := r.FindParser(bankName)
+	if parser == nil {
+		return nil, "", fmt.Errorf("unknown bank parser: %s", bankName)
+	}
+
+	content, err := r.ExtractText(pdfPath, passwords)
+	if err != nil {
+		return nil, "", err
+	}
+
+	transactions, err := parser.Parse(content)
+	if err != nil {
+		return nil, parser.BankName(), fmt.Errorf("parser error: %w", err)
+	}
+	return transactions, parser.BankName(), nil
+}
+
+// Statement is a parsed PDF statement
+type Statement struct {
+	Bank         string
+	Transactions []Transaction
+	Summary      *StatementSummary // nil when the statement has no due date
+}
+
+// ParseStatement parses a PDF file like ParseWithBank and also extracts the
+// statement's payment summary
+func (r *ParserRegistry) ParseStatement(pdfPath string, passwords []string, bankName string) (*Statement, error) {
+	var parser BankParser
+	if bankName != "" {
+		parser = r.FindParser(bankName)
+		if parser == nil {
+			return nil, fmt.Errorf("unknown bank parser: %s", bankName)
+		}
+	}
+
+	content, err := r.ExtractText(pdfPath, passwords)
+	if err != nil {
+		return nil, err
+	}
+
+	if parser == nil {
+		for _, p := range r.parsers {
+			if p.CanParse(content) {
+				parser = p
+				break
+			}
+		}
+		if parser == nil {
+			return nil, errors.New("no suitable parser found for this PDF")
+		}
+	}
+
+	transactions, err := parser.Parse(content)
+	if err != nil {
+		return &Statement{Bank: parser.BankName()}, fmt.Errorf("parser error: %w", err)
+	}
+	return &Statement{
+		Bank:         parser.BankName(),
+		Transactions: transactions,
+		Summary:      ExtractStatementSummary(content),
+	}, nil
+}
+
 // ParseWithAutoPassword parses a PDF file using auto-detected passwords
 func (r *ParserRegistry) ParseWithAutoPassword(pdfPath string) ([]Transaction, string, error) {
 	// Get filename for password lookup
@@ -81,6 +81,26 @@ func TestParserRegistry_RegisterParser(t *testing.T) {
 	assert.Len(t, registry.parsers, 1)
 }
 
+func TestParserRegistry_FindParser(t *testing.T) {
+	registry := NewParserRegistry()
+	registry.RegisterParser(&mockBankParser{name: "Test Bank"})
+	registry.RegisterParser(&mockBankParser{name: "國泰世華"})
+
+	assert.Equal(t, []string{"Test Bank", "國泰世華"}, registry.BankNames())
+	assert.NotNil(t, registry.FindParser("test bank"))
+	assert.NotNil(t, registry.FindParser("國泰世華"))
+	assert.Nil(t, registry.FindParser("Unknown"))
+}
+
+func TestParserRegistry_ParseWithBank_Unknown(t *testing.T) {
+	registry := NewParserRegistry()
+	registry.RegisterParser(&mockBankParser{name: "Test Bank"})
+
+	_, _, err := registry.ParseWithBank("/nonexistent.pdf", nil, "Unknown")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "unknown bank parser")
+}
+
 func TestParserRegistry_GetPasswordsForFile(t *testing.T) {
 	registry := NewParserRegistry()
 	registry.fileNameRules = []FileNameRule{
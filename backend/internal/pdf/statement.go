@@ -0,0 +1,170 @@
+package pdf
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// StatementSummary holds the payment details printed on a card statement
+type StatementSummary struct {
+	ClosingDate *time.Time `json:"closing_date,omitempty"`
+	DueDate     *time.Time `json:"due_date,omitempty"`
+	AmountDue   float64    `json:"amount_due"`
+	MinimumDue  float64    `json:"minimum_due"`
+}
+
+var (
+	// 2025/01/15, 2025-01-15, 114/01/15 (ROC year), 2025年01月15日
+	statementDatePattern   = regexp.MustCompile(`(\d{3,4})\s*[/\-.年]\s*(\d{1,2})\s*[/\-.月]\s*(\d{1,2})`)
+	statementAmountPattern = regexp.MustCompile(`-?[\d,]+(?:\.\d+)?`)
+
+	dueDateLabels     = []string{"繳款截止日", "繳款期限", "Payment Due Date"}
+	closingDateLabels = []string{"帳單結帳日", "結帳日", "Statement Date"}
+	amountDueLabels   = []string{"本期應繳總金額", "本期應繳總額", "本期應繳金額", "本期帳單總額", "應繳總金額", "Total Amount Due"}
+	minimumDueLabels  = []string{"最低應繳金額", "本期最低應繳", "Minimum Payment"}
+)
+
+// ExtractStatementSummary finds the due date and amounts on a card statement.
+// Each label is looked up on its own line first, then on the following line,
+// which covers pdftotext layouts that print headers above their values.
+// Returns nil when no due date is found.
+func ExtractStatementSummary(content string) *StatementSummary {
+	lines := strings.Split(content, "\n")
+
+	dueDate := findLabeledDate(lines, dueDateLabels)
+	if dueDate == nil {
+		return nil
+	}
+
+	summary := &StatementSummary{
+		DueDate:     dueDate,
+		ClosingDate: findLabeledDate(lines, closingDateLabels),
+	}
+	summary.AmountDue, _ = findLabeledAmount(lines, amountDueLabels)
+	summary.MinimumDue, _ = findLabeledAmount(lines, minimumDueLabels)
+	return summary
+}
+
+// findLabeledDate returns the first date following any of the labels
+func findLabeledDate(lines []string, labels []string) *time.Time {
+	for i, line := range lines {
+		for _, label := range labels {
+			idx := strings.Index(line, label)
+			if idx < 0 {
+				continue
+			}
+			if d := parseStatementDate(line[idx+len(label):]); d != nil {
+				return d
+			}
+			if below := valueBelow(lines, i, idx, label, statementDatePattern); below != "" {
+				if d := parseStatementDate(below); d != nil {
+					return d
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// findLabeledAmount returns the first amount following any of the labels
+func findLabeledAmount(lines []string, labels []string) (float64, bool) {
+	for i, line := range lines {
+		for _, label := range labels {
+			idx := strings.Index(line, label)
+			if idx < 0 {
+				continue
+			}
+			if amount, ok := parseStatementAmount(line[idx+len(label):]); ok {
+				return amount, true
+			}
+			if below := valueBelow(lines, i, idx, label, statementAmountPattern); below != "" {
+				if amount, ok := parseStatementAmount(below); ok {
+					return amount, true
+				}
+			}
+		}
+	}
+	return 0, false
+}
+
+// valueBelow returns the value on the next non-empty line whose columns
+// overlap the label at byte offset idx of lines[i]
+func valueBelow(lines []string, i, idx int, label string, pattern *regexp.Regexp) string {
+	next := nextNonEmpty(lines, i)
+	if next == "" {
+		return ""
+	}
+	labelStart := displayWidth(lines[i][:idx])
+	labelEnd := labelStart + displayWidth(label)
+	for _, loc := range pattern.FindAllStringIndex(next, -1) {
+		valueStart := displayWidth(next[:loc[0]])
+		valueEnd := valueStart + displayWidth(next[loc[0]:loc[1]])
+		// Allow a couple of columns of drift between header and value
+		if valueEnd >= labelStart-2 && valueStart <= labelEnd+2 {
+			return next[loc[0]:loc[1]]
+		}
+	}
+	return ""
+}
+
+func displayWidth(s string) int {
+	width := 0
+	for _, r := range s {
+		width += runeWidth(r)
+	}
+	return width
+}
+
+// runeWidth approximates the pdftotext layout width of a rune: CJK characters
+// take two columns
+func runeWidth(r rune) int {
+	if r >= 0x1100 {
+		return 2
+	}
+	return 1
+}
+
+func nextNonEmpty(lines []string, i int) string {
+	for j := i + 1; j < len(lines); j++ {
+		if strings.TrimSpace(lines[j]) != "" {
+			return lines[j]
+		}
+	}
+	return ""
+}
+
+// parseStatementDate parses the first date in s, converting ROC years
+func parseStatementDate(s string) *time.Time {
+	m := statementDatePattern.FindStringSubmatch(s)
+	if m == nil {
+		return nil
+	}
+	year, _ := strconv.Atoi(m[1])
+	month, _ := strconv.Atoi(m[2])
+	day, _ := strconv.Atoi(m[3])
+	if year < 1000 {
+		year += 1911
+	}
+	if month < 1 || month > 12 || day < 1 || day > 31 {
+		return nil
+	}
+	d := time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC)
+	return &d
+}
+
+// parseStatementAmount parses the first amount in s
+func parseStatementAmount(s string) (float64, bool) {
+	for _, m := range statementAmountPattern.FindAllString(s, -1) {
+		cleaned := strings.ReplaceAll(m, ",", "")
+		if cleaned == "" || cleaned == "-" {
+			continue
+		}
+		amount, err := strconv.ParseFloat(cleaned, 64)
+		if err == nil {
+			return amount, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,68 @@
+package pdf
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExtractStatementSummary(t *testing.T) {
+	tests := []struct {
+		name       string
+		content    string
+		dueDate    time.Time
+		closing    *time.Time
+		amountDue  float64
+		minimumDue float64
+	}{
+		{
+			name: "inline labels with ROC dates",
+			content: `國泰世華銀行 信用卡帳單
+帳單結帳日：114/11/28
+繳款截止日：114/12/15
+本期應繳總金額：12,345
+最低應繳金額：1,235`,
+			dueDate:    time.Date(2025, 12, 15, 0, 0, 0, 0, time.UTC),
+			closing:    timePtr(time.Date(2025, 11, 28, 0, 0, 0, 0, time.UTC)),
+			amountDue:  12345,
+			minimumDue: 1235,
+		},
+		{
+			name: "header row above values",
+			content: `   帳單結帳日      繳款截止日      本期應繳總額      最低應繳金額
+   2025/11/28      2025/12/15        8,900.50          890`,
+			dueDate:    time.Date(2025, 12, 15, 0, 0, 0, 0, time.UTC),
+			closing:    timePtr(time.Date(2025, 11, 28, 0, 0, 0, 0, time.UTC)),
+			amountDue:  8900.50,
+			minimumDue: 890,
+		},
+		{
+			name:      "english labels",
+			content:   "Payment Due Date 2025-01-20\nTotal Amount Due 3,000",
+			dueDate:   time.Date(2025, 1, 20, 0, 0, 0, 0, time.UTC),
+			amountDue: 3000,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			summary := ExtractStatementSummary(tt.content)
+			require.NotNil(t, summary)
+			require.NotNil(t, summary.DueDate)
+			assert.Equal(t, tt.dueDate, *summary.DueDate)
+			assert.Equal(t, tt.closing, summary.ClosingDate)
+			assert.Equal(t, tt.amountDue, summary.AmountDue)
+			assert.Equal(t, tt.minimumDue, summary.MinimumDue)
+		})
+	}
+}
+
+func TestExtractStatementSummary_NoDueDate(t *testing.T) {
+	assert.Nil(t, ExtractStatementSummary("11/19   11/25   連加＊５０嵐     65    3842"))
+}
+
+func timePtr(t time.Time) *time.Time {
+	return &t
+}
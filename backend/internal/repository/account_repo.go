@@ -0,0 +1,59 @@
+package repository
+
+import (
+	"billing-note/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// AccountRepository defines the interface for account data access
+type AccountRepository interface {
+	Create(account *models.Account) error
+	List(userID uint) ([]models.Account, error)
+	GetByID(userID, id uint) (*models.Account, error)
+	Update(account *models.Account) error
+	Delete(userID, id uint) error
+}
+
+type accountRepository struct {
+	db *gorm.DB
+}
+
+// NewAccountRepository creates a new account repository
+func NewAccountRepository(db *gorm.DB) AccountRepository {
+	return &accountRepository{db: db}
+}
+
+func (r *accountRepository) Create(account *models.Account) error {
+	return r.db.Create(account).Error
+}
+
+func (r *accountRepository) List(userID uint) ([]models.Account, error) {
+	var accounts []models.Account
+	err := r.db.Where("user_id = ?", userID).Order("id ASC").Find(&accounts).Error
+	return accounts, err
+}
+
+func (r *accountRepository) GetByID(userID, id uint) (*models.Account, error) {
+	var account models.Account
+	err := r.db.Where("user_id = ? AND id = ?", userID, id).First(&account).Error
+	if err != nil {
+		return nil, err
+	}
+	return &account, nil
+}
+
+func (r *accountRepository) Update(account *models.Account) error {
+	return r.db.Save(account).Error
+}
+
+func (r *accountRepository) Delete(userID, id uint) error {
+	result := r.db.Where("user_id = ? AND id = ?", userID, id).Delete(&models.Account{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"billing-note/internal/models"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type APITokenRepository interface {
+	Create(token *models.APIToken) error
+	GetByID(id uint) (*models.APIToken, error)
+	FindByHash(hash string) (*models.APIToken, error)
+	// ListByUser returns the user's tokens that have not been revoked
+	ListByUser(userID uint) ([]models.APIToken, error)
+	// Touch records that the token was used
+	Touch(id uint, usedAt time.Time, ip string) error
+	Revoke(id uint, at time.Time) error
+	// RevokeAllByUser revokes every token of the user
+	RevokeAllByUser(userID uint, at time.Time) (int64, error)
+}
+
+type apiTokenRepository struct {
+	db *gorm.DB
+}
+
+func NewAPITokenRepository(db *gorm.DB) APITokenRepository {
+	return &apiTokenRepository{db: db}
+}
+
+func (r *apiTokenRepository) Create(token *models.APIToken) error {
+	return r.db.Create(token).Error
+}
+
+func (r *apiTokenRepository) GetByID(id uint) (*models.APIToken, error) {
+	var token models.APIToken
+	err := r.db.First(&token, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &token, nil
+}
+
+func (r *apiTokenRepository) FindByHash(hash string) (*models.APIToken, error) {
+	var token models.APIToken
+	err := r.db.Where("token_hash = ?", hash).First(&token).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &token, nil
+}
+
+func (r *apiTokenRepository) ListByUser(userID uint) ([]models.APIToken, error) {
+	var tokens []models.APIToken
+	err := r.db.Where("user_id = ? AND revoked_at IS NULL", userID).Order("created_at DESC").Find(&tokens).Error
+	return tokens, err
+}
+
+func (r *apiTokenRepository) Touch(id uint, usedAt time.Time, ip string) error {
+	return r.db.Model(&models.APIToken{}).Where("id = ?", id).
+		Updates(map[string]interface{}{"last_used_at": usedAt, "last_used_ip": ip}).Error
+}
+
+func (r *apiTokenRepository) Revoke(id uint, at time.Time) error {
+	result := r.db.Model(&models.APIToken{}).Where("id = ? AND revoked_at IS NULL", id).Update("revoked_at", at)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (r *apiTokenRepository) RevokeAllByUser(userID uint, at time.Time) (int64, error) {
+	result := r.db.Model(&models.APIToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", at)
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,59 @@
+package repository
+
+import (
+	"billing-note/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// BalanceRepository defines the interface for account balance snapshot data access
+type BalanceRepository interface {
+	// Upsert records the balance, replacing any snapshot of the account on the same date
+	Upsert(balance *models.AccountBalance) error
+	// ListByUser returns all of the user's snapshots, oldest first
+	ListByUser(userID uint) ([]models.AccountBalance, error)
+	// ListByAccount returns an account's snapshots, newest first
+	ListByAccount(userID, accountID uint) ([]models.AccountBalance, error)
+	Delete(userID, id uint) error
+}
+
+type balanceRepository struct {
+	db *gorm.DB
+}
+
+// NewBalanceRepository creates a new balance repository
+func NewBalanceRepository(db *gorm.DB) BalanceRepository {
+	return &balanceRepository{db: db}
+}
+
+func (r *balanceRepository) Upsert(balance *models.AccountBalance) error {
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "account_id"}, {Name: "date"}},
+		DoUpdates: clause.AssignmentColumns([]string{"balance", "source", "note", "updated_at"}),
+	}).Create(balance).Error
+}
+
+func (r *balanceRepository) ListByUser(userID uint) ([]models.AccountBalance, error) {
+	var balances []models.AccountBalance
+	err := r.db.Where("user_id = ?", userID).Order("date ASC, id ASC").Find(&balances).Error
+	return balances, err
+}
+
+func (r *balanceRepository) ListByAccount(userID, accountID uint) ([]models.AccountBalance, error) {
+	var balances []models.AccountBalance
+	err := r.db.Where("user_id = ? AND account_id = ?", userID, accountID).
+		Order("date DESC").Find(&balances).Error
+	return balances, err
+}
+
+func (r *balanceRepository) Delete(userID, id uint) error {
+	result := r.db.Where("user_id = ? AND id = ?", userID, id).Delete(&models.AccountBalance{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package repository
+
+import (
+	"billing-note/internal/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// BillRepository defines the interface for bill data access
+type BillRepository interface {
+	Create(bill *models.Bill) error
+	List(userID uint, status string) ([]models.Bill, error)
+	GetByID(userID, id uint) (*models.Bill, error)
+	Update(bill *models.Bill) error
+	Delete(userID, id uint) error
+	// FindByIssuerDueDate returns the bill for a statement, used to de-duplicate re-uploads
+	FindByIssuerDueDate(userID uint, issuer string, dueDate time.Time) (*models.Bill, error)
+	// ListOverdue returns pending bills due before the given date
+	ListOverdue(userID uint, before time.Time) ([]models.Bill, error)
+	// ListPendingDueBy returns every user's pending bills due on or before the given date
+	ListPendingDueBy(date time.Time) ([]models.Bill, error)
+	// FindPendingByAmount returns pending bills with the amount due, due within [from, to]
+	FindPendingByAmount(userID uint, amount float64, from, to time.Time) ([]models.Bill, error)
+}
+
+type billRepository struct {
+	db *gorm.DB
+}
+
+// NewBillRepository creates a new bill repository
+func NewBillRepository(db *gorm.DB) BillRepository {
+	return &billRepository{db: db}
+}
+
+func (r *billRepository) Create(bill *models.Bill) error {
+	return r.db.Create(bill).Error
+}
+
+func (r *billRepository) List(userID uint, status string) ([]models.Bill, error) {
+	var bills []models.Bill
+	query := r.db.Preload("Account").Where("user_id = ?", userID)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	err := query.Order("due_date DESC, id DESC").Find(&bills).Error
+	return bills, err
+}
+
+func (r *billRepository) GetByID(userID, id uint) (*models.Bill, error) {
+	var bill models.Bill
+	err := r.db.Preload("Account").Preload("PaymentTransaction").
+		Where("user_id = ? AND id = ?", userID, id).First(&bill).Error
+	if err != nil {
+		return nil, err
+	}
+	return &bill, nil
+}
+
+func (r *billRepository) Update(bill *models.Bill) error {
+	return r.db.Omit("Account", "PaymentTransaction").Save(bill).Error
+}
+
+func (r *billRepository) Delete(userID, id uint) error {
+	result := r.db.Where("user_id = ? AND id = ?", userID, id).Delete(&models.Bill{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (r *billRepository) FindByIssuerDueDate(userID uint, issuer string, dueDate time.Time) (*models.Bill, error) {
+	var bill models.Bill
+	err := r.db.Where("user_id = ? AND issuer = ? AND due_date = ?", userID, issuer, dueDate).
+		First(&bill).Error
+	if err != nil {
+		return nil, err
+	}
+	return &bill, nil
+}
+
+func (r *billRepository) ListOverdue(userID uint, before time.Time) ([]models.Bill, error) {
+	var bills []models.Bill
+	err := r.db.Preload("Account").
+		Where("user_id = ? AND status = ? AND due_date < ?", userID, models.BillStatusPending, before).
+		Order("due_date ASC").Find(&bills).Error
+	return bills, err
+}
+
+func (r *billRepository) ListPendingDueBy(date time.Time) ([]models.Bill, error) {
+	var bills []models.Bill
+	err := r.db.Preload("Account").
+		Where("status = ? AND due_date <= ?", models.BillStatusPending, date).
+		Where("reminded_at IS NULL OR overdue_notified_at IS NULL").
+		Order("user_id ASC, due_date ASC").Find(&bills).Error
+	return bills, err
+}
+
+func (r *billRepository) FindPendingByAmount(userID uint, amount float64, from, to time.Time) ([]models.Bill, error) {
+	var bills []models.Bill
+	err := r.db.Where("user_id = ? AND status = ? AND amount_due = ? AND due_date BETWEEN ? AND ?",
+		userID, models.BillStatusPending, amount, from, to).
+		Order("due_date ASC").Find(&bills).Error
+	return bills, err
+}
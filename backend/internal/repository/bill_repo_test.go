@@ -0,0 +1,37 @@
+package repository
+
+import (
+	"billing-note/internal/models"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBillRepository_Create_RemindOnDueDate(t *testing.T) {
+	db, mock := setupMockDB(t)
+	repo := NewBillRepository(db)
+
+	bill := &models.Bill{
+		UserID:      1,
+		Description: "Rent",
+		Source:      models.BillSourceManual,
+		DueDate:     time.Date(2025, 12, 15, 0, 0, 0, 0, time.UTC),
+		AmountDue:   100,
+		RemindDays:  0,
+		Status:      models.BillStatusPending,
+	}
+
+	// remind_days=0 must be written, not left to the column default
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "bills" ("user_id","account_id","issuer","description","source","statement_date","due_date","amount_due","minimum_due","remind_days",`)).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectCommit()
+
+	err := repo.Create(bill)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, bill.RemindDays)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -2,6 +2,7 @@ package repository
 
 import (
 	"billing-note/internal/models"
+	"time"
 
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
@@ -9,10 +10,23 @@ import (
 
 type BudgetRepository interface {
 	Create(budget *models.Budget) error
+	// List returns the user's personal budgets
 	List(userID uint) ([]models.Budget, error)
+	// ListByLedger returns a household ledger's budgets
+	ListByLedger(ledgerID uint) ([]models.Budget, error)
 	GetByID(id uint) (*models.Budget, error)
 	Update(budget *models.Budget) error
 	Delete(id uint) error
+
+	// Ledger operations
+	ListLedger(budgetID uint) ([]models.BudgetLedgerEntry, error)
+	SaveLedgerEntry(entry *models.BudgetLedgerEntry) error
+
+	// SumSpending totals the expenses a budget counts within [start, end]
+	SumSpending(budget *models.Budget, start, end time.Time) (float64, error)
+
+	// CreateAlert records a threshold alert, returning false if it was already sent
+	CreateAlert(alert *models.BudgetAlert) (bool, error)
 }
 
 type budgetRepository struct {
@@ -24,21 +38,33 @@ func NewBudgetRepository(db *gorm.DB) BudgetRepository {
 }
 
 func (r *budgetRepository) Create(budget *models.Budget) error {
-	return r.db.Clauses(clause.OnConflict{
-		Columns:   []clause.Column{{Name: "user_id"}, {Name: "category_id"}},
-		DoUpdates: clause.AssignmentColumns([]string{"monthly_amount", "updated_at"}),
-	}).Create(budget).Error
+	conflict := clause.OnConflict{
+		Columns:     []clause.Column{{Name: "user_id"}, {Name: "category_id"}, {Name: "period_type"}},
+		TargetWhere: clause.Where{Exprs: []clause.Expression{clause.Expr{SQL: "ledger_id IS NULL"}}},
+		DoUpdates:   clause.AssignmentColumns([]string{"monthly_amount", "start_day", "start_date", "end_date", "rollover", "alert_thresholds", "updated_at"}),
+	}
+	if budget.LedgerID != nil {
+		conflict.Columns = []clause.Column{{Name: "ledger_id"}, {Name: "category_id"}, {Name: "period_type"}}
+		conflict.TargetWhere = clause.Where{Exprs: []clause.Expression{clause.Expr{SQL: "ledger_id IS NOT NULL"}}}
+	}
+	return r.db.Clauses(conflict).Create(budget).Error
 }
 
 func (r *budgetRepository) List(userID uint) ([]models.Budget, error) {
 	var budgets []models.Budget
-	err := r.db.Preload("Category").Where("user_id = ?", userID).Find(&budgets).Error
+	err := r.db.Preload("Category").Preload("Account").Where("user_id = ? AND ledger_id IS NULL", userID).Find(&budgets).Error
+	return budgets, err
+}
+
+func (r *budgetRepository) ListByLedger(ledgerID uint) ([]models.Budget, error) {
+	var budgets []models.Budget
+	err := r.db.Preload("Category").Preload("Account").Where("ledger_id = ?", ledgerID).Find(&budgets).Error
 	return budgets, err
 }
 
 func (r *budgetRepository) GetByID(id uint) (*models.Budget, error) {
 	var budget models.Budget
-	err := r.db.Preload("Category").First(&budget, id).Error
+	err := r.db.Preload("Category").Preload("Account").First(&budget, id).Error
 	if err != nil {
 		return nil, err
 	}
@@ -52,3 +78,52 @@ func (r *budgetRepository) Update(budget *models.Budget) error {
 func (r *budgetRepository) Delete(id uint) error {
 	return r.db.Delete(&models.Budget{}, id).Error
 }
+
+// ListLedger returns a budget's ledger entries in chronological order
+func (r *budgetRepository) ListLedger(budgetID uint) ([]models.BudgetLedgerEntry, error) {
+	var entries []models.BudgetLedgerEntry
+	err := r.db.Where("budget_id = ?", budgetID).Order("period_start ASC").Find(&entries).Error
+	return entries, err
+}
+
+func (r *budgetRepository) SaveLedgerEntry(entry *models.BudgetLedgerEntry) error {
+	if entry.ID != 0 {
+		return r.db.Save(entry).Error
+	}
+	return r.db.Create(entry).Error
+}
+
+func (r *budgetRepository) SumSpending(budget *models.Budget, start, end time.Time) (float64, error) {
+	// Ledger budgets count every member's spending in the ledger
+	query := r.db.Model(&models.Transaction{})
+	if budget.LedgerID != nil {
+		query = query.Where("ledger_id = ?", *budget.LedgerID)
+	} else {
+		query = query.Where("user_id = ? AND ledger_id IS NULL", budget.UserID)
+	}
+	query = query.Where("type = ? AND transaction_date BETWEEN ? AND ?", "expense", start, end)
+
+	switch budget.TargetType {
+	case models.BudgetTargetOverall:
+	case models.BudgetTargetCategories:
+		query = query.Where("category_id = ANY(?)", budget.CategoryIDs)
+	case models.BudgetTargetTag:
+		query = query.Where("? = ANY(tags)", budget.Tag)
+	case models.BudgetTargetAccount:
+		query = query.Where("account_id = ?", budget.AccountID)
+	default:
+		query = query.Where("category_id = ?", budget.CategoryID)
+	}
+
+	var total float64
+	err := query.Select("COALESCE(SUM(amount), 0)").Scan(&total).Error
+	return total, err
+}
+
+func (r *budgetRepository) CreateAlert(alert *models.BudgetAlert) (bool, error) {
+	result := r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(alert)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
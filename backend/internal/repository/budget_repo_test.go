@@ -0,0 +1,112 @@
+package repository
+
+import (
+	"billing-note/internal/models"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBudgetRepository_SumSpending_Category(t *testing.T) {
+	db, mock := setupMockDB(t)
+	repo := NewBudgetRepository(db)
+
+	catID := uint(5)
+	budget := &models.Budget{UserID: 1, TargetType: models.BudgetTargetCategory, CategoryID: &catID}
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2026, 1, 31, 0, 0, 0, 0, time.UTC)
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT COALESCE(SUM(amount), 0) FROM "transactions" WHERE (user_id = $1 AND ledger_id IS NULL) AND (type = $2 AND transaction_date BETWEEN $3 AND $4) AND category_id = $5`)).
+		WithArgs(uint(1), "expense", start, end, catID).
+		WillReturnRows(sqlmock.NewRows([]string{"coalesce"}).AddRow(1500.0))
+
+	total, err := repo.SumSpending(budget, start, end)
+	assert.NoError(t, err)
+	assert.Equal(t, 1500.0, total)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestBudgetRepository_SumSpending_Overall(t *testing.T) {
+	db, mock := setupMockDB(t)
+	repo := NewBudgetRepository(db)
+
+	budget := &models.Budget{UserID: 1, TargetType: models.BudgetTargetOverall}
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2026, 1, 31, 0, 0, 0, 0, time.UTC)
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT COALESCE(SUM(amount), 0) FROM "transactions" WHERE (user_id = $1 AND ledger_id IS NULL) AND (type = $2 AND transaction_date BETWEEN $3 AND $4)`)).
+		WithArgs(uint(1), "expense", start, end).
+		WillReturnRows(sqlmock.NewRows([]string{"coalesce"}).AddRow(42000.0))
+
+	total, err := repo.SumSpending(budget, start, end)
+	assert.NoError(t, err)
+	assert.Equal(t, 42000.0, total)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestBudgetRepository_SumSpending_Tag(t *testing.T) {
+	db, mock := setupMockDB(t)
+	repo := NewBudgetRepository(db)
+
+	budget := &models.Budget{UserID: 1, TargetType: models.BudgetTargetTag, Tag: "japan-trip-2026"}
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2026, 1, 31, 0, 0, 0, 0, time.UTC)
+
+	mock.ExpectQuery(regexp.QuoteMeta(`AND $5 = ANY(tags)`)).
+		WithArgs(uint(1), "expense", start, end, "japan-trip-2026").
+		WillReturnRows(sqlmock.NewRows([]string{"coalesce"}).AddRow(0.0))
+
+	total, err := repo.SumSpending(budget, start, end)
+	assert.NoError(t, err)
+	assert.Equal(t, 0.0, total)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestBudgetRepository_SumSpending_Ledger(t *testing.T) {
+	db, mock := setupMockDB(t)
+	repo := NewBudgetRepository(db)
+
+	ledgerID := uint(9)
+	budget := &models.Budget{UserID: 1, LedgerID: &ledgerID, TargetType: models.BudgetTargetOverall}
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2026, 1, 31, 0, 0, 0, 0, time.UTC)
+
+	// Every member's spending in the ledger counts, not just the creator's
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT COALESCE(SUM(amount), 0) FROM "transactions" WHERE ledger_id = $1 AND (type = $2 AND transaction_date BETWEEN $3 AND $4)`)).
+		WithArgs(ledgerID, "expense", start, end).
+		WillReturnRows(sqlmock.NewRows([]string{"coalesce"}).AddRow(8000.0))
+
+	total, err := repo.SumSpending(budget, start, end)
+	assert.NoError(t, err)
+	assert.Equal(t, 8000.0, total)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestBudgetRepository_Create_WeeklyFromSunday(t *testing.T) {
+	db, mock := setupMockDB(t)
+	repo := NewBudgetRepository(db)
+
+	catID := uint(5)
+	budget := &models.Budget{
+		UserID:        1,
+		TargetType:    models.BudgetTargetCategory,
+		CategoryID:    &catID,
+		PeriodType:    models.BudgetPeriodWeekly,
+		StartDay:      int(time.Sunday),
+		MonthlyAmount: 3000,
+	}
+
+	// start_day=0 must be written, not left to the column default
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO "budgets" \([^)]*"start_day"`).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectCommit()
+
+	err := repo.Create(budget)
+	assert.NoError(t, err)
+	assert.Equal(t, int(time.Sunday), budget.StartDay)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,71 @@
+package repository
+
+import (
+	"billing-note/internal/models"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// CalendarRepository defines the interface for calendar feed token data access
+type CalendarRepository interface {
+	GetFeedToken(userID uint) (*models.CalendarFeedToken, error)
+	SaveFeedToken(token *models.CalendarFeedToken) error
+	FindByFeedToken(token string) (*models.CalendarFeedToken, error)
+	DeleteFeedToken(userID uint) error
+	TouchFeedToken(id uint, at time.Time) error
+}
+
+type calendarRepository struct {
+	db *gorm.DB
+}
+
+// NewCalendarRepository creates a new calendar repository
+func NewCalendarRepository(db *gorm.DB) CalendarRepository {
+	return &calendarRepository{db: db}
+}
+
+func (r *calendarRepository) GetFeedToken(userID uint) (*models.CalendarFeedToken, error) {
+	var token models.CalendarFeedToken
+	err := r.db.Where("user_id = ?", userID).First(&token).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &token, nil
+}
+
+func (r *calendarRepository) SaveFeedToken(token *models.CalendarFeedToken) error {
+	return r.db.Save(token).Error
+}
+
+func (r *calendarRepository) FindByFeedToken(token string) (*models.CalendarFeedToken, error) {
+	var feedToken models.CalendarFeedToken
+	err := r.db.Where("token = ?", token).First(&feedToken).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &feedToken, nil
+}
+
+func (r *calendarRepository) DeleteFeedToken(userID uint) error {
+	result := r.db.Where("user_id = ?", userID).Delete(&models.CalendarFeedToken{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (r *calendarRepository) TouchFeedToken(id uint, at time.Time) error {
+	return r.db.Model(&models.CalendarFeedToken{}).Where("id = ?", id).
+		UpdateColumn("last_accessed_at", at).Error
+}
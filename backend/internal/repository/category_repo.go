@@ -8,9 +8,13 @@ import (
 )
 
 type CategoryRepository interface {
+	// GetAll and GetByType return the global categories
 	GetAll() ([]models.Category, error)
 	GetByID(id uint) (*models.Category, error)
 	GetByType(categoryType string) ([]models.Category, error)
+	// ListByLedger returns the global categories plus the household ledger's
+	// own, optionally of one type
+	ListByLedger(ledgerID uint, categoryType string) ([]models.Category, error)
 	Create(category *models.Category) error
 	Update(category *models.Category) error
 	Delete(id uint) error
@@ -26,7 +30,7 @@ func NewCategoryRepository(db *gorm.DB) CategoryRepository {
 
 func (r *categoryRepository) GetAll() ([]models.Category, error) {
 	var categories []models.Category
-	err := r.db.Find(&categories).Error
+	err := r.db.Where("ledger_id IS NULL").Find(&categories).Error
 	return categories, err
 }
 
@@ -44,7 +48,17 @@ func (r *categoryRepository) GetByID(id uint) (*models.Category, error) {
 
 func (r *categoryRepository) GetByType(categoryType string) ([]models.Category, error) {
 	var categories []models.Category
-	err := r.db.Where("type = ?", categoryType).Find(&categories).Error
+	err := r.db.Where("type = ? AND ledger_id IS NULL", categoryType).Find(&categories).Error
+	return categories, err
+}
+
+func (r *categoryRepository) ListByLedger(ledgerID uint, categoryType string) ([]models.Category, error) {
+	var categories []models.Category
+	query := r.db.Where("ledger_id IS NULL OR ledger_id = ?", ledgerID)
+	if categoryType != "" {
+		query = query.Where("type = ?", categoryType)
+	}
+	err := query.Order("ledger_id NULLS FIRST, id").Find(&categories).Error
 	return categories, err
 }
 
@@ -17,6 +17,12 @@ type GmailRepository interface {
 	GetScanRule(userID uint) (*models.GmailScanRule, error)
 	SaveScanRule(rule *models.GmailScanRule) error
 
+	// Sender rule operations
+	ListSenderRules(userID uint) ([]models.GmailSenderRule, error)
+	GetSenderRule(userID, id uint) (*models.GmailSenderRule, error)
+	SaveSenderRule(rule *models.GmailSenderRule) error
+	DeleteSenderRule(userID, id uint) error
+
 	// Scan history operations
 	CreateScanHistory(history *models.GmailScanHistory) error
 	ListScanHistory(userID uint, limit int) ([]models.GmailScanHistory, error)
@@ -81,10 +87,45 @@ func (r *gmailRepository) SaveScanRule(rule *models.GmailScanRule) error {
 	existing.SenderKeywords = rule.SenderKeywords
 	existing.SubjectKeywords = rule.SubjectKeywords
 	existing.RequireAttachment = rule.RequireAttachment
+	existing.ScanOrders = rule.ScanOrders
+	existing.ApplyLabels = rule.ApplyLabels
+	existing.ImportedLabel = rule.ImportedLabel
+	existing.FailedLabel = rule.FailedLabel
+	existing.ArchiveProcessed = rule.ArchiveProcessed
 	existing.LastScanAt = rule.LastScanAt
 	return r.db.Save(&existing).Error
 }
 
+func (r *gmailRepository) ListSenderRules(userID uint) ([]models.GmailSenderRule, error) {
+	var rules []models.GmailSenderRule
+	err := r.db.Where("user_id = ?", userID).Order("id ASC").Find(&rules).Error
+	return rules, err
+}
+
+func (r *gmailRepository) GetSenderRule(userID, id uint) (*models.GmailSenderRule, error) {
+	var rule models.GmailSenderRule
+	err := r.db.Where("id = ? AND user_id = ?", id, userID).First(&rule).Error
+	if err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+func (r *gmailRepository) SaveSenderRule(rule *models.GmailSenderRule) error {
+	return r.db.Save(rule).Error
+}
+
+func (r *gmailRepository) DeleteSenderRule(userID, id uint) error {
+	result := r.db.Where("id = ? AND user_id = ?", id, userID).Delete(&models.GmailSenderRule{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
 func (r *gmailRepository) CreateScanHistory(history *models.GmailScanHistory) error {
 	return r.db.Create(history).Error
 }
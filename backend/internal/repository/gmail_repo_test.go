@@ -0,0 +1,61 @@
+package repository
+
+import (
+	"billing-note/internal/models"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGmailRepository_SaveSenderRule_Disabled(t *testing.T) {
+	db, mock := setupMockDB(t)
+	repo := NewGmailRepository(db)
+
+	rule := &models.GmailSenderRule{UserID: 1, Sender: "cathaybk.com.tw", Enabled: false}
+
+	// enabled=false must be written, not left to the column default
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "gmail_sender_rules" ("user_id","sender","bank_hint","password_priority","label","enabled","created_at","updated_at")`)).
+		WithArgs(uint(1), "cathaybk.com.tw", "", 0, "", false, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectCommit()
+
+	err := repo.SaveSenderRule(rule)
+	assert.NoError(t, err)
+	assert.False(t, rule.Enabled)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGmailRepository_SaveScanRule_FirstSaveWithoutOrders(t *testing.T) {
+	db, mock := setupMockDB(t)
+	repo := NewGmailRepository(db)
+
+	rule := &models.GmailScanRule{
+		UserID:            1,
+		SenderKeywords:    []string{"credit"},
+		SubjectKeywords:   []string{"statement"},
+		RequireAttachment: false,
+		ScanOrders:        false,
+		ImportedLabel:     "BillingNote/Imported",
+		FailedLabel:       "BillingNote/Failed",
+	}
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "gmail_scan_rules" WHERE user_id = $1`)).
+		WithArgs(uint(1)).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	// require_attachment=false and scan_orders=false must be written, not
+	// left to the column defaults
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "gmail_scan_rules" ("user_id","enabled","require_attachment","scan_orders",`)).
+		WithArgs(uint(1), false, false, false, false, "BillingNote/Imported", "BillingNote/Failed", false,
+			sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"sender_keywords", "subject_keywords", "id"}).AddRow("{credit}", "{statement}", 1))
+	mock.ExpectCommit()
+
+	err := repo.SaveScanRule(rule)
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
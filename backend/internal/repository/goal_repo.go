@@ -0,0 +1,120 @@
+package repository
+
+import (
+	"billing-note/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// GoalRepository defines the interface for savings goal data access
+type GoalRepository interface {
+	Create(goal *models.Goal) error
+	List(userID uint, includeArchived bool) ([]models.Goal, error)
+	GetByID(userID, id uint) (*models.Goal, error)
+	Update(goal *models.Goal) error
+	Delete(userID, id uint) error
+	// ListLinked returns the user's active goals linked to an account or tag
+	ListLinked(userID uint) ([]models.Goal, error)
+
+	CreateContribution(contribution *models.GoalContribution) error
+	ListContributions(goalID uint) ([]models.GoalContribution, error)
+	DeleteContribution(goalID, id uint) error
+	// UpsertRuleContribution records the contribution derived from a transaction
+	UpsertRuleContribution(contribution *models.GoalContribution) error
+	// DeleteRuleContributions removes contributions derived from a transaction
+	DeleteRuleContributions(transactionID uint) error
+	// ResetRuleContributions removes all of a goal's transaction-derived contributions
+	ResetRuleContributions(goalID uint) error
+}
+
+type goalRepository struct {
+	db *gorm.DB
+}
+
+// NewGoalRepository creates a new goal repository
+func NewGoalRepository(db *gorm.DB) GoalRepository {
+	return &goalRepository{db: db}
+}
+
+func (r *goalRepository) Create(goal *models.Goal) error {
+	return r.db.Omit("Account").Create(goal).Error
+}
+
+func (r *goalRepository) List(userID uint, includeArchived bool) ([]models.Goal, error) {
+	var goals []models.Goal
+	query := r.db.Preload("Account").Where("user_id = ?", userID)
+	if !includeArchived {
+		query = query.Where("status = ?", models.GoalStatusActive)
+	}
+	err := query.Order("id ASC").Find(&goals).Error
+	return goals, err
+}
+
+func (r *goalRepository) GetByID(userID, id uint) (*models.Goal, error) {
+	var goal models.Goal
+	err := r.db.Preload("Account").Where("user_id = ? AND id = ?", userID, id).First(&goal).Error
+	if err != nil {
+		return nil, err
+	}
+	return &goal, nil
+}
+
+func (r *goalRepository) Update(goal *models.Goal) error {
+	return r.db.Omit("Account").Save(goal).Error
+}
+
+func (r *goalRepository) Delete(userID, id uint) error {
+	result := r.db.Where("user_id = ? AND id = ?", userID, id).Delete(&models.Goal{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (r *goalRepository) ListLinked(userID uint) ([]models.Goal, error) {
+	var goals []models.Goal
+	err := r.db.Where("user_id = ? AND status = ? AND (account_id IS NOT NULL OR tag <> '')",
+		userID, models.GoalStatusActive).Find(&goals).Error
+	return goals, err
+}
+
+func (r *goalRepository) CreateContribution(contribution *models.GoalContribution) error {
+	return r.db.Create(contribution).Error
+}
+
+func (r *goalRepository) ListContributions(goalID uint) ([]models.GoalContribution, error) {
+	var contributions []models.GoalContribution
+	err := r.db.Where("goal_id = ?", goalID).Order("date DESC, id DESC").Find(&contributions).Error
+	return contributions, err
+}
+
+func (r *goalRepository) DeleteContribution(goalID, id uint) error {
+	result := r.db.Where("goal_id = ? AND id = ?", goalID, id).Delete(&models.GoalContribution{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (r *goalRepository) UpsertRuleContribution(contribution *models.GoalContribution) error {
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "goal_id"}, {Name: "transaction_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"amount", "date", "note"}),
+	}).Create(contribution).Error
+}
+
+func (r *goalRepository) DeleteRuleContributions(transactionID uint) error {
+	return r.db.Where("transaction_id = ?", transactionID).Delete(&models.GoalContribution{}).Error
+}
+
+func (r *goalRepository) ResetRuleContributions(goalID uint) error {
+	return r.db.Where("goal_id = ? AND source = ?", goalID, models.ContributionSourceRule).
+		Delete(&models.GoalContribution{}).Error
+}
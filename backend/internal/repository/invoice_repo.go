@@ -16,6 +16,13 @@ type InvoiceRepository interface {
 	Update(invoice *models.Invoice) error
 	Delete(id uint) error
 	BatchCreate(invoices []*models.Invoice) (int, error)
+
+	// Scheduled sync operations
+	ListCarrierUsers() ([]models.InvoiceCarrierUser, error)
+	GetSyncState(userID uint) (*models.InvoiceSyncState, error)
+	SaveSyncState(state *models.InvoiceSyncState) error
+	CreateSyncHistory(history *models.InvoiceSyncHistory) error
+	ListSyncHistory(userID uint, limit int) ([]models.InvoiceSyncHistory, error)
 }
 
 type invoiceRepository struct {
@@ -98,3 +105,52 @@ func (r *invoiceRepository) BatchCreate(invoices []*models.Invoice) (int, error)
 	}
 	return created, nil
 }
+
+func (r *invoiceRepository) ListCarrierUsers() ([]models.InvoiceCarrierUser, error) {
+	var users []models.InvoiceCarrierUser
+	err := r.db.Table("users").
+		Select("users.id AS user_id, users.invoice_carrier").
+		Joins("LEFT JOIN invoice_sync_states ON invoice_sync_states.user_id = users.id").
+		Where("users.invoice_carrier IS NOT NULL AND users.invoice_carrier <> ''").
+		Where("COALESCE(invoice_sync_states.enabled, TRUE)").
+		Scan(&users).Error
+	return users, err
+}
+
+func (r *invoiceRepository) GetSyncState(userID uint) (*models.InvoiceSyncState, error) {
+	var state models.InvoiceSyncState
+	err := r.db.Where("user_id = ?", userID).First(&state).Error
+	if err != nil {
+		return nil, err
+	}
+	return &state, nil
+}
+
+func (r *invoiceRepository) SaveSyncState(state *models.InvoiceSyncState) error {
+	var existing models.InvoiceSyncState
+	err := r.db.Where("user_id = ?", state.UserID).First(&existing).Error
+	if err == gorm.ErrRecordNotFound {
+		return r.db.Create(state).Error
+	}
+	if err != nil {
+		return err
+	}
+	existing.Enabled = state.Enabled
+	existing.LastSyncedDate = state.LastSyncedDate
+	existing.LastSyncAt = state.LastSyncAt
+	return r.db.Save(&existing).Error
+}
+
+func (r *invoiceRepository) CreateSyncHistory(history *models.InvoiceSyncHistory) error {
+	return r.db.Create(history).Error
+}
+
+func (r *invoiceRepository) ListSyncHistory(userID uint, limit int) ([]models.InvoiceSyncHistory, error) {
+	var history []models.InvoiceSyncHistory
+	query := r.db.Where("user_id = ?", userID).Order("sync_at DESC")
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+	err := query.Find(&history).Error
+	return history, err
+}
@@ -0,0 +1,29 @@
+package repository
+
+import (
+	"billing-note/internal/models"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInvoiceRepository_SaveSyncState_CreatesDisabled(t *testing.T) {
+	db, mock := setupMockDB(t)
+	repo := NewInvoiceRepository(db)
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "invoice_sync_states" WHERE user_id = $1`)).
+		WithArgs(uint(1)).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	// Turning sync off before the first sync must store enabled=false
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "invoice_sync_states" ("user_id","enabled","last_synced_date","last_sync_at","created_at","updated_at")`)).
+		WithArgs(uint(1), false, nil, nil, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectCommit()
+
+	err := repo.SaveSyncState(&models.InvoiceSyncState{UserID: 1, Enabled: false})
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
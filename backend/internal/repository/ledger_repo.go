@@ -0,0 +1,114 @@
+package repository
+
+import (
+	"billing-note/internal/models"
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+// LedgerRepository defines the interface for household ledger data access
+type LedgerRepository interface {
+	Create(ledger *models.Ledger) error
+	// GetByID returns the ledger with its members, or nil if it does not exist
+	GetByID(id uint) (*models.Ledger, error)
+	// ListByUser returns the ledgers the user is a member of
+	ListByUser(userID uint) ([]models.Ledger, error)
+	Update(ledger *models.Ledger) error
+	Delete(id uint) error
+
+	// GetMember returns the user's membership, or nil if they are not a member
+	GetMember(ledgerID, userID uint) (*models.LedgerMember, error)
+	AddMember(member *models.LedgerMember) error
+	RemoveMember(ledgerID, userID uint) error
+}
+
+type ledgerRepository struct {
+	db *gorm.DB
+}
+
+// NewLedgerRepository creates a new ledger repository
+func NewLedgerRepository(db *gorm.DB) LedgerRepository {
+	return &ledgerRepository{db: db}
+}
+
+// Create stores the ledger and makes its owner the first member
+func (r *ledgerRepository) Create(ledger *models.Ledger) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(ledger).Error; err != nil {
+			return err
+		}
+		owner := models.LedgerMember{LedgerID: ledger.ID, UserID: ledger.OwnerID, Role: models.LedgerRoleOwner}
+		if err := tx.Create(&owner).Error; err != nil {
+			return err
+		}
+		ledger.Members = []models.LedgerMember{owner}
+		return nil
+	})
+}
+
+func (r *ledgerRepository) GetByID(id uint) (*models.Ledger, error) {
+	var ledger models.Ledger
+	err := r.db.Preload("Members", func(db *gorm.DB) *gorm.DB {
+		return db.Order("ledger_members.id ASC")
+	}).Preload("Members.User").First(&ledger, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &ledger, nil
+}
+
+func (r *ledgerRepository) ListByUser(userID uint) ([]models.Ledger, error) {
+	var ledgers []models.Ledger
+	err := r.db.Preload("Members", func(db *gorm.DB) *gorm.DB {
+		return db.Order("ledger_members.id ASC")
+	}).Preload("Members.User").
+		Where("id IN (?)", r.db.Model(&models.LedgerMember{}).Select("ledger_id").Where("user_id = ?", userID)).
+		Order("name ASC").Find(&ledgers).Error
+	return ledgers, err
+}
+
+func (r *ledgerRepository) Update(ledger *models.Ledger) error {
+	return r.db.Omit("Members").Save(ledger).Error
+}
+
+func (r *ledgerRepository) Delete(id uint) error {
+	result := r.db.Delete(&models.Ledger{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (r *ledgerRepository) GetMember(ledgerID, userID uint) (*models.LedgerMember, error) {
+	var member models.LedgerMember
+	err := r.db.Where("ledger_id = ? AND user_id = ?", ledgerID, userID).First(&member).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &member, nil
+}
+
+func (r *ledgerRepository) AddMember(member *models.LedgerMember) error {
+	return r.db.Create(member).Error
+}
+
+func (r *ledgerRepository) RemoveMember(ledgerID, userID uint) error {
+	result := r.db.Where("ledger_id = ? AND user_id = ?", ledgerID, userID).Delete(&models.LedgerMember{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package repository
+
+import (
+	"billing-note/internal/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type LoginAttemptRepository interface {
+	RecordFailure(attempt *models.LoginAttempt) error
+	// ListFailuresByEmail returns the failures for the email since the given
+	// time, newest first
+	ListFailuresByEmail(email string, since time.Time) ([]models.LoginAttempt, error)
+	// ListFailuresByIP returns the failures from the IP since the given time,
+	// newest first
+	ListFailuresByIP(ip string, since time.Time) ([]models.LoginAttempt, error)
+	// ClearFailures forgets the email's failures after a successful sign-in
+	ClearFailures(email string) error
+	// DeleteBefore removes failures recorded before the given time
+	DeleteBefore(before time.Time) error
+}
+
+type loginAttemptRepository struct {
+	db *gorm.DB
+}
+
+func NewLoginAttemptRepository(db *gorm.DB) LoginAttemptRepository {
+	return &loginAttemptRepository{db: db}
+}
+
+func (r *loginAttemptRepository) RecordFailure(attempt *models.LoginAttempt) error {
+	return r.db.Create(attempt).Error
+}
+
+func (r *loginAttemptRepository) ListFailuresByEmail(email string, since time.Time) ([]models.LoginAttempt, error) {
+	var attempts []models.LoginAttempt
+	err := r.db.Where("email = ? AND created_at >= ?", email, since).
+		Order("created_at DESC").
+		Find(&attempts).Error
+	return attempts, err
+}
+
+func (r *loginAttemptRepository) ListFailuresByIP(ip string, since time.Time) ([]models.LoginAttempt, error) {
+	var attempts []models.LoginAttempt
+	err := r.db.Where("ip = ? AND created_at >= ?", ip, since).
+		Order("created_at DESC").
+		Find(&attempts).Error
+	return attempts, err
+}
+
+func (r *loginAttemptRepository) ClearFailures(email string) error {
+	return r.db.Where("email = ?", email).Delete(&models.LoginAttempt{}).Error
+}
+
+func (r *loginAttemptRepository) DeleteBefore(before time.Time) error {
+	return r.db.Where("created_at < ?", before).Delete(&models.LoginAttempt{}).Error
+}
@@ -0,0 +1,97 @@
+package repository
+
+import (
+	"billing-note/internal/models"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// LotteryRepository defines the interface for invoice lottery data access
+type LotteryRepository interface {
+	// Winning numbers operations
+	SaveWinningNumbers(numbers *models.InvoiceWinningNumbers) error
+	GetWinningNumbers(period string) (*models.InvoiceWinningNumbers, error)
+	ListWinningNumbers() ([]models.InvoiceWinningNumbers, error)
+
+	// Prize operations
+	GetPrize(userID, id uint) (*models.InvoicePrize, error)
+	CreatePrize(prize *models.InvoicePrize) error
+	UpdatePrize(prize *models.InvoicePrize) error
+	DeletePrize(id uint) error
+	ListPrizes(userID uint, period string) ([]models.InvoicePrize, error)
+
+	// ListUserIDsWithInvoices returns users having invoices dated within [start, end)
+	ListUserIDsWithInvoices(start, end time.Time) ([]uint, error)
+}
+
+type lotteryRepository struct {
+	db *gorm.DB
+}
+
+func NewLotteryRepository(db *gorm.DB) LotteryRepository {
+	return &lotteryRepository{db: db}
+}
+
+func (r *lotteryRepository) SaveWinningNumbers(numbers *models.InvoiceWinningNumbers) error {
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "period"}},
+		DoUpdates: clause.AssignmentColumns([]string{"special_prize", "grand_prize", "first_prizes", "additional_sixth_prizes", "source", "updated_at"}),
+	}).Create(numbers).Error
+}
+
+func (r *lotteryRepository) GetWinningNumbers(period string) (*models.InvoiceWinningNumbers, error) {
+	var numbers models.InvoiceWinningNumbers
+	err := r.db.Where("period = ?", period).First(&numbers).Error
+	if err != nil {
+		return nil, err
+	}
+	return &numbers, nil
+}
+
+func (r *lotteryRepository) ListWinningNumbers() ([]models.InvoiceWinningNumbers, error) {
+	var list []models.InvoiceWinningNumbers
+	err := r.db.Order("period DESC").Find(&list).Error
+	return list, err
+}
+
+func (r *lotteryRepository) GetPrize(userID, id uint) (*models.InvoicePrize, error) {
+	var prize models.InvoicePrize
+	err := r.db.Preload("Invoice").Where("id = ? AND user_id = ?", id, userID).First(&prize).Error
+	if err != nil {
+		return nil, err
+	}
+	return &prize, nil
+}
+
+func (r *lotteryRepository) CreatePrize(prize *models.InvoicePrize) error {
+	return r.db.Create(prize).Error
+}
+
+func (r *lotteryRepository) UpdatePrize(prize *models.InvoicePrize) error {
+	return r.db.Save(prize).Error
+}
+
+func (r *lotteryRepository) DeletePrize(id uint) error {
+	return r.db.Delete(&models.InvoicePrize{}, id).Error
+}
+
+func (r *lotteryRepository) ListPrizes(userID uint, period string) ([]models.InvoicePrize, error) {
+	var prizes []models.InvoicePrize
+	query := r.db.Preload("Invoice").Where("user_id = ?", userID)
+	if period != "" {
+		query = query.Where("period = ?", period)
+	}
+	err := query.Order("period DESC, amount DESC").Find(&prizes).Error
+	return prizes, err
+}
+
+func (r *lotteryRepository) ListUserIDsWithInvoices(start, end time.Time) ([]uint, error) {
+	var userIDs []uint
+	err := r.db.Model(&models.Invoice{}).
+		Where("invoice_date >= ? AND invoice_date < ?", start, end).
+		Distinct().
+		Pluck("user_id", &userIDs).Error
+	return userIDs, err
+}
@@ -0,0 +1,89 @@
+package repository
+
+import (
+	"billing-note/internal/models"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// NotificationRepository defines the interface for notification data access
+type NotificationRepository interface {
+	Create(notification *models.Notification) error
+	List(userID uint, unreadOnly bool, limit int) ([]models.Notification, error)
+	CountUnread(userID uint) (int64, error)
+	MarkRead(userID, id uint) error
+	MarkAllRead(userID uint) error
+
+	// Delivery settings
+	GetSettings(userID uint) (*models.NotificationSettings, error)
+	SaveSettings(settings *models.NotificationSettings) error
+}
+
+type notificationRepository struct {
+	db *gorm.DB
+}
+
+// NewNotificationRepository creates a new notification repository
+func NewNotificationRepository(db *gorm.DB) NotificationRepository {
+	return &notificationRepository{db: db}
+}
+
+func (r *notificationRepository) Create(notification *models.Notification) error {
+	return r.db.Create(notification).Error
+}
+
+func (r *notificationRepository) List(userID uint, unreadOnly bool, limit int) ([]models.Notification, error) {
+	var notifications []models.Notification
+	query := r.db.Where("user_id = ?", userID)
+	if unreadOnly {
+		query = query.Where("read_at IS NULL")
+	}
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+	err := query.Order("created_at DESC, id DESC").Find(&notifications).Error
+	return notifications, err
+}
+
+func (r *notificationRepository) CountUnread(userID uint) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.Notification{}).Where("user_id = ? AND read_at IS NULL", userID).Count(&count).Error
+	return count, err
+}
+
+func (r *notificationRepository) MarkRead(userID, id uint) error {
+	result := r.db.Model(&models.Notification{}).
+		Where("user_id = ? AND id = ?", userID, id).
+		Update("read_at", gorm.Expr("COALESCE(read_at, ?)", time.Now()))
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (r *notificationRepository) MarkAllRead(userID uint) error {
+	return r.db.Model(&models.Notification{}).
+		Where("user_id = ? AND read_at IS NULL", userID).
+		Update("read_at", time.Now()).Error
+}
+
+func (r *notificationRepository) GetSettings(userID uint) (*models.NotificationSettings, error) {
+	var settings models.NotificationSettings
+	err := r.db.Where("user_id = ?", userID).First(&settings).Error
+	if err != nil {
+		return nil, err
+	}
+	return &settings, nil
+}
+
+func (r *notificationRepository) SaveSettings(settings *models.NotificationSettings) error {
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"email_enabled", "webhook_enabled", "webhook_url", "webhook_secret", "updated_at"}),
+	}).Create(settings).Error
+}
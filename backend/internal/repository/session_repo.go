@@ -0,0 +1,100 @@
+package repository
+
+import (
+	"billing-note/internal/models"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type SessionRepository interface {
+	Create(session *models.Session) error
+	GetByID(id uint) (*models.Session, error)
+	// FindByRefreshHash finds the session whose current or previous refresh
+	// token has the hash
+	FindByRefreshHash(hash string) (*models.Session, error)
+	Update(session *models.Session) error
+	// Touch records activity on the session
+	Touch(id uint, seenAt time.Time, ip string) error
+	// MarkVerified records that the user re-confirmed their identity
+	MarkVerified(id uint, at time.Time) error
+	ListActiveByUser(userID uint, now time.Time) ([]models.Session, error)
+	Revoke(id uint, at time.Time) error
+	// RevokeAllByUser revokes the user's sessions except exceptID (0 for none)
+	RevokeAllByUser(userID, exceptID uint, at time.Time) (int64, error)
+}
+
+type sessionRepository struct {
+	db *gorm.DB
+}
+
+func NewSessionRepository(db *gorm.DB) SessionRepository {
+	return &sessionRepository{db: db}
+}
+
+func (r *sessionRepository) Create(session *models.Session) error {
+	return r.db.Create(session).Error
+}
+
+func (r *sessionRepository) GetByID(id uint) (*models.Session, error) {
+	var session models.Session
+	err := r.db.First(&session, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &session, nil
+}
+
+func (r *sessionRepository) FindByRefreshHash(hash string) (*models.Session, error) {
+	var session models.Session
+	err := r.db.Where("refresh_token_hash = ? OR previous_token_hash = ?", hash, hash).First(&session).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &session, nil
+}
+
+func (r *sessionRepository) Update(session *models.Session) error {
+	return r.db.Save(session).Error
+}
+
+func (r *sessionRepository) Touch(id uint, seenAt time.Time, ip string) error {
+	return r.db.Model(&models.Session{}).Where("id = ?", id).
+		Updates(map[string]interface{}{"last_seen_at": seenAt, "ip": ip}).Error
+}
+
+func (r *sessionRepository) MarkVerified(id uint, at time.Time) error {
+	return r.db.Model(&models.Session{}).Where("id = ?", id).Update("verified_at", at).Error
+}
+
+func (r *sessionRepository) ListActiveByUser(userID uint, now time.Time) ([]models.Session, error) {
+	var sessions []models.Session
+	err := r.db.Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, now).
+		Order("last_seen_at DESC").Find(&sessions).Error
+	return sessions, err
+}
+
+func (r *sessionRepository) Revoke(id uint, at time.Time) error {
+	result := r.db.Model(&models.Session{}).Where("id = ? AND revoked_at IS NULL", id).Update("revoked_at", at)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (r *sessionRepository) RevokeAllByUser(userID, exceptID uint, at time.Time) (int64, error) {
+	result := r.db.Model(&models.Session{}).
+		Where("user_id = ? AND id <> ? AND revoked_at IS NULL", userID, exceptID).
+		Update("revoked_at", at)
+	return result.RowsAffected, result.Error
+}
@@ -3,6 +3,7 @@ package repository
 import (
 	"billing-note/internal/models"
 	"errors"
+	"time"
 
 	"gorm.io/gorm"
 )
@@ -11,11 +12,26 @@ type SharingRepository interface {
 	GetPairingCode(userID uint) (*models.UserPairingCode, error)
 	SavePairingCode(code *models.UserPairingCode) error
 	FindByCode(code string) (*models.UserPairingCode, error)
+	// ConsumePairingCode uses up one redemption of the code, reporting false
+	// when it has expired or has no uses left
+	ConsumePairingCode(id uint, now time.Time) (bool, error)
 	CreateSharedAccess(access *models.SharedAccess) error
 	ListSharedByOwner(ownerID uint) ([]models.SharedAccess, error)
 	ListSharedByViewer(viewerID uint) ([]models.SharedAccess, error)
 	DeleteSharedAccess(ownerID, viewerID uint) error
 	HasAccess(ownerID, viewerID uint) (bool, error)
+	GetSharedAccess(ownerID, viewerID uint) (*models.SharedAccess, error)
+	UpdateSharedAccess(access *models.SharedAccess) error
+
+	// Pairing requests and failed attempts
+	CreatePairingRequest(request *models.PairingRequest) error
+	GetPairingRequest(id uint) (*models.PairingRequest, error)
+	FindPendingRequest(ownerID, requesterID uint) (*models.PairingRequest, error)
+	ListPendingRequestsByOwner(ownerID uint) ([]models.PairingRequest, error)
+	ListPendingRequestsByRequester(requesterID uint) ([]models.PairingRequest, error)
+	UpdatePairingRequest(request *models.PairingRequest) error
+	RecordPairingFailure(userID uint) error
+	CountPairingFailures(userID uint, since time.Time) (int64, error)
 }
 
 type sharingRepository struct {
@@ -54,6 +70,13 @@ func (r *sharingRepository) FindByCode(code string) (*models.UserPairingCode, er
 	return &pairingCode, nil
 }
 
+func (r *sharingRepository) ConsumePairingCode(id uint, now time.Time) (bool, error) {
+	result := r.db.Model(&models.UserPairingCode{}).
+		Where("id = ? AND expires_at > ? AND use_count < max_uses", id, now).
+		Update("use_count", gorm.Expr("use_count + 1"))
+	return result.RowsAffected > 0, result.Error
+}
+
 func (r *sharingRepository) CreateSharedAccess(access *models.SharedAccess) error {
 	return r.db.Create(access).Error
 }
@@ -79,3 +102,81 @@ func (r *sharingRepository) HasAccess(ownerID, viewerID uint) (bool, error) {
 	err := r.db.Model(&models.SharedAccess{}).Where("owner_id = ? AND viewer_id = ?", ownerID, viewerID).Count(&count).Error
 	return count > 0, err
 }
+
+func (r *sharingRepository) GetSharedAccess(ownerID, viewerID uint) (*models.SharedAccess, error) {
+	var access models.SharedAccess
+	err := r.db.Where("owner_id = ? AND viewer_id = ?", ownerID, viewerID).First(&access).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &access, nil
+}
+
+func (r *sharingRepository) UpdateSharedAccess(access *models.SharedAccess) error {
+	return r.db.Save(access).Error
+}
+
+func (r *sharingRepository) CreatePairingRequest(request *models.PairingRequest) error {
+	return r.db.Create(request).Error
+}
+
+func (r *sharingRepository) GetPairingRequest(id uint) (*models.PairingRequest, error) {
+	var request models.PairingRequest
+	err := r.db.First(&request, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &request, nil
+}
+
+func (r *sharingRepository) FindPendingRequest(ownerID, requesterID uint) (*models.PairingRequest, error) {
+	var request models.PairingRequest
+	err := r.db.Where("owner_id = ? AND requester_id = ? AND status = ?", ownerID, requesterID, models.PairingRequestPending).
+		First(&request).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &request, nil
+}
+
+func (r *sharingRepository) ListPendingRequestsByOwner(ownerID uint) ([]models.PairingRequest, error) {
+	var requests []models.PairingRequest
+	err := r.db.Preload("Requester").
+		Where("owner_id = ? AND status = ?", ownerID, models.PairingRequestPending).
+		Order("created_at").Find(&requests).Error
+	return requests, err
+}
+
+func (r *sharingRepository) ListPendingRequestsByRequester(requesterID uint) ([]models.PairingRequest, error) {
+	var requests []models.PairingRequest
+	err := r.db.Preload("Owner").
+		Where("requester_id = ? AND status = ?", requesterID, models.PairingRequestPending).
+		Order("created_at").Find(&requests).Error
+	return requests, err
+}
+
+func (r *sharingRepository) UpdatePairingRequest(request *models.PairingRequest) error {
+	return r.db.Model(request).Updates(map[string]interface{}{
+		"status":     request.Status,
+		"decided_at": request.DecidedAt,
+	}).Error
+}
+
+func (r *sharingRepository) RecordPairingFailure(userID uint) error {
+	return r.db.Create(&models.PairingAttempt{UserID: userID}).Error
+}
+
+func (r *sharingRepository) CountPairingFailures(userID uint, since time.Time) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.PairingAttempt{}).Where("user_id = ? AND created_at >= ?", userID, since).Count(&count).Error
+	return count, err
+}
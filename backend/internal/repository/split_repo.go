@@ -0,0 +1,126 @@
+package repository
+
+import (
+	"billing-note/internal/models"
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+// SplitRepository defines the interface for split and settlement data access
+type SplitRepository interface {
+	// SaveSplit stores the split, replacing any existing split of the transaction
+	SaveSplit(split *models.TransactionSplit) error
+	// GetByTransaction returns the transaction's split, or nil if it is not split
+	GetByTransaction(transactionID uint) (*models.TransactionSplit, error)
+	DeleteByTransaction(transactionID uint) error
+	// ListByUser returns the splits the user paid or has a share in, newest first
+	ListByUser(userID uint) ([]models.TransactionSplit, error)
+	// ListAmong returns the splits paid by any of the users
+	ListAmong(userIDs []uint) ([]models.TransactionSplit, error)
+
+	CreateSettlement(settlement *models.Settlement) error
+	// GetSettlement returns the settlement, or nil if it does not exist
+	GetSettlement(id uint) (*models.Settlement, error)
+	// ListSettlements returns the settlements the user paid or received, newest first
+	ListSettlements(userID uint) ([]models.Settlement, error)
+	// ListSettlementsAmong returns the settlements paid by any of the users
+	ListSettlementsAmong(userIDs []uint) ([]models.Settlement, error)
+	DeleteSettlement(id uint) error
+}
+
+type splitRepository struct {
+	db *gorm.DB
+}
+
+// NewSplitRepository creates a new split repository
+func NewSplitRepository(db *gorm.DB) SplitRepository {
+	return &splitRepository{db: db}
+}
+
+func (r *splitRepository) SaveSplit(split *models.TransactionSplit) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("transaction_id = ?", split.TransactionID).Delete(&models.TransactionSplit{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(split).Error
+	})
+}
+
+func (r *splitRepository) GetByTransaction(transactionID uint) (*models.TransactionSplit, error) {
+	var split models.TransactionSplit
+	err := r.db.Preload("Shares").Where("transaction_id = ?", transactionID).First(&split).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &split, nil
+}
+
+func (r *splitRepository) DeleteByTransaction(transactionID uint) error {
+	result := r.db.Where("transaction_id = ?", transactionID).Delete(&models.TransactionSplit{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (r *splitRepository) ListByUser(userID uint) ([]models.TransactionSplit, error) {
+	var splits []models.TransactionSplit
+	err := r.db.Preload("Shares").
+		Where("payer_id = ? OR id IN (?)", userID,
+			r.db.Model(&models.SplitShare{}).Select("split_id").Where("user_id = ?", userID)).
+		Order("date DESC, id DESC").Find(&splits).Error
+	return splits, err
+}
+
+func (r *splitRepository) ListAmong(userIDs []uint) ([]models.TransactionSplit, error) {
+	var splits []models.TransactionSplit
+	err := r.db.Preload("Shares").Where("payer_id IN ?", userIDs).Find(&splits).Error
+	return splits, err
+}
+
+func (r *splitRepository) CreateSettlement(settlement *models.Settlement) error {
+	return r.db.Create(settlement).Error
+}
+
+func (r *splitRepository) GetSettlement(id uint) (*models.Settlement, error) {
+	var settlement models.Settlement
+	err := r.db.First(&settlement, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &settlement, nil
+}
+
+func (r *splitRepository) ListSettlements(userID uint) ([]models.Settlement, error) {
+	var settlements []models.Settlement
+	err := r.db.Where("from_user_id = ? OR to_user_id = ?", userID, userID).
+		Order("date DESC, id DESC").Find(&settlements).Error
+	return settlements, err
+}
+
+func (r *splitRepository) ListSettlementsAmong(userIDs []uint) ([]models.Settlement, error) {
+	var settlements []models.Settlement
+	err := r.db.Where("from_user_id IN ?", userIDs).Find(&settlements).Error
+	return settlements, err
+}
+
+func (r *splitRepository) DeleteSettlement(id uint) error {
+	result := r.db.Delete(&models.Settlement{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
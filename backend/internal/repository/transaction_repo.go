@@ -15,6 +15,7 @@ type TransactionFilter struct {
 	StartDate  *time.Time
 	EndDate    *time.Time
 	CategoryID *uint
+	AccountID  *uint
 	Query      string
 	Tags       []string
 	MinAmount  *float64
@@ -39,29 +40,82 @@ type TransactionRepository interface {
 	GetMonthlyStats(userID uint, year int, month int) (map[string]float64, error)
 	GetCategoryStats(userID uint, startDate, endDate time.Time, transactionType string) ([]map[string]interface{}, error)
 	GetTrendStats(userID uint, months int) ([]TrendDataPoint, error)
+	// WithScope returns a repository whose queries only see transactions in
+	// the share scope; a nil scope returns the repository unchanged
+	WithScope(scope *models.ShareScope) TransactionRepository
+	// ForLedger returns a repository whose queries read the household
+	// ledger's transactions instead of the user's personal ones; a nil
+	// ledger ID selects the personal ledger
+	ForLedger(ledgerID *uint) TransactionRepository
 }
 
 type transactionRepository struct {
-	db *gorm.DB
+	db       *gorm.DB
+	scope    *models.ShareScope
+	ledgerID *uint
 }
 
 func NewTransactionRepository(db *gorm.DB) TransactionRepository {
 	return &transactionRepository{db: db}
 }
 
+func (r *transactionRepository) WithScope(scope *models.ShareScope) TransactionRepository {
+	if scope == nil {
+		return r
+	}
+	return &transactionRepository{db: r.db, scope: scope, ledgerID: r.ledgerID}
+}
+
+func (r *transactionRepository) ForLedger(ledgerID *uint) TransactionRepository {
+	return &transactionRepository{db: r.db, scope: r.scope, ledgerID: ledgerID}
+}
+
+// owned restricts a transactions query to the user's personal transactions,
+// or to the household ledger's
+func (r *transactionRepository) owned(query *gorm.DB, userID uint) *gorm.DB {
+	if r.ledgerID != nil {
+		return query.Where("transactions.ledger_id = ?", *r.ledgerID)
+	}
+	return query.Where("transactions.user_id = ? AND transactions.ledger_id IS NULL", userID)
+}
+
+// scoped restricts a transactions query to the share scope
+func (r *transactionRepository) scoped(query *gorm.DB) *gorm.DB {
+	if r.scope == nil {
+		return query
+	}
+	if r.scope.StartDate != nil {
+		query = query.Where("transactions.transaction_date >= ?", *r.scope.StartDate)
+	}
+	if r.scope.EndDate != nil {
+		query = query.Where("transactions.transaction_date < ?", r.scope.EndDate.AddDate(0, 0, 1))
+	}
+	if len(r.scope.CategoryIDs) > 0 {
+		query = query.Where("transactions.category_id IN ?", r.scope.CategoryIDs)
+	}
+	if len(r.scope.AccountIDs) > 0 {
+		query = query.Where("(transactions.account_id IN ? OR transactions.to_account_id IN ?)", r.scope.AccountIDs, r.scope.AccountIDs)
+	}
+	return query
+}
+
+// redactedColumns are hidden from, and never overwritten by, a scope that hides descriptions
+var redactedColumns = []string{"description", "merchant", "order_number", "items"}
+
 func (r *transactionRepository) Create(transaction *models.Transaction) error {
 	return r.db.Create(transaction).Error
 }
 
 func (r *transactionRepository) GetByID(id uint) (*models.Transaction, error) {
 	var transaction models.Transaction
-	err := r.db.Preload("Category").First(&transaction, id).Error
+	err := r.scoped(r.db.Preload("Category")).First(&transaction, id).Error
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, errors.New("transaction not found")
 		}
 		return nil, err
 	}
+	r.scope.Redact(&transaction)
 	return &transaction, nil
 }
 
@@ -69,7 +123,7 @@ func (r *transactionRepository) List(filter TransactionFilter) ([]models.Transac
 	var transactions []models.Transaction
 	var total int64
 
-	query := r.db.Model(&models.Transaction{}).Where("user_id = ?", filter.UserID)
+	query := r.owned(r.scoped(r.db.Model(&models.Transaction{})), filter.UserID)
 
 	// Apply filters
 	if filter.Type != "" {
@@ -84,7 +138,11 @@ func (r *transactionRepository) List(filter TransactionFilter) ([]models.Transac
 	if filter.CategoryID != nil {
 		query = query.Where("category_id = ?", *filter.CategoryID)
 	}
-	if filter.Query != "" {
+	if filter.AccountID != nil {
+		// Transfers match on either side
+		query = query.Where("(account_id = ? OR to_account_id = ?)", *filter.AccountID, *filter.AccountID)
+	}
+	if filter.Query != "" && (r.scope == nil || !r.scope.HideDescriptions) {
 		query = query.Where("description ILIKE ?", "%"+filter.Query+"%")
 	}
 	if len(filter.Tags) > 0 {
@@ -110,15 +168,22 @@ func (r *transactionRepository) List(filter TransactionFilter) ([]models.Transac
 
 	// Execute query with preloading
 	err := query.Preload("Category").Order("transaction_date DESC, id DESC").Find(&transactions).Error
+	for i := range transactions {
+		r.scope.Redact(&transactions[i])
+	}
 	return transactions, total, err
 }
 
 func (r *transactionRepository) Update(transaction *models.Transaction) error {
+	if r.scope != nil && r.scope.HideDescriptions {
+		// The caller only saw redacted values; keep the stored ones
+		return r.db.Model(transaction).Select("*").Omit(append(redactedColumns, "created_at")...).Updates(transaction).Error
+	}
 	return r.db.Save(transaction).Error
 }
 
 func (r *transactionRepository) Delete(id uint) error {
-	return r.db.Delete(&models.Transaction{}, id).Error
+	return r.scoped(r.db).Delete(&models.Transaction{}, id).Error
 }
 
 func (r *transactionRepository) GetMonthlyStats(userID uint, year int, month int) (map[string]float64, error) {
@@ -129,8 +194,8 @@ func (r *transactionRepository) GetMonthlyStats(userID uint, year int, month int
 
 	// Get income
 	var income float64
-	if err := r.db.Model(&models.Transaction{}).
-		Where("user_id = ? AND type = ? AND transaction_date BETWEEN ? AND ?", userID, "income", startDate, endDate).
+	if err := r.owned(r.scoped(r.db.Model(&models.Transaction{})), userID).
+		Where("type = ? AND transaction_date BETWEEN ? AND ?", "income", startDate, endDate).
 		Select("COALESCE(SUM(amount), 0)").
 		Scan(&income).Error; err != nil {
 		return nil, err
@@ -138,8 +203,8 @@ func (r *transactionRepository) GetMonthlyStats(userID uint, year int, month int
 
 	// Get expense
 	var expense float64
-	if err := r.db.Model(&models.Transaction{}).
-		Where("user_id = ? AND type = ? AND transaction_date BETWEEN ? AND ?", userID, "expense", startDate, endDate).
+	if err := r.owned(r.scoped(r.db.Model(&models.Transaction{})), userID).
+		Where("type = ? AND transaction_date BETWEEN ? AND ?", "expense", startDate, endDate).
 		Select("COALESCE(SUM(amount), 0)").
 		Scan(&expense).Error; err != nil {
 		return nil, err
@@ -159,13 +224,15 @@ func (r *transactionRepository) GetCategoryStats(userID uint, startDate, endDate
 		Amount       float64
 	}
 
-	query := r.db.Model(&models.Transaction{}).
+	query := r.owned(r.scoped(r.db.Model(&models.Transaction{})), userID).
 		Select("transactions.category_id, categories.name as category_name, SUM(transactions.amount) as amount").
 		Joins("LEFT JOIN categories ON transactions.category_id = categories.id").
-		Where("transactions.user_id = ? AND transactions.transaction_date BETWEEN ? AND ?", userID, startDate, endDate)
+		Where("transactions.transaction_date BETWEEN ? AND ?", startDate, endDate)
 
 	if transactionType != "" {
 		query = query.Where("transactions.type = ?", transactionType)
+	} else {
+		query = query.Where("transactions.type <> ?", "transfer")
 	}
 
 	if err := query.Group("transactions.category_id, categories.name").Scan(&results).Error; err != nil {
@@ -198,9 +265,9 @@ func (r *transactionRepository) GetTrendStats(userID uint, months int) ([]TrendD
 		Total     float64
 	}
 
-	err := r.db.Model(&models.Transaction{}).
+	err := r.owned(r.scoped(r.db.Model(&models.Transaction{})), userID).
 		Select("TO_CHAR(transaction_date, 'YYYY-MM') as year_month, type, COALESCE(SUM(amount), 0) as total").
-		Where("user_id = ? AND transaction_date >= ?", userID, startDate).
+		Where("type IN ? AND transaction_date >= ?", []string{"income", "expense"}, startDate).
 		Group("year_month, type").
 		Order("year_month ASC").
 		Scan(&results).Error
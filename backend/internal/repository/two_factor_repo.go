@@ -0,0 +1,80 @@
+package repository
+
+import (
+	"billing-note/internal/models"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type TwoFactorRepository interface {
+	Get(userID uint) (*models.UserTwoFactor, error)
+	Save(twoFactor *models.UserTwoFactor) error
+	// Delete removes the enrollment and the recovery codes
+	Delete(userID uint) error
+	// ReplaceRecoveryCodes discards the user's recovery codes for new ones
+	ReplaceRecoveryCodes(userID uint, hashes []string) error
+	// UseRecoveryCode marks an unused code as used, reporting whether one matched
+	UseRecoveryCode(userID uint, hash string, at time.Time) (bool, error)
+	CountRecoveryCodes(userID uint) (int64, error)
+}
+
+type twoFactorRepository struct {
+	db *gorm.DB
+}
+
+func NewTwoFactorRepository(db *gorm.DB) TwoFactorRepository {
+	return &twoFactorRepository{db: db}
+}
+
+func (r *twoFactorRepository) Get(userID uint) (*models.UserTwoFactor, error) {
+	var twoFactor models.UserTwoFactor
+	err := r.db.Where("user_id = ?", userID).First(&twoFactor).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &twoFactor, nil
+}
+
+func (r *twoFactorRepository) Save(twoFactor *models.UserTwoFactor) error {
+	return r.db.Save(twoFactor).Error
+}
+
+func (r *twoFactorRepository) Delete(userID uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", userID).Delete(&models.UserTwoFactor{}).Error
+	})
+}
+
+func (r *twoFactorRepository) ReplaceRecoveryCodes(userID uint, hashes []string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+			return err
+		}
+		codes := make([]models.RecoveryCode, len(hashes))
+		for i, hash := range hashes {
+			codes[i] = models.RecoveryCode{UserID: userID, CodeHash: hash}
+		}
+		return tx.Create(&codes).Error
+	})
+}
+
+func (r *twoFactorRepository) UseRecoveryCode(userID uint, hash string, at time.Time) (bool, error) {
+	result := r.db.Model(&models.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, hash).
+		Update("used_at", at)
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *twoFactorRepository) CountRecoveryCodes(userID uint) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.RecoveryCode{}).Where("user_id = ? AND used_at IS NULL", userID).Count(&count).Error
+	return count, err
+}
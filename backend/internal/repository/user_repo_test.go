@@ -44,7 +44,7 @@ func TestUserRepository_Create(t *testing.T) {
 
 	mock.ExpectBegin()
 	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "users"`)).
-		WithArgs(user.Email, sqlmock.AnyArg(), user.Name, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WithArgs(user.Email, sqlmock.AnyArg(), user.Name, "Asia/Taipei", nil, sqlmock.AnyArg(), sqlmock.AnyArg()).
 		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
 	mock.ExpectCommit()
 
@@ -0,0 +1,59 @@
+package repository
+
+import (
+	"billing-note/internal/models"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type UserTokenRepository interface {
+	Create(token *models.UserToken) error
+	// Consume marks an unused, unexpired token as used and returns it, or nil
+	// if there is no such token. A token can only be consumed once.
+	Consume(purpose, hash string, now time.Time) (*models.UserToken, error)
+	// InvalidateAll marks the user's unused tokens for the purpose as used
+	InvalidateAll(userID uint, purpose string, at time.Time) error
+}
+
+type userTokenRepository struct {
+	db *gorm.DB
+}
+
+func NewUserTokenRepository(db *gorm.DB) UserTokenRepository {
+	return &userTokenRepository{db: db}
+}
+
+func (r *userTokenRepository) Create(token *models.UserToken) error {
+	return r.db.Create(token).Error
+}
+
+func (r *userTokenRepository) Consume(purpose, hash string, now time.Time) (*models.UserToken, error) {
+	var token models.UserToken
+	err := r.db.Where("purpose = ? AND token_hash = ? AND used_at IS NULL AND expires_at > ?", purpose, hash, now).
+		First(&token).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	// Conditional update so concurrent requests cannot both use the token
+	result := r.db.Model(&models.UserToken{}).Where("id = ? AND used_at IS NULL", token.ID).Update("used_at", now)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, nil
+	}
+	token.UsedAt = &now
+	return &token, nil
+}
+
+func (r *userTokenRepository) InvalidateAll(userID uint, purpose string, at time.Time) error {
+	return r.db.Model(&models.UserToken{}).
+		Where("user_id = ? AND purpose = ? AND used_at IS NULL", userID, purpose).
+		Update("used_at", at).Error
+}
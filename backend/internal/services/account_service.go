@@ -0,0 +1,92 @@
+package services
+
+import (
+	"billing-note/internal/models"
+	"billing-note/internal/repository"
+	"billing-note/pkg/errors"
+	"strings"
+)
+
+// AccountService manages a user's accounts
+type AccountService struct {
+	accountRepo repository.AccountRepository
+}
+
+// NewAccountService creates a new account service
+func NewAccountService(accountRepo repository.AccountRepository) *AccountService {
+	return &AccountService{accountRepo: accountRepo}
+}
+
+// List returns the user's accounts
+func (s *AccountService) List(userID uint) ([]models.Account, error) {
+	accounts, err := s.accountRepo.List(userID)
+	if err != nil {
+		return nil, errors.NewDBError("list accounts", err)
+	}
+	return accounts, nil
+}
+
+// Get returns one of the user's accounts
+func (s *AccountService) Get(userID, id uint) (*models.Account, error) {
+	account, err := s.accountRepo.GetByID(userID, id)
+	if err != nil {
+		return nil, errors.NewNotFoundError("Account", id)
+	}
+	return account, nil
+}
+
+// Create adds an account for the user
+func (s *AccountService) Create(userID uint, input models.AccountInput) (*models.Account, error) {
+	account := &models.Account{UserID: userID}
+	if err := applyAccountInput(account, input); err != nil {
+		return nil, err
+	}
+	if err := s.accountRepo.Create(account); err != nil {
+		return nil, errors.NewDBError("create account", err)
+	}
+	return account, nil
+}
+
+// Update renames or retypes one of the user's accounts
+func (s *AccountService) Update(userID, id uint, input models.AccountInput) (*models.Account, error) {
+	account, err := s.accountRepo.GetByID(userID, id)
+	if err != nil {
+		return nil, errors.NewNotFoundError("Account", id)
+	}
+	if err := applyAccountInput(account, input); err != nil {
+		return nil, err
+	}
+	if err := s.accountRepo.Update(account); err != nil {
+		return nil, errors.NewDBError("update account", err)
+	}
+	return account, nil
+}
+
+// Delete removes one of the user's accounts; its transactions are kept unassigned
+func (s *AccountService) Delete(userID, id uint) error {
+	if err := s.accountRepo.Delete(userID, id); err != nil {
+		return errors.NewNotFoundError("Account", id)
+	}
+	return nil
+}
+
+func applyAccountInput(account *models.Account, input models.AccountInput) error {
+	name := strings.TrimSpace(input.Name)
+	if name == "" {
+		return errors.NewInvalidInputError("name", "must not be empty")
+	}
+	account.Name = name
+
+	switch input.Type {
+	case "":
+		if account.Type == "" {
+			account.Type = models.AccountTypeOther
+		}
+	case models.AccountTypeCash, models.AccountTypeBank, models.AccountTypeCreditCard,
+		models.AccountTypeEWallet, models.AccountTypeInvestment, models.AccountTypeLoan, models.AccountTypeOther:
+		account.Type = input.Type
+	default:
+		return errors.NewInvalidInputError("type", "must be cash, bank, credit_card, e_wallet, investment, loan or other")
+	}
+	return nil
+}
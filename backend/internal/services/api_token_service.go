@@ -0,0 +1,125 @@
+package services
+
+import (
+	"billing-note/internal/models"
+	"billing-note/internal/repository"
+	"billing-note/pkg/errors"
+	"billing-note/pkg/logger"
+	"strings"
+	"time"
+)
+
+// maxAPITokens caps the active tokens per user
+const maxAPITokens = 20
+
+// APITokenService manages personal API tokens
+type APITokenService struct {
+	repo repository.APITokenRepository
+	now  func() time.Time
+}
+
+// NewAPITokenService creates a new API token service
+func NewAPITokenService(repo repository.APITokenRepository) *APITokenService {
+	return &APITokenService{repo: repo, now: time.Now}
+}
+
+// List returns the user's tokens that have not been revoked
+func (s *APITokenService) List(userID uint) ([]models.APIToken, error) {
+	tokens, err := s.repo.ListByUser(userID)
+	if err != nil {
+		return nil, errors.NewDBError("list API tokens", err)
+	}
+	return tokens, nil
+}
+
+// Create issues a token with the given scopes. The token is only returned here.
+func (s *APITokenService) Create(userID uint, input models.APITokenInput) (*models.APITokenCreated, error) {
+	log := logger.ServiceLog("APITokenService", "Create")
+
+	name := strings.TrimSpace(input.Name)
+	if name == "" {
+		return nil, errors.NewInvalidInputError("name", "must not be empty")
+	}
+	scopes, err := normalizeScopes(input.Scopes)
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := s.repo.ListByUser(userID)
+	if err != nil {
+		return nil, errors.NewDBError("list API tokens", err)
+	}
+	if len(existing) >= maxAPITokens {
+		return nil, errors.NewValidationError("Too many API tokens; revoke one you no longer use")
+	}
+
+	secret, err := generateSecretToken()
+	if err != nil {
+		return nil, errors.NewInternalError("Failed to generate API token", err)
+	}
+	raw := models.APITokenPrefix + secret
+
+	token := models.APIToken{
+		UserID:    userID,
+		Name:      name,
+		TokenHash: hashToken(raw),
+		Hint:      raw[:len(models.APITokenPrefix)+4],
+		Scopes:    scopes,
+	}
+	if input.ExpiresInDays != nil {
+		expiresAt := s.now().AddDate(0, 0, *input.ExpiresInDays)
+		token.ExpiresAt = &expiresAt
+	}
+	if err := s.repo.Create(&token); err != nil {
+		return nil, errors.NewDBError("create API token", err)
+	}
+
+	log.WithFields(logger.Fields{
+		"user_id":  userID,
+		"token_id": token.ID,
+		"scopes":   scopes,
+	}).Info("API token created")
+
+	return &models.APITokenCreated{APIToken: token, Token: raw}, nil
+}
+
+// Revoke stops a token from working
+func (s *APITokenService) Revoke(userID, id uint) error {
+	token, err := s.repo.GetByID(id)
+	if err != nil {
+		return errors.NewDBError("get API token", err)
+	}
+	if token == nil || token.UserID != userID || token.RevokedAt != nil {
+		return errors.NewNotFoundError("API token", id)
+	}
+	if err := s.repo.Revoke(id, s.now()); err != nil {
+		return errors.NewDBError("revoke API token", err)
+	}
+	return nil
+}
+
+// normalizeScopes rejects unknown scopes and drops duplicates
+func normalizeScopes(scopes []string) ([]string, error) {
+	seen := map[string]bool{}
+	var result []string
+	for _, scope := range scopes {
+		valid := false
+		for _, known := range models.APITokenScopes {
+			if scope == known {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			return nil, errors.NewInvalidInputError("scopes", "must be one of "+strings.Join(models.APITokenScopes, ", "))
+		}
+		if !seen[scope] {
+			seen[scope] = true
+			result = append(result, scope)
+		}
+	}
+	if len(result) == 0 {
+		return nil, errors.NewInvalidInputError("scopes", "must not be empty")
+	}
+	return result, nil
+}
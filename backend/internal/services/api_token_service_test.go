@@ -0,0 +1,126 @@
+package services
+
+import (
+	"billing-note/internal/models"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// --- Mock API Token Repository ---
+
+type mockAPITokenRepo struct {
+	mock.Mock
+}
+
+func (m *mockAPITokenRepo) Create(token *models.APIToken) error {
+	args := m.Called(token)
+	return args.Error(0)
+}
+
+func (m *mockAPITokenRepo) GetByID(id uint) (*models.APIToken, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.APIToken), args.Error(1)
+}
+
+func (m *mockAPITokenRepo) FindByHash(hash string) (*models.APIToken, error) {
+	args := m.Called(hash)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.APIToken), args.Error(1)
+}
+
+func (m *mockAPITokenRepo) ListByUser(userID uint) ([]models.APIToken, error) {
+	args := m.Called(userID)
+	return args.Get(0).([]models.APIToken), args.Error(1)
+}
+
+func (m *mockAPITokenRepo) Touch(id uint, usedAt time.Time, ip string) error {
+	args := m.Called(id, usedAt, ip)
+	return args.Error(0)
+}
+
+func (m *mockAPITokenRepo) Revoke(id uint, at time.Time) error {
+	args := m.Called(id, at)
+	return args.Error(0)
+}
+
+func (m *mockAPITokenRepo) RevokeAllByUser(userID uint, at time.Time) (int64, error) {
+	args := m.Called(userID, at)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+var apiTokenNow = time.Date(2026, 6, 15, 12, 0, 0, 0, time.UTC)
+
+func newTestAPITokenService() (*APITokenService, *mockAPITokenRepo) {
+	repo := new(mockAPITokenRepo)
+	svc := NewAPITokenService(repo)
+	svc.now = func() time.Time { return apiTokenNow }
+	return svc, repo
+}
+
+func TestAPITokenService_Create(t *testing.T) {
+	svc, repo := newTestAPITokenService()
+
+	repo.On("ListByUser", uint(1)).Return([]models.APIToken{}, nil)
+	var stored *models.APIToken
+	repo.On("Create", mock.Anything).Run(func(args mock.Arguments) {
+		stored = args.Get(0).(*models.APIToken)
+		stored.ID = 5
+	}).Return(nil)
+
+	days := 30
+	created, err := svc.Create(1, models.APITokenInput{
+		Name:          " backup script ",
+		Scopes:        []string{models.APIScopeExport, models.APIScopeExport, models.APIScopeStats},
+		ExpiresInDays: &days,
+	})
+	require.NoError(t, err)
+
+	assert.True(t, strings.HasPrefix(created.Token, models.APITokenPrefix))
+	assert.Equal(t, uint(5), created.ID)
+	assert.Equal(t, "backup script", stored.Name)
+	assert.Equal(t, []string{models.APIScopeExport, models.APIScopeStats}, []string(stored.Scopes))
+	require.NotNil(t, stored.ExpiresAt)
+	assert.Equal(t, apiTokenNow.AddDate(0, 0, 30), *stored.ExpiresAt)
+
+	// Only the hash is stored; the hint is the start of the token
+	assert.Equal(t, hashToken(created.Token), stored.TokenHash)
+	assert.NotContains(t, stored.TokenHash, created.Token)
+	assert.True(t, strings.HasPrefix(created.Token, stored.Hint))
+}
+
+func TestAPITokenService_Create_InvalidScope(t *testing.T) {
+	svc, repo := newTestAPITokenService()
+
+	_, err := svc.Create(1, models.APITokenInput{Name: "script", Scopes: []string{"admin"}})
+
+	assert.Equal(t, http.StatusBadRequest, statusOf(err))
+	repo.AssertNotCalled(t, "Create", mock.Anything)
+}
+
+func TestAPITokenService_Revoke(t *testing.T) {
+	svc, repo := newTestAPITokenService()
+
+	revokedAt := apiTokenNow.Add(-time.Hour)
+	repo.On("GetByID", uint(5)).Return(&models.APIToken{ID: 5, UserID: 1}, nil)
+	repo.On("GetByID", uint(6)).Return(&models.APIToken{ID: 6, UserID: 2}, nil)
+	repo.On("GetByID", uint(7)).Return(&models.APIToken{ID: 7, UserID: 1, RevokedAt: &revokedAt}, nil)
+	repo.On("Revoke", uint(5), apiTokenNow).Return(nil)
+
+	require.NoError(t, svc.Revoke(1, 5))
+
+	// Another user's token and an already revoked token are not found
+	assert.Equal(t, http.StatusNotFound, statusOf(svc.Revoke(1, 6)))
+	assert.Equal(t, http.StatusNotFound, statusOf(svc.Revoke(1, 7)))
+	repo.AssertNumberOfCalls(t, "Revoke", 1)
+}
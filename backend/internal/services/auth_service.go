@@ -6,41 +6,97 @@ import (
 	"billing-note/pkg/errors"
 	"billing-note/pkg/logger"
 	"billing-note/pkg/utils"
+	"crypto/rand"
+	"encoding/hex"
 	"time"
+
+	"gorm.io/gorm"
 )
 
 type AuthService interface {
 	Register(req *RegisterRequest) (*AuthResponse, error)
+	// Login checks the password. For users with two-factor enabled it returns
+	// a challenge to complete with LoginTwoFactor instead of tokens.
 	Login(req *LoginRequest) (*AuthResponse, error)
+	LoginTwoFactor(req *TwoFactorLoginRequest) (*AuthResponse, error)
+	// Refresh rotates the refresh token and issues a new access token
+	Refresh(refreshToken string, client models.ClientInfo) (*AuthResponse, error)
+	Logout(sessionID uint) error
+	// ListSessions returns the user's active sessions, marking the current one
+	ListSessions(userID, currentSessionID uint) ([]models.Session, error)
+	RevokeSession(userID, sessionID uint) error
+	// RevokeAllSessions revokes every session of the user except exceptID
+	RevokeAllSessions(userID, exceptID uint) (int64, error)
 }
 
 type RegisterRequest struct {
 	Email    string `json:"email" binding:"required,email"`
 	Password string `json:"password" binding:"required,min=6"`
 	Name     string `json:"name"`
+
+	Client models.ClientInfo `json:"-"`
 }
 
 type LoginRequest struct {
 	Email    string `json:"email" binding:"required,email"`
 	Password string `json:"password" binding:"required"`
+
+	Client models.ClientInfo `json:"-"`
+}
+
+type TwoFactorLoginRequest struct {
+	ChallengeToken string `json:"challenge_token" binding:"required"`
+	Code           string `json:"code" binding:"required"` // TOTP or recovery code
+
+	Client models.ClientInfo `json:"-"`
+}
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
 }
 
 type AuthResponse struct {
-	Token string       `json:"token"`
-	User  *models.User `json:"user"`
+	Token        string       `json:"token,omitempty"`
+	RefreshToken string       `json:"refresh_token,omitempty"`
+	ExpiresIn    int          `json:"expires_in,omitempty"` // access token lifetime in seconds
+	User         *models.User `json:"user,omitempty"`
+
+	// Set instead of the tokens when the login still needs the second factor
+	TwoFactorRequired bool   `json:"two_factor_required,omitempty"`
+	ChallengeToken    string `json:"challenge_token,omitempty"`
 }
 
+// twoFactorChallengeExpiry is how long the user has to enter the second factor
+const twoFactorChallengeExpiry = 5 * time.Minute
+
 type authService struct {
-	userRepo  repository.UserRepository
-	jwtSecret string
-	jwtExpiry time.Duration
+	userRepo      repository.UserRepository
+	sessionRepo   repository.SessionRepository
+	twoFactor     *TwoFactorService
+	credentials   *CredentialService
+	throttle      *LoginThrottle
+	jwtSecret     string
+	jwtExpiry     time.Duration
+	refreshExpiry time.Duration
+	now           func() time.Time
 }
 
-func NewAuthService(userRepo repository.UserRepository, jwtSecret string, jwtExpiry time.Duration) AuthService {
+// NewAuthService creates the auth service. Access tokens live for jwtExpiry;
+// sessions, and so refresh tokens, for refreshExpiry since their last refresh.
+// Without a two-factor service, logins only check the password; without a
+// credential service, new users are not sent a verification email; without a
+// login throttle, failed logins are not limited.
+func NewAuthService(userRepo repository.UserRepository, sessionRepo repository.SessionRepository, twoFactor *TwoFactorService, credentials *CredentialService, throttle *LoginThrottle, jwtSecret string, jwtExpiry, refreshExpiry time.Duration) AuthService {
 	return &authService{
-		userRepo:  userRepo,
-		jwtSecret: jwtSecret,
-		jwtExpiry: jwtExpiry,
+		userRepo:      userRepo,
+		sessionRepo:   sessionRepo,
+		twoFactor:     twoFactor,
+		credentials:   credentials,
+		throttle:      throttle,
+		jwtSecret:     jwtSecret,
+		jwtExpiry:     jwtExpiry,
+		refreshExpiry: refreshExpiry,
+		now:           time.Now,
 	}
 }
 
@@ -76,16 +132,26 @@ func (s *authService) Register(req *RegisterRequest) (*AuthResponse, error) {
 	log.WithFields(logger.Fields{
 		"user_id": user.ID,
 		"email":   user.Email,
-	}).Debug("User created, generating JWT token")
+	}).Debug("User created, starting session")
 
-	// Generate JWT token
-	token, err := utils.GenerateToken(user.ID, user.Email, s.jwtSecret, s.jwtExpiry)
+	if s.credentials != nil {
+		// Registration goes ahead even if the email cannot be sent; the
+		// user can ask for another one
+		if err := s.credentials.SendVerification(user); err != nil {
+			log.WithFields(logger.Fields{
+				"user_id": user.ID,
+				"error":   err.Error(),
+			}).Warn("Failed to send verification email")
+		}
+	}
+
+	response, err := s.startSession(user, req.Client, false)
 	if err != nil {
 		log.WithFields(logger.Fields{
 			"user_id": user.ID,
 			"error":   err.Error(),
-		}).Error("Failed to generate JWT token")
-		return nil, errors.NewInternalError("Failed to generate authentication token", err)
+		}).Error("Failed to start session")
+		return nil, err
 	}
 
 	log.WithFields(logger.Fields{
@@ -93,10 +159,7 @@ func (s *authService) Register(req *RegisterRequest) (*AuthResponse, error) {
 		"email":   user.Email,
 	}).Info("User registered successfully")
 
-	return &AuthResponse{
-		Token: token,
-		User:  user,
-	}, nil
+	return response, nil
 }
 
 func (s *authService) Login(req *LoginRequest) (*AuthResponse, error) {
@@ -104,6 +167,16 @@ func (s *authService) Login(req *LoginRequest) (*AuthResponse, error) {
 
 	log.WithField("email", req.Email).Debug("Attempting user login")
 
+	if s.throttle != nil {
+		if err := s.throttle.Check(req.Email, req.Client.IP); err != nil {
+			log.WithFields(logger.Fields{
+				"email": req.Email,
+				"ip":    req.Client.IP,
+			}).Warn("Login refused: too many failed attempts")
+			return nil, err
+		}
+	}
+
 	// Find user by email
 	user, err := s.userRepo.FindByEmail(req.Email)
 	if err != nil {
@@ -111,6 +184,7 @@ func (s *authService) Login(req *LoginRequest) (*AuthResponse, error) {
 			"email": req.Email,
 			"error": err.Error(),
 		}).Warn("Login failed: user not found")
+		s.recordLoginFailure(req, nil)
 		return nil, errors.NewInvalidCredentialsError()
 	}
 
@@ -120,22 +194,46 @@ func (s *authService) Login(req *LoginRequest) (*AuthResponse, error) {
 			"email":   req.Email,
 			"user_id": user.ID,
 		}).Warn("Login failed: invalid password")
+		s.recordLoginFailure(req, user)
 		return nil, errors.NewInvalidCredentialsError()
 	}
 
+	if s.throttle != nil {
+		if err := s.throttle.Reset(req.Email); err != nil {
+			log.WithFields(logger.Fields{
+				"user_id": user.ID,
+				"error":   err.Error(),
+			}).Warn("Failed to clear login failures")
+		}
+	}
+
+	if s.twoFactor != nil {
+		required, err := s.twoFactor.Required(user.ID)
+		if err != nil {
+			return nil, err
+		}
+		if required {
+			challenge, err := utils.GenerateChallengeToken(user.ID, user.Email, s.jwtSecret, twoFactorChallengeExpiry)
+			if err != nil {
+				return nil, errors.NewInternalError("Failed to generate two-factor challenge", err)
+			}
+			log.WithField("user_id", user.ID).Debug("Password verified, awaiting second factor")
+			return &AuthResponse{TwoFactorRequired: true, ChallengeToken: challenge}, nil
+		}
+	}
+
 	log.WithFields(logger.Fields{
 		"user_id": user.ID,
 		"email":   user.Email,
-	}).Debug("Password verified, generating JWT token")
+	}).Debug("Password verified, starting session")
 
-	// Generate JWT token
-	token, err := utils.GenerateToken(user.ID, user.Email, s.jwtSecret, s.jwtExpiry)
+	response, err := s.startSession(user, req.Client, false)
 	if err != nil {
 		log.WithFields(logger.Fields{
 			"user_id": user.ID,
 			"error":   err.Error(),
-		}).Error("Failed to generate JWT token")
-		return nil, errors.NewInternalError("Failed to generate authentication token", err)
+		}).Error("Failed to start session")
+		return nil, err
 	}
 
 	log.WithFields(logger.Fields{
@@ -143,8 +241,216 @@ func (s *authService) Login(req *LoginRequest) (*AuthResponse, error) {
 		"email":   user.Email,
 	}).Info("User logged in successfully")
 
+	return response, nil
+}
+
+// recordLoginFailure counts a failed login towards the throttle. Errors are
+// logged rather than returned so the caller still sees invalid credentials.
+func (s *authService) recordLoginFailure(req *LoginRequest, user *models.User) {
+	if s.throttle == nil {
+		return
+	}
+	if err := s.throttle.RecordFailure(req.Email, req.Client.IP, user); err != nil {
+		logger.ServiceLog("AuthService", "Login").WithFields(logger.Fields{
+			"email": req.Email,
+			"error": err.Error(),
+		}).Error("Failed to record login failure")
+	}
+}
+
+func (s *authService) LoginTwoFactor(req *TwoFactorLoginRequest) (*AuthResponse, error) {
+	log := logger.ServiceLog("AuthService", "LoginTwoFactor")
+
+	if s.twoFactor == nil {
+		return nil, errors.NewValidationError("Two-factor authentication is not available")
+	}
+
+	claims, err := utils.ValidateChallengeToken(req.ChallengeToken, s.jwtSecret)
+	if err != nil {
+		return nil, errors.NewInvalidTokenError("Invalid or expired two-factor challenge")
+	}
+	user, err := s.userRepo.FindByID(claims.UserID)
+	if err != nil {
+		return nil, errors.NewInvalidTokenError("Invalid or expired two-factor challenge")
+	}
+
+	if err := s.twoFactor.VerifyCode(user.ID, req.Code); err != nil {
+		return nil, err
+	}
+
+	response, err := s.startSession(user, req.Client, true)
+	if err != nil {
+		log.WithFields(logger.Fields{
+			"user_id": user.ID,
+			"error":   err.Error(),
+		}).Error("Failed to start session")
+		return nil, err
+	}
+
+	log.WithFields(logger.Fields{
+		"user_id": user.ID,
+		"email":   user.Email,
+	}).Info("User logged in with two-factor authentication")
+
+	return response, nil
+}
+
+func (s *authService) Refresh(refreshToken string, client models.ClientInfo) (*AuthResponse, error) {
+	log := logger.ServiceLog("AuthService", "Refresh")
+
+	hash := hashToken(refreshToken)
+	session, err := s.sessionRepo.FindByRefreshHash(hash)
+	if err != nil {
+		return nil, errors.NewDBError("find session", err)
+	}
+	now := s.now()
+	if session == nil || !session.Active(now) {
+		return nil, errors.NewInvalidTokenError("Invalid or expired refresh token")
+	}
+
+	// A rotated-out token being used again means it was copied; end the
+	// session so neither copy keeps working
+	if session.RefreshTokenHash != hash {
+		log.WithFields(logger.Fields{
+			"user_id":    session.UserID,
+			"session_id": session.ID,
+		}).Warn("Refresh token reuse detected, revoking session")
+		if err := s.sessionRepo.Revoke(session.ID, now); err != nil {
+			return nil, errors.NewDBError("revoke session", err)
+		}
+		return nil, errors.NewInvalidTokenError("Invalid or expired refresh token")
+	}
+
+	user, err := s.userRepo.FindByID(session.UserID)
+	if err != nil {
+		return nil, errors.NewInvalidTokenError("Invalid or expired refresh token")
+	}
+
+	newToken, err := generateSecretToken()
+	if err != nil {
+		return nil, errors.NewInternalError("Failed to generate refresh token", err)
+	}
+	session.PreviousTokenHash = session.RefreshTokenHash
+	session.RefreshTokenHash = hashToken(newToken)
+	session.LastSeenAt = now
+	session.ExpiresAt = now.Add(s.refreshExpiry)
+	if client.UserAgent != "" {
+		session.UserAgent = truncate(client.UserAgent, 255)
+	}
+	if client.IP != "" {
+		session.IP = client.IP
+	}
+	if err := s.sessionRepo.Update(session); err != nil {
+		return nil, errors.NewDBError("rotate refresh token", err)
+	}
+
+	return s.tokens(user, session, newToken)
+}
+
+func (s *authService) Logout(sessionID uint) error {
+	if sessionID == 0 {
+		return errors.NewValidationError("Request is not bound to a session")
+	}
+	if err := s.sessionRepo.Revoke(sessionID, s.now()); err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil
+		}
+		return errors.NewDBError("revoke session", err)
+	}
+	return nil
+}
+
+func (s *authService) ListSessions(userID, currentSessionID uint) ([]models.Session, error) {
+	sessions, err := s.sessionRepo.ListActiveByUser(userID, s.now())
+	if err != nil {
+		return nil, errors.NewDBError("list sessions", err)
+	}
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID == currentSessionID
+	}
+	return sessions, nil
+}
+
+func (s *authService) RevokeSession(userID, sessionID uint) error {
+	session, err := s.sessionRepo.GetByID(sessionID)
+	if err != nil {
+		return errors.NewDBError("get session", err)
+	}
+	if session == nil || session.UserID != userID || session.RevokedAt != nil {
+		return errors.NewNotFoundError("Session", sessionID)
+	}
+	if err := s.sessionRepo.Revoke(sessionID, s.now()); err != nil {
+		return errors.NewDBError("revoke session", err)
+	}
+	return nil
+}
+
+func (s *authService) RevokeAllSessions(userID, exceptID uint) (int64, error) {
+	count, err := s.sessionRepo.RevokeAllByUser(userID, exceptID, s.now())
+	if err != nil {
+		return 0, errors.NewDBError("revoke sessions", err)
+	}
+	return count, nil
+}
+
+// startSession creates a session for the device and issues its tokens. A
+// verified session starts with the user's identity freshly confirmed.
+func (s *authService) startSession(user *models.User, client models.ClientInfo, verified bool) (*AuthResponse, error) {
+	refreshToken, err := generateSecretToken()
+	if err != nil {
+		return nil, errors.NewInternalError("Failed to generate refresh token", err)
+	}
+
+	now := s.now()
+	session := &models.Session{
+		UserID:           user.ID,
+		RefreshTokenHash: hashToken(refreshToken),
+		UserAgent:        truncate(client.UserAgent, 255),
+		IP:               client.IP,
+		LastSeenAt:       now,
+		ExpiresAt:        now.Add(s.refreshExpiry),
+	}
+	if verified {
+		session.VerifiedAt = &now
+	}
+	if err := s.sessionRepo.Create(session); err != nil {
+		return nil, errors.NewDBError("session creation", err)
+	}
+
+	return s.tokens(user, session, refreshToken)
+}
+
+func (s *authService) tokens(user *models.User, session *models.Session, refreshToken string) (*AuthResponse, error) {
+	token, err := utils.GenerateSessionToken(user.ID, user.Email, session.ID, s.jwtSecret, s.jwtExpiry)
+	if err != nil {
+		return nil, errors.NewInternalError("Failed to generate authentication token", err)
+	}
+
 	return &AuthResponse{
-		Token: token,
-		User:  user,
+		Token:        token,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int(s.jwtExpiry.Seconds()),
+		User:         user,
 	}, nil
 }
+
+// generateSecretToken returns 32 random bytes, hex encoded
+func generateSecretToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// hashToken returns the SHA-256 hex digest stored in place of a secret token
+func hashToken(token string) string {
+	return utils.HashToken(token)
+}
+
+func truncate(s string, max int) string {
+	if len(s) > max {
+		return s[:max]
+	}
+	return s
+}
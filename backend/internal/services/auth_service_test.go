@@ -2,12 +2,15 @@ package services
 
 import (
 	"billing-note/internal/models"
+	"billing-note/pkg/utils"
 	"errors"
+	"net/http"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 )
 
 // Mock UserRepository
@@ -46,9 +49,75 @@ func (m *MockUserRepository) Delete(id uint) error {
 	return args.Error(0)
 }
 
+// --- Mock Session Repository ---
+
+type mockSessionRepo struct {
+	mock.Mock
+}
+
+func (m *mockSessionRepo) Create(session *models.Session) error {
+	args := m.Called(session)
+	return args.Error(0)
+}
+
+func (m *mockSessionRepo) GetByID(id uint) (*models.Session, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Session), args.Error(1)
+}
+
+func (m *mockSessionRepo) FindByRefreshHash(hash string) (*models.Session, error) {
+	args := m.Called(hash)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Session), args.Error(1)
+}
+
+func (m *mockSessionRepo) Update(session *models.Session) error {
+	args := m.Called(session)
+	return args.Error(0)
+}
+
+func (m *mockSessionRepo) Touch(id uint, seenAt time.Time, ip string) error {
+	args := m.Called(id, seenAt, ip)
+	return args.Error(0)
+}
+
+func (m *mockSessionRepo) MarkVerified(id uint, at time.Time) error {
+	args := m.Called(id, at)
+	return args.Error(0)
+}
+
+func (m *mockSessionRepo) ListActiveByUser(userID uint, now time.Time) ([]models.Session, error) {
+	args := m.Called(userID, now)
+	return args.Get(0).([]models.Session), args.Error(1)
+}
+
+func (m *mockSessionRepo) Revoke(id uint, at time.Time) error {
+	args := m.Called(id, at)
+	return args.Error(0)
+}
+
+func (m *mockSessionRepo) RevokeAllByUser(userID, exceptID uint, at time.Time) (int64, error) {
+	args := m.Called(userID, exceptID, at)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+// acceptingSessionRepo stores every new session as session 7
+func acceptingSessionRepo() *mockSessionRepo {
+	repo := new(mockSessionRepo)
+	repo.On("Create", mock.AnythingOfType("*models.Session")).Run(func(args mock.Arguments) {
+		args.Get(0).(*models.Session).ID = 7
+	}).Return(nil)
+	return repo
+}
+
 func TestAuthService_Register_Success(t *testing.T) {
 	mockRepo := new(MockUserRepository)
-	service := NewAuthService(mockRepo, "test-secret", 24*time.Hour)
+	service := NewAuthService(mockRepo, acceptingSessionRepo(), nil, nil, nil, "test-secret", 24*time.Hour, 720*time.Hour)
 
 	req := &RegisterRequest{
 		Email:    "test@example.com",
@@ -71,7 +140,7 @@ func TestAuthService_Register_Success(t *testing.T) {
 
 func TestAuthService_Register_EmailExists(t *testing.T) {
 	mockRepo := new(MockUserRepository)
-	service := NewAuthService(mockRepo, "test-secret", 24*time.Hour)
+	service := NewAuthService(mockRepo, acceptingSessionRepo(), nil, nil, nil, "test-secret", 24*time.Hour, 720*time.Hour)
 
 	existingUser := &models.User{
 		ID:    1,
@@ -95,7 +164,7 @@ func TestAuthService_Register_EmailExists(t *testing.T) {
 
 func TestAuthService_Register_CreateError(t *testing.T) {
 	mockRepo := new(MockUserRepository)
-	service := NewAuthService(mockRepo, "test-secret", 24*time.Hour)
+	service := NewAuthService(mockRepo, acceptingSessionRepo(), nil, nil, nil, "test-secret", 24*time.Hour, 720*time.Hour)
 
 	req := &RegisterRequest{
 		Email:    "test@example.com",
@@ -114,7 +183,7 @@ func TestAuthService_Register_CreateError(t *testing.T) {
 
 func TestAuthService_Login_Success(t *testing.T) {
 	mockRepo := new(MockUserRepository)
-	service := NewAuthService(mockRepo, "test-secret", 24*time.Hour)
+	service := NewAuthService(mockRepo, acceptingSessionRepo(), nil, nil, nil, "test-secret", 24*time.Hour, 720*time.Hour)
 
 	password := "password123"
 	user := &models.User{
@@ -141,7 +210,7 @@ func TestAuthService_Login_Success(t *testing.T) {
 
 func TestAuthService_Login_UserNotFound(t *testing.T) {
 	mockRepo := new(MockUserRepository)
-	service := NewAuthService(mockRepo, "test-secret", 24*time.Hour)
+	service := NewAuthService(mockRepo, acceptingSessionRepo(), nil, nil, nil, "test-secret", 24*time.Hour, 720*time.Hour)
 
 	req := &LoginRequest{
 		Email:    "notfound@example.com",
@@ -160,7 +229,7 @@ func TestAuthService_Login_UserNotFound(t *testing.T) {
 
 func TestAuthService_Login_WrongPassword(t *testing.T) {
 	mockRepo := new(MockUserRepository)
-	service := NewAuthService(mockRepo, "test-secret", 24*time.Hour)
+	service := NewAuthService(mockRepo, acceptingSessionRepo(), nil, nil, nil, "test-secret", 24*time.Hour, 720*time.Hour)
 
 	user := &models.User{
 		ID:    1,
@@ -182,3 +251,166 @@ func TestAuthService_Login_WrongPassword(t *testing.T) {
 
 	mockRepo.AssertExpectations(t)
 }
+
+var authNow = time.Date(2026, 6, 15, 12, 0, 0, 0, time.UTC)
+
+func newTestAuthService(userRepo *MockUserRepository, sessionRepo *mockSessionRepo) *authService {
+	svc := NewAuthService(userRepo, sessionRepo, nil, nil, nil, "test-secret", 15*time.Minute, 720*time.Hour).(*authService)
+	svc.now = func() time.Time { return authNow }
+	return svc
+}
+
+func TestAuthService_Login_StartsSession(t *testing.T) {
+	userRepo := new(MockUserRepository)
+	sessionRepo := acceptingSessionRepo()
+	svc := newTestAuthService(userRepo, sessionRepo)
+
+	user := &models.User{ID: 1, Email: "test@example.com"}
+	user.SetPassword("password123")
+	userRepo.On("FindByEmail", "test@example.com").Return(user, nil)
+
+	response, err := svc.Login(&LoginRequest{
+		Email:    "test@example.com",
+		Password: "password123",
+		Client:   models.ClientInfo{UserAgent: "Firefox", IP: "10.0.0.1"},
+	})
+
+	assert.NoError(t, err)
+	assert.Len(t, response.RefreshToken, 64)
+	assert.Equal(t, 900, response.ExpiresIn)
+
+	session := sessionRepo.Calls[0].Arguments.Get(0).(*models.Session)
+	assert.Equal(t, hashToken(response.RefreshToken), session.RefreshTokenHash)
+	assert.Equal(t, "Firefox", session.UserAgent)
+	assert.Equal(t, "10.0.0.1", session.IP)
+	assert.Equal(t, authNow.Add(720*time.Hour), session.ExpiresAt)
+
+	claims, err := utils.ValidateToken(response.Token, "test-secret")
+	assert.NoError(t, err)
+	assert.Equal(t, uint(7), claims.SessionID)
+}
+
+func TestAuthService_Refresh_RotatesToken(t *testing.T) {
+	userRepo := new(MockUserRepository)
+	sessionRepo := new(mockSessionRepo)
+	svc := newTestAuthService(userRepo, sessionRepo)
+
+	session := &models.Session{ID: 7, UserID: 1, RefreshTokenHash: hashToken("old-token"), ExpiresAt: authNow.Add(time.Hour)}
+	sessionRepo.On("FindByRefreshHash", hashToken("old-token")).Return(session, nil)
+	sessionRepo.On("Update", mock.Anything).Return(nil)
+	userRepo.On("FindByID", uint(1)).Return(&models.User{ID: 1, Email: "test@example.com"}, nil)
+
+	response, err := svc.Refresh("old-token", models.ClientInfo{IP: "10.0.0.2"})
+
+	assert.NoError(t, err)
+	assert.NotEqual(t, "old-token", response.RefreshToken)
+	assert.Equal(t, hashToken(response.RefreshToken), session.RefreshTokenHash)
+	assert.Equal(t, hashToken("old-token"), session.PreviousTokenHash)
+	assert.Equal(t, authNow, session.LastSeenAt)
+	assert.Equal(t, authNow.Add(720*time.Hour), session.ExpiresAt)
+	assert.Equal(t, "10.0.0.2", session.IP)
+}
+
+func TestAuthService_Refresh_ReuseRevokesSession(t *testing.T) {
+	userRepo := new(MockUserRepository)
+	sessionRepo := new(mockSessionRepo)
+	svc := newTestAuthService(userRepo, sessionRepo)
+
+	session := &models.Session{ID: 7, UserID: 1, RefreshTokenHash: hashToken("new-token"), PreviousTokenHash: hashToken("old-token"), ExpiresAt: authNow.Add(time.Hour)}
+	sessionRepo.On("FindByRefreshHash", hashToken("old-token")).Return(session, nil)
+	sessionRepo.On("Revoke", uint(7), authNow).Return(nil)
+
+	_, err := svc.Refresh("old-token", models.ClientInfo{})
+
+	assert.Equal(t, http.StatusUnauthorized, statusOf(err))
+	sessionRepo.AssertExpectations(t)
+	sessionRepo.AssertNotCalled(t, "Update", mock.Anything)
+}
+
+func TestAuthService_Refresh_Rejected(t *testing.T) {
+	userRepo := new(MockUserRepository)
+	sessionRepo := new(mockSessionRepo)
+	svc := newTestAuthService(userRepo, sessionRepo)
+
+	revokedAt := authNow.Add(-time.Minute)
+	sessionRepo.On("FindByRefreshHash", hashToken("unknown")).Return(nil, nil)
+	sessionRepo.On("FindByRefreshHash", hashToken("revoked")).Return(&models.Session{ID: 8, RefreshTokenHash: hashToken("revoked"), ExpiresAt: authNow.Add(time.Hour), RevokedAt: &revokedAt}, nil)
+	sessionRepo.On("FindByRefreshHash", hashToken("expired")).Return(&models.Session{ID: 9, RefreshTokenHash: hashToken("expired"), ExpiresAt: authNow}, nil)
+
+	for _, token := range []string{"unknown", "revoked", "expired"} {
+		_, err := svc.Refresh(token, models.ClientInfo{})
+		assert.Equal(t, http.StatusUnauthorized, statusOf(err), token)
+	}
+	sessionRepo.AssertNotCalled(t, "Update", mock.Anything)
+}
+
+func TestAuthService_Sessions(t *testing.T) {
+	userRepo := new(MockUserRepository)
+	sessionRepo := new(mockSessionRepo)
+	svc := newTestAuthService(userRepo, sessionRepo)
+
+	sessionRepo.On("ListActiveByUser", uint(1), authNow).Return([]models.Session{{ID: 7, UserID: 1}, {ID: 8, UserID: 1}}, nil)
+	sessionRepo.On("GetByID", uint(8)).Return(&models.Session{ID: 8, UserID: 1}, nil)
+	sessionRepo.On("GetByID", uint(9)).Return(&models.Session{ID: 9, UserID: 2}, nil)
+	sessionRepo.On("Revoke", uint(8), authNow).Return(nil)
+	sessionRepo.On("RevokeAllByUser", uint(1), uint(7), authNow).Return(int64(1), nil)
+
+	sessions, err := svc.ListSessions(1, 7)
+	assert.NoError(t, err)
+	assert.True(t, sessions[0].Current)
+	assert.False(t, sessions[1].Current)
+
+	assert.NoError(t, svc.RevokeSession(1, 8))
+
+	// Another user's session is not found
+	assert.Equal(t, http.StatusNotFound, statusOf(svc.RevokeSession(1, 9)))
+
+	count, err := svc.RevokeAllSessions(1, 7)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+	sessionRepo.AssertExpectations(t)
+}
+
+func TestAuthService_Login_TwoFactor(t *testing.T) {
+	twoFactor, twoFactorRepo, userRepo, _ := newTestTwoFactorService(t)
+	sessionRepo := acceptingSessionRepo()
+	svc := NewAuthService(userRepo, sessionRepo, twoFactor, nil, nil, "test-secret", 15*time.Minute, 720*time.Hour).(*authService)
+	svc.now = func() time.Time { return twoFactorNow }
+
+	user := &models.User{ID: 1, Email: "test@example.com"}
+	user.SetPassword("password123")
+	userRepo.On("FindByEmail", "test@example.com").Return(user, nil)
+	userRepo.On("FindByID", uint(1)).Return(user, nil)
+	enrolled := enrollment(t, twoFactor, true)
+	twoFactorRepo.On("Get", uint(1)).Return(enrolled, nil)
+	twoFactorRepo.On("Save", enrolled).Return(nil)
+
+	// The password step only returns a challenge
+	response, err := svc.Login(&LoginRequest{Email: "test@example.com", Password: "password123"})
+	require.NoError(t, err)
+	assert.True(t, response.TwoFactorRequired)
+	assert.Empty(t, response.Token)
+	assert.Empty(t, response.RefreshToken)
+	sessionRepo.AssertNotCalled(t, "Create", mock.Anything)
+
+	// The challenge is not an access token
+	_, err = utils.ValidateToken(response.ChallengeToken, "test-secret")
+	assert.Error(t, err)
+
+	_, err = svc.LoginTwoFactor(&TwoFactorLoginRequest{ChallengeToken: "forged", Code: currentCode(t)})
+	assert.Equal(t, http.StatusUnauthorized, statusOf(err))
+
+	// A wrong code does not complete the login
+	_, err = svc.LoginTwoFactor(&TwoFactorLoginRequest{ChallengeToken: response.ChallengeToken, Code: "000000"})
+	assert.Equal(t, http.StatusBadRequest, statusOf(err))
+
+	loggedIn, err := svc.LoginTwoFactor(&TwoFactorLoginRequest{ChallengeToken: response.ChallengeToken, Code: currentCode(t)})
+	require.NoError(t, err)
+	assert.NotEmpty(t, loggedIn.Token)
+	assert.NotEmpty(t, loggedIn.RefreshToken)
+
+	// Passing the second factor counts as a fresh verification
+	session := sessionRepo.Calls[0].Arguments.Get(0).(*models.Session)
+	require.NotNil(t, session.VerifiedAt)
+	assert.Equal(t, twoFactorNow, *session.VerifiedAt)
+}
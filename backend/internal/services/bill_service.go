@@ -0,0 +1,421 @@
+package services
+
+import (
+	"billing-note/internal/models"
+	"billing-note/internal/pdf"
+	"billing-note/internal/repository"
+	"billing-note/pkg/errors"
+	"billing-note/pkg/logger"
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+// BillReminderInterval is how often pending bills are checked for reminders
+const BillReminderInterval = time.Hour
+
+// maxBillRemindDays caps how early a reminder can be requested
+const maxBillRemindDays = 30
+
+// Payments are matched to bills due up to billPaymentLeadDays after, or
+// billPaymentGraceDays before, the payment date
+const (
+	billPaymentLeadDays  = 31
+	billPaymentGraceDays = 7
+)
+
+// BillService manages payment obligations and their due-date reminders
+type BillService struct {
+	billRepo        repository.BillRepository
+	accountRepo     repository.AccountRepository
+	transactionRepo repository.TransactionRepository
+	userRepo        repository.UserRepository
+	notifications   *NotificationService
+	netWorth        *NetWorthService
+	now             func() time.Time
+}
+
+// NewBillService creates a new bill service
+func NewBillService(billRepo repository.BillRepository, accountRepo repository.AccountRepository, transactionRepo repository.TransactionRepository, userRepo repository.UserRepository, notifications *NotificationService) *BillService {
+	return &BillService{
+		billRepo:        billRepo,
+		accountRepo:     accountRepo,
+		transactionRepo: transactionRepo,
+		userRepo:        userRepo,
+		notifications:   notifications,
+		now:             time.Now,
+	}
+}
+
+// SetNetWorthService records statement balances as card account snapshots
+func (s *BillService) SetNetWorthService(svc *NetWorthService) {
+	s.netWorth = svc
+}
+
+// List returns the user's bills, optionally filtered by status
+func (s *BillService) List(userID uint, status string) ([]models.Bill, error) {
+	if status != "" && status != models.BillStatusPending && status != models.BillStatusPaid {
+		return nil, errors.NewInvalidInputError("status", "must be pending or paid")
+	}
+	bills, err := s.billRepo.List(userID, status)
+	if err != nil {
+		return nil, errors.NewDBError("list bills", err)
+	}
+	return bills, nil
+}
+
+// Get returns one of the user's bills
+func (s *BillService) Get(userID, id uint) (*models.Bill, error) {
+	bill, err := s.billRepo.GetByID(userID, id)
+	if err != nil {
+		return nil, errors.NewNotFoundError("Bill", id)
+	}
+	return bill, nil
+}
+
+// Overdue returns the user's unpaid bills whose due date has passed
+func (s *BillService) Overdue(userID uint) ([]models.Bill, error) {
+	today := dateOf(s.now(), userLocation(s.userRepo, userID))
+	bills, err := s.billRepo.ListOverdue(userID, today)
+	if err != nil {
+		return nil, errors.NewDBError("list overdue bills", err)
+	}
+	return bills, nil
+}
+
+// Create adds a manually entered bill
+func (s *BillService) Create(userID uint, input models.BillInput) (*models.Bill, error) {
+	bill := &models.Bill{
+		UserID:     userID,
+		Source:     models.BillSourceManual,
+		Status:     models.BillStatusPending,
+		RemindDays: models.DefaultBillRemindDays,
+	}
+	if err := s.applyBillInput(bill, input); err != nil {
+		return nil, err
+	}
+	if err := s.billRepo.Create(bill); err != nil {
+		return nil, errors.NewDBError("create bill", err)
+	}
+	return bill, nil
+}
+
+// Update edits a bill. Changing the due date or reminder lead time re-arms
+// the reminder.
+func (s *BillService) Update(userID, id uint, input models.BillInput) (*models.Bill, error) {
+	bill, err := s.billRepo.GetByID(userID, id)
+	if err != nil {
+		return nil, errors.NewNotFoundError("Bill", id)
+	}
+
+	before := *bill
+	if err := s.applyBillInput(bill, input); err != nil {
+		return nil, err
+	}
+	if !bill.DueDate.Equal(before.DueDate) || bill.RemindDays != before.RemindDays {
+		bill.RemindedAt = nil
+		bill.OverdueNotifiedAt = nil
+	}
+
+	if err := s.billRepo.Update(bill); err != nil {
+		return nil, errors.NewDBError("update bill", err)
+	}
+	return bill, nil
+}
+
+// Delete removes one of the user's bills
+func (s *BillService) Delete(userID, id uint) error {
+	if err := s.billRepo.Delete(userID, id); err != nil {
+		return errors.NewNotFoundError("Bill", id)
+	}
+	return nil
+}
+
+// MarkPaid settles a bill, optionally linking the transaction that paid it
+func (s *BillService) MarkPaid(userID, id uint, req models.MarkBillPaidRequest) (*models.Bill, error) {
+	bill, err := s.billRepo.GetByID(userID, id)
+	if err != nil {
+		return nil, errors.NewNotFoundError("Bill", id)
+	}
+
+	paidAt := dateOf(s.now(), userLocation(s.userRepo, userID))
+	paidAmount := bill.AmountDue
+
+	bill.PaymentTransactionID = nil
+	bill.PaymentTransaction = nil
+	if req.TransactionID != nil {
+		txn, err := s.transactionRepo.GetByID(*req.TransactionID)
+		if err != nil || txn.UserID != userID {
+			return nil, errors.NewNotFoundError("Transaction", *req.TransactionID)
+		}
+		bill.PaymentTransactionID = &txn.ID
+		paidAt = dateOf(txn.TransactionDate, time.UTC)
+		paidAmount = txn.Amount
+	}
+	if req.PaidAt != "" {
+		d, err := time.Parse(budgetDateLayout, req.PaidAt)
+		if err != nil {
+			return nil, errors.NewInvalidInputError("paid_at", "must be YYYY-MM-DD")
+		}
+		paidAt = d
+	}
+	if req.PaidAmount != nil {
+		if *req.PaidAmount < 0 {
+			return nil, errors.NewInvalidInputError("paid_amount", "must not be negative")
+		}
+		paidAmount = *req.PaidAmount
+	}
+
+	bill.Status = models.BillStatusPaid
+	bill.PaidAt = &paidAt
+	bill.PaidAmount = paidAmount
+	if err := s.billRepo.Update(bill); err != nil {
+		return nil, errors.NewDBError("update bill", err)
+	}
+	return bill, nil
+}
+
+// RecordStatement creates the bill for an imported card statement. Parsing
+// the same statement again updates the pending bill instead of adding another.
+func (s *BillService) RecordStatement(userID uint, issuer string, summary *pdf.StatementSummary) (*models.Bill, error) {
+	if summary == nil || summary.DueDate == nil {
+		return nil, nil
+	}
+	dueDate := dateOf(*summary.DueDate, time.UTC)
+
+	bill, err := s.billRepo.FindByIssuerDueDate(userID, issuer, dueDate)
+	if err == nil {
+		if bill.Status == models.BillStatusPending {
+			bill.AmountDue = summary.AmountDue
+			bill.MinimumDue = summary.MinimumDue
+			bill.StatementDate = summary.ClosingDate
+			if err := s.billRepo.Update(bill); err != nil {
+				return nil, errors.NewDBError("update bill", err)
+			}
+			s.recordStatementBalance(bill)
+		}
+		return bill, nil
+	}
+
+	bill = &models.Bill{
+		UserID:        userID,
+		Issuer:        issuer,
+		Description:   fmt.Sprintf("%s card statement", issuer),
+		Source:        models.BillSourceStatement,
+		StatementDate: summary.ClosingDate,
+		DueDate:       dueDate,
+		AmountDue:     summary.AmountDue,
+		MinimumDue:    summary.MinimumDue,
+		RemindDays:    models.DefaultBillRemindDays,
+		Status:        models.BillStatusPending,
+		AccountID:     s.cardAccountFor(userID, issuer),
+	}
+	// Nothing to pay on a zero or credit balance
+	if bill.AmountDue <= 0 {
+		bill.Status = models.BillStatusPaid
+	}
+	if err := s.billRepo.Create(bill); err != nil {
+		return nil, errors.NewDBError("create bill", err)
+	}
+	s.recordStatementBalance(bill)
+	return bill, nil
+}
+
+// cardAccountFor returns the user's only credit card account named after the
+// issuer, if any
+func (s *BillService) cardAccountFor(userID uint, issuer string) *uint {
+	if s.accountRepo == nil || issuer == "" {
+		return nil
+	}
+	accounts, err := s.accountRepo.List(userID)
+	if err != nil {
+		return nil
+	}
+	issuer = strings.ToLower(issuer)
+	var match *uint
+	for _, account := range accounts {
+		name := strings.ToLower(account.Name)
+		if account.Type != models.AccountTypeCreditCard || !(strings.Contains(name, issuer) || strings.Contains(issuer, name)) {
+			continue
+		}
+		if match != nil {
+			return nil // ambiguous
+		}
+		id := account.ID
+		match = &id
+	}
+	return match
+}
+
+// recordStatementBalance records a statement bill's amount due as the card
+// account's balance on the closing date
+func (s *BillService) recordStatementBalance(bill *models.Bill) {
+	if s.netWorth == nil || bill.AccountID == nil || bill.StatementDate == nil {
+		return
+	}
+	if err := s.netWorth.RecordStatementBalance(bill.UserID, *bill.AccountID, *bill.StatementDate, bill.AmountDue); err != nil {
+		logger.ServiceLog("BillService", "RecordStatement").WithError(err).Warn("Failed to record statement balance")
+	}
+}
+
+// MatchPayment marks the pending bill paid by a card payment, such as the
+// transfers recorded from bill-pay lines on import, and links the payment
+// transaction if given. A payment matches when it equals the amount due of
+// exactly one bill due around its date.
+func (s *BillService) MatchPayment(userID uint, date time.Time, amount float64, transactionID *uint) (*models.Bill, bool) {
+	amount = math.Abs(amount)
+	if amount == 0 {
+		return nil, false
+	}
+	date = dateOf(date, time.UTC)
+
+	bills, err := s.billRepo.FindPendingByAmount(userID, amount,
+		date.AddDate(0, 0, -billPaymentGraceDays), date.AddDate(0, 0, billPaymentLeadDays))
+	if err != nil || len(bills) != 1 {
+		return nil, false
+	}
+
+	bill := &bills[0]
+	bill.Status = models.BillStatusPaid
+	bill.PaidAt = &date
+	bill.PaidAmount = amount
+	bill.PaymentTransactionID = transactionID
+	if err := s.billRepo.Update(bill); err != nil {
+		logger.ServiceLog("BillService", "MatchPayment").WithError(err).Warn("Failed to mark bill paid")
+		return nil, false
+	}
+	return bill, true
+}
+
+// RunReminders sends due-soon reminders and overdue notices for every user's
+// pending bills. Each is sent at most once per bill.
+func (s *BillService) RunReminders() {
+	log := logger.ServiceLog("BillService", "RunReminders")
+
+	horizon := dateOf(s.now(), time.UTC).AddDate(0, 0, maxBillRemindDays+1)
+	bills, err := s.billRepo.ListPendingDueBy(horizon)
+	if err != nil {
+		log.WithError(err).Error("Failed to list pending bills")
+		return
+	}
+
+	todayByUser := make(map[uint]time.Time)
+	for i := range bills {
+		bill := &bills[i]
+		today, ok := todayByUser[bill.UserID]
+		if !ok {
+			today = dateOf(s.now(), userLocation(s.userRepo, bill.UserID))
+			todayByUser[bill.UserID] = today
+		}
+		dueDate := dateOf(bill.DueDate, time.UTC)
+
+		var notificationType, title string
+		switch {
+		case dueDate.Before(today) && bill.OverdueNotifiedAt == nil:
+			notificationType = models.NotificationBillOverdue
+			title = fmt.Sprintf("%s is overdue", billLabel(bill))
+		case !dueDate.Before(today) && bill.RemindedAt == nil &&
+			!today.Before(dueDate.AddDate(0, 0, -bill.RemindDays)):
+			notificationType = models.NotificationBillReminder
+			if dueDate.Equal(today) {
+				title = fmt.Sprintf("%s is due today", billLabel(bill))
+			} else {
+				title = fmt.Sprintf("%s is due in %d days", billLabel(bill), int(dueDate.Sub(today).Hours()/24))
+			}
+		default:
+			continue
+		}
+
+		message := fmt.Sprintf("Amount due: %.0f (minimum %.0f), due %s.",
+			bill.AmountDue, bill.MinimumDue, dueDate.Format(budgetDateLayout))
+		if _, err := s.notifications.Notify(bill.UserID, notificationType, title, message, map[string]interface{}{
+			"bill_id":    bill.ID,
+			"due_date":   dueDate.Format(budgetDateLayout),
+			"amount_due": bill.AmountDue,
+		}); err != nil {
+			log.WithError(err).Warn("Failed to send bill reminder")
+			continue
+		}
+
+		now := s.now()
+		if notificationType == models.NotificationBillOverdue {
+			bill.OverdueNotifiedAt = &now
+		}
+		// An overdue notice also covers a reminder that was never sent
+		bill.RemindedAt = &now
+		if err := s.billRepo.Update(bill); err != nil {
+			log.WithError(err).Warn("Failed to record bill reminder")
+		}
+	}
+}
+
+// StartScheduler runs RunReminders immediately and then on every interval
+// until the context is cancelled
+func (s *BillService) StartScheduler(ctx context.Context, interval time.Duration) {
+	go func() {
+		s.RunReminders()
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				s.RunReminders()
+			}
+		}
+	}()
+}
+
+// applyBillInput validates the input and sets it on the bill
+func (s *BillService) applyBillInput(bill *models.Bill, input models.BillInput) error {
+	dueDate, err := time.Parse(budgetDateLayout, input.DueDate)
+	if err != nil {
+		return errors.NewInvalidInputError("due_date", "must be YYYY-MM-DD")
+	}
+	if input.AccountID != nil {
+		if _, err := s.accountRepo.GetByID(bill.UserID, *input.AccountID); err != nil {
+			return errors.NewNotFoundError("Account", *input.AccountID)
+		}
+	}
+	if input.RemindDays != nil {
+		if *input.RemindDays < 0 || *input.RemindDays > maxBillRemindDays {
+			return errors.NewInvalidInputError("remind_days", fmt.Sprintf("must be between 0 and %d", maxBillRemindDays))
+		}
+		bill.RemindDays = *input.RemindDays
+	}
+	if input.MinimumDue > input.AmountDue {
+		return errors.NewInvalidInputError("minimum_due", "must not exceed amount_due")
+	}
+
+	bill.AccountID = input.AccountID
+	bill.Account = nil
+	if issuer := strings.TrimSpace(input.Issuer); issuer != "" || bill.Source == models.BillSourceManual {
+		bill.Issuer = issuer
+	}
+	bill.Description = strings.TrimSpace(input.Description)
+	bill.DueDate = dueDate
+	bill.AmountDue = input.AmountDue
+	bill.MinimumDue = input.MinimumDue
+	if bill.Description == "" && bill.Issuer == "" && bill.AccountID == nil {
+		return errors.NewValidationError("Invalid request: description, issuer or account_id is required")
+	}
+	return nil
+}
+
+// billLabel names a bill for display
+func billLabel(bill *models.Bill) string {
+	switch {
+	case bill.Description != "":
+		return bill.Description
+	case bill.Account != nil:
+		return bill.Account.Name + " bill"
+	case bill.Issuer != "":
+		return bill.Issuer + " bill"
+	default:
+		return "Bill"
+	}
+}
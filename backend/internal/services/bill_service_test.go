@@ -0,0 +1,269 @@
+package services
+
+import (
+	"billing-note/internal/models"
+	"billing-note/internal/pdf"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// --- Mock Bill Repository ---
+
+type mockBillRepo struct {
+	mock.Mock
+}
+
+func (m *mockBillRepo) Create(bill *models.Bill) error {
+	args := m.Called(bill)
+	return args.Error(0)
+}
+
+func (m *mockBillRepo) List(userID uint, status string) ([]models.Bill, error) {
+	args := m.Called(userID, status)
+	return args.Get(0).([]models.Bill), args.Error(1)
+}
+
+func (m *mockBillRepo) GetByID(userID, id uint) (*models.Bill, error) {
+	args := m.Called(userID, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Bill), args.Error(1)
+}
+
+func (m *mockBillRepo) Update(bill *models.Bill) error {
+	args := m.Called(bill)
+	return args.Error(0)
+}
+
+func (m *mockBillRepo) Delete(userID, id uint) error {
+	args := m.Called(userID, id)
+	return args.Error(0)
+}
+
+func (m *mockBillRepo) FindByIssuerDueDate(userID uint, issuer string, dueDate time.Time) (*models.Bill, error) {
+	args := m.Called(userID, issuer, dueDate)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Bill), args.Error(1)
+}
+
+func (m *mockBillRepo) ListOverdue(userID uint, before time.Time) ([]models.Bill, error) {
+	args := m.Called(userID, before)
+	return args.Get(0).([]models.Bill), args.Error(1)
+}
+
+func (m *mockBillRepo) ListPendingDueBy(date time.Time) ([]models.Bill, error) {
+	args := m.Called(date)
+	return args.Get(0).([]models.Bill), args.Error(1)
+}
+
+func (m *mockBillRepo) FindPendingByAmount(userID uint, amount float64, from, to time.Time) ([]models.Bill, error) {
+	args := m.Called(userID, amount, from, to)
+	return args.Get(0).([]models.Bill), args.Error(1)
+}
+
+func newTestBillService(billRepo *mockBillRepo, txnRepo *mockTransactionRepo, notifRepo *mockNotificationRepo, now time.Time) *BillService {
+	s := NewBillService(billRepo, nil, txnRepo, nil, NewNotificationService(notifRepo, nil))
+	s.now = func() time.Time { return now }
+	return s
+}
+
+func ymd(y int, m time.Month, d int) time.Time {
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+// --- Tests ---
+
+func TestBillService_RecordStatement_CreatesBill(t *testing.T) {
+	billRepo := new(mockBillRepo)
+	svc := newTestBillService(billRepo, nil, nil, time.Now())
+
+	due := ymd(2025, 12, 15)
+	billRepo.On("FindByIssuerDueDate", uint(1), "國泰世華", due).Return(nil, gorm.ErrRecordNotFound)
+	billRepo.On("Create", mock.AnythingOfType("*models.Bill")).Return(nil)
+
+	bill, err := svc.RecordStatement(1, "國泰世華", &pdf.StatementSummary{DueDate: &due, AmountDue: 12345, MinimumDue: 1235})
+
+	require.NoError(t, err)
+	assert.Equal(t, models.BillSourceStatement, bill.Source)
+	assert.Equal(t, models.BillStatusPending, bill.Status)
+	assert.Equal(t, 12345.0, bill.AmountDue)
+	assert.Equal(t, models.DefaultBillRemindDays, bill.RemindDays)
+}
+
+func TestBillService_RecordStatement_UpdatesExisting(t *testing.T) {
+	billRepo := new(mockBillRepo)
+	svc := newTestBillService(billRepo, nil, nil, time.Now())
+
+	due := ymd(2025, 12, 15)
+	existing := &models.Bill{ID: 7, UserID: 1, Issuer: "國泰世華", DueDate: due, AmountDue: 100, Status: models.BillStatusPending}
+	billRepo.On("FindByIssuerDueDate", uint(1), "國泰世華", due).Return(existing, nil)
+	billRepo.On("Update", existing).Return(nil)
+
+	bill, err := svc.RecordStatement(1, "國泰世華", &pdf.StatementSummary{DueDate: &due, AmountDue: 200})
+
+	require.NoError(t, err)
+	assert.Equal(t, uint(7), bill.ID)
+	assert.Equal(t, 200.0, bill.AmountDue)
+	billRepo.AssertNotCalled(t, "Create", mock.Anything)
+}
+
+func TestBillService_RecordStatement_RecordsCardBalance(t *testing.T) {
+	billRepo := new(mockBillRepo)
+	accountRepo := new(mockAccountRepo)
+	balanceRepo := new(mockBalanceRepo)
+	svc := NewBillService(billRepo, accountRepo, nil, nil, nil)
+	svc.SetNetWorthService(NewNetWorthService(balanceRepo, accountRepo, nil, nil))
+
+	due, closing := ymd(2025, 12, 15), ymd(2025, 11, 28)
+	accountRepo.On("List", uint(1)).Return([]models.Account{
+		{ID: 2, Name: "Savings", Type: models.AccountTypeBank},
+		{ID: 3, Name: "國泰世華 Visa", Type: models.AccountTypeCreditCard},
+	}, nil)
+	billRepo.On("FindByIssuerDueDate", uint(1), "國泰世華", due).Return(nil, gorm.ErrRecordNotFound)
+	billRepo.On("Create", mock.AnythingOfType("*models.Bill")).Return(nil)
+	balanceRepo.On("Upsert", mock.MatchedBy(func(b *models.AccountBalance) bool {
+		return b.AccountID == 3 && b.Balance == 12345 && b.Date.Equal(closing) && b.Source == models.BalanceSourceStatement
+	})).Return(nil)
+
+	bill, err := svc.RecordStatement(1, "國泰世華", &pdf.StatementSummary{ClosingDate: &closing, DueDate: &due, AmountDue: 12345})
+
+	require.NoError(t, err)
+	require.NotNil(t, bill.AccountID)
+	assert.Equal(t, uint(3), *bill.AccountID)
+	balanceRepo.AssertExpectations(t)
+}
+
+func TestBillService_MatchPayment(t *testing.T) {
+	billRepo := new(mockBillRepo)
+	svc := newTestBillService(billRepo, nil, nil, time.Now())
+
+	paidOn := ymd(2025, 12, 10)
+	billRepo.On("FindPendingByAmount", uint(1), 12345.0, ymd(2025, 12, 3), ymd(2026, 1, 10)).
+		Return([]models.Bill{{ID: 7, UserID: 1, AmountDue: 12345, Status: models.BillStatusPending}}, nil)
+	billRepo.On("Update", mock.MatchedBy(func(b *models.Bill) bool {
+		return b.ID == 7 && b.Status == models.BillStatusPaid && b.PaidAt.Equal(paidOn) &&
+			b.PaymentTransactionID != nil && *b.PaymentTransactionID == 42
+	})).Return(nil)
+
+	// Card payment lines are credits on the statement
+	txnID := uint(42)
+	bill, ok := svc.MatchPayment(1, paidOn, -12345, &txnID)
+
+	assert.True(t, ok)
+	assert.Equal(t, 12345.0, bill.PaidAmount)
+}
+
+func TestBillService_MatchPayment_Ambiguous(t *testing.T) {
+	billRepo := new(mockBillRepo)
+	svc := newTestBillService(billRepo, nil, nil, time.Now())
+
+	billRepo.On("FindPendingByAmount", uint(1), 500.0, mock.Anything, mock.Anything).
+		Return([]models.Bill{{ID: 1}, {ID: 2}}, nil)
+
+	_, ok := svc.MatchPayment(1, ymd(2025, 12, 10), 500, nil)
+
+	assert.False(t, ok)
+	billRepo.AssertNotCalled(t, "Update", mock.Anything)
+}
+
+func TestBillService_MarkPaid_LinksTransaction(t *testing.T) {
+	billRepo := new(mockBillRepo)
+	txnRepo := new(mockTransactionRepo)
+	svc := newTestBillService(billRepo, txnRepo, nil, time.Now())
+
+	bill := &models.Bill{ID: 7, UserID: 1, AmountDue: 12345, Status: models.BillStatusPending}
+	billRepo.On("GetByID", uint(1), uint(7)).Return(bill, nil)
+	billRepo.On("Update", bill).Return(nil)
+	txnRepo.On("GetByID", uint(42)).Return(&models.Transaction{ID: 42, UserID: 1, Amount: 12000, TransactionDate: ymd(2025, 12, 12)}, nil)
+
+	txnID := uint(42)
+	result, err := svc.MarkPaid(1, 7, models.MarkBillPaidRequest{TransactionID: &txnID})
+
+	require.NoError(t, err)
+	assert.Equal(t, models.BillStatusPaid, result.Status)
+	assert.Equal(t, &txnID, result.PaymentTransactionID)
+	assert.Equal(t, 12000.0, result.PaidAmount)
+	assert.True(t, result.PaidAt.Equal(ymd(2025, 12, 12)))
+}
+
+func TestBillService_MarkPaid_OtherUsersTransaction(t *testing.T) {
+	billRepo := new(mockBillRepo)
+	txnRepo := new(mockTransactionRepo)
+	svc := newTestBillService(billRepo, txnRepo, nil, time.Now())
+
+	billRepo.On("GetByID", uint(1), uint(7)).Return(&models.Bill{ID: 7, UserID: 1}, nil)
+	txnRepo.On("GetByID", uint(42)).Return(&models.Transaction{ID: 42, UserID: 2}, nil)
+
+	txnID := uint(42)
+	_, err := svc.MarkPaid(1, 7, models.MarkBillPaidRequest{TransactionID: &txnID})
+
+	assert.Error(t, err)
+	billRepo.AssertNotCalled(t, "Update", mock.Anything)
+}
+
+func TestBillService_RunReminders(t *testing.T) {
+	billRepo := new(mockBillRepo)
+	notifRepo := new(mockNotificationRepo)
+	// 2025-12-12 09:00 in Asia/Taipei
+	svc := newTestBillService(billRepo, nil, notifRepo, time.Date(2025, 12, 12, 1, 0, 0, 0, time.UTC))
+
+	billRepo.On("ListPendingDueBy", mock.Anything).Return([]models.Bill{
+		{ID: 1, UserID: 1, Description: "Due soon", DueDate: ymd(2025, 12, 15), RemindDays: 3},
+		{ID: 2, UserID: 1, Description: "Later", DueDate: ymd(2025, 12, 20), RemindDays: 3},
+		{ID: 3, UserID: 1, Description: "Late", DueDate: ymd(2025, 12, 10), RemindDays: 3},
+	}, nil)
+	billRepo.On("Update", mock.AnythingOfType("*models.Bill")).Return(nil)
+
+	var sent []*models.Notification
+	notifRepo.On("Create", mock.AnythingOfType("*models.Notification")).
+		Run(func(args mock.Arguments) { sent = append(sent, args.Get(0).(*models.Notification)) }).
+		Return(nil)
+
+	svc.RunReminders()
+
+	require.Len(t, sent, 2)
+	assert.Equal(t, models.NotificationBillReminder, sent[0].Type)
+	assert.Equal(t, "Due soon is due in 3 days", sent[0].Title)
+	assert.Equal(t, models.NotificationBillOverdue, sent[1].Type)
+	assert.Equal(t, "Late is overdue", sent[1].Title)
+	billRepo.AssertNumberOfCalls(t, "Update", 2)
+}
+
+func TestBillService_Create_RemindDays(t *testing.T) {
+	billRepo := new(mockBillRepo)
+	svc := newTestBillService(billRepo, nil, nil, time.Now())
+
+	billRepo.On("Create", mock.AnythingOfType("*models.Bill")).Return(nil)
+
+	bill, err := svc.Create(1, models.BillInput{Description: "Rent", DueDate: "2025-12-15", AmountDue: 100})
+	assert.NoError(t, err)
+	assert.Equal(t, models.DefaultBillRemindDays, bill.RemindDays)
+
+	// 0 asks for the reminder on the due date itself
+	days := 0
+	bill, err = svc.Create(1, models.BillInput{Description: "Rent", DueDate: "2025-12-15", AmountDue: 100, RemindDays: &days})
+	assert.NoError(t, err)
+	assert.Equal(t, 0, bill.RemindDays)
+}
+
+func TestBillService_Create_Validation(t *testing.T) {
+	svc := newTestBillService(new(mockBillRepo), nil, nil, time.Now())
+
+	_, err := svc.Create(1, models.BillInput{Description: "Rent", DueDate: "15/12/2025"})
+	assert.Error(t, err)
+
+	days := 90
+	_, err = svc.Create(1, models.BillInput{Description: "Rent", DueDate: "2025-12-15", RemindDays: &days})
+	assert.Error(t, err)
+
+	_, err = svc.Create(1, models.BillInput{DueDate: "2025-12-15", AmountDue: 100})
+	assert.Error(t, err)
+}
@@ -0,0 +1,151 @@
+package services
+
+import (
+	"billing-note/internal/models"
+	"billing-note/internal/repository"
+	"billing-note/pkg/logger"
+	"fmt"
+	"time"
+)
+
+// BudgetAlertService notifies users when spending crosses a budget's alert
+// thresholds. It listens for saved transactions; each threshold fires at most
+// once per budget period.
+type BudgetAlertService struct {
+	budgetService *BudgetService
+	budgetRepo    repository.BudgetRepository
+	notifications *NotificationService
+	now           func() time.Time
+}
+
+// NewBudgetAlertService creates a new budget alert service
+func NewBudgetAlertService(budgetService *BudgetService, budgetRepo repository.BudgetRepository, notifications *NotificationService) *BudgetAlertService {
+	return &BudgetAlertService{
+		budgetService: budgetService,
+		budgetRepo:    budgetRepo,
+		notifications: notifications,
+		now:           time.Now,
+	}
+}
+
+// TransactionsSaved implements TransactionListener
+func (s *BudgetAlertService) TransactionsSaved(userID uint, transactions []models.Transaction) {
+	var dates []time.Time
+	for _, txn := range transactions {
+		if txn.Type == "expense" {
+			dates = append(dates, dateOf(txn.TransactionDate, time.UTC))
+		}
+	}
+	if len(dates) == 0 {
+		return
+	}
+	s.CheckUser(userID, dates)
+}
+
+// CheckUser evaluates the user's budgets for the current period and sends
+// alerts for newly crossed thresholds. Only periods containing one of the
+// given dates are considered, so back-dated imports do not alert.
+func (s *BudgetAlertService) CheckUser(userID uint, dates []time.Time) {
+	log := logger.ServiceLog("BudgetAlertService", "CheckUser")
+
+	today := dateOf(s.now(), userLocation(s.budgetService.userRepo, userID))
+	comparisons, err := s.budgetService.CompareAt(userID, today)
+	if err != nil {
+		log.WithError(err).Warn("Failed to compare budgets for alerts")
+		return
+	}
+
+	for _, comp := range comparisons {
+		if len(comp.Budget.AlertThresholds) == 0 || !anyWithin(dates, comp.PeriodStart, comp.PeriodEnd) {
+			continue
+		}
+
+		// Record every crossed threshold, but notify once with the highest
+		crossed := 0
+		for _, threshold := range comp.Budget.AlertThresholds {
+			if comp.Percentage < float64(threshold) {
+				continue
+			}
+			created, err := s.budgetRepo.CreateAlert(&models.BudgetAlert{
+				BudgetID:    comp.Budget.ID,
+				PeriodStart: comp.PeriodStart,
+				Threshold:   int(threshold),
+			})
+			if err != nil {
+				log.WithError(err).Warn("Failed to record budget alert")
+				continue
+			}
+			if created && int(threshold) > crossed {
+				crossed = int(threshold)
+			}
+		}
+		if crossed == 0 {
+			continue
+		}
+
+		if _, err := s.notifications.Notify(userID, models.NotificationBudgetAlert,
+			budgetAlertTitle(&comp.Budget, crossed),
+			budgetAlertMessage(comp),
+			map[string]interface{}{
+				"budget_id":    comp.Budget.ID,
+				"threshold":    crossed,
+				"percentage":   comp.Percentage,
+				"period_start": comp.PeriodStart.Format(budgetDateLayout),
+				"period_end":   comp.PeriodEnd.Format(budgetDateLayout),
+			}); err != nil {
+			log.WithError(err).Warn("Failed to send budget alert")
+			continue
+		}
+
+		log.WithFields(logger.Fields{
+			"user_id":   userID,
+			"budget_id": comp.Budget.ID,
+			"threshold": crossed,
+		}).Info("Budget alert sent")
+	}
+}
+
+func anyWithin(dates []time.Time, start, end time.Time) bool {
+	for _, d := range dates {
+		if !d.Before(start) && !d.After(end) {
+			return true
+		}
+	}
+	return false
+}
+
+// budgetLabel names a budget by its target for display
+func budgetLabel(budget *models.Budget) string {
+	switch budget.TargetType {
+	case models.BudgetTargetOverall:
+		return "Overall spending"
+	case models.BudgetTargetCategories:
+		return "Category group"
+	case models.BudgetTargetTag:
+		return "#" + budget.Tag
+	case models.BudgetTargetAccount:
+		if budget.Account != nil {
+			return budget.Account.Name
+		}
+		return "Account"
+	default:
+		if budget.Category != nil {
+			return budget.Category.Name
+		}
+		return "Category"
+	}
+}
+
+func budgetAlertTitle(budget *models.Budget, threshold int) string {
+	if threshold >= 100 {
+		return fmt.Sprintf("%s budget exceeded (%d%%)", budgetLabel(budget), threshold)
+	}
+	return fmt.Sprintf("%s budget reached %d%%", budgetLabel(budget), threshold)
+}
+
+func budgetAlertMessage(comp models.BudgetComparison) string {
+	return fmt.Sprintf("You have spent %.0f of %.0f (%.1f%%) for %s to %s. Remaining: %.0f.",
+		comp.ActualAmount, comp.Available, comp.Percentage,
+		comp.PeriodStart.Format(budgetDateLayout), comp.PeriodEnd.Format(budgetDateLayout),
+		comp.Remaining)
+}
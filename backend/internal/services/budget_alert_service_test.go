@@ -0,0 +1,108 @@
+package services
+
+import (
+	"billing-note/internal/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func newTestBudgetAlertService(budgetRepo *mockBudgetRepo, notifRepo *mockNotificationRepo, now time.Time) *BudgetAlertService {
+	budgetService := NewBudgetService(budgetRepo, nil, nil)
+	notifications := NewNotificationService(notifRepo, nil)
+	s := NewBudgetAlertService(budgetService, budgetRepo, notifications)
+	s.now = func() time.Time { return now }
+	return s
+}
+
+func TestBudgetAlertService_NotifiesHighestNewThreshold(t *testing.T) {
+	budgetRepo := new(mockBudgetRepo)
+	notifRepo := new(mockNotificationRepo)
+	now := time.Date(2025, 3, 15, 4, 0, 0, 0, time.UTC)
+	svc := newTestBudgetAlertService(budgetRepo, notifRepo, now)
+
+	budget := models.Budget{
+		ID: 1, UserID: 1, TargetType: models.BudgetTargetOverall,
+		PeriodType: models.BudgetPeriodMonthly, MonthlyAmount: 1000,
+		AlertThresholds: []int64{50, 80, 100},
+	}
+	budgetRepo.On("List", uint(1)).Return([]models.Budget{budget}, nil)
+	budgetRepo.On("SumSpending", mock.Anything, mock.Anything, mock.Anything).Return(850.0, nil)
+	budgetRepo.On("CreateAlert", mock.MatchedBy(func(a *models.BudgetAlert) bool { return a.Threshold == 50 })).Return(false, nil)
+	budgetRepo.On("CreateAlert", mock.MatchedBy(func(a *models.BudgetAlert) bool { return a.Threshold == 80 })).Return(true, nil)
+
+	var sent *models.Notification
+	notifRepo.On("Create", mock.AnythingOfType("*models.Notification")).
+		Run(func(args mock.Arguments) { sent = args.Get(0).(*models.Notification) }).
+		Return(nil)
+
+	svc.TransactionsSaved(1, []models.Transaction{
+		{Type: "expense", TransactionDate: time.Date(2025, 3, 14, 0, 0, 0, 0, time.UTC)},
+	})
+
+	budgetRepo.AssertNumberOfCalls(t, "CreateAlert", 2)
+	if assert.NotNil(t, sent) {
+		assert.Equal(t, models.NotificationBudgetAlert, sent.Type)
+		assert.Equal(t, "Overall spending budget reached 80%", sent.Title)
+		assert.Contains(t, string(sent.Data), `"threshold":80`)
+	}
+}
+
+func TestBudgetAlertService_AlreadyAlerted(t *testing.T) {
+	budgetRepo := new(mockBudgetRepo)
+	notifRepo := new(mockNotificationRepo)
+	now := time.Date(2025, 3, 15, 4, 0, 0, 0, time.UTC)
+	svc := newTestBudgetAlertService(budgetRepo, notifRepo, now)
+
+	budget := models.Budget{
+		ID: 1, UserID: 1, TargetType: models.BudgetTargetOverall,
+		PeriodType: models.BudgetPeriodMonthly, MonthlyAmount: 1000,
+		AlertThresholds: []int64{80},
+	}
+	budgetRepo.On("List", uint(1)).Return([]models.Budget{budget}, nil)
+	budgetRepo.On("SumSpending", mock.Anything, mock.Anything, mock.Anything).Return(900.0, nil)
+	budgetRepo.On("CreateAlert", mock.Anything).Return(false, nil)
+
+	svc.CheckUser(1, []time.Time{time.Date(2025, 3, 15, 0, 0, 0, 0, time.UTC)})
+
+	notifRepo.AssertNotCalled(t, "Create", mock.Anything)
+}
+
+func TestBudgetAlertService_IgnoresOtherPeriodsAndIncome(t *testing.T) {
+	budgetRepo := new(mockBudgetRepo)
+	notifRepo := new(mockNotificationRepo)
+	now := time.Date(2025, 3, 15, 4, 0, 0, 0, time.UTC)
+	svc := newTestBudgetAlertService(budgetRepo, notifRepo, now)
+
+	budget := models.Budget{
+		ID: 1, UserID: 1, TargetType: models.BudgetTargetOverall,
+		PeriodType: models.BudgetPeriodMonthly, MonthlyAmount: 1000,
+		AlertThresholds: []int64{80},
+	}
+	budgetRepo.On("List", uint(1)).Return([]models.Budget{budget}, nil)
+	budgetRepo.On("SumSpending", mock.Anything, mock.Anything, mock.Anything).Return(900.0, nil)
+
+	// Income does not trigger a check at all
+	svc.TransactionsSaved(1, []models.Transaction{
+		{Type: "income", TransactionDate: now},
+	})
+	budgetRepo.AssertNotCalled(t, "List", mock.Anything)
+
+	// Back-dated expense from last month does not alert for this period
+	svc.TransactionsSaved(1, []models.Transaction{
+		{Type: "expense", TransactionDate: time.Date(2025, 2, 20, 0, 0, 0, 0, time.UTC)},
+	})
+	budgetRepo.AssertNotCalled(t, "CreateAlert", mock.Anything)
+	notifRepo.AssertNotCalled(t, "Create", mock.Anything)
+}
+
+func TestApplyAlertThresholds(t *testing.T) {
+	budget := &models.Budget{}
+	assert.NoError(t, applyAlertThresholds(budget, []int64{100, 80, 80, 50}))
+	assert.Equal(t, []int64{50, 80, 100}, []int64(budget.AlertThresholds))
+
+	assert.Error(t, applyAlertThresholds(budget, []int64{0}))
+	assert.Error(t, applyAlertThresholds(budget, []int64{1001}))
+}
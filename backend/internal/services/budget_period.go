@@ -0,0 +1,132 @@
+package services
+
+import (
+	"billing-note/internal/models"
+	"billing-note/internal/repository"
+	"billing-note/pkg/errors"
+	"time"
+)
+
+// DefaultTimezone is used when a user has no (or an unknown) time zone set
+const DefaultTimezone = "Asia/Taipei"
+
+const budgetDateLayout = "2006-01-02"
+
+// userLocation resolves a user's time zone, falling back to DefaultTimezone
+func userLocation(userRepo repository.UserRepository, userID uint) *time.Location {
+	name := DefaultTimezone
+	if userRepo != nil {
+		if user, err := userRepo.FindByID(userID); err == nil && user.Timezone != "" {
+			name = user.Timezone
+		}
+	}
+	if loc, err := time.LoadLocation(name); err == nil {
+		return loc
+	}
+	return time.FixedZone(DefaultTimezone, 8*60*60)
+}
+
+// dateOf truncates t to its calendar date in loc, returned as UTC midnight
+// to match how DATE columns are stored
+func dateOf(t time.Time, loc *time.Location) time.Time {
+	y, m, d := t.In(loc).Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+// BudgetWindow returns the first and last (inclusive) dates of the budget period
+// containing ref. ref must already be a calendar date (see dateOf).
+func BudgetWindow(budget *models.Budget, ref time.Time) (time.Time, time.Time) {
+	y, m, d := ref.Date()
+	day := budget.StartDay
+
+	var start, end time.Time
+	switch budget.PeriodType {
+	case models.BudgetPeriodWeekly:
+		offset := (int(ref.Weekday()) - day + 7) % 7
+		start = ref.AddDate(0, 0, -offset)
+		end = start.AddDate(0, 0, 7)
+	case models.BudgetPeriodQuarterly:
+		qm := time.Month((int(m)-1)/3*3 + 1)
+		start = time.Date(y, qm, day, 0, 0, 0, 0, time.UTC)
+		if ref.Before(start) {
+			start = start.AddDate(0, -3, 0)
+		}
+		end = start.AddDate(0, 3, 0)
+	case models.BudgetPeriodYearly:
+		start = time.Date(y, time.January, day, 0, 0, 0, 0, time.UTC)
+		if ref.Before(start) {
+			start = start.AddDate(-1, 0, 0)
+		}
+		end = start.AddDate(1, 0, 0)
+	case models.BudgetPeriodCustom:
+		if budget.StartDate != nil && budget.EndDate != nil {
+			return dateOf(*budget.StartDate, time.UTC), dateOf(*budget.EndDate, time.UTC)
+		}
+		fallthrough
+	default:
+		if day < 1 {
+			day = 1
+		}
+		start = time.Date(y, m, day, 0, 0, 0, 0, time.UTC)
+		if d < day {
+			start = start.AddDate(0, -1, 0)
+		}
+		end = start.AddDate(0, 1, 0)
+	}
+	return start, end.AddDate(0, 0, -1)
+}
+
+// applyBudgetPeriod validates and applies period settings to a budget
+func applyBudgetPeriod(budget *models.Budget, periodType string, startDay *int, startDate, endDate string) error {
+	if periodType != "" {
+		budget.PeriodType = periodType
+	}
+	if budget.PeriodType == "" {
+		budget.PeriodType = models.BudgetPeriodMonthly
+	}
+	if startDay != nil {
+		budget.StartDay = *startDay
+	}
+
+	switch budget.PeriodType {
+	case models.BudgetPeriodWeekly:
+		if startDay == nil && periodType != "" {
+			budget.StartDay = int(time.Monday)
+		}
+		if budget.StartDay < 0 || budget.StartDay > 6 {
+			return errors.NewInvalidInputError("start_day", "must be 0 (Sunday) to 6 (Saturday) for weekly budgets")
+		}
+	case models.BudgetPeriodMonthly, models.BudgetPeriodQuarterly, models.BudgetPeriodYearly:
+		if budget.StartDay == 0 {
+			budget.StartDay = 1
+		}
+		if budget.StartDay < 1 || budget.StartDay > 28 {
+			return errors.NewInvalidInputError("start_day", "must be between 1 and 28")
+		}
+	case models.BudgetPeriodCustom:
+		if startDate != "" || endDate != "" {
+			start, err := time.Parse(budgetDateLayout, startDate)
+			if err != nil {
+				return errors.NewInvalidInputError("start_date", "must be YYYY-MM-DD")
+			}
+			end, err := time.Parse(budgetDateLayout, endDate)
+			if err != nil {
+				return errors.NewInvalidInputError("end_date", "must be YYYY-MM-DD")
+			}
+			budget.StartDate, budget.EndDate = &start, &end
+		}
+		if budget.StartDate == nil || budget.EndDate == nil {
+			return errors.NewInvalidInputError("start_date", "custom budgets require start_date and end_date")
+		}
+		if budget.EndDate.Before(*budget.StartDate) {
+			return errors.NewInvalidInputError("end_date", "must not be before start_date")
+		}
+	default:
+		return errors.NewInvalidInputError("period_type", "must be weekly, monthly, quarterly, yearly or custom")
+	}
+
+	if budget.PeriodType != models.BudgetPeriodCustom {
+		budget.StartDate, budget.EndDate = nil, nil
+	}
+	return nil
+}
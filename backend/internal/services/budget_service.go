@@ -5,26 +5,73 @@ import (
 	"billing-note/internal/repository"
 	"billing-note/pkg/errors"
 	"math"
+	"sort"
+	"strings"
 	"time"
 )
 
 type BudgetService struct {
-	budgetRepo      repository.BudgetRepository
-	transactionRepo repository.TransactionRepository
+	budgetRepo  repository.BudgetRepository
+	accountRepo repository.AccountRepository
+	userRepo    repository.UserRepository
+	// ledgerID selects a household ledger's budgets instead of personal ones
+	ledgerID *uint
 }
 
-func NewBudgetService(budgetRepo repository.BudgetRepository, transactionRepo repository.TransactionRepository) *BudgetService {
+// NewBudgetService creates a budget service. accountRepo checks the accounts
+// of account budgets belong to the user. userRepo supplies the user's time
+// zone; when nil, DefaultTimezone is used.
+func NewBudgetService(budgetRepo repository.BudgetRepository, accountRepo repository.AccountRepository, userRepo repository.UserRepository) *BudgetService {
 	return &BudgetService{
-		budgetRepo:      budgetRepo,
-		transactionRepo: transactionRepo,
+		budgetRepo:  budgetRepo,
+		accountRepo: accountRepo,
+		userRepo:    userRepo,
 	}
 }
 
+// ForLedger returns a service working on the household ledger's budgets; a
+// nil ledger ID selects the user's personal budgets
+func (s *BudgetService) ForLedger(ledgerID *uint) *BudgetService {
+	scoped := *s
+	scoped.ledgerID = ledgerID
+	return &scoped
+}
+
+// owns reports whether the budget belongs to the ledger the service works on
+func (s *BudgetService) owns(budget *models.Budget, userID uint) bool {
+	if s.ledgerID != nil {
+		return budget.LedgerID != nil && *budget.LedgerID == *s.ledgerID
+	}
+	return budget.UserID == userID && budget.LedgerID == nil
+}
+
+func (s *BudgetService) list(userID uint) ([]models.Budget, error) {
+	if s.ledgerID != nil {
+		return s.budgetRepo.ListByLedger(*s.ledgerID)
+	}
+	return s.budgetRepo.List(userID)
+}
+
 func (s *BudgetService) Create(userID uint, req models.CreateBudgetRequest) (*models.Budget, error) {
 	budget := &models.Budget{
 		UserID:        userID,
-		CategoryID:    req.CategoryID,
+		LedgerID:      s.ledgerID,
 		MonthlyAmount: req.MonthlyAmount,
+		Rollover:      req.Rollover,
+	}
+	if err := applyBudgetTarget(budget, req); err != nil {
+		return nil, err
+	}
+	if budget.AccountID != nil {
+		if _, err := s.accountRepo.GetByID(userID, *budget.AccountID); err != nil {
+			return nil, errors.NewNotFoundError("Account", *budget.AccountID)
+		}
+	}
+	if err := applyAlertThresholds(budget, req.AlertThresholds); err != nil {
+		return nil, err
+	}
+	if err := applyBudgetPeriod(budget, req.PeriodType, req.StartDay, req.StartDate, req.EndDate); err != nil {
+		return nil, err
 	}
 	if err := s.budgetRepo.Create(budget); err != nil {
 		return nil, errors.NewDBError("create budget", err)
@@ -33,7 +80,7 @@ func (s *BudgetService) Create(userID uint, req models.CreateBudgetRequest) (*mo
 }
 
 func (s *BudgetService) List(userID uint) ([]models.Budget, error) {
-	return s.budgetRepo.List(userID)
+	return s.list(userID)
 }
 
 func (s *BudgetService) Update(id, userID uint, req models.UpdateBudgetRequest) (*models.Budget, error) {
@@ -41,13 +88,42 @@ func (s *BudgetService) Update(id, userID uint, req models.UpdateBudgetRequest)
 	if err != nil {
 		return nil, errors.NewNotFoundError("Budget", id)
 	}
-	if budget.UserID != userID {
+	if !s.owns(budget, userID) {
 		return nil, errors.NewUnauthorizedError("Not authorized to update this budget")
 	}
+	before := *budget
 	budget.MonthlyAmount = req.MonthlyAmount
+	if req.Rollover != nil {
+		budget.Rollover = *req.Rollover
+	}
+	if req.AlertThresholds != nil {
+		if err := applyAlertThresholds(budget, req.AlertThresholds); err != nil {
+			return nil, err
+		}
+	}
+
+	periodRequested := req.PeriodType != "" || req.StartDay != nil || req.StartDate != "" || req.EndDate != ""
+	if err := applyBudgetPeriod(budget, req.PeriodType, req.StartDay, req.StartDate, req.EndDate); err != nil {
+		return nil, err
+	}
 	if err := s.budgetRepo.Update(budget); err != nil {
 		return nil, errors.NewDBError("update budget", err)
 	}
+
+	today := dateOf(time.Now(), userLocation(s.userRepo, userID))
+	switch {
+	case periodRequested && periodChanged(&before, budget):
+		// Recorded periods no longer line up once the period definition
+		// changes, so they are closed as they stand
+		if err := s.closeLedger(&before, today); err != nil {
+			return nil, errors.NewDBError("close budget ledger", err)
+		}
+	case before.MonthlyAmount != budget.MonthlyAmount:
+		// Periods that have started keep the amount they began with
+		if _, err := s.syncLedger(&before, today); err != nil {
+			return nil, errors.NewDBError("sync budget ledger", err)
+		}
+	}
 	return budget, nil
 }
 
@@ -56,59 +132,295 @@ func (s *BudgetService) Delete(id, userID uint) error {
 	if err != nil {
 		return errors.NewNotFoundError("Budget", id)
 	}
-	if budget.UserID != userID {
+	if !s.owns(budget, userID) {
 		return errors.NewUnauthorizedError("Not authorized to delete this budget")
 	}
 	return s.budgetRepo.Delete(id)
 }
 
+// Compare reports spending against each budget for the given month. For the
+// current month each budget's in-progress period is used; for other months, the
+// period containing the month's last day. Dates follow the user's time zone.
 func (s *BudgetService) Compare(userID uint, year, month int) ([]models.BudgetComparison, error) {
-	budgets, err := s.budgetRepo.List(userID)
+	today := dateOf(time.Now(), userLocation(s.userRepo, userID))
+
+	ref := time.Date(year, time.Month(month)+1, 0, 0, 0, 0, 0, time.UTC)
+	if today.Year() == year && int(today.Month()) == month {
+		ref = today
+	}
+	return s.CompareAt(userID, ref)
+}
+
+// CompareAt reports spending against each budget for the period containing the
+// calendar date ref. Rollover budgets include the amount carried in from
+// earlier periods.
+func (s *BudgetService) CompareAt(userID uint, ref time.Time) ([]models.BudgetComparison, error) {
+	budgets, err := s.list(userID)
 	if err != nil {
 		return nil, err
 	}
-
-	startDate := time.Date(year, time.Month(month), 1, 0, 0, 0, 0, time.UTC)
-	endDate := startDate.AddDate(0, 1, 0).Add(-time.Second)
+	today := dateOf(time.Now(), userLocation(s.userRepo, userID))
 
 	var comparisons []models.BudgetComparison
 	for _, budget := range budgets {
-		catID := budget.CategoryID
-		filter := repository.TransactionFilter{
-			UserID:     userID,
-			CategoryID: &catID,
-			StartDate:  &startDate,
-			EndDate:    &endDate,
-			Page:       0,
-			PageSize:   0,
-		}
+		startDate, endDate := BudgetWindow(&budget, ref)
 
-		transactions, _, err := s.transactionRepo.List(filter)
+		actual, err := s.actualSpent(&budget, startDate, endDate)
 		if err != nil {
 			continue
 		}
 
-		var actual float64
-		for _, txn := range transactions {
-			if txn.Type == "expense" {
-				actual += txn.Amount
+		carryIn := 0.0
+		if budget.Rollover {
+			if entries, err := s.syncLedger(&budget, today); err == nil {
+				for _, e := range entries {
+					if e.PeriodStart.Equal(startDate) {
+						carryIn = e.CarryIn
+						break
+					}
+				}
 			}
 		}
 
-		remaining := budget.MonthlyAmount - actual
+		available := budget.MonthlyAmount + carryIn
+		remaining := available - actual
 		percentage := 0.0
-		if budget.MonthlyAmount > 0 {
-			percentage = math.Round(actual/budget.MonthlyAmount*10000) / 100
+		if available > 0 {
+			percentage = math.Round(actual/available*10000) / 100
 		}
 
 		comparisons = append(comparisons, models.BudgetComparison{
 			Budget:       budget,
+			PeriodStart:  startDate,
+			PeriodEnd:    endDate,
+			CarryIn:      carryIn,
+			Available:    available,
 			ActualAmount: actual,
 			Remaining:    remaining,
 			Percentage:   percentage,
-			IsOverBudget: actual > budget.MonthlyAmount,
+			IsOverBudget: actual > available,
 		})
 	}
 
 	return comparisons, nil
 }
+
+// History returns the most recent ledger periods of a budget, oldest first
+func (s *BudgetService) History(id, userID uint, limit int) ([]models.BudgetLedgerEntry, error) {
+	budget, err := s.budgetRepo.GetByID(id)
+	if err != nil {
+		return nil, errors.NewNotFoundError("Budget", id)
+	}
+	if !s.owns(budget, userID) {
+		return nil, errors.NewUnauthorizedError("Not authorized to view this budget")
+	}
+
+	today := dateOf(time.Now(), userLocation(s.userRepo, userID))
+	entries, err := s.syncLedger(budget, today)
+	if err != nil {
+		return nil, errors.NewDBError("sync budget ledger", err)
+	}
+
+	if limit > 0 && len(entries) > limit {
+		entries = entries[len(entries)-limit:]
+	}
+	return entries, nil
+}
+
+// ledgerSettleDays is how long after a period ends its actuals keep being
+// recalculated, so late imports such as card statements still count
+const ledgerSettleDays = 45
+
+// syncLedger records every period from the budget's first period up to the one
+// containing today, carrying balances forward for rollover budgets. An entry's
+// amount is fixed when it is recorded; later edits to the budget only affect
+// new periods. Closed entries are kept as recorded, including ones left from an
+// earlier period definition, and periods resume the day after them.
+func (s *BudgetService) syncLedger(budget *models.Budget, today time.Time) ([]models.BudgetLedgerEntry, error) {
+	existing, err := s.budgetRepo.ListLedger(budget.ID)
+	if err != nil {
+		return nil, err
+	}
+	byStart := make(map[time.Time]models.BudgetLedgerEntry, len(existing))
+	for _, e := range existing {
+		byStart[dateOf(e.PeriodStart, time.UTC)] = e
+	}
+
+	var periodStart time.Time
+	switch {
+	case len(existing) > 0:
+		periodStart = dateOf(existing[0].PeriodStart, time.UTC)
+	case !budget.CreatedAt.IsZero():
+		periodStart, _ = BudgetWindow(budget, dateOf(budget.CreatedAt, time.UTC))
+	default:
+		periodStart, _ = BudgetWindow(budget, today)
+	}
+
+	entries := make([]models.BudgetLedgerEntry, 0, len(existing)+1)
+	carry := 0.0
+	for !periodStart.After(today) {
+		if entry, ok := byStart[periodStart]; ok && entry.Closed {
+			entries = append(entries, entry)
+			carry = entry.CarryOut
+			periodStart = dateOf(entry.PeriodEnd, time.UTC).AddDate(0, 0, 1)
+			continue
+		}
+
+		start, end := BudgetWindow(budget, periodStart)
+		// The first period after a change of definition begins where the
+		// closed entries end
+		if start.Before(periodStart) {
+			start = periodStart
+		}
+		if end.Before(start) {
+			break
+		}
+
+		entry, ok := byStart[start]
+		if !ok || !entry.Closed {
+			if !ok {
+				entry = models.BudgetLedgerEntry{
+					BudgetID:    budget.ID,
+					UserID:      budget.UserID,
+					PeriodStart: start,
+					PeriodEnd:   end,
+					Amount:      budget.MonthlyAmount,
+				}
+			}
+			actual, err := s.actualSpent(budget, start, end)
+			if err != nil {
+				return nil, err
+			}
+			entry.Actual = actual
+			entry.CarryIn, entry.CarryOut = 0, 0
+			if budget.Rollover {
+				entry.CarryIn = carry
+				entry.CarryOut = entry.Amount + entry.CarryIn - entry.Actual
+			}
+			entry.Closed = today.After(end.AddDate(0, 0, ledgerSettleDays))
+			if err := s.budgetRepo.SaveLedgerEntry(&entry); err != nil {
+				return nil, err
+			}
+		}
+
+		entries = append(entries, entry)
+		carry = entry.CarryOut
+
+		if budget.PeriodType == models.BudgetPeriodCustom {
+			break
+		}
+		periodStart = end.AddDate(0, 0, 1)
+	}
+	return entries, nil
+}
+
+// closeLedger records the budget's periods up to today under its current
+// definition and closes them, ending the period in progress yesterday unless it
+// began today. It is used when the definition changes, so that the new periods
+// begin after the closed ones.
+func (s *BudgetService) closeLedger(budget *models.Budget, today time.Time) error {
+	entries, err := s.syncLedger(budget, today)
+	if err != nil {
+		return err
+	}
+
+	carry := 0.0
+	for i := range entries {
+		entry := &entries[i]
+		if entry.Closed {
+			carry = entry.CarryOut
+			continue
+		}
+		if end := today.AddDate(0, 0, -1); end.Before(dateOf(entry.PeriodEnd, time.UTC)) && !end.Before(dateOf(entry.PeriodStart, time.UTC)) {
+			actual, err := s.actualSpent(budget, entry.PeriodStart, end)
+			if err != nil {
+				return err
+			}
+			entry.PeriodEnd = end
+			entry.Actual = actual
+			if budget.Rollover {
+				entry.CarryIn = carry
+				entry.CarryOut = entry.Amount + entry.CarryIn - entry.Actual
+			}
+		}
+		entry.Closed = true
+		if err := s.budgetRepo.SaveLedgerEntry(entry); err != nil {
+			return err
+		}
+		carry = entry.CarryOut
+	}
+	return nil
+}
+
+// actualSpent sums the expenses counted against a budget within [start, end]
+func (s *BudgetService) actualSpent(budget *models.Budget, start, end time.Time) (float64, error) {
+	return s.budgetRepo.SumSpending(budget, start, end)
+}
+
+// applyBudgetTarget validates the request's target and sets it on the budget
+func applyBudgetTarget(budget *models.Budget, req models.CreateBudgetRequest) error {
+	budget.TargetType = req.TargetType
+	if budget.TargetType == "" {
+		budget.TargetType = models.BudgetTargetCategory
+	}
+
+	switch budget.TargetType {
+	case models.BudgetTargetCategory:
+		if req.CategoryID == nil {
+			return errors.NewInvalidInputError("category_id", "is required for category budgets")
+		}
+		budget.CategoryID = req.CategoryID
+	case models.BudgetTargetOverall:
+	case models.BudgetTargetCategories:
+		if len(req.CategoryIDs) == 0 {
+			return errors.NewInvalidInputError("category_ids", "is required for category set budgets")
+		}
+		budget.CategoryIDs = req.CategoryIDs
+	case models.BudgetTargetTag:
+		tag := strings.TrimSpace(req.Tag)
+		if tag == "" {
+			return errors.NewInvalidInputError("tag", "is required for tag budgets")
+		}
+		budget.Tag = tag
+	case models.BudgetTargetAccount:
+		if req.AccountID == nil {
+			return errors.NewInvalidInputError("account_id", "is required for account budgets")
+		}
+		budget.AccountID = req.AccountID
+	default:
+		return errors.NewInvalidInputError("target_type", "must be category, overall, categories, tag or account")
+	}
+	return nil
+}
+
+// applyAlertThresholds validates, sorts and de-duplicates alert percentages
+func applyAlertThresholds(budget *models.Budget, thresholds []int64) error {
+	seen := make(map[int64]bool, len(thresholds))
+	result := make([]int64, 0, len(thresholds))
+	for _, t := range thresholds {
+		if t < 1 || t > 1000 {
+			return errors.NewInvalidInputError("alert_thresholds", "must be percentages between 1 and 1000")
+		}
+		if !seen[t] {
+			seen[t] = true
+			result = append(result, t)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i] < result[j] })
+	budget.AlertThresholds = result
+	return nil
+}
+
+// periodChanged reports whether a budget's period windows differ between a and b
+func periodChanged(a, b *models.Budget) bool {
+	if a.PeriodType != b.PeriodType || a.StartDay != b.StartDay {
+		return true
+	}
+	sameDate := func(x, y *time.Time) bool {
+		if x == nil || y == nil {
+			return x == nil && y == nil
+		}
+		return x.Equal(*y)
+	}
+	return !sameDate(a.StartDate, b.StartDate) || !sameDate(a.EndDate, b.EndDate)
+}
@@ -2,11 +2,14 @@ package services
 
 import (
 	"billing-note/internal/models"
+	"net/http"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
 )
 
 // --- Mock Budget Repository ---
@@ -25,6 +28,11 @@ func (m *mockBudgetRepo) List(userID uint) ([]models.Budget, error) {
 	return args.Get(0).([]models.Budget), args.Error(1)
 }
 
+func (m *mockBudgetRepo) ListByLedger(ledgerID uint) ([]models.Budget, error) {
+	args := m.Called(ledgerID)
+	return args.Get(0).([]models.Budget), args.Error(1)
+}
+
 func (m *mockBudgetRepo) GetByID(id uint) (*models.Budget, error) {
 	args := m.Called(id)
 	if args.Get(0) == nil {
@@ -43,34 +51,55 @@ func (m *mockBudgetRepo) Delete(id uint) error {
 	return args.Error(0)
 }
 
+func (m *mockBudgetRepo) ListLedger(budgetID uint) ([]models.BudgetLedgerEntry, error) {
+	args := m.Called(budgetID)
+	return args.Get(0).([]models.BudgetLedgerEntry), args.Error(1)
+}
+
+func (m *mockBudgetRepo) SaveLedgerEntry(entry *models.BudgetLedgerEntry) error {
+	args := m.Called(entry)
+	return args.Error(0)
+}
+
+func (m *mockBudgetRepo) SumSpending(budget *models.Budget, start, end time.Time) (float64, error) {
+	args := m.Called(budget, start, end)
+	return args.Get(0).(float64), args.Error(1)
+}
+
+func (m *mockBudgetRepo) CreateAlert(alert *models.BudgetAlert) (bool, error) {
+	args := m.Called(alert)
+	return args.Bool(0), args.Error(1)
+}
+
+func uintPtr(v uint) *uint { return &v }
+
 // --- Tests ---
 
 func TestBudgetService_Create(t *testing.T) {
 	budgetRepo := new(mockBudgetRepo)
-	txnRepo := new(mockTransactionRepo)
-	svc := NewBudgetService(budgetRepo, txnRepo)
+	svc := NewBudgetService(budgetRepo, nil, nil)
 
 	budgetRepo.On("Create", mock.AnythingOfType("*models.Budget")).Return(nil)
 
 	budget, err := svc.Create(1, models.CreateBudgetRequest{
-		CategoryID:    5,
+		CategoryID:    uintPtr(5),
 		MonthlyAmount: 3000,
 	})
 
 	assert.NoError(t, err)
 	assert.Equal(t, uint(1), budget.UserID)
-	assert.Equal(t, uint(5), budget.CategoryID)
+	assert.Equal(t, uint(5), *budget.CategoryID)
+	assert.Equal(t, models.BudgetTargetCategory, budget.TargetType)
 	assert.Equal(t, 3000.0, budget.MonthlyAmount)
 }
 
 func TestBudgetService_List(t *testing.T) {
 	budgetRepo := new(mockBudgetRepo)
-	txnRepo := new(mockTransactionRepo)
-	svc := NewBudgetService(budgetRepo, txnRepo)
+	svc := NewBudgetService(budgetRepo, nil, nil)
 
 	budgets := []models.Budget{
-		{ID: 1, UserID: 1, CategoryID: 5, MonthlyAmount: 3000},
-		{ID: 2, UserID: 1, CategoryID: 6, MonthlyAmount: 5000},
+		{ID: 1, UserID: 1, CategoryID: uintPtr(5), MonthlyAmount: 3000},
+		{ID: 2, UserID: 1, CategoryID: uintPtr(6), MonthlyAmount: 5000},
 	}
 	budgetRepo.On("List", uint(1)).Return(budgets, nil)
 
@@ -82,25 +111,30 @@ func TestBudgetService_List(t *testing.T) {
 
 func TestBudgetService_Update(t *testing.T) {
 	budgetRepo := new(mockBudgetRepo)
-	txnRepo := new(mockTransactionRepo)
-	svc := NewBudgetService(budgetRepo, txnRepo)
+	svc := NewBudgetService(budgetRepo, nil, nil)
 
-	budget := &models.Budget{ID: 1, UserID: 1, CategoryID: 5, MonthlyAmount: 3000}
+	budget := &models.Budget{ID: 1, UserID: 1, CategoryID: uintPtr(5), MonthlyAmount: 3000}
 	budgetRepo.On("GetByID", uint(1)).Return(budget, nil)
 	budgetRepo.On("Update", mock.AnythingOfType("*models.Budget")).Return(nil)
+	budgetRepo.On("ListLedger", uint(1)).Return([]models.BudgetLedgerEntry{}, nil)
+	budgetRepo.On("SumSpending", mock.AnythingOfType("*models.Budget"), mock.Anything, mock.Anything).Return(0.0, nil)
+	// The period in progress is recorded at the old amount first
+	budgetRepo.On("SaveLedgerEntry", mock.MatchedBy(func(entry *models.BudgetLedgerEntry) bool {
+		return entry.Amount == 3000
+	})).Return(nil)
 
 	result, err := svc.Update(1, 1, models.UpdateBudgetRequest{MonthlyAmount: 5000})
 
 	assert.NoError(t, err)
 	assert.Equal(t, 5000.0, result.MonthlyAmount)
+	budgetRepo.AssertCalled(t, "SaveLedgerEntry", mock.Anything)
 }
 
 func TestBudgetService_Update_Unauthorized(t *testing.T) {
 	budgetRepo := new(mockBudgetRepo)
-	txnRepo := new(mockTransactionRepo)
-	svc := NewBudgetService(budgetRepo, txnRepo)
+	svc := NewBudgetService(budgetRepo, nil, nil)
 
-	budget := &models.Budget{ID: 1, UserID: 2, CategoryID: 5, MonthlyAmount: 3000}
+	budget := &models.Budget{ID: 1, UserID: 2, CategoryID: uintPtr(5), MonthlyAmount: 3000}
 	budgetRepo.On("GetByID", uint(1)).Return(budget, nil)
 
 	_, err := svc.Update(1, 1, models.UpdateBudgetRequest{MonthlyAmount: 5000})
@@ -110,8 +144,7 @@ func TestBudgetService_Update_Unauthorized(t *testing.T) {
 
 func TestBudgetService_Delete(t *testing.T) {
 	budgetRepo := new(mockBudgetRepo)
-	txnRepo := new(mockTransactionRepo)
-	svc := NewBudgetService(budgetRepo, txnRepo)
+	svc := NewBudgetService(budgetRepo, nil, nil)
 
 	budget := &models.Budget{ID: 1, UserID: 1}
 	budgetRepo.On("GetByID", uint(1)).Return(budget, nil)
@@ -124,28 +157,23 @@ func TestBudgetService_Delete(t *testing.T) {
 
 func TestBudgetService_Compare(t *testing.T) {
 	budgetRepo := new(mockBudgetRepo)
-	txnRepo := new(mockTransactionRepo)
-	svc := NewBudgetService(budgetRepo, txnRepo)
+	svc := NewBudgetService(budgetRepo, nil, nil)
 
 	budgets := []models.Budget{
-		{ID: 1, UserID: 1, CategoryID: 5, MonthlyAmount: 3000},
+		{ID: 1, UserID: 1, CategoryID: uintPtr(5), MonthlyAmount: 3000},
 	}
 	budgetRepo.On("List", uint(1)).Return(budgets, nil)
 
-	// Transactions for the category
-	transactions := []models.Transaction{
-		{ID: 10, Amount: 1000, Type: "expense", TransactionDate: time.Date(2026, 1, 5, 0, 0, 0, 0, time.UTC)},
-		{ID: 11, Amount: 500, Type: "expense", TransactionDate: time.Date(2026, 1, 10, 0, 0, 0, 0, time.UTC)},
-		{ID: 12, Amount: 200, Type: "income", TransactionDate: time.Date(2026, 1, 15, 0, 0, 0, 0, time.UTC)}, // income, should not count
-	}
-	txnRepo.On("List", mock.AnythingOfType("repository.TransactionFilter")).
-		Return(transactions, int64(3), nil)
+	// Expenses for the category in January, summed in SQL
+	janStart := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	janEnd := time.Date(2026, 1, 31, 0, 0, 0, 0, time.UTC)
+	budgetRepo.On("SumSpending", mock.AnythingOfType("*models.Budget"), janStart, janEnd).Return(1500.0, nil)
 
 	comparisons, err := svc.Compare(1, 2026, 1)
 
 	assert.NoError(t, err)
 	assert.Len(t, comparisons, 1)
-	assert.Equal(t, 1500.0, comparisons[0].ActualAmount) // 1000 + 500, income excluded
+	assert.Equal(t, 1500.0, comparisons[0].ActualAmount)
 	assert.Equal(t, 1500.0, comparisons[0].Remaining)
 	assert.Equal(t, 50.0, comparisons[0].Percentage)
 	assert.False(t, comparisons[0].IsOverBudget)
@@ -153,20 +181,14 @@ func TestBudgetService_Compare(t *testing.T) {
 
 func TestBudgetService_Compare_OverBudget(t *testing.T) {
 	budgetRepo := new(mockBudgetRepo)
-	txnRepo := new(mockTransactionRepo)
-	svc := NewBudgetService(budgetRepo, txnRepo)
+	svc := NewBudgetService(budgetRepo, nil, nil)
 
 	budgets := []models.Budget{
-		{ID: 1, UserID: 1, CategoryID: 5, MonthlyAmount: 1000},
+		{ID: 1, UserID: 1, CategoryID: uintPtr(5), MonthlyAmount: 1000},
 	}
 	budgetRepo.On("List", uint(1)).Return(budgets, nil)
 
-	transactions := []models.Transaction{
-		{ID: 10, Amount: 800, Type: "expense"},
-		{ID: 11, Amount: 500, Type: "expense"},
-	}
-	txnRepo.On("List", mock.AnythingOfType("repository.TransactionFilter")).
-		Return(transactions, int64(2), nil)
+	budgetRepo.On("SumSpending", mock.AnythingOfType("*models.Budget"), mock.Anything, mock.Anything).Return(1300.0, nil)
 
 	comparisons, err := svc.Compare(1, 2026, 1)
 
@@ -175,3 +197,303 @@ func TestBudgetService_Compare_OverBudget(t *testing.T) {
 	assert.Equal(t, 1300.0, comparisons[0].ActualAmount)
 	assert.Equal(t, -300.0, comparisons[0].Remaining)
 }
+
+func TestBudgetService_Create_WeeklyStartDay(t *testing.T) {
+	budgetRepo := new(mockBudgetRepo)
+	svc := NewBudgetService(budgetRepo, nil, nil)
+
+	budgetRepo.On("Create", mock.AnythingOfType("*models.Budget")).Return(nil)
+
+	// Sunday is kept, not replaced by the default
+	sunday := int(time.Sunday)
+	budget, err := svc.Create(1, models.CreateBudgetRequest{CategoryID: uintPtr(5), MonthlyAmount: 3000, PeriodType: models.BudgetPeriodWeekly, StartDay: &sunday})
+	assert.NoError(t, err)
+	assert.Equal(t, int(time.Sunday), budget.StartDay)
+
+	// Without a start day weekly budgets begin on Monday
+	budget, err = svc.Create(1, models.CreateBudgetRequest{CategoryID: uintPtr(5), MonthlyAmount: 3000, PeriodType: models.BudgetPeriodWeekly})
+	assert.NoError(t, err)
+	assert.Equal(t, int(time.Monday), budget.StartDay)
+}
+
+func TestBudgetService_Create_InvalidPeriod(t *testing.T) {
+	budgetRepo := new(mockBudgetRepo)
+	svc := NewBudgetService(budgetRepo, nil, nil)
+
+	_, err := svc.Create(1, models.CreateBudgetRequest{CategoryID: uintPtr(5), MonthlyAmount: 3000, PeriodType: "daily"})
+	assert.Error(t, err)
+
+	day := 31
+	_, err = svc.Create(1, models.CreateBudgetRequest{CategoryID: uintPtr(5), MonthlyAmount: 3000, StartDay: &day})
+	assert.Error(t, err)
+
+	_, err = svc.Create(1, models.CreateBudgetRequest{CategoryID: uintPtr(5), MonthlyAmount: 3000, PeriodType: models.BudgetPeriodCustom})
+	assert.Error(t, err)
+
+	budgetRepo.AssertNotCalled(t, "Create", mock.Anything)
+}
+
+func TestBudgetWindow(t *testing.T) {
+	date := func(y int, m time.Month, d int) time.Time { return time.Date(y, m, d, 0, 0, 0, 0, time.UTC) }
+	customStart, customEnd := date(2026, 2, 10), date(2026, 2, 20)
+
+	tests := []struct {
+		name   string
+		budget models.Budget
+		ref    time.Time
+		start  time.Time
+		end    time.Time
+	}{
+		{"monthly", models.Budget{PeriodType: models.BudgetPeriodMonthly, StartDay: 1}, date(2026, 2, 14), date(2026, 2, 1), date(2026, 2, 28)},
+		{"monthly salary day before", models.Budget{PeriodType: models.BudgetPeriodMonthly, StartDay: 5}, date(2026, 2, 3), date(2026, 1, 5), date(2026, 2, 4)},
+		{"monthly salary day after", models.Budget{PeriodType: models.BudgetPeriodMonthly, StartDay: 5}, date(2026, 2, 5), date(2026, 2, 5), date(2026, 3, 4)},
+		{"weekly monday", models.Budget{PeriodType: models.BudgetPeriodWeekly, StartDay: 1}, date(2026, 2, 14), date(2026, 2, 9), date(2026, 2, 15)},
+		{"weekly sunday", models.Budget{PeriodType: models.BudgetPeriodWeekly, StartDay: 0}, date(2026, 2, 14), date(2026, 2, 8), date(2026, 2, 14)},
+		{"quarterly", models.Budget{PeriodType: models.BudgetPeriodQuarterly, StartDay: 1}, date(2026, 5, 20), date(2026, 4, 1), date(2026, 6, 30)},
+		{"quarterly before start day", models.Budget{PeriodType: models.BudgetPeriodQuarterly, StartDay: 10}, date(2026, 4, 3), date(2026, 1, 10), date(2026, 4, 9)},
+		{"yearly", models.Budget{PeriodType: models.BudgetPeriodYearly, StartDay: 1}, date(2026, 7, 1), date(2026, 1, 1), date(2026, 12, 31)},
+		{"custom", models.Budget{PeriodType: models.BudgetPeriodCustom, StartDate: &customStart, EndDate: &customEnd}, date(2026, 5, 1), customStart, customEnd},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start, end := BudgetWindow(&tt.budget, tt.ref)
+			assert.Equal(t, tt.start, start)
+			assert.Equal(t, tt.end, end)
+		})
+	}
+}
+
+func TestBudgetService_CompareAt_UsesPeriodWindow(t *testing.T) {
+	budgetRepo := new(mockBudgetRepo)
+	svc := NewBudgetService(budgetRepo, nil, nil)
+
+	budgets := []models.Budget{
+		{ID: 1, UserID: 1, CategoryID: uintPtr(5), MonthlyAmount: 500, PeriodType: models.BudgetPeriodWeekly, StartDay: 1},
+	}
+	budgetRepo.On("List", uint(1)).Return(budgets, nil)
+
+	weekStart := time.Date(2026, 2, 9, 0, 0, 0, 0, time.UTC)
+	weekEnd := time.Date(2026, 2, 15, 0, 0, 0, 0, time.UTC)
+	budgetRepo.On("SumSpending", mock.AnythingOfType("*models.Budget"), weekStart, weekEnd).Return(600.0, nil)
+
+	comparisons, err := svc.CompareAt(1, time.Date(2026, 2, 11, 0, 0, 0, 0, time.UTC))
+
+	assert.NoError(t, err)
+	assert.Len(t, comparisons, 1)
+	assert.Equal(t, weekStart, comparisons[0].PeriodStart)
+	assert.True(t, comparisons[0].IsOverBudget)
+}
+
+func TestBudgetService_History_CarriesForward(t *testing.T) {
+	budgetRepo := new(mockBudgetRepo)
+	svc := NewBudgetService(budgetRepo, nil, nil)
+
+	// Weekly rollover budget created two weeks ago
+	today := dateOf(time.Now(), userLocation(nil, 1))
+	budget := &models.Budget{
+		ID: 1, UserID: 1, CategoryID: uintPtr(5), MonthlyAmount: 1000,
+		PeriodType: models.BudgetPeriodWeekly, StartDay: 1, Rollover: true,
+		CreatedAt: today.AddDate(0, 0, -14),
+	}
+	budgetRepo.On("GetByID", uint(1)).Return(budget, nil)
+	budgetRepo.On("ListLedger", uint(1)).Return([]models.BudgetLedgerEntry{}, nil)
+	budgetRepo.On("SaveLedgerEntry", mock.AnythingOfType("*models.BudgetLedgerEntry")).Return(nil)
+	budgetRepo.On("SumSpending", mock.AnythingOfType("*models.Budget"), mock.Anything, mock.Anything).Return(700.0, nil)
+
+	history, err := svc.History(1, 1, 0)
+
+	assert.NoError(t, err)
+	assert.Len(t, history, 3)
+	assert.Equal(t, 0.0, history[0].CarryIn)
+	assert.Equal(t, 300.0, history[0].CarryOut)
+	assert.Equal(t, 300.0, history[1].CarryIn)
+	assert.Equal(t, 600.0, history[1].CarryOut)
+	assert.Equal(t, 600.0, history[2].CarryIn)
+	assert.False(t, history[2].Closed)
+
+	limited, err := svc.History(1, 1, 1)
+	assert.NoError(t, err)
+	assert.Len(t, limited, 1)
+}
+
+func TestBudgetService_History_KeepsClosedEntries(t *testing.T) {
+	budgetRepo := new(mockBudgetRepo)
+	svc := NewBudgetService(budgetRepo, nil, nil)
+
+	today := dateOf(time.Now(), userLocation(nil, 1))
+	budget := &models.Budget{
+		ID: 1, UserID: 1, CategoryID: uintPtr(5), MonthlyAmount: 2000,
+		PeriodType: models.BudgetPeriodMonthly, StartDay: 1, Rollover: true,
+	}
+	closedStart, closedEnd := BudgetWindow(budget, today.AddDate(0, -3, 0))
+	closed := models.BudgetLedgerEntry{
+		ID: 7, BudgetID: 1, UserID: 1, PeriodStart: closedStart, PeriodEnd: closedEnd,
+		Amount: 1000, Actual: 400, CarryOut: 600, Closed: true,
+	}
+	budgetRepo.On("GetByID", uint(1)).Return(budget, nil)
+	budgetRepo.On("ListLedger", uint(1)).Return([]models.BudgetLedgerEntry{closed}, nil)
+	budgetRepo.On("SaveLedgerEntry", mock.AnythingOfType("*models.BudgetLedgerEntry")).Return(nil)
+	budgetRepo.On("SumSpending", mock.AnythingOfType("*models.Budget"), mock.Anything, mock.Anything).Return(0.0, nil)
+
+	history, err := svc.History(1, 1, 0)
+
+	assert.NoError(t, err)
+	assert.Len(t, history, 4)
+	assert.Equal(t, 1000.0, history[0].Amount) // snapshot survives the amount change
+	assert.Equal(t, 600.0, history[1].CarryIn)
+	assert.Equal(t, 2000.0, history[1].Amount)
+	budgetRepo.AssertNumberOfCalls(t, "SaveLedgerEntry", 3)
+}
+
+func TestBudgetService_History_Unauthorized(t *testing.T) {
+	budgetRepo := new(mockBudgetRepo)
+	svc := NewBudgetService(budgetRepo, nil, nil)
+
+	budgetRepo.On("GetByID", uint(1)).Return(&models.Budget{ID: 1, UserID: 2}, nil)
+
+	_, err := svc.History(1, 1, 12)
+	assert.Error(t, err)
+}
+
+func TestBudgetService_History_KeepsAmountOfRecordedPeriods(t *testing.T) {
+	budgetRepo := new(mockBudgetRepo)
+	svc := NewBudgetService(budgetRepo, nil, nil)
+
+	// Last month's entry is still settling, and the budget was raised since
+	today := dateOf(time.Now(), userLocation(nil, 1))
+	budget := &models.Budget{
+		ID: 1, UserID: 1, CategoryID: uintPtr(5), MonthlyAmount: 2000,
+		PeriodType: models.BudgetPeriodMonthly, StartDay: 1,
+	}
+	lastStart, lastEnd := BudgetWindow(budget, today.AddDate(0, -1, 0))
+	open := models.BudgetLedgerEntry{
+		ID: 7, BudgetID: 1, UserID: 1, PeriodStart: lastStart, PeriodEnd: lastEnd, Amount: 1000,
+	}
+	budgetRepo.On("GetByID", uint(1)).Return(budget, nil)
+	budgetRepo.On("ListLedger", uint(1)).Return([]models.BudgetLedgerEntry{open}, nil)
+	budgetRepo.On("SaveLedgerEntry", mock.AnythingOfType("*models.BudgetLedgerEntry")).Return(nil)
+	budgetRepo.On("SumSpending", mock.AnythingOfType("*models.Budget"), mock.Anything, mock.Anything).Return(800.0, nil)
+
+	history, err := svc.History(1, 1, 0)
+
+	assert.NoError(t, err)
+	assert.Len(t, history, 2)
+	assert.Equal(t, 1000.0, history[0].Amount)
+	assert.Equal(t, 800.0, history[0].Actual) // actuals are still recalculated
+	assert.Equal(t, 2000.0, history[1].Amount)
+}
+
+func TestBudgetService_Update_PeriodChangeClosesLedger(t *testing.T) {
+	budgetRepo := new(mockBudgetRepo)
+	svc := NewBudgetService(budgetRepo, nil, nil)
+
+	// Weekly periods starting yesterday's weekday, so the current one began yesterday
+	today := dateOf(time.Now(), userLocation(nil, 1))
+	yesterday := today.AddDate(0, 0, -1)
+	budget := &models.Budget{
+		ID: 1, UserID: 1, CategoryID: uintPtr(5), MonthlyAmount: 1000,
+		PeriodType: models.BudgetPeriodWeekly, StartDay: int(yesterday.Weekday()),
+		CreatedAt: yesterday.AddDate(0, 0, -7),
+	}
+	var saved []models.BudgetLedgerEntry
+	budgetRepo.On("GetByID", uint(1)).Return(budget, nil)
+	budgetRepo.On("Update", mock.AnythingOfType("*models.Budget")).Return(nil)
+	budgetRepo.On("ListLedger", uint(1)).Return([]models.BudgetLedgerEntry{}, nil)
+	budgetRepo.On("SaveLedgerEntry", mock.AnythingOfType("*models.BudgetLedgerEntry")).Run(func(args mock.Arguments) {
+		saved = append(saved, *args.Get(0).(*models.BudgetLedgerEntry))
+	}).Return(nil)
+	budgetRepo.On("SumSpending", mock.AnythingOfType("*models.Budget"), mock.Anything, mock.Anything).Return(100.0, nil)
+
+	_, err := svc.Update(1, 1, models.UpdateBudgetRequest{MonthlyAmount: 3000, PeriodType: models.BudgetPeriodMonthly})
+	assert.NoError(t, err)
+
+	// Both recorded weeks are saved again as closed, the current one ending yesterday
+	assert.Len(t, saved, 4)
+	closed := saved[2:]
+	for _, entry := range closed {
+		assert.True(t, entry.Closed)
+		assert.Equal(t, 1000.0, entry.Amount)
+	}
+	assert.Equal(t, yesterday, closed[1].PeriodStart)
+	assert.Equal(t, yesterday, closed[1].PeriodEnd)
+}
+
+func TestBudgetService_History_ResumesAfterClosedEntries(t *testing.T) {
+	budgetRepo := new(mockBudgetRepo)
+	svc := NewBudgetService(budgetRepo, nil, nil)
+
+	// Weekly entries closed yesterday when the budget became monthly
+	today := dateOf(time.Now(), userLocation(nil, 1))
+	budget := &models.Budget{
+		ID: 1, UserID: 1, CategoryID: uintPtr(5), MonthlyAmount: 3000,
+		PeriodType: models.BudgetPeriodMonthly, StartDay: 1, Rollover: true,
+	}
+	closed := models.BudgetLedgerEntry{
+		ID: 7, BudgetID: 1, UserID: 1, PeriodStart: today.AddDate(0, 0, -7), PeriodEnd: today.AddDate(0, 0, -1),
+		Amount: 1000, Actual: 600, CarryOut: 400, Closed: true,
+	}
+	budgetRepo.On("GetByID", uint(1)).Return(budget, nil)
+	budgetRepo.On("ListLedger", uint(1)).Return([]models.BudgetLedgerEntry{closed}, nil)
+	budgetRepo.On("SaveLedgerEntry", mock.AnythingOfType("*models.BudgetLedgerEntry")).Return(nil)
+	budgetRepo.On("SumSpending", mock.AnythingOfType("*models.Budget"), mock.Anything, mock.Anything).Return(0.0, nil)
+
+	history, err := svc.History(1, 1, 0)
+
+	assert.NoError(t, err)
+	assert.Len(t, history, 2)
+	assert.Equal(t, closed, history[0])
+	_, monthEnd := BudgetWindow(budget, today)
+	assert.Equal(t, today, history[1].PeriodStart)
+	assert.Equal(t, monthEnd, history[1].PeriodEnd)
+	assert.Equal(t, 400.0, history[1].CarryIn)
+	assert.Equal(t, 3000.0, history[1].Amount)
+}
+
+func TestBudgetService_Create_Targets(t *testing.T) {
+	budgetRepo := new(mockBudgetRepo)
+	svc := NewBudgetService(budgetRepo, nil, nil)
+
+	budgetRepo.On("Create", mock.AnythingOfType("*models.Budget")).Return(nil)
+
+	overall, err := svc.Create(1, models.CreateBudgetRequest{TargetType: models.BudgetTargetOverall, MonthlyAmount: 30000})
+	assert.NoError(t, err)
+	assert.Nil(t, overall.CategoryID)
+
+	tagged, err := svc.Create(1, models.CreateBudgetRequest{TargetType: models.BudgetTargetTag, Tag: " japan-trip-2026 ", MonthlyAmount: 80000})
+	assert.NoError(t, err)
+	assert.Equal(t, "japan-trip-2026", tagged.Tag)
+
+	set, err := svc.Create(1, models.CreateBudgetRequest{TargetType: models.BudgetTargetCategories, CategoryIDs: []int64{1, 2}, MonthlyAmount: 5000})
+	assert.NoError(t, err)
+	assert.Len(t, set.CategoryIDs, 2)
+
+	_, err = svc.Create(1, models.CreateBudgetRequest{TargetType: models.BudgetTargetAccount, MonthlyAmount: 5000})
+	assert.Error(t, err)
+
+	_, err = svc.Create(1, models.CreateBudgetRequest{MonthlyAmount: 5000})
+	assert.Error(t, err) // category target needs category_id
+
+	_, err = svc.Create(1, models.CreateBudgetRequest{TargetType: "merchant", MonthlyAmount: 5000})
+	assert.Error(t, err)
+}
+
+func TestBudgetService_Create_AccountTarget(t *testing.T) {
+	budgetRepo := new(mockBudgetRepo)
+	accountRepo := new(mockAccountRepo)
+	svc := NewBudgetService(budgetRepo, accountRepo, nil)
+
+	cardID, otherID := uint(4), uint(9)
+	accountRepo.On("GetByID", uint(1), cardID).Return(&models.Account{ID: cardID, UserID: 1}, nil)
+	// Account 9 belongs to someone else, so the user's lookup finds nothing
+	accountRepo.On("GetByID", uint(1), otherID).Return(nil, gorm.ErrRecordNotFound)
+	budgetRepo.On("Create", mock.AnythingOfType("*models.Budget")).Return(nil)
+
+	budget, err := svc.Create(1, models.CreateBudgetRequest{TargetType: models.BudgetTargetAccount, AccountID: &cardID, MonthlyAmount: 5000})
+	require.NoError(t, err)
+	assert.Equal(t, cardID, *budget.AccountID)
+
+	_, err = svc.Create(1, models.CreateBudgetRequest{TargetType: models.BudgetTargetAccount, AccountID: &otherID, MonthlyAmount: 5000})
+	assert.Equal(t, http.StatusNotFound, statusOf(err))
+	budgetRepo.AssertNumberOfCalls(t, "Create", 1)
+}
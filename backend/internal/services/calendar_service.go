@@ -0,0 +1,255 @@
+package services
+
+import (
+	"billing-note/internal/models"
+	"billing-note/internal/repository"
+	"billing-note/pkg/errors"
+	"billing-note/pkg/logger"
+	"bytes"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Calendar feed windows
+const (
+	calendarHistoryDays    = 90  // past bills kept in the feed
+	calendarLookbackDays   = 400 // transaction history used for detection
+	calendarRecurringAhead = 3   // expected charges per recurring series
+)
+
+// CalendarService manages iCalendar feed tokens and renders the feed of
+// upcoming bills, installment payments and recurring charges
+type CalendarService struct {
+	repo            repository.CalendarRepository
+	billRepo        repository.BillRepository
+	transactionRepo repository.TransactionRepository
+	userRepo        repository.UserRepository
+	now             func() time.Time
+}
+
+// NewCalendarService creates a new calendar service
+func NewCalendarService(repo repository.CalendarRepository, billRepo repository.BillRepository, transactionRepo repository.TransactionRepository, userRepo repository.UserRepository) *CalendarService {
+	return &CalendarService{
+		repo:            repo,
+		billRepo:        billRepo,
+		transactionRepo: transactionRepo,
+		userRepo:        userRepo,
+		now:             time.Now,
+	}
+}
+
+// GetFeedToken returns the user's feed token, or nil if the feed is not enabled
+func (s *CalendarService) GetFeedToken(userID uint) (*models.CalendarFeedToken, error) {
+	token, err := s.repo.GetFeedToken(userID)
+	if err != nil {
+		return nil, errors.NewDBError("get calendar feed token", err)
+	}
+	return token, nil
+}
+
+// RegenerateFeedToken enables the feed with a new token, invalidating any
+// previous feed URL
+func (s *CalendarService) RegenerateFeedToken(userID uint) (*models.CalendarFeedToken, error) {
+	existing, err := s.repo.GetFeedToken(userID)
+	if err != nil {
+		return nil, errors.NewDBError("get calendar feed token", err)
+	}
+
+	value, err := generateFeedToken()
+	if err != nil {
+		return nil, errors.NewInternalError("Failed to generate feed token", err)
+	}
+
+	token := existing
+	if token == nil {
+		token = &models.CalendarFeedToken{UserID: userID}
+	}
+	token.Token = value
+	token.LastAccessedAt = nil
+	if err := s.repo.SaveFeedToken(token); err != nil {
+		return nil, errors.NewDBError("save calendar feed token", err)
+	}
+	return token, nil
+}
+
+// RevokeFeedToken disables the user's feed
+func (s *CalendarService) RevokeFeedToken(userID uint) error {
+	if err := s.repo.DeleteFeedToken(userID); err != nil {
+		return errors.NewNotFoundError("Calendar feed", userID)
+	}
+	return nil
+}
+
+// Feed renders the iCalendar feed for a feed token
+func (s *CalendarService) Feed(token string) ([]byte, error) {
+	feedToken, err := s.repo.FindByFeedToken(token)
+	if err != nil {
+		return nil, errors.NewDBError("find calendar feed token", err)
+	}
+	if feedToken == nil {
+		return nil, errors.NewNotFoundError("Calendar feed", token)
+	}
+	userID := feedToken.UserID
+
+	now := s.now()
+	if err := s.repo.TouchFeedToken(feedToken.ID, now); err != nil {
+		logger.ServiceLog("CalendarService", "Feed").WithError(err).Warn("Failed to record feed access")
+	}
+
+	today := dateOf(now, userLocation(s.userRepo, userID))
+	cal := newICalendar(now)
+
+	bills, err := s.billRepo.List(userID, "")
+	if err != nil {
+		return nil, errors.NewDBError("list bills", err)
+	}
+	historyStart := today.AddDate(0, 0, -calendarHistoryDays)
+	for _, bill := range bills {
+		due := dateOf(bill.DueDate, time.UTC)
+		if due.Before(historyStart) {
+			continue
+		}
+		summary := fmt.Sprintf("Pay %s: %s", billLabel(&bill), formatAmount(bill.AmountDue))
+		if bill.Status == models.BillStatusPaid {
+			summary += " (paid)"
+		}
+		description := fmt.Sprintf("Amount due: %s\nMinimum due: %s", formatAmount(bill.AmountDue), formatAmount(bill.MinimumDue))
+		alarm := 0
+		if bill.Status == models.BillStatusPending {
+			alarm = bill.RemindDays
+		}
+		cal.addAllDayEvent(fmt.Sprintf("bill-%d@billing-note", bill.ID), due, summary, description, alarm)
+	}
+
+	start := today.AddDate(0, 0, -calendarLookbackDays)
+	transactions, _, err := s.transactionRepo.List(repository.TransactionFilter{
+		UserID:    userID,
+		Type:      "expense",
+		StartDate: &start,
+	})
+	if err != nil {
+		return nil, errors.NewDBError("list transactions", err)
+	}
+
+	for _, inst := range DetectInstallments(transactions, today) {
+		for i, due := range inst.Upcoming {
+			n := inst.Paid + i + 1
+			cal.addAllDayEvent(
+				fmt.Sprintf("installment-%s-%d@billing-note", shortHash(inst.Key), n),
+				due,
+				fmt.Sprintf("Installment %d/%d: %s %s", n, inst.Total, inst.Description, formatAmount(inst.Amount)),
+				"Expected card installment payment",
+				0,
+			)
+		}
+	}
+
+	for _, charge := range DetectRecurringCharges(transactions, today) {
+		next := charge.NextDate
+		for i := 0; i < calendarRecurringAhead; i++ {
+			cal.addAllDayEvent(
+				fmt.Sprintf("recurring-%s-%s@billing-note", shortHash(charge.Key), next.Format("20060102")),
+				next,
+				fmt.Sprintf("Expected charge: %s ~%s", charge.Description, formatAmount(charge.Amount)),
+				fmt.Sprintf("Charged monthly for the last %d months", charge.Occurrences),
+				0,
+			)
+			next = addMonthsClamped(next, 1, charge.DayOfMonth)
+		}
+	}
+
+	return cal.bytes(), nil
+}
+
+// generateFeedToken returns 32 random bytes, hex encoded
+func generateFeedToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func shortHash(s string) string {
+	sum := sha1.Sum([]byte(s))
+	return hex.EncodeToString(sum[:6])
+}
+
+func formatAmount(amount float64) string {
+	return fmt.Sprintf("%.0f", amount)
+}
+
+// --- iCalendar (RFC 5545) rendering ---
+
+type iCalendar struct {
+	buf   bytes.Buffer
+	stamp string
+}
+
+func newICalendar(now time.Time) *iCalendar {
+	c := &iCalendar{stamp: now.UTC().Format("20060102T150405Z")}
+	c.line("BEGIN:VCALENDAR")
+	c.line("VERSION:2.0")
+	c.line("PRODID:-//Billing Note//Bills Calendar//EN")
+	c.line("CALSCALE:GREGORIAN")
+	c.line("METHOD:PUBLISH")
+	c.line("X-WR-CALNAME:" + escapeICalText("Billing Note"))
+	c.line("REFRESH-INTERVAL;VALUE=DURATION:PT6H")
+	c.line("X-PUBLISHED-TTL:PT6H")
+	return c
+}
+
+// addAllDayEvent adds a VEVENT on date; alarmDays > 0 adds a reminder that
+// many days before
+func (c *iCalendar) addAllDayEvent(uid string, date time.Time, summary, description string, alarmDays int) {
+	c.line("BEGIN:VEVENT")
+	c.line("UID:" + uid)
+	c.line("DTSTAMP:" + c.stamp)
+	c.line("DTSTART;VALUE=DATE:" + date.Format("20060102"))
+	c.line("DTEND;VALUE=DATE:" + date.AddDate(0, 0, 1).Format("20060102"))
+	c.line("SUMMARY:" + escapeICalText(summary))
+	if description != "" {
+		c.line("DESCRIPTION:" + escapeICalText(description))
+	}
+	c.line("TRANSP:TRANSPARENT")
+	if alarmDays > 0 {
+		c.line("BEGIN:VALARM")
+		c.line("ACTION:DISPLAY")
+		c.line("DESCRIPTION:" + escapeICalText(summary))
+		c.line(fmt.Sprintf("TRIGGER:-P%dD", alarmDays))
+		c.line("END:VALARM")
+	}
+	c.line("END:VEVENT")
+}
+
+func (c *iCalendar) bytes() []byte {
+	c.line("END:VCALENDAR")
+	return c.buf.Bytes()
+}
+
+// line writes a content line, folding it at 75 octets without splitting
+// UTF-8 sequences
+func (c *iCalendar) line(s string) {
+	const limit = 75
+	width := 0
+	for _, r := range s {
+		n := len(string(r))
+		if width+n > limit {
+			c.buf.WriteString("\r\n ")
+			width = 1
+		}
+		c.buf.WriteRune(r)
+		width += n
+	}
+	c.buf.WriteString("\r\n")
+}
+
+var icalTextEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`)
+
+func escapeICalText(s string) string {
+	return icalTextEscaper.Replace(s)
+}
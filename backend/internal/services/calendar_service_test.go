@@ -0,0 +1,119 @@
+package services
+
+import (
+	"billing-note/internal/models"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// --- Mock Calendar Repository ---
+
+type mockCalendarRepo struct {
+	mock.Mock
+}
+
+func (m *mockCalendarRepo) GetFeedToken(userID uint) (*models.CalendarFeedToken, error) {
+	args := m.Called(userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.CalendarFeedToken), args.Error(1)
+}
+
+func (m *mockCalendarRepo) SaveFeedToken(token *models.CalendarFeedToken) error {
+	args := m.Called(token)
+	return args.Error(0)
+}
+
+func (m *mockCalendarRepo) FindByFeedToken(token string) (*models.CalendarFeedToken, error) {
+	args := m.Called(token)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.CalendarFeedToken), args.Error(1)
+}
+
+func (m *mockCalendarRepo) DeleteFeedToken(userID uint) error {
+	args := m.Called(userID)
+	return args.Error(0)
+}
+
+func (m *mockCalendarRepo) TouchFeedToken(id uint, at time.Time) error {
+	args := m.Called(id, at)
+	return args.Error(0)
+}
+
+// --- Tests ---
+
+func TestCalendarService_RegenerateFeedToken_Rotates(t *testing.T) {
+	repo := new(mockCalendarRepo)
+	svc := NewCalendarService(repo, nil, nil, nil)
+
+	existing := &models.CalendarFeedToken{ID: 1, UserID: 1, Token: "old"}
+	repo.On("GetFeedToken", uint(1)).Return(existing, nil)
+	repo.On("SaveFeedToken", existing).Return(nil)
+
+	token, err := svc.RegenerateFeedToken(1)
+
+	require.NoError(t, err)
+	assert.Equal(t, uint(1), token.ID)
+	assert.NotEqual(t, "old", token.Token)
+	assert.Len(t, token.Token, 64)
+}
+
+func TestCalendarService_Feed_UnknownToken(t *testing.T) {
+	repo := new(mockCalendarRepo)
+	svc := NewCalendarService(repo, nil, nil, nil)
+
+	repo.On("FindByFeedToken", "revoked").Return(nil, nil)
+
+	_, err := svc.Feed("revoked")
+	assert.Error(t, err)
+}
+
+func TestCalendarService_Feed(t *testing.T) {
+	repo := new(mockCalendarRepo)
+	billRepo := new(mockBillRepo)
+	txnRepo := new(mockTransactionRepo)
+	svc := NewCalendarService(repo, billRepo, txnRepo, nil)
+	svc.now = func() time.Time { return time.Date(2025, 12, 10, 1, 0, 0, 0, time.UTC) }
+
+	repo.On("FindByFeedToken", "secret").Return(&models.CalendarFeedToken{ID: 3, UserID: 1, Token: "secret"}, nil)
+	repo.On("TouchFeedToken", uint(3), mock.Anything).Return(nil)
+	billRepo.On("List", uint(1), "").Return([]models.Bill{
+		{ID: 7, UserID: 1, Description: "國泰世華 card statement", DueDate: ymd(2025, 12, 15), AmountDue: 12345, RemindDays: 3, Status: models.BillStatusPending},
+		{ID: 1, UserID: 1, Description: "Ancient", DueDate: ymd(2024, 1, 15), Status: models.BillStatusPaid},
+	}, nil)
+	txnRepo.On("List", mock.Anything).Return([]models.Transaction{
+		expense("燦坤 分期 3/4", 1500, ymd(2025, 11, 20)),
+		expense("NETFLIX.COM", 390, ymd(2025, 9, 15)),
+		expense("NETFLIX.COM", 390, ymd(2025, 10, 15)),
+		expense("NETFLIX.COM", 390, ymd(2025, 11, 15)),
+	}, int64(4), nil)
+
+	data, err := svc.Feed("secret")
+	require.NoError(t, err)
+	ics := string(data)
+
+	assert.True(t, strings.HasPrefix(ics, "BEGIN:VCALENDAR\r\n"))
+	assert.True(t, strings.HasSuffix(ics, "END:VCALENDAR\r\n"))
+	assert.Contains(t, ics, "UID:bill-7@billing-note")
+	assert.Contains(t, ics, "DTSTART;VALUE=DATE:20251215")
+	assert.Contains(t, ics, "TRIGGER:-P3D")
+	assert.NotContains(t, ics, "bill-1@billing-note")
+	assert.Contains(t, ics, "SUMMARY:Installment 4/4: 燦坤 1500")
+	assert.Contains(t, ics, "DTSTART;VALUE=DATE:20251220")
+	assert.Equal(t, 3, strings.Count(ics, "Expected charge: NETFLIX.COM"))
+	for _, line := range strings.Split(ics, "\r\n") {
+		assert.LessOrEqual(t, len(line), 75)
+	}
+}
+
+func TestEscapeICalText(t *testing.T) {
+	assert.Equal(t, `a\, b\; c\\d\nnext`, escapeICalText("a, b; c\\d\nnext"))
+}
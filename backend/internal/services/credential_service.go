@@ -0,0 +1,239 @@
+package services
+
+import (
+	"billing-note/internal/models"
+	"billing-note/internal/repository"
+	"billing-note/pkg/errors"
+	"billing-note/pkg/logger"
+	"fmt"
+	"net/url"
+	"time"
+)
+
+const (
+	passwordResetExpiry     = time.Hour
+	emailVerificationExpiry = 48 * time.Hour
+)
+
+// CredentialService handles password changes, password resets and email
+// verification. Reset and verification links are single-use tokens sent
+// through the mailer. A new password revokes the user's personal API tokens,
+// since they may have been created by whoever knew the old one.
+type CredentialService struct {
+	userRepo     repository.UserRepository
+	tokenRepo    repository.UserTokenRepository
+	sessionRepo  repository.SessionRepository
+	apiTokenRepo repository.APITokenRepository
+	mailer       Mailer
+	appURL       string
+	now          func() time.Time
+}
+
+// NewCredentialService creates a new credential service; emailed links point
+// at appURL
+func NewCredentialService(userRepo repository.UserRepository, tokenRepo repository.UserTokenRepository, sessionRepo repository.SessionRepository, apiTokenRepo repository.APITokenRepository, mailer Mailer, appURL string) *CredentialService {
+	return &CredentialService{
+		userRepo:     userRepo,
+		tokenRepo:    tokenRepo,
+		sessionRepo:  sessionRepo,
+		apiTokenRepo: apiTokenRepo,
+		mailer:       mailer,
+		appURL:       appURL,
+		now:          time.Now,
+	}
+}
+
+// ChangePassword sets a new password after checking the current one, and
+// signs out every other session
+func (s *CredentialService) ChangePassword(userID, sessionID uint, input models.ChangePasswordInput) error {
+	log := logger.ServiceLog("CredentialService", "ChangePassword")
+
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return errors.NewNotFoundError("User", userID)
+	}
+	if !user.CheckPassword(input.CurrentPassword) {
+		return errors.NewInvalidInputError("current_password", "is incorrect")
+	}
+
+	if err := s.setPassword(user, input.NewPassword, sessionID); err != nil {
+		return err
+	}
+
+	log.WithField("user_id", userID).Info("Password changed")
+	s.send(user, "Your password was changed",
+		"The password for your Billing Note account was just changed and your other devices were signed out.\n\n"+
+			"If this wasn't you, reset your password right away.")
+	return nil
+}
+
+// RequestPasswordReset emails a reset link if the address belongs to a user.
+// It succeeds either way, so it cannot be used to find registered addresses.
+func (s *CredentialService) RequestPasswordReset(email string) error {
+	log := logger.ServiceLog("CredentialService", "RequestPasswordReset")
+
+	user, err := s.userRepo.FindByEmail(email)
+	if err != nil || user == nil {
+		log.WithField("email", email).Debug("Password reset requested for unknown email")
+		return nil
+	}
+
+	// Only the newest link works
+	if err := s.tokenRepo.InvalidateAll(user.ID, models.UserTokenPasswordReset, s.now()); err != nil {
+		return errors.NewDBError("invalidate reset tokens", err)
+	}
+	token, err := s.issueToken(user.ID, models.UserTokenPasswordReset, passwordResetExpiry)
+	if err != nil {
+		return err
+	}
+
+	log.WithField("user_id", user.ID).Info("Password reset requested")
+	s.send(user, "Reset your password", fmt.Sprintf(
+		"Someone asked to reset the password for your Billing Note account.\n\n"+
+			"Set a new password within the next hour:\n%s\n\n"+
+			"If this wasn't you, you can ignore this email.",
+		s.link("/reset-password", token)))
+	return nil
+}
+
+// ResetPassword sets a new password with a reset token and signs out every
+// session
+func (s *CredentialService) ResetPassword(input models.ResetPasswordInput) error {
+	log := logger.ServiceLog("CredentialService", "ResetPassword")
+
+	now := s.now()
+	token, err := s.tokenRepo.Consume(models.UserTokenPasswordReset, hashToken(input.Token), now)
+	if err != nil {
+		return errors.NewDBError("consume reset token", err)
+	}
+	if token == nil {
+		return errors.NewInvalidTokenError("Invalid or expired password reset link")
+	}
+
+	user, err := s.userRepo.FindByID(token.UserID)
+	if err != nil {
+		return errors.NewInvalidTokenError("Invalid or expired password reset link")
+	}
+	// The reset link reached the inbox, which proves the address
+	if user.EmailVerifiedAt == nil {
+		user.EmailVerifiedAt = &now
+	}
+	if err := s.setPassword(user, input.NewPassword, 0); err != nil {
+		return err
+	}
+
+	log.WithField("user_id", user.ID).Info("Password reset")
+	s.send(user, "Your password was reset",
+		"The password for your Billing Note account was reset and all devices were signed out.")
+	return nil
+}
+
+// SendVerification emails a link to verify the user's address
+func (s *CredentialService) SendVerification(user *models.User) error {
+	if user.EmailVerifiedAt != nil {
+		return errors.NewConflictError("Email address is already verified")
+	}
+	if err := s.tokenRepo.InvalidateAll(user.ID, models.UserTokenEmailVerification, s.now()); err != nil {
+		return errors.NewDBError("invalidate verification tokens", err)
+	}
+	token, err := s.issueToken(user.ID, models.UserTokenEmailVerification, emailVerificationExpiry)
+	if err != nil {
+		return err
+	}
+
+	s.send(user, "Verify your email address", fmt.Sprintf(
+		"Welcome to Billing Note! Confirm your email address within 48 hours:\n%s",
+		s.link("/verify-email", token)))
+	return nil
+}
+
+// ResendVerification emails a new verification link to a signed-in user
+func (s *CredentialService) ResendVerification(userID uint) error {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return errors.NewNotFoundError("User", userID)
+	}
+	return s.SendVerification(user)
+}
+
+// VerifyEmail marks the address of the token's user verified
+func (s *CredentialService) VerifyEmail(rawToken string) (*models.User, error) {
+	now := s.now()
+	token, err := s.tokenRepo.Consume(models.UserTokenEmailVerification, hashToken(rawToken), now)
+	if err != nil {
+		return nil, errors.NewDBError("consume verification token", err)
+	}
+	if token == nil {
+		return nil, errors.NewInvalidTokenError("Invalid or expired verification link")
+	}
+
+	user, err := s.userRepo.FindByID(token.UserID)
+	if err != nil {
+		return nil, errors.NewInvalidTokenError("Invalid or expired verification link")
+	}
+	if user.EmailVerifiedAt == nil {
+		user.EmailVerifiedAt = &now
+		if err := s.userRepo.Update(user); err != nil {
+			return nil, errors.NewDBError("verify email", err)
+		}
+	}
+	return user, nil
+}
+
+// setPassword stores the new password, voids outstanding reset links, revokes
+// the user's sessions except keepSessionID (0 for all) and revokes all of
+// their API tokens
+func (s *CredentialService) setPassword(user *models.User, password string, keepSessionID uint) error {
+	if err := user.SetPassword(password); err != nil {
+		return errors.NewInternalError("Failed to hash password", err)
+	}
+	if err := s.userRepo.Update(user); err != nil {
+		return errors.NewDBError("update password", err)
+	}
+
+	now := s.now()
+	if err := s.tokenRepo.InvalidateAll(user.ID, models.UserTokenPasswordReset, now); err != nil {
+		return errors.NewDBError("invalidate reset tokens", err)
+	}
+	if _, err := s.sessionRepo.RevokeAllByUser(user.ID, keepSessionID, now); err != nil {
+		return errors.NewDBError("revoke sessions", err)
+	}
+	if _, err := s.apiTokenRepo.RevokeAllByUser(user.ID, now); err != nil {
+		return errors.NewDBError("revoke API tokens", err)
+	}
+	return nil
+}
+
+// issueToken stores the hash of a new token and returns the token
+func (s *CredentialService) issueToken(userID uint, purpose string, expiry time.Duration) (string, error) {
+	token, err := generateSecretToken()
+	if err != nil {
+		return "", errors.NewInternalError("Failed to generate token", err)
+	}
+	record := &models.UserToken{
+		UserID:    userID,
+		Purpose:   purpose,
+		TokenHash: hashToken(token),
+		ExpiresAt: s.now().Add(expiry),
+	}
+	if err := s.tokenRepo.Create(record); err != nil {
+		return "", errors.NewDBError("create token", err)
+	}
+	return token, nil
+}
+
+func (s *CredentialService) link(path, token string) string {
+	return s.appURL + path + "?token=" + url.QueryEscape(token)
+}
+
+// send emails the user; delivery failures are logged, not returned, since the
+// account change has already happened
+func (s *CredentialService) send(user *models.User, subject, body string) {
+	if err := s.mailer.Send(user.Email, subject, body); err != nil {
+		logger.ServiceLog("CredentialService", "send").WithFields(logger.Fields{
+			"user_id": user.ID,
+			"subject": subject,
+			"error":   err.Error(),
+		}).Error("Failed to send email")
+	}
+}
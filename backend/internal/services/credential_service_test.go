@@ -0,0 +1,201 @@
+package services
+
+import (
+	"billing-note/internal/models"
+	"errors"
+	"net/http"
+	"net/url"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// --- Mock User Token Repository ---
+
+type mockUserTokenRepo struct {
+	mock.Mock
+}
+
+func (m *mockUserTokenRepo) Create(token *models.UserToken) error {
+	args := m.Called(token)
+	return args.Error(0)
+}
+
+func (m *mockUserTokenRepo) Consume(purpose, hash string, now time.Time) (*models.UserToken, error) {
+	args := m.Called(purpose, hash, now)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.UserToken), args.Error(1)
+}
+
+func (m *mockUserTokenRepo) InvalidateAll(userID uint, purpose string, at time.Time) error {
+	args := m.Called(userID, purpose, at)
+	return args.Error(0)
+}
+
+var credentialNow = time.Date(2026, 6, 15, 12, 0, 0, 0, time.UTC)
+
+func newTestCredentialService() (*CredentialService, *MockUserRepository, *mockUserTokenRepo, *mockSessionRepo, *mockAPITokenRepo, *MemoryMailer) {
+	userRepo := new(MockUserRepository)
+	tokenRepo := new(mockUserTokenRepo)
+	sessionRepo := new(mockSessionRepo)
+	apiTokenRepo := new(mockAPITokenRepo)
+	mailer := &MemoryMailer{}
+	svc := NewCredentialService(userRepo, tokenRepo, sessionRepo, apiTokenRepo, mailer, "https://app.example.com")
+	svc.now = func() time.Time { return credentialNow }
+	return svc, userRepo, tokenRepo, sessionRepo, apiTokenRepo, mailer
+}
+
+func userWithPassword(password string) *models.User {
+	user := &models.User{ID: 1, Email: "user@example.com"}
+	user.SetPassword(password)
+	return user
+}
+
+// tokenFromLink extracts the token from the link in an email body
+func tokenFromLink(t *testing.T, body string) string {
+	link := regexp.MustCompile(`https://\S+`).FindString(body)
+	require.NotEmpty(t, link)
+	parsed, err := url.Parse(link)
+	require.NoError(t, err)
+	return parsed.Query().Get("token")
+}
+
+func TestCredentialService_ChangePassword(t *testing.T) {
+	svc, userRepo, tokenRepo, sessionRepo, apiTokenRepo, mailer := newTestCredentialService()
+
+	user := userWithPassword("old-password")
+	userRepo.On("FindByID", uint(1)).Return(user, nil)
+	userRepo.On("Update", user).Return(nil)
+	tokenRepo.On("InvalidateAll", uint(1), models.UserTokenPasswordReset, credentialNow).Return(nil)
+	sessionRepo.On("RevokeAllByUser", uint(1), uint(7), credentialNow).Return(int64(2), nil)
+	apiTokenRepo.On("RevokeAllByUser", uint(1), credentialNow).Return(int64(1), nil)
+
+	err := svc.ChangePassword(1, 7, models.ChangePasswordInput{CurrentPassword: "wrong", NewPassword: "new-password"})
+	assert.Equal(t, http.StatusBadRequest, statusOf(err))
+	userRepo.AssertNotCalled(t, "Update", mock.Anything)
+
+	err = svc.ChangePassword(1, 7, models.ChangePasswordInput{CurrentPassword: "old-password", NewPassword: "new-password"})
+	require.NoError(t, err)
+	assert.True(t, user.CheckPassword("new-password"))
+
+	// Other sessions are signed out, the current one is kept; API tokens are revoked
+	sessionRepo.AssertExpectations(t)
+	tokenRepo.AssertExpectations(t)
+	apiTokenRepo.AssertExpectations(t)
+	require.Len(t, mailer.Messages(), 1)
+	assert.Equal(t, "Your password was changed", mailer.Messages()[0].Subject)
+}
+
+func TestCredentialService_RequestPasswordReset_UnknownEmail(t *testing.T) {
+	svc, userRepo, tokenRepo, _, _, mailer := newTestCredentialService()
+
+	userRepo.On("FindByEmail", "nobody@example.com").Return(nil, errors.New("user not found"))
+
+	assert.NoError(t, svc.RequestPasswordReset("nobody@example.com"))
+	assert.Empty(t, mailer.Messages())
+	tokenRepo.AssertNotCalled(t, "Create", mock.Anything)
+}
+
+func TestCredentialService_RequestPasswordReset(t *testing.T) {
+	svc, userRepo, tokenRepo, _, _, mailer := newTestCredentialService()
+
+	userRepo.On("FindByEmail", "user@example.com").Return(userWithPassword("old-password"), nil)
+	tokenRepo.On("InvalidateAll", uint(1), models.UserTokenPasswordReset, credentialNow).Return(nil)
+	var stored *models.UserToken
+	tokenRepo.On("Create", mock.Anything).Run(func(args mock.Arguments) {
+		stored = args.Get(0).(*models.UserToken)
+	}).Return(nil)
+
+	require.NoError(t, svc.RequestPasswordReset("user@example.com"))
+
+	require.Len(t, mailer.Messages(), 1)
+	message := mailer.Messages()[0]
+	assert.Equal(t, "user@example.com", message.To)
+	assert.Contains(t, message.Body, "https://app.example.com/reset-password?token=")
+
+	// Only the hash of the emailed token is stored
+	token := tokenFromLink(t, message.Body)
+	assert.Equal(t, hashToken(token), stored.TokenHash)
+	assert.Equal(t, models.UserTokenPasswordReset, stored.Purpose)
+	assert.Equal(t, credentialNow.Add(time.Hour), stored.ExpiresAt)
+}
+
+func TestCredentialService_ResetPassword(t *testing.T) {
+	svc, userRepo, tokenRepo, sessionRepo, apiTokenRepo, _ := newTestCredentialService()
+
+	user := userWithPassword("old-password")
+	tokenRepo.On("Consume", models.UserTokenPasswordReset, hashToken("bad-token"), credentialNow).Return(nil, nil)
+	tokenRepo.On("Consume", models.UserTokenPasswordReset, hashToken("reset-token"), credentialNow).
+		Return(&models.UserToken{ID: 3, UserID: 1, Purpose: models.UserTokenPasswordReset}, nil)
+	tokenRepo.On("InvalidateAll", uint(1), models.UserTokenPasswordReset, credentialNow).Return(nil)
+	userRepo.On("FindByID", uint(1)).Return(user, nil)
+	userRepo.On("Update", user).Return(nil)
+	sessionRepo.On("RevokeAllByUser", uint(1), uint(0), credentialNow).Return(int64(3), nil)
+	apiTokenRepo.On("RevokeAllByUser", uint(1), credentialNow).Return(int64(2), nil)
+
+	err := svc.ResetPassword(models.ResetPasswordInput{Token: "bad-token", NewPassword: "new-password"})
+	assert.Equal(t, http.StatusUnauthorized, statusOf(err))
+
+	require.NoError(t, svc.ResetPassword(models.ResetPasswordInput{Token: "reset-token", NewPassword: "new-password"}))
+	assert.True(t, user.CheckPassword("new-password"))
+	require.NotNil(t, user.EmailVerifiedAt)
+
+	// Every session and API token is revoked
+	sessionRepo.AssertExpectations(t)
+	apiTokenRepo.AssertExpectations(t)
+}
+
+func TestCredentialService_VerifyEmail(t *testing.T) {
+	svc, userRepo, tokenRepo, _, _, _ := newTestCredentialService()
+
+	user := &models.User{ID: 1, Email: "user@example.com"}
+	tokenRepo.On("Consume", models.UserTokenEmailVerification, hashToken("verify-token"), credentialNow).
+		Return(&models.UserToken{ID: 4, UserID: 1, Purpose: models.UserTokenEmailVerification}, nil)
+	tokenRepo.On("Consume", models.UserTokenEmailVerification, hashToken("used-token"), credentialNow).Return(nil, nil)
+	userRepo.On("FindByID", uint(1)).Return(user, nil)
+	userRepo.On("Update", user).Return(nil)
+
+	verified, err := svc.VerifyEmail("verify-token")
+	require.NoError(t, err)
+	require.NotNil(t, verified.EmailVerifiedAt)
+	assert.Equal(t, credentialNow, *verified.EmailVerifiedAt)
+
+	_, err = svc.VerifyEmail("used-token")
+	assert.Equal(t, http.StatusUnauthorized, statusOf(err))
+}
+
+func TestCredentialService_SendVerification_AlreadyVerified(t *testing.T) {
+	svc, _, tokenRepo, _, _, mailer := newTestCredentialService()
+
+	verifiedAt := credentialNow.Add(-time.Hour)
+	err := svc.SendVerification(&models.User{ID: 1, Email: "user@example.com", EmailVerifiedAt: &verifiedAt})
+
+	assert.Equal(t, http.StatusConflict, statusOf(err))
+	assert.Empty(t, mailer.Messages())
+	tokenRepo.AssertNotCalled(t, "Create", mock.Anything)
+}
+
+func TestAuthService_Register_SendsVerification(t *testing.T) {
+	credentials, userRepo, tokenRepo, _, _, mailer := newTestCredentialService()
+	svc := NewAuthService(userRepo, acceptingSessionRepo(), nil, credentials, nil, "test-secret", 15*time.Minute, 720*time.Hour)
+
+	userRepo.On("FindByEmail", "new@example.com").Return(nil, errors.New("user not found"))
+	userRepo.On("Create", mock.AnythingOfType("*models.User")).Run(func(args mock.Arguments) {
+		args.Get(0).(*models.User).ID = 1
+	}).Return(nil)
+	tokenRepo.On("InvalidateAll", uint(1), models.UserTokenEmailVerification, credentialNow).Return(nil)
+	tokenRepo.On("Create", mock.Anything).Return(nil)
+
+	_, err := svc.Register(&RegisterRequest{Email: "new@example.com", Password: "password123"})
+	require.NoError(t, err)
+
+	require.Len(t, mailer.Messages(), 1)
+	assert.Equal(t, "new@example.com", mailer.Messages()[0].To)
+	assert.Contains(t, mailer.Messages()[0].Body, "https://app.example.com/verify-email?token=")
+}
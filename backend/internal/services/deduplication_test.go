@@ -59,6 +59,14 @@ func (m *mockTransactionRepo) GetTrendStats(userID uint, months int) ([]reposito
 	return args.Get(0).([]repository.TrendDataPoint), args.Error(1)
 }
 
+func (m *mockTransactionRepo) WithScope(scope *models.ShareScope) repository.TransactionRepository {
+	return m
+}
+
+func (m *mockTransactionRepo) ForLedger(ledgerID *uint) repository.TransactionRepository {
+	return m
+}
+
 // --- Helper ---
 
 func newTestDeduplicationService(txnRepo *mockTransactionRepo, invRepo *mockInvoiceRepo) *DeduplicationService {
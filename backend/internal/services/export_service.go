@@ -16,6 +16,12 @@ func NewExportService(transactionRepo repository.TransactionRepository) *ExportS
 	return &ExportService{transactionRepo: transactionRepo}
 }
 
+// ForLedger returns a service exporting the household ledger's transactions;
+// a nil ledger ID selects the user's personal ones
+func (s *ExportService) ForLedger(ledgerID *uint) *ExportService {
+	return &ExportService{transactionRepo: s.transactionRepo.ForLedger(ledgerID)}
+}
+
 func (s *ExportService) ExportCSV(userID uint, startDate, endDate time.Time) ([]byte, error) {
 	filter := repository.TransactionFilter{
 		UserID:    userID,
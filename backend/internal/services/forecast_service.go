@@ -0,0 +1,314 @@
+package services
+
+import (
+	"billing-note/internal/models"
+	"billing-note/internal/repository"
+	"billing-note/pkg/errors"
+	"math"
+	"sort"
+	"time"
+)
+
+// Forecast parameters
+const (
+	MaxForecastMonths       = 6
+	forecastLookbackDays    = 400 // history used to detect recurring items
+	forecastAverageMonths   = 3   // full months averaged for discretionary spend
+	forecastConfidence      = 0.8
+	forecastConfidenceZ     = 1.2816 // two-sided z-score for forecastConfidence
+	uncategorizedSpendLabel = "Uncategorized"
+)
+
+// ForecastService projects cash flow from recurring income, subscriptions,
+// installment schedules, upcoming bills and average discretionary spending
+type ForecastService struct {
+	transactionRepo repository.TransactionRepository
+	billRepo        repository.BillRepository
+	userRepo        repository.UserRepository
+	now             func() time.Time
+}
+
+// NewForecastService creates a new forecast service
+func NewForecastService(transactionRepo repository.TransactionRepository, billRepo repository.BillRepository, userRepo repository.UserRepository) *ForecastService {
+	return &ForecastService{
+		transactionRepo: transactionRepo,
+		billRepo:        billRepo,
+		userRepo:        userRepo,
+		now:             time.Now,
+	}
+}
+
+// forecastFlow is a scheduled amount with its variance
+type forecastFlow struct {
+	income, expense, variance float64
+}
+
+// Forecast projects daily balance and monthly totals from today through the
+// end of the given number of months. startingBalance is today's balance; pass
+// 0 to see the relative change.
+//
+// Pending card bills are cash out on their due date. A bill stands in for its
+// card's spending until then, so that card's subscriptions, installment
+// payments and share of discretionary spending are only projected after the
+// bill is due.
+func (s *ForecastService) Forecast(userID uint, months int, startingBalance float64) (*models.CashFlowForecast, error) {
+	if months < 1 || months > MaxForecastMonths {
+		return nil, errors.NewInvalidInputError("months", "must be between 1 and 6")
+	}
+
+	today := dateOf(s.now(), userLocation(s.userRepo, userID))
+	end := time.Date(today.Year(), today.Month()+time.Month(months), 1, 0, 0, 0, 0, time.UTC).AddDate(0, 0, -1)
+
+	start := today.AddDate(0, 0, -forecastLookbackDays)
+	transactions, _, err := s.transactionRepo.List(repository.TransactionFilter{
+		UserID:    userID,
+		StartDate: &start,
+	})
+	if err != nil {
+		return nil, errors.NewDBError("list transactions", err)
+	}
+
+	forecast := &models.CashFlowForecast{
+		StartDate:       today,
+		EndDate:         end,
+		StartingBalance: startingBalance,
+		Confidence:      forecastConfidence,
+		Scheduled:       []models.ForecastItem{},
+	}
+	flows := make(map[time.Time]*forecastFlow)
+	addFlow := func(d time.Time, item models.ForecastItem, stdDev float64) {
+		if d.Before(today) || d.After(end) {
+			return
+		}
+		f, ok := flows[d]
+		if !ok {
+			f = &forecastFlow{}
+			flows[d] = f
+		}
+		if item.Type == "income" {
+			f.income += item.Amount
+		} else {
+			f.expense += item.Amount
+		}
+		f.variance += stdDev * stdDev
+		item.Date = d
+		forecast.Scheduled = append(forecast.Scheduled, item)
+	}
+
+	// Recurring income
+	recurringKeys := make(map[string]bool)
+	for _, income := range DetectRecurringIncome(transactions, today) {
+		for d := income.NextDate; !d.After(end); d = addMonthsClamped(d, 1, income.DayOfMonth) {
+			addFlow(d, models.ForecastItem{Kind: models.ForecastRecurringIncome, Type: "income", Description: income.Description, Amount: income.Amount}, income.StdDev)
+		}
+	}
+
+	// Upcoming card bills; overdue ones are assumed paid today
+	billedUntil := make(map[uint]time.Time) // card account -> latest pending bill due date
+	if s.billRepo != nil {
+		bills, err := s.billRepo.List(userID, models.BillStatusPending)
+		if err != nil {
+			return nil, errors.NewDBError("list bills", err)
+		}
+		for _, bill := range bills {
+			due := dateOf(bill.DueDate, time.UTC)
+			if due.Before(today) {
+				due = today
+			}
+			addFlow(due, models.ForecastItem{Kind: models.ForecastBill, Type: "expense", Description: billLabel(&bill), Amount: bill.AmountDue}, 0)
+			if bill.AccountID != nil && due.After(billedUntil[*bill.AccountID]) {
+				billedUntil[*bill.AccountID] = due
+			}
+		}
+	}
+	// billed reports whether spending on the account on day d is covered by a
+	// pending bill
+	billed := func(accountID *uint, d time.Time) bool {
+		if accountID == nil {
+			return false
+		}
+		until, ok := billedUntil[*accountID]
+		return ok && !d.After(until)
+	}
+
+	// Subscriptions and installment schedules
+	for _, charge := range DetectRecurringCharges(transactions, today) {
+		recurringKeys[charge.Key] = true
+		for d := charge.NextDate; !d.After(end); d = addMonthsClamped(d, 1, charge.DayOfMonth) {
+			if billed(charge.AccountID, d) {
+				continue
+			}
+			addFlow(d, models.ForecastItem{Kind: models.ForecastSubscription, Type: "expense", Description: charge.Description, Amount: charge.Amount}, charge.StdDev)
+		}
+	}
+	for _, inst := range DetectInstallments(transactions, today) {
+		for _, d := range inst.Upcoming {
+			if billed(inst.AccountID, d) {
+				continue
+			}
+			addFlow(d, models.ForecastItem{Kind: models.ForecastInstallment, Type: "expense", Description: inst.Description, Amount: inst.Amount}, 0)
+		}
+	}
+	sort.SliceStable(forecast.Scheduled, func(i, j int) bool {
+		return forecast.Scheduled[i].Date.Before(forecast.Scheduled[j].Date)
+	})
+
+	// Discretionary spending: average of the last full months per category
+	forecast.Discretionary = discretionarySpending(transactions, recurringKeys, today)
+	monthlyMean, monthlyVariance := 0.0, 0.0
+	for _, d := range forecast.Discretionary {
+		monthlyMean += d.MonthlyAverage
+		monthlyVariance += d.MonthlyStdDev * d.MonthlyStdDev
+	}
+	cardMeans := cardSpending(transactions, recurringKeys, today, billedUntil)
+
+	// Daily projection
+	balance := startingBalance
+	variance := 0.0
+	monthIndex := make(map[string]int)
+	monthVariance := make(map[string]float64)
+	for d := today; !d.After(end); d = d.AddDate(0, 0, 1) {
+		daysInMonth := float64(time.Date(d.Year(), d.Month()+1, 0, 0, 0, 0, 0, time.UTC).Day())
+		dayMean := monthlyMean
+		for accountID, mean := range cardMeans {
+			if billed(&accountID, d) {
+				dayMean -= mean
+			}
+		}
+		day := models.ForecastDay{
+			Date:    d,
+			Expense: math.Max(0, dayMean) / daysInMonth,
+		}
+		dayVariance := monthlyVariance / daysInMonth
+		if f, ok := flows[d]; ok {
+			day.Income += f.income
+			day.Expense += f.expense
+			dayVariance += f.variance
+		}
+		day.Income = roundCents(day.Income)
+		day.Expense = roundCents(day.Expense)
+		balance += day.Income - day.Expense
+		variance += dayVariance
+		margin := forecastConfidenceZ * math.Sqrt(variance)
+		day.Balance = roundCents(balance)
+		day.Low = roundCents(balance - margin)
+		day.High = roundCents(balance + margin)
+		forecast.Days = append(forecast.Days, day)
+
+		key := d.Format("2006-01")
+		i, ok := monthIndex[key]
+		if !ok {
+			i = len(forecast.Months)
+			monthIndex[key] = i
+			forecast.Months = append(forecast.Months, models.ForecastMonth{Month: key})
+		}
+		m := &forecast.Months[i]
+		m.Income += day.Income
+		m.Expense += day.Expense
+		m.EndBalance = day.Balance
+		monthVariance[key] += dayVariance
+	}
+	for i := range forecast.Months {
+		m := &forecast.Months[i]
+		margin := forecastConfidenceZ * math.Sqrt(monthVariance[m.Month])
+		m.Income = roundCents(m.Income)
+		m.Expense = roundCents(m.Expense)
+		m.Net = roundCents(m.Income - m.Expense)
+		m.ExpenseLow = roundCents(math.Max(0, m.Expense-margin))
+		m.ExpenseHigh = roundCents(m.Expense + margin)
+	}
+
+	return forecast, nil
+}
+
+// discretionarySpending averages each category's spending over the last
+// forecastAverageMonths full months, leaving out subscriptions and installments
+func discretionarySpending(transactions []models.Transaction, recurringKeys map[string]bool, today time.Time) []models.DiscretionaryForecast {
+	periodEnd := time.Date(today.Year(), today.Month(), 1, 0, 0, 0, 0, time.UTC)
+	periodStart := periodEnd.AddDate(0, -forecastAverageMonths, 0)
+
+	type categoryTotals struct {
+		id      *uint
+		name    string
+		byMonth [forecastAverageMonths]float64
+	}
+	totals := make(map[uint]*categoryTotals)
+	for _, txn := range transactions {
+		if !isDiscretionary(txn, recurringKeys, periodStart, periodEnd) {
+			continue
+		}
+		d := dateOf(txn.TransactionDate, time.UTC)
+
+		var id uint
+		if txn.CategoryID != nil {
+			id = *txn.CategoryID
+		}
+		t, ok := totals[id]
+		if !ok {
+			t = &categoryTotals{id: txn.CategoryID, name: uncategorizedSpendLabel}
+			if txn.Category != nil {
+				t.name = txn.Category.Name
+			}
+			totals[id] = t
+		}
+		month := (d.Year()-periodStart.Year())*12 + int(d.Month()) - int(periodStart.Month())
+		t.byMonth[month] += txn.Amount
+	}
+
+	result := make([]models.DiscretionaryForecast, 0, len(totals))
+	for _, t := range totals {
+		mean := 0.0
+		for _, v := range t.byMonth {
+			mean += v
+		}
+		mean /= forecastAverageMonths
+		variance := 0.0
+		for _, v := range t.byMonth {
+			variance += (v - mean) * (v - mean)
+		}
+		variance /= forecastAverageMonths
+		result = append(result, models.DiscretionaryForecast{
+			CategoryID:     t.id,
+			CategoryName:   t.name,
+			MonthlyAverage: roundCents(mean),
+			MonthlyStdDev:  roundCents(math.Sqrt(variance)),
+		})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].MonthlyAverage > result[j].MonthlyAverage })
+	return result
+}
+
+// cardSpending averages the discretionary spending of each card account with
+// a pending bill over the same months as discretionarySpending
+func cardSpending(transactions []models.Transaction, recurringKeys map[string]bool, today time.Time, billedUntil map[uint]time.Time) map[uint]float64 {
+	periodEnd := time.Date(today.Year(), today.Month(), 1, 0, 0, 0, 0, time.UTC)
+	periodStart := periodEnd.AddDate(0, -forecastAverageMonths, 0)
+
+	means := make(map[uint]float64)
+	for _, txn := range transactions {
+		if txn.AccountID == nil || !isDiscretionary(txn, recurringKeys, periodStart, periodEnd) {
+			continue
+		}
+		if _, ok := billedUntil[*txn.AccountID]; ok {
+			means[*txn.AccountID] += txn.Amount / forecastAverageMonths
+		}
+	}
+	return means
+}
+
+// isDiscretionary reports whether the transaction is an expense within
+// [periodStart, periodEnd) that is neither an installment nor a recurring charge
+func isDiscretionary(txn models.Transaction, recurringKeys map[string]bool, periodStart, periodEnd time.Time) bool {
+	d := dateOf(txn.TransactionDate, time.UTC)
+	if txn.Type != "expense" || d.Before(periodStart) || !d.Before(periodEnd) {
+		return false
+	}
+	if _, _, _, ok := parseInstallment(txn.Description); ok {
+		return false
+	}
+	return !recurringKeys[transactionKey(txn)]
+}
+
+func roundCents(v float64) float64 {
+	return math.Round(v*100) / 100
+}
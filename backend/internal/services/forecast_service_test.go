@@ -0,0 +1,134 @@
+package services
+
+import (
+	"billing-note/internal/models"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestForecastService(txns []models.Transaction, bills []models.Bill, now time.Time) *ForecastService {
+	txnRepo := new(mockTransactionRepo)
+	txnRepo.On("List", mock.Anything).Return(txns, int64(len(txns)), nil)
+	billRepo := new(mockBillRepo)
+	billRepo.On("List", uint(1), models.BillStatusPending).Return(bills, nil)
+
+	svc := NewForecastService(txnRepo, billRepo, nil)
+	svc.now = func() time.Time { return now }
+	return svc
+}
+
+func TestForecastService_Forecast(t *testing.T) {
+	food := uint(1)
+	foodCategory := &models.Category{ID: 1, Name: "餐飲"}
+	var txns []models.Transaction
+	lunch := map[time.Month]float64{9: 2500, 10: 3500, 11: 3000}
+	for _, m := range []time.Month{9, 10, 11} {
+		txns = append(txns,
+			models.Transaction{Type: "income", Description: "薪資", Amount: 50000, TransactionDate: ymd(2025, m, 5)},
+			models.Transaction{Type: "expense", Description: "NETFLIX.COM", Amount: 390, TransactionDate: ymd(2025, m, 15)},
+			models.Transaction{Type: "expense", Description: "午餐", Amount: lunch[m], CategoryID: &food, Category: foodCategory, TransactionDate: ymd(2025, m, 10)},
+		)
+	}
+	bills := []models.Bill{{ID: 7, Description: "Card", DueDate: ymd(2025, 12, 20), AmountDue: 10000}}
+
+	// 2025-12-01 in Asia/Taipei
+	svc := newTestForecastService(txns, bills, time.Date(2025, 12, 1, 1, 0, 0, 0, time.UTC))
+
+	forecast, err := svc.Forecast(1, 2, 100000)
+	require.NoError(t, err)
+
+	assert.Equal(t, ymd(2025, 12, 1), forecast.StartDate)
+	assert.Equal(t, ymd(2026, 1, 31), forecast.EndDate)
+	assert.Len(t, forecast.Days, 62)
+	require.Len(t, forecast.Months, 2)
+
+	// Salary, Netflix and the bill in December; salary and Netflix in January
+	kinds := map[string]int{}
+	for _, item := range forecast.Scheduled {
+		kinds[item.Kind]++
+	}
+	assert.Equal(t, 2, kinds[models.ForecastRecurringIncome])
+	assert.Equal(t, 2, kinds[models.ForecastSubscription])
+	assert.Equal(t, 1, kinds[models.ForecastBill])
+
+	// Netflix is a subscription, not discretionary spend
+	require.Len(t, forecast.Discretionary, 1)
+	assert.Equal(t, "餐飲", forecast.Discretionary[0].CategoryName)
+	assert.Equal(t, 3000.0, forecast.Discretionary[0].MonthlyAverage)
+
+	dec := forecast.Months[0]
+	assert.Equal(t, "2025-12", dec.Month)
+	assert.Equal(t, 50000.0, dec.Income)
+	assert.InDelta(t, 390+10000+3000, dec.Expense, 0.5)
+
+	last := forecast.Days[len(forecast.Days)-1]
+	assert.InDelta(t, 100000+2*50000-2*390-10000-2*3000, last.Balance, 1)
+	assert.LessOrEqual(t, last.Low, last.Balance)
+	assert.GreaterOrEqual(t, last.High, last.Balance)
+}
+
+func TestForecastService_ConfidenceBandWidens(t *testing.T) {
+	food := uint(1)
+	txns := []models.Transaction{
+		{Type: "expense", Description: "午餐", Amount: 1000, CategoryID: &food, TransactionDate: ymd(2025, 9, 10)},
+		{Type: "expense", Description: "晚餐", Amount: 5000, CategoryID: &food, TransactionDate: ymd(2025, 10, 10)},
+		{Type: "expense", Description: "聚餐", Amount: 3000, CategoryID: &food, TransactionDate: ymd(2025, 11, 10)},
+	}
+	svc := newTestForecastService(txns, nil, time.Date(2025, 12, 1, 1, 0, 0, 0, time.UTC))
+
+	forecast, err := svc.Forecast(1, 3, 0)
+	require.NoError(t, err)
+
+	first := forecast.Days[0]
+	last := forecast.Days[len(forecast.Days)-1]
+	assert.Less(t, first.High-first.Low, last.High-last.Low)
+	assert.Equal(t, uncategorizedSpendLabel, forecast.Discretionary[0].CategoryName)
+}
+
+func TestForecastService_CardBillCoversCardSpending(t *testing.T) {
+	food := uint(1)
+	card := uint(5)
+	var txns []models.Transaction
+	lunch := map[time.Month]float64{9: 2500, 10: 3500, 11: 3000}
+	for i, m := range []time.Month{9, 10, 11} {
+		txns = append(txns,
+			models.Transaction{Type: "expense", Description: "午餐", Amount: lunch[m], CategoryID: &food, AccountID: &card, TransactionDate: ymd(2025, m, 12)},
+			models.Transaction{Type: "expense", Description: "NETFLIX.COM", Amount: 390, AccountID: &card, TransactionDate: ymd(2025, m, 15)},
+			models.Transaction{Type: "expense", Description: fmt.Sprintf("家電 分期 %d/6", i+1), Amount: 1200, AccountID: &card, TransactionDate: ymd(2025, m, 10)},
+		)
+	}
+	// The pending bill already includes the card's spending up to its due date
+	bills := []models.Bill{{ID: 7, AccountID: &card, Description: "Card", DueDate: ymd(2025, 12, 20), AmountDue: 4590}}
+
+	svc := newTestForecastService(txns, bills, time.Date(2025, 12, 1, 1, 0, 0, 0, time.UTC))
+
+	forecast, err := svc.Forecast(1, 2, 0)
+	require.NoError(t, err)
+
+	// December's card charges are in the bill; January's are projected
+	scheduled := map[string][]time.Time{}
+	for _, item := range forecast.Scheduled {
+		scheduled[item.Kind] = append(scheduled[item.Kind], item.Date)
+	}
+	assert.Equal(t, []time.Time{ymd(2026, 1, 10)}, scheduled[models.ForecastInstallment])
+	assert.Equal(t, []time.Time{ymd(2026, 1, 15)}, scheduled[models.ForecastSubscription])
+
+	// Discretionary card spending is projected only for the days after the bill is due
+	require.Len(t, forecast.Months, 2)
+	assert.InDelta(t, 4590+3000*11.0/31, forecast.Months[0].Expense, 0.5)
+	assert.InDelta(t, 1200+390+3000, forecast.Months[1].Expense, 0.5)
+}
+
+func TestForecastService_InvalidMonths(t *testing.T) {
+	svc := NewForecastService(nil, nil, nil)
+
+	_, err := svc.Forecast(1, 0, 0)
+	assert.Error(t, err)
+	_, err = svc.Forecast(1, 7, 0)
+	assert.Error(t, err)
+}
@@ -1,6 +1,8 @@
 package services
 
 import (
+	"billing-note/internal/email"
+	"billing-note/internal/email/order_extractors"
 	"billing-note/internal/models"
 	"billing-note/internal/repository"
 	"billing-note/pkg/errors"
@@ -8,8 +10,10 @@ import (
 	"context"
 	"encoding/base64"
 	"fmt"
+	"net/mail"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"time"
 
@@ -23,6 +27,9 @@ type GmailAPIClient interface {
 	ListMessages(query string, maxResults int64) ([]*gmail.Message, error)
 	GetMessage(id string) (*gmail.Message, error)
 	GetAttachment(messageID, attachmentID string) ([]byte, error)
+	// Label operations (require the gmail.modify scope)
+	EnsureLabel(name string) (string, error)
+	ModifyLabels(messageID string, addLabelIDs, removeLabelIDs []string) error
 }
 
 // realGmailClient wraps the actual Google Gmail API
@@ -73,6 +80,37 @@ func (c *realGmailClient) GetAttachment(messageID, attachmentID string) ([]byte,
 	return base64.URLEncoding.DecodeString(att.Data)
 }
 
+// EnsureLabel returns the ID of the user label with the given name, creating it if missing
+func (c *realGmailClient) EnsureLabel(name string) (string, error) {
+	resp, err := c.service.Users.Labels.List("me").Do()
+	if err != nil {
+		return "", err
+	}
+	for _, label := range resp.Labels {
+		if label.Name == name {
+			return label.Id, nil
+		}
+	}
+
+	label, err := c.service.Users.Labels.Create("me", &gmail.Label{
+		Name:                  name,
+		LabelListVisibility:   "labelShow",
+		MessageListVisibility: "show",
+	}).Do()
+	if err != nil {
+		return "", err
+	}
+	return label.Id, nil
+}
+
+func (c *realGmailClient) ModifyLabels(messageID string, addLabelIDs, removeLabelIDs []string) error {
+	_, err := c.service.Users.Messages.Modify("me", messageID, &gmail.ModifyMessageRequest{
+		AddLabelIds:    addLabelIDs,
+		RemoveLabelIds: removeLabelIDs,
+	}).Do()
+	return err
+}
+
 // ScanResult represents the result of a Gmail scan
 type ScanResult struct {
 	Scanned      int            `json:"scanned"`
@@ -80,6 +118,8 @@ type ScanResult struct {
 	AutoParsed   int            `json:"auto_parsed"`
 	Imported     int            `json:"imported"`
 	Failed       int            `json:"failed"`
+	OrdersFound  int            `json:"orders_found"`
+	Labeled      int            `json:"labeled"`
 	ParseResults []UploadResult `json:"parse_results,omitempty"`
 	Status       string         `json:"status"`
 	ErrorMessage string         `json:"error_message,omitempty"`
@@ -91,6 +131,7 @@ type GmailScanService struct {
 	uploadService *UploadService
 	repo          repository.GmailRepository
 	uploadDir     string
+	orderRegistry *email.ExtractorRegistry
 	// For testing: injectable client factory
 	clientFactory func(ctx context.Context, token *oauth2.Token) (GmailAPIClient, error)
 }
@@ -107,6 +148,7 @@ func NewGmailScanService(
 		uploadService: uploadService,
 		repo:          repo,
 		uploadDir:     uploadDir,
+		orderRegistry: order_extractors.NewRegistryWithAllExtractors(),
 	}
 	// Default client factory uses real Gmail API
 	s.clientFactory = func(ctx context.Context, token *oauth2.Token) (GmailAPIClient, error) {
@@ -140,8 +182,18 @@ func (s *GmailScanService) TriggerScan(userID uint) (*ScanResult, error) {
 		return nil, errors.NewInternalError("Failed to get scan settings", err)
 	}
 
+	// Get per-sender routing rules
+	senderRules, err := s.repo.ListSenderRules(userID)
+	if err != nil {
+		log.WithFields(logger.Fields{
+			"user_id": userID,
+			"error":   err.Error(),
+		}).Warn("Failed to load sender rules, scanning without them")
+		senderRules = nil
+	}
+
 	// Build Gmail query
-	query := s.buildQuery(rule)
+	query := s.buildQuery(rule, senderRules)
 
 	log.WithFields(logger.Fields{
 		"user_id": userID,
@@ -157,6 +209,16 @@ func (s *GmailScanService) TriggerScan(userID uint) (*ScanResult, error) {
 		return nil, errors.NewInternalError("Failed to create Gmail client", err)
 	}
 
+	// Label processed messages only when enabled and the grant allows it
+	var labeler *messageLabeler
+	if rule.ApplyLabels {
+		if s.gmailService.HasModifyScope(userID) {
+			labeler = newMessageLabeler(client, rule)
+		} else {
+			log.WithField("user_id", userID).Warn("Labeling enabled but Gmail modify scope not granted, skipping labels")
+		}
+	}
+
 	// Search for matching emails
 	messages, err := client.ListMessages(query, 50)
 	if err != nil {
@@ -170,6 +232,8 @@ func (s *GmailScanService) TriggerScan(userID uint) (*ScanResult, error) {
 	autoParsed := 0
 	totalImported := 0
 	failed := 0
+	ordersFound := 0
+	labeled := 0
 	var parseResults []UploadResult
 
 	log.WithFields(logger.Fields{
@@ -189,6 +253,22 @@ func (s *GmailScanService) TriggerScan(userID uint) (*ScanResult, error) {
 			continue
 		}
 
+		from := getHeader(fullMsg, "From")
+		senderRule := matchSenderRule(senderRules, from)
+
+		// Shopping order emails carry the transaction in the body, not a PDF
+		if rule.ScanOrders && senderRule == nil {
+			if s.orderRegistry.Find(from) != nil {
+				found, imported, importErr := s.processOrderEmail(client, userID, fullMsg, from)
+				ordersFound += found
+				totalImported += imported
+				if labeler.apply(msg.Id, importErr == nil, nil) {
+					labeled++
+				}
+				continue
+			}
+		}
+
 		// Extract PDF attachments
 		pdfPaths, err := s.downloadPDFAttachments(client, userID, fullMsg)
 		if err != nil {
@@ -197,15 +277,26 @@ func (s *GmailScanService) TriggerScan(userID uint) (*ScanResult, error) {
 				"message_id": msg.Id,
 				"error":      err.Error(),
 			}).Warn("Failed to download attachments, skipping")
+			if labeler.apply(msg.Id, false, senderRule) {
+				labeled++
+			}
 			continue
 		}
 
 		downloaded += len(pdfPaths)
+		msgParsed, msgFailed := 0, 0
 
 		// Parse downloaded PDFs using existing pipeline
 		if s.uploadService != nil {
 			for _, pdfPath := range pdfPaths {
-				result, err := s.uploadService.ParsePDF(userID, pdfPath)
+				var opts ParseOptions
+				if senderRule != nil {
+					opts = ParseOptions{
+						BankHint:         senderRule.BankHint,
+						PasswordPriority: senderRule.PasswordPriority,
+					}
+				}
+				result, err := s.uploadService.ParsePDFWithOptions(userID, pdfPath, opts)
 				if err != nil {
 					log.WithFields(logger.Fields{
 						"user_id":  userID,
@@ -217,10 +308,12 @@ func (s *GmailScanService) TriggerScan(userID uint) (*ScanResult, error) {
 						Error:    err.Error(),
 					})
 					failed++
+					msgFailed++
 					continue
 				}
 				if result.Error != "" {
 					failed++
+					msgFailed++
 				} else {
 					// Auto-import parsed transactions into database
 					imported, importErr := s.uploadService.ImportTransactions(userID, result.Transactions)
@@ -241,10 +334,18 @@ func (s *GmailScanService) TriggerScan(userID uint) (*ScanResult, error) {
 						}
 					}
 					autoParsed++
+					msgParsed++
 				}
 				parseResults = append(parseResults, *result)
 			}
 		}
+
+		// A message is imported only when every statement PDF parsed
+		if msgParsed > 0 || msgFailed > 0 {
+			if labeler.apply(msg.Id, msgFailed == 0, senderRule) {
+				labeled++
+			}
+		}
 	}
 
 	// Update last scan time
@@ -254,7 +355,7 @@ func (s *GmailScanService) TriggerScan(userID uint) (*ScanResult, error) {
 	// Record scan history
 	status := "completed"
 	errMsg := ""
-	if downloaded == 0 && scanned > 0 {
+	if downloaded == 0 && ordersFound == 0 && scanned > 0 {
 		status = "no_pdfs"
 		errMsg = "Emails found but no PDF attachments"
 	}
@@ -267,6 +368,8 @@ func (s *GmailScanService) TriggerScan(userID uint) (*ScanResult, error) {
 		"auto_parsed":        autoParsed,
 		"total_imported":     totalImported,
 		"failed":             failed,
+		"orders_found":       ordersFound,
+		"labeled":            labeled,
 	}).Info("Gmail scan completed")
 
 	return &ScanResult{
@@ -275,6 +378,8 @@ func (s *GmailScanService) TriggerScan(userID uint) (*ScanResult, error) {
 		AutoParsed:   autoParsed,
 		Imported:     totalImported,
 		Failed:       failed,
+		OrdersFound:  ordersFound,
+		Labeled:      labeled,
 		ParseResults: parseResults,
 		Status:       status,
 		ErrorMessage: errMsg,
@@ -291,7 +396,7 @@ func (s *GmailScanService) GetScanHistory(userID uint, limit int) ([]models.Gmai
 
 // --- Internal helpers ---
 
-func (s *GmailScanService) buildQuery(rule *models.GmailScanRule) string {
+func (s *GmailScanService) buildQuery(rule *models.GmailScanRule, senderRules []models.GmailSenderRule) string {
 	var parts []string
 
 	// Combine sender and subject keywords into one OR group
@@ -303,13 +408,38 @@ func (s *GmailScanService) buildQuery(rule *models.GmailScanRule) string {
 	for _, kw := range rule.SubjectKeywords {
 		orParts = append(orParts, fmt.Sprintf("subject:%s", kw))
 	}
+	for _, sr := range senderRules {
+		if sr.Enabled {
+			orParts = append(orParts, fmt.Sprintf("from:%s", sr.Sender))
+		}
+	}
+	var statementParts []string
 	if len(orParts) > 0 {
-		parts = append(parts, "{"+strings.Join(orParts, " ")+"}")
+		statementParts = append(statementParts, "{"+strings.Join(orParts, " ")+"}")
 	}
 
 	// Require attachment
 	if rule.RequireAttachment {
-		parts = append(parts, "has:attachment")
+		statementParts = append(statementParts, "has:attachment")
+	}
+
+	// Order emails have no attachment, so OR the known merchant senders
+	// with the statement criteria: {(statement criteria) from:shop1 from:shop2}
+	var orderParts []string
+	if rule.ScanOrders && s.orderRegistry != nil {
+		senders := s.orderRegistry.Senders()
+		sort.Strings(senders)
+		for _, sender := range senders {
+			orderParts = append(orderParts, fmt.Sprintf("from:%s", sender))
+		}
+	}
+
+	if len(orderParts) > 0 && len(statementParts) > 0 {
+		parts = append(parts, "{("+strings.Join(statementParts, " ")+") "+strings.Join(orderParts, " ")+"}")
+	} else if len(orderParts) > 0 {
+		parts = append(parts, "{"+strings.Join(orderParts, " ")+"}")
+	} else {
+		parts = append(parts, statementParts...)
 	}
 
 	// Time range: use last scan time, or default to 6 months back
@@ -370,6 +500,228 @@ func (s *GmailScanService) downloadPDFAttachments(client GmailAPIClient, userID
 	return pdfPaths, nil
 }
 
+// processOrderEmail extracts orders from a shopping email body and imports them.
+// Returns the number of orders found and transactions imported, and an error
+// if the body could not be read, had no orders or failed to import.
+func (s *GmailScanService) processOrderEmail(client GmailAPIClient, userID uint, msg *gmail.Message, from string) (int, int, error) {
+	log := logger.ServiceLog("GmailScanService", "processOrderEmail")
+
+	body, err := getMessageBody(client, msg)
+	if err != nil {
+		log.WithFields(logger.Fields{
+			"user_id":    userID,
+			"message_id": msg.Id,
+			"error":      err.Error(),
+		}).Warn("Failed to read order email body, skipping")
+		return 0, 0, err
+	}
+
+	orders, merchant, err := s.orderRegistry.Extract(from, body)
+	if err == nil && len(orders) == 0 {
+		err = fmt.Errorf("no orders found")
+	}
+	if err != nil {
+		log.WithFields(logger.Fields{
+			"user_id":    userID,
+			"message_id": msg.Id,
+			"merchant":   merchant,
+		}).Debug("No orders extracted from email")
+		return 0, 0, err
+	}
+
+	// Fall back to the email date when the body has no order date
+	if msg.InternalDate > 0 {
+		for i := range orders {
+			if orders[i].Date.IsZero() {
+				orders[i].Date = time.UnixMilli(msg.InternalDate)
+			}
+		}
+	}
+
+	if s.uploadService == nil {
+		return len(orders), 0, nil
+	}
+
+	imported, err := s.uploadService.ImportTransactions(userID, s.uploadService.BuildOrderTransactions(userID, orders))
+	if err != nil {
+		log.WithFields(logger.Fields{
+			"user_id":    userID,
+			"message_id": msg.Id,
+			"error":      err.Error(),
+		}).Warn("Failed to import order transactions")
+		return len(orders), 0, err
+	}
+	if imported > 0 {
+		log.WithFields(logger.Fields{
+			"user_id":  userID,
+			"merchant": merchant,
+			"imported": imported,
+		}).Info("Auto-imported transactions from order email")
+	}
+	return len(orders), imported, nil
+}
+
+// messageLabeler applies the configured labels to processed messages,
+// caching label IDs for the duration of a scan
+type messageLabeler struct {
+	client   GmailAPIClient
+	rule     *models.GmailScanRule
+	labelIDs map[string]string
+}
+
+func newMessageLabeler(client GmailAPIClient, rule *models.GmailScanRule) *messageLabeler {
+	return &messageLabeler{
+		client:   client,
+		rule:     rule,
+		labelIDs: make(map[string]string),
+	}
+}
+
+// apply labels a message as imported or failed, adding the sender rule label
+// on success and archiving if configured. A nil labeler is a no-op.
+func (l *messageLabeler) apply(messageID string, success bool, senderRule *models.GmailSenderRule) bool {
+	if l == nil {
+		return false
+	}
+
+	var names []string
+	var remove []string
+	if success {
+		names = append(names, l.rule.ImportedLabel)
+		if senderRule != nil && senderRule.Label != "" {
+			names = append(names, senderRule.Label)
+		}
+		if l.rule.ArchiveProcessed {
+			remove = append(remove, "INBOX")
+		}
+	} else {
+		names = append(names, l.rule.FailedLabel)
+	}
+
+	var add []string
+	for _, name := range names {
+		if name == "" {
+			continue
+		}
+		id, err := l.labelID(name)
+		if err != nil {
+			logger.WithError(err).WithField("label", name).Warn("Failed to resolve Gmail label")
+			continue
+		}
+		add = append(add, id)
+	}
+	if len(add) == 0 && len(remove) == 0 {
+		return false
+	}
+
+	if err := l.client.ModifyLabels(messageID, add, remove); err != nil {
+		logger.WithError(err).WithField("message_id", messageID).Warn("Failed to label Gmail message")
+		return false
+	}
+	return true
+}
+
+func (l *messageLabeler) labelID(name string) (string, error) {
+	if id, ok := l.labelIDs[name]; ok {
+		return id, nil
+	}
+	id, err := l.client.EnsureLabel(name)
+	if err != nil {
+		return "", err
+	}
+	l.labelIDs[name] = id
+	return id, nil
+}
+
+// matchSenderRule returns the first enabled sender rule matching the From header, or nil.
+// A rule's sender matches the exact address, or the address's domain or any
+// subdomain of it, so "bank.com" does not match "evilbank.com".
+func matchSenderRule(rules []models.GmailSenderRule, from string) *models.GmailSenderRule {
+	parsed, err := mail.ParseAddress(from)
+	if err != nil {
+		return nil
+	}
+	address := strings.ToLower(parsed.Address)
+	at := strings.LastIndex(address, "@")
+	if at < 0 {
+		return nil
+	}
+	domain := address[at+1:]
+	for i := range rules {
+		sender := strings.ToLower(strings.TrimSpace(rules[i].Sender))
+		if !rules[i].Enabled || sender == "" {
+			continue
+		}
+		if strings.Contains(sender, "@") {
+			if address == sender {
+				return &rules[i]
+			}
+			continue
+		}
+		if domain == sender || strings.HasSuffix(domain, "."+sender) {
+			return &rules[i]
+		}
+	}
+	return nil
+}
+
+// getHeader returns the value of a top-level message header
+func getHeader(msg *gmail.Message, name string) string {
+	if msg.Payload == nil {
+		return ""
+	}
+	for _, h := range msg.Payload.Headers {
+		if strings.EqualFold(h.Name, name) {
+			return h.Value
+		}
+	}
+	return ""
+}
+
+// getMessageBody returns the HTML body of a message, falling back to plain text
+func getMessageBody(client GmailAPIClient, msg *gmail.Message) (string, error) {
+	var htmlPart, textPart *gmail.MessagePart
+	for _, part := range getAllParts(msg.Payload) {
+		if part.Filename != "" || part.Body == nil {
+			continue
+		}
+		switch {
+		case strings.HasPrefix(part.MimeType, "text/html") && htmlPart == nil:
+			htmlPart = part
+		case strings.HasPrefix(part.MimeType, "text/plain") && textPart == nil:
+			textPart = part
+		}
+	}
+
+	part := htmlPart
+	if part == nil {
+		part = textPart
+	}
+	if part == nil {
+		return "", fmt.Errorf("no text body found")
+	}
+
+	if part.Body.Data != "" {
+		data, err := base64.URLEncoding.DecodeString(part.Body.Data)
+		if err != nil {
+			// Gmail sometimes omits padding
+			data, err = base64.RawURLEncoding.DecodeString(part.Body.Data)
+			if err != nil {
+				return "", fmt.Errorf("failed to decode body: %w", err)
+			}
+		}
+		return string(data), nil
+	}
+	if part.Body.AttachmentId != "" {
+		data, err := client.GetAttachment(msg.Id, part.Body.AttachmentId)
+		if err != nil {
+			return "", fmt.Errorf("failed to download body: %w", err)
+		}
+		return string(data), nil
+	}
+	return "", fmt.Errorf("empty body")
+}
+
 func getAllParts(part *gmail.MessagePart) []*gmail.MessagePart {
 	var parts []*gmail.MessagePart
 	if part == nil {
@@ -1,15 +1,21 @@
 package services
 
 import (
+	"billing-note/internal/email"
 	"billing-note/internal/models"
+	"billing-note/internal/pdf"
 	"context"
+	"encoding/base64"
 	"testing"
 	"time"
 
+	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 	"golang.org/x/oauth2"
 	gmail "google.golang.org/api/gmail/v1"
+	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 )
 
@@ -43,6 +49,16 @@ func (m *mockGmailAPIClient) GetAttachment(messageID, attachmentID string) ([]by
 	return args.Get(0).([]byte), args.Error(1)
 }
 
+func (m *mockGmailAPIClient) EnsureLabel(name string) (string, error) {
+	args := m.Called(name)
+	return args.String(0), args.Error(1)
+}
+
+func (m *mockGmailAPIClient) ModifyLabels(messageID string, addLabelIDs, removeLabelIDs []string) error {
+	args := m.Called(messageID, addLabelIDs, removeLabelIDs)
+	return args.Error(0)
+}
+
 // --- Tests ---
 
 func newTestScanService(t *testing.T, repo *mockGmailRepo, client *mockGmailAPIClient) (*GmailScanService, *GmailService) {
@@ -69,7 +85,7 @@ func TestBuildQuery_Default(t *testing.T) {
 		RequireAttachment: true,
 	}
 
-	query := scanSvc.buildQuery(rule)
+	query := scanSvc.buildQuery(rule, nil)
 	assert.Contains(t, query, "from:credit")
 	assert.Contains(t, query, "from:信用卡")
 	assert.Contains(t, query, "subject:帳單")
@@ -89,7 +105,7 @@ func TestBuildQuery_WithLastScan(t *testing.T) {
 		LastScanAt:        &lastScan,
 	}
 
-	query := scanSvc.buildQuery(rule)
+	query := scanSvc.buildQuery(rule, nil)
 	assert.Contains(t, query, "after:2026/03/01")
 }
 
@@ -102,10 +118,61 @@ func TestBuildQuery_NoAttachmentRequired(t *testing.T) {
 		RequireAttachment: false,
 	}
 
-	query := scanSvc.buildQuery(rule)
+	query := scanSvc.buildQuery(rule, nil)
 	assert.NotContains(t, query, "has:attachment")
 }
 
+func TestBuildQuery_WithSenderRules(t *testing.T) {
+	repo := new(mockGmailRepo)
+	scanSvc, _ := newTestScanService(t, repo, nil)
+
+	rule := &models.GmailScanRule{
+		SenderKeywords:    []string{"credit"},
+		RequireAttachment: true,
+	}
+	senderRules := []models.GmailSenderRule{
+		{Sender: "cathaybk.com.tw", Enabled: true},
+		{Sender: "disabled.com.tw", Enabled: false},
+	}
+
+	query := scanSvc.buildQuery(rule, senderRules)
+	assert.Contains(t, query, "from:cathaybk.com.tw")
+	assert.NotContains(t, query, "from:disabled.com.tw")
+}
+
+func TestMatchSenderRule(t *testing.T) {
+	rules := []models.GmailSenderRule{
+		{ID: 1, Sender: "disabled.com.tw", Enabled: false},
+		{ID: 2, Sender: "cathaybk.com.tw", Enabled: true, BankHint: "國泰世華", PasswordPriority: 2},
+	}
+
+	matched := matchSenderRule(rules, "國泰世華銀行 <service@pxbillrc01.CathayBK.com.tw>")
+	assert.NotNil(t, matched)
+	assert.Equal(t, uint(2), matched.ID)
+	assert.Equal(t, 2, matched.PasswordPriority)
+
+	assert.Nil(t, matchSenderRule(rules, "notice@disabled.com.tw"))
+	assert.Nil(t, matchSenderRule(rules, "someone@example.com"))
+	assert.Nil(t, matchSenderRule(rules, ""))
+}
+
+func TestMatchSenderRule_OnlyMatchesSenderDomainOrAddress(t *testing.T) {
+	rules := []models.GmailSenderRule{
+		{ID: 1, Sender: "bank.com", Enabled: true},
+		{ID: 2, Sender: "billing@shop.com", Enabled: true},
+	}
+
+	assert.NotNil(t, matchSenderRule(rules, "Bank <notice@bank.com>"))
+	assert.NotNil(t, matchSenderRule(rules, "notice@mail.bank.com"))
+	assert.NotNil(t, matchSenderRule(rules, "Shop <Billing@Shop.com>"))
+
+	// Lookalike domains and other addresses are not the bank
+	assert.Nil(t, matchSenderRule(rules, "notice@evilbank.com"))
+	assert.Nil(t, matchSenderRule(rules, "notice@bank.com.attacker.io"))
+	assert.Nil(t, matchSenderRule(rules, "\"notice@bank.com\" <phish@attacker.io>"))
+	assert.Nil(t, matchSenderRule(rules, "promo@shop.com"))
+}
+
 func TestTriggerScan_NotConnected(t *testing.T) {
 	repo := new(mockGmailRepo)
 	repo.On("GetToken", uint(1)).Return(nil, gorm.ErrRecordNotFound)
@@ -136,6 +203,7 @@ func TestTriggerScan_NoEmails(t *testing.T) {
 	}
 	repo.On("GetToken", uint(1)).Return(token, nil)
 	repo.On("GetScanRule", uint(1)).Return(nil, gorm.ErrRecordNotFound)
+	repo.On("ListSenderRules", uint(1)).Return([]models.GmailSenderRule{}, nil)
 	repo.On("SaveScanRule", mock.Anything).Return(nil)
 	repo.On("CreateScanHistory", mock.Anything).Return(nil)
 
@@ -167,6 +235,7 @@ func TestTriggerScan_WithPDFAttachment(t *testing.T) {
 	}
 	repo.On("GetToken", uint(1)).Return(token, nil)
 	repo.On("GetScanRule", uint(1)).Return(nil, gorm.ErrRecordNotFound)
+	repo.On("ListSenderRules", uint(1)).Return([]models.GmailSenderRule{}, nil)
 	repo.On("SaveScanRule", mock.Anything).Return(nil)
 	repo.On("CreateScanHistory", mock.Anything).Return(nil)
 
@@ -218,6 +287,7 @@ func TestTriggerScan_SkipNonPDFAttachments(t *testing.T) {
 	}
 	repo.On("GetToken", uint(1)).Return(token, nil)
 	repo.On("GetScanRule", uint(1)).Return(nil, gorm.ErrRecordNotFound)
+	repo.On("ListSenderRules", uint(1)).Return([]models.GmailSenderRule{}, nil)
 	repo.On("SaveScanRule", mock.Anything).Return(nil)
 	repo.On("CreateScanHistory", mock.Anything).Return(nil)
 
@@ -251,6 +321,274 @@ func TestTriggerScan_SkipNonPDFAttachments(t *testing.T) {
 	client.AssertNotCalled(t, "GetAttachment", mock.Anything, mock.Anything)
 }
 
+func TestBuildQuery_WithOrderSenders(t *testing.T) {
+	repo := new(mockGmailRepo)
+	scanSvc, _ := newTestScanService(t, repo, nil)
+
+	rule := &models.GmailScanRule{
+		SenderKeywords:    []string{"credit"},
+		RequireAttachment: true,
+		ScanOrders:        true,
+	}
+
+	query := scanSvc.buildQuery(rule, nil)
+	assert.Contains(t, query, "({from:credit} has:attachment)")
+	assert.Contains(t, query, "from:shopee.tw")
+	assert.Contains(t, query, "from:momoshop.com.tw")
+}
+
+func TestTriggerScan_OrderEmail(t *testing.T) {
+	repo := new(mockGmailRepo)
+	client := new(mockGmailAPIClient)
+	scanSvc, gmailSvc := newTestScanService(t, repo, client)
+
+	accessEnc, _ := gmailSvc.crypto.Encrypt("test-access-token")
+	refreshEnc, _ := gmailSvc.crypto.Encrypt("test-refresh-token")
+	futureExpiry := time.Now().Add(1 * time.Hour)
+
+	token := &models.GmailToken{
+		UserID:                1,
+		AccessTokenEncrypted:  accessEnc,
+		RefreshTokenEncrypted: refreshEnc,
+		TokenExpiry:           &futureExpiry,
+	}
+	repo.On("GetToken", uint(1)).Return(token, nil)
+	repo.On("GetScanRule", uint(1)).Return(nil, gorm.ErrRecordNotFound)
+	repo.On("ListSenderRules", uint(1)).Return([]models.GmailSenderRule{}, nil)
+	repo.On("SaveScanRule", mock.Anything).Return(nil)
+	repo.On("CreateScanHistory", mock.Anything).Return(nil)
+
+	messages := []*gmail.Message{{Id: "msg1"}}
+	client.On("ListMessages", mock.Anything, int64(50)).Return(messages, nil)
+
+	body := "<p>訂單編號：240101ABCDEFGH</p><p>訂單成立時間：2024-01-01 12:34:56</p>" +
+		"<table><tr><td>手機殼</td><td>x2</td><td>$300</td></tr></table><p>訂單金額：$360</p>"
+	fullMsg := &gmail.Message{
+		Id: "msg1",
+		Payload: &gmail.MessagePart{
+			Headers: []*gmail.MessagePartHeader{
+				{Name: "From", Value: "蝦皮購物 <info@mail.shopee.tw>"},
+			},
+			Parts: []*gmail.MessagePart{
+				{
+					MimeType: "text/html; charset=UTF-8",
+					Body: &gmail.MessagePartBody{
+						Data: base64.URLEncoding.EncodeToString([]byte(body)),
+					},
+				},
+			},
+		},
+	}
+	client.On("GetMessage", "msg1").Return(fullMsg, nil)
+
+	db, sqlMock := setupUploadMockDB(t)
+	scanSvc.uploadService = NewUploadService(db, nil, t.TempDir())
+	// Neither an earlier order email nor a statement line has the order
+	sqlMock.ExpectQuery(`SELECT count\(\*\) FROM "transactions" WHERE user_id = \$1 AND order_number = \$2`).
+		WithArgs(uint(1), "240101ABCDEFGH").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	sqlMock.ExpectQuery(`SELECT count\(\*\) FROM "transactions" WHERE .*source <> \$2`).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	sqlMock.ExpectBegin()
+	sqlMock.ExpectQuery(`INSERT INTO "transactions"`).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	sqlMock.ExpectCommit()
+
+	result, err := scanSvc.TriggerScan(1)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, result.Scanned)
+	assert.Equal(t, 0, result.Downloaded)
+	assert.Equal(t, 1, result.OrdersFound)
+	assert.Equal(t, 1, result.Imported)
+	assert.Equal(t, "completed", result.Status)
+
+	client.AssertNotCalled(t, "GetAttachment", mock.Anything, mock.Anything)
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
+
+func setupUploadMockDB(t *testing.T) (*gorm.DB, sqlmock.Sqlmock) {
+	db, sqlMock, err := sqlmock.New()
+	require.NoError(t, err)
+	gormDB, err := gorm.Open(postgres.New(postgres.Config{Conn: db, DriverName: "postgres"}), &gorm.Config{})
+	require.NoError(t, err)
+	return gormDB, sqlMock
+}
+
+func TestBuildOrderTransactions_MatchesStatementLine(t *testing.T) {
+	db, sqlMock := setupUploadMockDB(t)
+	uploadSvc := NewUploadService(db, nil, t.TempDir())
+
+	order := email.Order{
+		OrderNumber: "3F2A1B",
+		Merchant:    "Uber Eats 優食 麥當勞",
+		Date:        time.Date(2026, 3, 1, 19, 30, 0, 0, time.UTC),
+		Total:       350,
+	}
+	day := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	sqlMock.ExpectQuery(`order_number = \$2`).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	// The card statement already has the charge
+	sqlMock.ExpectQuery(`source <> \$2 AND type = \$3 AND amount = \$4 AND transaction_date BETWEEN \$5 AND \$6\) AND LOWER\(description\) LIKE \$7`).
+		WithArgs(uint(1), "gmail_order", "expense", 350.0, day, day.AddDate(0, 0, orderPostingDays), "%uber%").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+
+	parsed := uploadSvc.BuildOrderTransactions(1, []email.Order{order})
+
+	require.Len(t, parsed, 1)
+	assert.True(t, parsed[0].IsDuplicate)
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
+
+func setupLabelScan(t *testing.T, scopes string, archive bool) (*GmailScanService, *mockGmailRepo, *mockGmailAPIClient) {
+	repo := new(mockGmailRepo)
+	client := new(mockGmailAPIClient)
+	scanSvc, gmailSvc := newTestScanService(t, repo, client)
+
+	accessEnc, _ := gmailSvc.crypto.Encrypt("test-access-token")
+	refreshEnc, _ := gmailSvc.crypto.Encrypt("test-refresh-token")
+	futureExpiry := time.Now().Add(1 * time.Hour)
+
+	token := &models.GmailToken{
+		UserID:                1,
+		AccessTokenEncrypted:  accessEnc,
+		RefreshTokenEncrypted: refreshEnc,
+		TokenExpiry:           &futureExpiry,
+		Scopes:                scopes,
+	}
+	rule := &models.GmailScanRule{
+		UserID:            1,
+		SenderKeywords:    []string{"credit"},
+		RequireAttachment: true,
+		ScanOrders:        true,
+		ApplyLabels:       true,
+		ImportedLabel:     DefaultImportedLabel,
+		FailedLabel:       DefaultFailedLabel,
+		ArchiveProcessed:  archive,
+	}
+	repo.On("GetToken", uint(1)).Return(token, nil)
+	repo.On("GetScanRule", uint(1)).Return(rule, nil)
+	repo.On("ListSenderRules", uint(1)).Return([]models.GmailSenderRule{}, nil)
+	repo.On("SaveScanRule", mock.Anything).Return(nil)
+	repo.On("CreateScanHistory", mock.Anything).Return(nil)
+
+	return scanSvc, repo, client
+}
+
+func orderEmailMessage(id string) *gmail.Message {
+	body := "<p>訂單編號：240101ABCDEFGH</p><p>訂單成立時間：2024-01-01 12:34:56</p>" +
+		"<table><tr><td>手機殼</td><td>x2</td><td>$300</td></tr></table><p>訂單金額：$360</p>"
+	return &gmail.Message{
+		Id: id,
+		Payload: &gmail.MessagePart{
+			Headers: []*gmail.MessagePartHeader{
+				{Name: "From", Value: "蝦皮購物 <info@mail.shopee.tw>"},
+			},
+			Parts: []*gmail.MessagePart{
+				{
+					MimeType: "text/html",
+					Body:     &gmail.MessagePartBody{Data: base64.URLEncoding.EncodeToString([]byte(body))},
+				},
+			},
+		},
+	}
+}
+
+func TestTriggerScan_LabelsImportedAndArchives(t *testing.T) {
+	scanSvc, _, client := setupLabelScan(t, "https://www.googleapis.com/auth/gmail.modify", true)
+
+	client.On("ListMessages", mock.Anything, int64(50)).Return([]*gmail.Message{{Id: "msg1"}}, nil)
+	client.On("GetMessage", "msg1").Return(orderEmailMessage("msg1"), nil)
+	client.On("EnsureLabel", DefaultImportedLabel).Return("Label_1", nil).Once()
+	client.On("ModifyLabels", "msg1", []string{"Label_1"}, []string{"INBOX"}).Return(nil)
+
+	result, err := scanSvc.TriggerScan(1)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, result.OrdersFound)
+	assert.Equal(t, 1, result.Labeled)
+	client.AssertExpectations(t)
+}
+
+func TestTriggerScan_LabelsFailedDownload(t *testing.T) {
+	scanSvc, _, client := setupLabelScan(t, "https://www.googleapis.com/auth/gmail.modify", true)
+
+	client.On("ListMessages", mock.Anything, int64(50)).Return([]*gmail.Message{{Id: "msg1"}}, nil)
+	client.On("GetMessage", "msg1").Return(&gmail.Message{
+		Id: "msg1",
+		Payload: &gmail.MessagePart{
+			Parts: []*gmail.MessagePart{
+				{Filename: "statement.pdf", Body: &gmail.MessagePartBody{AttachmentId: "att1"}},
+			},
+		},
+	}, nil)
+	client.On("GetAttachment", "msg1", "att1").Return(nil, assert.AnError)
+	client.On("EnsureLabel", DefaultFailedLabel).Return("Label_2", nil)
+	// Failed messages stay in the inbox
+	client.On("ModifyLabels", "msg1", []string{"Label_2"}, []string(nil)).Return(nil)
+
+	result, err := scanSvc.TriggerScan(1)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, result.Labeled)
+	client.AssertExpectations(t)
+}
+
+func TestTriggerScan_LabelsFailedOrderEmail(t *testing.T) {
+	scanSvc, _, client := setupLabelScan(t, "https://www.googleapis.com/auth/gmail.modify", true)
+
+	msg := orderEmailMessage("msg1")
+	msg.Payload.Parts[0].Body.Data = base64.URLEncoding.EncodeToString([]byte("<p>Your order has shipped</p>"))
+	client.On("ListMessages", mock.Anything, int64(50)).Return([]*gmail.Message{{Id: "msg1"}}, nil)
+	client.On("GetMessage", "msg1").Return(msg, nil)
+	client.On("EnsureLabel", DefaultFailedLabel).Return("Label_2", nil)
+	client.On("ModifyLabels", "msg1", []string{"Label_2"}, []string(nil)).Return(nil)
+
+	result, err := scanSvc.TriggerScan(1)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, result.OrdersFound)
+	assert.Equal(t, 1, result.Labeled)
+	client.AssertExpectations(t)
+}
+
+func TestTriggerScan_LabelsFailedOrderImport(t *testing.T) {
+	scanSvc, _, client := setupLabelScan(t, "https://www.googleapis.com/auth/gmail.modify", true)
+
+	client.On("ListMessages", mock.Anything, int64(50)).Return([]*gmail.Message{{Id: "msg1"}}, nil)
+	client.On("GetMessage", "msg1").Return(orderEmailMessage("msg1"), nil)
+	client.On("EnsureLabel", DefaultFailedLabel).Return("Label_2", nil)
+	client.On("ModifyLabels", "msg1", []string{"Label_2"}, []string(nil)).Return(nil)
+
+	db, sqlMock := setupUploadMockDB(t)
+	scanSvc.uploadService = NewUploadService(db, nil, t.TempDir())
+	sqlMock.ExpectQuery(`SELECT count\(\*\) FROM "transactions"`).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	sqlMock.ExpectQuery(`SELECT count\(\*\) FROM "transactions"`).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	sqlMock.ExpectBegin()
+	sqlMock.ExpectQuery(`INSERT INTO "transactions"`).WillReturnError(assert.AnError)
+	sqlMock.ExpectRollback()
+
+	result, err := scanSvc.TriggerScan(1)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, result.OrdersFound)
+	assert.Equal(t, 0, result.Imported)
+	assert.Equal(t, 1, result.Labeled)
+	client.AssertExpectations(t)
+	client.AssertNotCalled(t, "EnsureLabel", DefaultImportedLabel)
+}
+
+func TestTriggerScan_LabelsSkippedWithoutModifyScope(t *testing.T) {
+	scanSvc, _, client := setupLabelScan(t, "https://www.googleapis.com/auth/gmail.readonly", false)
+
+	client.On("ListMessages", mock.Anything, int64(50)).Return([]*gmail.Message{{Id: "msg1"}}, nil)
+	client.On("GetMessage", "msg1").Return(orderEmailMessage("msg1"), nil)
+
+	result, err := scanSvc.TriggerScan(1)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, result.OrdersFound)
+	assert.Equal(t, 0, result.Labeled)
+	client.AssertNotCalled(t, "EnsureLabel", mock.Anything)
+	client.AssertNotCalled(t, "ModifyLabels", mock.Anything, mock.Anything, mock.Anything)
+}
+
 func TestGetScanHistory(t *testing.T) {
 	repo := new(mockGmailRepo)
 	scanSvc, _ := newTestScanService(t, repo, nil)
@@ -308,3 +646,19 @@ func TestGetAllParts_Nested(t *testing.T) {
 	}
 	assert.True(t, pdfFound)
 }
+
+func TestCheckDuplicate_MatchesOrderEmail(t *testing.T) {
+	db, sqlMock := setupUploadMockDB(t)
+	uploadSvc := NewUploadService(db, nil, t.TempDir())
+
+	line := pdf.Transaction{Date: time.Date(2026, 3, 3, 0, 0, 0, 0, time.UTC), Description: "UBER *EATS TAIPEI", Amount: 350}
+	sqlMock.ExpectQuery(`description = \$4`).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	// The order email for the charge was imported first
+	sqlMock.ExpectQuery(`source = \$2 .* LIKE '%' \|\| LOWER\(split_part\(merchant, ' ', 1\)\) \|\| '%'`).
+		WithArgs(uint(1), "gmail_order", "expense", 350.0, line.Date.AddDate(0, 0, -orderPostingDays), line.Date, "uber *eats taipei").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+
+	assert.True(t, uploadSvc.checkDuplicate(1, line))
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
@@ -2,6 +2,7 @@ package services
 
 import (
 	"billing-note/internal/models"
+	"billing-note/internal/pdf/bank_parsers"
 	"billing-note/internal/repository"
 	"billing-note/pkg/crypto"
 	"billing-note/pkg/errors"
@@ -11,6 +12,7 @@ import (
 	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
+	"strings"
 	"time"
 
 	"golang.org/x/oauth2"
@@ -19,12 +21,19 @@ import (
 	"google.golang.org/api/option"
 )
 
+// Default labels applied to processed messages
+const (
+	DefaultImportedLabel = "BillingNote/Imported"
+	DefaultFailedLabel   = "BillingNote/Failed"
+)
+
 // GmailService handles Gmail OAuth and integration operations
 type GmailService struct {
 	repo        repository.GmailRepository
 	crypto      *crypto.AESCrypto
 	oauthConfig *oauth2.Config
 	stateSecret string
+	bankNames   []string
 }
 
 // NewGmailService creates a new Gmail service
@@ -59,6 +68,7 @@ func NewGmailService(
 		crypto:      aesCrypto,
 		oauthConfig: oauthConfig,
 		stateSecret: stateSecret,
+		bankNames:   bank_parsers.NewRegistryWithAllParsers().BankNames(),
 	}, nil
 }
 
@@ -68,7 +78,13 @@ func (s *GmailService) GetAuthURL(userID uint) (string, error) {
 
 	state := s.generateState(userID)
 
-	url := s.oauthConfig.AuthCodeURL(state,
+	// Labeling processed messages needs the modify scope; only ask for it when enabled
+	config := *s.oauthConfig
+	if rule, err := s.repo.GetScanRule(userID); err == nil && rule.ApplyLabels {
+		config.Scopes = append([]string{gmail.GmailModifyScope}, s.oauthConfig.Scopes...)
+	}
+
+	url := config.AuthCodeURL(state,
 		oauth2.AccessTypeOffline,
 		oauth2.SetAuthURLParam("prompt", "consent"),
 	)
@@ -110,13 +126,19 @@ func (s *GmailService) HandleCallback(userID uint, code, state string) error {
 		return errors.NewEncryptionError("refresh token encryption", err)
 	}
 
+	// Record the scopes actually granted (modify is optional)
+	scopes := gmail.GmailReadonlyScope
+	if granted, ok := token.Extra("scope").(string); ok && granted != "" {
+		scopes = granted
+	}
+
 	// Store tokens
 	gmailToken := &models.GmailToken{
 		UserID:                userID,
 		AccessTokenEncrypted:  accessTokenEnc,
 		RefreshTokenEncrypted: refreshTokenEnc,
 		TokenExpiry:           &token.Expiry,
-		Scopes:                gmail.GmailReadonlyScope,
+		Scopes:                scopes,
 	}
 
 	if err := s.repo.SaveToken(gmailToken); err != nil {
@@ -130,13 +152,7 @@ func (s *GmailService) HandleCallback(userID uint, code, state string) error {
 	// Create default scan rules if not exist
 	_, err = s.repo.GetScanRule(userID)
 	if err != nil {
-		defaultRule := &models.GmailScanRule{
-			UserID:            userID,
-			Enabled:           false,
-			SenderKeywords:    []string{"credit", "信用卡", "帳單", "statement"},
-			SubjectKeywords:   []string{"帳單", "電子帳單", "statement"},
-			RequireAttachment: true,
-		}
+		defaultRule := defaultScanRule(userID)
 		if saveErr := s.repo.SaveScanRule(defaultRule); saveErr != nil {
 			log.WithError(saveErr).Warn("Failed to create default scan rules")
 		}
@@ -159,6 +175,7 @@ func (s *GmailService) GetStatus(userID uint) (*models.GmailStatusResponse, erro
 	status := &models.GmailStatusResponse{
 		Connected:   true,
 		Scopes:      token.Scopes,
+		CanModify:   hasModifyScope(token.Scopes),
 		ConnectedAt: &token.CreatedAt,
 	}
 
@@ -216,7 +233,8 @@ func (s *GmailService) UpdateSettings(userID uint, input models.GmailSettingsInp
 
 	rule, err := s.repo.GetScanRule(userID)
 	if err != nil {
-		rule = &models.GmailScanRule{UserID: userID}
+		// First save: start from the defaults shown by GetSettings
+		rule = defaultScanRule(userID)
 	}
 
 	if input.Enabled != nil {
@@ -231,6 +249,27 @@ func (s *GmailService) UpdateSettings(userID uint, input models.GmailSettingsInp
 	if input.RequireAttachment != nil {
 		rule.RequireAttachment = *input.RequireAttachment
 	}
+	if input.ScanOrders != nil {
+		rule.ScanOrders = *input.ScanOrders
+	}
+	if input.ApplyLabels != nil {
+		rule.ApplyLabels = *input.ApplyLabels
+	}
+	if input.ImportedLabel != nil {
+		rule.ImportedLabel = strings.TrimSpace(*input.ImportedLabel)
+	}
+	if input.FailedLabel != nil {
+		rule.FailedLabel = strings.TrimSpace(*input.FailedLabel)
+	}
+	if input.ArchiveProcessed != nil {
+		rule.ArchiveProcessed = *input.ArchiveProcessed
+	}
+	if rule.ImportedLabel == "" {
+		rule.ImportedLabel = DefaultImportedLabel
+	}
+	if rule.FailedLabel == "" {
+		rule.FailedLabel = DefaultFailedLabel
+	}
 
 	if err := s.repo.SaveScanRule(rule); err != nil {
 		log.WithFields(logger.Fields{
@@ -248,17 +287,94 @@ func (s *GmailService) UpdateSettings(userID uint, input models.GmailSettingsInp
 func (s *GmailService) GetSettings(userID uint) (*models.GmailScanRule, error) {
 	rule, err := s.repo.GetScanRule(userID)
 	if err != nil {
-		return &models.GmailScanRule{
-			UserID:            userID,
-			Enabled:           false,
-			SenderKeywords:    []string{"credit", "信用卡", "帳單", "statement"},
-			SubjectKeywords:   []string{"帳單", "電子帳單", "statement"},
-			RequireAttachment: true,
-		}, nil
+		return defaultScanRule(userID), nil
+	}
+	return rule, nil
+}
+
+// HasModifyScope reports whether the user's Gmail grant allows labeling messages
+func (s *GmailService) HasModifyScope(userID uint) bool {
+	token, err := s.repo.GetToken(userID)
+	if err != nil {
+		return false
+	}
+	return hasModifyScope(token.Scopes)
+}
+
+// ListSenderRules returns the per-sender routing rules for a user
+func (s *GmailService) ListSenderRules(userID uint) ([]models.GmailSenderRule, error) {
+	rules, err := s.repo.ListSenderRules(userID)
+	if err != nil {
+		return nil, errors.NewDBError("list Gmail sender rules", err)
+	}
+	return rules, nil
+}
+
+// CreateSenderRule creates a per-sender routing rule
+func (s *GmailService) CreateSenderRule(userID uint, input models.GmailSenderRuleInput) (*models.GmailSenderRule, error) {
+	log := logger.ServiceLog("GmailService", "CreateSenderRule")
+
+	rule := &models.GmailSenderRule{UserID: userID, Enabled: true}
+	if err := s.applySenderRuleInput(rule, input); err != nil {
+		return nil, err
 	}
+
+	if err := s.repo.SaveSenderRule(rule); err != nil {
+		log.WithFields(logger.Fields{
+			"user_id": userID,
+			"error":   err.Error(),
+		}).Error("Failed to create Gmail sender rule")
+		return nil, errors.NewDBError("create Gmail sender rule", err)
+	}
+
+	log.WithFields(logger.Fields{
+		"user_id": userID,
+		"rule_id": rule.ID,
+		"sender":  rule.Sender,
+	}).Info("Gmail sender rule created")
 	return rule, nil
 }
 
+// UpdateSenderRule updates a per-sender routing rule
+func (s *GmailService) UpdateSenderRule(userID, id uint, input models.GmailSenderRuleInput) (*models.GmailSenderRule, error) {
+	log := logger.ServiceLog("GmailService", "UpdateSenderRule")
+
+	rule, err := s.repo.GetSenderRule(userID, id)
+	if err != nil {
+		return nil, errors.NewNotFoundError("Gmail sender rule", id)
+	}
+	if err := s.applySenderRuleInput(rule, input); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.SaveSenderRule(rule); err != nil {
+		log.WithFields(logger.Fields{
+			"user_id": userID,
+			"rule_id": id,
+			"error":   err.Error(),
+		}).Error("Failed to update Gmail sender rule")
+		return nil, errors.NewDBError("update Gmail sender rule", err)
+	}
+
+	log.WithFields(logger.Fields{
+		"user_id": userID,
+		"rule_id": id,
+	}).Info("Gmail sender rule updated")
+	return rule, nil
+}
+
+// DeleteSenderRule deletes a per-sender routing rule
+func (s *GmailService) DeleteSenderRule(userID, id uint) error {
+	if err := s.repo.DeleteSenderRule(userID, id); err != nil {
+		return errors.NewNotFoundError("Gmail sender rule", id)
+	}
+	logger.ServiceLog("GmailService", "DeleteSenderRule").WithFields(logger.Fields{
+		"user_id": userID,
+		"rule_id": id,
+	}).Info("Gmail sender rule deleted")
+	return nil
+}
+
 // GetOAuthTokenForUser returns a valid OAuth2 token for the user, refreshing if needed
 func (s *GmailService) GetOAuthTokenForUser(userID uint) (*oauth2.Token, error) {
 	token, err := s.repo.GetToken(userID)
@@ -286,6 +402,65 @@ func (s *GmailService) GetOAuthTokenForUser(userID uint) (*oauth2.Token, error)
 
 // --- Internal helpers ---
 
+// defaultScanRule returns the scan settings used before the user customizes them
+func defaultScanRule(userID uint) *models.GmailScanRule {
+	return &models.GmailScanRule{
+		UserID:            userID,
+		Enabled:           false,
+		SenderKeywords:    []string{"credit", "信用卡", "帳單", "statement"},
+		SubjectKeywords:   []string{"帳單", "電子帳單", "statement"},
+		RequireAttachment: true,
+		ScanOrders:        true,
+		ImportedLabel:     DefaultImportedLabel,
+		FailedLabel:       DefaultFailedLabel,
+	}
+}
+
+// hasModifyScope reports whether a granted scope list includes gmail.modify
+func hasModifyScope(scopes string) bool {
+	for _, scope := range strings.Fields(scopes) {
+		if scope == gmail.GmailModifyScope {
+			return true
+		}
+	}
+	return false
+}
+
+// applySenderRuleInput validates the input and copies it onto the rule
+func (s *GmailService) applySenderRuleInput(rule *models.GmailSenderRule, input models.GmailSenderRuleInput) error {
+	sender := strings.ToLower(strings.TrimSpace(input.Sender))
+	if sender == "" {
+		return errors.NewInvalidInputError("sender", "must not be empty")
+	}
+	if input.PasswordPriority < 0 || input.PasswordPriority > 4 {
+		return errors.NewInvalidInputError("password_priority", "must be between 0 and 4")
+	}
+
+	bankHint := strings.TrimSpace(input.BankHint)
+	if bankHint != "" {
+		known := false
+		for _, name := range s.bankNames {
+			if strings.EqualFold(name, bankHint) {
+				bankHint = name
+				known = true
+				break
+			}
+		}
+		if !known {
+			return errors.NewInvalidInputError("bank_hint", "unknown bank parser")
+		}
+	}
+
+	rule.Sender = sender
+	rule.BankHint = bankHint
+	rule.PasswordPriority = input.PasswordPriority
+	rule.Label = strings.TrimSpace(input.Label)
+	if input.Enabled != nil {
+		rule.Enabled = *input.Enabled
+	}
+	return nil
+}
+
 func (s *GmailService) getOAuthToken(token *models.GmailToken) (*oauth2.Token, error) {
 	accessToken, err := s.crypto.Decrypt(token.AccessTokenEncrypted)
 	if err != nil {
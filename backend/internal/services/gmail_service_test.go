@@ -51,6 +51,29 @@ func (m *mockGmailRepo) SaveScanRule(rule *models.GmailScanRule) error {
 	return args.Error(0)
 }
 
+func (m *mockGmailRepo) ListSenderRules(userID uint) ([]models.GmailSenderRule, error) {
+	args := m.Called(userID)
+	return args.Get(0).([]models.GmailSenderRule), args.Error(1)
+}
+
+func (m *mockGmailRepo) GetSenderRule(userID, id uint) (*models.GmailSenderRule, error) {
+	args := m.Called(userID, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.GmailSenderRule), args.Error(1)
+}
+
+func (m *mockGmailRepo) SaveSenderRule(rule *models.GmailSenderRule) error {
+	args := m.Called(rule)
+	return args.Error(0)
+}
+
+func (m *mockGmailRepo) DeleteSenderRule(userID, id uint) error {
+	args := m.Called(userID, id)
+	return args.Error(0)
+}
+
 func (m *mockGmailRepo) CreateScanHistory(history *models.GmailScanHistory) error {
 	args := m.Called(history)
 	return args.Error(0)
@@ -109,6 +132,7 @@ func TestNewGmailService_Success(t *testing.T) {
 
 func TestGetAuthURL(t *testing.T) {
 	repo := new(mockGmailRepo)
+	repo.On("GetScanRule", uint(1)).Return(nil, gorm.ErrRecordNotFound)
 	svc := newTestGmailService(repo)
 
 	url, err := svc.GetAuthURL(1)
@@ -116,6 +140,24 @@ func TestGetAuthURL(t *testing.T) {
 	assert.Contains(t, url, "accounts.google.com")
 	assert.Contains(t, url, "test-client-id")
 	assert.Contains(t, url, "state=")
+	assert.NotContains(t, url, "gmail.modify")
+}
+
+func TestGetAuthURL_WithLabelsRequestsModifyScope(t *testing.T) {
+	repo := new(mockGmailRepo)
+	repo.On("GetScanRule", uint(1)).Return(&models.GmailScanRule{UserID: 1, ApplyLabels: true}, nil)
+	svc := newTestGmailService(repo)
+
+	url, err := svc.GetAuthURL(1)
+	assert.NoError(t, err)
+	assert.Contains(t, url, "gmail.modify")
+	assert.Len(t, svc.oauthConfig.Scopes, 1) // shared config is not mutated
+}
+
+func TestHasModifyScope(t *testing.T) {
+	assert.True(t, hasModifyScope("https://www.googleapis.com/auth/gmail.readonly https://www.googleapis.com/auth/gmail.modify"))
+	assert.False(t, hasModifyScope("https://www.googleapis.com/auth/gmail.readonly"))
+	assert.False(t, hasModifyScope(""))
 }
 
 func TestGetStatus_NotConnected(t *testing.T) {
@@ -199,16 +241,23 @@ func TestUpdateSettings_NewRule(t *testing.T) {
 
 	svc := newTestGmailService(repo)
 
-	enabled := true
+	enabled, scanOrders := true, false
 	input := models.GmailSettingsInput{
 		Enabled:         &enabled,
 		SenderKeywords:  []string{"test"},
 		SubjectKeywords: []string{"bill"},
+		ScanOrders:      &scanOrders,
 	}
 
 	err := svc.UpdateSettings(1, input)
 	assert.NoError(t, err)
 	repo.AssertExpectations(t)
+
+	// Settings not in the input start from the defaults
+	savedRule := repo.Calls[1].Arguments.Get(0).(*models.GmailScanRule)
+	assert.False(t, savedRule.ScanOrders)
+	assert.True(t, savedRule.RequireAttachment)
+	assert.Equal(t, DefaultImportedLabel, savedRule.ImportedLabel)
 }
 
 func TestUpdateSettings_ExistingRule(t *testing.T) {
@@ -303,3 +352,61 @@ func TestHandleCallback_InvalidState(t *testing.T) {
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "Invalid OAuth state")
 }
+
+func TestCreateSenderRule_Success(t *testing.T) {
+	repo := new(mockGmailRepo)
+	svc := newTestGmailService(repo)
+
+	repo.On("SaveSenderRule", mock.AnythingOfType("*models.GmailSenderRule")).Return(nil)
+
+	rule, err := svc.CreateSenderRule(1, models.GmailSenderRuleInput{
+		Sender:           " CathayBK.com.tw ",
+		BankHint:         "國泰世華",
+		PasswordPriority: 2,
+		Label:            "Cathay",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "cathaybk.com.tw", rule.Sender)
+	assert.Equal(t, "國泰世華", rule.BankHint)
+	assert.Equal(t, 2, rule.PasswordPriority)
+	assert.True(t, rule.Enabled)
+	repo.AssertExpectations(t)
+}
+
+func TestCreateSenderRule_UnknownBank(t *testing.T) {
+	repo := new(mockGmailRepo)
+	svc := newTestGmailService(repo)
+
+	_, err := svc.CreateSenderRule(1, models.GmailSenderRuleInput{
+		Sender:   "example.com",
+		BankHint: "Unknown Bank",
+	})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "bank_hint")
+	repo.AssertNotCalled(t, "SaveSenderRule", mock.Anything)
+}
+
+func TestUpdateSenderRule_NotFound(t *testing.T) {
+	repo := new(mockGmailRepo)
+	svc := newTestGmailService(repo)
+
+	repo.On("GetSenderRule", uint(1), uint(99)).Return(nil, gorm.ErrRecordNotFound)
+
+	_, err := svc.UpdateSenderRule(1, 99, models.GmailSenderRuleInput{Sender: "example.com"})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "not found")
+}
+
+func TestUpdateSenderRule_Disable(t *testing.T) {
+	repo := new(mockGmailRepo)
+	svc := newTestGmailService(repo)
+
+	existing := &models.GmailSenderRule{ID: 5, UserID: 1, Sender: "cathaybk.com.tw", Enabled: true}
+	repo.On("GetSenderRule", uint(1), uint(5)).Return(existing, nil)
+	repo.On("SaveSenderRule", existing).Return(nil)
+
+	enabled := false
+	rule, err := svc.UpdateSenderRule(1, 5, models.GmailSenderRuleInput{Sender: "cathaybk.com.tw", Enabled: &enabled})
+	assert.NoError(t, err)
+	assert.False(t, rule.Enabled)
+}
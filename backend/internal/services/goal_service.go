@@ -0,0 +1,442 @@
+package services
+
+import (
+	"billing-note/internal/models"
+	"billing-note/internal/repository"
+	"billing-note/pkg/errors"
+	"billing-note/pkg/logger"
+	"math"
+	"strings"
+	"time"
+)
+
+// goalPaceMonths is the window used for the average monthly saving pace
+const goalPaceMonths = 3
+
+// GoalService manages savings goals and their contributions. It listens for
+// saved transactions to record contributions from a goal's linked account
+// (income and transfers in add, expenses and transfers out subtract) or tag
+// (every tagged transaction adds).
+type GoalService struct {
+	goalRepo        repository.GoalRepository
+	accountRepo     repository.AccountRepository
+	transactionRepo repository.TransactionRepository
+	userRepo        repository.UserRepository
+	now             func() time.Time
+}
+
+// NewGoalService creates a new goal service
+func NewGoalService(goalRepo repository.GoalRepository, accountRepo repository.AccountRepository, transactionRepo repository.TransactionRepository, userRepo repository.UserRepository) *GoalService {
+	return &GoalService{
+		goalRepo:        goalRepo,
+		accountRepo:     accountRepo,
+		transactionRepo: transactionRepo,
+		userRepo:        userRepo,
+		now:             time.Now,
+	}
+}
+
+// List returns progress for the user's goals as of today
+func (s *GoalService) List(userID uint, includeArchived bool) ([]models.GoalProgress, error) {
+	goals, err := s.goalRepo.List(userID, includeArchived)
+	if err != nil {
+		return nil, errors.NewDBError("list goals", err)
+	}
+	return s.progressAll(goals, s.today(userID))
+}
+
+// Get returns progress for one of the user's goals
+func (s *GoalService) Get(userID, id uint) (*models.GoalProgress, error) {
+	goal, err := s.goalRepo.GetByID(userID, id)
+	if err != nil {
+		return nil, errors.NewNotFoundError("Goal", id)
+	}
+	return s.progress(goal, s.today(userID))
+}
+
+// MonthlyStatus returns progress for the user's active goals as of the end of
+// the given month, or today for the current month
+func (s *GoalService) MonthlyStatus(userID uint, year, month int) ([]models.GoalProgress, error) {
+	today := s.today(userID)
+	asOf := time.Date(year, time.Month(month)+1, 0, 0, 0, 0, 0, time.UTC)
+	if asOf.After(today) && today.Year() == year && int(today.Month()) == month {
+		asOf = today
+	}
+
+	goals, err := s.goalRepo.List(userID, false)
+	if err != nil {
+		return nil, errors.NewDBError("list goals", err)
+	}
+	active := goals[:0]
+	for _, goal := range goals {
+		if !goal.StartDate.After(asOf) {
+			active = append(active, goal)
+		}
+	}
+	return s.progressAll(active, asOf)
+}
+
+// Create adds a goal and records contributions from existing transactions on
+// its linked account or tag
+func (s *GoalService) Create(userID uint, input models.GoalInput) (*models.GoalProgress, error) {
+	goal := &models.Goal{UserID: userID, Status: models.GoalStatusActive}
+	if err := s.applyGoalInput(goal, input); err != nil {
+		return nil, err
+	}
+	if err := s.goalRepo.Create(goal); err != nil {
+		return nil, errors.NewDBError("create goal", err)
+	}
+	if err := s.backfill(goal); err != nil {
+		return nil, errors.NewDBError("record goal contributions", err)
+	}
+	return s.progress(goal, s.today(userID))
+}
+
+// Update edits a goal. Changing the linked account, tag or start date
+// re-derives its transaction contributions.
+func (s *GoalService) Update(userID, id uint, input models.GoalInput) (*models.GoalProgress, error) {
+	goal, err := s.goalRepo.GetByID(userID, id)
+	if err != nil {
+		return nil, errors.NewNotFoundError("Goal", id)
+	}
+
+	before := *goal
+	if err := s.applyGoalInput(goal, input); err != nil {
+		return nil, err
+	}
+	switch input.Status {
+	case "":
+	case models.GoalStatusActive, models.GoalStatusArchived:
+		goal.Status = input.Status
+	default:
+		return nil, errors.NewInvalidInputError("status", "must be active or archived")
+	}
+
+	if err := s.goalRepo.Update(goal); err != nil {
+		return nil, errors.NewDBError("update goal", err)
+	}
+
+	linkChanged := !sameUintPtr(before.AccountID, goal.AccountID) || before.Tag != goal.Tag ||
+		!before.StartDate.Equal(goal.StartDate)
+	if linkChanged {
+		if err := s.goalRepo.ResetRuleContributions(goal.ID); err != nil {
+			return nil, errors.NewDBError("reset goal contributions", err)
+		}
+		if err := s.backfill(goal); err != nil {
+			return nil, errors.NewDBError("record goal contributions", err)
+		}
+	}
+	return s.progress(goal, s.today(userID))
+}
+
+// Delete removes a goal and its contributions
+func (s *GoalService) Delete(userID, id uint) error {
+	if err := s.goalRepo.Delete(userID, id); err != nil {
+		return errors.NewNotFoundError("Goal", id)
+	}
+	return nil
+}
+
+// ListContributions returns a goal's contributions, newest first
+func (s *GoalService) ListContributions(userID, goalID uint) ([]models.GoalContribution, error) {
+	if _, err := s.goalRepo.GetByID(userID, goalID); err != nil {
+		return nil, errors.NewNotFoundError("Goal", goalID)
+	}
+	contributions, err := s.goalRepo.ListContributions(goalID)
+	if err != nil {
+		return nil, errors.NewDBError("list goal contributions", err)
+	}
+	return contributions, nil
+}
+
+// AddContribution records a manual contribution; negative amounts are withdrawals
+func (s *GoalService) AddContribution(userID, goalID uint, input models.ContributionInput) (*models.GoalContribution, error) {
+	if _, err := s.goalRepo.GetByID(userID, goalID); err != nil {
+		return nil, errors.NewNotFoundError("Goal", goalID)
+	}
+
+	date := s.today(userID)
+	if input.Date != "" {
+		d, err := time.Parse(budgetDateLayout, input.Date)
+		if err != nil {
+			return nil, errors.NewInvalidInputError("date", "must be YYYY-MM-DD")
+		}
+		date = d
+	}
+
+	contribution := &models.GoalContribution{
+		GoalID: goalID,
+		UserID: userID,
+		Amount: input.Amount,
+		Date:   date,
+		Note:   strings.TrimSpace(input.Note),
+		Source: models.ContributionSourceManual,
+	}
+	if err := s.goalRepo.CreateContribution(contribution); err != nil {
+		return nil, errors.NewDBError("create goal contribution", err)
+	}
+	return contribution, nil
+}
+
+// DeleteContribution removes a manual contribution. Contributions derived
+// from transactions follow their transaction instead.
+func (s *GoalService) DeleteContribution(userID, goalID, id uint) error {
+	contributions, err := s.ListContributions(userID, goalID)
+	if err != nil {
+		return err
+	}
+	for _, c := range contributions {
+		if c.ID != id {
+			continue
+		}
+		if c.Source != models.ContributionSourceManual {
+			return errors.NewValidationError("Contributions from transactions cannot be deleted; edit the transaction instead")
+		}
+		if err := s.goalRepo.DeleteContribution(goalID, id); err != nil {
+			return errors.NewDBError("delete goal contribution", err)
+		}
+		return nil
+	}
+	return errors.NewNotFoundError("Contribution", id)
+}
+
+// TransactionsSaved implements TransactionListener
+func (s *GoalService) TransactionsSaved(userID uint, transactions []models.Transaction) {
+	log := logger.ServiceLog("GoalService", "TransactionsSaved")
+
+	goals, err := s.goalRepo.ListLinked(userID)
+	if err != nil {
+		log.WithError(err).Warn("Failed to list linked goals")
+		return
+	}
+
+	for _, txn := range transactions {
+		// An edited transaction may no longer match the goal it counted toward
+		if err := s.goalRepo.DeleteRuleContributions(txn.ID); err != nil {
+			log.WithError(err).Warn("Failed to clear goal contributions")
+			continue
+		}
+		for i := range goals {
+			if err := s.recordRuleContribution(&goals[i], txn); err != nil {
+				log.WithError(err).Warn("Failed to record goal contribution")
+			}
+		}
+	}
+}
+
+// backfill records contributions from the goal's existing transactions
+func (s *GoalService) backfill(goal *models.Goal) error {
+	if goal.AccountID == nil && goal.Tag == "" {
+		return nil
+	}
+
+	var filters []repository.TransactionFilter
+	if goal.AccountID != nil {
+		filters = append(filters, repository.TransactionFilter{UserID: goal.UserID, AccountID: goal.AccountID, StartDate: &goal.StartDate})
+	}
+	if goal.Tag != "" {
+		filters = append(filters, repository.TransactionFilter{UserID: goal.UserID, Tags: []string{goal.Tag}, StartDate: &goal.StartDate})
+	}
+
+	seen := make(map[uint]bool)
+	for _, filter := range filters {
+		transactions, _, err := s.transactionRepo.List(filter)
+		if err != nil {
+			return err
+		}
+		for _, txn := range transactions {
+			if seen[txn.ID] {
+				continue
+			}
+			seen[txn.ID] = true
+			if err := s.recordRuleContribution(goal, txn); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// recordRuleContribution records txn toward goal if it matches the goal's link
+func (s *GoalService) recordRuleContribution(goal *models.Goal, txn models.Transaction) error {
+	amount, ok := goalContributionAmount(goal, txn)
+	if !ok {
+		return nil
+	}
+	txnID := txn.ID
+	return s.goalRepo.UpsertRuleContribution(&models.GoalContribution{
+		GoalID:        goal.ID,
+		UserID:        goal.UserID,
+		Amount:        amount,
+		Date:          dateOf(txn.TransactionDate, time.UTC),
+		Note:          txn.Description,
+		Source:        models.ContributionSourceRule,
+		TransactionID: &txnID,
+	})
+}
+
+// goalContributionAmount returns what a transaction contributes to a goal
+func goalContributionAmount(goal *models.Goal, txn models.Transaction) (float64, bool) {
+	if dateOf(txn.TransactionDate, time.UTC).Before(goal.StartDate) {
+		return 0, false
+	}
+	if goal.AccountID != nil {
+		if txn.Type == "transfer" && txn.ToAccountID != nil && *txn.ToAccountID == *goal.AccountID {
+			return txn.Amount, true
+		}
+		if txn.AccountID != nil && *txn.AccountID == *goal.AccountID {
+			if txn.Type == "income" {
+				return txn.Amount, true
+			}
+			return -txn.Amount, true
+		}
+	}
+	if goal.Tag != "" {
+		for _, tag := range txn.Tags {
+			if tag == goal.Tag {
+				return txn.Amount, true
+			}
+		}
+	}
+	return 0, false
+}
+
+func (s *GoalService) progressAll(goals []models.Goal, asOf time.Time) ([]models.GoalProgress, error) {
+	result := make([]models.GoalProgress, 0, len(goals))
+	for i := range goals {
+		p, err := s.progress(&goals[i], asOf)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, *p)
+	}
+	return result, nil
+}
+
+// progress computes a goal's savings, required monthly saving and status as of a date
+func (s *GoalService) progress(goal *models.Goal, asOf time.Time) (*models.GoalProgress, error) {
+	contributions, err := s.goalRepo.ListContributions(goal.ID)
+	if err != nil {
+		return nil, errors.NewDBError("list goal contributions", err)
+	}
+	return goalProgress(goal, contributions, asOf), nil
+}
+
+func goalProgress(goal *models.Goal, contributions []models.GoalContribution, asOf time.Time) *models.GoalProgress {
+	monthStart := time.Date(asOf.Year(), asOf.Month(), 1, 0, 0, 0, 0, time.UTC)
+	paceStart := asOf.AddDate(0, -goalPaceMonths, 0)
+
+	p := &models.GoalProgress{Goal: *goal}
+	pace := 0.0
+	for _, c := range contributions {
+		d := dateOf(c.Date, time.UTC)
+		if d.After(asOf) {
+			continue
+		}
+		p.Saved += c.Amount
+		if !d.Before(monthStart) {
+			p.ContributedThisMonth += c.Amount
+		}
+		if d.After(paceStart) {
+			pace += c.Amount
+		}
+	}
+	p.Saved = roundCents(p.Saved)
+	p.ContributedThisMonth = roundCents(p.ContributedThisMonth)
+	p.AverageMonthlySaving = roundCents(pace / goalPaceMonths)
+	p.Remaining = roundCents(math.Max(0, goal.TargetAmount-p.Saved))
+	if goal.TargetAmount > 0 {
+		p.Percentage = math.Round(p.Saved/goal.TargetAmount*10000) / 100
+	}
+
+	switch {
+	case p.Remaining == 0:
+		p.Status = models.GoalProgressAchieved
+	case goal.Deadline == nil:
+		p.Status = models.GoalProgressNoDeadline
+	case dateOf(*goal.Deadline, time.UTC).Before(asOf):
+		p.Status = models.GoalProgressOverdue
+	default:
+		p.MonthsLeft = monthsUntil(asOf, dateOf(*goal.Deadline, time.UTC))
+		p.RequiredMonthlySaving = roundCents(p.Remaining / float64(p.MonthsLeft))
+		if p.AverageMonthlySaving >= p.RequiredMonthlySaving {
+			p.Status = models.GoalProgressOnTrack
+		} else {
+			p.Status = models.GoalProgressBehind
+		}
+	}
+	return p
+}
+
+// monthsUntil counts the monthly saving opportunities from from through to,
+// including the current month; at least 1
+func monthsUntil(from, to time.Time) int {
+	months := (to.Year()-from.Year())*12 + int(to.Month()) - int(from.Month())
+	if to.Day() >= from.Day() {
+		months++
+	}
+	if months < 1 {
+		months = 1
+	}
+	return months
+}
+
+// applyGoalInput validates the input and sets it on the goal
+func (s *GoalService) applyGoalInput(goal *models.Goal, input models.GoalInput) error {
+	name := strings.TrimSpace(input.Name)
+	if name == "" {
+		return errors.NewInvalidInputError("name", "is required")
+	}
+	if input.TargetAmount <= 0 {
+		return errors.NewInvalidInputError("target_amount", "must be positive")
+	}
+
+	startDate := goal.StartDate
+	if input.StartDate != "" {
+		d, err := time.Parse(budgetDateLayout, input.StartDate)
+		if err != nil {
+			return errors.NewInvalidInputError("start_date", "must be YYYY-MM-DD")
+		}
+		startDate = d
+	} else if startDate.IsZero() {
+		startDate = s.today(goal.UserID)
+	}
+
+	var deadline *time.Time
+	if input.Deadline != "" {
+		d, err := time.Parse(budgetDateLayout, input.Deadline)
+		if err != nil {
+			return errors.NewInvalidInputError("deadline", "must be YYYY-MM-DD")
+		}
+		if !d.After(startDate) {
+			return errors.NewInvalidInputError("deadline", "must be after start_date")
+		}
+		deadline = &d
+	}
+
+	if input.AccountID != nil {
+		if _, err := s.accountRepo.GetByID(goal.UserID, *input.AccountID); err != nil {
+			return errors.NewNotFoundError("Account", *input.AccountID)
+		}
+	}
+
+	goal.Name = name
+	goal.TargetAmount = input.TargetAmount
+	goal.StartDate = startDate
+	goal.Deadline = deadline
+	goal.AccountID = input.AccountID
+	goal.Account = nil
+	goal.Tag = strings.TrimSpace(input.Tag)
+	return nil
+}
+
+func (s *GoalService) today(userID uint) time.Time {
+	return dateOf(s.now(), userLocation(s.userRepo, userID))
+}
+
+func sameUintPtr(a, b *uint) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
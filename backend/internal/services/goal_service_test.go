@@ -0,0 +1,229 @@
+package services
+
+import (
+	"billing-note/internal/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// --- Mock Goal Repository ---
+
+type mockGoalRepo struct {
+	mock.Mock
+}
+
+func (m *mockGoalRepo) Create(goal *models.Goal) error {
+	args := m.Called(goal)
+	return args.Error(0)
+}
+
+func (m *mockGoalRepo) List(userID uint, includeArchived bool) ([]models.Goal, error) {
+	args := m.Called(userID, includeArchived)
+	return args.Get(0).([]models.Goal), args.Error(1)
+}
+
+func (m *mockGoalRepo) GetByID(userID, id uint) (*models.Goal, error) {
+	args := m.Called(userID, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Goal), args.Error(1)
+}
+
+func (m *mockGoalRepo) Update(goal *models.Goal) error {
+	args := m.Called(goal)
+	return args.Error(0)
+}
+
+func (m *mockGoalRepo) Delete(userID, id uint) error {
+	args := m.Called(userID, id)
+	return args.Error(0)
+}
+
+func (m *mockGoalRepo) ListLinked(userID uint) ([]models.Goal, error) {
+	args := m.Called(userID)
+	return args.Get(0).([]models.Goal), args.Error(1)
+}
+
+func (m *mockGoalRepo) CreateContribution(contribution *models.GoalContribution) error {
+	args := m.Called(contribution)
+	return args.Error(0)
+}
+
+func (m *mockGoalRepo) ListContributions(goalID uint) ([]models.GoalContribution, error) {
+	args := m.Called(goalID)
+	return args.Get(0).([]models.GoalContribution), args.Error(1)
+}
+
+func (m *mockGoalRepo) DeleteContribution(goalID, id uint) error {
+	args := m.Called(goalID, id)
+	return args.Error(0)
+}
+
+func (m *mockGoalRepo) UpsertRuleContribution(contribution *models.GoalContribution) error {
+	args := m.Called(contribution)
+	return args.Error(0)
+}
+
+func (m *mockGoalRepo) DeleteRuleContributions(transactionID uint) error {
+	args := m.Called(transactionID)
+	return args.Error(0)
+}
+
+func (m *mockGoalRepo) ResetRuleContributions(goalID uint) error {
+	args := m.Called(goalID)
+	return args.Error(0)
+}
+
+func newTestGoalService(goalRepo *mockGoalRepo, txnRepo *mockTransactionRepo, now time.Time) *GoalService {
+	s := NewGoalService(goalRepo, nil, txnRepo, nil)
+	s.now = func() time.Time { return now }
+	return s
+}
+
+// --- Tests ---
+
+func TestGoalProgress_OnTrackAndBehind(t *testing.T) {
+	deadline := ymd(2026, 12, 31)
+	goal := &models.Goal{ID: 1, TargetAmount: 120000, StartDate: ymd(2026, 1, 1), Deadline: &deadline}
+	contributions := []models.GoalContribution{
+		{Amount: 10000, Date: ymd(2026, 1, 5)},
+		{Amount: 10000, Date: ymd(2026, 2, 5)},
+		{Amount: 10000, Date: ymd(2026, 3, 5)},
+		{Amount: 10000, Date: ymd(2026, 4, 5)},
+		{Amount: 10000, Date: ymd(2026, 5, 5)},
+		{Amount: 10000, Date: ymd(2026, 6, 5)},
+	}
+
+	p := goalProgress(goal, contributions, ymd(2026, 6, 20))
+
+	assert.Equal(t, 60000.0, p.Saved)
+	assert.Equal(t, 60000.0, p.Remaining)
+	assert.Equal(t, 50.0, p.Percentage)
+	assert.Equal(t, 7, p.MonthsLeft) // June through December
+	assert.Equal(t, 8571.43, p.RequiredMonthlySaving)
+	assert.Equal(t, 10000.0, p.AverageMonthlySaving)
+	assert.Equal(t, 10000.0, p.ContributedThisMonth)
+	assert.Equal(t, models.GoalProgressOnTrack, p.Status)
+
+	// Without the last three contributions the pace falls short
+	p = goalProgress(goal, contributions[:3], ymd(2026, 6, 20))
+	assert.Equal(t, 12857.14, p.RequiredMonthlySaving)
+	assert.Equal(t, 0.0, p.AverageMonthlySaving)
+	assert.Equal(t, models.GoalProgressBehind, p.Status)
+}
+
+func TestGoalProgress_Statuses(t *testing.T) {
+	deadline := ymd(2026, 3, 31)
+	goal := &models.Goal{ID: 1, TargetAmount: 1000, StartDate: ymd(2026, 1, 1), Deadline: &deadline}
+
+	p := goalProgress(goal, []models.GoalContribution{{Amount: 1200, Date: ymd(2026, 2, 1)}}, ymd(2026, 4, 15))
+	assert.Equal(t, models.GoalProgressAchieved, p.Status)
+	assert.Equal(t, 0.0, p.Remaining)
+
+	p = goalProgress(goal, []models.GoalContribution{{Amount: 400, Date: ymd(2026, 2, 1)}}, ymd(2026, 4, 15))
+	assert.Equal(t, models.GoalProgressOverdue, p.Status)
+
+	// Contributions after the as-of date are ignored
+	p = goalProgress(goal, []models.GoalContribution{{Amount: 1200, Date: ymd(2026, 5, 1)}}, ymd(2026, 4, 15))
+	assert.Equal(t, 0.0, p.Saved)
+
+	goal.Deadline = nil
+	p = goalProgress(goal, nil, ymd(2026, 4, 15))
+	assert.Equal(t, models.GoalProgressNoDeadline, p.Status)
+	assert.Equal(t, 0.0, p.RequiredMonthlySaving)
+}
+
+func TestGoalService_TransactionsSaved(t *testing.T) {
+	goalRepo := new(mockGoalRepo)
+	svc := newTestGoalService(goalRepo, nil, ymd(2026, 6, 20))
+
+	savings := uint(5)
+	goalRepo.On("ListLinked", uint(1)).Return([]models.Goal{
+		{ID: 1, UserID: 1, AccountID: &savings, StartDate: ymd(2026, 1, 1)},
+		{ID: 2, UserID: 1, Tag: "trip", StartDate: ymd(2026, 1, 1)},
+	}, nil)
+	goalRepo.On("DeleteRuleContributions", mock.Anything).Return(nil)
+	var recorded []*models.GoalContribution
+	goalRepo.On("UpsertRuleContribution", mock.Anything).Run(func(args mock.Arguments) {
+		recorded = append(recorded, args.Get(0).(*models.GoalContribution))
+	}).Return(nil)
+
+	svc.TransactionsSaved(1, []models.Transaction{
+		{ID: 10, AccountID: &savings, Type: "income", Amount: 5000, TransactionDate: ymd(2026, 6, 1)},
+		{ID: 11, AccountID: &savings, Type: "expense", Amount: 800, TransactionDate: ymd(2026, 6, 2)},
+		{ID: 12, Type: "expense", Amount: 3000, Tags: []string{"trip"}, TransactionDate: ymd(2026, 6, 3)},
+		{ID: 13, Type: "expense", Amount: 100, Tags: []string{"food"}, TransactionDate: ymd(2026, 6, 3)},
+		{ID: 14, AccountID: &savings, Type: "income", Amount: 900, TransactionDate: ymd(2025, 12, 31)},
+	})
+
+	require.Len(t, recorded, 3)
+	assert.Equal(t, uint(1), recorded[0].GoalID)
+	assert.Equal(t, 5000.0, recorded[0].Amount)
+	assert.Equal(t, uint(1), recorded[1].GoalID)
+	assert.Equal(t, -800.0, recorded[1].Amount)
+	assert.Equal(t, uint(2), recorded[2].GoalID)
+	assert.Equal(t, 3000.0, recorded[2].Amount)
+	assert.Equal(t, uint(12), *recorded[2].TransactionID)
+	assert.Equal(t, models.ContributionSourceRule, recorded[2].Source)
+	goalRepo.AssertNumberOfCalls(t, "DeleteRuleContributions", 5)
+}
+
+func TestGoalService_Create_BackfillsFromTag(t *testing.T) {
+	goalRepo := new(mockGoalRepo)
+	txnRepo := new(mockTransactionRepo)
+	svc := newTestGoalService(goalRepo, txnRepo, ymd(2026, 6, 20))
+
+	goalRepo.On("Create", mock.AnythingOfType("*models.Goal")).Run(func(args mock.Arguments) {
+		args.Get(0).(*models.Goal).ID = 3
+	}).Return(nil)
+	txnRepo.On("List", mock.Anything).Return([]models.Transaction{
+		{ID: 20, Type: "expense", Amount: 1500, Tags: []string{"trip"}, TransactionDate: ymd(2026, 6, 10)},
+	}, int64(1), nil)
+	goalRepo.On("UpsertRuleContribution", mock.Anything).Return(nil)
+	goalRepo.On("ListContributions", uint(3)).Return([]models.GoalContribution{
+		{Amount: 1500, Date: ymd(2026, 6, 10), Source: models.ContributionSourceRule},
+	}, nil)
+
+	p, err := svc.Create(1, models.GoalInput{Name: " Japan trip ", TargetAmount: 60000, StartDate: "2026-06-01", Deadline: "2026-12-31", Tag: "trip"})
+
+	require.NoError(t, err)
+	assert.Equal(t, "Japan trip", p.Goal.Name)
+	assert.Equal(t, 1500.0, p.Saved)
+	assert.Equal(t, 7, p.MonthsLeft)
+	goalRepo.AssertNumberOfCalls(t, "UpsertRuleContribution", 1)
+}
+
+func TestGoalService_Create_Validation(t *testing.T) {
+	svc := newTestGoalService(new(mockGoalRepo), nil, ymd(2026, 6, 20))
+
+	_, err := svc.Create(1, models.GoalInput{Name: "Car", TargetAmount: 0})
+	assert.Error(t, err)
+
+	_, err = svc.Create(1, models.GoalInput{Name: "Car", TargetAmount: 1000, Deadline: "31/12/2026"})
+	assert.Error(t, err)
+
+	_, err = svc.Create(1, models.GoalInput{Name: "Car", TargetAmount: 1000, StartDate: "2026-06-01", Deadline: "2026-05-01"})
+	assert.Error(t, err)
+}
+
+func TestGoalService_DeleteContribution_RuleBasedRejected(t *testing.T) {
+	goalRepo := new(mockGoalRepo)
+	svc := newTestGoalService(goalRepo, nil, ymd(2026, 6, 20))
+
+	txnID := uint(10)
+	goalRepo.On("GetByID", uint(1), uint(1)).Return(&models.Goal{ID: 1, UserID: 1}, nil)
+	goalRepo.On("ListContributions", uint(1)).Return([]models.GoalContribution{
+		{ID: 7, GoalID: 1, Source: models.ContributionSourceRule, TransactionID: &txnID},
+		{ID: 8, GoalID: 1, Source: models.ContributionSourceManual},
+	}, nil)
+	goalRepo.On("DeleteContribution", uint(1), uint(8)).Return(nil)
+
+	assert.Error(t, svc.DeleteContribution(1, 1, 7))
+	assert.NoError(t, svc.DeleteContribution(1, 1, 8))
+	goalRepo.AssertNumberOfCalls(t, "DeleteContribution", 1)
+}
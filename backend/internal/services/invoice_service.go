@@ -5,7 +5,9 @@ import (
 	"billing-note/internal/repository"
 	"billing-note/pkg/errors"
 	"billing-note/pkg/logger"
+	"context"
 	"encoding/json"
+	stderrors "errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -43,6 +45,10 @@ type MOFInvoice struct {
 	Details     json.RawMessage `json:"details"`
 }
 
+// ErrMOFRejected marks MOF API failures that retrying cannot fix, such as a
+// rejected app ID or carrier code
+var ErrMOFRejected = stderrors.New("MOF API rejected the request")
+
 // realMOFClient implements MOFAPIClient with actual HTTP calls
 type realMOFClient struct {
 	apiURL string
@@ -77,6 +83,14 @@ func (c *realMOFClient) FetchInvoices(carrierCode, startDate, endDate string) (*
 	}
 	defer resp.Body.Close()
 
+	// Server errors and throttling may pass; other 4xx responses will not
+	if resp.StatusCode >= 400 && resp.StatusCode < 500 && resp.StatusCode != http.StatusTooManyRequests {
+		return nil, fmt.Errorf("%w: HTTP %d", ErrMOFRejected, resp.StatusCode)
+	}
+	if resp.StatusCode >= 400 {
+		return nil, fmt.Errorf("MOF API unavailable: HTTP %d", resp.StatusCode)
+	}
+
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read MOF API response: %w", err)
@@ -88,16 +102,32 @@ func (c *realMOFClient) FetchInvoices(carrierCode, startDate, endDate string) (*
 	}
 
 	if mofResp.Code != 200 {
-		return nil, fmt.Errorf("MOF API error: %s (code: %d)", mofResp.Message, mofResp.Code)
+		// 5xx codes report the service busy; the rest reject the parameters,
+		// the app ID or the carrier
+		if mofResp.Code >= 500 && mofResp.Code < 600 {
+			return nil, fmt.Errorf("MOF API error: %s (code: %d)", mofResp.Message, mofResp.Code)
+		}
+		return nil, fmt.Errorf("%w: %s (code: %d)", ErrMOFRejected, mofResp.Message, mofResp.Code)
 	}
 
 	return &mofResp, nil
 }
 
+const (
+	mofDateLayout = "2006/01/02"
+	// initialSyncMonths is how far back the first scheduled sync reaches
+	initialSyncMonths = 3
+	// syncOverlapDays re-fetches recent days since merchants upload invoices late
+	syncOverlapDays = 2
+	// maxSyncAttempts is the number of tries per date chunk before giving up
+	maxSyncAttempts = 3
+)
+
 // InvoiceService handles invoice operations
 type InvoiceService struct {
-	repo      repository.InvoiceRepository
-	mofClient MOFAPIClient
+	repo       repository.InvoiceRepository
+	mofClient  MOFAPIClient
+	retryDelay time.Duration
 }
 
 // NewInvoiceService creates a new invoice service
@@ -106,8 +136,9 @@ func NewInvoiceService(
 	apiURL, appID string,
 ) *InvoiceService {
 	svc := &InvoiceService{
-		repo:      repo,
-		mofClient: newRealMOFClient(apiURL, appID),
+		repo:       repo,
+		mofClient:  newRealMOFClient(apiURL, appID),
+		retryDelay: 2 * time.Second,
 	}
 	return svc
 }
@@ -117,17 +148,30 @@ func (s *InvoiceService) SetMOFClient(client MOFAPIClient) {
 	s.mofClient = client
 }
 
-// SyncInvoices syncs invoices from the MOF API
+// SetRetryDelay sets the base delay between MOF API retries (doubled per attempt)
+func (s *InvoiceService) SetRetryDelay(delay time.Duration) {
+	s.retryDelay = delay
+}
+
+// SyncInvoices syncs invoices from the MOF API for a range the user picked,
+// recording the attempt in the sync history as a manual sync
 func (s *InvoiceService) SyncInvoices(userID uint, carrierCode, startDate, endDate string) (int, error) {
 	log := logger.ServiceLog("InvoiceService", "SyncInvoices")
 
-	if carrierCode == "" {
-		return 0, errors.NewValidationError("Invoice carrier code is required")
+	if err := validateCarrierCode(carrierCode); err != nil {
+		return 0, err
 	}
 
-	// Validate carrier code format
-	if !strings.HasPrefix(carrierCode, "/") || len(carrierCode) != 8 {
-		return 0, errors.NewValidationError("Invalid carrier code format. Must be /XXXXXXX (7 characters after /)")
+	start, err := time.ParseInLocation(mofDateLayout, startDate, time.Local)
+	if err != nil {
+		return 0, errors.NewValidationError("Invalid start_date format. Use YYYY/MM/DD")
+	}
+	end, err := time.ParseInLocation(mofDateLayout, endDate, time.Local)
+	if err != nil {
+		return 0, errors.NewValidationError("Invalid end_date format. Use YYYY/MM/DD")
+	}
+	if end.Before(start) {
+		return 0, errors.NewValidationError("end_date must not be before start_date")
 	}
 
 	log.WithFields(logger.Fields{
@@ -136,42 +180,156 @@ func (s *InvoiceService) SyncInvoices(userID uint, carrierCode, startDate, endDa
 		"end_date":   endDate,
 	}).Info("Starting invoice sync from MOF API")
 
-	// Fetch from MOF API
-	resp, err := s.mofClient.FetchInvoices(carrierCode, startDate, endDate)
+	fetched, created, syncedTo, err := s.syncRange(userID, carrierCode, start, end)
+	s.recordHistory(userID, "manual", time.Now(), start, end, fetched, created, syncedTo, err)
 	if err != nil {
-		log.WithError(err).Error("Failed to fetch invoices from MOF API")
-		return 0, errors.NewInternalError("Failed to fetch invoices from MOF API", err)
+		return created, err
 	}
+	return created, nil
+}
 
-	// Convert to model and store
-	invoices := make([]*models.Invoice, 0, len(resp.Details))
-	for _, detail := range resp.Details {
-		invoice, err := s.convertMOFInvoice(userID, detail)
-		if err != nil {
+// SyncUser runs an incremental sync for one user, continuing from the last
+// synced date, and records the attempt in the sync history
+func (s *InvoiceService) SyncUser(userID uint, carrierCode string) (*models.InvoiceSyncHistory, error) {
+	log := logger.ServiceLog("InvoiceService", "SyncUser")
+
+	if err := validateCarrierCode(carrierCode); err != nil {
+		return nil, err
+	}
+
+	state, err := s.repo.GetSyncState(userID)
+	if err != nil || state == nil {
+		state = &models.InvoiceSyncState{UserID: userID, Enabled: true}
+	}
+
+	now := time.Now()
+	end := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+	start := end.AddDate(0, -initialSyncMonths, 0)
+	if state.LastSyncedDate != nil {
+		start = state.LastSyncedDate.AddDate(0, 0, -syncOverlapDays)
+	}
+
+	fetched, created, syncedTo, syncErr := s.syncRange(userID, carrierCode, start, end)
+
+	state.LastSyncAt = &now
+	if syncedTo != nil {
+		state.LastSyncedDate = syncedTo
+	}
+	if err := s.repo.SaveSyncState(state); err != nil {
+		log.WithError(err).Warn("Failed to save invoice sync state")
+	}
+	history := s.recordHistory(userID, "scheduled", now, start, end, fetched, created, syncedTo, syncErr)
+
+	log.WithFields(logger.Fields{
+		"user_id": userID,
+		"fetched": fetched,
+		"created": created,
+		"status":  history.Status,
+	}).Info("Scheduled invoice sync finished")
+
+	return history, syncErr
+}
+
+// recordHistory stores a sync attempt in the history. source is "scheduled"
+// or "manual".
+func (s *InvoiceService) recordHistory(userID uint, source string, at, start, end time.Time, fetched, created int, syncedTo *time.Time, syncErr error) *models.InvoiceSyncHistory {
+	history := &models.InvoiceSyncHistory{
+		UserID:          userID,
+		SyncAt:          at,
+		StartDate:       start,
+		EndDate:         end,
+		InvoicesFetched: fetched,
+		InvoicesCreated: created,
+		Source:          source,
+		Status:          "completed",
+	}
+	if syncErr != nil {
+		history.Status = "error"
+		if syncedTo != nil {
+			history.Status = "partial"
+		}
+		history.ErrorMessage = syncErr.Error()
+	}
+	if err := s.repo.CreateSyncHistory(history); err != nil {
+		logger.ServiceLog("InvoiceService", "recordHistory").WithError(err).Warn("Failed to record invoice sync history")
+	}
+	return history
+}
+
+// RunScheduledSync syncs every user with a carrier code and auto-sync enabled.
+// Returns the number of users synced without error.
+func (s *InvoiceService) RunScheduledSync() int {
+	log := logger.ServiceLog("InvoiceService", "RunScheduledSync")
+
+	users, err := s.repo.ListCarrierUsers()
+	if err != nil {
+		log.WithError(err).Error("Failed to list users for invoice sync")
+		return 0
+	}
+
+	succeeded := 0
+	for _, u := range users {
+		if _, err := s.SyncUser(u.UserID, u.InvoiceCarrier); err != nil {
 			log.WithFields(logger.Fields{
-				"invoice_number": detail.InvNum,
-				"error":          err.Error(),
-			}).Warn("Failed to convert invoice, skipping")
+				"user_id": u.UserID,
+				"error":   err.Error(),
+			}).Warn("Scheduled invoice sync failed for user")
 			continue
 		}
-		invoices = append(invoices, invoice)
+		succeeded++
 	}
 
-	// Batch create (skips duplicates)
-	created, err := s.repo.BatchCreate(invoices)
+	log.WithFields(logger.Fields{
+		"users":     len(users),
+		"succeeded": succeeded,
+	}).Info("Scheduled invoice sync run completed")
+	return succeeded
+}
+
+// StartScheduler runs RunScheduledSync immediately and then on every interval
+// until the context is cancelled
+func (s *InvoiceService) StartScheduler(ctx context.Context, interval time.Duration) {
+	go func() {
+		s.RunScheduledSync()
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				s.RunScheduledSync()
+			}
+		}
+	}()
+}
+
+// GetSyncState returns the scheduled sync state for a user
+func (s *InvoiceService) GetSyncState(userID uint) (*models.InvoiceSyncState, error) {
+	state, err := s.repo.GetSyncState(userID)
 	if err != nil {
-		log.WithError(err).Error("Failed to store invoices")
-		return 0, errors.NewDBError("store invoices", err)
+		return &models.InvoiceSyncState{UserID: userID, Enabled: true}, nil
 	}
+	return state, nil
+}
 
-	log.WithFields(logger.Fields{
-		"user_id":    userID,
-		"fetched":    len(resp.Details),
-		"created":    created,
-		"duplicates": len(resp.Details) - created,
-	}).Info("Invoice sync completed")
+// SetAutoSync enables or disables the scheduled sync for a user
+func (s *InvoiceService) SetAutoSync(userID uint, enabled bool) error {
+	state, _ := s.GetSyncState(userID)
+	state.Enabled = enabled
+	if err := s.repo.SaveSyncState(state); err != nil {
+		return errors.NewDBError("save invoice sync settings", err)
+	}
+	return nil
+}
 
-	return created, nil
+// GetSyncHistory returns recent sync history for a user
+func (s *InvoiceService) GetSyncHistory(userID uint, limit int) ([]models.InvoiceSyncHistory, error) {
+	if limit <= 0 {
+		limit = 20
+	}
+	return s.repo.ListSyncHistory(userID, limit)
 }
 
 // ListInvoices returns invoices with pagination
@@ -185,11 +343,11 @@ func (s *InvoiceService) GetInvoice(id uint) (*models.Invoice, error) {
 }
 
 // ConfirmDuplicate marks an invoice as a confirmed duplicate of a transaction
-func (s *InvoiceService) ConfirmDuplicate(invoiceID, transactionID uint) error {
+func (s *InvoiceService) ConfirmDuplicate(userID, invoiceID, transactionID uint) error {
 	log := logger.ServiceLog("InvoiceService", "ConfirmDuplicate")
 
 	invoice, err := s.repo.GetByID(invoiceID)
-	if err != nil {
+	if err != nil || invoice.UserID != userID {
 		return errors.NewNotFoundError("Invoice", invoiceID)
 	}
 
@@ -211,13 +369,122 @@ func (s *InvoiceService) ConfirmDuplicate(invoiceID, transactionID uint) error {
 	return nil
 }
 
-// DeleteInvoice deletes an invoice
-func (s *InvoiceService) DeleteInvoice(id uint) error {
+// DeleteInvoice deletes one of the user's invoices
+func (s *InvoiceService) DeleteInvoice(userID, id uint) error {
+	invoice, err := s.repo.GetByID(id)
+	if err != nil || invoice.UserID != userID {
+		return errors.NewNotFoundError("Invoice", id)
+	}
 	return s.repo.Delete(id)
 }
 
 // --- Internal helpers ---
 
+func validateCarrierCode(carrierCode string) error {
+	if carrierCode == "" {
+		return errors.NewValidationError("Invoice carrier code is required")
+	}
+
+	// Validate carrier code format
+	if !strings.HasPrefix(carrierCode, "/") || len(carrierCode) != 8 {
+		return errors.NewValidationError("Invalid carrier code format. Must be /XXXXXXX (7 characters after /)")
+	}
+	return nil
+}
+
+// syncRange fetches and stores invoices between start and end (inclusive),
+// one calendar month per MOF API call. Chunks are processed in order and the
+// sync stops at the first failure; syncedTo is the end of the last stored chunk.
+func (s *InvoiceService) syncRange(userID uint, carrierCode string, start, end time.Time) (fetched, created int, syncedTo *time.Time, err error) {
+	log := logger.ServiceLog("InvoiceService", "syncRange")
+
+	for _, chunk := range monthChunks(start, end) {
+		resp, fetchErr := s.fetchWithRetry(carrierCode, chunk[0].Format(mofDateLayout), chunk[1].Format(mofDateLayout))
+		if fetchErr != nil {
+			log.WithError(fetchErr).Error("Failed to fetch invoices from MOF API")
+			return fetched, created, syncedTo, errors.NewInternalError("Failed to fetch invoices from MOF API", fetchErr)
+		}
+
+		// Convert to model and store
+		invoices := make([]*models.Invoice, 0, len(resp.Details))
+		for _, detail := range resp.Details {
+			invoice, convErr := s.convertMOFInvoice(userID, detail)
+			if convErr != nil {
+				log.WithFields(logger.Fields{
+					"invoice_number": detail.InvNum,
+					"error":          convErr.Error(),
+				}).Warn("Failed to convert invoice, skipping")
+				continue
+			}
+			invoices = append(invoices, invoice)
+		}
+
+		// Batch create (skips duplicates)
+		n, dbErr := s.repo.BatchCreate(invoices)
+		if dbErr != nil {
+			log.WithError(dbErr).Error("Failed to store invoices")
+			return fetched, created, syncedTo, errors.NewDBError("store invoices", dbErr)
+		}
+
+		fetched += len(resp.Details)
+		created += n
+		chunkEnd := chunk[1]
+		syncedTo = &chunkEnd
+
+		log.WithFields(logger.Fields{
+			"user_id":    userID,
+			"start_date": chunk[0].Format(mofDateLayout),
+			"end_date":   chunk[1].Format(mofDateLayout),
+			"fetched":    len(resp.Details),
+			"created":    n,
+			"duplicates": len(resp.Details) - n,
+		}).Info("Invoice sync chunk completed")
+	}
+
+	return fetched, created, syncedTo, nil
+}
+
+// fetchWithRetry calls the MOF API, retrying transient failures with
+// exponential backoff. Rejected requests (ErrMOFRejected) are not retried.
+func (s *InvoiceService) fetchWithRetry(carrierCode, startDate, endDate string) (*MOFResponse, error) {
+	var lastErr error
+	delay := s.retryDelay
+	for attempt := 1; attempt <= maxSyncAttempts; attempt++ {
+		resp, err := s.mofClient.FetchInvoices(carrierCode, startDate, endDate)
+		if err == nil {
+			return resp, nil
+		}
+		if stderrors.Is(err, ErrMOFRejected) {
+			return nil, err
+		}
+		lastErr = err
+		if attempt < maxSyncAttempts {
+			logger.WithFields(logger.Fields{
+				"attempt": attempt,
+				"error":   err.Error(),
+			}).Warn("MOF API request failed, retrying")
+			time.Sleep(delay)
+			delay *= 2
+		}
+	}
+	return nil, lastErr
+}
+
+// monthChunks splits [start, end] into ranges that do not cross a calendar
+// month, as the MOF API rejects queries spanning months
+func monthChunks(start, end time.Time) [][2]time.Time {
+	var chunks [][2]time.Time
+	for cur := start; !cur.After(end); {
+		monthEnd := time.Date(cur.Year(), cur.Month()+1, 0, 0, 0, 0, 0, cur.Location())
+		if monthEnd.After(end) {
+			monthEnd = end
+		}
+		chunks = append(chunks, [2]time.Time{cur, monthEnd})
+		cur = time.Date(cur.Year(), cur.Month()+1, 1, 0, 0, 0, 0, cur.Location())
+	}
+	return chunks
+}
+
 func (s *InvoiceService) convertMOFInvoice(userID uint, mof MOFInvoice) (*models.Invoice, error) {
 	// Parse amount
 	amount, err := strconv.ParseFloat(string(mof.Amount), 64)
@@ -3,11 +3,14 @@ package services
 import (
 	"billing-note/internal/models"
 	"encoding/json"
+	"fmt"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
 )
 
 // --- Mock MOF API Client ---
@@ -71,10 +74,39 @@ func (m *mockInvoiceRepo) BatchCreate(invoices []*models.Invoice) (int, error) {
 	return args.Int(0), args.Error(1)
 }
 
+func (m *mockInvoiceRepo) ListCarrierUsers() ([]models.InvoiceCarrierUser, error) {
+	args := m.Called()
+	return args.Get(0).([]models.InvoiceCarrierUser), args.Error(1)
+}
+
+func (m *mockInvoiceRepo) GetSyncState(userID uint) (*models.InvoiceSyncState, error) {
+	args := m.Called(userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.InvoiceSyncState), args.Error(1)
+}
+
+func (m *mockInvoiceRepo) SaveSyncState(state *models.InvoiceSyncState) error {
+	args := m.Called(state)
+	return args.Error(0)
+}
+
+func (m *mockInvoiceRepo) CreateSyncHistory(history *models.InvoiceSyncHistory) error {
+	args := m.Called(history)
+	return args.Error(0)
+}
+
+func (m *mockInvoiceRepo) ListSyncHistory(userID uint, limit int) ([]models.InvoiceSyncHistory, error) {
+	args := m.Called(userID, limit)
+	return args.Get(0).([]models.InvoiceSyncHistory), args.Error(1)
+}
+
 // --- Tests ---
 
 func newTestInvoiceService(repo *mockInvoiceRepo, mofClient *mockMOFClient) *InvoiceService {
 	svc := NewInvoiceService(repo, "https://api.test.com", "test-app-id")
+	svc.SetRetryDelay(0)
 	if mofClient != nil {
 		svc.SetMOFClient(mofClient)
 	}
@@ -85,6 +117,7 @@ func TestSyncInvoices_Success(t *testing.T) {
 	repo := new(mockInvoiceRepo)
 	mofClient := new(mockMOFClient)
 	svc := newTestInvoiceService(repo, mofClient)
+	repo.On("CreateSyncHistory", mock.AnythingOfType("*models.InvoiceSyncHistory")).Return(nil)
 
 	mofResp := &MOFResponse{
 		Version: "0.5",
@@ -148,6 +181,7 @@ func TestSyncInvoices_MOFAPIError(t *testing.T) {
 	repo := new(mockInvoiceRepo)
 	mofClient := new(mockMOFClient)
 	svc := newTestInvoiceService(repo, mofClient)
+	repo.On("CreateSyncHistory", mock.AnythingOfType("*models.InvoiceSyncHistory")).Return(nil)
 
 	mofClient.On("FetchInvoices", "/ABCD123", "2026/01/01", "2026/01/31").
 		Return(nil, assert.AnError)
@@ -161,6 +195,7 @@ func TestSyncInvoices_WithDuplicates(t *testing.T) {
 	repo := new(mockInvoiceRepo)
 	mofClient := new(mockMOFClient)
 	svc := newTestInvoiceService(repo, mofClient)
+	repo.On("CreateSyncHistory", mock.AnythingOfType("*models.InvoiceSyncHistory")).Return(nil)
 
 	mofResp := &MOFResponse{
 		Version: "0.5",
@@ -205,11 +240,11 @@ func TestConfirmDuplicate(t *testing.T) {
 	repo := new(mockInvoiceRepo)
 	svc := newTestInvoiceService(repo, nil)
 
-	invoice := &models.Invoice{ID: 1, InvoiceNumber: "AB12345678", Amount: 100}
+	invoice := &models.Invoice{ID: 1, UserID: 1, InvoiceNumber: "AB12345678", Amount: 100}
 	repo.On("GetByID", uint(1)).Return(invoice, nil)
 	repo.On("Update", mock.AnythingOfType("*models.Invoice")).Return(nil)
 
-	err := svc.ConfirmDuplicate(1, 42)
+	err := svc.ConfirmDuplicate(1, 1, 42)
 	assert.NoError(t, err)
 
 	updatedInvoice := repo.Calls[1].Arguments.Get(0).(*models.Invoice)
@@ -222,13 +257,30 @@ func TestDeleteInvoice(t *testing.T) {
 	repo := new(mockInvoiceRepo)
 	svc := newTestInvoiceService(repo, nil)
 
+	repo.On("GetByID", uint(1)).Return(&models.Invoice{ID: 1, UserID: 1}, nil)
 	repo.On("Delete", uint(1)).Return(nil)
 
-	err := svc.DeleteInvoice(1)
+	err := svc.DeleteInvoice(1, 1)
 	assert.NoError(t, err)
 	repo.AssertCalled(t, "Delete", uint(1))
 }
 
+func TestInvoiceService_OtherUsersInvoice(t *testing.T) {
+	repo := new(mockInvoiceRepo)
+	svc := newTestInvoiceService(repo, nil)
+
+	repo.On("GetByID", uint(1)).Return(&models.Invoice{ID: 1, UserID: 2}, nil)
+
+	err := svc.DeleteInvoice(1, 1)
+	assert.Error(t, err)
+
+	err = svc.ConfirmDuplicate(1, 1, 42)
+	assert.Error(t, err)
+
+	repo.AssertNotCalled(t, "Delete", mock.Anything)
+	repo.AssertNotCalled(t, "Update", mock.Anything)
+}
+
 func TestConvertMOFInvoice(t *testing.T) {
 	svc := NewInvoiceService(nil, "", "")
 
@@ -281,3 +333,174 @@ func TestConvertMOFInvoice_InvalidAmount(t *testing.T) {
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "invalid amount")
 }
+
+func TestSyncInvoices_ChunksByMonth(t *testing.T) {
+	repo := new(mockInvoiceRepo)
+	mofClient := new(mockMOFClient)
+	svc := newTestInvoiceService(repo, mofClient)
+	repo.On("CreateSyncHistory", mock.AnythingOfType("*models.InvoiceSyncHistory")).Return(nil)
+
+	empty := &MOFResponse{Code: 200}
+	mofClient.On("FetchInvoices", "/ABCD123", "2026/01/15", "2026/01/31").Return(empty, nil).Once()
+	mofClient.On("FetchInvoices", "/ABCD123", "2026/02/01", "2026/02/28").Return(empty, nil).Once()
+	mofClient.On("FetchInvoices", "/ABCD123", "2026/03/01", "2026/03/10").Return(empty, nil).Once()
+	repo.On("BatchCreate", mock.Anything).Return(0, nil)
+
+	_, err := svc.SyncInvoices(1, "/ABCD123", "2026/01/15", "2026/03/10")
+	assert.NoError(t, err)
+	mofClient.AssertExpectations(t)
+}
+
+func TestSyncInvoices_InvalidDate(t *testing.T) {
+	repo := new(mockInvoiceRepo)
+	svc := newTestInvoiceService(repo, nil)
+
+	_, err := svc.SyncInvoices(1, "/ABCD123", "2026-01-01", "2026/01/31")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "start_date")
+}
+
+func TestSyncInvoices_RetriesTransientError(t *testing.T) {
+	repo := new(mockInvoiceRepo)
+	mofClient := new(mockMOFClient)
+	svc := newTestInvoiceService(repo, mofClient)
+	repo.On("CreateSyncHistory", mock.AnythingOfType("*models.InvoiceSyncHistory")).Return(nil)
+
+	mofClient.On("FetchInvoices", "/ABCD123", "2026/01/01", "2026/01/31").
+		Return(nil, assert.AnError).Twice()
+	mofClient.On("FetchInvoices", "/ABCD123", "2026/01/01", "2026/01/31").
+		Return(&MOFResponse{Code: 200}, nil).Once()
+	repo.On("BatchCreate", mock.Anything).Return(0, nil)
+
+	_, err := svc.SyncInvoices(1, "/ABCD123", "2026/01/01", "2026/01/31")
+	assert.NoError(t, err)
+	mofClient.AssertNumberOfCalls(t, "FetchInvoices", 3)
+}
+
+func TestSyncInvoices_DoesNotRetryRejectedRequest(t *testing.T) {
+	repo := new(mockInvoiceRepo)
+	mofClient := new(mockMOFClient)
+	svc := newTestInvoiceService(repo, mofClient)
+	repo.On("CreateSyncHistory", mock.AnythingOfType("*models.InvoiceSyncHistory")).Return(nil)
+
+	mofClient.On("FetchInvoices", "/ABCD123", "2026/01/01", "2026/01/31").
+		Return(nil, fmt.Errorf("%w: 驗證碼錯誤 (code: 919)", ErrMOFRejected))
+
+	_, err := svc.SyncInvoices(1, "/ABCD123", "2026/01/01", "2026/01/31")
+	assert.Error(t, err)
+	mofClient.AssertNumberOfCalls(t, "FetchInvoices", 1)
+}
+
+func TestSyncInvoices_RecordsManualHistory(t *testing.T) {
+	repo := new(mockInvoiceRepo)
+	mofClient := new(mockMOFClient)
+	svc := newTestInvoiceService(repo, mofClient)
+
+	var history *models.InvoiceSyncHistory
+	repo.On("CreateSyncHistory", mock.AnythingOfType("*models.InvoiceSyncHistory")).Run(func(args mock.Arguments) {
+		history = args.Get(0).(*models.InvoiceSyncHistory)
+	}).Return(nil)
+	mofClient.On("FetchInvoices", "/ABCD123", "2026/01/01", "2026/01/31").
+		Return(&MOFResponse{Code: 200, Details: []MOFInvoice{{InvNum: "AB12345678", Amount: json.Number("150"), InvDate: "2026/01/05 14:30:00"}}}, nil)
+	repo.On("BatchCreate", mock.Anything).Return(1, nil)
+
+	_, err := svc.SyncInvoices(1, "/ABCD123", "2026/01/01", "2026/01/31")
+	require.NoError(t, err)
+
+	require.NotNil(t, history)
+	assert.Equal(t, "manual", history.Source)
+	assert.Equal(t, "completed", history.Status)
+	assert.Equal(t, 1, history.InvoicesFetched)
+	assert.Equal(t, 1, history.InvoicesCreated)
+	// A manual range does not move the scheduled sync's progress
+	repo.AssertNotCalled(t, "SaveSyncState", mock.Anything)
+}
+
+func TestSyncUser_ContinuesFromLastSyncedDate(t *testing.T) {
+	repo := new(mockInvoiceRepo)
+	mofClient := new(mockMOFClient)
+	svc := newTestInvoiceService(repo, mofClient)
+
+	today := time.Now()
+	lastSynced := time.Date(today.Year(), today.Month(), 1, 0, 0, 0, 0, time.Local).AddDate(0, -1, 5)
+	repo.On("GetSyncState", uint(1)).Return(&models.InvoiceSyncState{UserID: 1, Enabled: true, LastSyncedDate: &lastSynced}, nil)
+	mofClient.On("FetchInvoices", "/ABCD123", lastSynced.AddDate(0, 0, -syncOverlapDays).Format(mofDateLayout), mock.Anything).
+		Return(&MOFResponse{Code: 200, Details: []MOFInvoice{{InvNum: "AB12345678", Amount: json.Number("50"), InvDate: "2026/01/05"}}}, nil)
+	mofClient.On("FetchInvoices", "/ABCD123", mock.Anything, mock.Anything).Return(&MOFResponse{Code: 200}, nil)
+	repo.On("BatchCreate", mock.Anything).Return(1, nil).Once()
+	repo.On("BatchCreate", mock.Anything).Return(0, nil)
+	repo.On("SaveSyncState", mock.AnythingOfType("*models.InvoiceSyncState")).Return(nil)
+	repo.On("CreateSyncHistory", mock.AnythingOfType("*models.InvoiceSyncHistory")).Return(nil)
+
+	history, err := svc.SyncUser(1, "/ABCD123")
+	assert.NoError(t, err)
+	assert.Equal(t, "completed", history.Status)
+	assert.Equal(t, "scheduled", history.Source)
+	assert.Equal(t, 1, history.InvoicesFetched)
+	assert.Equal(t, 1, history.InvoicesCreated)
+
+	state := repo.Calls[len(repo.Calls)-2].Arguments.Get(0).(*models.InvoiceSyncState)
+	assert.Equal(t, today.Format(mofDateLayout), state.LastSyncedDate.Format(mofDateLayout))
+	assert.NotNil(t, state.LastSyncAt)
+}
+
+func TestSyncUser_PartialFailureKeepsProgress(t *testing.T) {
+	repo := new(mockInvoiceRepo)
+	mofClient := new(mockMOFClient)
+	svc := newTestInvoiceService(repo, mofClient)
+
+	today := time.Now()
+	lastSynced := time.Date(today.Year(), today.Month(), 1, 0, 0, 0, 0, time.Local).AddDate(0, -1, 9)
+	firstChunkEnd := time.Date(lastSynced.Year(), lastSynced.Month()+1, 0, 0, 0, 0, 0, time.Local)
+	repo.On("GetSyncState", uint(1)).Return(&models.InvoiceSyncState{UserID: 1, Enabled: true, LastSyncedDate: &lastSynced}, nil)
+	mofClient.On("FetchInvoices", "/ABCD123", mock.Anything, firstChunkEnd.Format(mofDateLayout)).Return(&MOFResponse{Code: 200}, nil)
+	mofClient.On("FetchInvoices", "/ABCD123", mock.Anything, mock.Anything).Return(nil, assert.AnError)
+	repo.On("BatchCreate", mock.Anything).Return(0, nil)
+	repo.On("SaveSyncState", mock.AnythingOfType("*models.InvoiceSyncState")).Return(nil)
+	repo.On("CreateSyncHistory", mock.AnythingOfType("*models.InvoiceSyncHistory")).Return(nil)
+
+	history, err := svc.SyncUser(1, "/ABCD123")
+	assert.Error(t, err)
+	assert.Equal(t, "partial", history.Status)
+	assert.NotEmpty(t, history.ErrorMessage)
+
+	state := repo.Calls[len(repo.Calls)-2].Arguments.Get(0).(*models.InvoiceSyncState)
+	assert.Equal(t, firstChunkEnd.Format(mofDateLayout), state.LastSyncedDate.Format(mofDateLayout))
+}
+
+func TestRunScheduledSync(t *testing.T) {
+	repo := new(mockInvoiceRepo)
+	mofClient := new(mockMOFClient)
+	svc := newTestInvoiceService(repo, mofClient)
+
+	repo.On("ListCarrierUsers").Return([]models.InvoiceCarrierUser{
+		{UserID: 1, InvoiceCarrier: "/ABCD123"},
+		{UserID: 2, InvoiceCarrier: "invalid"},
+	}, nil)
+	repo.On("GetSyncState", uint(1)).Return(nil, gorm.ErrRecordNotFound)
+	mofClient.On("FetchInvoices", "/ABCD123", mock.Anything, mock.Anything).Return(&MOFResponse{Code: 200}, nil)
+	repo.On("BatchCreate", mock.Anything).Return(0, nil)
+	repo.On("SaveSyncState", mock.AnythingOfType("*models.InvoiceSyncState")).Return(nil)
+	repo.On("CreateSyncHistory", mock.AnythingOfType("*models.InvoiceSyncHistory")).Return(nil)
+
+	succeeded := svc.RunScheduledSync()
+	assert.Equal(t, 1, succeeded)
+	// First sync reaches back initialSyncMonths, one call per month
+	assert.GreaterOrEqual(t, len(mofClient.Calls), initialSyncMonths)
+}
+
+func TestMonthChunks(t *testing.T) {
+	start := time.Date(2025, 12, 20, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2026, 2, 3, 0, 0, 0, 0, time.UTC)
+
+	chunks := monthChunks(start, end)
+	assert.Len(t, chunks, 3)
+	assert.Equal(t, "2025/12/20", chunks[0][0].Format(mofDateLayout))
+	assert.Equal(t, "2025/12/31", chunks[0][1].Format(mofDateLayout))
+	assert.Equal(t, "2026/01/01", chunks[1][0].Format(mofDateLayout))
+	assert.Equal(t, "2026/01/31", chunks[1][1].Format(mofDateLayout))
+	assert.Equal(t, "2026/02/01", chunks[2][0].Format(mofDateLayout))
+	assert.Equal(t, "2026/02/03", chunks[2][1].Format(mofDateLayout))
+
+	assert.Empty(t, monthChunks(end, start))
+}
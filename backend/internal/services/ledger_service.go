@@ -0,0 +1,206 @@
+package services
+
+import (
+	"billing-note/internal/models"
+	"billing-note/internal/repository"
+	"billing-note/pkg/errors"
+	"strings"
+)
+
+// LedgerService manages household ledgers, their members and their categories
+type LedgerService struct {
+	ledgerRepo   repository.LedgerRepository
+	userRepo     repository.UserRepository
+	categoryRepo repository.CategoryRepository
+}
+
+// NewLedgerService creates a new ledger service
+func NewLedgerService(ledgerRepo repository.LedgerRepository, userRepo repository.UserRepository, categoryRepo repository.CategoryRepository) *LedgerService {
+	return &LedgerService{
+		ledgerRepo:   ledgerRepo,
+		userRepo:     userRepo,
+		categoryRepo: categoryRepo,
+	}
+}
+
+// List returns the ledgers the user belongs to
+func (s *LedgerService) List(userID uint) ([]models.Ledger, error) {
+	ledgers, err := s.ledgerRepo.ListByUser(userID)
+	if err != nil {
+		return nil, errors.NewDBError("list ledgers", err)
+	}
+	return ledgers, nil
+}
+
+// Get returns a ledger the user belongs to
+func (s *LedgerService) Get(userID, id uint) (*models.Ledger, error) {
+	ledger, _, err := s.loadForMember(userID, id)
+	return ledger, err
+}
+
+// Create creates a ledger owned by the user
+func (s *LedgerService) Create(userID uint, input models.LedgerInput) (*models.Ledger, error) {
+	name := strings.TrimSpace(input.Name)
+	if name == "" {
+		return nil, errors.NewInvalidInputError("name", "must not be empty")
+	}
+	ledger := &models.Ledger{Name: name, OwnerID: userID}
+	if err := s.ledgerRepo.Create(ledger); err != nil {
+		return nil, errors.NewDBError("create ledger", err)
+	}
+	return ledger, nil
+}
+
+// Rename changes the ledger's name; only the owner may rename it
+func (s *LedgerService) Rename(userID, id uint, input models.LedgerInput) (*models.Ledger, error) {
+	ledger, err := s.loadForOwner(userID, id)
+	if err != nil {
+		return nil, err
+	}
+	name := strings.TrimSpace(input.Name)
+	if name == "" {
+		return nil, errors.NewInvalidInputError("name", "must not be empty")
+	}
+	ledger.Name = name
+	if err := s.ledgerRepo.Update(ledger); err != nil {
+		return nil, errors.NewDBError("update ledger", err)
+	}
+	return ledger, nil
+}
+
+// Delete removes the ledger and everything it owns; only the owner may delete it
+func (s *LedgerService) Delete(userID, id uint) error {
+	if _, err := s.loadForOwner(userID, id); err != nil {
+		return err
+	}
+	if err := s.ledgerRepo.Delete(id); err != nil {
+		return errors.NewDBError("delete ledger", err)
+	}
+	return nil
+}
+
+// AddMember adds the user with the given email to the ledger; only the owner
+// may add members
+func (s *LedgerService) AddMember(userID, id uint, input models.LedgerMemberInput) (*models.LedgerMember, error) {
+	if _, err := s.loadForOwner(userID, id); err != nil {
+		return nil, err
+	}
+	user, err := s.userRepo.FindByEmail(strings.TrimSpace(input.Email))
+	if err != nil || user == nil {
+		return nil, errors.NewNotFoundError("User", input.Email)
+	}
+
+	existing, err := s.ledgerRepo.GetMember(id, user.ID)
+	if err != nil {
+		return nil, errors.NewDBError("get ledger member", err)
+	}
+	if existing != nil {
+		return nil, errors.NewConflictError("User is already a member of this ledger")
+	}
+
+	member := &models.LedgerMember{LedgerID: id, UserID: user.ID, Role: models.LedgerRoleMember}
+	if err := s.ledgerRepo.AddMember(member); err != nil {
+		return nil, errors.NewDBError("add ledger member", err)
+	}
+	member.User = *user
+	return member, nil
+}
+
+// RemoveMember removes a member from the ledger. The owner may remove anyone
+// else; members may only remove themselves. The owner cannot leave their own
+// ledger and deletes it instead.
+func (s *LedgerService) RemoveMember(userID, id, memberID uint) error {
+	ledger, _, err := s.loadForMember(userID, id)
+	if err != nil {
+		return err
+	}
+	if memberID == ledger.OwnerID {
+		return errors.NewValidationError("The owner cannot leave the ledger; delete it instead")
+	}
+	if userID != ledger.OwnerID && userID != memberID {
+		return errors.NewForbiddenError("Only the ledger owner can remove other members")
+	}
+	if err := s.ledgerRepo.RemoveMember(id, memberID); err != nil {
+		return errors.NewNotFoundError("Ledger member", memberID)
+	}
+	return nil
+}
+
+// CreateCategory adds a category owned by the ledger
+func (s *LedgerService) CreateCategory(userID, id uint, input models.LedgerCategoryInput) (*models.Category, error) {
+	if _, _, err := s.loadForMember(userID, id); err != nil {
+		return nil, err
+	}
+	name := strings.TrimSpace(input.Name)
+	if name == "" {
+		return nil, errors.NewInvalidInputError("name", "must not be empty")
+	}
+
+	existing, err := s.categoryRepo.ListByLedger(id, "")
+	if err != nil {
+		return nil, errors.NewDBError("list categories", err)
+	}
+	for _, c := range existing {
+		if strings.EqualFold(c.Name, name) {
+			return nil, errors.NewConflictError("A category with this name already exists")
+		}
+	}
+
+	category := &models.Category{
+		Name:     name,
+		Type:     input.Type,
+		Icon:     input.Icon,
+		Color:    input.Color,
+		LedgerID: &id,
+	}
+	if err := s.categoryRepo.Create(category); err != nil {
+		return nil, errors.NewDBError("create category", err)
+	}
+	return category, nil
+}
+
+// DeleteCategory removes one of the ledger's own categories
+func (s *LedgerService) DeleteCategory(userID, id, categoryID uint) error {
+	if _, _, err := s.loadForMember(userID, id); err != nil {
+		return err
+	}
+	category, err := s.categoryRepo.GetByID(categoryID)
+	if err != nil || category.LedgerID == nil || *category.LedgerID != id {
+		return errors.NewNotFoundError("Category", categoryID)
+	}
+	if err := s.categoryRepo.Delete(categoryID); err != nil {
+		return errors.NewDBError("delete category", err)
+	}
+	return nil
+}
+
+// loadForMember returns the ledger and the user's membership. Ledgers the
+// user does not belong to are reported as not found.
+func (s *LedgerService) loadForMember(userID, id uint) (*models.Ledger, *models.LedgerMember, error) {
+	member, err := s.ledgerRepo.GetMember(id, userID)
+	if err != nil {
+		return nil, nil, errors.NewDBError("get ledger member", err)
+	}
+	if member == nil {
+		return nil, nil, errors.NewNotFoundError("Ledger", id)
+	}
+	ledger, err := s.ledgerRepo.GetByID(id)
+	if err != nil {
+		return nil, nil, errors.NewDBError("get ledger", err)
+	}
+	if ledger == nil {
+		return nil, nil, errors.NewNotFoundError("Ledger", id)
+	}
+	return ledger, member, nil
+}
+
+func (s *LedgerService) loadForOwner(userID, id uint) (*models.Ledger, error) {
+	ledger, _, err := s.loadForMember(userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if ledger.OwnerID != userID {
+		return nil, errors.NewForbiddenError("Only the ledger owner can do this")
+	}
+	return ledger, nil
+}
@@ -0,0 +1,183 @@
+package services
+
+import (
+	"billing-note/internal/models"
+	"billing-note/pkg/errors"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// --- Mock Ledger Repository ---
+
+type mockLedgerRepo struct {
+	mock.Mock
+}
+
+func (m *mockLedgerRepo) Create(ledger *models.Ledger) error {
+	args := m.Called(ledger)
+	return args.Error(0)
+}
+
+func (m *mockLedgerRepo) GetByID(id uint) (*models.Ledger, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Ledger), args.Error(1)
+}
+
+func (m *mockLedgerRepo) ListByUser(userID uint) ([]models.Ledger, error) {
+	args := m.Called(userID)
+	return args.Get(0).([]models.Ledger), args.Error(1)
+}
+
+func (m *mockLedgerRepo) Update(ledger *models.Ledger) error {
+	args := m.Called(ledger)
+	return args.Error(0)
+}
+
+func (m *mockLedgerRepo) Delete(id uint) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func (m *mockLedgerRepo) GetMember(ledgerID, userID uint) (*models.LedgerMember, error) {
+	args := m.Called(ledgerID, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.LedgerMember), args.Error(1)
+}
+
+func (m *mockLedgerRepo) AddMember(member *models.LedgerMember) error {
+	args := m.Called(member)
+	return args.Error(0)
+}
+
+func (m *mockLedgerRepo) RemoveMember(ledgerID, userID uint) error {
+	args := m.Called(ledgerID, userID)
+	return args.Error(0)
+}
+
+// household is ledger 10, owned by user 1 with user 2 as a member
+func householdLedgerRepo() *mockLedgerRepo {
+	repo := new(mockLedgerRepo)
+	repo.On("GetByID", uint(10)).Return(&models.Ledger{ID: 10, Name: "Home", OwnerID: 1}, nil)
+	repo.On("GetMember", uint(10), uint(1)).Return(&models.LedgerMember{LedgerID: 10, UserID: 1, Role: models.LedgerRoleOwner}, nil)
+	repo.On("GetMember", uint(10), uint(2)).Return(&models.LedgerMember{LedgerID: 10, UserID: 2, Role: models.LedgerRoleMember}, nil)
+	repo.On("GetMember", uint(10), mock.Anything).Return(nil, nil)
+	return repo
+}
+
+func statusOf(err error) int {
+	if appErr := errors.GetAppError(err); appErr != nil {
+		return appErr.HTTPStatus
+	}
+	return 0
+}
+
+// --- Tests ---
+
+func TestLedgerService_AddMember(t *testing.T) {
+	repo := householdLedgerRepo()
+	userRepo := new(MockUserRepository)
+	svc := NewLedgerService(repo, userRepo, nil)
+
+	userRepo.On("FindByEmail", "carol@example.com").Return(&models.User{ID: 3, Email: "carol@example.com"}, nil)
+	repo.On("AddMember", mock.MatchedBy(func(m *models.LedgerMember) bool {
+		return m.LedgerID == 10 && m.UserID == 3 && m.Role == models.LedgerRoleMember
+	})).Return(nil)
+
+	member, err := svc.AddMember(1, 10, models.LedgerMemberInput{Email: "carol@example.com"})
+
+	require.NoError(t, err)
+	assert.Equal(t, uint(3), member.UserID)
+	repo.AssertCalled(t, "AddMember", mock.Anything)
+}
+
+func TestLedgerService_AddMember_OnlyOwner(t *testing.T) {
+	repo := householdLedgerRepo()
+	svc := NewLedgerService(repo, new(MockUserRepository), nil)
+
+	_, err := svc.AddMember(2, 10, models.LedgerMemberInput{Email: "carol@example.com"})
+
+	assert.Equal(t, http.StatusForbidden, statusOf(err))
+	repo.AssertNotCalled(t, "AddMember", mock.Anything)
+}
+
+func TestLedgerService_Get_NonMemberNotFound(t *testing.T) {
+	svc := NewLedgerService(householdLedgerRepo(), nil, nil)
+
+	_, err := svc.Get(5, 10)
+
+	assert.Equal(t, http.StatusNotFound, statusOf(err))
+}
+
+func TestLedgerService_RemoveMember(t *testing.T) {
+	repo := householdLedgerRepo()
+	svc := NewLedgerService(repo, nil, nil)
+	repo.On("RemoveMember", uint(10), uint(2)).Return(nil)
+
+	// Members may leave, but not remove others; the owner cannot leave
+	assert.NoError(t, svc.RemoveMember(2, 10, 2))
+	assert.Equal(t, http.StatusForbidden, statusOf(svc.RemoveMember(2, 10, 4)))
+	assert.Equal(t, http.StatusBadRequest, statusOf(svc.RemoveMember(1, 10, 1)))
+}
+
+func TestLedgerService_CreateCategory(t *testing.T) {
+	repo := householdLedgerRepo()
+	categoryRepo := new(mockCategoryRepo)
+	svc := NewLedgerService(repo, nil, categoryRepo)
+
+	categoryRepo.On("ListByLedger", uint(10), "").Return([]models.Category{{ID: 1, Name: "Food", Type: "expense"}}, nil)
+	categoryRepo.On("Create", mock.MatchedBy(func(c *models.Category) bool {
+		return c.Name == "Pets" && c.LedgerID != nil && *c.LedgerID == 10
+	})).Return(nil)
+
+	category, err := svc.CreateCategory(2, 10, models.LedgerCategoryInput{Name: " Pets ", Type: "expense"})
+	require.NoError(t, err)
+	assert.Equal(t, "Pets", category.Name)
+
+	_, err = svc.CreateCategory(2, 10, models.LedgerCategoryInput{Name: "food", Type: "expense"})
+	assert.Equal(t, http.StatusConflict, statusOf(err))
+}
+
+func TestLedgerService_DeleteCategory_OtherLedger(t *testing.T) {
+	categoryRepo := new(mockCategoryRepo)
+	svc := NewLedgerService(householdLedgerRepo(), nil, categoryRepo)
+
+	other := uint(11)
+	categoryRepo.On("GetByID", uint(7)).Return(&models.Category{ID: 7, LedgerID: &other}, nil)
+	categoryRepo.On("GetByID", uint(8)).Return(&models.Category{ID: 8}, nil) // global
+
+	assert.Equal(t, http.StatusNotFound, statusOf(svc.DeleteCategory(1, 10, 7)))
+	assert.Equal(t, http.StatusNotFound, statusOf(svc.DeleteCategory(1, 10, 8)))
+	categoryRepo.AssertNotCalled(t, "Delete", mock.Anything)
+}
+
+func TestTransactionService_ForLedger(t *testing.T) {
+	txnRepo := new(mockTransactionRepo)
+	ledgerID := uint(10)
+	svc := NewTransactionService(txnRepo, nil).ForLedger(&ledgerID)
+
+	// Entries created by another member are part of the ledger
+	txnRepo.On("GetByID", uint(1)).Return(&models.Transaction{ID: 1, UserID: 2, LedgerID: &ledgerID}, nil)
+	// A member's personal transaction is not
+	txnRepo.On("GetByID", uint(2)).Return(&models.Transaction{ID: 2, UserID: 1}, nil)
+	txnRepo.On("Create", mock.MatchedBy(func(t *models.Transaction) bool {
+		return t.UserID == 1 && t.LedgerID != nil && *t.LedgerID == ledgerID
+	})).Return(nil)
+
+	_, err := svc.GetTransaction(1, 1)
+	assert.NoError(t, err)
+	_, err = svc.GetTransaction(2, 1)
+	assert.Error(t, err)
+
+	_, err = svc.CreateTransaction(1, &CreateTransactionRequest{Amount: 100, Type: "expense"})
+	assert.NoError(t, err)
+	txnRepo.AssertExpectations(t)
+}
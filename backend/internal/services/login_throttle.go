@@ -0,0 +1,174 @@
+package services
+
+import (
+	"billing-note/internal/models"
+	"billing-note/internal/repository"
+	"billing-note/pkg/errors"
+	"billing-note/pkg/logger"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Login throttling. After loginBackoffAfter failures for an email each further
+// attempt must wait twice as long as the last; at maxLoginFailures the account
+// is locked for loginLockout. Failures from one IP across any emails back off
+// the same way from ipLoginBackoffAfter and lock the IP at maxIPLoginFailures,
+// which stops one client spraying many accounts.
+const (
+	loginFailureWindow  = 15 * time.Minute
+	loginBackoffAfter   = 3
+	loginBackoffBase    = time.Second
+	maxLoginFailures    = 10
+	loginLockout        = 15 * time.Minute
+	ipLoginBackoffAfter = 10
+	maxIPLoginFailures  = 50
+)
+
+// LoginThrottle tracks failed sign-ins and refuses attempts while an email is
+// backing off or locked, emailing the user when their account gets locked
+type LoginThrottle struct {
+	repo   repository.LoginAttemptRepository
+	mailer Mailer
+	now    func() time.Time
+}
+
+// NewLoginThrottle creates a new login throttle
+func NewLoginThrottle(repo repository.LoginAttemptRepository, mailer Mailer) *LoginThrottle {
+	return &LoginThrottle{repo: repo, mailer: mailer, now: time.Now}
+}
+
+// Check returns a rate limit error if a sign-in for the email from the IP must
+// not be attempted yet
+func (t *LoginThrottle) Check(email, ip string) error {
+	now := t.now()
+
+	if ip != "" {
+		failures, err := t.repo.ListFailuresByIP(ip, now.Add(-loginFailureWindow))
+		if err != nil {
+			return errors.NewDBError("list login failures", err)
+		}
+		// failures are newest first
+		if len(failures) > 0 {
+			if wait := failures[0].CreatedAt.Add(ipLoginDelay(len(failures))).Sub(now); wait > 0 {
+				return errors.NewRateLimitError("Too many failed sign-in attempts. " + retryIn(wait))
+			}
+		}
+	}
+
+	failures, err := t.repo.ListFailuresByEmail(normalizeEmail(email), now.Add(-loginFailureWindow))
+	if err != nil {
+		return errors.NewDBError("list login failures", err)
+	}
+	if len(failures) == 0 {
+		return nil
+	}
+	// failures are newest first
+	if wait := failures[0].CreatedAt.Add(loginDelay(len(failures))).Sub(now); wait > 0 {
+		if len(failures) >= maxLoginFailures {
+			return errors.NewRateLimitError("This account is temporarily locked after too many failed sign-in attempts. " + retryIn(wait))
+		}
+		return errors.NewRateLimitError("Too many failed sign-in attempts. " + retryIn(wait))
+	}
+	return nil
+}
+
+// RecordFailure records a failed sign-in. user is nil when the email is not
+// registered; otherwise the user is emailed if this failure locks the account.
+// Failures older than loginFailureWindow no longer count and are pruned.
+func (t *LoginThrottle) RecordFailure(email, ip string, user *models.User) error {
+	log := logger.ServiceLog("LoginThrottle", "RecordFailure")
+
+	now := t.now()
+	email = normalizeEmail(email)
+	if err := t.repo.RecordFailure(&models.LoginAttempt{Email: email, IP: ip, CreatedAt: now}); err != nil {
+		return errors.NewDBError("record login failure", err)
+	}
+	if err := t.repo.DeleteBefore(now.Add(-loginFailureWindow)); err != nil {
+		// Best effort; stale rows are ignored by the queries anyway
+		log.WithError(err).Warn("Failed to prune old login failures")
+	}
+
+	failures, err := t.repo.ListFailuresByEmail(email, now.Add(-loginFailureWindow))
+	if err != nil {
+		return errors.NewDBError("list login failures", err)
+	}
+	if len(failures) != maxLoginFailures {
+		return nil
+	}
+
+	log.WithFields(logger.Fields{
+		"email": email,
+		"ip":    ip,
+	}).Warn("Account locked after repeated failed sign-ins")
+
+	if user != nil && t.mailer != nil {
+		body := fmt.Sprintf(
+			"There were %d failed attempts to sign in to your Billing Note account, the last from %s, "+
+				"so sign-in is locked for the next %d minutes.\n\n"+
+				"If this wasn't you, someone may be guessing your password. Consider resetting it once the lock ends.",
+			maxLoginFailures, ip, int(loginLockout.Minutes()))
+		if err := t.mailer.Send(user.Email, "Your account was temporarily locked", body); err != nil {
+			log.WithFields(logger.Fields{
+				"user_id": user.ID,
+				"error":   err.Error(),
+			}).Error("Failed to send lockout email")
+		}
+	}
+	return nil
+}
+
+// Reset forgets the email's failures after a successful sign-in
+func (t *LoginThrottle) Reset(email string) error {
+	if err := t.repo.ClearFailures(normalizeEmail(email)); err != nil {
+		return errors.NewDBError("clear login failures", err)
+	}
+	return nil
+}
+
+// loginDelay is how long to wait after the latest of the given number of
+// failures for an email before trying again
+func loginDelay(failures int) time.Duration {
+	return backoffDelay(failures, loginBackoffAfter, maxLoginFailures)
+}
+
+// ipLoginDelay is how long an IP waits after the latest of the given number
+// of failures before trying again
+func ipLoginDelay(failures int) time.Duration {
+	return backoffDelay(failures, ipLoginBackoffAfter, maxIPLoginFailures)
+}
+
+// backoffDelay doubles from loginBackoffBase once failures reach after, up to
+// loginLockout, which also applies from max failures on
+func backoffDelay(failures, after, max int) time.Duration {
+	if failures >= max {
+		return loginLockout
+	}
+	if failures < after {
+		return 0
+	}
+	delay := loginBackoffBase
+	for i := after; i < failures && delay < loginLockout; i++ {
+		delay *= 2
+	}
+	if delay > loginLockout {
+		return loginLockout
+	}
+	return delay
+}
+
+// retryIn tells the user how long to wait, rounded up
+func retryIn(wait time.Duration) string {
+	if wait > time.Minute {
+		return fmt.Sprintf("Try again in %d minutes.", int((wait+time.Minute-1)/time.Minute))
+	}
+	seconds := int((wait + time.Second - 1) / time.Second)
+	if seconds == 1 {
+		return "Try again in 1 second."
+	}
+	return fmt.Sprintf("Try again in %d seconds.", seconds)
+}
+
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
@@ -0,0 +1,224 @@
+package services
+
+import (
+	"billing-note/internal/models"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// --- Mock Login Attempt Repository ---
+
+type mockLoginAttemptRepo struct {
+	mock.Mock
+}
+
+func (m *mockLoginAttemptRepo) RecordFailure(attempt *models.LoginAttempt) error {
+	args := m.Called(attempt)
+	return args.Error(0)
+}
+
+func (m *mockLoginAttemptRepo) ListFailuresByEmail(email string, since time.Time) ([]models.LoginAttempt, error) {
+	args := m.Called(email, since)
+	return args.Get(0).([]models.LoginAttempt), args.Error(1)
+}
+
+func (m *mockLoginAttemptRepo) ListFailuresByIP(ip string, since time.Time) ([]models.LoginAttempt, error) {
+	args := m.Called(ip, since)
+	return args.Get(0).([]models.LoginAttempt), args.Error(1)
+}
+
+func (m *mockLoginAttemptRepo) ClearFailures(email string) error {
+	args := m.Called(email)
+	return args.Error(0)
+}
+
+func (m *mockLoginAttemptRepo) DeleteBefore(before time.Time) error {
+	args := m.Called(before)
+	return args.Error(0)
+}
+
+var throttleNow = time.Date(2026, 6, 15, 12, 0, 0, 0, time.UTC)
+
+func newTestLoginThrottle() (*LoginThrottle, *mockLoginAttemptRepo, *MemoryMailer) {
+	repo := new(mockLoginAttemptRepo)
+	mailer := &MemoryMailer{}
+	throttle := NewLoginThrottle(repo, mailer)
+	throttle.now = func() time.Time { return throttleNow }
+	return throttle, repo, mailer
+}
+
+// failures returns n failed attempts, the latest ago before throttleNow
+func failures(n int, ago time.Duration) []models.LoginAttempt {
+	attempts := make([]models.LoginAttempt, n)
+	for i := range attempts {
+		attempts[i] = models.LoginAttempt{Email: "user@example.com", CreatedAt: throttleNow.Add(-ago - time.Duration(i)*time.Second)}
+	}
+	return attempts
+}
+
+func TestLoginDelay(t *testing.T) {
+	assert.Equal(t, time.Duration(0), loginDelay(2))
+	assert.Equal(t, time.Second, loginDelay(3))
+	assert.Equal(t, 2*time.Second, loginDelay(4))
+	assert.Equal(t, 64*time.Second, loginDelay(9))
+	assert.Equal(t, loginLockout, loginDelay(10))
+
+	assert.Equal(t, time.Duration(0), ipLoginDelay(ipLoginBackoffAfter-1))
+	assert.Equal(t, time.Second, ipLoginDelay(ipLoginBackoffAfter))
+	assert.Equal(t, 16*time.Second, ipLoginDelay(ipLoginBackoffAfter+4))
+	assert.Equal(t, loginLockout, ipLoginDelay(ipLoginBackoffAfter+20))
+	assert.Equal(t, loginLockout, ipLoginDelay(maxIPLoginFailures))
+}
+
+func TestLoginThrottle_Check(t *testing.T) {
+	since := throttleNow.Add(-loginFailureWindow)
+	tests := []struct {
+		name     string
+		failures []models.LoginAttempt
+		status   int
+	}{
+		{"no failures", nil, 0},
+		{"below backoff", failures(2, 0), 0},
+		{"backing off", failures(3, 500*time.Millisecond), http.StatusTooManyRequests},
+		{"backoff over", failures(3, 2*time.Second), 0},
+		{"longer backoff", failures(5, 3*time.Second), http.StatusTooManyRequests},
+		{"locked", failures(10, 5*time.Minute), http.StatusTooManyRequests},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			throttle, repo, _ := newTestLoginThrottle()
+			repo.On("ListFailuresByIP", "10.0.0.1", since).Return([]models.LoginAttempt(nil), nil)
+			repo.On("ListFailuresByEmail", "user@example.com", since).Return(tt.failures, nil)
+
+			err := throttle.Check(" User@Example.com", "10.0.0.1")
+			assert.Equal(t, tt.status, statusOf(err))
+		})
+	}
+}
+
+func TestLoginThrottle_Check_LockedMessage(t *testing.T) {
+	throttle, repo, _ := newTestLoginThrottle()
+	repo.On("ListFailuresByIP", mock.Anything, mock.Anything).Return([]models.LoginAttempt(nil), nil)
+	repo.On("ListFailuresByEmail", mock.Anything, mock.Anything).Return(failures(10, 5*time.Minute), nil)
+
+	err := throttle.Check("user@example.com", "10.0.0.1")
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "temporarily locked")
+	assert.Contains(t, err.Error(), "Try again in 10 minutes.")
+}
+
+func TestLoginThrottle_Check_IPBackoff(t *testing.T) {
+	since := throttleNow.Add(-loginFailureWindow)
+	tests := []struct {
+		name     string
+		failures []models.LoginAttempt
+		status   int
+	}{
+		{"below backoff", failures(ipLoginBackoffAfter-1, 0), 0},
+		{"backing off", failures(ipLoginBackoffAfter, 500*time.Millisecond), http.StatusTooManyRequests},
+		{"backoff over", failures(ipLoginBackoffAfter, 2*time.Second), 0},
+		{"longer backoff", failures(ipLoginBackoffAfter+4, 10*time.Second), http.StatusTooManyRequests},
+		{"locked", failures(maxIPLoginFailures, 5*time.Minute), http.StatusTooManyRequests},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			throttle, repo, _ := newTestLoginThrottle()
+			repo.On("ListFailuresByIP", "10.0.0.1", since).Return(tt.failures, nil)
+			repo.On("ListFailuresByEmail", "someone@example.com", since).Return([]models.LoginAttempt(nil), nil)
+
+			// Each attempt sprays a different account, so only the IP backs off
+			err := throttle.Check("someone@example.com", "10.0.0.1")
+			assert.Equal(t, tt.status, statusOf(err))
+			if tt.status != 0 {
+				repo.AssertNotCalled(t, "ListFailuresByEmail", mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
+
+func TestLoginThrottle_RecordFailure_NotifiesOnLockout(t *testing.T) {
+	throttle, repo, mailer := newTestLoginThrottle()
+	user := &models.User{ID: 1, Email: "user@example.com"}
+	repo.On("RecordFailure", mock.Anything).Return(nil)
+	repo.On("DeleteBefore", throttleNow.Add(-loginFailureWindow)).Return(nil)
+	repo.On("ListFailuresByEmail", "user@example.com", mock.Anything).Return(failures(9, 0), nil).Once()
+
+	// The ninth failure backs off without locking
+	require.NoError(t, throttle.RecordFailure("user@example.com", "10.0.0.1", user))
+	assert.Empty(t, mailer.Messages())
+
+	// The tenth locks the account and tells the user
+	repo.On("ListFailuresByEmail", "user@example.com", mock.Anything).Return(failures(10, 0), nil).Once()
+	require.NoError(t, throttle.RecordFailure("user@example.com", "10.0.0.1", user))
+
+	require.Len(t, mailer.Messages(), 1)
+	assert.Equal(t, "user@example.com", mailer.Messages()[0].To)
+	assert.Equal(t, "Your account was temporarily locked", mailer.Messages()[0].Subject)
+	assert.Contains(t, mailer.Messages()[0].Body, "10.0.0.1")
+
+	recorded := repo.Calls[0].Arguments.Get(0).(*models.LoginAttempt)
+	assert.Equal(t, "10.0.0.1", recorded.IP)
+	assert.Equal(t, throttleNow, recorded.CreatedAt)
+
+	// Failures outside the window are pruned
+	repo.AssertCalled(t, "DeleteBefore", throttleNow.Add(-loginFailureWindow))
+}
+
+func TestLoginThrottle_RecordFailure_UnknownEmail(t *testing.T) {
+	throttle, repo, mailer := newTestLoginThrottle()
+	repo.On("RecordFailure", mock.Anything).Return(nil)
+	repo.On("DeleteBefore", throttleNow.Add(-loginFailureWindow)).Return(nil)
+	repo.On("ListFailuresByEmail", "nobody@example.com", mock.Anything).Return(failures(10, 0), nil)
+
+	require.NoError(t, throttle.RecordFailure("nobody@example.com", "10.0.0.1", nil))
+	assert.Empty(t, mailer.Messages())
+}
+
+func TestAuthService_Login_Throttled(t *testing.T) {
+	throttle, attempts, _ := newTestLoginThrottle()
+	userRepo := new(MockUserRepository)
+	svc := NewAuthService(userRepo, acceptingSessionRepo(), nil, nil, throttle, "test-secret", 15*time.Minute, 720*time.Hour)
+
+	user := userWithPassword("password123")
+	userRepo.On("FindByEmail", "user@example.com").Return(user, nil)
+	attempts.On("ListFailuresByIP", "10.0.0.1", mock.Anything).Return([]models.LoginAttempt(nil), nil)
+	attempts.On("ListFailuresByEmail", "user@example.com", mock.Anything).Return(failures(1, time.Minute), nil)
+	attempts.On("RecordFailure", mock.Anything).Return(nil)
+	attempts.On("DeleteBefore", throttleNow.Add(-loginFailureWindow)).Return(nil)
+	attempts.On("ClearFailures", "user@example.com").Return(nil)
+
+	client := models.ClientInfo{IP: "10.0.0.1"}
+
+	// A wrong password counts as a failure
+	_, err := svc.Login(&LoginRequest{Email: "user@example.com", Password: "wrong", Client: client})
+	assert.Equal(t, http.StatusUnauthorized, statusOf(err))
+	attempts.AssertCalled(t, "RecordFailure", mock.Anything)
+
+	// A correct password clears the failures
+	response, err := svc.Login(&LoginRequest{Email: "user@example.com", Password: "password123", Client: client})
+	require.NoError(t, err)
+	assert.NotEmpty(t, response.Token)
+	attempts.AssertCalled(t, "ClearFailures", "user@example.com")
+}
+
+func TestAuthService_Login_Locked(t *testing.T) {
+	throttle, attempts, _ := newTestLoginThrottle()
+	userRepo := new(MockUserRepository)
+	svc := NewAuthService(userRepo, acceptingSessionRepo(), nil, nil, throttle, "test-secret", 15*time.Minute, 720*time.Hour)
+
+	attempts.On("ListFailuresByIP", mock.Anything, mock.Anything).Return([]models.LoginAttempt(nil), nil)
+	attempts.On("ListFailuresByEmail", "user@example.com", mock.Anything).Return(failures(10, time.Minute), nil)
+
+	// Even the right password is refused, without being checked
+	_, err := svc.Login(&LoginRequest{Email: "user@example.com", Password: "password123"})
+
+	assert.Equal(t, http.StatusTooManyRequests, statusOf(err))
+	userRepo.AssertNotCalled(t, "FindByEmail", mock.Anything)
+	attempts.AssertNotCalled(t, "RecordFailure", mock.Anything)
+}
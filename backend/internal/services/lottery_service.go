@@ -0,0 +1,548 @@
+package services
+
+import (
+	"billing-note/internal/models"
+	"billing-note/internal/repository"
+	"billing-note/pkg/errors"
+	"billing-note/pkg/logger"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// WinningNumbersClient abstracts fetching lottery winning numbers for testability
+type WinningNumbersClient interface {
+	FetchWinningNumbers(period string) (*models.WinningNumbersInput, error)
+}
+
+// mofWinningListResponse represents the MOF QryWinningList response
+type mofWinningListResponse struct {
+	Version       string `json:"v"`
+	Code          string `json:"code"`
+	Message       string `json:"msg"`
+	InvoYm        string `json:"invoYm"`
+	SuperPrizeNo  string `json:"superPrizeNo"`
+	SpcPrzNo      string `json:"spcPrzNo"`
+	FirstPrizeNo1 string `json:"firstPrizeNo1"`
+	FirstPrizeNo2 string `json:"firstPrizeNo2"`
+	FirstPrizeNo3 string `json:"firstPrizeNo3"`
+	SixthPrizeNo1 string `json:"sixthPrizeNo1"`
+	SixthPrizeNo2 string `json:"sixthPrizeNo2"`
+	SixthPrizeNo3 string `json:"sixthPrizeNo3"`
+}
+
+// realWinningNumbersClient fetches winning numbers from the MOF API
+type realWinningNumbersClient struct {
+	apiURL string
+	appID  string
+}
+
+func newRealWinningNumbersClient(apiURL, appID string) *realWinningNumbersClient {
+	return &realWinningNumbersClient{apiURL: apiURL, appID: appID}
+}
+
+func (c *realWinningNumbersClient) FetchWinningNumbers(period string) (*models.WinningNumbersInput, error) {
+	params := url.Values{}
+	params.Set("version", "0.2")
+	params.Set("action", "QryWinningList")
+	params.Set("invTerm", period)
+	params.Set("UUID", c.appID)
+	params.Set("appID", c.appID)
+
+	reqURL := fmt.Sprintf("%s?%s", c.apiURL, params.Encode())
+	resp, err := http.Get(reqURL)
+	if err != nil {
+		return nil, fmt.Errorf("MOF API request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read MOF API response: %w", err)
+	}
+
+	var list mofWinningListResponse
+	if err := json.Unmarshal(body, &list); err != nil {
+		return nil, fmt.Errorf("failed to parse MOF API response: %w", err)
+	}
+	if list.Code != "200" {
+		return nil, fmt.Errorf("MOF API error: %s (code: %s)", list.Message, list.Code)
+	}
+
+	input := &models.WinningNumbersInput{
+		Period:       period,
+		SpecialPrize: list.SuperPrizeNo,
+		GrandPrize:   list.SpcPrzNo,
+	}
+	for _, n := range []string{list.FirstPrizeNo1, list.FirstPrizeNo2, list.FirstPrizeNo3} {
+		if n != "" {
+			input.FirstPrizes = append(input.FirstPrizes, n)
+		}
+	}
+	for _, n := range []string{list.SixthPrizeNo1, list.SixthPrizeNo2, list.SixthPrizeNo3} {
+		if n != "" {
+			input.AdditionalSixthPrizes = append(input.AdditionalSixthPrizes, n)
+		}
+	}
+	return input, nil
+}
+
+var (
+	periodPattern = regexp.MustCompile(`^\d{3}(02|04|06|08|10|12)$`)
+	digits8       = regexp.MustCompile(`^\d{8}$`)
+	digits3       = regexp.MustCompile(`^\d{3}$`)
+)
+
+// LotteryService handles uniform invoice lottery checking
+type LotteryService struct {
+	lotteryRepo     repository.LotteryRepository
+	invoiceRepo     repository.InvoiceRepository
+	transactionRepo repository.TransactionRepository
+	categoryRepo    repository.CategoryRepository
+	client          WinningNumbersClient
+}
+
+// NewLotteryService creates a new lottery service
+func NewLotteryService(
+	lotteryRepo repository.LotteryRepository,
+	invoiceRepo repository.InvoiceRepository,
+	transactionRepo repository.TransactionRepository,
+	categoryRepo repository.CategoryRepository,
+	apiURL, appID string,
+) *LotteryService {
+	return &LotteryService{
+		lotteryRepo:     lotteryRepo,
+		invoiceRepo:     invoiceRepo,
+		transactionRepo: transactionRepo,
+		categoryRepo:    categoryRepo,
+		client:          newRealWinningNumbersClient(apiURL, appID),
+	}
+}
+
+// SetWinningNumbersClient allows injecting a mock winning numbers client for testing
+func (s *LotteryService) SetWinningNumbersClient(client WinningNumbersClient) {
+	s.client = client
+}
+
+// ImportWinningNumbers validates and stores winning numbers, then checks every
+// user's invoices for the imported periods
+func (s *LotteryService) ImportWinningNumbers(inputs []models.WinningNumbersInput, source string) ([]models.InvoiceWinningNumbers, error) {
+	log := logger.ServiceLog("LotteryService", "ImportWinningNumbers")
+
+	saved := make([]models.InvoiceWinningNumbers, 0, len(inputs))
+	for _, input := range inputs {
+		numbers, err := validateWinningNumbers(input)
+		if err != nil {
+			return saved, err
+		}
+		numbers.Source = source
+		if err := s.lotteryRepo.SaveWinningNumbers(numbers); err != nil {
+			return saved, errors.NewDBError("save winning numbers", err)
+		}
+		saved = append(saved, *numbers)
+
+		log.WithFields(logger.Fields{
+			"period": numbers.Period,
+			"source": source,
+		}).Info("Winning numbers stored")
+
+		s.checkAllUsers(numbers)
+	}
+	return saved, nil
+}
+
+// FetchWinningNumbers fetches a period's winning numbers through the client and stores them
+func (s *LotteryService) FetchWinningNumbers(period string) (*models.InvoiceWinningNumbers, error) {
+	if !periodPattern.MatchString(period) {
+		return nil, errors.NewInvalidInputError("period", "must be ROC year + even month, e.g. 11410")
+	}
+
+	input, err := s.client.FetchWinningNumbers(period)
+	if err != nil {
+		return nil, errors.NewInternalError("Failed to fetch winning numbers", err)
+	}
+
+	saved, err := s.ImportWinningNumbers([]models.WinningNumbersInput{*input}, "api")
+	if err != nil {
+		return nil, err
+	}
+	return &saved[0], nil
+}
+
+// GetWinningNumbers returns the stored winning numbers for a period
+func (s *LotteryService) GetWinningNumbers(period string) (*models.InvoiceWinningNumbers, error) {
+	numbers, err := s.lotteryRepo.GetWinningNumbers(period)
+	if err != nil {
+		return nil, errors.NewNotFoundError("Winning numbers", period)
+	}
+	return numbers, nil
+}
+
+// ListWinningNumbers returns all stored periods
+func (s *LotteryService) ListWinningNumbers() ([]models.InvoiceWinningNumbers, error) {
+	return s.lotteryRepo.ListWinningNumbers()
+}
+
+// CheckPeriod matches a user's invoices against a period's winning numbers,
+// optionally recording prizes as income transactions
+func (s *LotteryService) CheckPeriod(userID uint, period string, recordIncome bool) (*models.LotteryCheckResult, error) {
+	log := logger.ServiceLog("LotteryService", "CheckPeriod")
+
+	if !periodPattern.MatchString(period) {
+		return nil, errors.NewInvalidInputError("period", "must be ROC year + even month, e.g. 11410")
+	}
+
+	numbers, err := s.lotteryRepo.GetWinningNumbers(period)
+	if err != nil {
+		return nil, errors.NewNotFoundError("Winning numbers", period)
+	}
+
+	result, err := s.checkUser(userID, numbers)
+	if err != nil {
+		return nil, err
+	}
+
+	if recordIncome {
+		for i := range result.Prizes {
+			if err := s.recordIncome(&result.Prizes[i]); err != nil {
+				log.WithFields(logger.Fields{
+					"user_id":  userID,
+					"prize_id": result.Prizes[i].ID,
+					"error":    err.Error(),
+				}).Warn("Failed to record prize income")
+			}
+		}
+	}
+
+	log.WithFields(logger.Fields{
+		"user_id": userID,
+		"period":  period,
+		"checked": result.Checked,
+		"prizes":  len(result.Prizes),
+		"total":   result.TotalAmount,
+	}).Info("Invoice lottery checked")
+
+	return result, nil
+}
+
+// ListWinnings returns a user's prizes, optionally filtered by period
+func (s *LotteryService) ListWinnings(userID uint, period string) ([]models.InvoicePrize, float64, error) {
+	prizes, err := s.lotteryRepo.ListPrizes(userID, period)
+	if err != nil {
+		return nil, 0, errors.NewDBError("list invoice prizes", err)
+	}
+	total := 0.0
+	for _, p := range prizes {
+		total += p.Amount
+	}
+	return prizes, total, nil
+}
+
+// RecordPrizeIncome records a prize as an income transaction
+func (s *LotteryService) RecordPrizeIncome(userID, prizeID uint) (*models.InvoicePrize, error) {
+	prize, err := s.lotteryRepo.GetPrize(userID, prizeID)
+	if err != nil {
+		return nil, errors.NewNotFoundError("Invoice prize", prizeID)
+	}
+	if prize.TransactionID != nil {
+		return nil, errors.NewConflictError("Prize income already recorded")
+	}
+	if err := s.recordIncome(prize); err != nil {
+		return nil, err
+	}
+	return prize, nil
+}
+
+// --- Internal helpers ---
+
+// checkAllUsers checks every user with invoices in the period (without recording income)
+func (s *LotteryService) checkAllUsers(numbers *models.InvoiceWinningNumbers) {
+	log := logger.ServiceLog("LotteryService", "checkAllUsers")
+
+	start, end, _ := periodRange(numbers.Period)
+	userIDs, err := s.lotteryRepo.ListUserIDsWithInvoices(start, end)
+	if err != nil {
+		log.WithError(err).Warn("Failed to list users for lottery check")
+		return
+	}
+	for _, userID := range userIDs {
+		if _, err := s.checkUser(userID, numbers); err != nil {
+			log.WithFields(logger.Fields{
+				"user_id": userID,
+				"period":  numbers.Period,
+				"error":   err.Error(),
+			}).Warn("Lottery check failed for user")
+		}
+	}
+}
+
+// checkUser matches one user's invoices for the period against the stored
+// numbers. New prizes are created, and prizes from earlier checks are brought
+// in line, so re-importing corrected numbers fixes or removes stale prizes.
+func (s *LotteryService) checkUser(userID uint, numbers *models.InvoiceWinningNumbers) (*models.LotteryCheckResult, error) {
+	start, end, err := periodRange(numbers.Period)
+	if err != nil {
+		return nil, err
+	}
+	last := end.Add(-time.Nanosecond)
+
+	invoices, _, err := s.invoiceRepo.List(userID, &start, &last, 0, 0)
+	if err != nil {
+		return nil, errors.NewDBError("list invoices", err)
+	}
+	existing, err := s.lotteryRepo.ListPrizes(userID, numbers.Period)
+	if err != nil {
+		return nil, errors.NewDBError("list invoice prizes", err)
+	}
+	prizes := make(map[uint]*models.InvoicePrize, len(existing))
+	for i := range existing {
+		prizes[existing[i].InvoiceID] = &existing[i]
+	}
+
+	result := &models.LotteryCheckResult{
+		Period: numbers.Period,
+		Prizes: []models.InvoicePrize{},
+	}
+	for _, inv := range invoices {
+		tier := ""
+		// Voided invoices are not eligible
+		if !strings.Contains(inv.Status, "作廢") {
+			result.Checked++
+			tier = MatchPrize(inv.InvoiceNumber, numbers)
+		}
+
+		prize := prizes[inv.ID]
+		delete(prizes, inv.ID)
+		switch {
+		case tier == "" && prize == nil:
+			continue
+		case tier == "":
+			if err := s.removePrize(prize); err != nil {
+				return nil, err
+			}
+			continue
+		case prize == nil:
+			prize = &models.InvoicePrize{
+				UserID:    userID,
+				InvoiceID: inv.ID,
+				Period:    numbers.Period,
+				Tier:      tier,
+				Amount:    models.PrizeAmounts[tier],
+			}
+			if err := s.lotteryRepo.CreatePrize(prize); err != nil {
+				return nil, errors.NewDBError("create invoice prize", err)
+			}
+		case prize.Tier != tier:
+			if err := s.changePrizeTier(prize, tier); err != nil {
+				return nil, err
+			}
+		}
+		prize.Invoice = inv
+		result.Prizes = append(result.Prizes, *prize)
+		result.TotalAmount += prize.Amount
+	}
+
+	// Prizes whose invoice is no longer in the period
+	for _, prize := range prizes {
+		if err := s.removePrize(prize); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// changePrizeTier moves a prize to another tier, correcting the recorded
+// income if there is any
+func (s *LotteryService) changePrizeTier(prize *models.InvoicePrize, tier string) error {
+	prize.Tier = tier
+	prize.Amount = models.PrizeAmounts[tier]
+	if prize.TransactionID != nil {
+		txn, err := s.transactionRepo.GetByID(*prize.TransactionID)
+		if err == nil && txn != nil {
+			txn.Amount = prize.Amount
+			if err := s.transactionRepo.Update(txn); err != nil {
+				return errors.NewDBError("update prize income", err)
+			}
+		}
+	}
+	if err := s.lotteryRepo.UpdatePrize(prize); err != nil {
+		return errors.NewDBError("update invoice prize", err)
+	}
+	return nil
+}
+
+// removePrize deletes a prize the stored numbers no longer award, with its
+// recorded income
+func (s *LotteryService) removePrize(prize *models.InvoicePrize) error {
+	if prize.TransactionID != nil {
+		if err := s.transactionRepo.Delete(*prize.TransactionID); err != nil {
+			return errors.NewDBError("delete prize income", err)
+		}
+	}
+	if err := s.lotteryRepo.DeletePrize(prize.ID); err != nil {
+		return errors.NewDBError("delete invoice prize", err)
+	}
+	logger.ServiceLog("LotteryService", "removePrize").WithFields(logger.Fields{
+		"user_id":  prize.UserID,
+		"prize_id": prize.ID,
+		"period":   prize.Period,
+	}).Info("Invoice prize removed after winning numbers changed")
+	return nil
+}
+
+// recordIncome creates the income transaction for a prize and links it
+func (s *LotteryService) recordIncome(prize *models.InvoicePrize) error {
+	if prize.TransactionID != nil {
+		return nil
+	}
+
+	txn := &models.Transaction{
+		UserID:          prize.UserID,
+		CategoryID:      s.prizeCategoryID(),
+		Amount:          prize.Amount,
+		Type:            "income",
+		Description:     fmt.Sprintf("統一發票中獎 %s %s", prize.Period, prize.Invoice.InvoiceNumber),
+		TransactionDate: time.Now(),
+		Source:          "invoice_lottery",
+	}
+	if err := s.transactionRepo.Create(txn); err != nil {
+		return errors.NewDBError("create prize income", err)
+	}
+
+	prize.TransactionID = &txn.ID
+	if err := s.lotteryRepo.UpdatePrize(prize); err != nil {
+		return errors.NewDBError("link prize income", err)
+	}
+	return nil
+}
+
+// prizeCategoryID returns the "獎金" income category, if present
+func (s *LotteryService) prizeCategoryID() *uint {
+	categories, err := s.categoryRepo.GetByType("income")
+	if err != nil {
+		return nil
+	}
+	for _, c := range categories {
+		if c.Name == "獎金" {
+			id := c.ID
+			return &id
+		}
+	}
+	return nil
+}
+
+// MatchPrize returns the prize tier won by an invoice number, or "" if none.
+// Invoice numbers are two letters followed by eight digits; only the digits count.
+func MatchPrize(invoiceNumber string, numbers *models.InvoiceWinningNumbers) string {
+	num := invoiceNumber
+	if len(num) > 8 {
+		num = num[len(num)-8:]
+	}
+	if !digits8.MatchString(num) {
+		return ""
+	}
+
+	if numbers.SpecialPrize != "" && num == numbers.SpecialPrize {
+		return models.PrizeSpecial
+	}
+	if numbers.GrandPrize != "" && num == numbers.GrandPrize {
+		return models.PrizeGrand
+	}
+
+	// First prize numbers pay by the length of the matching suffix
+	tiers := map[int]string{
+		8: models.PrizeFirst,
+		7: models.PrizeSecond,
+		6: models.PrizeThird,
+		5: models.PrizeFourth,
+		4: models.PrizeFifth,
+		3: models.PrizeSixth,
+	}
+	best := 0
+	for _, first := range numbers.FirstPrizes {
+		if n := commonSuffixLen(num, first); n > best {
+			best = n
+		}
+	}
+	if best >= 3 {
+		return tiers[best]
+	}
+
+	for _, sixth := range numbers.AdditionalSixthPrizes {
+		if strings.HasSuffix(num, sixth) {
+			return models.PrizeSixth
+		}
+	}
+	return ""
+}
+
+func commonSuffixLen(a, b string) int {
+	n := 0
+	for i, j := len(a)-1, len(b)-1; i >= 0 && j >= 0 && a[i] == b[j]; i, j = i-1, j-1 {
+		n++
+	}
+	return n
+}
+
+// periodRange returns the [start, end) dates of a bi-monthly period such as "11410"
+func periodRange(period string) (time.Time, time.Time, error) {
+	if !periodPattern.MatchString(period) {
+		return time.Time{}, time.Time{}, errors.NewInvalidInputError("period", "must be ROC year + even month, e.g. 11410")
+	}
+	rocYear, _ := strconv.Atoi(period[:3])
+	month, _ := strconv.Atoi(period[3:])
+
+	start := time.Date(rocYear+1911, time.Month(month-1), 1, 0, 0, 0, 0, time.Local)
+	return start, start.AddDate(0, 2, 0), nil
+}
+
+// InvoicePeriod returns the lottery period an invoice date belongs to
+func InvoicePeriod(date time.Time) string {
+	month := int(date.Month())
+	if month%2 == 1 {
+		month++
+	}
+	return fmt.Sprintf("%03d%02d", date.Year()-1911, month)
+}
+
+func validateWinningNumbers(input models.WinningNumbersInput) (*models.InvoiceWinningNumbers, error) {
+	period := strings.TrimSpace(input.Period)
+	if !periodPattern.MatchString(period) {
+		return nil, errors.NewInvalidInputError("period", "must be ROC year + even month, e.g. 11410")
+	}
+
+	numbers := &models.InvoiceWinningNumbers{
+		Period:       period,
+		SpecialPrize: strings.TrimSpace(input.SpecialPrize),
+		GrandPrize:   strings.TrimSpace(input.GrandPrize),
+	}
+	for _, n := range []string{numbers.SpecialPrize, numbers.GrandPrize} {
+		if n != "" && !digits8.MatchString(n) {
+			return nil, errors.NewInvalidInputError("winning numbers", "special and grand prizes must be 8 digits")
+		}
+	}
+	for _, n := range input.FirstPrizes {
+		n = strings.TrimSpace(n)
+		if !digits8.MatchString(n) {
+			return nil, errors.NewInvalidInputError("first_prizes", "must be 8 digits")
+		}
+		numbers.FirstPrizes = append(numbers.FirstPrizes, n)
+	}
+	for _, n := range input.AdditionalSixthPrizes {
+		n = strings.TrimSpace(n)
+		if !digits3.MatchString(n) {
+			return nil, errors.NewInvalidInputError("additional_sixth_prizes", "must be 3 digits")
+		}
+		numbers.AdditionalSixthPrizes = append(numbers.AdditionalSixthPrizes, n)
+	}
+	if len(numbers.FirstPrizes) == 0 {
+		return nil, errors.NewInvalidInputError("first_prizes", "at least one first prize number is required")
+	}
+	return numbers, nil
+}
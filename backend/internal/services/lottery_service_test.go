@@ -0,0 +1,353 @@
+package services
+
+import (
+	"billing-note/internal/models"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// --- Mock Lottery Repository ---
+
+type mockLotteryRepo struct {
+	mock.Mock
+}
+
+func (m *mockLotteryRepo) SaveWinningNumbers(numbers *models.InvoiceWinningNumbers) error {
+	args := m.Called(numbers)
+	return args.Error(0)
+}
+
+func (m *mockLotteryRepo) GetWinningNumbers(period string) (*models.InvoiceWinningNumbers, error) {
+	args := m.Called(period)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.InvoiceWinningNumbers), args.Error(1)
+}
+
+func (m *mockLotteryRepo) ListWinningNumbers() ([]models.InvoiceWinningNumbers, error) {
+	args := m.Called()
+	return args.Get(0).([]models.InvoiceWinningNumbers), args.Error(1)
+}
+
+func (m *mockLotteryRepo) GetPrize(userID, id uint) (*models.InvoicePrize, error) {
+	args := m.Called(userID, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.InvoicePrize), args.Error(1)
+}
+
+func (m *mockLotteryRepo) CreatePrize(prize *models.InvoicePrize) error {
+	args := m.Called(prize)
+	return args.Error(0)
+}
+
+func (m *mockLotteryRepo) UpdatePrize(prize *models.InvoicePrize) error {
+	args := m.Called(prize)
+	return args.Error(0)
+}
+
+func (m *mockLotteryRepo) DeletePrize(id uint) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func (m *mockLotteryRepo) ListPrizes(userID uint, period string) ([]models.InvoicePrize, error) {
+	args := m.Called(userID, period)
+	return args.Get(0).([]models.InvoicePrize), args.Error(1)
+}
+
+func (m *mockLotteryRepo) ListUserIDsWithInvoices(start, end time.Time) ([]uint, error) {
+	args := m.Called(start, end)
+	return args.Get(0).([]uint), args.Error(1)
+}
+
+// --- Mock Category Repository ---
+
+type mockCategoryRepo struct {
+	mock.Mock
+}
+
+func (m *mockCategoryRepo) GetAll() ([]models.Category, error) {
+	args := m.Called()
+	return args.Get(0).([]models.Category), args.Error(1)
+}
+
+func (m *mockCategoryRepo) GetByID(id uint) (*models.Category, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Category), args.Error(1)
+}
+
+func (m *mockCategoryRepo) GetByType(categoryType string) ([]models.Category, error) {
+	args := m.Called(categoryType)
+	return args.Get(0).([]models.Category), args.Error(1)
+}
+
+func (m *mockCategoryRepo) ListByLedger(ledgerID uint, categoryType string) ([]models.Category, error) {
+	args := m.Called(ledgerID, categoryType)
+	return args.Get(0).([]models.Category), args.Error(1)
+}
+
+func (m *mockCategoryRepo) Create(category *models.Category) error {
+	args := m.Called(category)
+	return args.Error(0)
+}
+
+func (m *mockCategoryRepo) Update(category *models.Category) error {
+	args := m.Called(category)
+	return args.Error(0)
+}
+
+func (m *mockCategoryRepo) Delete(id uint) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+// --- Mock Winning Numbers Client ---
+
+type mockWinningNumbersClient struct {
+	mock.Mock
+}
+
+func (m *mockWinningNumbersClient) FetchWinningNumbers(period string) (*models.WinningNumbersInput, error) {
+	args := m.Called(period)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.WinningNumbersInput), args.Error(1)
+}
+
+// --- Helpers ---
+
+func testWinningNumbers() *models.InvoiceWinningNumbers {
+	return &models.InvoiceWinningNumbers{
+		Period:                "11410",
+		SpecialPrize:          "12345678",
+		GrandPrize:            "87654321",
+		FirstPrizes:           []string{"11112222", "33334444"},
+		AdditionalSixthPrizes: []string{"999"},
+	}
+}
+
+func newTestLotteryService(lotteryRepo *mockLotteryRepo, invRepo *mockInvoiceRepo, txnRepo *mockTransactionRepo, catRepo *mockCategoryRepo) *LotteryService {
+	return NewLotteryService(lotteryRepo, invRepo, txnRepo, catRepo, "https://api.test.com", "test-app-id")
+}
+
+// --- Tests ---
+
+func TestMatchPrize_Tiers(t *testing.T) {
+	numbers := testWinningNumbers()
+
+	tests := []struct {
+		number string
+		tier   string
+	}{
+		{"AB12345678", models.PrizeSpecial},
+		{"AB87654321", models.PrizeGrand},
+		{"AB11112222", models.PrizeFirst},
+		{"AB01112222", models.PrizeSecond},
+		{"AB00112222", models.PrizeThird},
+		{"AB00034444", models.PrizeFourth},
+		{"AB00004444", models.PrizeFifth},
+		{"AB00000222", models.PrizeSixth},
+		{"AB00000999", models.PrizeSixth},
+		{"AB00000022", ""},
+		{"INVALID", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.number, func(t *testing.T) {
+			assert.Equal(t, tt.tier, MatchPrize(tt.number, numbers))
+		})
+	}
+}
+
+func TestInvoicePeriod(t *testing.T) {
+	assert.Equal(t, "11410", InvoicePeriod(time.Date(2025, 9, 15, 0, 0, 0, 0, time.Local)))
+	assert.Equal(t, "11410", InvoicePeriod(time.Date(2025, 10, 31, 0, 0, 0, 0, time.Local)))
+	assert.Equal(t, "11402", InvoicePeriod(time.Date(2025, 1, 1, 0, 0, 0, 0, time.Local)))
+}
+
+func TestPeriodRange(t *testing.T) {
+	start, end, err := periodRange("11410")
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2025, 9, 1, 0, 0, 0, 0, time.Local), start)
+	assert.Equal(t, time.Date(2025, 11, 1, 0, 0, 0, 0, time.Local), end)
+
+	_, _, err = periodRange("11409")
+	assert.Error(t, err)
+}
+
+func TestCheckPeriod_RecordsPrizesAndIncome(t *testing.T) {
+	lotteryRepo := new(mockLotteryRepo)
+	invRepo := new(mockInvoiceRepo)
+	txnRepo := new(mockTransactionRepo)
+	catRepo := new(mockCategoryRepo)
+	svc := newTestLotteryService(lotteryRepo, invRepo, txnRepo, catRepo)
+
+	invoices := []models.Invoice{
+		{ID: 1, UserID: 1, InvoiceNumber: "AB00000222", Status: "已確認"},
+		{ID: 2, UserID: 1, InvoiceNumber: "AB55556666", Status: "已確認"},
+		{ID: 3, UserID: 1, InvoiceNumber: "AB12345678", Status: "作廢"},
+	}
+
+	lotteryRepo.On("GetWinningNumbers", "11410").Return(testWinningNumbers(), nil)
+	invRepo.On("List", uint(1), mock.Anything, mock.Anything, 0, 0).Return(invoices, int64(3), nil)
+	lotteryRepo.On("ListPrizes", uint(1), "11410").Return([]models.InvoicePrize{}, nil)
+	lotteryRepo.On("CreatePrize", mock.AnythingOfType("*models.InvoicePrize")).Return(nil)
+	catRepo.On("GetByType", "income").Return([]models.Category{{ID: 12, Name: "獎金", Type: "income"}}, nil)
+	txnRepo.On("Create", mock.MatchedBy(func(txn *models.Transaction) bool {
+		return txn.Type == "income" && txn.Amount == 200 && txn.Source == "invoice_lottery" &&
+			txn.CategoryID != nil && *txn.CategoryID == 12
+	})).Run(func(args mock.Arguments) {
+		args.Get(0).(*models.Transaction).ID = 99
+	}).Return(nil)
+	lotteryRepo.On("UpdatePrize", mock.AnythingOfType("*models.InvoicePrize")).Return(nil)
+
+	result, err := svc.CheckPeriod(1, "11410", true)
+	require.NoError(t, err)
+
+	assert.Equal(t, 2, result.Checked) // voided invoice skipped
+	require.Len(t, result.Prizes, 1)
+	assert.Equal(t, models.PrizeSixth, result.Prizes[0].Tier)
+	assert.Equal(t, 200.0, result.TotalAmount)
+	require.NotNil(t, result.Prizes[0].TransactionID)
+	assert.Equal(t, uint(99), *result.Prizes[0].TransactionID)
+	txnRepo.AssertExpectations(t)
+}
+
+func TestCheckPeriod_ExistingPrizeNotDuplicated(t *testing.T) {
+	lotteryRepo := new(mockLotteryRepo)
+	invRepo := new(mockInvoiceRepo)
+	svc := newTestLotteryService(lotteryRepo, invRepo, new(mockTransactionRepo), new(mockCategoryRepo))
+
+	existing := models.InvoicePrize{ID: 5, UserID: 1, InvoiceID: 1, Period: "11410", Tier: models.PrizeSixth, Amount: 200}
+	lotteryRepo.On("GetWinningNumbers", "11410").Return(testWinningNumbers(), nil)
+	invRepo.On("List", uint(1), mock.Anything, mock.Anything, 0, 0).
+		Return([]models.Invoice{{ID: 1, UserID: 1, InvoiceNumber: "AB00000222"}}, int64(1), nil)
+	lotteryRepo.On("ListPrizes", uint(1), "11410").Return([]models.InvoicePrize{existing}, nil)
+
+	result, err := svc.CheckPeriod(1, "11410", false)
+	require.NoError(t, err)
+	require.Len(t, result.Prizes, 1)
+	assert.Equal(t, uint(5), result.Prizes[0].ID)
+	lotteryRepo.AssertNotCalled(t, "CreatePrize", mock.Anything)
+	lotteryRepo.AssertNotCalled(t, "UpdatePrize", mock.Anything)
+}
+
+func TestCheckPeriod_CorrectedNumbersUpdateTier(t *testing.T) {
+	lotteryRepo := new(mockLotteryRepo)
+	invRepo := new(mockInvoiceRepo)
+	txnRepo := new(mockTransactionRepo)
+	svc := newTestLotteryService(lotteryRepo, invRepo, txnRepo, new(mockCategoryRepo))
+
+	// Stored as a sixth prize, but the corrected numbers make it a fifth prize
+	txnID := uint(99)
+	existing := models.InvoicePrize{ID: 5, UserID: 1, InvoiceID: 1, Period: "11410", Tier: models.PrizeSixth, Amount: 200, TransactionID: &txnID}
+	lotteryRepo.On("GetWinningNumbers", "11410").Return(testWinningNumbers(), nil)
+	invRepo.On("List", uint(1), mock.Anything, mock.Anything, 0, 0).
+		Return([]models.Invoice{{ID: 1, UserID: 1, InvoiceNumber: "AB00004444"}}, int64(1), nil)
+	lotteryRepo.On("ListPrizes", uint(1), "11410").Return([]models.InvoicePrize{existing}, nil)
+	txnRepo.On("GetByID", uint(99)).Return(&models.Transaction{ID: 99, Type: "income", Amount: 200}, nil)
+	txnRepo.On("Update", mock.MatchedBy(func(txn *models.Transaction) bool {
+		return txn.ID == 99 && txn.Amount == 1000
+	})).Return(nil)
+	lotteryRepo.On("UpdatePrize", mock.MatchedBy(func(prize *models.InvoicePrize) bool {
+		return prize.ID == 5 && prize.Tier == models.PrizeFifth && prize.Amount == 1000
+	})).Return(nil)
+
+	result, err := svc.CheckPeriod(1, "11410", false)
+	require.NoError(t, err)
+	require.Len(t, result.Prizes, 1)
+	assert.Equal(t, 1000.0, result.TotalAmount)
+	txnRepo.AssertExpectations(t)
+	lotteryRepo.AssertExpectations(t)
+}
+
+func TestCheckPeriod_CorrectedNumbersRemoveStalePrize(t *testing.T) {
+	lotteryRepo := new(mockLotteryRepo)
+	invRepo := new(mockInvoiceRepo)
+	txnRepo := new(mockTransactionRepo)
+	svc := newTestLotteryService(lotteryRepo, invRepo, txnRepo, new(mockCategoryRepo))
+
+	// A prize from wrongly imported numbers that the corrected numbers do not award
+	txnID := uint(99)
+	stale := models.InvoicePrize{ID: 5, UserID: 1, InvoiceID: 1, Period: "11410", Tier: models.PrizeSixth, Amount: 200, TransactionID: &txnID}
+	lotteryRepo.On("GetWinningNumbers", "11410").Return(testWinningNumbers(), nil)
+	invRepo.On("List", uint(1), mock.Anything, mock.Anything, 0, 0).
+		Return([]models.Invoice{{ID: 1, UserID: 1, InvoiceNumber: "AB00000123"}}, int64(1), nil)
+	lotteryRepo.On("ListPrizes", uint(1), "11410").Return([]models.InvoicePrize{stale}, nil)
+	txnRepo.On("Delete", uint(99)).Return(nil)
+	lotteryRepo.On("DeletePrize", uint(5)).Return(nil)
+
+	result, err := svc.CheckPeriod(1, "11410", false)
+	require.NoError(t, err)
+	assert.Empty(t, result.Prizes)
+	assert.Equal(t, 0.0, result.TotalAmount)
+	txnRepo.AssertExpectations(t)
+	lotteryRepo.AssertExpectations(t)
+}
+
+func TestCheckPeriod_NoWinningNumbers(t *testing.T) {
+	lotteryRepo := new(mockLotteryRepo)
+	svc := newTestLotteryService(lotteryRepo, new(mockInvoiceRepo), new(mockTransactionRepo), new(mockCategoryRepo))
+
+	lotteryRepo.On("GetWinningNumbers", "11410").Return(nil, gorm.ErrRecordNotFound)
+
+	_, err := svc.CheckPeriod(1, "11410", false)
+	assert.Error(t, err)
+}
+
+func TestImportWinningNumbers_ChecksAllUsers(t *testing.T) {
+	lotteryRepo := new(mockLotteryRepo)
+	invRepo := new(mockInvoiceRepo)
+	svc := newTestLotteryService(lotteryRepo, invRepo, new(mockTransactionRepo), new(mockCategoryRepo))
+
+	lotteryRepo.On("SaveWinningNumbers", mock.AnythingOfType("*models.InvoiceWinningNumbers")).Return(nil)
+	lotteryRepo.On("ListUserIDsWithInvoices", mock.Anything, mock.Anything).Return([]uint{1, 2}, nil)
+	invRepo.On("List", mock.Anything, mock.Anything, mock.Anything, 0, 0).Return([]models.Invoice{}, int64(0), nil)
+	lotteryRepo.On("ListPrizes", mock.Anything, "11410").Return([]models.InvoicePrize{}, nil)
+
+	saved, err := svc.ImportWinningNumbers([]models.WinningNumbersInput{{
+		Period:                "11410",
+		SpecialPrize:          "12345678",
+		GrandPrize:            "87654321",
+		FirstPrizes:           []string{"11112222"},
+		AdditionalSixthPrizes: []string{"999"},
+	}}, "import")
+	require.NoError(t, err)
+	require.Len(t, saved, 1)
+	assert.Equal(t, "import", saved[0].Source)
+	invRepo.AssertNumberOfCalls(t, "List", 2)
+}
+
+func TestImportWinningNumbers_InvalidInput(t *testing.T) {
+	svc := newTestLotteryService(new(mockLotteryRepo), new(mockInvoiceRepo), new(mockTransactionRepo), new(mockCategoryRepo))
+
+	_, err := svc.ImportWinningNumbers([]models.WinningNumbersInput{{Period: "11409", FirstPrizes: []string{"11112222"}}}, "import")
+	assert.Error(t, err)
+
+	_, err = svc.ImportWinningNumbers([]models.WinningNumbersInput{{Period: "11410", FirstPrizes: []string{"1234"}}}, "import")
+	assert.Error(t, err)
+}
+
+func TestFetchWinningNumbers_ClientError(t *testing.T) {
+	svc := newTestLotteryService(new(mockLotteryRepo), new(mockInvoiceRepo), new(mockTransactionRepo), new(mockCategoryRepo))
+	client := new(mockWinningNumbersClient)
+	svc.SetWinningNumbersClient(client)
+
+	client.On("FetchWinningNumbers", "11410").Return(nil, errors.New("network error"))
+
+	_, err := svc.FetchWinningNumbers("11410")
+	assert.Error(t, err)
+}
@@ -0,0 +1,94 @@
+package services
+
+import (
+	"billing-note/pkg/config"
+	"billing-note/pkg/logger"
+	"bytes"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"sync"
+)
+
+// Mailer sends transactional email such as password reset links
+type Mailer interface {
+	Send(to, subject, body string) error
+}
+
+// NewMailer returns an SMTP mailer, or a LogMailer when SMTP is not configured
+// so local setups can still follow the links
+func NewMailer(cfg config.SMTPConfig) Mailer {
+	if cfg.Host == "" {
+		return LogMailer{}
+	}
+	return &smtpMailer{cfg: cfg, send: smtp.SendMail}
+}
+
+// smtpMailer sends email through the configured SMTP server
+type smtpMailer struct {
+	cfg  config.SMTPConfig
+	send func(addr string, a smtp.Auth, from string, to []string, msg []byte) error
+}
+
+func (m *smtpMailer) Send(to, subject, body string) error {
+	var auth smtp.Auth
+	if m.cfg.Username != "" {
+		auth = smtp.PlainAuth("", m.cfg.Username, m.cfg.Password, m.cfg.Host)
+	}
+	addr := net.JoinHostPort(m.cfg.Host, m.cfg.Port)
+	return m.send(addr, auth, m.cfg.From, []string{to}, composeEmail(m.cfg.From, to, subject, body))
+}
+
+// LogMailer writes email to the log instead of sending it. For local
+// development only: the log then holds the links the email carried.
+type LogMailer struct{}
+
+func (LogMailer) Send(to, subject, body string) error {
+	logger.WithFields(logger.Fields{
+		"to":      to,
+		"subject": subject,
+		"body":    body,
+	}).Warn("SMTP not configured, email logged instead of sent")
+	return nil
+}
+
+// MailMessage is an email captured by MemoryMailer
+type MailMessage struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// MemoryMailer keeps sent email in memory, standing in for SMTP in tests
+type MemoryMailer struct {
+	mu       sync.Mutex
+	messages []MailMessage
+}
+
+func (m *MemoryMailer) Send(to, subject, body string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.messages = append(m.messages, MailMessage{To: to, Subject: subject, Body: body})
+	return nil
+}
+
+// Messages returns the email sent so far
+func (m *MemoryMailer) Messages() []MailMessage {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]MailMessage(nil), m.messages...)
+}
+
+// composeEmail formats a plain text message with the headers SMTP needs
+func composeEmail(from, to, subject, body string) []byte {
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", from)
+	fmt.Fprintf(&msg, "To: %s\r\n", to)
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("UTF-8", subject))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+	msg.WriteString(body)
+	msg.WriteString("\r\n")
+	return msg.Bytes()
+}
@@ -0,0 +1,366 @@
+package services
+
+import (
+	"billing-note/internal/models"
+	"billing-note/internal/repository"
+	"billing-note/pkg/errors"
+	"strings"
+	"time"
+)
+
+// MaxNetWorthMonths limits the length of the net worth series
+const MaxNetWorthMonths = 60
+
+// NetWorthService records account balance snapshots, derives expected
+// balances from transactions and reports net worth over time.
+//
+// An account's balance at a date starts from its latest snapshot on or before
+// that date and applies the account's transactions since. Before the first
+// snapshot, transactions are unwound from it; accounts without snapshots
+// start from zero.
+type NetWorthService struct {
+	balanceRepo     repository.BalanceRepository
+	accountRepo     repository.AccountRepository
+	transactionRepo repository.TransactionRepository
+	userRepo        repository.UserRepository
+	now             func() time.Time
+}
+
+// NewNetWorthService creates a new net worth service
+func NewNetWorthService(balanceRepo repository.BalanceRepository, accountRepo repository.AccountRepository, transactionRepo repository.TransactionRepository, userRepo repository.UserRepository) *NetWorthService {
+	return &NetWorthService{
+		balanceRepo:     balanceRepo,
+		accountRepo:     accountRepo,
+		transactionRepo: transactionRepo,
+		userRepo:        userRepo,
+		now:             time.Now,
+	}
+}
+
+// ListBalances returns an account's balance snapshots, newest first
+func (s *NetWorthService) ListBalances(userID, accountID uint) ([]models.AccountBalance, error) {
+	if _, err := s.accountRepo.GetByID(userID, accountID); err != nil {
+		return nil, errors.NewNotFoundError("Account", accountID)
+	}
+	balances, err := s.balanceRepo.ListByAccount(userID, accountID)
+	if err != nil {
+		return nil, errors.NewDBError("list account balances", err)
+	}
+	return balances, nil
+}
+
+// RecordBalance records a manually entered balance snapshot
+func (s *NetWorthService) RecordBalance(userID, accountID uint, input models.BalanceInput) (*models.AccountBalance, error) {
+	if input.Balance == nil {
+		return nil, errors.NewInvalidInputError("balance", "is required")
+	}
+	if _, err := s.accountRepo.GetByID(userID, accountID); err != nil {
+		return nil, errors.NewNotFoundError("Account", accountID)
+	}
+	date, err := s.parseDate(userID, input.Date)
+	if err != nil {
+		return nil, err
+	}
+
+	balance := &models.AccountBalance{
+		UserID:    userID,
+		AccountID: accountID,
+		Date:      date,
+		Balance:   *input.Balance,
+		Source:    models.BalanceSourceManual,
+		Note:      strings.TrimSpace(input.Note),
+	}
+	if err := s.balanceRepo.Upsert(balance); err != nil {
+		return nil, errors.NewDBError("record account balance", err)
+	}
+	return balance, nil
+}
+
+// RecordStatementBalance records the outstanding card balance printed on a
+// statement as of its closing date
+func (s *NetWorthService) RecordStatementBalance(userID, accountID uint, date time.Time, amount float64) error {
+	balance := &models.AccountBalance{
+		UserID:    userID,
+		AccountID: accountID,
+		Date:      dateOf(date, time.UTC),
+		Balance:   amount,
+		Source:    models.BalanceSourceStatement,
+		Note:      "Statement balance",
+	}
+	if err := s.balanceRepo.Upsert(balance); err != nil {
+		return errors.NewDBError("record statement balance", err)
+	}
+	return nil
+}
+
+// DeleteBalance removes a balance snapshot
+func (s *NetWorthService) DeleteBalance(userID, id uint) error {
+	if err := s.balanceRepo.Delete(userID, id); err != nil {
+		return errors.NewNotFoundError("Account balance", id)
+	}
+	return nil
+}
+
+// ExpectedBalance returns the balance expected from snapshots and
+// transactions at the end of the given date (YYYY-MM-DD, default today)
+func (s *NetWorthService) ExpectedBalance(userID, accountID uint, date string) (*models.Reconciliation, error) {
+	d, err := s.parseDate(userID, date)
+	if err != nil {
+		return nil, err
+	}
+	account, snapshots, transactions, err := s.loadAccount(userID, accountID)
+	if err != nil {
+		return nil, err
+	}
+
+	expected, base, count := balanceAt(account, snapshots, transactions, d)
+	return &models.Reconciliation{
+		AccountID:        accountID,
+		Date:             d,
+		ExpectedBalance:  expected,
+		BaseSnapshot:     base,
+		TransactionCount: count,
+	}, nil
+}
+
+// Reconcile compares the user's actual balance with the balance expected from
+// the previous snapshot and the transactions since, then records the actual
+// balance as a new snapshot
+func (s *NetWorthService) Reconcile(userID, accountID uint, input models.ReconcileInput) (*models.Reconciliation, error) {
+	if input.ActualBalance == nil {
+		return nil, errors.NewInvalidInputError("actual_balance", "is required")
+	}
+	d, err := s.parseDate(userID, input.Date)
+	if err != nil {
+		return nil, err
+	}
+	account, snapshots, transactions, err := s.loadAccount(userID, accountID)
+	if err != nil {
+		return nil, err
+	}
+
+	// A snapshot on the same day is what is being reconciled, not the base
+	previous := snapshots[:0:0]
+	for _, snap := range snapshots {
+		if !dateOf(snap.Date, time.UTC).Equal(d) {
+			previous = append(previous, snap)
+		}
+	}
+
+	expected, base, count := balanceAt(account, previous, transactions, d)
+	actual := *input.ActualBalance
+	difference := roundCents(actual - expected)
+
+	if err := s.balanceRepo.Upsert(&models.AccountBalance{
+		UserID:    userID,
+		AccountID: accountID,
+		Date:      d,
+		Balance:   actual,
+		Source:    models.BalanceSourceReconciliation,
+	}); err != nil {
+		return nil, errors.NewDBError("record account balance", err)
+	}
+
+	return &models.Reconciliation{
+		AccountID:        accountID,
+		Date:             d,
+		ExpectedBalance:  expected,
+		ActualBalance:    &actual,
+		Difference:       &difference,
+		Reconciled:       difference == 0,
+		BaseSnapshot:     base,
+		TransactionCount: count,
+	}, nil
+}
+
+// NetWorth returns today's balances and net worth with month-end values for
+// the given number of months, the last point being today
+func (s *NetWorthService) NetWorth(userID uint, months int) (*models.NetWorth, error) {
+	if months < 1 || months > MaxNetWorthMonths {
+		return nil, errors.NewInvalidInputError("months", "must be between 1 and 60")
+	}
+	today := s.today(userID)
+
+	accounts, err := s.accountRepo.List(userID)
+	if err != nil {
+		return nil, errors.NewDBError("list accounts", err)
+	}
+	allSnapshots, err := s.balanceRepo.ListByUser(userID)
+	if err != nil {
+		return nil, errors.NewDBError("list account balances", err)
+	}
+	allTransactions, _, err := s.transactionRepo.List(repository.TransactionFilter{UserID: userID})
+	if err != nil {
+		return nil, errors.NewDBError("list transactions", err)
+	}
+
+	snapshots := make(map[uint][]models.AccountBalance)
+	for _, snap := range allSnapshots {
+		snapshots[snap.AccountID] = append(snapshots[snap.AccountID], snap)
+	}
+	transactions := make(map[uint][]models.Transaction)
+	for _, txn := range allTransactions {
+		if txn.AccountID != nil {
+			transactions[*txn.AccountID] = append(transactions[*txn.AccountID], txn)
+		}
+		if txn.ToAccountID != nil {
+			transactions[*txn.ToAccountID] = append(transactions[*txn.ToAccountID], txn)
+		}
+	}
+
+	var dates []time.Time
+	for i := months - 1; i >= 1; i-- {
+		dates = append(dates, time.Date(today.Year(), today.Month()-time.Month(i)+1, 0, 0, 0, 0, 0, time.UTC))
+	}
+	dates = append(dates, today)
+
+	result := &models.NetWorth{Date: today, Accounts: []models.AccountNetWorth{}}
+	series := make([]models.NetWorthPoint, len(dates))
+	for i, d := range dates {
+		series[i].Date = d
+	}
+	for i := range accounts {
+		account := &accounts[i]
+		accountSnapshots := snapshots[account.ID]
+		accountTransactions := transactions[account.ID]
+
+		for j, d := range dates {
+			balance, _, _ := balanceAt(account, accountSnapshots, accountTransactions, d)
+			if account.IsLiability() {
+				series[j].Liabilities += balance
+			} else {
+				series[j].Assets += balance
+			}
+		}
+
+		balance, _, _ := balanceAt(account, accountSnapshots, accountTransactions, today)
+		entry := models.AccountNetWorth{
+			AccountID: account.ID,
+			Name:      account.Name,
+			Type:      account.Type,
+			Liability: account.IsLiability(),
+			Balance:   balance,
+		}
+		if n := len(accountSnapshots); n > 0 {
+			last := accountSnapshots[n-1].Date
+			entry.LastSnapshotDate = &last
+		}
+		result.Accounts = append(result.Accounts, entry)
+	}
+
+	for i := range series {
+		p := &series[i]
+		p.Assets = roundCents(p.Assets)
+		p.Liabilities = roundCents(p.Liabilities)
+		p.NetWorth = roundCents(p.Assets - p.Liabilities)
+	}
+	result.Series = series
+	current := series[len(series)-1]
+	result.Assets = current.Assets
+	result.Liabilities = current.Liabilities
+	result.NetWorth = current.NetWorth
+	return result, nil
+}
+
+// balanceAt returns the account's balance at the end of date, the snapshot it
+// was derived from and the number of transactions applied to it. snapshots
+// must be sorted oldest first.
+func balanceAt(account *models.Account, snapshots []models.AccountBalance, transactions []models.Transaction, date time.Time) (float64, *models.AccountBalance, int) {
+	var base *models.AccountBalance
+	for i := range snapshots {
+		if dateOf(snapshots[i].Date, time.UTC).After(date) {
+			break
+		}
+		base = &snapshots[i]
+	}
+
+	balance, count := 0.0, 0
+	switch {
+	case base != nil:
+		// Roll forward from the latest snapshot
+		from := dateOf(base.Date, time.UTC)
+		balance = base.Balance
+		for _, txn := range transactions {
+			d := dateOf(txn.TransactionDate, time.UTC)
+			if d.After(from) && !d.After(date) {
+				balance += accountFlow(account, txn)
+				count++
+			}
+		}
+	case len(snapshots) > 0:
+		// Unwind from the first snapshot after date
+		base = &snapshots[0]
+		to := dateOf(base.Date, time.UTC)
+		balance = base.Balance
+		for _, txn := range transactions {
+			d := dateOf(txn.TransactionDate, time.UTC)
+			if d.After(date) && !d.After(to) {
+				balance -= accountFlow(account, txn)
+				count++
+			}
+		}
+	default:
+		for _, txn := range transactions {
+			if !dateOf(txn.TransactionDate, time.UTC).After(date) {
+				balance += accountFlow(account, txn)
+				count++
+			}
+		}
+	}
+	return roundCents(balance), base, count
+}
+
+// accountFlow returns how a transaction changes the account's balance. On a
+// liability account spending increases the amount owed. A transfer leaves its
+// source account and arrives in its destination.
+func accountFlow(account *models.Account, txn models.Transaction) float64 {
+	amount := txn.Amount
+	switch txn.Type {
+	case "expense":
+		amount = -amount
+	case "transfer":
+		if txn.ToAccountID == nil || *txn.ToAccountID != account.ID {
+			amount = -amount
+		}
+	}
+	if account.IsLiability() {
+		amount = -amount
+	}
+	return amount
+}
+
+// loadAccount returns an account with its snapshots (oldest first) and transactions
+func (s *NetWorthService) loadAccount(userID, accountID uint) (*models.Account, []models.AccountBalance, []models.Transaction, error) {
+	account, err := s.accountRepo.GetByID(userID, accountID)
+	if err != nil {
+		return nil, nil, nil, errors.NewNotFoundError("Account", accountID)
+	}
+	snapshots, err := s.balanceRepo.ListByAccount(userID, accountID)
+	if err != nil {
+		return nil, nil, nil, errors.NewDBError("list account balances", err)
+	}
+	for i, j := 0, len(snapshots)-1; i < j; i, j = i+1, j-1 {
+		snapshots[i], snapshots[j] = snapshots[j], snapshots[i]
+	}
+	transactions, _, err := s.transactionRepo.List(repository.TransactionFilter{UserID: userID, AccountID: &accountID})
+	if err != nil {
+		return nil, nil, nil, errors.NewDBError("list transactions", err)
+	}
+	return account, snapshots, transactions, nil
+}
+
+// parseDate parses a YYYY-MM-DD date, defaulting to the user's today
+func (s *NetWorthService) parseDate(userID uint, value string) (time.Time, error) {
+	if value == "" {
+		return s.today(userID), nil
+	}
+	d, err := time.Parse(budgetDateLayout, value)
+	if err != nil {
+		return time.Time{}, errors.NewInvalidInputError("date", "must be YYYY-MM-DD")
+	}
+	return d, nil
+}
+
+func (s *NetWorthService) today(userID uint) time.Time {
+	return dateOf(s.now(), userLocation(s.userRepo, userID))
+}
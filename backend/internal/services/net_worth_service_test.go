@@ -0,0 +1,221 @@
+package services
+
+import (
+	"billing-note/internal/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// --- Mock Balance and Account Repositories ---
+
+type mockBalanceRepo struct {
+	mock.Mock
+}
+
+func (m *mockBalanceRepo) Upsert(balance *models.AccountBalance) error {
+	args := m.Called(balance)
+	return args.Error(0)
+}
+
+func (m *mockBalanceRepo) ListByUser(userID uint) ([]models.AccountBalance, error) {
+	args := m.Called(userID)
+	return args.Get(0).([]models.AccountBalance), args.Error(1)
+}
+
+func (m *mockBalanceRepo) ListByAccount(userID, accountID uint) ([]models.AccountBalance, error) {
+	args := m.Called(userID, accountID)
+	return args.Get(0).([]models.AccountBalance), args.Error(1)
+}
+
+func (m *mockBalanceRepo) Delete(userID, id uint) error {
+	args := m.Called(userID, id)
+	return args.Error(0)
+}
+
+type mockAccountRepo struct {
+	mock.Mock
+}
+
+func (m *mockAccountRepo) Create(account *models.Account) error {
+	args := m.Called(account)
+	return args.Error(0)
+}
+
+func (m *mockAccountRepo) List(userID uint) ([]models.Account, error) {
+	args := m.Called(userID)
+	return args.Get(0).([]models.Account), args.Error(1)
+}
+
+func (m *mockAccountRepo) GetByID(userID, id uint) (*models.Account, error) {
+	args := m.Called(userID, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Account), args.Error(1)
+}
+
+func (m *mockAccountRepo) Update(account *models.Account) error {
+	args := m.Called(account)
+	return args.Error(0)
+}
+
+func (m *mockAccountRepo) Delete(userID, id uint) error {
+	args := m.Called(userID, id)
+	return args.Error(0)
+}
+
+func newTestNetWorthService(balanceRepo *mockBalanceRepo, accountRepo *mockAccountRepo, txnRepo *mockTransactionRepo, now time.Time) *NetWorthService {
+	s := NewNetWorthService(balanceRepo, accountRepo, txnRepo, nil)
+	s.now = func() time.Time { return now }
+	return s
+}
+
+func accountTxn(accountID uint, txType string, amount float64, date time.Time) models.Transaction {
+	return models.Transaction{AccountID: &accountID, Type: txType, Amount: amount, TransactionDate: date}
+}
+
+// --- Tests ---
+
+func TestBalanceAt(t *testing.T) {
+	bank := &models.Account{ID: 1, Type: models.AccountTypeBank}
+	snapshots := []models.AccountBalance{
+		{AccountID: 1, Date: ymd(2026, 3, 31), Balance: 10000},
+		{AccountID: 1, Date: ymd(2026, 5, 31), Balance: 20000},
+	}
+	txns := []models.Transaction{
+		accountTxn(1, "expense", 500, ymd(2026, 3, 15)),
+		accountTxn(1, "income", 3000, ymd(2026, 4, 5)),
+		accountTxn(1, "expense", 1000, ymd(2026, 4, 20)),
+		accountTxn(1, "expense", 200, ymd(2026, 6, 2)),
+	}
+
+	// Rolled forward from the March snapshot
+	balance, base, count := balanceAt(bank, snapshots, txns, ymd(2026, 4, 30))
+	assert.Equal(t, 12000.0, balance)
+	assert.Equal(t, 10000.0, base.Balance)
+	assert.Equal(t, 2, count)
+
+	// The latest snapshot wins once it applies
+	balance, _, _ = balanceAt(bank, snapshots, txns, ymd(2026, 6, 30))
+	assert.Equal(t, 19800.0, balance)
+
+	// Before the first snapshot, transactions are unwound
+	balance, _, count = balanceAt(bank, snapshots, txns, ymd(2026, 3, 1))
+	assert.Equal(t, 10500.0, balance)
+	assert.Equal(t, 1, count)
+
+	// Without snapshots the balance starts at zero
+	balance, base, _ = balanceAt(bank, nil, txns, ymd(2026, 4, 30))
+	assert.Equal(t, 1500.0, balance)
+	assert.Nil(t, base)
+}
+
+func TestBalanceAt_LiabilityGrowsWithSpending(t *testing.T) {
+	card := &models.Account{ID: 2, Type: models.AccountTypeCreditCard}
+	snapshots := []models.AccountBalance{{AccountID: 2, Date: ymd(2026, 5, 31), Balance: 8000}}
+	txns := []models.Transaction{
+		accountTxn(2, "expense", 1200, ymd(2026, 6, 3)),
+		accountTxn(2, "income", 200, ymd(2026, 6, 4)), // refund
+	}
+
+	balance, _, _ := balanceAt(card, snapshots, txns, ymd(2026, 6, 30))
+	assert.Equal(t, 9000.0, balance)
+}
+
+func TestBalanceAt_Transfers(t *testing.T) {
+	bank := &models.Account{ID: 1, Type: models.AccountTypeBank}
+	card := &models.Account{ID: 2, Type: models.AccountTypeCreditCard}
+	bankID, cardID := uint(1), uint(2)
+	payment := models.Transaction{Type: "transfer", AccountID: &bankID, ToAccountID: &cardID, Amount: 5000, TransactionDate: ymd(2026, 6, 10)}
+
+	balance, _, _ := balanceAt(bank, []models.AccountBalance{{Date: ymd(2026, 5, 31), Balance: 30000}}, []models.Transaction{payment}, ymd(2026, 6, 30))
+	assert.Equal(t, 25000.0, balance)
+
+	// Paying the card reduces the amount owed
+	balance, _, _ = balanceAt(card, []models.AccountBalance{{Date: ymd(2026, 5, 31), Balance: 8000}}, []models.Transaction{payment}, ymd(2026, 6, 30))
+	assert.Equal(t, 3000.0, balance)
+}
+
+func TestNetWorthService_Reconcile(t *testing.T) {
+	balanceRepo := new(mockBalanceRepo)
+	accountRepo := new(mockAccountRepo)
+	txnRepo := new(mockTransactionRepo)
+	svc := newTestNetWorthService(balanceRepo, accountRepo, txnRepo, ymd(2026, 6, 30))
+
+	accountRepo.On("GetByID", uint(1), uint(1)).Return(&models.Account{ID: 1, Type: models.AccountTypeBank}, nil)
+	balanceRepo.On("ListByAccount", uint(1), uint(1)).Return([]models.AccountBalance{
+		{AccountID: 1, Date: ymd(2026, 6, 30), Balance: 99999, Source: models.BalanceSourceManual},
+		{AccountID: 1, Date: ymd(2026, 5, 31), Balance: 20000},
+	}, nil)
+	txnRepo.On("List", mock.Anything).Return([]models.Transaction{
+		accountTxn(1, "income", 50000, ymd(2026, 6, 5)),
+		accountTxn(1, "expense", 12000, ymd(2026, 6, 10)),
+	}, int64(2), nil)
+	balanceRepo.On("Upsert", mock.MatchedBy(func(b *models.AccountBalance) bool {
+		return b.Balance == 57500 && b.Source == models.BalanceSourceReconciliation && b.Date.Equal(ymd(2026, 6, 30))
+	})).Return(nil)
+
+	actual := 57500.0
+	rec, err := svc.Reconcile(1, 1, models.ReconcileInput{ActualBalance: &actual})
+
+	require.NoError(t, err)
+	// The same-day snapshot is replaced, not used as the base
+	assert.Equal(t, 58000.0, rec.ExpectedBalance)
+	assert.Equal(t, -500.0, *rec.Difference)
+	assert.False(t, rec.Reconciled)
+	assert.Equal(t, 2, rec.TransactionCount)
+	balanceRepo.AssertExpectations(t)
+}
+
+func TestNetWorthService_NetWorth(t *testing.T) {
+	balanceRepo := new(mockBalanceRepo)
+	accountRepo := new(mockAccountRepo)
+	txnRepo := new(mockTransactionRepo)
+	svc := newTestNetWorthService(balanceRepo, accountRepo, txnRepo, ymd(2026, 6, 15))
+
+	accountRepo.On("List", uint(1)).Return([]models.Account{
+		{ID: 1, Name: "Savings", Type: models.AccountTypeBank},
+		{ID: 2, Name: "Visa", Type: models.AccountTypeCreditCard},
+	}, nil)
+	balanceRepo.On("ListByUser", uint(1)).Return([]models.AccountBalance{
+		{AccountID: 1, Date: ymd(2026, 4, 30), Balance: 100000},
+		{AccountID: 2, Date: ymd(2026, 5, 20), Balance: 15000},
+	}, nil)
+	txnRepo.On("List", mock.Anything).Return([]models.Transaction{
+		accountTxn(1, "income", 40000, ymd(2026, 5, 5)),
+		accountTxn(2, "expense", 3000, ymd(2026, 6, 1)),
+		{Type: "expense", Amount: 999, TransactionDate: ymd(2026, 6, 1)}, // no account
+	}, int64(3), nil)
+
+	nw, err := svc.NetWorth(1, 3)
+
+	require.NoError(t, err)
+	require.Len(t, nw.Series, 3)
+	assert.Equal(t, ymd(2026, 4, 30), nw.Series[0].Date)
+	assert.Equal(t, ymd(2026, 5, 31), nw.Series[1].Date)
+	assert.Equal(t, ymd(2026, 6, 15), nw.Series[2].Date)
+
+	assert.Equal(t, 140000.0, nw.Series[1].Assets)
+	assert.Equal(t, 15000.0, nw.Series[1].Liabilities)
+	assert.Equal(t, 125000.0, nw.Series[1].NetWorth)
+
+	assert.Equal(t, 140000.0, nw.Assets)
+	assert.Equal(t, 18000.0, nw.Liabilities)
+	assert.Equal(t, 122000.0, nw.NetWorth)
+	require.Len(t, nw.Accounts, 2)
+	assert.True(t, nw.Accounts[1].Liability)
+	assert.Equal(t, ymd(2026, 5, 20), *nw.Accounts[1].LastSnapshotDate)
+}
+
+func TestNetWorthService_InvalidMonths(t *testing.T) {
+	svc := newTestNetWorthService(nil, nil, nil, time.Now())
+
+	_, err := svc.NetWorth(1, 0)
+	assert.Error(t, err)
+	_, err = svc.NetWorth(1, MaxNetWorthMonths+1)
+	assert.Error(t, err)
+}
@@ -0,0 +1,294 @@
+package services
+
+import (
+	"billing-note/internal/models"
+	"billing-note/internal/repository"
+	"billing-note/pkg/config"
+	"billing-note/pkg/errors"
+	"billing-note/pkg/logger"
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/smtp"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// NotificationChannel delivers a stored notification outside the app
+type NotificationChannel interface {
+	Name() string
+	// Enabled reports whether the user has opted in to this channel
+	Enabled(settings *models.NotificationSettings) bool
+	Send(user *models.User, settings *models.NotificationSettings, notification *models.Notification) error
+}
+
+// NotificationService stores in-app notifications and fans them out to
+// delivery channels the user has enabled
+type NotificationService struct {
+	repo     repository.NotificationRepository
+	userRepo repository.UserRepository
+	channels []NotificationChannel
+	// dispatch runs channel delivery, in the background so callers such as a
+	// transaction save do not wait on slow channels
+	dispatch func(func())
+}
+
+// NewNotificationService creates a notification service with the given delivery
+// channels; nil channels (e.g. unconfigured SMTP) are skipped
+func NewNotificationService(repo repository.NotificationRepository, userRepo repository.UserRepository, channels ...NotificationChannel) *NotificationService {
+	s := &NotificationService{
+		repo:     repo,
+		userRepo: userRepo,
+		dispatch: func(deliver func()) { go deliver() },
+	}
+	for _, ch := range channels {
+		if ch != nil {
+			s.channels = append(s.channels, ch)
+		}
+	}
+	return s
+}
+
+// Notify stores an in-app notification and delivers it through enabled channels
+// in the background. Channel failures are logged and do not fail the call.
+func (s *NotificationService) Notify(userID uint, notificationType, title, message string, data interface{}) (*models.Notification, error) {
+	notification := &models.Notification{
+		UserID:  userID,
+		Type:    notificationType,
+		Title:   title,
+		Message: message,
+	}
+	if data != nil {
+		raw, err := json.Marshal(data)
+		if err != nil {
+			return nil, errors.NewInternalError("Failed to encode notification data", err)
+		}
+		notification.Data = raw
+	}
+	if err := s.repo.Create(notification); err != nil {
+		return nil, errors.NewDBError("create notification", err)
+	}
+
+	if len(s.channels) > 0 {
+		delivered := *notification
+		s.dispatch(func() { s.deliver(userID, &delivered) })
+	}
+	return notification, nil
+}
+
+// deliver sends a stored notification through the channels the user enabled
+func (s *NotificationService) deliver(userID uint, notification *models.Notification) {
+	log := logger.ServiceLog("NotificationService", "deliver")
+
+	settings, err := s.repo.GetSettings(userID)
+	if err != nil {
+		// No settings saved: in-app only
+		return
+	}
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		log.WithError(err).Warn("Failed to load user for notification delivery")
+		return
+	}
+
+	for _, ch := range s.channels {
+		if !ch.Enabled(settings) {
+			continue
+		}
+		if err := ch.Send(user, settings, notification); err != nil {
+			log.WithFields(logger.Fields{
+				"user_id":         userID,
+				"channel":         ch.Name(),
+				"notification_id": notification.ID,
+				"error":           err.Error(),
+			}).Warn("Notification delivery failed")
+		}
+	}
+}
+
+// List returns the user's notifications, newest first
+func (s *NotificationService) List(userID uint, unreadOnly bool, limit int) ([]models.Notification, int64, error) {
+	if limit <= 0 || limit > 100 {
+		limit = 50
+	}
+	notifications, err := s.repo.List(userID, unreadOnly, limit)
+	if err != nil {
+		return nil, 0, errors.NewDBError("list notifications", err)
+	}
+	unread, err := s.repo.CountUnread(userID)
+	if err != nil {
+		return nil, 0, errors.NewDBError("count unread notifications", err)
+	}
+	return notifications, unread, nil
+}
+
+// MarkRead marks one notification as read
+func (s *NotificationService) MarkRead(userID, id uint) error {
+	if err := s.repo.MarkRead(userID, id); err != nil {
+		return errors.NewNotFoundError("Notification", id)
+	}
+	return nil
+}
+
+// MarkAllRead marks all of the user's notifications as read
+func (s *NotificationService) MarkAllRead(userID uint) error {
+	if err := s.repo.MarkAllRead(userID); err != nil {
+		return errors.NewDBError("mark notifications read", err)
+	}
+	return nil
+}
+
+// GetSettings returns the user's delivery settings, or defaults if none are saved
+func (s *NotificationService) GetSettings(userID uint) *models.NotificationSettings {
+	settings, err := s.repo.GetSettings(userID)
+	if err != nil {
+		return &models.NotificationSettings{UserID: userID}
+	}
+	return settings
+}
+
+// UpdateSettings applies a partial update to the user's delivery settings
+func (s *NotificationService) UpdateSettings(userID uint, input models.NotificationSettingsInput) (*models.NotificationSettings, error) {
+	settings := s.GetSettings(userID)
+
+	if input.EmailEnabled != nil {
+		settings.EmailEnabled = *input.EmailEnabled
+	}
+	if input.WebhookURL != nil {
+		webhookURL := strings.TrimSpace(*input.WebhookURL)
+		if webhookURL != "" {
+			u, err := url.Parse(webhookURL)
+			if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				return nil, errors.NewInvalidInputError("webhook_url", "must be an http(s) URL")
+			}
+		}
+		settings.WebhookURL = webhookURL
+	}
+	if input.WebhookSecret != nil {
+		settings.WebhookSecret = *input.WebhookSecret
+	}
+	if input.WebhookEnabled != nil {
+		settings.WebhookEnabled = *input.WebhookEnabled
+	}
+	if settings.WebhookEnabled && settings.WebhookURL == "" {
+		return nil, errors.NewInvalidInputError("webhook_url", "is required to enable webhook delivery")
+	}
+
+	if err := s.repo.SaveSettings(settings); err != nil {
+		return nil, errors.NewDBError("save notification settings", err)
+	}
+	return settings, nil
+}
+
+// --- Channels ---
+
+// smtpChannel delivers notifications by email
+type smtpChannel struct {
+	cfg  config.SMTPConfig
+	send func(addr string, a smtp.Auth, from string, to []string, msg []byte) error
+}
+
+// NewSMTPChannel creates an email delivery channel, or nil if SMTP is not configured
+func NewSMTPChannel(cfg config.SMTPConfig) NotificationChannel {
+	if cfg.Host == "" {
+		return nil
+	}
+	return &smtpChannel{cfg: cfg, send: smtp.SendMail}
+}
+
+func (c *smtpChannel) Name() string { return "email" }
+
+func (c *smtpChannel) Enabled(settings *models.NotificationSettings) bool {
+	return settings.EmailEnabled
+}
+
+func (c *smtpChannel) Send(user *models.User, _ *models.NotificationSettings, notification *models.Notification) error {
+	var auth smtp.Auth
+	if c.cfg.Username != "" {
+		auth = smtp.PlainAuth("", c.cfg.Username, c.cfg.Password, c.cfg.Host)
+	}
+
+	addr := net.JoinHostPort(c.cfg.Host, c.cfg.Port)
+	msg := composeEmail(c.cfg.From, user.Email, notification.Title, notification.Message)
+	return c.send(addr, auth, c.cfg.From, []string{user.Email}, msg)
+}
+
+// webhookChannel POSTs notifications as JSON to a user-configured URL
+type webhookChannel struct {
+	client *http.Client
+}
+
+// NewWebhookChannel creates a generic webhook delivery channel. Webhook URLs
+// come from users, so only public addresses may be dialed; the check is made
+// on the resolved address of every connection, redirects included.
+func NewWebhookChannel() NotificationChannel {
+	dialer := &net.Dialer{Timeout: 5 * time.Second, Control: publicAddressOnly}
+	return &webhookChannel{client: &http.Client{
+		Timeout:   10 * time.Second,
+		Transport: &http.Transport{DialContext: dialer.DialContext},
+	}}
+}
+
+// cgnatRange is the shared address space (RFC 6598), used by some cloud
+// metadata services
+var cgnatRange = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// publicAddressOnly refuses connections to loopback, private, link-local
+// (including cloud metadata), shared, multicast and unspecified addresses
+func publicAddressOnly(_, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() || ip.IsMulticast() ||
+		ip.IsUnspecified() || cgnatRange.Contains(ip) {
+		return fmt.Errorf("webhook address %s is not allowed", host)
+	}
+	return nil
+}
+
+// WebhookSignatureHeader carries the hex HMAC-SHA256 of the body when the user set a secret
+const WebhookSignatureHeader = "X-BillingNote-Signature"
+
+func (c *webhookChannel) Name() string { return "webhook" }
+
+func (c *webhookChannel) Enabled(settings *models.NotificationSettings) bool {
+	return settings.WebhookEnabled && settings.WebhookURL != ""
+}
+
+func (c *webhookChannel) Send(_ *models.User, settings *models.NotificationSettings, notification *models.Notification) error {
+	body, err := json.Marshal(notification)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, settings.WebhookURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if settings.WebhookSecret != "" {
+		mac := hmac.New(sha256.New, []byte(settings.WebhookSecret))
+		mac.Write(body)
+		req.Header.Set(WebhookSignatureHeader, hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
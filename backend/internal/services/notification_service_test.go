@@ -0,0 +1,234 @@
+package services
+
+import (
+	"billing-note/internal/models"
+	"billing-note/pkg/config"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/smtp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// --- Mock Notification Repository ---
+
+type mockNotificationRepo struct {
+	mock.Mock
+}
+
+func (m *mockNotificationRepo) Create(notification *models.Notification) error {
+	args := m.Called(notification)
+	return args.Error(0)
+}
+
+func (m *mockNotificationRepo) List(userID uint, unreadOnly bool, limit int) ([]models.Notification, error) {
+	args := m.Called(userID, unreadOnly, limit)
+	return args.Get(0).([]models.Notification), args.Error(1)
+}
+
+func (m *mockNotificationRepo) CountUnread(userID uint) (int64, error) {
+	args := m.Called(userID)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *mockNotificationRepo) MarkRead(userID, id uint) error {
+	args := m.Called(userID, id)
+	return args.Error(0)
+}
+
+func (m *mockNotificationRepo) MarkAllRead(userID uint) error {
+	args := m.Called(userID)
+	return args.Error(0)
+}
+
+func (m *mockNotificationRepo) GetSettings(userID uint) (*models.NotificationSettings, error) {
+	args := m.Called(userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.NotificationSettings), args.Error(1)
+}
+
+func (m *mockNotificationRepo) SaveSettings(settings *models.NotificationSettings) error {
+	args := m.Called(settings)
+	return args.Error(0)
+}
+
+// --- Fake channel ---
+
+type fakeChannel struct {
+	sent []*models.Notification
+	err  error
+}
+
+func (c *fakeChannel) Name() string { return "fake" }
+
+func (c *fakeChannel) Enabled(settings *models.NotificationSettings) bool {
+	return settings.EmailEnabled
+}
+
+func (c *fakeChannel) Send(_ *models.User, _ *models.NotificationSettings, n *models.Notification) error {
+	c.sent = append(c.sent, n)
+	return c.err
+}
+
+// --- Tests ---
+
+func TestNotificationService_Notify_DeliversToEnabledChannels(t *testing.T) {
+	repo := new(mockNotificationRepo)
+	userRepo := new(MockUserRepository)
+	ch := &fakeChannel{err: errors.New("smtp down")}
+	svc := NewNotificationService(repo, userRepo, ch, nil)
+	svc.dispatch = func(deliver func()) { deliver() }
+
+	repo.On("Create", mock.AnythingOfType("*models.Notification")).Return(nil)
+	repo.On("GetSettings", uint(1)).Return(&models.NotificationSettings{UserID: 1, EmailEnabled: true}, nil)
+	userRepo.On("FindByID", uint(1)).Return(&models.User{ID: 1, Email: "a@example.com"}, nil)
+
+	n, err := svc.Notify(1, models.NotificationBudgetAlert, "Food budget reached 80%", "msg", map[string]int{"threshold": 80})
+
+	require.NoError(t, err) // channel failures do not fail Notify
+	assert.JSONEq(t, `{"threshold":80}`, string(n.Data))
+	assert.Len(t, ch.sent, 1)
+}
+
+func TestNotificationService_Notify_NoSettingsInAppOnly(t *testing.T) {
+	repo := new(mockNotificationRepo)
+	ch := &fakeChannel{}
+	svc := NewNotificationService(repo, new(MockUserRepository), ch)
+	svc.dispatch = func(deliver func()) { deliver() }
+
+	repo.On("Create", mock.AnythingOfType("*models.Notification")).Return(nil)
+	repo.On("GetSettings", uint(1)).Return(nil, gorm.ErrRecordNotFound)
+
+	_, err := svc.Notify(1, models.NotificationBudgetAlert, "title", "msg", nil)
+
+	require.NoError(t, err)
+	assert.Empty(t, ch.sent)
+	repo.AssertCalled(t, "Create", mock.Anything)
+}
+
+func TestNotificationService_Notify_DoesNotWaitForDelivery(t *testing.T) {
+	repo := new(mockNotificationRepo)
+	ch := &fakeChannel{}
+	svc := NewNotificationService(repo, new(MockUserRepository), ch)
+	var queued []func()
+	svc.dispatch = func(deliver func()) { queued = append(queued, deliver) }
+
+	repo.On("Create", mock.AnythingOfType("*models.Notification")).Return(nil)
+
+	_, err := svc.Notify(1, models.NotificationBudgetAlert, "title", "msg", nil)
+
+	require.NoError(t, err)
+	assert.Len(t, queued, 1)
+	repo.AssertNotCalled(t, "GetSettings", mock.Anything)
+}
+
+func TestNotificationService_UpdateSettings_Validation(t *testing.T) {
+	repo := new(mockNotificationRepo)
+	svc := NewNotificationService(repo, new(MockUserRepository))
+
+	repo.On("GetSettings", uint(1)).Return(nil, gorm.ErrRecordNotFound)
+
+	enabled := true
+	_, err := svc.UpdateSettings(1, models.NotificationSettingsInput{WebhookEnabled: &enabled})
+	assert.Error(t, err)
+
+	badURL := "ftp://example.com/hook"
+	_, err = svc.UpdateSettings(1, models.NotificationSettingsInput{WebhookURL: &badURL})
+	assert.Error(t, err)
+
+	repo.AssertNotCalled(t, "SaveSettings", mock.Anything)
+}
+
+func TestWebhookChannel_SignsPayload(t *testing.T) {
+	var gotBody []byte
+	var gotSig string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotBody, _ = io.ReadAll(r.Body)
+		gotSig = r.Header.Get(WebhookSignatureHeader)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	// The test server listens on loopback, which the real channel refuses
+	ch := &webhookChannel{client: server.Client()}
+	settings := &models.NotificationSettings{WebhookEnabled: true, WebhookURL: server.URL, WebhookSecret: "s3cret"}
+	require.True(t, ch.Enabled(settings))
+
+	err := ch.Send(&models.User{ID: 1}, settings, &models.Notification{ID: 9, Title: "hello"})
+	require.NoError(t, err)
+
+	mac := hmac.New(sha256.New, []byte("s3cret"))
+	mac.Write(gotBody)
+	assert.Equal(t, hex.EncodeToString(mac.Sum(nil)), gotSig)
+	assert.Contains(t, string(gotBody), `"title":"hello"`)
+}
+
+func TestWebhookChannel_ErrorStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	ch := &webhookChannel{client: server.Client()}
+	err := ch.Send(&models.User{}, &models.NotificationSettings{WebhookURL: server.URL}, &models.Notification{})
+	assert.Error(t, err)
+}
+
+func TestWebhookChannel_RefusesInternalAddresses(t *testing.T) {
+	called := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	defer server.Close()
+
+	err := NewWebhookChannel().Send(&models.User{}, &models.NotificationSettings{WebhookURL: server.URL}, &models.Notification{})
+	assert.Error(t, err)
+	assert.False(t, called)
+}
+
+func TestPublicAddressOnly(t *testing.T) {
+	refused := []string{
+		"127.0.0.1:80", "[::1]:80", "10.0.0.5:443", "172.16.3.4:443", "192.168.1.1:80",
+		"169.254.169.254:80", "100.100.100.200:80", "0.0.0.0:80", "[fd00:ec2::254]:80", "[fe80::1]:80",
+		"[::ffff:127.0.0.1]:80",
+	}
+	for _, address := range refused {
+		assert.Error(t, publicAddressOnly("tcp", address, nil), address)
+	}
+
+	for _, address := range []string{"93.184.216.34:443", "[2606:2800:220:1::]:443"} {
+		assert.NoError(t, publicAddressOnly("tcp", address, nil), address)
+	}
+}
+
+func TestSMTPChannel(t *testing.T) {
+	assert.Nil(t, NewSMTPChannel(config.SMTPConfig{}))
+
+	ch := NewSMTPChannel(config.SMTPConfig{Host: "smtp.example.com", Port: "587", From: "noreply@example.com"}).(*smtpChannel)
+	var gotAddr string
+	var gotMsg string
+	ch.send = func(addr string, _ smtp.Auth, _ string, to []string, msg []byte) error {
+		gotAddr = addr
+		gotMsg = string(msg)
+		assert.Equal(t, []string{"a@example.com"}, to)
+		return nil
+	}
+
+	err := ch.Send(&models.User{Email: "a@example.com"}, &models.NotificationSettings{}, &models.Notification{Title: "餐飲 budget reached 80%", Message: "body"})
+	require.NoError(t, err)
+	assert.Equal(t, "smtp.example.com:587", gotAddr)
+	assert.True(t, strings.Contains(gotMsg, "Subject: =?UTF-8?q?"))
+	assert.True(t, strings.HasSuffix(gotMsg, "body\r\n"))
+}
@@ -154,6 +154,12 @@ func (s *PDFPasswordService) GetPasswords(userID uint) ([]models.PDFPasswordResp
 
 // GetDecryptedPasswords returns decrypted passwords for PDF parsing
 func (s *PDFPasswordService) GetDecryptedPasswords(userID uint) ([]string, error) {
+	return s.GetDecryptedPasswordsPreferring(userID, 0)
+}
+
+// GetDecryptedPasswordsPreferring returns decrypted passwords with the password at
+// the given priority moved to the front. A priority of 0 keeps the stored order.
+func (s *PDFPasswordService) GetDecryptedPasswordsPreferring(userID uint, preferredPriority int) ([]string, error) {
 	log := logger.ServiceLog("PDFPasswordService", "GetDecryptedPasswords")
 
 	log.WithField("user_id", userID).Debug("Fetching and decrypting PDF passwords")
@@ -186,7 +192,11 @@ func (s *PDFPasswordService) GetDecryptedPasswords(userID uint) ([]string, error
 			failedCount++
 			continue
 		}
-		decrypted = append(decrypted, plaintext)
+		if preferredPriority > 0 && p.Priority == preferredPriority {
+			decrypted = append([]string{plaintext}, decrypted...)
+		} else {
+			decrypted = append(decrypted, plaintext)
+		}
 		decryptedCount++
 	}
 
@@ -0,0 +1,278 @@
+package services
+
+import (
+	"billing-note/internal/models"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Recurring charge detection thresholds
+const (
+	minRecurringMonths       = 3   // consecutive months a charge must appear in
+	recurringAmountTolerance = 0.1 // max relative deviation from the mean amount
+	recurringDayTolerance    = 4   // max days from the usual day of month
+	recurringStaleDays       = 45  // charges not seen for longer are considered cancelled
+)
+
+// RecurringCharge is an expense (or income) that repeats monthly, detected
+// from history
+type RecurringCharge struct {
+	Key         string    `json:"key"`
+	Description string    `json:"description"`
+	CategoryID  *uint     `json:"category_id,omitempty"`
+	AccountID   *uint     `json:"account_id,omitempty"` // account of the latest charge
+	Amount      float64   `json:"amount"`               // mean of the detected charges
+	StdDev      float64   `json:"std_dev"`              // standard deviation of the amounts
+	DayOfMonth  int       `json:"day_of_month"`
+	Occurrences int       `json:"occurrences"`
+	LastDate    time.Time `json:"last_date"`
+	NextDate    time.Time `json:"next_date"`
+}
+
+// Installment is a card installment plan with payments still to come
+type Installment struct {
+	Key         string      `json:"key"`
+	Description string      `json:"description"`
+	CategoryID  *uint       `json:"category_id,omitempty"`
+	AccountID   *uint       `json:"account_id,omitempty"` // card the payments are charged to
+	Amount      float64     `json:"amount"`               // per payment
+	Paid        int         `json:"paid"`
+	Total       int         `json:"total"`
+	LastDate    time.Time   `json:"last_date"`
+	Upcoming    []time.Time `json:"upcoming"`
+}
+
+// Installment counters as printed on card statements, e.g. "分期 3/12",
+// "第3期/共12期" or "3/12期"
+var installmentPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`第\s*(\d{1,2})\s*期\s*/?\s*共\s*(\d{1,2})\s*期`),
+	regexp.MustCompile(`分期\D{0,6}?(\d{1,2})\s*/\s*(\d{1,2})`),
+	regexp.MustCompile(`(\d{1,2})\s*/\s*(\d{1,2})\s*期`),
+}
+
+// parseInstallment extracts the installment counter from a description and
+// returns the description without it
+func parseInstallment(description string) (paid, total int, rest string, ok bool) {
+	for _, re := range installmentPatterns {
+		loc := re.FindStringSubmatchIndex(description)
+		if loc == nil {
+			continue
+		}
+		paid, _ = strconv.Atoi(description[loc[2]:loc[3]])
+		total, _ = strconv.Atoi(description[loc[4]:loc[5]])
+		if paid < 1 || total < 2 || paid > total {
+			continue
+		}
+		rest = strings.TrimSpace(description[:loc[0]] + " " + description[loc[1]:])
+		return paid, total, rest, true
+	}
+	return 0, 0, "", false
+}
+
+// DetectInstallments finds installment plans in the expenses and projects
+// their remaining monthly payments from today onward
+func DetectInstallments(transactions []models.Transaction, today time.Time) []Installment {
+	latest := make(map[string]Installment)
+	for _, txn := range transactions {
+		if txn.Type != "expense" {
+			continue
+		}
+		paid, total, rest, ok := parseInstallment(txn.Description)
+		if !ok {
+			continue
+		}
+		key := recurringKey(rest) + "|" + strconv.Itoa(total) + "|" + strconv.FormatFloat(math.Round(txn.Amount), 'f', 0, 64)
+		if existing, found := latest[key]; found && existing.Paid >= paid {
+			continue
+		}
+		latest[key] = Installment{
+			Key:         key,
+			Description: rest,
+			CategoryID:  txn.CategoryID,
+			AccountID:   txn.AccountID,
+			Amount:      txn.Amount,
+			Paid:        paid,
+			Total:       total,
+			LastDate:    dateOf(txn.TransactionDate, time.UTC),
+		}
+	}
+
+	result := make([]Installment, 0, len(latest))
+	for _, inst := range latest {
+		for n := 1; n <= inst.Total-inst.Paid; n++ {
+			due := addMonthsClamped(inst.LastDate, n, inst.LastDate.Day())
+			if !due.Before(today) {
+				inst.Upcoming = append(inst.Upcoming, due)
+			}
+		}
+		if len(inst.Upcoming) > 0 {
+			result = append(result, inst)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Upcoming[0].Before(result[j].Upcoming[0]) })
+	return result
+}
+
+// DetectRecurringCharges finds expenses charged every month for at least
+// minRecurringMonths consecutive months at a similar amount and day, such as
+// subscriptions. Installments are excluded. NextDate is the first expected
+// charge on or after today.
+func DetectRecurringCharges(transactions []models.Transaction, today time.Time) []RecurringCharge {
+	return detectRecurring(transactions, today, "expense")
+}
+
+// DetectRecurringIncome finds monthly income such as salary, using the same
+// rules as DetectRecurringCharges
+func DetectRecurringIncome(transactions []models.Transaction, today time.Time) []RecurringCharge {
+	return detectRecurring(transactions, today, "income")
+}
+
+func detectRecurring(transactions []models.Transaction, today time.Time, txType string) []RecurringCharge {
+	groups := make(map[string][]models.Transaction)
+	for _, txn := range transactions {
+		if txn.Type != txType {
+			continue
+		}
+		if _, _, _, ok := parseInstallment(txn.Description); ok {
+			continue
+		}
+		key := transactionKey(txn)
+		if key == "" {
+			continue
+		}
+		groups[key] = append(groups[key], txn)
+	}
+
+	var result []RecurringCharge
+	for key, txns := range groups {
+		if charge, ok := detectMonthly(key, txns, today); ok {
+			result = append(result, charge)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if !result[i].NextDate.Equal(result[j].NextDate) {
+			return result[i].NextDate.Before(result[j].NextDate)
+		}
+		return result[i].Key < result[j].Key
+	})
+	return result
+}
+
+// detectMonthly checks whether a group of same-named expenses forms a
+// monthly series ending recently
+func detectMonthly(key string, txns []models.Transaction, today time.Time) (RecurringCharge, bool) {
+	// One charge per month, newest first
+	sort.Slice(txns, func(i, j int) bool { return txns[i].TransactionDate.After(txns[j].TransactionDate) })
+	var series []models.Transaction
+	seen := make(map[int]bool)
+	for _, txn := range txns {
+		d := dateOf(txn.TransactionDate, time.UTC)
+		month := d.Year()*12 + int(d.Month())
+		if seen[month] {
+			continue
+		}
+		if len(series) > 0 {
+			prev := dateOf(series[len(series)-1].TransactionDate, time.UTC)
+			if prev.Year()*12+int(prev.Month())-month != 1 {
+				break // series must be consecutive months
+			}
+		}
+		seen[month] = true
+		series = append(series, txn)
+	}
+	if len(series) < minRecurringMonths {
+		return RecurringCharge{}, false
+	}
+
+	last := dateOf(series[0].TransactionDate, time.UTC)
+	if today.Sub(last) > recurringStaleDays*24*time.Hour {
+		return RecurringCharge{}, false
+	}
+
+	total := 0.0
+	days := make([]int, 0, len(series))
+	for _, txn := range series {
+		total += txn.Amount
+		days = append(days, dateOf(txn.TransactionDate, time.UTC).Day())
+	}
+	mean := total / float64(len(series))
+	sort.Ints(days)
+	day := days[len(days)/2]
+	variance := 0.0
+	for _, txn := range series {
+		if mean > 0 && math.Abs(txn.Amount-mean)/mean > recurringAmountTolerance {
+			return RecurringCharge{}, false
+		}
+		variance += (txn.Amount - mean) * (txn.Amount - mean)
+	}
+	if day-days[0] > recurringDayTolerance || days[len(days)-1]-day > recurringDayTolerance {
+		return RecurringCharge{}, false
+	}
+
+	next := addMonthsClamped(last, 1, day)
+	for next.Before(today) {
+		next = addMonthsClamped(next, 1, day)
+	}
+
+	description := series[0].Merchant
+	if description == "" {
+		description = series[0].Description
+	}
+	return RecurringCharge{
+		Key:         key,
+		Description: description,
+		CategoryID:  series[0].CategoryID,
+		AccountID:   series[0].AccountID,
+		Amount:      math.Round(mean*100) / 100,
+		StdDev:      math.Round(math.Sqrt(variance/float64(len(series)))*100) / 100,
+		DayOfMonth:  day,
+		Occurrences: len(series),
+		LastDate:    last,
+		NextDate:    next,
+	}, true
+}
+
+// transactionKey returns the grouping key used for recurring detection
+func transactionKey(txn models.Transaction) string {
+	if txn.Merchant != "" {
+		return recurringKey(txn.Merchant)
+	}
+	return recurringKey(txn.Description)
+}
+
+// recurringKey normalizes a description for grouping: lower case, without
+// digits, punctuation or extra spaces, so "NETFLIX.COM 12/05" and
+// "Netflix.com 01/05" match
+func recurringKey(s string) string {
+	var b strings.Builder
+	space := false
+	for _, r := range strings.ToLower(s) {
+		switch {
+		case unicode.IsLetter(r):
+			if space && b.Len() > 0 {
+				b.WriteByte(' ')
+			}
+			space = false
+			b.WriteRune(r)
+		default:
+			space = true
+		}
+	}
+	return b.String()
+}
+
+// addMonthsClamped returns the date n months after t on the given day,
+// clamped to the end of shorter months
+func addMonthsClamped(t time.Time, n, day int) time.Time {
+	first := time.Date(t.Year(), t.Month()+time.Month(n), 1, 0, 0, 0, 0, time.UTC)
+	lastDay := first.AddDate(0, 1, -1).Day()
+	if day > lastDay {
+		day = lastDay
+	}
+	return first.AddDate(0, 0, day-1)
+}
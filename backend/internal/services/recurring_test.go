@@ -0,0 +1,92 @@
+package services
+
+import (
+	"billing-note/internal/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func expense(desc string, amount float64, d time.Time) models.Transaction {
+	return models.Transaction{Type: "expense", Description: desc, Amount: amount, TransactionDate: d}
+}
+
+func TestParseInstallment(t *testing.T) {
+	tests := []struct {
+		desc  string
+		paid  int
+		total int
+		rest  string
+		ok    bool
+	}{
+		{"燦坤 分期 3/12", 3, 12, "燦坤", true},
+		{"APPLE STORE 第2期/共6期", 2, 6, "APPLE STORE", true},
+		{"家樂福 05/24期", 5, 24, "家樂福", true},
+		{"NETFLIX.COM", 0, 0, "", false},
+		{"分期 13/12", 0, 0, "", false},
+	}
+	for _, tt := range tests {
+		paid, total, rest, ok := parseInstallment(tt.desc)
+		assert.Equal(t, tt.ok, ok, tt.desc)
+		assert.Equal(t, tt.paid, paid, tt.desc)
+		assert.Equal(t, tt.total, total, tt.desc)
+		assert.Equal(t, tt.rest, rest, tt.desc)
+	}
+}
+
+func TestDetectInstallments(t *testing.T) {
+	today := ymd(2025, 12, 1)
+	txns := []models.Transaction{
+		expense("燦坤 分期 2/4", 1500, ymd(2025, 10, 5)),
+		expense("燦坤 分期 3/4", 1500, ymd(2025, 11, 5)),
+		expense("舊分期 6/6", 800, ymd(2025, 11, 1)), // finished
+	}
+
+	result := DetectInstallments(txns, today)
+
+	require.Len(t, result, 1)
+	assert.Equal(t, 3, result[0].Paid)
+	assert.Equal(t, []time.Time{ymd(2025, 12, 5)}, result[0].Upcoming)
+}
+
+func TestDetectRecurringCharges(t *testing.T) {
+	today := ymd(2025, 12, 10)
+	txns := []models.Transaction{
+		expense("NETFLIX.COM 09/15", 390, ymd(2025, 9, 15)),
+		expense("Netflix.com 10/14", 390, ymd(2025, 10, 14)),
+		expense("NETFLIX.COM 11/16", 390, ymd(2025, 11, 16)),
+		// Irregular amounts
+		expense("全聯", 300, ymd(2025, 9, 3)),
+		expense("全聯", 1200, ymd(2025, 10, 3)),
+		expense("全聯", 650, ymd(2025, 11, 3)),
+		// Only two months
+		expense("SPOTIFY", 149, ymd(2025, 10, 1)),
+		expense("SPOTIFY", 149, ymd(2025, 11, 1)),
+	}
+
+	result := DetectRecurringCharges(txns, today)
+
+	require.Len(t, result, 1)
+	assert.Equal(t, "netflix com", result[0].Key)
+	assert.Equal(t, 390.0, result[0].Amount)
+	assert.Equal(t, 15, result[0].DayOfMonth)
+	assert.Equal(t, ymd(2025, 12, 15), result[0].NextDate)
+}
+
+func TestDetectRecurringCharges_Stale(t *testing.T) {
+	txns := []models.Transaction{
+		expense("GYM", 999, ymd(2025, 6, 1)),
+		expense("GYM", 999, ymd(2025, 7, 1)),
+		expense("GYM", 999, ymd(2025, 8, 1)),
+	}
+
+	assert.Empty(t, DetectRecurringCharges(txns, ymd(2025, 12, 1)))
+}
+
+func TestAddMonthsClamped(t *testing.T) {
+	assert.Equal(t, ymd(2025, 2, 28), addMonthsClamped(ymd(2025, 1, 31), 1, 31))
+	assert.Equal(t, ymd(2025, 3, 31), addMonthsClamped(ymd(2025, 2, 28), 1, 31))
+	assert.Equal(t, ymd(2026, 1, 15), addMonthsClamped(ymd(2025, 12, 15), 1, 15))
+}
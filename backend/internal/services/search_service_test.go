@@ -13,7 +13,7 @@ import (
 
 func TestListTransactions_WithSearchQuery(t *testing.T) {
 	txnRepo := new(mockTransactionRepo)
-	svc := NewTransactionService(txnRepo)
+	svc := NewTransactionService(txnRepo, nil)
 
 	transactions := []models.Transaction{
 		{
@@ -46,7 +46,7 @@ func TestListTransactions_WithSearchQuery(t *testing.T) {
 
 func TestListTransactions_WithTags(t *testing.T) {
 	txnRepo := new(mockTransactionRepo)
-	svc := NewTransactionService(txnRepo)
+	svc := NewTransactionService(txnRepo, nil)
 
 	transactions := []models.Transaction{
 		{
@@ -81,7 +81,7 @@ func TestListTransactions_WithTags(t *testing.T) {
 
 func TestListTransactions_WithAmountRange(t *testing.T) {
 	txnRepo := new(mockTransactionRepo)
-	svc := NewTransactionService(txnRepo)
+	svc := NewTransactionService(txnRepo, nil)
 
 	transactions := []models.Transaction{
 		{
@@ -117,7 +117,7 @@ func TestListTransactions_WithAmountRange(t *testing.T) {
 
 func TestCreateTransaction_WithTags(t *testing.T) {
 	txnRepo := new(mockTransactionRepo)
-	svc := NewTransactionService(txnRepo)
+	svc := NewTransactionService(txnRepo, nil)
 
 	txnRepo.On("Create", mock.AnythingOfType("*models.Transaction")).
 		Return(nil).
@@ -143,7 +143,7 @@ func TestCreateTransaction_WithTags(t *testing.T) {
 
 func TestUpdateTransaction_WithTags(t *testing.T) {
 	txnRepo := new(mockTransactionRepo)
-	svc := NewTransactionService(txnRepo)
+	svc := NewTransactionService(txnRepo, nil)
 
 	existing := &models.Transaction{
 		ID:              1,
@@ -176,7 +176,7 @@ func TestUpdateTransaction_WithTags(t *testing.T) {
 
 func TestListTransactions_MultiConditionFilter(t *testing.T) {
 	txnRepo := new(mockTransactionRepo)
-	svc := NewTransactionService(txnRepo)
+	svc := NewTransactionService(txnRepo, nil)
 
 	startDate := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
 	endDate := time.Date(2026, 1, 31, 0, 0, 0, 0, time.UTC)
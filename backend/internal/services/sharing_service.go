@@ -7,66 +7,87 @@ import (
 	"errors"
 	"fmt"
 	"math/big"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Pairing code defaults and limits on redemption attempts
+const (
+	defaultPairingCodeTTL  = 7 * 24 * time.Hour
+	defaultPairingCodeUses = 1
+	maxPairingFailures     = 5
+	pairingFailureWindow   = 15 * time.Minute
 )
 
+// ErrPairingRateLimited is returned when a user has made too many failed
+// pairing attempts recently
+var ErrPairingRateLimited = errors.New("too many failed pairing attempts, please try again later")
+
 type SharingService struct {
 	repo repository.SharingRepository
+	now  func() time.Time
 }
 
 func NewSharingService(repo repository.SharingRepository) *SharingService {
-	return &SharingService{repo: repo}
+	return &SharingService{repo: repo, now: time.Now}
 }
 
-// GetOrCreateCode returns the user's existing pairing code or generates a new one.
+// GetOrCreateCode returns the user's pairing code, generating a new one with
+// the default expiry and use limit when there is none or it can no longer be used.
 func (s *SharingService) GetOrCreateCode(userID uint) (*models.UserPairingCode, error) {
 	existing, err := s.repo.GetPairingCode(userID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get pairing code: %w", err)
 	}
-	if existing != nil {
+	if existing != nil && existing.Usable(s.now()) {
 		return existing, nil
 	}
 
-	code, err := s.generateCode()
-	if err != nil {
-		return nil, fmt.Errorf("failed to generate code: %w", err)
-	}
+	return s.issueCode(userID, existing, models.PairingCodeInput{})
+}
 
-	pairingCode := &models.UserPairingCode{
-		UserID: userID,
-		Code:   code,
+// RegenerateCode replaces the user's pairing code, invalidating the old one.
+func (s *SharingService) RegenerateCode(userID uint, input models.PairingCodeInput) (*models.UserPairingCode, error) {
+	if input.ExpiresInHours < 0 || input.ExpiresInHours > 720 {
+		return nil, errors.New("expires_in_hours must be between 1 and 720")
 	}
-	if err := s.repo.SavePairingCode(pairingCode); err != nil {
-		return nil, fmt.Errorf("failed to save pairing code: %w", err)
+	if input.MaxUses < 0 || input.MaxUses > 20 {
+		return nil, errors.New("max_uses must be between 1 and 20")
 	}
 
-	return pairingCode, nil
-}
-
-// RegenerateCode creates a new pairing code for the user.
-func (s *SharingService) RegenerateCode(userID uint) (*models.UserPairingCode, error) {
 	existing, err := s.repo.GetPairingCode(userID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get pairing code: %w", err)
 	}
 
+	return s.issueCode(userID, existing, input)
+}
+
+// issueCode saves a fresh code for the user, reusing the existing row if any
+func (s *SharingService) issueCode(userID uint, existing *models.UserPairingCode, input models.PairingCodeInput) (*models.UserPairingCode, error) {
 	code, err := s.generateCode()
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate code: %w", err)
 	}
 
-	if existing != nil {
-		existing.Code = code
-		if err := s.repo.SavePairingCode(existing); err != nil {
-			return nil, fmt.Errorf("failed to save pairing code: %w", err)
-		}
-		return existing, nil
+	ttl := defaultPairingCodeTTL
+	if input.ExpiresInHours > 0 {
+		ttl = time.Duration(input.ExpiresInHours) * time.Hour
+	}
+	maxUses := defaultPairingCodeUses
+	if input.MaxUses > 0 {
+		maxUses = input.MaxUses
 	}
 
-	pairingCode := &models.UserPairingCode{
-		UserID: userID,
-		Code:   code,
+	pairingCode := existing
+	if pairingCode == nil {
+		pairingCode = &models.UserPairingCode{UserID: userID}
 	}
+	pairingCode.Code = code
+	pairingCode.ExpiresAt = s.now().Add(ttl)
+	pairingCode.MaxUses = maxUses
+	pairingCode.UseCount = 0
 	if err := s.repo.SavePairingCode(pairingCode); err != nil {
 		return nil, fmt.Errorf("failed to save pairing code: %w", err)
 	}
@@ -74,38 +95,135 @@ func (s *SharingService) RegenerateCode(userID uint) (*models.UserPairingCode, e
 	return pairingCode, nil
 }
 
-// Pair links the viewer to the owner via pairing code.
-func (s *SharingService) Pair(viewerID uint, code string) error {
+// Pair redeems the owner's pairing code, creating a request the owner must
+// approve before the viewer gets access. Failed attempts are rate limited to
+// prevent guessing codes.
+func (s *SharingService) Pair(viewerID uint, code string) (*models.PairingRequest, error) {
+	failures, err := s.repo.CountPairingFailures(viewerID, s.now().Add(-pairingFailureWindow))
+	if err != nil {
+		return nil, fmt.Errorf("failed to check pairing attempts: %w", err)
+	}
+	if failures >= maxPairingFailures {
+		return nil, ErrPairingRateLimited
+	}
+
 	pairingCode, err := s.repo.FindByCode(code)
 	if err != nil {
-		return fmt.Errorf("failed to find pairing code: %w", err)
+		return nil, fmt.Errorf("failed to find pairing code: %w", err)
 	}
-	if pairingCode == nil {
-		return errors.New("invalid pairing code")
+	if pairingCode == nil || !pairingCode.Usable(s.now()) {
+		if err := s.repo.RecordPairingFailure(viewerID); err != nil {
+			return nil, fmt.Errorf("failed to record pairing attempt: %w", err)
+		}
+		return nil, errors.New("invalid or expired pairing code")
 	}
 
 	if pairingCode.UserID == viewerID {
-		return errors.New("cannot pair with yourself")
+		return nil, errors.New("cannot pair with yourself")
 	}
 
-	// Check if already paired
+	// Check if already paired or waiting for approval
 	hasAccess, err := s.repo.HasAccess(pairingCode.UserID, viewerID)
 	if err != nil {
-		return fmt.Errorf("failed to check access: %w", err)
+		return nil, fmt.Errorf("failed to check access: %w", err)
 	}
 	if hasAccess {
-		return errors.New("already paired with this user")
+		return nil, errors.New("already paired with this user")
+	}
+	pending, err := s.repo.FindPendingRequest(pairingCode.UserID, viewerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check pairing requests: %w", err)
+	}
+	if pending != nil {
+		return nil, errors.New("a pairing request to this user is already pending")
+	}
+
+	consumed, err := s.repo.ConsumePairingCode(pairingCode.ID, s.now())
+	if err != nil {
+		return nil, fmt.Errorf("failed to use pairing code: %w", err)
+	}
+	if !consumed {
+		return nil, errors.New("invalid or expired pairing code")
+	}
+
+	request := &models.PairingRequest{
+		OwnerID:     pairingCode.UserID,
+		RequesterID: viewerID,
+		Status:      models.PairingRequestPending,
+	}
+	if err := s.repo.CreatePairingRequest(request); err != nil {
+		return nil, fmt.Errorf("failed to create pairing request: %w", err)
+	}
+
+	return request, nil
+}
+
+// ListIncomingRequests returns pending requests waiting for the owner's approval.
+func (s *SharingService) ListIncomingRequests(ownerID uint) ([]models.PairingRequest, error) {
+	return s.repo.ListPendingRequestsByOwner(ownerID)
+}
+
+// ListOutgoingRequests returns the user's own requests that are still pending.
+func (s *SharingService) ListOutgoingRequests(requesterID uint) ([]models.PairingRequest, error) {
+	return s.repo.ListPendingRequestsByRequester(requesterID)
+}
+
+// ApproveRequest grants the requester viewer access to the owner's data.
+func (s *SharingService) ApproveRequest(ownerID, requestID uint) (*models.PairingRequest, error) {
+	request, err := s.pendingRequest(ownerID, requestID)
+	if err != nil {
+		return nil, err
 	}
 
-	access := &models.SharedAccess{
-		OwnerID:  pairingCode.UserID,
-		ViewerID: viewerID,
+	hasAccess, err := s.repo.HasAccess(ownerID, request.RequesterID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check access: %w", err)
 	}
-	if err := s.repo.CreateSharedAccess(access); err != nil {
-		return fmt.Errorf("failed to create shared access: %w", err)
+	if !hasAccess {
+		access := &models.SharedAccess{
+			OwnerID:  ownerID,
+			ViewerID: request.RequesterID,
+			Role:     models.ShareRoleViewer,
+		}
+		if err := s.repo.CreateSharedAccess(access); err != nil {
+			return nil, fmt.Errorf("failed to create shared access: %w", err)
+		}
 	}
 
-	return nil
+	return s.decide(request, models.PairingRequestApproved)
+}
+
+// RejectRequest declines a pending request without granting access.
+func (s *SharingService) RejectRequest(ownerID, requestID uint) (*models.PairingRequest, error) {
+	request, err := s.pendingRequest(ownerID, requestID)
+	if err != nil {
+		return nil, err
+	}
+	return s.decide(request, models.PairingRequestRejected)
+}
+
+func (s *SharingService) pendingRequest(ownerID, requestID uint) (*models.PairingRequest, error) {
+	request, err := s.repo.GetPairingRequest(requestID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pairing request: %w", err)
+	}
+	if request == nil || request.OwnerID != ownerID {
+		return nil, errors.New("pairing request not found")
+	}
+	if request.Status != models.PairingRequestPending {
+		return nil, errors.New("pairing request has already been decided")
+	}
+	return request, nil
+}
+
+func (s *SharingService) decide(request *models.PairingRequest, status string) (*models.PairingRequest, error) {
+	decidedAt := s.now()
+	request.Status = status
+	request.DecidedAt = &decidedAt
+	if err := s.repo.UpdatePairingRequest(request); err != nil {
+		return nil, fmt.Errorf("failed to update pairing request: %w", err)
+	}
+	return request, nil
 }
 
 // ListViewers returns users who have view access to the owner's data.
@@ -123,6 +241,58 @@ func (s *SharingService) Revoke(ownerID, viewerID uint) error {
 	return s.repo.DeleteSharedAccess(ownerID, viewerID)
 }
 
+// UpdateAccess changes the role and scope of a viewer's access to the owner's data.
+func (s *SharingService) UpdateAccess(ownerID, viewerID uint, input models.ShareAccessInput) (*models.SharedAccess, error) {
+	access, err := s.repo.GetSharedAccess(ownerID, viewerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get shared access: %w", err)
+	}
+	if access == nil {
+		return nil, errors.New("shared access not found")
+	}
+
+	switch input.Role {
+	case models.ShareRoleViewer, models.ShareRoleContributor, models.ShareRoleEditor:
+	default:
+		return nil, errors.New("role must be 'viewer', 'contributor' or 'editor'")
+	}
+
+	start, err := parseScopeDate(input.ScopeStartDate)
+	if err != nil {
+		return nil, errors.New("invalid scope_start_date, expected YYYY-MM-DD")
+	}
+	end, err := parseScopeDate(input.ScopeEndDate)
+	if err != nil {
+		return nil, errors.New("invalid scope_end_date, expected YYYY-MM-DD")
+	}
+	if start != nil && end != nil && end.Before(*start) {
+		return nil, errors.New("scope_end_date must not be before scope_start_date")
+	}
+
+	access.Role = input.Role
+	access.ScopeStartDate = start
+	access.ScopeEndDate = end
+	access.ScopeCategoryIDs = pq.Int64Array(input.ScopeCategoryIDs)
+	access.ScopeAccountIDs = pq.Int64Array(input.ScopeAccountIDs)
+	access.HideDescriptions = input.HideDescriptions
+	if err := s.repo.UpdateSharedAccess(access); err != nil {
+		return nil, fmt.Errorf("failed to update shared access: %w", err)
+	}
+
+	return access, nil
+}
+
+func parseScopeDate(value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	date, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return nil, err
+	}
+	return &date, nil
+}
+
 // generateCode generates a code in AB12-CD34 format.
 func (s *SharingService) generateCode() (string, error) {
 	const letters = "ABCDEFGHJKLMNPQRSTUVWXYZ" // exclude I, O to avoid confusion
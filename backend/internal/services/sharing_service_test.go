@@ -4,6 +4,7 @@ import (
 	"billing-note/internal/models"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -61,14 +62,90 @@ func (m *mockSharingRepo) HasAccess(ownerID, viewerID uint) (bool, error) {
 	return args.Bool(0), args.Error(1)
 }
 
+func (m *mockSharingRepo) GetSharedAccess(ownerID, viewerID uint) (*models.SharedAccess, error) {
+	args := m.Called(ownerID, viewerID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.SharedAccess), args.Error(1)
+}
+
+func (m *mockSharingRepo) UpdateSharedAccess(access *models.SharedAccess) error {
+	args := m.Called(access)
+	return args.Error(0)
+}
+
+func (m *mockSharingRepo) ConsumePairingCode(id uint, now time.Time) (bool, error) {
+	args := m.Called(id, now)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *mockSharingRepo) CreatePairingRequest(request *models.PairingRequest) error {
+	args := m.Called(request)
+	return args.Error(0)
+}
+
+func (m *mockSharingRepo) GetPairingRequest(id uint) (*models.PairingRequest, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.PairingRequest), args.Error(1)
+}
+
+func (m *mockSharingRepo) FindPendingRequest(ownerID, requesterID uint) (*models.PairingRequest, error) {
+	args := m.Called(ownerID, requesterID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.PairingRequest), args.Error(1)
+}
+
+func (m *mockSharingRepo) ListPendingRequestsByOwner(ownerID uint) ([]models.PairingRequest, error) {
+	args := m.Called(ownerID)
+	return args.Get(0).([]models.PairingRequest), args.Error(1)
+}
+
+func (m *mockSharingRepo) ListPendingRequestsByRequester(requesterID uint) ([]models.PairingRequest, error) {
+	args := m.Called(requesterID)
+	return args.Get(0).([]models.PairingRequest), args.Error(1)
+}
+
+func (m *mockSharingRepo) UpdatePairingRequest(request *models.PairingRequest) error {
+	args := m.Called(request)
+	return args.Error(0)
+}
+
+func (m *mockSharingRepo) RecordPairingFailure(userID uint) error {
+	args := m.Called(userID)
+	return args.Error(0)
+}
+
+func (m *mockSharingRepo) CountPairingFailures(userID uint, since time.Time) (int64, error) {
+	args := m.Called(userID, since)
+	return args.Get(0).(int64), args.Error(1)
+}
+
 // --- Tests ---
 
+var sharingNow = time.Date(2026, 6, 15, 12, 0, 0, 0, time.UTC)
+
+func newTestSharingService(repo *mockSharingRepo) *SharingService {
+	svc := NewSharingService(repo)
+	svc.now = func() time.Time { return sharingNow }
+	return svc
+}
+
+// activeCode returns user 1's code, valid for another day with one use left
+func activeCode() *models.UserPairingCode {
+	return &models.UserPairingCode{ID: 1, UserID: 1, Code: "AB12-CD34", ExpiresAt: sharingNow.Add(24 * time.Hour), MaxUses: 1}
+}
+
 func TestGetOrCreateCode_ExistingCode(t *testing.T) {
 	repo := new(mockSharingRepo)
-	svc := NewSharingService(repo)
+	svc := newTestSharingService(repo)
 
-	existing := &models.UserPairingCode{ID: 1, UserID: 1, Code: "AB12-CD34"}
-	repo.On("GetPairingCode", uint(1)).Return(existing, nil)
+	repo.On("GetPairingCode", uint(1)).Return(activeCode(), nil)
 
 	code, err := svc.GetOrCreateCode(1)
 
@@ -79,7 +156,7 @@ func TestGetOrCreateCode_ExistingCode(t *testing.T) {
 
 func TestGetOrCreateCode_NewCode(t *testing.T) {
 	repo := new(mockSharingRepo)
-	svc := NewSharingService(repo)
+	svc := newTestSharingService(repo)
 
 	repo.On("GetPairingCode", uint(1)).Return(nil, nil)
 	repo.On("SavePairingCode", mock.AnythingOfType("*models.UserPairingCode")).Return(nil)
@@ -90,60 +167,128 @@ func TestGetOrCreateCode_NewCode(t *testing.T) {
 	assert.NotEmpty(t, code.Code)
 	assert.Len(t, code.Code, 9) // AB12-CD34 = 9 chars
 	assert.Equal(t, '-', rune(code.Code[4]))
+	assert.Equal(t, sharingNow.Add(7*24*time.Hour), code.ExpiresAt)
+	assert.Equal(t, 1, code.MaxUses)
+	repo.AssertExpectations(t)
+}
+
+func TestGetOrCreateCode_ReplacesUsedUpCode(t *testing.T) {
+	repo := new(mockSharingRepo)
+	svc := newTestSharingService(repo)
+
+	expired := activeCode()
+	expired.ExpiresAt = sharingNow.Add(-time.Minute)
+	repo.On("GetPairingCode", uint(1)).Return(expired, nil)
+	repo.On("SavePairingCode", mock.AnythingOfType("*models.UserPairingCode")).Return(nil)
+
+	code, err := svc.GetOrCreateCode(1)
+
+	assert.NoError(t, err)
+	assert.Equal(t, uint(1), code.ID)
+	assert.NotEqual(t, "AB12-CD34", code.Code)
+	assert.True(t, code.Usable(sharingNow))
 	repo.AssertExpectations(t)
 }
 
 func TestRegenerateCode(t *testing.T) {
 	repo := new(mockSharingRepo)
-	svc := NewSharingService(repo)
+	svc := newTestSharingService(repo)
 
-	existing := &models.UserPairingCode{ID: 1, UserID: 1, Code: "AB12-CD34"}
-	repo.On("GetPairingCode", uint(1)).Return(existing, nil)
+	used := activeCode()
+	used.UseCount = 1
+	repo.On("GetPairingCode", uint(1)).Return(used, nil)
 	repo.On("SavePairingCode", mock.AnythingOfType("*models.UserPairingCode")).Return(nil)
 
-	code, err := svc.RegenerateCode(1)
+	code, err := svc.RegenerateCode(1, models.PairingCodeInput{ExpiresInHours: 48, MaxUses: 3})
 
 	assert.NoError(t, err)
 	assert.NotEmpty(t, code.Code)
 	assert.Len(t, code.Code, 9)
+	assert.Equal(t, sharingNow.Add(48*time.Hour), code.ExpiresAt)
+	assert.Equal(t, 3, code.MaxUses)
+	assert.Equal(t, 0, code.UseCount)
 	repo.AssertExpectations(t)
 }
 
 func TestPair_Success(t *testing.T) {
 	repo := new(mockSharingRepo)
-	svc := NewSharingService(repo)
+	svc := newTestSharingService(repo)
 
-	pairingCode := &models.UserPairingCode{ID: 1, UserID: 1, Code: "AB12-CD34"}
-	repo.On("FindByCode", "AB12-CD34").Return(pairingCode, nil)
+	repo.On("CountPairingFailures", uint(2), sharingNow.Add(-15*time.Minute)).Return(int64(0), nil)
+	repo.On("FindByCode", "AB12-CD34").Return(activeCode(), nil)
 	repo.On("HasAccess", uint(1), uint(2)).Return(false, nil)
-	repo.On("CreateSharedAccess", mock.AnythingOfType("*models.SharedAccess")).Return(nil)
+	repo.On("FindPendingRequest", uint(1), uint(2)).Return(nil, nil)
+	repo.On("ConsumePairingCode", uint(1), sharingNow).Return(true, nil)
+	repo.On("CreatePairingRequest", mock.AnythingOfType("*models.PairingRequest")).Return(nil)
 
-	err := svc.Pair(2, "AB12-CD34")
+	request, err := svc.Pair(2, "AB12-CD34")
 
 	assert.NoError(t, err)
+	assert.Equal(t, uint(1), request.OwnerID)
+	assert.Equal(t, uint(2), request.RequesterID)
+	assert.Equal(t, models.PairingRequestPending, request.Status)
 	repo.AssertExpectations(t)
+	// Access is only granted once the owner approves
+	repo.AssertNotCalled(t, "CreateSharedAccess", mock.Anything)
 }
 
 func TestPair_InvalidCode(t *testing.T) {
 	repo := new(mockSharingRepo)
-	svc := NewSharingService(repo)
+	svc := newTestSharingService(repo)
 
+	repo.On("CountPairingFailures", uint(2), mock.Anything).Return(int64(0), nil)
 	repo.On("FindByCode", "XXXX-YYYY").Return(nil, nil)
+	repo.On("RecordPairingFailure", uint(2)).Return(nil)
 
-	err := svc.Pair(2, "XXXX-YYYY")
+	_, err := svc.Pair(2, "XXXX-YYYY")
 
 	assert.Error(t, err)
-	assert.Equal(t, "invalid pairing code", err.Error())
+	assert.Equal(t, "invalid or expired pairing code", err.Error())
+	repo.AssertExpectations(t)
+}
+
+func TestPair_ExpiredOrUsedUpCode(t *testing.T) {
+	repo := new(mockSharingRepo)
+	svc := newTestSharingService(repo)
+
+	expired := activeCode()
+	expired.ExpiresAt = sharingNow
+	usedUp := &models.UserPairingCode{ID: 2, UserID: 3, Code: "EF56-GH78", ExpiresAt: sharingNow.Add(time.Hour), MaxUses: 2, UseCount: 2}
+	repo.On("CountPairingFailures", uint(2), mock.Anything).Return(int64(0), nil)
+	repo.On("FindByCode", "AB12-CD34").Return(expired, nil)
+	repo.On("FindByCode", "EF56-GH78").Return(usedUp, nil)
+	repo.On("RecordPairingFailure", uint(2)).Return(nil)
+
+	_, err := svc.Pair(2, "AB12-CD34")
+	assert.Equal(t, "invalid or expired pairing code", err.Error())
+
+	_, err = svc.Pair(2, "EF56-GH78")
+	assert.Equal(t, "invalid or expired pairing code", err.Error())
+
+	repo.AssertNumberOfCalls(t, "RecordPairingFailure", 2)
+	repo.AssertNotCalled(t, "CreatePairingRequest", mock.Anything)
+}
+
+func TestPair_RateLimited(t *testing.T) {
+	repo := new(mockSharingRepo)
+	svc := newTestSharingService(repo)
+
+	repo.On("CountPairingFailures", uint(2), sharingNow.Add(-15*time.Minute)).Return(int64(5), nil)
+
+	_, err := svc.Pair(2, "AB12-CD34")
+
+	assert.Equal(t, ErrPairingRateLimited, err)
+	repo.AssertNotCalled(t, "FindByCode", mock.Anything)
 }
 
 func TestPair_SelfPairing(t *testing.T) {
 	repo := new(mockSharingRepo)
-	svc := NewSharingService(repo)
+	svc := newTestSharingService(repo)
 
-	pairingCode := &models.UserPairingCode{ID: 1, UserID: 1, Code: "AB12-CD34"}
-	repo.On("FindByCode", "AB12-CD34").Return(pairingCode, nil)
+	repo.On("CountPairingFailures", uint(1), mock.Anything).Return(int64(0), nil)
+	repo.On("FindByCode", "AB12-CD34").Return(activeCode(), nil)
 
-	err := svc.Pair(1, "AB12-CD34")
+	_, err := svc.Pair(1, "AB12-CD34")
 
 	assert.Error(t, err)
 	assert.Equal(t, "cannot pair with yourself", err.Error())
@@ -151,18 +296,74 @@ func TestPair_SelfPairing(t *testing.T) {
 
 func TestPair_AlreadyPaired(t *testing.T) {
 	repo := new(mockSharingRepo)
-	svc := NewSharingService(repo)
+	svc := newTestSharingService(repo)
 
-	pairingCode := &models.UserPairingCode{ID: 1, UserID: 1, Code: "AB12-CD34"}
-	repo.On("FindByCode", "AB12-CD34").Return(pairingCode, nil)
+	repo.On("CountPairingFailures", uint(2), mock.Anything).Return(int64(0), nil)
+	repo.On("FindByCode", "AB12-CD34").Return(activeCode(), nil)
 	repo.On("HasAccess", uint(1), uint(2)).Return(true, nil)
 
-	err := svc.Pair(2, "AB12-CD34")
+	_, err := svc.Pair(2, "AB12-CD34")
 
 	assert.Error(t, err)
 	assert.Equal(t, "already paired with this user", err.Error())
 }
 
+func TestPair_RequestPending(t *testing.T) {
+	repo := new(mockSharingRepo)
+	svc := newTestSharingService(repo)
+
+	repo.On("CountPairingFailures", uint(2), mock.Anything).Return(int64(0), nil)
+	repo.On("FindByCode", "AB12-CD34").Return(activeCode(), nil)
+	repo.On("HasAccess", uint(1), uint(2)).Return(false, nil)
+	repo.On("FindPendingRequest", uint(1), uint(2)).Return(&models.PairingRequest{ID: 4, OwnerID: 1, RequesterID: 2, Status: models.PairingRequestPending}, nil)
+
+	_, err := svc.Pair(2, "AB12-CD34")
+
+	assert.Error(t, err)
+	repo.AssertNotCalled(t, "ConsumePairingCode", mock.Anything, mock.Anything)
+}
+
+func TestApproveRequest(t *testing.T) {
+	repo := new(mockSharingRepo)
+	svc := newTestSharingService(repo)
+
+	repo.On("GetPairingRequest", uint(4)).Return(&models.PairingRequest{ID: 4, OwnerID: 1, RequesterID: 2, Status: models.PairingRequestPending}, nil)
+	repo.On("HasAccess", uint(1), uint(2)).Return(false, nil)
+	repo.On("CreateSharedAccess", mock.MatchedBy(func(a *models.SharedAccess) bool {
+		return a.OwnerID == 1 && a.ViewerID == 2 && a.Role == models.ShareRoleViewer
+	})).Return(nil)
+	repo.On("UpdatePairingRequest", mock.Anything).Return(nil)
+
+	request, err := svc.ApproveRequest(1, 4)
+
+	assert.NoError(t, err)
+	assert.Equal(t, models.PairingRequestApproved, request.Status)
+	assert.Equal(t, sharingNow, *request.DecidedAt)
+	repo.AssertExpectations(t)
+}
+
+func TestRejectRequest(t *testing.T) {
+	repo := new(mockSharingRepo)
+	svc := newTestSharingService(repo)
+
+	repo.On("GetPairingRequest", uint(4)).Return(&models.PairingRequest{ID: 4, OwnerID: 1, RequesterID: 2, Status: models.PairingRequestPending}, nil)
+	repo.On("GetPairingRequest", uint(5)).Return(&models.PairingRequest{ID: 5, OwnerID: 3, RequesterID: 2, Status: models.PairingRequestPending}, nil)
+	repo.On("UpdatePairingRequest", mock.Anything).Return(nil)
+
+	request, err := svc.RejectRequest(1, 4)
+	assert.NoError(t, err)
+	assert.Equal(t, models.PairingRequestRejected, request.Status)
+
+	// Only the owner can decide a request
+	_, err = svc.RejectRequest(1, 5)
+	assert.Error(t, err)
+
+	// A decided request cannot be approved afterwards
+	_, err = svc.ApproveRequest(1, 4)
+	assert.Error(t, err)
+	repo.AssertNotCalled(t, "CreateSharedAccess", mock.Anything)
+}
+
 func TestListViewers(t *testing.T) {
 	repo := new(mockSharingRepo)
 	svc := NewSharingService(repo)
@@ -223,3 +424,51 @@ func TestCodeFormat(t *testing.T) {
 		}
 	}
 }
+
+func TestUpdateAccess(t *testing.T) {
+	repo := new(mockSharingRepo)
+	svc := NewSharingService(repo)
+
+	repo.On("GetSharedAccess", uint(1), uint(2)).Return(&models.SharedAccess{ID: 5, OwnerID: 1, ViewerID: 2, Role: models.ShareRoleViewer}, nil)
+	repo.On("UpdateSharedAccess", mock.Anything).Return(nil)
+
+	access, err := svc.UpdateAccess(1, 2, models.ShareAccessInput{
+		Role:             models.ShareRoleContributor,
+		ScopeStartDate:   "2026-01-01",
+		ScopeCategoryIDs: []int64{3, 4},
+		HideDescriptions: true,
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, models.ShareRoleContributor, access.Role)
+	assert.Equal(t, time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), *access.ScopeStartDate)
+	assert.Nil(t, access.ScopeEndDate)
+	assert.Equal(t, []uint{3, 4}, access.Scope().CategoryIDs)
+	repo.AssertExpectations(t)
+}
+
+func TestUpdateAccess_InvalidScope(t *testing.T) {
+	repo := new(mockSharingRepo)
+	svc := NewSharingService(repo)
+
+	repo.On("GetSharedAccess", uint(1), uint(2)).Return(&models.SharedAccess{OwnerID: 1, ViewerID: 2}, nil)
+
+	_, err := svc.UpdateAccess(1, 2, models.ShareAccessInput{Role: models.ShareRoleViewer, ScopeStartDate: "2026-03-01", ScopeEndDate: "2026-02-01"})
+	assert.Error(t, err)
+
+	_, err = svc.UpdateAccess(1, 2, models.ShareAccessInput{Role: "owner"})
+	assert.Error(t, err)
+	repo.AssertNotCalled(t, "UpdateSharedAccess", mock.Anything)
+}
+
+func TestUpdateAccess_NotPaired(t *testing.T) {
+	repo := new(mockSharingRepo)
+	svc := NewSharingService(repo)
+
+	repo.On("GetSharedAccess", uint(1), uint(3)).Return(nil, nil)
+
+	_, err := svc.UpdateAccess(1, 3, models.ShareAccessInput{Role: models.ShareRoleEditor})
+
+	assert.Error(t, err)
+	assert.Equal(t, "shared access not found", err.Error())
+}
@@ -0,0 +1,448 @@
+package services
+
+import (
+	"billing-note/internal/models"
+	"billing-note/internal/repository"
+	"billing-note/pkg/errors"
+	"math"
+	"sort"
+	"time"
+)
+
+// SplitService divides expenses among paired users and tracks what they owe
+// each other
+type SplitService struct {
+	splitRepo       repository.SplitRepository
+	transactionRepo repository.TransactionRepository
+	sharingRepo     repository.SharingRepository
+	userRepo        repository.UserRepository
+	now             func() time.Time
+}
+
+// NewSplitService creates a new split service
+func NewSplitService(splitRepo repository.SplitRepository, transactionRepo repository.TransactionRepository, sharingRepo repository.SharingRepository, userRepo repository.UserRepository) *SplitService {
+	return &SplitService{
+		splitRepo:       splitRepo,
+		transactionRepo: transactionRepo,
+		sharingRepo:     sharingRepo,
+		userRepo:        userRepo,
+		now:             time.Now,
+	}
+}
+
+// Split divides one of the user's expenses among them and paired users,
+// replacing any earlier split of the transaction
+func (s *SplitService) Split(userID, transactionID uint, input models.SplitInput) (*models.TransactionSplit, error) {
+	transaction, err := s.transactionRepo.GetByID(transactionID)
+	if err != nil || transaction.UserID != userID || transaction.LedgerID != nil {
+		return nil, errors.NewNotFoundError("Transaction", transactionID)
+	}
+	if transaction.Type != "expense" {
+		return nil, errors.NewValidationError("Only expenses can be split")
+	}
+
+	seen := make(map[uint]bool)
+	others := 0
+	for _, share := range input.Shares {
+		if seen[share.UserID] {
+			return nil, errors.NewValidationError("Each user can only appear once in a split")
+		}
+		seen[share.UserID] = true
+		if share.UserID == userID {
+			continue
+		}
+		others++
+		paired, err := s.isPaired(userID, share.UserID)
+		if err != nil {
+			return nil, err
+		}
+		if !paired {
+			return nil, errors.NewValidationError("Expenses can only be split with paired users")
+		}
+	}
+	if others == 0 {
+		return nil, errors.NewValidationError("A split needs at least one other user")
+	}
+
+	shares, err := computeShares(input.Method, transaction.Amount, input.Shares)
+	if err != nil {
+		return nil, err
+	}
+
+	split := &models.TransactionSplit{
+		TransactionID: transaction.ID,
+		PayerID:       userID,
+		Method:        input.Method,
+		Amount:        transaction.Amount,
+		Date:          transaction.TransactionDate,
+		Description:   transaction.Description,
+		Shares:        shares,
+	}
+	if err := s.splitRepo.SaveSplit(split); err != nil {
+		return nil, errors.NewDBError("save split", err)
+	}
+	return split, nil
+}
+
+// Get returns a transaction's split; the payer and participants may see it
+func (s *SplitService) Get(userID, transactionID uint) (*models.TransactionSplit, error) {
+	split, err := s.splitRepo.GetByTransaction(transactionID)
+	if err != nil {
+		return nil, errors.NewDBError("get split", err)
+	}
+	if split == nil || !splitInvolves(split, userID) {
+		return nil, errors.NewNotFoundError("Split", transactionID)
+	}
+	return split, nil
+}
+
+// Delete removes a transaction's split; only the payer may remove it
+func (s *SplitService) Delete(userID, transactionID uint) error {
+	split, err := s.splitRepo.GetByTransaction(transactionID)
+	if err != nil {
+		return errors.NewDBError("get split", err)
+	}
+	if split == nil || split.PayerID != userID {
+		return errors.NewNotFoundError("Split", transactionID)
+	}
+	if err := s.splitRepo.DeleteByTransaction(transactionID); err != nil {
+		return errors.NewDBError("delete split", err)
+	}
+	return nil
+}
+
+// List returns the splits the user paid or has a share in
+func (s *SplitService) List(userID uint) ([]models.TransactionSplit, error) {
+	splits, err := s.splitRepo.ListByUser(userID)
+	if err != nil {
+		return nil, errors.NewDBError("list splits", err)
+	}
+	return splits, nil
+}
+
+// Summary returns the user's running balance with each other user and the
+// payments that would settle them
+func (s *SplitService) Summary(userID uint) (*models.SplitSummary, error) {
+	splits, err := s.splitRepo.ListByUser(userID)
+	if err != nil {
+		return nil, errors.NewDBError("list splits", err)
+	}
+	settlements, err := s.splitRepo.ListSettlements(userID)
+	if err != nil {
+		return nil, errors.NewDBError("list settlements", err)
+	}
+
+	owed := make(map[uint]float64) // by the other user to this user
+	for _, flow := range splitFlows(splits, settlements) {
+		if flow.creditor == userID {
+			owed[flow.debtor] += flow.amount
+		} else if flow.debtor == userID {
+			owed[flow.creditor] -= flow.amount
+		}
+	}
+
+	summary := &models.SplitSummary{
+		Balances:    []models.SplitBalance{},
+		Suggestions: []models.SettleUpSuggestion{},
+	}
+	group := []uint{userID}
+	for otherID, amount := range owed {
+		amount = roundCents(amount)
+		if amount == 0 {
+			continue
+		}
+		balance := models.SplitBalance{UserID: otherID, Amount: amount}
+		if s.userRepo != nil {
+			if user, err := s.userRepo.FindByID(otherID); err == nil {
+				balance.Name = user.Name
+				balance.Email = user.Email
+			}
+		}
+		summary.Balances = append(summary.Balances, balance)
+		summary.Net = roundCents(summary.Net + amount)
+		group = append(group, otherID)
+	}
+	sort.Slice(summary.Balances, func(i, j int) bool {
+		a, b := math.Abs(summary.Balances[i].Amount), math.Abs(summary.Balances[j].Amount)
+		if a != b {
+			return a > b
+		}
+		return summary.Balances[i].UserID < summary.Balances[j].UserID
+	})
+	if len(group) == 1 {
+		return summary, nil
+	}
+
+	// Simplify across everyone the user has a balance with, so debts between
+	// them can be passed along instead of paid separately
+	groupSplits, err := s.splitRepo.ListAmong(group)
+	if err != nil {
+		return nil, errors.NewDBError("list splits", err)
+	}
+	groupSettlements, err := s.splitRepo.ListSettlementsAmong(group)
+	if err != nil {
+		return nil, errors.NewDBError("list settlements", err)
+	}
+	members := make(map[uint]bool, len(group))
+	for _, id := range group {
+		members[id] = true
+	}
+	net := make(map[uint]float64)
+	for _, flow := range splitFlows(groupSplits, groupSettlements) {
+		if members[flow.creditor] && members[flow.debtor] {
+			net[flow.creditor] += flow.amount
+			net[flow.debtor] -= flow.amount
+		}
+	}
+	for _, suggestion := range simplifyDebts(net) {
+		if suggestion.FromUserID == userID || suggestion.ToUserID == userID {
+			summary.Suggestions = append(summary.Suggestions, suggestion)
+		}
+	}
+	return summary, nil
+}
+
+// RecordSettlement records a payment between the user and a paired user
+func (s *SplitService) RecordSettlement(userID uint, input models.SettlementInput) (*models.Settlement, error) {
+	if input.FromUserID != userID && input.ToUserID != userID {
+		return nil, errors.NewValidationError("You must be the payer or the recipient of a settlement")
+	}
+	if input.FromUserID == input.ToUserID {
+		return nil, errors.NewValidationError("A settlement needs two different users")
+	}
+	if *input.Amount <= 0 {
+		return nil, errors.NewInvalidInputError("amount", "must be greater than 0")
+	}
+	otherID := input.ToUserID
+	if otherID == userID {
+		otherID = input.FromUserID
+	}
+	paired, err := s.isPaired(userID, otherID)
+	if err != nil {
+		return nil, err
+	}
+	if !paired {
+		return nil, errors.NewValidationError("Settlements can only be recorded with paired users")
+	}
+
+	date := dateOf(s.now(), userLocation(s.userRepo, userID))
+	if input.Date != "" {
+		date, err = time.Parse(budgetDateLayout, input.Date)
+		if err != nil {
+			return nil, errors.NewInvalidInputError("date", "must be YYYY-MM-DD")
+		}
+	}
+
+	settlement := &models.Settlement{
+		FromUserID:  input.FromUserID,
+		ToUserID:    input.ToUserID,
+		Amount:      roundCents(*input.Amount),
+		Date:        date,
+		Note:        input.Note,
+		CreatedByID: userID,
+	}
+	if err := s.splitRepo.CreateSettlement(settlement); err != nil {
+		return nil, errors.NewDBError("create settlement", err)
+	}
+	return settlement, nil
+}
+
+// ListSettlements returns the settlements the user paid or received
+func (s *SplitService) ListSettlements(userID uint) ([]models.Settlement, error) {
+	settlements, err := s.splitRepo.ListSettlements(userID)
+	if err != nil {
+		return nil, errors.NewDBError("list settlements", err)
+	}
+	return settlements, nil
+}
+
+// DeleteSettlement removes a settlement; only the user who recorded it may
+func (s *SplitService) DeleteSettlement(userID, id uint) error {
+	settlement, err := s.splitRepo.GetSettlement(id)
+	if err != nil {
+		return errors.NewDBError("get settlement", err)
+	}
+	if settlement == nil || settlement.CreatedByID != userID {
+		return errors.NewNotFoundError("Settlement", id)
+	}
+	if err := s.splitRepo.DeleteSettlement(id); err != nil {
+		return errors.NewDBError("delete settlement", err)
+	}
+	return nil
+}
+
+// isPaired reports whether either user shares their data with the other
+func (s *SplitService) isPaired(userID, otherID uint) (bool, error) {
+	for _, pair := range [][2]uint{{userID, otherID}, {otherID, userID}} {
+		ok, err := s.sharingRepo.HasAccess(pair[0], pair[1])
+		if err != nil {
+			return false, errors.NewDBError("check pairing", err)
+		}
+		if ok {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func splitInvolves(split *models.TransactionSplit, userID uint) bool {
+	if split.PayerID == userID {
+		return true
+	}
+	for _, share := range split.Shares {
+		if share.UserID == userID {
+			return true
+		}
+	}
+	return false
+}
+
+// computeShares works out each participant's amount. Amounts are in whole
+// cents; rounding leftovers go to the first participants so the shares always
+// add up to the total.
+func computeShares(method string, total float64, inputs []models.SplitShareInput) ([]models.SplitShare, error) {
+	totalCents := int64(math.Round(total * 100))
+	shares := make([]models.SplitShare, len(inputs))
+	cents := make([]int64, len(inputs))
+
+	switch method {
+	case models.SplitMethodEqual:
+		n := int64(len(inputs))
+		for i := range inputs {
+			cents[i] = totalCents / n
+			if int64(i) < totalCents%n {
+				cents[i]++
+			}
+		}
+	case models.SplitMethodPercent:
+		sum := 0.0
+		for i, in := range inputs {
+			if in.Percent == nil || *in.Percent < 0 {
+				return nil, errors.NewInvalidInputError("percent", "is required for every share and must not be negative")
+			}
+			sum += *in.Percent
+			cents[i] = int64(math.Floor(float64(totalCents) * *in.Percent / 100))
+		}
+		if math.Abs(sum-100) > 0.01 {
+			return nil, errors.NewInvalidInputError("percent", "shares must add up to 100")
+		}
+		distributeRemainder(cents, totalCents)
+	case models.SplitMethodExact:
+		var sum int64
+		for i, in := range inputs {
+			if in.Amount == nil || *in.Amount < 0 {
+				return nil, errors.NewInvalidInputError("amount", "is required for every share and must not be negative")
+			}
+			cents[i] = int64(math.Round(*in.Amount * 100))
+			sum += cents[i]
+		}
+		if sum != totalCents {
+			return nil, errors.NewInvalidInputError("amount", "shares must add up to the transaction amount")
+		}
+	default:
+		return nil, errors.NewInvalidInputError("method", "must be equal, percent or exact")
+	}
+
+	for i, in := range inputs {
+		shares[i] = models.SplitShare{UserID: in.UserID, Amount: float64(cents[i]) / 100}
+		if method == models.SplitMethodPercent {
+			percent := *in.Percent
+			shares[i].Percent = &percent
+		}
+	}
+	return shares, nil
+}
+
+// distributeRemainder adjusts the shares one cent at a time until they add
+// up to the total
+func distributeRemainder(cents []int64, total int64) {
+	var sum int64
+	for _, c := range cents {
+		sum += c
+	}
+	for i := 0; sum < total; i = (i + 1) % len(cents) {
+		cents[i]++
+		sum++
+	}
+	for i := len(cents) - 1; sum > total; i = (i + len(cents) - 1) % len(cents) {
+		if cents[i] > 0 {
+			cents[i]--
+			sum--
+		}
+	}
+}
+
+// debtFlow is an amount the debtor owes the creditor
+type debtFlow struct {
+	creditor uint
+	debtor   uint
+	amount   float64
+}
+
+// splitFlows lists who owes whom from splits and settlements. A settlement
+// from A to B counts as B owing A, which offsets what A owed B.
+func splitFlows(splits []models.TransactionSplit, settlements []models.Settlement) []debtFlow {
+	var flows []debtFlow
+	for _, split := range splits {
+		for _, share := range split.Shares {
+			if share.UserID != split.PayerID && share.Amount != 0 {
+				flows = append(flows, debtFlow{creditor: split.PayerID, debtor: share.UserID, amount: share.Amount})
+			}
+		}
+	}
+	for _, settlement := range settlements {
+		flows = append(flows, debtFlow{creditor: settlement.FromUserID, debtor: settlement.ToUserID, amount: settlement.Amount})
+	}
+	return flows
+}
+
+// simplifyDebts turns net balances (positive when owed) into a short list of
+// payments by repeatedly settling the largest debtor against the largest
+// creditor
+func simplifyDebts(net map[uint]float64) []models.SettleUpSuggestion {
+	type party struct {
+		id    uint
+		cents int64
+	}
+	var creditors, debtors []party
+	for id, amount := range net {
+		cents := int64(math.Round(amount * 100))
+		if cents > 0 {
+			creditors = append(creditors, party{id, cents})
+		} else if cents < 0 {
+			debtors = append(debtors, party{id, -cents})
+		}
+	}
+	byAmount := func(parties []party) {
+		sort.Slice(parties, func(i, j int) bool {
+			if parties[i].cents != parties[j].cents {
+				return parties[i].cents > parties[j].cents
+			}
+			return parties[i].id < parties[j].id
+		})
+	}
+
+	var suggestions []models.SettleUpSuggestion
+	for len(creditors) > 0 && len(debtors) > 0 {
+		byAmount(creditors)
+		byAmount(debtors)
+		amount := creditors[0].cents
+		if debtors[0].cents < amount {
+			amount = debtors[0].cents
+		}
+		suggestions = append(suggestions, models.SettleUpSuggestion{
+			FromUserID: debtors[0].id,
+			ToUserID:   creditors[0].id,
+			Amount:     float64(amount) / 100,
+		})
+		creditors[0].cents -= amount
+		debtors[0].cents -= amount
+		if creditors[0].cents == 0 {
+			creditors = creditors[1:]
+		}
+		if debtors[0].cents == 0 {
+			debtors = debtors[1:]
+		}
+	}
+	return suggestions
+}
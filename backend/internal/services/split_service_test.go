@@ -0,0 +1,234 @@
+package services
+
+import (
+	"billing-note/internal/models"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// --- Mock Split Repository ---
+
+type mockSplitRepo struct {
+	mock.Mock
+}
+
+func (m *mockSplitRepo) SaveSplit(split *models.TransactionSplit) error {
+	args := m.Called(split)
+	return args.Error(0)
+}
+
+func (m *mockSplitRepo) GetByTransaction(transactionID uint) (*models.TransactionSplit, error) {
+	args := m.Called(transactionID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.TransactionSplit), args.Error(1)
+}
+
+func (m *mockSplitRepo) DeleteByTransaction(transactionID uint) error {
+	args := m.Called(transactionID)
+	return args.Error(0)
+}
+
+func (m *mockSplitRepo) ListByUser(userID uint) ([]models.TransactionSplit, error) {
+	args := m.Called(userID)
+	return args.Get(0).([]models.TransactionSplit), args.Error(1)
+}
+
+func (m *mockSplitRepo) ListAmong(userIDs []uint) ([]models.TransactionSplit, error) {
+	args := m.Called(userIDs)
+	return args.Get(0).([]models.TransactionSplit), args.Error(1)
+}
+
+func (m *mockSplitRepo) CreateSettlement(settlement *models.Settlement) error {
+	args := m.Called(settlement)
+	return args.Error(0)
+}
+
+func (m *mockSplitRepo) GetSettlement(id uint) (*models.Settlement, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Settlement), args.Error(1)
+}
+
+func (m *mockSplitRepo) ListSettlements(userID uint) ([]models.Settlement, error) {
+	args := m.Called(userID)
+	return args.Get(0).([]models.Settlement), args.Error(1)
+}
+
+func (m *mockSplitRepo) ListSettlementsAmong(userIDs []uint) ([]models.Settlement, error) {
+	args := m.Called(userIDs)
+	return args.Get(0).([]models.Settlement), args.Error(1)
+}
+
+func (m *mockSplitRepo) DeleteSettlement(id uint) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func newTestSplitService(splitRepo *mockSplitRepo, txnRepo *mockTransactionRepo, sharingRepo *mockSharingRepo) *SplitService {
+	s := NewSplitService(splitRepo, txnRepo, sharingRepo, nil)
+	s.now = func() time.Time { return time.Date(2026, 6, 15, 12, 0, 0, 0, time.UTC) }
+	return s
+}
+
+func float(v float64) *float64 { return &v }
+
+func split(payer uint, shares map[uint]float64) models.TransactionSplit {
+	s := models.TransactionSplit{PayerID: payer}
+	for userID, amount := range shares {
+		s.Shares = append(s.Shares, models.SplitShare{UserID: userID, Amount: amount})
+	}
+	return s
+}
+
+// --- Tests ---
+
+func TestComputeShares(t *testing.T) {
+	shares, err := computeShares(models.SplitMethodEqual, 100, []models.SplitShareInput{{UserID: 1}, {UserID: 2}, {UserID: 3}})
+	require.NoError(t, err)
+	assert.Equal(t, 33.34, shares[0].Amount)
+	assert.Equal(t, 33.33, shares[1].Amount)
+	assert.Equal(t, 33.33, shares[2].Amount)
+
+	shares, err = computeShares(models.SplitMethodPercent, 999, []models.SplitShareInput{
+		{UserID: 1, Percent: float(60)}, {UserID: 2, Percent: float(40)},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 599.4, shares[0].Amount)
+	assert.Equal(t, 399.6, shares[1].Amount)
+	assert.Equal(t, 40.0, *shares[1].Percent)
+
+	shares, err = computeShares(models.SplitMethodExact, 1200, []models.SplitShareInput{
+		{UserID: 1, Amount: float(450)}, {UserID: 2, Amount: float(750)},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 750.0, shares[1].Amount)
+}
+
+func TestComputeShares_Invalid(t *testing.T) {
+	_, err := computeShares(models.SplitMethodPercent, 100, []models.SplitShareInput{
+		{UserID: 1, Percent: float(60)}, {UserID: 2, Percent: float(30)},
+	})
+	assert.Error(t, err)
+
+	_, err = computeShares(models.SplitMethodExact, 100, []models.SplitShareInput{
+		{UserID: 1, Amount: float(60)}, {UserID: 2},
+	})
+	assert.Error(t, err)
+
+	_, err = computeShares(models.SplitMethodExact, 100, []models.SplitShareInput{
+		{UserID: 1, Amount: float(60)}, {UserID: 2, Amount: float(30)},
+	})
+	assert.Error(t, err)
+}
+
+func TestSplitService_Split(t *testing.T) {
+	splitRepo := new(mockSplitRepo)
+	txnRepo := new(mockTransactionRepo)
+	sharingRepo := new(mockSharingRepo)
+	svc := newTestSplitService(splitRepo, txnRepo, sharingRepo)
+
+	txnRepo.On("GetByID", uint(5)).Return(&models.Transaction{ID: 5, UserID: 1, Type: "expense", Amount: 2400, Description: "Family dinner"}, nil)
+	sharingRepo.On("HasAccess", uint(1), uint(2)).Return(false, nil)
+	sharingRepo.On("HasAccess", uint(2), uint(1)).Return(true, nil)
+	splitRepo.On("SaveSplit", mock.Anything).Return(nil)
+
+	result, err := svc.Split(1, 5, models.SplitInput{
+		Method: models.SplitMethodEqual,
+		Shares: []models.SplitShareInput{{UserID: 1}, {UserID: 2}},
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, uint(1), result.PayerID)
+	assert.Equal(t, 2400.0, result.Amount)
+	assert.Equal(t, 1200.0, result.Shares[1].Amount)
+	splitRepo.AssertExpectations(t)
+}
+
+func TestSplitService_Split_Rejected(t *testing.T) {
+	splitRepo := new(mockSplitRepo)
+	txnRepo := new(mockTransactionRepo)
+	sharingRepo := new(mockSharingRepo)
+	svc := newTestSplitService(splitRepo, txnRepo, sharingRepo)
+
+	txnRepo.On("GetByID", uint(5)).Return(&models.Transaction{ID: 5, UserID: 1, Type: "expense", Amount: 100}, nil)
+	txnRepo.On("GetByID", uint(6)).Return(&models.Transaction{ID: 6, UserID: 2, Type: "expense", Amount: 100}, nil)
+	sharingRepo.On("HasAccess", mock.Anything, mock.Anything).Return(false, nil)
+
+	// Not paired
+	_, err := svc.Split(1, 5, models.SplitInput{Method: models.SplitMethodEqual, Shares: []models.SplitShareInput{{UserID: 3}}})
+	assert.Equal(t, http.StatusBadRequest, statusOf(err))
+
+	// Only the payer's share
+	_, err = svc.Split(1, 5, models.SplitInput{Method: models.SplitMethodEqual, Shares: []models.SplitShareInput{{UserID: 1}}})
+	assert.Equal(t, http.StatusBadRequest, statusOf(err))
+
+	// Someone else's transaction
+	_, err = svc.Split(1, 6, models.SplitInput{Method: models.SplitMethodEqual, Shares: []models.SplitShareInput{{UserID: 2}}})
+	assert.Equal(t, http.StatusNotFound, statusOf(err))
+
+	splitRepo.AssertNotCalled(t, "SaveSplit", mock.Anything)
+}
+
+func TestSplitService_Summary(t *testing.T) {
+	splitRepo := new(mockSplitRepo)
+	svc := newTestSplitService(splitRepo, nil, nil)
+
+	// User 1 paid dinner for 2 and 3; 2 paid a trip shared with 1 and 3;
+	// 3 already paid 1 back 100
+	dinner := split(1, map[uint]float64{1: 300, 2: 300, 3: 300})
+	trip := split(2, map[uint]float64{1: 500, 2: 500, 3: 500})
+	settlement := models.Settlement{FromUserID: 3, ToUserID: 1, Amount: 100}
+
+	splitRepo.On("ListByUser", uint(1)).Return([]models.TransactionSplit{dinner, trip}, nil)
+	splitRepo.On("ListSettlements", uint(1)).Return([]models.Settlement{settlement}, nil)
+	splitRepo.On("ListAmong", mock.Anything).Return([]models.TransactionSplit{dinner, trip}, nil)
+	splitRepo.On("ListSettlementsAmong", mock.Anything).Return([]models.Settlement{settlement}, nil)
+
+	summary, err := svc.Summary(1)
+
+	require.NoError(t, err)
+	require.Len(t, summary.Balances, 2)
+	// User 1 owes 2 a net 200; 3 owes 1 a net 200
+	assert.Equal(t, uint(2), summary.Balances[0].UserID)
+	assert.Equal(t, -200.0, summary.Balances[0].Amount)
+	assert.Equal(t, 200.0, summary.Balances[1].Amount)
+	assert.Equal(t, 0.0, summary.Net)
+
+	// User 1 is square overall, so 3 can pay 2 directly and 1 pays nothing
+	assert.Empty(t, summary.Suggestions)
+}
+
+func TestSimplifyDebts(t *testing.T) {
+	suggestions := simplifyDebts(map[uint]float64{1: 150, 2: -100, 3: -50, 4: 0})
+
+	require.Len(t, suggestions, 2)
+	assert.Equal(t, models.SettleUpSuggestion{FromUserID: 2, ToUserID: 1, Amount: 100}, suggestions[0])
+	assert.Equal(t, models.SettleUpSuggestion{FromUserID: 3, ToUserID: 1, Amount: 50}, suggestions[1])
+}
+
+func TestSplitService_RecordSettlement(t *testing.T) {
+	splitRepo := new(mockSplitRepo)
+	sharingRepo := new(mockSharingRepo)
+	svc := newTestSplitService(splitRepo, nil, sharingRepo)
+
+	sharingRepo.On("HasAccess", uint(1), uint(2)).Return(true, nil)
+	splitRepo.On("CreateSettlement", mock.Anything).Return(nil)
+
+	settlement, err := svc.RecordSettlement(1, models.SettlementInput{FromUserID: 2, ToUserID: 1, Amount: float(200)})
+	require.NoError(t, err)
+	assert.Equal(t, ymd(2026, 6, 15), settlement.Date)
+	assert.Equal(t, uint(1), settlement.CreatedByID)
+
+	// Cannot record payments between other users
+	_, err = svc.RecordSettlement(1, models.SettlementInput{FromUserID: 2, ToUserID: 3, Amount: float(200)})
+	assert.Equal(t, http.StatusBadRequest, statusOf(err))
+}
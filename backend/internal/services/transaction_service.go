@@ -3,7 +3,8 @@ package services
 import (
 	"billing-note/internal/models"
 	"billing-note/internal/repository"
-	"errors"
+	"billing-note/pkg/errors"
+	stderrors "errors"
 	"time"
 )
 
@@ -16,12 +17,20 @@ type TransactionService interface {
 	GetMonthlyStats(userID uint, year int, month int) (map[string]float64, error)
 	GetCategoryStats(userID uint, startDate, endDate time.Time, transactionType string) ([]map[string]interface{}, error)
 	GetTrendStats(userID uint, months int) ([]repository.TrendDataPoint, error)
+	// WithScope returns a service limited to the share scope; a nil scope
+	// returns the service unchanged
+	WithScope(scope *models.ShareScope) TransactionService
+	// ForLedger returns a service working on the household ledger; a nil
+	// ledger ID selects the user's personal ledger
+	ForLedger(ledgerID *uint) TransactionService
 }
 
 type CreateTransactionRequest struct {
 	CategoryID      *uint     `json:"category_id"`
+	AccountID       *uint     `json:"account_id"`
+	ToAccountID     *uint     `json:"to_account_id"`
 	Amount          float64   `json:"amount" binding:"required,gt=0"`
-	Type            string    `json:"type" binding:"required,oneof=income expense"`
+	Type            string    `json:"type" binding:"required,oneof=income expense transfer"`
 	Description     string    `json:"description"`
 	TransactionDate time.Time `json:"transaction_date" binding:"required"`
 	Source          string    `json:"source"`
@@ -30,28 +39,73 @@ type CreateTransactionRequest struct {
 
 type UpdateTransactionRequest struct {
 	CategoryID      *uint     `json:"category_id"`
+	AccountID       *uint     `json:"account_id"`
+	ToAccountID     *uint     `json:"to_account_id"`
 	Amount          float64   `json:"amount" binding:"gt=0"`
-	Type            string    `json:"type" binding:"oneof=income expense"`
+	Type            string    `json:"type" binding:"oneof=income expense transfer"`
 	Description     string    `json:"description"`
 	TransactionDate time.Time `json:"transaction_date"`
 	Tags            []string  `json:"tags"`
 }
 
+// TransactionListener is notified after transactions are created, updated or imported
+type TransactionListener interface {
+	TransactionsSaved(userID uint, transactions []models.Transaction)
+}
+
 type transactionService struct {
-	repo repository.TransactionRepository
+	repo        repository.TransactionRepository
+	accountRepo repository.AccountRepository
+	listeners   []TransactionListener
+	scope       *models.ShareScope
+	ledgerID    *uint
+}
+
+func NewTransactionService(repo repository.TransactionRepository, accountRepo repository.AccountRepository, listeners ...TransactionListener) TransactionService {
+	return &transactionService{repo: repo, accountRepo: accountRepo, listeners: listeners}
+}
+
+func (s *transactionService) WithScope(scope *models.ShareScope) TransactionService {
+	if scope == nil {
+		return s
+	}
+	return &transactionService{repo: s.repo.WithScope(scope), accountRepo: s.accountRepo, listeners: s.listeners, scope: scope, ledgerID: s.ledgerID}
+}
+
+func (s *transactionService) ForLedger(ledgerID *uint) TransactionService {
+	if ledgerID == nil && s.ledgerID == nil {
+		return s
+	}
+	// Listeners track personal goals and budgets, so ledger entries skip them
+	listeners := s.listeners
+	if ledgerID != nil {
+		listeners = nil
+	}
+	return &transactionService{repo: s.repo.ForLedger(ledgerID), accountRepo: s.accountRepo, listeners: listeners, scope: s.scope, ledgerID: ledgerID}
 }
 
-func NewTransactionService(repo repository.TransactionRepository) TransactionService {
-	return &transactionService{repo: repo}
+// owns reports whether the transaction belongs to the ledger the service
+// works on: the household ledger, or the user's personal one
+func (s *transactionService) owns(transaction *models.Transaction, userID uint) bool {
+	if s.ledgerID != nil {
+		return transaction.LedgerID != nil && *transaction.LedgerID == *s.ledgerID
+	}
+	return transaction.UserID == userID && transaction.LedgerID == nil
+}
+
+func (s *transactionService) notifySaved(userID uint, transaction *models.Transaction) {
+	for _, l := range s.listeners {
+		l.TransactionsSaved(userID, []models.Transaction{*transaction})
+	}
 }
 
 func (s *transactionService) CreateTransaction(userID uint, req *CreateTransactionRequest) (*models.Transaction, error) {
 	if req.Amount <= 0 {
-		return nil, errors.New("amount must be greater than 0")
+		return nil, stderrors.New("amount must be greater than 0")
 	}
 
-	if req.Type != "income" && req.Type != "expense" {
-		return nil, errors.New("type must be either 'income' or 'expense'")
+	if req.Type != "income" && req.Type != "expense" && req.Type != "transfer" {
+		return nil, stderrors.New("type must be 'income', 'expense' or 'transfer'")
 	}
 
 	source := req.Source
@@ -66,7 +120,10 @@ func (s *transactionService) CreateTransaction(userID uint, req *CreateTransacti
 
 	transaction := &models.Transaction{
 		UserID:          userID,
+		LedgerID:        s.ledgerID,
 		CategoryID:      req.CategoryID,
+		AccountID:       req.AccountID,
+		ToAccountID:     req.ToAccountID,
 		Amount:          req.Amount,
 		Type:            req.Type,
 		Description:     req.Description,
@@ -74,22 +131,65 @@ func (s *transactionService) CreateTransaction(userID uint, req *CreateTransacti
 		Source:          source,
 		Tags:            tags,
 	}
+	if err := validateTransfer(transaction); err != nil {
+		return nil, err
+	}
+	if err := s.checkAccounts(userID, transaction.AccountID, transaction.ToAccountID); err != nil {
+		return nil, err
+	}
+	if !s.scope.AllowsTransaction(transaction) {
+		return nil, stderrors.New("transaction is outside the shared scope")
+	}
 
 	if err := s.repo.Create(transaction); err != nil {
 		return nil, err
 	}
+	s.notifySaved(userID, transaction)
 
 	return transaction, nil
 }
 
+// validateTransfer checks a transfer's accounts. Transfers move money between
+// the user's own accounts, so they need at least one side and cannot loop.
+func validateTransfer(transaction *models.Transaction) error {
+	if transaction.Type != "transfer" {
+		if transaction.ToAccountID != nil {
+			return stderrors.New("to_account_id is only allowed for transfers")
+		}
+		return nil
+	}
+	if transaction.AccountID == nil && transaction.ToAccountID == nil {
+		return stderrors.New("a transfer needs a source or destination account")
+	}
+	if transaction.AccountID != nil && transaction.ToAccountID != nil && *transaction.AccountID == *transaction.ToAccountID {
+		return stderrors.New("a transfer's source and destination accounts must differ")
+	}
+	return nil
+}
+
+// checkAccounts rejects account IDs that are not the user's own accounts
+func (s *transactionService) checkAccounts(userID uint, accountID, toAccountID *uint) error {
+	if accountID != nil {
+		if _, err := s.accountRepo.GetByID(userID, *accountID); err != nil {
+			return errors.NewInvalidInputError("account_id", "is not one of your accounts")
+		}
+	}
+	if toAccountID != nil {
+		if _, err := s.accountRepo.GetByID(userID, *toAccountID); err != nil {
+			return errors.NewInvalidInputError("to_account_id", "is not one of your accounts")
+		}
+	}
+	return nil
+}
+
 func (s *transactionService) GetTransaction(id uint, userID uint) (*models.Transaction, error) {
 	transaction, err := s.repo.GetByID(id)
 	if err != nil {
 		return nil, err
 	}
 
-	if transaction.UserID != userID {
-		return nil, errors.New("unauthorized access to transaction")
+	if !s.owns(transaction, userID) {
+		return nil, stderrors.New("unauthorized access to transaction")
 	}
 
 	return transaction, nil
@@ -106,8 +206,8 @@ func (s *transactionService) UpdateTransaction(id uint, userID uint, req *Update
 		return nil, err
 	}
 
-	if transaction.UserID != userID {
-		return nil, errors.New("unauthorized access to transaction")
+	if !s.owns(transaction, userID) {
+		return nil, stderrors.New("unauthorized access to transaction")
 	}
 
 	// Update fields if provided
@@ -115,14 +215,29 @@ func (s *transactionService) UpdateTransaction(id uint, userID uint, req *Update
 		transaction.Amount = req.Amount
 	}
 	if req.Type != "" {
-		if req.Type != "income" && req.Type != "expense" {
-			return nil, errors.New("type must be either 'income' or 'expense'")
+		if req.Type != "income" && req.Type != "expense" && req.Type != "transfer" {
+			return nil, stderrors.New("type must be 'income', 'expense' or 'transfer'")
 		}
 		transaction.Type = req.Type
 	}
 	if req.CategoryID != nil {
 		transaction.CategoryID = req.CategoryID
 	}
+	if req.AccountID != nil {
+		transaction.AccountID = req.AccountID
+	}
+	if req.ToAccountID != nil {
+		transaction.ToAccountID = req.ToAccountID
+	}
+	if transaction.Type != "transfer" {
+		transaction.ToAccountID = nil
+	}
+	if err := validateTransfer(transaction); err != nil {
+		return nil, err
+	}
+	if err := s.checkAccounts(userID, req.AccountID, req.ToAccountID); err != nil {
+		return nil, err
+	}
 	if req.Description != "" {
 		transaction.Description = req.Description
 	}
@@ -132,10 +247,14 @@ func (s *transactionService) UpdateTransaction(id uint, userID uint, req *Update
 	if req.Tags != nil {
 		transaction.Tags = req.Tags
 	}
+	if !s.scope.AllowsTransaction(transaction) {
+		return nil, stderrors.New("transaction is outside the shared scope")
+	}
 
 	if err := s.repo.Update(transaction); err != nil {
 		return nil, err
 	}
+	s.notifySaved(userID, transaction)
 
 	return transaction, nil
 }
@@ -146,8 +265,8 @@ func (s *transactionService) DeleteTransaction(id uint, userID uint) error {
 		return err
 	}
 
-	if transaction.UserID != userID {
-		return errors.New("unauthorized access to transaction")
+	if !s.owns(transaction, userID) {
+		return stderrors.New("unauthorized access to transaction")
 	}
 
 	return s.repo.Delete(id)
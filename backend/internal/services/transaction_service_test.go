@@ -0,0 +1,74 @@
+package services
+
+import (
+	"billing-note/internal/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func TestCreateTransaction_OwnAccounts(t *testing.T) {
+	txnRepo := new(mockTransactionRepo)
+	accountRepo := new(mockAccountRepo)
+	svc := NewTransactionService(txnRepo, accountRepo)
+
+	bankID, cardID := uint(3), uint(4)
+	accountRepo.On("GetByID", uint(1), bankID).Return(&models.Account{ID: bankID, UserID: 1}, nil)
+	accountRepo.On("GetByID", uint(1), cardID).Return(&models.Account{ID: cardID, UserID: 1}, nil)
+	txnRepo.On("Create", mock.AnythingOfType("*models.Transaction")).Return(nil)
+
+	txn, err := svc.CreateTransaction(1, &CreateTransactionRequest{
+		AccountID:       &bankID,
+		ToAccountID:     &cardID,
+		Amount:          5000,
+		Type:            "transfer",
+		TransactionDate: time.Now(),
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, bankID, *txn.AccountID)
+	assert.Equal(t, cardID, *txn.ToAccountID)
+	accountRepo.AssertExpectations(t)
+}
+
+func TestCreateTransaction_RejectsOtherUsersAccount(t *testing.T) {
+	txnRepo := new(mockTransactionRepo)
+	accountRepo := new(mockAccountRepo)
+	svc := NewTransactionService(txnRepo, accountRepo)
+
+	// Account 9 belongs to someone else, so the user's lookup finds nothing
+	otherID := uint(9)
+	accountRepo.On("GetByID", uint(1), otherID).Return(nil, gorm.ErrRecordNotFound)
+
+	_, err := svc.CreateTransaction(1, &CreateTransactionRequest{
+		AccountID:       &otherID,
+		Amount:          100,
+		Type:            "expense",
+		TransactionDate: time.Now(),
+	})
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "account_id")
+	txnRepo.AssertNotCalled(t, "Create", mock.Anything)
+}
+
+func TestUpdateTransaction_RejectsOtherUsersToAccount(t *testing.T) {
+	txnRepo := new(mockTransactionRepo)
+	accountRepo := new(mockAccountRepo)
+	svc := NewTransactionService(txnRepo, accountRepo)
+
+	bankID, otherID := uint(3), uint(9)
+	existing := &models.Transaction{ID: 1, UserID: 1, AccountID: &bankID, Amount: 5000, Type: "transfer", TransactionDate: time.Now()}
+	txnRepo.On("GetByID", uint(1)).Return(existing, nil)
+	accountRepo.On("GetByID", uint(1), otherID).Return(nil, gorm.ErrRecordNotFound)
+
+	_, err := svc.UpdateTransaction(1, 1, &UpdateTransactionRequest{ToAccountID: &otherID})
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "to_account_id")
+	txnRepo.AssertNotCalled(t, "Update", mock.Anything)
+}
@@ -0,0 +1,320 @@
+package services
+
+import (
+	"billing-note/internal/models"
+	"billing-note/internal/repository"
+	"billing-note/pkg/crypto"
+	"billing-note/pkg/errors"
+	"billing-note/pkg/logger"
+	"billing-note/pkg/utils"
+	"crypto/rand"
+	"encoding/base32"
+	"strings"
+	"time"
+)
+
+const (
+	// totpIssuer names the account in authenticator apps
+	totpIssuer = "Billing Note"
+	// recoveryCodeCount is how many recovery codes are issued at a time
+	recoveryCodeCount = 10
+	// Wrong codes allowed within twoFactorLockout before verification is refused
+	maxTwoFactorFailures = 5
+	twoFactorLockout     = 15 * time.Minute
+)
+
+var recoveryCodeEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// TwoFactorService manages TOTP enrollment, recovery codes and the
+// re-verification required before sensitive actions
+type TwoFactorService struct {
+	repo        repository.TwoFactorRepository
+	userRepo    repository.UserRepository
+	sessionRepo repository.SessionRepository
+	crypto      *crypto.AESCrypto
+	now         func() time.Time
+}
+
+// NewTwoFactorService creates a new two-factor service; TOTP secrets are
+// encrypted with the encryption key
+func NewTwoFactorService(repo repository.TwoFactorRepository, userRepo repository.UserRepository, sessionRepo repository.SessionRepository, encryptionKey string) (*TwoFactorService, error) {
+	aesCrypto, err := crypto.NewAESCrypto(encryptionKey)
+	if err != nil {
+		return nil, errors.NewEncryptionError("initialization", err)
+	}
+	return &TwoFactorService{
+		repo:        repo,
+		userRepo:    userRepo,
+		sessionRepo: sessionRepo,
+		crypto:      aesCrypto,
+		now:         time.Now,
+	}, nil
+}
+
+// Status returns whether two-factor is enabled and how many recovery codes are left
+func (s *TwoFactorService) Status(userID uint) (*models.TwoFactorStatus, error) {
+	twoFactor, err := s.repo.Get(userID)
+	if err != nil {
+		return nil, errors.NewDBError("get two-factor", err)
+	}
+	status := &models.TwoFactorStatus{}
+	if twoFactor == nil || !twoFactor.Enabled() {
+		return status, nil
+	}
+	status.Enabled = true
+	status.EnabledAt = twoFactor.EnabledAt
+	status.RecoveryCodesRemaining, err = s.repo.CountRecoveryCodes(userID)
+	if err != nil {
+		return nil, errors.NewDBError("count recovery codes", err)
+	}
+	return status, nil
+}
+
+// Required reports whether the user must pass the second factor to log in
+func (s *TwoFactorService) Required(userID uint) (bool, error) {
+	twoFactor, err := s.repo.Get(userID)
+	if err != nil {
+		return false, errors.NewDBError("get two-factor", err)
+	}
+	return twoFactor != nil && twoFactor.Enabled(), nil
+}
+
+// Setup starts enrollment with a new secret, replacing any unconfirmed one.
+// Two-factor stays off until Enable confirms a code from the authenticator.
+func (s *TwoFactorService) Setup(userID uint) (*models.TwoFactorSetup, error) {
+	existing, err := s.repo.Get(userID)
+	if err != nil {
+		return nil, errors.NewDBError("get two-factor", err)
+	}
+	if existing != nil && existing.Enabled() {
+		return nil, errors.NewConflictError("Two-factor authentication is already enabled")
+	}
+
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return nil, errors.NewNotFoundError("User", userID)
+	}
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		return nil, errors.NewInternalError("Failed to generate two-factor secret", err)
+	}
+	encrypted, err := s.crypto.Encrypt(secret)
+	if err != nil {
+		return nil, errors.NewEncryptionError("two-factor secret encryption", err)
+	}
+
+	if err := s.repo.Save(&models.UserTwoFactor{UserID: userID, SecretEncrypted: encrypted}); err != nil {
+		return nil, errors.NewDBError("save two-factor", err)
+	}
+
+	return &models.TwoFactorSetup{
+		Secret:     secret,
+		OTPAuthURL: utils.TOTPURI(totpIssuer, user.Email, secret),
+	}, nil
+}
+
+// Enable confirms enrollment with a code from the authenticator and returns
+// the recovery codes, which are only shown this once
+func (s *TwoFactorService) Enable(userID, sessionID uint, code string) ([]string, error) {
+	log := logger.ServiceLog("TwoFactorService", "Enable")
+
+	twoFactor, err := s.repo.Get(userID)
+	if err != nil {
+		return nil, errors.NewDBError("get two-factor", err)
+	}
+	if twoFactor == nil {
+		return nil, errors.NewValidationError("Start two-factor setup first")
+	}
+	if twoFactor.Enabled() {
+		return nil, errors.NewConflictError("Two-factor authentication is already enabled")
+	}
+
+	if err := s.checkCode(twoFactor, code, false); err != nil {
+		return nil, err
+	}
+	now := s.now()
+	twoFactor.EnabledAt = &now
+	if err := s.repo.Save(twoFactor); err != nil {
+		return nil, errors.NewDBError("save two-factor", err)
+	}
+
+	codes, err := s.issueRecoveryCodes(userID)
+	if err != nil {
+		return nil, err
+	}
+	if sessionID != 0 {
+		if err := s.sessionRepo.MarkVerified(sessionID, now); err != nil {
+			return nil, errors.NewDBError("mark session verified", err)
+		}
+	}
+
+	log.WithField("user_id", userID).Info("Two-factor authentication enabled")
+	return codes, nil
+}
+
+// Disable turns two-factor off after checking a TOTP or recovery code
+func (s *TwoFactorService) Disable(userID uint, code string) error {
+	log := logger.ServiceLog("TwoFactorService", "Disable")
+
+	if err := s.VerifyCode(userID, code); err != nil {
+		return err
+	}
+	if err := s.repo.Delete(userID); err != nil {
+		return errors.NewDBError("delete two-factor", err)
+	}
+
+	log.WithField("user_id", userID).Info("Two-factor authentication disabled")
+	return nil
+}
+
+// RegenerateRecoveryCodes replaces the recovery codes after checking a TOTP
+// or recovery code
+func (s *TwoFactorService) RegenerateRecoveryCodes(userID uint, code string) ([]string, error) {
+	if err := s.VerifyCode(userID, code); err != nil {
+		return nil, err
+	}
+	return s.issueRecoveryCodes(userID)
+}
+
+// VerifyCode checks a TOTP or recovery code for a user with two-factor
+// enabled. Too many wrong codes lock verification for a while.
+func (s *TwoFactorService) VerifyCode(userID uint, code string) error {
+	twoFactor, err := s.repo.Get(userID)
+	if err != nil {
+		return errors.NewDBError("get two-factor", err)
+	}
+	if twoFactor == nil || !twoFactor.Enabled() {
+		return errors.NewValidationError("Two-factor authentication is not enabled")
+	}
+	return s.checkCode(twoFactor, code, true)
+}
+
+// Reverify re-confirms the user's identity on the session, unlocking
+// sensitive actions for a while. Users with two-factor give a code, others
+// their password.
+func (s *TwoFactorService) Reverify(userID, sessionID uint, input models.ReverifyInput) error {
+	if sessionID == 0 {
+		return errors.NewValidationError("Request is not bound to a session")
+	}
+
+	required, err := s.Required(userID)
+	if err != nil {
+		return err
+	}
+	if required {
+		if input.Code == "" {
+			return errors.NewMissingFieldError("code")
+		}
+		if err := s.VerifyCode(userID, input.Code); err != nil {
+			return err
+		}
+	} else {
+		if input.Password == "" {
+			return errors.NewMissingFieldError("password")
+		}
+		user, err := s.userRepo.FindByID(userID)
+		if err != nil {
+			return errors.NewNotFoundError("User", userID)
+		}
+		if !user.CheckPassword(input.Password) {
+			return errors.NewInvalidInputError("password", "is incorrect")
+		}
+	}
+
+	if err := s.sessionRepo.MarkVerified(sessionID, s.now()); err != nil {
+		return errors.NewDBError("mark session verified", err)
+	}
+	return nil
+}
+
+// checkCode accepts a TOTP code for a step not used before, or, when
+// allowRecovery is set, an unused recovery code
+func (s *TwoFactorService) checkCode(twoFactor *models.UserTwoFactor, code string, allowRecovery bool) error {
+	log := logger.ServiceLog("TwoFactorService", "checkCode")
+
+	now := s.now()
+	if twoFactor.FailedAttempts >= maxTwoFactorFailures && twoFactor.LastFailedAt != nil &&
+		now.Sub(*twoFactor.LastFailedAt) < twoFactorLockout {
+		return errors.NewRateLimitError("Too many incorrect codes. Please try again later.")
+	}
+
+	secret, err := s.crypto.Decrypt(twoFactor.SecretEncrypted)
+	if err != nil {
+		return errors.NewDecryptionError(err)
+	}
+
+	if step, ok := utils.ValidateTOTP(secret, code, now); ok && step > twoFactor.LastUsedStep {
+		twoFactor.LastUsedStep = step
+		twoFactor.FailedAttempts = 0
+		twoFactor.LastFailedAt = nil
+		if err := s.repo.Save(twoFactor); err != nil {
+			return errors.NewDBError("save two-factor", err)
+		}
+		return nil
+	}
+
+	if allowRecovery {
+		if normalized := normalizeRecoveryCode(code); normalized != "" {
+			used, err := s.repo.UseRecoveryCode(twoFactor.UserID, hashToken(normalized), now)
+			if err != nil {
+				return errors.NewDBError("use recovery code", err)
+			}
+			if used {
+				log.WithField("user_id", twoFactor.UserID).Info("Recovery code used")
+				twoFactor.FailedAttempts = 0
+				twoFactor.LastFailedAt = nil
+				if err := s.repo.Save(twoFactor); err != nil {
+					return errors.NewDBError("save two-factor", err)
+				}
+				return nil
+			}
+		}
+	}
+
+	// Failures older than the lockout window no longer count
+	if twoFactor.LastFailedAt == nil || now.Sub(*twoFactor.LastFailedAt) >= twoFactorLockout {
+		twoFactor.FailedAttempts = 0
+	}
+	twoFactor.FailedAttempts++
+	twoFactor.LastFailedAt = &now
+	if err := s.repo.Save(twoFactor); err != nil {
+		return errors.NewDBError("save two-factor", err)
+	}
+
+	log.WithFields(logger.Fields{
+		"user_id":  twoFactor.UserID,
+		"failures": twoFactor.FailedAttempts,
+	}).Warn("Incorrect two-factor code")
+	return errors.NewInvalidInputError("code", "is incorrect or expired")
+}
+
+// issueRecoveryCodes replaces the user's recovery codes and returns them
+// formatted for display
+func (s *TwoFactorService) issueRecoveryCodes(userID uint) ([]string, error) {
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		b := make([]byte, 5)
+		if _, err := rand.Read(b); err != nil {
+			return nil, errors.NewInternalError("Failed to generate recovery codes", err)
+		}
+		code := strings.ToLower(recoveryCodeEncoding.EncodeToString(b))
+		codes[i] = code[:4] + "-" + code[4:]
+		hashes[i] = hashToken(code)
+	}
+	if err := s.repo.ReplaceRecoveryCodes(userID, hashes); err != nil {
+		return nil, errors.NewDBError("save recovery codes", err)
+	}
+	return codes, nil
+}
+
+// normalizeRecoveryCode drops the separator and case a user may type
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	code = strings.NewReplacer("-", "", " ", "").Replace(code)
+	if len(code) != 8 {
+		return ""
+	}
+	return code
+}
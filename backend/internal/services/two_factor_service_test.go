@@ -0,0 +1,244 @@
+package services
+
+import (
+	"billing-note/internal/models"
+	"billing-note/pkg/utils"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// --- Mock Two-Factor Repository ---
+
+type mockTwoFactorRepo struct {
+	mock.Mock
+}
+
+func (m *mockTwoFactorRepo) Get(userID uint) (*models.UserTwoFactor, error) {
+	args := m.Called(userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.UserTwoFactor), args.Error(1)
+}
+
+func (m *mockTwoFactorRepo) Save(twoFactor *models.UserTwoFactor) error {
+	args := m.Called(twoFactor)
+	return args.Error(0)
+}
+
+func (m *mockTwoFactorRepo) Delete(userID uint) error {
+	args := m.Called(userID)
+	return args.Error(0)
+}
+
+func (m *mockTwoFactorRepo) ReplaceRecoveryCodes(userID uint, hashes []string) error {
+	args := m.Called(userID, hashes)
+	return args.Error(0)
+}
+
+func (m *mockTwoFactorRepo) UseRecoveryCode(userID uint, hash string, at time.Time) (bool, error) {
+	args := m.Called(userID, hash, at)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *mockTwoFactorRepo) CountRecoveryCodes(userID uint) (int64, error) {
+	args := m.Called(userID)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+const testTOTPSecret = "JBSWY3DPEHPK3PXP"
+
+var twoFactorNow = time.Date(2026, 6, 15, 12, 0, 0, 0, time.UTC)
+
+func newTestTwoFactorService(t *testing.T) (*TwoFactorService, *mockTwoFactorRepo, *MockUserRepository, *mockSessionRepo) {
+	repo := new(mockTwoFactorRepo)
+	userRepo := new(MockUserRepository)
+	sessionRepo := new(mockSessionRepo)
+	svc, err := NewTwoFactorService(repo, userRepo, sessionRepo, "test-encryption-key")
+	require.NoError(t, err)
+	svc.now = func() time.Time { return twoFactorNow }
+	return svc, repo, userRepo, sessionRepo
+}
+
+// enrollment returns a two-factor record for the test secret
+func enrollment(t *testing.T, svc *TwoFactorService, enabled bool) *models.UserTwoFactor {
+	encrypted, err := svc.crypto.Encrypt(testTOTPSecret)
+	require.NoError(t, err)
+	twoFactor := &models.UserTwoFactor{UserID: 1, SecretEncrypted: encrypted}
+	if enabled {
+		enabledAt := twoFactorNow.Add(-24 * time.Hour)
+		twoFactor.EnabledAt = &enabledAt
+	}
+	return twoFactor
+}
+
+func currentCode(t *testing.T) string {
+	code, err := utils.TOTPCode(testTOTPSecret, utils.TOTPStep(twoFactorNow))
+	require.NoError(t, err)
+	return code
+}
+
+func TestTwoFactorService_Setup(t *testing.T) {
+	svc, repo, userRepo, _ := newTestTwoFactorService(t)
+
+	repo.On("Get", uint(1)).Return(nil, nil)
+	userRepo.On("FindByID", uint(1)).Return(&models.User{ID: 1, Email: "user@example.com"}, nil)
+	repo.On("Save", mock.Anything).Return(nil)
+
+	setup, err := svc.Setup(1)
+
+	require.NoError(t, err)
+	assert.Len(t, setup.Secret, 32)
+	assert.True(t, strings.HasPrefix(setup.OTPAuthURL, "otpauth://totp/"))
+	assert.Contains(t, setup.OTPAuthURL, "secret="+setup.Secret)
+
+	saved := repo.Calls[1].Arguments.Get(0).(*models.UserTwoFactor)
+	assert.NotContains(t, saved.SecretEncrypted, setup.Secret)
+	assert.False(t, saved.Enabled())
+}
+
+func TestTwoFactorService_Setup_AlreadyEnabled(t *testing.T) {
+	svc, repo, _, _ := newTestTwoFactorService(t)
+	repo.On("Get", uint(1)).Return(enrollment(t, svc, true), nil)
+
+	_, err := svc.Setup(1)
+
+	assert.Equal(t, http.StatusConflict, statusOf(err))
+}
+
+func TestTwoFactorService_Enable(t *testing.T) {
+	svc, repo, _, sessionRepo := newTestTwoFactorService(t)
+
+	twoFactor := enrollment(t, svc, false)
+	repo.On("Get", uint(1)).Return(twoFactor, nil)
+	repo.On("Save", twoFactor).Return(nil)
+	var hashes []string
+	repo.On("ReplaceRecoveryCodes", uint(1), mock.Anything).Run(func(args mock.Arguments) {
+		hashes = args.Get(1).([]string)
+	}).Return(nil)
+	sessionRepo.On("MarkVerified", uint(7), twoFactorNow).Return(nil)
+
+	codes, err := svc.Enable(1, 7, currentCode(t))
+
+	require.NoError(t, err)
+	assert.Len(t, codes, recoveryCodeCount)
+	assert.Regexp(t, `^[a-z2-7]{4}-[a-z2-7]{4}$`, codes[0])
+	assert.True(t, twoFactor.Enabled())
+	assert.Equal(t, utils.TOTPStep(twoFactorNow), twoFactor.LastUsedStep)
+
+	// Only hashes are stored
+	assert.Equal(t, hashToken(strings.ReplaceAll(codes[0], "-", "")), hashes[0])
+	sessionRepo.AssertExpectations(t)
+}
+
+func TestTwoFactorService_Enable_WrongCode(t *testing.T) {
+	svc, repo, _, _ := newTestTwoFactorService(t)
+
+	twoFactor := enrollment(t, svc, false)
+	repo.On("Get", uint(1)).Return(twoFactor, nil)
+	repo.On("Save", twoFactor).Return(nil)
+
+	_, err := svc.Enable(1, 7, "000000")
+
+	assert.Equal(t, http.StatusBadRequest, statusOf(err))
+	assert.False(t, twoFactor.Enabled())
+	assert.Equal(t, 1, twoFactor.FailedAttempts)
+	repo.AssertNotCalled(t, "ReplaceRecoveryCodes", mock.Anything, mock.Anything)
+}
+
+func TestTwoFactorService_VerifyCode_RejectsReplay(t *testing.T) {
+	svc, repo, _, _ := newTestTwoFactorService(t)
+
+	twoFactor := enrollment(t, svc, true)
+	repo.On("Get", uint(1)).Return(twoFactor, nil)
+	repo.On("Save", twoFactor).Return(nil)
+	repo.On("UseRecoveryCode", uint(1), mock.Anything, twoFactorNow).Return(false, nil)
+
+	code := currentCode(t)
+	assert.NoError(t, svc.VerifyCode(1, code))
+	assert.Equal(t, http.StatusBadRequest, statusOf(svc.VerifyCode(1, code)))
+}
+
+func TestTwoFactorService_VerifyCode_RecoveryCode(t *testing.T) {
+	svc, repo, _, _ := newTestTwoFactorService(t)
+
+	twoFactor := enrollment(t, svc, true)
+	repo.On("Get", uint(1)).Return(twoFactor, nil)
+	repo.On("Save", twoFactor).Return(nil)
+	repo.On("UseRecoveryCode", uint(1), hashToken("abcd2345"), twoFactorNow).Return(true, nil).Once()
+	repo.On("UseRecoveryCode", uint(1), hashToken("abcd2345"), twoFactorNow).Return(false, nil)
+
+	// Case and separator do not matter; each code works once
+	assert.NoError(t, svc.VerifyCode(1, "ABCD-2345"))
+	assert.Equal(t, http.StatusBadRequest, statusOf(svc.VerifyCode(1, "abcd-2345")))
+}
+
+func TestTwoFactorService_VerifyCode_LocksAfterFailures(t *testing.T) {
+	svc, repo, _, _ := newTestTwoFactorService(t)
+
+	twoFactor := enrollment(t, svc, true)
+	repo.On("Get", uint(1)).Return(twoFactor, nil)
+	repo.On("Save", twoFactor).Return(nil)
+
+	for i := 0; i < maxTwoFactorFailures; i++ {
+		assert.Equal(t, http.StatusBadRequest, statusOf(svc.VerifyCode(1, "000000")))
+	}
+
+	// Even the right code is refused while locked
+	assert.Equal(t, http.StatusTooManyRequests, statusOf(svc.VerifyCode(1, currentCode(t))))
+
+	// The lock lifts after the lockout window
+	svc.now = func() time.Time { return twoFactorNow.Add(twoFactorLockout) }
+	code, err := utils.TOTPCode(testTOTPSecret, utils.TOTPStep(twoFactorNow.Add(twoFactorLockout)))
+	require.NoError(t, err)
+	assert.NoError(t, svc.VerifyCode(1, code))
+	assert.Equal(t, 0, twoFactor.FailedAttempts)
+}
+
+func TestTwoFactorService_Disable(t *testing.T) {
+	svc, repo, _, _ := newTestTwoFactorService(t)
+
+	twoFactor := enrollment(t, svc, true)
+	repo.On("Get", uint(1)).Return(twoFactor, nil)
+	repo.On("Save", twoFactor).Return(nil)
+	repo.On("Delete", uint(1)).Return(nil)
+
+	assert.NoError(t, svc.Disable(1, currentCode(t)))
+	repo.AssertCalled(t, "Delete", uint(1))
+}
+
+func TestTwoFactorService_Reverify(t *testing.T) {
+	t.Run("password without two-factor", func(t *testing.T) {
+		svc, repo, userRepo, sessionRepo := newTestTwoFactorService(t)
+
+		user := &models.User{ID: 1}
+		user.SetPassword("password123")
+		repo.On("Get", uint(1)).Return(nil, nil)
+		userRepo.On("FindByID", uint(1)).Return(user, nil)
+		sessionRepo.On("MarkVerified", uint(7), twoFactorNow).Return(nil)
+
+		assert.Equal(t, http.StatusBadRequest, statusOf(svc.Reverify(1, 7, models.ReverifyInput{Password: "wrong"})))
+		assert.NoError(t, svc.Reverify(1, 7, models.ReverifyInput{Password: "password123"}))
+		sessionRepo.AssertNumberOfCalls(t, "MarkVerified", 1)
+	})
+
+	t.Run("code with two-factor", func(t *testing.T) {
+		svc, repo, _, sessionRepo := newTestTwoFactorService(t)
+
+		twoFactor := enrollment(t, svc, true)
+		repo.On("Get", uint(1)).Return(twoFactor, nil)
+		repo.On("Save", twoFactor).Return(nil)
+		sessionRepo.On("MarkVerified", uint(7), twoFactorNow).Return(nil)
+
+		// A password alone is not enough
+		assert.Equal(t, http.StatusBadRequest, statusOf(svc.Reverify(1, 7, models.ReverifyInput{Password: "password123"})))
+		assert.NoError(t, svc.Reverify(1, 7, models.ReverifyInput{Code: currentCode(t)}))
+		sessionRepo.AssertExpectations(t)
+	})
+}
@@ -1,11 +1,14 @@
 package services
 
 import (
+	"billing-note/internal/email"
 	"billing-note/internal/models"
 	"billing-note/internal/pdf"
 	"billing-note/internal/pdf/bank_parsers"
+	"encoding/json"
 	"fmt"
 	"io"
+	"math"
 	"mime/multipart"
 	"os"
 	"path/filepath"
@@ -22,6 +25,8 @@ type UploadService struct {
 	uploadDir       string
 	registry        *pdf.ParserRegistry
 	catKeywordSvc   *CategoryKeywordService
+	listeners       []TransactionListener
+	billService     *BillService
 }
 
 // SetCategoryKeywordService injects the keyword service for auto-classification
@@ -29,6 +34,17 @@ func (s *UploadService) SetCategoryKeywordService(svc *CategoryKeywordService) {
 	s.catKeywordSvc = svc
 }
 
+// AddTransactionListener registers a listener notified after imports
+func (s *UploadService) AddTransactionListener(l TransactionListener) {
+	s.listeners = append(s.listeners, l)
+}
+
+// SetBillService injects the bill service that records statement due dates
+// and settles bills from card payment lines
+func (s *UploadService) SetBillService(svc *BillService) {
+	s.billService = svc
+}
+
 // ParsedTransaction represents a transaction parsed from PDF
 type ParsedTransaction struct {
 	Date        time.Time `json:"date"`
@@ -38,15 +54,25 @@ type ParsedTransaction struct {
 	Category    string    `json:"category"`
 	CardLast4   string    `json:"card_last4"`
 	IsDuplicate bool      `json:"is_duplicate"`
+	// AccountID is the account the statement belongs to, if known
+	AccountID *uint `json:"account_id,omitempty"`
+	// Order email fields (empty for PDF statements)
+	Source      string            `json:"source,omitempty"`
+	Merchant    string            `json:"merchant,omitempty"`
+	OrderNumber string            `json:"order_number,omitempty"`
+	Items       []email.OrderItem `json:"items,omitempty"`
 }
 
 // UploadResult represents the result of PDF upload and parsing
 type UploadResult struct {
-	Filename     string              `json:"filename"`
-	Bank         string              `json:"bank"`
-	Transactions []ParsedTransaction `json:"transactions"`
-	TotalAmount  float64             `json:"total_amount"`
-	Error        string              `json:"error,omitempty"`
+	Filename     string                `json:"filename"`
+	Bank         string                `json:"bank"`
+	Transactions []ParsedTransaction   `json:"transactions"`
+	TotalAmount  float64               `json:"total_amount"`
+	Statement    *pdf.StatementSummary `json:"statement,omitempty"`
+	BillID       *uint                 `json:"bill_id,omitempty"`
+	AccountID    *uint                 `json:"account_id,omitempty"`
+	Error        string                `json:"error,omitempty"`
 }
 
 // NewUploadService creates a new upload service
@@ -98,12 +124,24 @@ func (s *UploadService) SaveUploadedFile(userID uint, file *multipart.FileHeader
 	return filePath, nil
 }
 
+// ParseOptions routes a PDF to a specific parser and password
+type ParseOptions struct {
+	BankHint         string // forced parser bank name, empty = auto-detect
+	PasswordPriority int    // password priority to try first, 0 = default order
+}
+
 // ParsePDF parses a PDF file and returns transactions
 func (s *UploadService) ParsePDF(userID uint, filePath string) (*UploadResult, error) {
+	return s.ParsePDFWithOptions(userID, filePath, ParseOptions{})
+}
+
+// ParsePDFWithOptions parses a PDF file using a forced bank parser and/or
+// preferred password priority
+func (s *UploadService) ParsePDFWithOptions(userID uint, filePath string, opts ParseOptions) (*UploadResult, error) {
 	filename := filepath.Base(filePath)
 
 	// Get user's passwords
-	passwords, err := s.passwordService.GetDecryptedPasswords(userID)
+	passwords, err := s.passwordService.GetDecryptedPasswordsPreferring(userID, opts.PasswordPriority)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get passwords: %w", err)
 	}
@@ -113,7 +151,7 @@ func (s *UploadService) ParsePDF(userID uint, filePath string) (*UploadResult, e
 	passwords = append(passwords, rulePasswords...)
 
 	// Parse the PDF
-	transactions, bankName, err := s.registry.Parse(filePath, passwords)
+	statement, err := s.registry.ParseStatement(filePath, passwords, opts.BankHint)
 	if err != nil {
 		fmt.Printf("Error parsing PDF %s: %v\n", filename, err)
 		return &UploadResult{
@@ -123,10 +161,10 @@ func (s *UploadService) ParsePDF(userID uint, filePath string) (*UploadResult, e
 	}
 
 	// Convert to ParsedTransaction and check for duplicates
-	parsedTransactions := make([]ParsedTransaction, len(transactions))
+	parsedTransactions := make([]ParsedTransaction, len(statement.Transactions))
 	totalAmount := 0.0
 
-	for i, t := range transactions {
+	for i, t := range statement.Transactions {
 		isDuplicate := s.checkDuplicate(userID, t)
 		parsedTransactions[i] = ParsedTransaction{
 			Date:        t.Date,
@@ -140,35 +178,166 @@ func (s *UploadService) ParsePDF(userID uint, filePath string) (*UploadResult, e
 		totalAmount += t.Amount
 	}
 
-	return &UploadResult{
+	result := &UploadResult{
 		Filename:     filename,
-		Bank:         bankName,
+		Bank:         statement.Bank,
 		Transactions: parsedTransactions,
 		TotalAmount:  totalAmount,
-	}, nil
+		Statement:    statement.Summary,
+	}
+
+	// Record the payment obligation printed on the statement
+	if s.billService != nil && statement.Summary != nil {
+		if bill, err := s.billService.RecordStatement(userID, statement.Bank, statement.Summary); err != nil {
+			fmt.Printf("Error recording bill for %s: %v\n", filename, err)
+		} else if bill != nil {
+			result.BillID = &bill.ID
+			// A statement's lines belong to its card account
+			if bill.AccountID != nil {
+				result.AccountID = bill.AccountID
+				for i := range result.Transactions {
+					result.Transactions[i].AccountID = bill.AccountID
+				}
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// BankNames returns the names of the available bank parsers
+func (s *UploadService) BankNames() []string {
+	return s.registry.BankNames()
 }
 
-// checkDuplicate checks if a transaction already exists
+// orderPostingDays is how many days after an order its card charge may post
+const orderPostingDays = 7
+
+// checkDuplicate checks if a transaction already exists, either as the same
+// statement line or as a shopping order imported from email. Amounts are
+// stored unsigned, so credits such as refunds and card payments compare by
+// their absolute value.
 func (s *UploadService) checkDuplicate(userID uint, t pdf.Transaction) bool {
 	var count int64
 	s.db.Model(&models.Transaction{}).
 		Where("user_id = ? AND transaction_date = ? AND amount = ? AND description = ?",
-			userID, t.Date, t.Amount, t.Description).
+			userID, t.Date, math.Abs(t.Amount), t.Description).
+		Count(&count)
+	if count > 0 {
+		return true
+	}
+
+	// An order email for the same amount, a few days before the charge, from
+	// a merchant the statement line names
+	s.db.Model(&models.Transaction{}).
+		Where("user_id = ? AND source = ? AND type = ? AND amount = ? AND transaction_date BETWEEN ? AND ?",
+			userID, "gmail_order", "expense", t.Amount, t.Date.AddDate(0, 0, -orderPostingDays), t.Date).
+		Where("? LIKE '%' || LOWER(split_part(merchant, ' ', 1)) || '%'", strings.ToLower(t.Description)).
+		Count(&count)
+	return count > 0
+}
+
+// checkOrderDuplicate checks if an order has already been imported, either
+// from an earlier email or as a line of a card statement
+func (s *UploadService) checkOrderDuplicate(userID uint, o email.Order) bool {
+	var count int64
+	s.db.Model(&models.Transaction{}).
+		Where("user_id = ? AND order_number = ?", userID, o.OrderNumber).
+		Count(&count)
+	if count > 0 {
+		return true
+	}
+
+	keyword := merchantKeyword(o.Merchant)
+	if keyword == "" || o.Date.IsZero() {
+		return false
+	}
+	day := time.Date(o.Date.Year(), o.Date.Month(), o.Date.Day(), 0, 0, 0, 0, time.UTC)
+	s.db.Model(&models.Transaction{}).
+		Where("user_id = ? AND source <> ? AND type = ? AND amount = ? AND transaction_date BETWEEN ? AND ?",
+			userID, "gmail_order", "expense", o.Total, day, day.AddDate(0, 0, orderPostingDays)).
+		Where("LOWER(description) LIKE ?", "%"+keyword+"%").
 		Count(&count)
 	return count > 0
 }
 
+// merchantKeyword returns the part of a merchant name a card statement line
+// is expected to contain, e.g. "uber" for "Uber Eats 優食 麥當勞"
+func merchantKeyword(merchant string) string {
+	fields := strings.Fields(merchant)
+	if len(fields) == 0 {
+		return ""
+	}
+	return strings.ToLower(fields[0])
+}
+
+// BuildOrderTransactions converts orders extracted from emails into parsed
+// transactions, flagging orders that were already imported
+func (s *UploadService) BuildOrderTransactions(userID uint, orders []email.Order) []ParsedTransaction {
+	parsed := make([]ParsedTransaction, 0, len(orders))
+	for _, o := range orders {
+		parsed = append(parsed, ParsedTransaction{
+			Date:        o.Date,
+			Description: orderDescription(o),
+			Amount:      o.Total,
+			Currency:    "TWD",
+			IsDuplicate: s.checkOrderDuplicate(userID, o),
+			Source:      "gmail_order",
+			Merchant:    o.Merchant,
+			OrderNumber: o.OrderNumber,
+			Items:       o.Items,
+		})
+	}
+	return parsed
+}
+
+// orderDescription builds a transaction description from the merchant and item names
+func orderDescription(o email.Order) string {
+	if len(o.Items) == 0 {
+		return fmt.Sprintf("%s 訂單 %s", o.Merchant, o.OrderNumber)
+	}
+	names := make([]string, 0, len(o.Items))
+	for _, item := range o.Items {
+		names = append(names, item.Name)
+	}
+	return o.Merchant + " - " + strings.Join(names, ", ")
+}
+
 // ImportTransactions imports parsed transactions to database
 func (s *UploadService) ImportTransactions(userID uint, transactions []ParsedTransaction) (int, error) {
 	imported := 0
+	var saved []models.Transaction
+	defer func() {
+		if len(saved) == 0 {
+			return
+		}
+		for _, l := range s.listeners {
+			l.TransactionsSaved(userID, saved)
+		}
+	}()
 
 	for _, t := range transactions {
 		if t.IsDuplicate {
 			continue
 		}
 
-		// Skip card payment / bill-pay transactions (not actual spending)
-		if shouldSkipTransaction(t.Description) {
+		source := t.Source
+		if source == "" {
+			source = "pdf_import"
+		}
+
+		// Card payment / bill-pay lines move money between accounts, not spending
+		if isTransferLine(t.Description) {
+			transfer, recorded, err := s.importTransfer(userID, t, source)
+			if err != nil {
+				return imported, fmt.Errorf("failed to import transfer: %w", err)
+			}
+			if !recorded {
+				continue
+			}
+			s.settleBill(userID, transfer)
+			saved = append(saved, *transfer)
+			imported++
 			continue
 		}
 
@@ -182,11 +351,19 @@ func (s *UploadService) ImportTransactions(userID uint, transactions []ParsedTra
 
 		transaction := models.Transaction{
 			UserID:          userID,
+			AccountID:       t.AccountID,
 			TransactionDate: t.Date,
 			Description:     t.Description,
 			Amount:          txAmount,
 			Type:            txType,
-			Source:          "pdf_import",
+			Source:          source,
+			Merchant:        t.Merchant,
+			OrderNumber:     t.OrderNumber,
+		}
+		if len(t.Items) > 0 {
+			if items, err := json.Marshal(t.Items); err == nil {
+				transaction.Items = items
+			}
 		}
 
 		// Try to find category from parsed data
@@ -205,15 +382,158 @@ func (s *UploadService) ImportTransactions(userID uint, transactions []ParsedTra
 		if err := s.db.Create(&transaction).Error; err != nil {
 			return imported, fmt.Errorf("failed to import transaction: %w", err)
 		}
+		saved = append(saved, transaction)
 		imported++
 	}
 
 	return imported, nil
 }
 
-// shouldSkipTransaction returns true for non-spending entries that should not
-// be imported as expenses (e.g., card bill payments, balance transfers).
-func shouldSkipTransaction(description string) bool {
+// transferPairDays is how far apart the bank debit and the card payment line
+// of one transfer may be dated
+const transferPairDays = 3
+
+// importTransfer records a card payment or bill-pay line as a transfer. The
+// line's account is the destination of a credit (the payment line on a card
+// statement) and the source of a debit (the bill-pay debit on a bank
+// statement). When the other side was imported already, the line completes
+// that transfer instead of creating a second one. The bool is false when the
+// line itself was imported before, e.g. from a re-uploaded statement.
+func (s *UploadService) importTransfer(userID uint, t ParsedTransaction, source string) (*models.Transaction, bool, error) {
+	amount := math.Abs(t.Amount)
+	incoming := t.Amount < 0
+
+	var candidates []models.Transaction
+	if err := s.db.Where("user_id = ? AND type = ? AND amount = ? AND transaction_date BETWEEN ? AND ?",
+		userID, "transfer", amount, t.Date.AddDate(0, 0, -transferPairDays), t.Date.AddDate(0, 0, transferPairDays)).
+		Order("transaction_date ASC, id ASC").
+		Find(&candidates).Error; err != nil {
+		return nil, false, err
+	}
+
+	if existing := duplicateTransfer(candidates, t, incoming); existing != nil {
+		// An import without the account may be completed by one with it
+		if t.AccountID != nil && setTransferSide(existing, t.AccountID, incoming) {
+			if err := s.db.Save(existing).Error; err != nil {
+				return nil, false, err
+			}
+		}
+		return existing, false, nil
+	}
+
+	if peer := pairTransfer(candidates, t.AccountID, incoming); peer != nil {
+		if t.AccountID != nil {
+			setTransferSide(peer, t.AccountID, incoming)
+			if err := s.db.Save(peer).Error; err != nil {
+				return nil, false, err
+			}
+		}
+		return peer, true, nil
+	}
+
+	transfer := &models.Transaction{
+		UserID:          userID,
+		TransactionDate: t.Date,
+		Description:     t.Description,
+		Amount:          amount,
+		Type:            "transfer",
+		Source:          source,
+	}
+	if incoming {
+		transfer.ToAccountID = t.AccountID
+	} else {
+		transfer.AccountID = t.AccountID
+	}
+	if err := s.db.Create(transfer).Error; err != nil {
+		return nil, false, err
+	}
+	return transfer, true, nil
+}
+
+// duplicateTransfer returns the transfer an earlier import of the same line
+// created: same date and description, with this line's side either its
+// account or still unknown
+func duplicateTransfer(candidates []models.Transaction, t ParsedTransaction, incoming bool) *models.Transaction {
+	for i := range candidates {
+		c := &candidates[i]
+		if !dateOf(c.TransactionDate, time.UTC).Equal(dateOf(t.Date, time.UTC)) || c.Description != t.Description {
+			continue
+		}
+		side, other := c.AccountID, c.ToAccountID
+		if incoming {
+			side, other = c.ToAccountID, c.AccountID
+		}
+		if side == nil {
+			if t.AccountID != nil && other != nil && *other == *t.AccountID {
+				continue // the other side of a transfer from this very account
+			}
+			return c
+		}
+		if t.AccountID != nil && *side == *t.AccountID {
+			return c
+		}
+	}
+	return nil
+}
+
+// setTransferSide records the line's account on the transfer, as the
+// destination of an incoming line or the source of an outgoing one. It
+// reports whether the transfer changed.
+func setTransferSide(transfer *models.Transaction, accountID *uint, incoming bool) bool {
+	side := &transfer.AccountID
+	if incoming {
+		side = &transfer.ToAccountID
+	}
+	if *side != nil {
+		return false
+	}
+	*side = accountID
+	return true
+}
+
+// pairTransfer returns the transfer, imported from the other side's
+// statement, that a line completes: one whose side for this line is still
+// unknown while its other side is known and is not the line's own account
+func pairTransfer(candidates []models.Transaction, accountID *uint, incoming bool) *models.Transaction {
+	for i := range candidates {
+		c := &candidates[i]
+		side, other := c.AccountID, c.ToAccountID
+		if incoming {
+			side, other = c.ToAccountID, c.AccountID
+		}
+		if side != nil || other == nil {
+			continue
+		}
+		if accountID != nil && *other == *accountID {
+			continue
+		}
+		return c
+	}
+	return nil
+}
+
+// settleBill marks the bill paid by a transfer and, if the bill belongs to a
+// card account, records that account as the transfer's destination
+func (s *UploadService) settleBill(userID uint, transfer *models.Transaction) {
+	if s.billService == nil {
+		return
+	}
+	bill, ok := s.billService.MatchPayment(userID, transfer.TransactionDate, transfer.Amount, &transfer.ID)
+	if !ok || bill.AccountID == nil || transfer.ToAccountID != nil {
+		return
+	}
+	if transfer.AccountID != nil && *transfer.AccountID == *bill.AccountID {
+		return
+	}
+	transfer.ToAccountID = bill.AccountID
+	if err := s.db.Model(transfer).Update("to_account_id", bill.AccountID).Error; err != nil {
+		fmt.Printf("Error linking transfer %d to card account: %v\n", transfer.ID, err)
+	}
+}
+
+// isTransferLine returns true for entries that move money between the user's
+// own accounts rather than spend it (e.g., card bill payments, balance transfers).
+func isTransferLine(description string) bool {
 	skipPatterns := []string{
 		"自動轉帳扣繳",  // auto-debit card payment
 		"繳信用卡款",    // pay credit card bill
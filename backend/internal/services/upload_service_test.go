@@ -0,0 +1,103 @@
+package services
+
+import (
+	"billing-note/internal/models"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsTransferLine(t *testing.T) {
+	assert.True(t, isTransferLine("繳信用卡款 國泰世華"))
+	assert.True(t, isTransferLine("自動轉帳扣繳"))
+	assert.False(t, isTransferLine("全聯福利中心"))
+}
+
+func TestPairTransfer(t *testing.T) {
+	bank, card, other := uint(1), uint(2), uint(3)
+
+	// The bank debit was imported first; the card's payment line completes it
+	candidates := []models.Transaction{
+		{ID: 10, Type: "transfer", AccountID: &bank, ToAccountID: &other}, // already paired
+		{ID: 11, Type: "transfer", AccountID: &bank},
+	}
+	peer := pairTransfer(candidates, &card, true)
+	require.NotNil(t, peer)
+	assert.Equal(t, uint(11), peer.ID)
+
+	// A second debit of the same amount is a separate transfer
+	assert.Nil(t, pairTransfer(candidates, &bank, false))
+
+	// A line never pairs with a transfer from its own account
+	assert.Nil(t, pairTransfer([]models.Transaction{{ID: 12, Type: "transfer", ToAccountID: &card}}, &card, false))
+	assert.NotNil(t, pairTransfer([]models.Transaction{{ID: 12, Type: "transfer", ToAccountID: &card}}, &bank, false))
+
+	// Transfers with neither side known cannot be paired
+	assert.Nil(t, pairTransfer([]models.Transaction{{ID: 13, Type: "transfer"}}, nil, true))
+}
+
+func TestValidateTransfer(t *testing.T) {
+	bank, card := uint(1), uint(2)
+
+	assert.NoError(t, validateTransfer(&models.Transaction{Type: "transfer", AccountID: &bank, ToAccountID: &card}))
+	assert.NoError(t, validateTransfer(&models.Transaction{Type: "transfer", ToAccountID: &card}))
+	assert.Error(t, validateTransfer(&models.Transaction{Type: "transfer"}))
+	assert.Error(t, validateTransfer(&models.Transaction{Type: "transfer", AccountID: &bank, ToAccountID: &bank}))
+	assert.Error(t, validateTransfer(&models.Transaction{Type: "expense", ToAccountID: &card}))
+}
+
+func TestDuplicateTransfer(t *testing.T) {
+	bank, card := uint(1), uint(2)
+	date := time.Date(2026, 3, 10, 0, 0, 0, 0, time.UTC)
+	payment := ParsedTransaction{Date: date, Description: "繳信用卡款 國泰世華", Amount: -12000, AccountID: &card}
+
+	// The card's payment line was imported before, with or without the account
+	imported := []models.Transaction{{ID: 10, Type: "transfer", TransactionDate: date, Description: payment.Description, AccountID: &bank, ToAccountID: &card}}
+	assert.NotNil(t, duplicateTransfer(imported, payment, true))
+	withoutAccount := []models.Transaction{{ID: 11, Type: "transfer", TransactionDate: date, Description: payment.Description}}
+	assert.NotNil(t, duplicateTransfer(withoutAccount, payment, true))
+	payment.AccountID = nil
+	assert.NotNil(t, duplicateTransfer(withoutAccount, payment, true))
+
+	// Another line, or the same line of another card, is not a duplicate
+	other := uint(3)
+	payment.AccountID = &other
+	assert.Nil(t, duplicateTransfer(imported, payment, true))
+	payment.Description = "繳信用卡款 玉山"
+	assert.Nil(t, duplicateTransfer(withoutAccount, payment, true))
+}
+
+func TestImportTransactions_ReimportedStatementKeepsOneTransfer(t *testing.T) {
+	db, sqlMock := setupUploadMockDB(t)
+	uploadSvc := NewUploadService(db, nil, t.TempDir())
+
+	card := uint(2)
+	date := time.Date(2026, 3, 10, 0, 0, 0, 0, time.UTC)
+	lines := []ParsedTransaction{{Date: date, Description: "繳信用卡款 國泰世華", Amount: -12000, AccountID: &card}}
+	transferColumns := []string{"id", "user_id", "type", "amount", "transaction_date", "description", "account_id", "to_account_id"}
+
+	// First upload: nothing to pair with, so the transfer is created
+	sqlMock.ExpectQuery(`SELECT \* FROM "transactions" WHERE user_id = \$1 AND type = \$2`).
+		WillReturnRows(sqlmock.NewRows(transferColumns))
+	sqlMock.ExpectBegin()
+	sqlMock.ExpectQuery(`INSERT INTO "transactions"`).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	sqlMock.ExpectCommit()
+
+	imported, err := uploadSvc.ImportTransactions(1, lines)
+	require.NoError(t, err)
+	assert.Equal(t, 1, imported)
+
+	// Second upload of the same statement finds that transfer
+	sqlMock.ExpectQuery(`SELECT \* FROM "transactions" WHERE user_id = \$1 AND type = \$2`).
+		WillReturnRows(sqlmock.NewRows(transferColumns).
+			AddRow(1, 1, "transfer", 12000.0, date, "繳信用卡款 國泰世華", nil, card))
+
+	imported, err = uploadSvc.ImportTransactions(1, lines)
+	require.NoError(t, err)
+	assert.Equal(t, 0, imported)
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
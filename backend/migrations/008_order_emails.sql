@@ -0,0 +1,9 @@
+-- Itemized transactions extracted from shopping order emails
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS merchant VARCHAR(255);
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS order_number VARCHAR(64);
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS items JSONB;
+
+CREATE INDEX IF NOT EXISTS idx_transactions_user_order_number ON transactions(user_id, order_number);
+
+-- Scan order emails (Shopee, momo, PChome, Uber Eats) alongside PDF statements
+ALTER TABLE gmail_scan_rules ADD COLUMN IF NOT EXISTS scan_orders BOOLEAN DEFAULT TRUE;
@@ -0,0 +1,14 @@
+-- Per-sender Gmail rules: route a sender to a bank parser and PDF password
+CREATE TABLE IF NOT EXISTS gmail_sender_rules (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    sender VARCHAR(255) NOT NULL,
+    bank_hint VARCHAR(50),
+    password_priority INT DEFAULT 0,
+    label VARCHAR(100),
+    enabled BOOLEAN DEFAULT TRUE,
+    created_at TIMESTAMP DEFAULT NOW(),
+    updated_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_gmail_sender_rules_user_id ON gmail_sender_rules(user_id);
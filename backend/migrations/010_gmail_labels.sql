@@ -0,0 +1,5 @@
+-- Label (and optionally archive) messages processed by the Gmail scan
+ALTER TABLE gmail_scan_rules ADD COLUMN IF NOT EXISTS apply_labels BOOLEAN DEFAULT FALSE;
+ALTER TABLE gmail_scan_rules ADD COLUMN IF NOT EXISTS imported_label VARCHAR(100) DEFAULT 'BillingNote/Imported';
+ALTER TABLE gmail_scan_rules ADD COLUMN IF NOT EXISTS failed_label VARCHAR(100) DEFAULT 'BillingNote/Failed';
+ALTER TABLE gmail_scan_rules ADD COLUMN IF NOT EXISTS archive_processed BOOLEAN DEFAULT FALSE;
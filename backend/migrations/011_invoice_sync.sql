@@ -0,0 +1,26 @@
+-- Scheduled e-invoice carrier sync state (per user)
+CREATE TABLE IF NOT EXISTS invoice_sync_states (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL UNIQUE REFERENCES users(id) ON DELETE CASCADE,
+    enabled BOOLEAN DEFAULT TRUE,
+    last_synced_date TIMESTAMP,
+    last_sync_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT NOW(),
+    updated_at TIMESTAMP DEFAULT NOW()
+);
+
+-- E-invoice sync history log
+CREATE TABLE IF NOT EXISTS invoice_sync_history (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    sync_at TIMESTAMP DEFAULT NOW(),
+    start_date TIMESTAMP,
+    end_date TIMESTAMP,
+    invoices_fetched INT DEFAULT 0,
+    invoices_created INT DEFAULT 0,
+    source VARCHAR(20) DEFAULT 'scheduled',
+    status VARCHAR(20) DEFAULT 'completed',
+    error_message TEXT
+);
+
+CREATE INDEX IF NOT EXISTS idx_invoice_sync_history_user ON invoice_sync_history(user_id, sync_at DESC);
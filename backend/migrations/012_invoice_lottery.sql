@@ -0,0 +1,26 @@
+-- Uniform invoice lottery winning numbers (one row per bi-monthly period)
+CREATE TABLE IF NOT EXISTS invoice_winning_numbers (
+    id SERIAL PRIMARY KEY,
+    period VARCHAR(5) NOT NULL UNIQUE,
+    special_prize VARCHAR(8),
+    grand_prize VARCHAR(8),
+    first_prizes TEXT[],
+    additional_sixth_prizes TEXT[],
+    source VARCHAR(20),
+    created_at TIMESTAMP DEFAULT NOW(),
+    updated_at TIMESTAMP DEFAULT NOW()
+);
+
+-- Winning invoices per user
+CREATE TABLE IF NOT EXISTS invoice_prizes (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    invoice_id INT NOT NULL UNIQUE REFERENCES invoices(id) ON DELETE CASCADE,
+    period VARCHAR(5) NOT NULL,
+    tier VARCHAR(20) NOT NULL,
+    amount DECIMAL(12, 2) NOT NULL,
+    transaction_id INT REFERENCES transactions(id) ON DELETE SET NULL,
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_invoice_prizes_user_period ON invoice_prizes(user_id, period);
@@ -0,0 +1,12 @@
+-- Budget periods beyond monthly
+ALTER TABLE budgets ADD COLUMN IF NOT EXISTS period_type VARCHAR(20) NOT NULL DEFAULT 'monthly';
+ALTER TABLE budgets ADD COLUMN IF NOT EXISTS start_day INT NOT NULL DEFAULT 1;
+ALTER TABLE budgets ADD COLUMN IF NOT EXISTS start_date DATE;
+ALTER TABLE budgets ADD COLUMN IF NOT EXISTS end_date DATE;
+
+-- A category may now have one budget per period type
+ALTER TABLE budgets DROP CONSTRAINT IF EXISTS budgets_user_id_category_id_key;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_budgets_user_category_period ON budgets(user_id, category_id, period_type);
+
+-- User time zone for period boundaries
+ALTER TABLE users ADD COLUMN IF NOT EXISTS timezone VARCHAR(64) NOT NULL DEFAULT 'Asia/Taipei';
@@ -0,0 +1,20 @@
+-- Budget rollover and per-period ledger
+ALTER TABLE budgets ADD COLUMN IF NOT EXISTS rollover BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE TABLE IF NOT EXISTS budget_ledger_entries (
+    id SERIAL PRIMARY KEY,
+    budget_id INT NOT NULL REFERENCES budgets(id) ON DELETE CASCADE,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    period_start DATE NOT NULL,
+    period_end DATE NOT NULL,
+    amount DECIMAL(10, 2) NOT NULL,
+    carry_in DECIMAL(10, 2) NOT NULL DEFAULT 0,
+    actual DECIMAL(10, 2) NOT NULL DEFAULT 0,
+    carry_out DECIMAL(10, 2) NOT NULL DEFAULT 0,
+    closed BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP DEFAULT NOW(),
+    updated_at TIMESTAMP DEFAULT NOW(),
+    UNIQUE(budget_id, period_start)
+);
+
+CREATE INDEX IF NOT EXISTS idx_budget_ledger_user_id ON budget_ledger_entries(user_id);
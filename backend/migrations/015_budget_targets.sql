@@ -0,0 +1,24 @@
+-- Accounts
+CREATE TABLE IF NOT EXISTS accounts (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    type VARCHAR(20) NOT NULL DEFAULT 'other',
+    created_at TIMESTAMP DEFAULT NOW(),
+    updated_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_accounts_user_id ON accounts(user_id);
+
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS account_id INT REFERENCES accounts(id) ON DELETE SET NULL;
+CREATE INDEX IF NOT EXISTS idx_transactions_account_id ON transactions(account_id);
+
+-- Budgets targeting the whole ledger, a category set, a tag or an account
+ALTER TABLE budgets ADD COLUMN IF NOT EXISTS target_type VARCHAR(20) NOT NULL DEFAULT 'category';
+ALTER TABLE budgets ALTER COLUMN category_id DROP NOT NULL;
+ALTER TABLE budgets ADD COLUMN IF NOT EXISTS category_ids INTEGER[];
+ALTER TABLE budgets ADD COLUMN IF NOT EXISTS tag VARCHAR(100);
+ALTER TABLE budgets ADD COLUMN IF NOT EXISTS account_id INT REFERENCES accounts(id) ON DELETE CASCADE;
+
+-- Supports the SQL aggregates behind budget comparison
+CREATE INDEX IF NOT EXISTS idx_transactions_user_type_date ON transactions(user_id, type, transaction_date);
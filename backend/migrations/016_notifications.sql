@@ -0,0 +1,37 @@
+-- In-app notifications
+CREATE TABLE IF NOT EXISTS notifications (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    type VARCHAR(50) NOT NULL,
+    title VARCHAR(255) NOT NULL,
+    message TEXT,
+    data JSONB,
+    read_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_notifications_user_created ON notifications(user_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_notifications_unread ON notifications(user_id) WHERE read_at IS NULL;
+
+-- External delivery channel preferences
+CREATE TABLE IF NOT EXISTS notification_settings (
+    user_id INT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    email_enabled BOOLEAN NOT NULL DEFAULT FALSE,
+    webhook_enabled BOOLEAN NOT NULL DEFAULT FALSE,
+    webhook_url VARCHAR(500),
+    webhook_secret VARCHAR(255),
+    created_at TIMESTAMP DEFAULT NOW(),
+    updated_at TIMESTAMP DEFAULT NOW()
+);
+
+-- Budget threshold alerts
+ALTER TABLE budgets ADD COLUMN IF NOT EXISTS alert_thresholds INTEGER[];
+
+CREATE TABLE IF NOT EXISTS budget_alerts (
+    id SERIAL PRIMARY KEY,
+    budget_id INT NOT NULL REFERENCES budgets(id) ON DELETE CASCADE,
+    period_start DATE NOT NULL,
+    threshold INT NOT NULL,
+    created_at TIMESTAMP DEFAULT NOW(),
+    UNIQUE(budget_id, period_start, threshold)
+);
@@ -0,0 +1,26 @@
+-- Payment obligations (card statements and manual bills) with due-date reminders
+CREATE TABLE IF NOT EXISTS bills (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    account_id INT REFERENCES accounts(id) ON DELETE SET NULL,
+    issuer VARCHAR(100),
+    description VARCHAR(255),
+    source VARCHAR(20) NOT NULL DEFAULT 'manual',
+    statement_date DATE,
+    due_date DATE NOT NULL,
+    amount_due DECIMAL(15, 2) NOT NULL DEFAULT 0,
+    minimum_due DECIMAL(15, 2) NOT NULL DEFAULT 0,
+    remind_days INT NOT NULL DEFAULT 3,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'paid')),
+    paid_at TIMESTAMP,
+    paid_amount DECIMAL(15, 2) NOT NULL DEFAULT 0,
+    payment_transaction_id INT REFERENCES transactions(id) ON DELETE SET NULL,
+    reminded_at TIMESTAMP,
+    overdue_notified_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT NOW(),
+    updated_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_bills_user_due ON bills(user_id, due_date);
+CREATE INDEX IF NOT EXISTS idx_bills_pending_due ON bills(due_date) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_bills_issuer_due ON bills(user_id, issuer, due_date);
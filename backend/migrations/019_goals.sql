@@ -0,0 +1,33 @@
+-- Savings goals
+CREATE TABLE IF NOT EXISTS goals (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    target_amount DECIMAL(15, 2) NOT NULL CHECK (target_amount > 0),
+    start_date DATE NOT NULL,
+    deadline DATE,
+    account_id INT REFERENCES accounts(id) ON DELETE SET NULL,
+    tag VARCHAR(50) NOT NULL DEFAULT '',
+    status VARCHAR(20) NOT NULL DEFAULT 'active' CHECK (status IN ('active', 'archived')),
+    created_at TIMESTAMP DEFAULT NOW(),
+    updated_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_goals_user_id ON goals(user_id);
+
+-- Contributions: manual, or derived from transactions on the goal's account or tag
+CREATE TABLE IF NOT EXISTS goal_contributions (
+    id SERIAL PRIMARY KEY,
+    goal_id INT NOT NULL REFERENCES goals(id) ON DELETE CASCADE,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    amount DECIMAL(15, 2) NOT NULL,
+    date DATE NOT NULL,
+    note VARCHAR(255),
+    source VARCHAR(20) NOT NULL DEFAULT 'manual',
+    transaction_id INT REFERENCES transactions(id) ON DELETE CASCADE,
+    created_at TIMESTAMP DEFAULT NOW(),
+    UNIQUE(goal_id, transaction_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_goal_contributions_goal_date ON goal_contributions(goal_id, date);
+CREATE INDEX IF NOT EXISTS idx_goal_contributions_transaction ON goal_contributions(transaction_id);
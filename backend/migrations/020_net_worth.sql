@@ -0,0 +1,17 @@
+-- Account balance snapshots: entered manually, taken from card statements or
+-- recorded when reconciling. Liability balances (cards, loans) are stored as
+-- the positive amount owed.
+CREATE TABLE IF NOT EXISTS account_balances (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    account_id INT NOT NULL REFERENCES accounts(id) ON DELETE CASCADE,
+    date DATE NOT NULL,
+    balance DECIMAL(15, 2) NOT NULL,
+    source VARCHAR(20) NOT NULL DEFAULT 'manual',
+    note VARCHAR(255),
+    created_at TIMESTAMP DEFAULT NOW(),
+    updated_at TIMESTAMP DEFAULT NOW(),
+    UNIQUE(account_id, date)
+);
+
+CREATE INDEX IF NOT EXISTS idx_account_balances_user_id ON account_balances(user_id);
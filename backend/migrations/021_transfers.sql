@@ -0,0 +1,7 @@
+-- Transfers between accounts: account_id is the source, to_account_id the
+-- destination. Either may be unknown until both statement lines are paired.
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS to_account_id INT REFERENCES accounts(id) ON DELETE SET NULL;
+CREATE INDEX IF NOT EXISTS idx_transactions_to_account_id ON transactions(to_account_id);
+
+-- Supports pairing a bank debit with the card payment line
+CREATE INDEX IF NOT EXISTS idx_transactions_user_type_amount ON transactions(user_id, type, amount);
@@ -0,0 +1,11 @@
+-- Sharing roles and optional scope
+ALTER TABLE shared_access ADD COLUMN IF NOT EXISTS role VARCHAR(20) NOT NULL DEFAULT 'viewer'
+    CHECK (role IN ('viewer', 'contributor', 'editor'));
+ALTER TABLE shared_access ADD COLUMN IF NOT EXISTS scope_start_date DATE;
+ALTER TABLE shared_access ADD COLUMN IF NOT EXISTS scope_end_date DATE;
+ALTER TABLE shared_access ADD COLUMN IF NOT EXISTS scope_category_ids INTEGER[];
+ALTER TABLE shared_access ADD COLUMN IF NOT EXISTS scope_account_ids INTEGER[];
+ALTER TABLE shared_access ADD COLUMN IF NOT EXISTS hide_descriptions BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE shared_access ADD COLUMN IF NOT EXISTS updated_at TIMESTAMP DEFAULT NOW();
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_shared_access_owner_viewer ON shared_access(owner_id, viewer_id);
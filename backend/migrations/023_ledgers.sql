@@ -0,0 +1,40 @@
+-- Household ledgers shared by several users
+CREATE TABLE IF NOT EXISTS ledgers (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    owner_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMP DEFAULT NOW(),
+    updated_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_ledgers_owner_id ON ledgers(owner_id);
+
+CREATE TABLE IF NOT EXISTS ledger_members (
+    id SERIAL PRIMARY KEY,
+    ledger_id INT NOT NULL REFERENCES ledgers(id) ON DELETE CASCADE,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    role VARCHAR(20) NOT NULL DEFAULT 'member' CHECK (role IN ('owner', 'member')),
+    created_at TIMESTAMP DEFAULT NOW(),
+    UNIQUE(ledger_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_ledger_members_user_id ON ledger_members(user_id);
+
+-- Entries owned by a ledger; NULL means personal (or, for categories, global)
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS ledger_id INT REFERENCES ledgers(id) ON DELETE CASCADE;
+ALTER TABLE budgets ADD COLUMN IF NOT EXISTS ledger_id INT REFERENCES ledgers(id) ON DELETE CASCADE;
+ALTER TABLE categories ADD COLUMN IF NOT EXISTS ledger_id INT REFERENCES ledgers(id) ON DELETE CASCADE;
+
+CREATE INDEX IF NOT EXISTS idx_transactions_ledger_date ON transactions(ledger_id, transaction_date);
+CREATE INDEX IF NOT EXISTS idx_budgets_ledger_id ON budgets(ledger_id);
+
+-- Category names are unique among global categories and within each ledger
+ALTER TABLE categories DROP CONSTRAINT IF EXISTS categories_name_key;
+DROP INDEX IF EXISTS idx_categories_name;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_categories_global_name ON categories(name) WHERE ledger_id IS NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_categories_ledger_name ON categories(ledger_id, name) WHERE ledger_id IS NOT NULL;
+
+-- A category budget is unique per period within the personal or household ledger
+DROP INDEX IF EXISTS idx_budgets_user_category_period;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_budgets_user_category_period ON budgets(user_id, category_id, period_type) WHERE ledger_id IS NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_budgets_ledger_category_period ON budgets(ledger_id, category_id, period_type) WHERE ledger_id IS NOT NULL;
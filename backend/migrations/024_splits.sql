@@ -0,0 +1,41 @@
+-- Shared-expense splits: the transaction owner paid, the other shares are owed to them
+CREATE TABLE IF NOT EXISTS transaction_splits (
+    id SERIAL PRIMARY KEY,
+    transaction_id INT NOT NULL UNIQUE REFERENCES transactions(id) ON DELETE CASCADE,
+    payer_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    method VARCHAR(20) NOT NULL CHECK (method IN ('equal', 'percent', 'exact')),
+    amount DECIMAL(15, 2) NOT NULL,
+    date DATE NOT NULL,
+    description VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMP DEFAULT NOW(),
+    updated_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_transaction_splits_payer_id ON transaction_splits(payer_id);
+
+CREATE TABLE IF NOT EXISTS split_shares (
+    id SERIAL PRIMARY KEY,
+    split_id INT NOT NULL REFERENCES transaction_splits(id) ON DELETE CASCADE,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    amount DECIMAL(15, 2) NOT NULL,
+    percent DECIMAL(7, 4),
+    UNIQUE(split_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_split_shares_user_id ON split_shares(user_id);
+
+-- Payments between users that clear split balances
+CREATE TABLE IF NOT EXISTS settlements (
+    id SERIAL PRIMARY KEY,
+    from_user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    to_user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    amount DECIMAL(15, 2) NOT NULL CHECK (amount > 0),
+    date DATE NOT NULL,
+    note VARCHAR(255),
+    created_by_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMP DEFAULT NOW(),
+    CHECK (from_user_id <> to_user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_settlements_from_user_id ON settlements(from_user_id);
+CREATE INDEX IF NOT EXISTS idx_settlements_to_user_id ON settlements(to_user_id);
@@ -0,0 +1,28 @@
+-- Pairing codes expire and have a limited number of uses; existing static
+-- codes expire immediately and are replaced on next request
+ALTER TABLE user_pairing_codes ADD COLUMN IF NOT EXISTS expires_at TIMESTAMP NOT NULL DEFAULT NOW();
+ALTER TABLE user_pairing_codes ADD COLUMN IF NOT EXISTS max_uses INT NOT NULL DEFAULT 1;
+ALTER TABLE user_pairing_codes ADD COLUMN IF NOT EXISTS use_count INT NOT NULL DEFAULT 0;
+
+-- Redeemed codes wait for the owner's approval before access is granted
+CREATE TABLE IF NOT EXISTS pairing_requests (
+    id SERIAL PRIMARY KEY,
+    owner_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    requester_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending'
+        CHECK (status IN ('pending', 'approved', 'rejected')),
+    decided_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_pairing_requests_owner_id ON pairing_requests(owner_id);
+CREATE INDEX IF NOT EXISTS idx_pairing_requests_requester_id ON pairing_requests(requester_id);
+
+-- Failed pairing attempts, used to rate limit code guessing
+CREATE TABLE IF NOT EXISTS pairing_attempts (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_pairing_attempts_user_created ON pairing_attempts(user_id, created_at);
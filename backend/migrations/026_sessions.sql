@@ -0,0 +1,16 @@
+-- Signed-in sessions with rotating refresh tokens
+CREATE TABLE IF NOT EXISTS sessions (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    refresh_token_hash VARCHAR(64) NOT NULL UNIQUE, -- SHA-256 hex of the current refresh token
+    previous_token_hash VARCHAR(64),                -- hash of the rotated-out token, for reuse detection
+    user_agent VARCHAR(255),
+    ip VARCHAR(45),
+    last_seen_at TIMESTAMP DEFAULT NOW(),
+    expires_at TIMESTAMP NOT NULL,
+    revoked_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_sessions_user_id ON sessions(user_id);
+CREATE INDEX IF NOT EXISTS idx_sessions_previous_token_hash ON sessions(previous_token_hash);
@@ -0,0 +1,25 @@
+-- TOTP two-factor authentication
+CREATE TABLE IF NOT EXISTS user_two_factor (
+    user_id INT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    secret_encrypted TEXT NOT NULL,     -- AES-256-GCM encrypted base32 secret
+    enabled_at TIMESTAMP,               -- NULL while enrollment awaits confirmation
+    last_used_step BIGINT NOT NULL DEFAULT 0,
+    failed_attempts INT NOT NULL DEFAULT 0,
+    last_failed_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT NOW(),
+    updated_at TIMESTAMP DEFAULT NOW()
+);
+
+-- Single-use recovery codes, stored as SHA-256 hex
+CREATE TABLE IF NOT EXISTS recovery_codes (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    code_hash VARCHAR(64) NOT NULL,
+    used_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_recovery_codes_user_id ON recovery_codes(user_id);
+
+-- Last time the session's user re-confirmed their identity
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS verified_at TIMESTAMP;
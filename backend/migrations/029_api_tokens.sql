@@ -0,0 +1,16 @@
+-- Personal API tokens for scripts and integrations
+CREATE TABLE IF NOT EXISTS api_tokens (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    token_hash VARCHAR(64) NOT NULL UNIQUE, -- SHA-256 hex of the token
+    hint VARCHAR(16) NOT NULL,              -- start of the token, shown in lists
+    scopes TEXT[] NOT NULL,                 -- transactions:read, transactions:write, export, stats
+    expires_at TIMESTAMP,                   -- NULL for tokens that do not expire
+    last_used_at TIMESTAMP,
+    last_used_ip VARCHAR(45),
+    revoked_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_api_tokens_user_id ON api_tokens(user_id);
@@ -18,6 +18,16 @@ type Config struct {
 	Encryption EncryptionConfig
 	Google     GoogleConfig
 	EInvoice   EInvoiceConfig
+	SMTP       SMTPConfig
+}
+
+// SMTPConfig configures outgoing email; email delivery is disabled when Host is empty
+type SMTPConfig struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
 }
 
 type GoogleConfig struct {
@@ -27,8 +37,10 @@ type GoogleConfig struct {
 }
 
 type EInvoiceConfig struct {
-	AppID  string
-	APIURL string
+	AppID        string
+	APIURL       string
+	AutoSync     bool
+	SyncInterval time.Duration
 }
 
 type EncryptionConfig struct {
@@ -39,6 +51,12 @@ type ServerConfig struct {
 	Port         string
 	Mode         string
 	AllowOrigins []string
+	AppURL       string // frontend base URL, for links in emails
+	AdminUserIDs []uint // users allowed to manage data shared by everyone, e.g. winning numbers
+	// TrustedProxies are the proxy addresses or CIDRs whose X-Forwarded-For
+	// header is believed for the client IP. None by default, so the client IP
+	// is the connection's remote address and cannot be spoofed.
+	TrustedProxies []string
 }
 
 type DatabaseConfig struct {
@@ -51,8 +69,9 @@ type DatabaseConfig struct {
 }
 
 type JWTConfig struct {
-	Secret string
-	Expiry time.Duration
+	Secret        string
+	Expiry        time.Duration // access token lifetime
+	RefreshExpiry time.Duration // session lifetime since the last refresh
 }
 
 type UploadConfig struct {
@@ -66,9 +85,12 @@ func Load() (*Config, error) {
 
 	config := &Config{
 		Server: ServerConfig{
-			Port:         getEnv("PORT", "8080"),
-			Mode:         getEnv("GIN_MODE", "debug"),
-			AllowOrigins: parseCSV(getEnv("ALLOWED_ORIGINS", "http://localhost:5173")),
+			Port:           getEnv("PORT", "8080"),
+			Mode:           getEnv("GIN_MODE", "debug"),
+			AllowOrigins:   parseCSV(getEnv("ALLOWED_ORIGINS", "http://localhost:5173")),
+			AppURL:         strings.TrimRight(getEnv("APP_URL", "http://localhost:5173"), "/"),
+			AdminUserIDs:   parseUintCSV(getEnv("ADMIN_USER_IDS", "")),
+			TrustedProxies: parseCSV(getEnv("TRUSTED_PROXIES", "")),
 		},
 		Database: DatabaseConfig{
 			Host:     getEnv("DB_HOST", "localhost"),
@@ -79,8 +101,9 @@ func Load() (*Config, error) {
 			SSLMode:  getEnv("DB_SSLMODE", "disable"),
 		},
 		JWT: JWTConfig{
-			Secret: getEnv("JWT_SECRET", "your-secret-key-change-this"),
-			Expiry: parseDuration(getEnv("JWT_EXPIRY", "24h")),
+			Secret:        getEnv("JWT_SECRET", "your-secret-key-change-this"),
+			Expiry:        parseDuration(getEnv("JWT_EXPIRY", "15m")),
+			RefreshExpiry: parseDuration(getEnv("JWT_REFRESH_EXPIRY", "720h")),
 		},
 		Upload: UploadConfig{
 			Dir:     getEnv("UPLOAD_DIR", "./uploads"),
@@ -95,8 +118,17 @@ func Load() (*Config, error) {
 			RedirectURI:  getEnv("GOOGLE_REDIRECT_URI", "http://localhost:5173/settings"),
 		},
 		EInvoice: EInvoiceConfig{
-			AppID:  getEnv("EINVOICE_APP_ID", ""),
-			APIURL: getEnv("EINVOICE_API_URL", "https://api.einvoice.nat.gov.tw/PB2CAPIVAN/invapp/InvApp"),
+			AppID:        getEnv("EINVOICE_APP_ID", ""),
+			APIURL:       getEnv("EINVOICE_API_URL", "https://api.einvoice.nat.gov.tw/PB2CAPIVAN/invapp/InvApp"),
+			AutoSync:     getEnv("EINVOICE_AUTO_SYNC", "true") == "true",
+			SyncInterval: parseDuration(getEnv("EINVOICE_SYNC_INTERVAL", "24h")),
+		},
+		SMTP: SMTPConfig{
+			Host:     getEnv("SMTP_HOST", ""),
+			Port:     getEnv("SMTP_PORT", "587"),
+			Username: getEnv("SMTP_USERNAME", ""),
+			Password: getEnv("SMTP_PASSWORD", ""),
+			From:     getEnv("SMTP_FROM", "noreply@billingnote.local"),
 		},
 	}
 
@@ -141,3 +173,14 @@ func parseCSV(s string) []string {
 	}
 	return result
 }
+
+// parseUintCSV parses a comma-separated list of IDs, skipping invalid entries
+func parseUintCSV(s string) []uint {
+	var result []uint
+	for _, part := range parseCSV(s) {
+		if id, err := strconv.ParseUint(part, 10, 32); err == nil {
+			result = append(result, uint(id))
+		}
+	}
+	return result
+}
@@ -67,6 +67,9 @@ const (
 	ErrCodeConflict         = "RESOURCE_CONFLICT"
 	ErrCodeForbidden        = "RESOURCE_FORBIDDEN"
 
+	// Rate limiting errors
+	ErrCodeRateLimited      = "RATE_LIMITED"
+
 	// Database errors
 	ErrCodeDBError          = "DATABASE_ERROR"
 	ErrCodeDBConnection     = "DATABASE_CONNECTION_ERROR"
@@ -179,6 +182,16 @@ func NewForbiddenError(message string) *AppError {
 	}
 }
 
+// NewRateLimitError creates a too many requests error
+func NewRateLimitError(message string) *AppError {
+	return &AppError{
+		Code:       ErrCodeRateLimited,
+		Message:    message,
+		HTTPStatus: http.StatusTooManyRequests,
+		Stack:      getStack(),
+	}
+}
+
 // NewDBError creates a database error
 func NewDBError(operation string, err error) *AppError {
 	return &AppError{
@@ -8,15 +8,25 @@ import (
 )
 
 type Claims struct {
-	UserID uint   `json:"user_id"`
-	Email  string `json:"email"`
+	UserID    uint   `json:"user_id"`
+	Email     string `json:"email"`
+	SessionID uint   `json:"sid,omitempty"`
+	// Purpose is set on tokens that are not access tokens
+	Purpose string `json:"purpose,omitempty"`
 	jwt.RegisteredClaims
 }
 
 func GenerateToken(userID uint, email string, secret string, expiry time.Duration) (string, error) {
+	return GenerateSessionToken(userID, email, 0, secret, expiry)
+}
+
+// GenerateSessionToken issues an access token bound to a session, so it stops
+// working once the session is revoked
+func GenerateSessionToken(userID uint, email string, sessionID uint, secret string, expiry time.Duration) (string, error) {
 	claims := Claims{
-		UserID: userID,
-		Email:  email,
+		UserID:    userID,
+		Email:     email,
+		SessionID: sessionID,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(expiry)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
@@ -27,7 +37,50 @@ func GenerateToken(userID uint, email string, secret string, expiry time.Duratio
 	return token.SignedString([]byte(secret))
 }
 
+// TwoFactorChallengePurpose marks the token a password login returns while
+// the second factor is still outstanding
+const TwoFactorChallengePurpose = "2fa_challenge"
+
 func ValidateToken(tokenString string, secret string) (*Claims, error) {
+	claims, err := parseToken(tokenString, secret)
+	if err != nil {
+		return nil, err
+	}
+	if claims.Purpose != "" {
+		return nil, errors.New("not an access token")
+	}
+	return claims, nil
+}
+
+// GenerateChallengeToken issues a short-lived token that only proves the
+// password step of a login
+func GenerateChallengeToken(userID uint, email string, secret string, expiry time.Duration) (string, error) {
+	claims := Claims{
+		UserID:  userID,
+		Email:   email,
+		Purpose: TwoFactorChallengePurpose,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(expiry)),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString([]byte(secret))
+}
+
+func ValidateChallengeToken(tokenString string, secret string) (*Claims, error) {
+	claims, err := parseToken(tokenString, secret)
+	if err != nil {
+		return nil, err
+	}
+	if claims.Purpose != TwoFactorChallengePurpose {
+		return nil, errors.New("not a two-factor challenge token")
+	}
+	return claims, nil
+}
+
+func parseToken(tokenString string, secret string) (*Claims, error) {
 	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, errors.New("unexpected signing method")
@@ -37,15 +37,16 @@ func setupAuthTestServer(t *testing.T) (*gin.Engine, *gorm.DB, func()) {
 		return nil, nil, nil
 	}
 
-	db, err := database.Connect(cfg.Database.DSN())
-	if err != nil {
+	if err := database.Connect(&cfg.Database); err != nil {
 		t.Skip("Skipping integration test: unable to connect to database")
 		return nil, nil, nil
 	}
+	db := database.GetDB()
 
 	// Setup repositories and services
 	userRepo := repository.NewUserRepository(db)
-	authService := services.NewAuthService(userRepo, cfg.JWT.Secret, cfg.JWT.Expiry)
+	sessionRepo := repository.NewSessionRepository(db)
+	authService := services.NewAuthService(userRepo, sessionRepo, nil, nil, nil, cfg.JWT.Secret, cfg.JWT.Expiry, cfg.JWT.RefreshExpiry)
 
 	// Setup handlers
 	authHandler := handlers.NewAuthHandler(authService)
@@ -54,7 +55,7 @@ func setupAuthTestServer(t *testing.T) (*gin.Engine, *gorm.DB, func()) {
 	router := gin.New()
 	router.POST("/api/auth/register", authHandler.Register)
 	router.POST("/api/auth/login", authHandler.Login)
-	router.GET("/api/auth/me", middleware.AuthMiddleware(cfg.JWT.Secret), authHandler.Me)
+	router.GET("/api/auth/me", middleware.AuthMiddleware(cfg.JWT.Secret, sessionRepo, nil), authHandler.Me)
 
 	// Cleanup function
 	cleanup := func() {
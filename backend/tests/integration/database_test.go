@@ -25,11 +25,11 @@ func setupTestDB(t *testing.T) *gorm.DB {
 		return nil
 	}
 
-	db, err := database.Connect(cfg.Database.DSN())
-	if err != nil {
+	if err := database.Connect(&cfg.Database); err != nil {
 		t.Skip("Skipping integration test: unable to connect to database")
 		return nil
 	}
+	db := database.GetDB()
 
 	return db
 }
@@ -183,6 +183,6 @@ func TestDatabaseErrorHandling(t *testing.T) {
 	os.Setenv("DB_PASSWORD", "invalid_password")
 	cfg, _ := config.Load()
 
-	_, err := database.Connect(cfg.Database.DSN())
+	err := database.Connect(&cfg.Database)
 	assert.Error(t, err)
 }
@@ -38,11 +38,11 @@ func setupTransactionTestServer(t *testing.T) (*gin.Engine, *gorm.DB, string, ui
 		return nil, nil, "", 0, nil
 	}
 
-	db, err := database.Connect(cfg.Database.DSN())
-	if err != nil {
+	if err := database.Connect(&cfg.Database); err != nil {
 		t.Skip("Skipping integration test: unable to connect to database")
 		return nil, nil, "", 0, nil
 	}
+	db := database.GetDB()
 
 	// Create test user
 	testUser := &models.User{
@@ -57,7 +57,7 @@ func setupTransactionTestServer(t *testing.T) (*gin.Engine, *gorm.DB, string, ui
 
 	// Setup repositories and services
 	transactionRepo := repository.NewTransactionRepository(db)
-	transactionService := services.NewTransactionService(transactionRepo)
+	transactionService := services.NewTransactionService(transactionRepo, repository.NewAccountRepository(db))
 	categoryRepo := repository.NewCategoryRepository(db)
 
 	// Setup handlers
@@ -66,7 +66,7 @@ func setupTransactionTestServer(t *testing.T) (*gin.Engine, *gorm.DB, string, ui
 
 	// Setup router
 	router := gin.New()
-	authMiddleware := middleware.AuthMiddleware(cfg.JWT.Secret)
+	authMiddleware := middleware.AuthMiddleware(cfg.JWT.Secret, nil, nil)
 
 	api := router.Group("/api")
 	{
@@ -432,7 +432,7 @@ func TestTransactionAPI_UnauthorizedAccess(t *testing.T) {
 }
 
 func TestTransactionAPI_CrossUserAccess(t *testing.T) {
-	router, db, token, userID, cleanup := setupTransactionTestServer(t)
+	router, db, token, _, cleanup := setupTransactionTestServer(t)
 	if router == nil {
 		return
 	}
@@ -0,0 +1,480 @@
+package integration
+
+import (
+	"billing-note/internal/handlers"
+	"billing-note/internal/middleware"
+	"billing-note/internal/models"
+	"billing-note/internal/repository"
+	"billing-note/internal/services"
+	"billing-note/pkg/config"
+	"billing-note/pkg/database"
+	"billing-note/pkg/utils"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// viewAsFixture holds an owner with some data, a viewer and an editor the
+// owner shares with, and a stranger with no access
+type viewAsFixture struct {
+	router *gin.Engine
+	db     *gorm.DB
+	owner  *models.User
+	tokens map[string]string
+
+	transaction *models.Transaction
+	account     *models.Account
+	bill        *models.Bill
+	goal        *models.Goal
+	budget      *models.Budget
+}
+
+// setupViewAsTestServer wires the data routes with the same middleware chain
+// as the server
+func setupViewAsTestServer(t *testing.T) (*viewAsFixture, func()) {
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+
+	gin.SetMode(gin.TestMode)
+
+	os.Setenv("DB_NAME", "billing_note_test")
+	cfg, err := config.Load()
+	if err != nil {
+		t.Skip("Skipping integration test: unable to load config")
+		return nil, nil
+	}
+
+	if err := database.Connect(&cfg.Database); err != nil {
+		t.Skip("Skipping integration test: unable to connect to database")
+		return nil, nil
+	}
+	db := database.GetDB()
+
+	// Users
+	stamp := time.Now().UnixNano()
+	users := map[string]*models.User{}
+	for _, name := range []string{"owner", "viewer", "editor", "stranger"} {
+		user := &models.User{
+			Email:        fmt.Sprintf("viewas_%s_%d@test.com", name, stamp),
+			Name:         "View As " + name,
+			PasswordHash: "password123",
+		}
+		require.NoError(t, db.Create(user).Error)
+		users[name] = user
+	}
+	owner := users["owner"]
+
+	tokens := map[string]string{}
+	for name, user := range users {
+		token, err := utils.GenerateToken(user.ID, user.Email, cfg.JWT.Secret, cfg.JWT.Expiry)
+		require.NoError(t, err)
+		tokens[name] = token
+	}
+
+	require.NoError(t, db.Create(&models.SharedAccess{OwnerID: owner.ID, ViewerID: users["viewer"].ID, Role: models.ShareRoleViewer}).Error)
+	require.NoError(t, db.Create(&models.SharedAccess{OwnerID: owner.ID, ViewerID: users["editor"].ID, Role: models.ShareRoleEditor}).Error)
+
+	// Owner data
+	var category models.Category
+	require.NoError(t, db.Where("type = ?", "expense").First(&category).Error)
+
+	account := &models.Account{UserID: owner.ID, Name: "Owner bank", Type: "bank"}
+	require.NoError(t, db.Create(account).Error)
+	transaction := &models.Transaction{
+		UserID:          owner.ID,
+		CategoryID:      &category.ID,
+		AccountID:       &account.ID,
+		Amount:          120,
+		Type:            "expense",
+		Description:     "Owner groceries",
+		TransactionDate: time.Now(),
+		Source:          "manual",
+	}
+	require.NoError(t, db.Create(transaction).Error)
+	bill := &models.Bill{UserID: owner.ID, Description: "Owner rent", DueDate: time.Now().AddDate(0, 0, 7), AmountDue: 500, Source: "manual", Status: "pending"}
+	require.NoError(t, db.Create(bill).Error)
+	goal := &models.Goal{UserID: owner.ID, Name: "Owner trip", TargetAmount: 3000, StartDate: time.Now(), Status: "active"}
+	require.NoError(t, db.Create(goal).Error)
+	budget := &models.Budget{UserID: owner.ID, TargetType: "category", CategoryID: &category.ID, PeriodType: "monthly", StartDay: 1, MonthlyAmount: 1000}
+	require.NoError(t, db.Create(budget).Error)
+
+	// Repositories and services, as in the server
+	userRepo := repository.NewUserRepository(db)
+	transactionRepo := repository.NewTransactionRepository(db)
+	categoryRepo := repository.NewCategoryRepository(db)
+	accountRepo := repository.NewAccountRepository(db)
+	billRepo := repository.NewBillRepository(db)
+	budgetRepo := repository.NewBudgetRepository(db)
+	goalRepo := repository.NewGoalRepository(db)
+	balanceRepo := repository.NewBalanceRepository(db)
+	invoiceRepo := repository.NewInvoiceRepository(db)
+	lotteryRepo := repository.NewLotteryRepository(db)
+	sharingRepo := repository.NewSharingRepository(db)
+	ledgerRepo := repository.NewLedgerRepository(db)
+
+	notificationService := services.NewNotificationService(repository.NewNotificationRepository(db), userRepo)
+	goalService := services.NewGoalService(goalRepo, accountRepo, transactionRepo, userRepo)
+	pdfPasswordService, err := services.NewPDFPasswordService(db, cfg.Encryption.Key)
+	if err != nil {
+		t.Skip("Skipping integration test: unable to initialize PDF password service")
+		return nil, nil
+	}
+	invoiceService := services.NewInvoiceService(invoiceRepo, cfg.EInvoice.APIURL, cfg.EInvoice.AppID)
+
+	transactionHandler := handlers.NewTransactionHandler(services.NewTransactionService(transactionRepo, repository.NewAccountRepository(db)))
+	transactionHandler.SetGoalService(goalService)
+	categoryHandler := handlers.NewCategoryHandler(categoryRepo)
+	accountHandler := handlers.NewAccountHandler(services.NewAccountService(accountRepo))
+	netWorthHandler := handlers.NewNetWorthHandler(services.NewNetWorthService(balanceRepo, accountRepo, transactionRepo, userRepo))
+	billHandler := handlers.NewBillHandler(services.NewBillService(billRepo, accountRepo, transactionRepo, userRepo, notificationService))
+	forecastHandler := handlers.NewForecastHandler(services.NewForecastService(transactionRepo, billRepo, userRepo))
+	uploadHandler := handlers.NewUploadHandler(services.NewUploadService(db, pdfPasswordService, t.TempDir()))
+	budgetHandler := handlers.NewBudgetHandler(services.NewBudgetService(budgetRepo, accountRepo, userRepo))
+	goalHandler := handlers.NewGoalHandler(goalService)
+	exportHandler := handlers.NewExportHandler(services.NewExportService(transactionRepo))
+	invoiceHandler := handlers.NewInvoiceHandler(invoiceService, db)
+	lotteryHandler := handlers.NewLotteryHandler(services.NewLotteryService(lotteryRepo, invoiceRepo, transactionRepo, categoryRepo, cfg.EInvoice.APIURL, cfg.EInvoice.AppID))
+
+	router := gin.New()
+	data := router.Group("/api")
+	data.Use(middleware.AuthMiddleware(cfg.JWT.Secret, nil, nil))
+	data.Use(middleware.ViewAsMiddleware(sharingRepo))
+	data.Use(middleware.SharePermissionGuard())
+	data.Use(middleware.LedgerMiddleware(ledgerRepo))
+	{
+		data.GET("/categories", categoryHandler.GetAll)
+		data.GET("/categories/type/:type", categoryHandler.GetByType)
+
+		data.POST("/transactions", transactionHandler.Create)
+		data.GET("/transactions", transactionHandler.List)
+		data.GET("/transactions/:id", transactionHandler.Get)
+		data.PUT("/transactions/:id", transactionHandler.Update)
+		data.DELETE("/transactions/:id", transactionHandler.Delete)
+
+		data.GET("/accounts", accountHandler.List)
+		data.POST("/accounts", accountHandler.Create)
+		data.PUT("/accounts/:id", accountHandler.Update)
+		data.DELETE("/accounts/:id", accountHandler.Delete)
+		data.GET("/accounts/:id/balances", netWorthHandler.ListBalances)
+		data.POST("/accounts/:id/balances", netWorthHandler.RecordBalance)
+		data.DELETE("/accounts/:id/balances/:bid", netWorthHandler.DeleteBalance)
+		data.GET("/accounts/:id/reconcile", netWorthHandler.ExpectedBalance)
+		data.POST("/accounts/:id/reconcile", netWorthHandler.Reconcile)
+		data.GET("/net-worth", netWorthHandler.Get)
+
+		data.GET("/bills", billHandler.List)
+		data.GET("/bills/overdue", billHandler.Overdue)
+		data.POST("/bills", billHandler.Create)
+		data.GET("/bills/:id", billHandler.Get)
+		data.PUT("/bills/:id", billHandler.Update)
+		data.DELETE("/bills/:id", billHandler.Delete)
+		data.POST("/bills/:id/pay", billHandler.MarkPaid)
+
+		data.GET("/stats/monthly", transactionHandler.GetMonthlyStats)
+		data.GET("/stats/category", transactionHandler.GetCategoryStats)
+		data.GET("/stats/trend", transactionHandler.GetTrendStats)
+		data.GET("/forecast", forecastHandler.Get)
+
+		data.POST("/upload/pdf", uploadHandler.UploadAndParse)
+		data.POST("/transactions/import", uploadHandler.Import)
+
+		data.POST("/budget", budgetHandler.Create)
+		data.GET("/budget", budgetHandler.List)
+		data.PUT("/budget/:id", budgetHandler.Update)
+		data.DELETE("/budget/:id", budgetHandler.Delete)
+		data.GET("/budget/compare", budgetHandler.Compare)
+		data.GET("/budget/:id/history", budgetHandler.History)
+
+		data.GET("/goals", goalHandler.List)
+		data.POST("/goals", goalHandler.Create)
+		data.GET("/goals/:id", goalHandler.Get)
+		data.PUT("/goals/:id", goalHandler.Update)
+		data.DELETE("/goals/:id", goalHandler.Delete)
+		data.GET("/goals/:id/contributions", goalHandler.ListContributions)
+		data.POST("/goals/:id/contributions", goalHandler.AddContribution)
+		data.DELETE("/goals/:id/contributions/:cid", goalHandler.DeleteContribution)
+
+		data.GET("/export/csv", exportHandler.ExportCSV)
+
+		data.POST("/invoice/sync", invoiceHandler.Sync)
+		data.GET("/invoice/list", invoiceHandler.List)
+		data.POST("/invoice/confirm-duplicate", invoiceHandler.ConfirmDuplicate)
+		data.DELETE("/invoice/:id", invoiceHandler.Delete)
+		data.PUT("/invoice/settings", invoiceHandler.UpdateSettings)
+		data.GET("/invoice/sync/status", invoiceHandler.GetSyncStatus)
+		data.PUT("/invoice/sync/settings", invoiceHandler.UpdateSyncSettings)
+		data.GET("/invoice/sync/history", invoiceHandler.GetSyncHistory)
+
+		data.GET("/invoice/winning-numbers", lotteryHandler.ListWinningNumbers)
+		data.GET("/invoice/winning-numbers/:period", lotteryHandler.GetWinningNumbers)
+		data.POST("/invoice/winning-numbers/import", lotteryHandler.ImportWinningNumbers)
+		data.POST("/invoice/winning-numbers/fetch", lotteryHandler.FetchWinningNumbers)
+		data.POST("/invoice/lottery/check", lotteryHandler.Check)
+		data.GET("/invoice/winnings", lotteryHandler.ListWinnings)
+		data.POST("/invoice/winnings/:id/record", lotteryHandler.RecordIncome)
+	}
+
+	fixture := &viewAsFixture{
+		router:      router,
+		db:          db,
+		owner:       owner,
+		tokens:      tokens,
+		transaction: transaction,
+		account:     account,
+		bill:        bill,
+		goal:        goal,
+		budget:      budget,
+	}
+
+	cleanup := func() {
+		for _, user := range users {
+			db.Exec("DELETE FROM transactions WHERE user_id = ?", user.ID)
+			db.Exec("DELETE FROM bills WHERE user_id = ?", user.ID)
+			db.Exec("DELETE FROM goals WHERE user_id = ?", user.ID)
+			db.Exec("DELETE FROM budgets WHERE user_id = ?", user.ID)
+			db.Exec("DELETE FROM accounts WHERE user_id = ?", user.ID)
+			db.Exec("DELETE FROM shared_access WHERE owner_id = ? OR viewer_id = ?", user.ID, user.ID)
+			db.Exec("DELETE FROM users WHERE id = ?", user.ID)
+		}
+		sqlDB, _ := db.DB()
+		sqlDB.Close()
+	}
+
+	return fixture, cleanup
+}
+
+// viewAsRoute is one data route in the matrix. Owned routes address one of
+// the owner's records, so they only succeed when view_as is honored.
+type viewAsRoute struct {
+	method string
+	path   string
+	owned  bool
+}
+
+// routes lists every route in the data group
+func (f *viewAsFixture) routes() []viewAsRoute {
+	today := time.Now().Format("2006-01-02")
+	monthAgo := time.Now().AddDate(0, -1, 0).Format("2006-01-02")
+	txn := fmt.Sprintf("/api/transactions/%d", f.transaction.ID)
+	account := fmt.Sprintf("/api/accounts/%d", f.account.ID)
+	bill := fmt.Sprintf("/api/bills/%d", f.bill.ID)
+	goal := fmt.Sprintf("/api/goals/%d", f.goal.ID)
+	budget := fmt.Sprintf("/api/budget/%d", f.budget.ID)
+
+	return []viewAsRoute{
+		{http.MethodGet, "/api/categories", false},
+		{http.MethodGet, "/api/categories/type/expense", false},
+
+		{http.MethodPost, "/api/transactions", false},
+		{http.MethodGet, "/api/transactions", false},
+		{http.MethodGet, txn, true},
+		{http.MethodPut, txn, true},
+		{http.MethodDelete, txn, true},
+
+		{http.MethodGet, "/api/accounts", false},
+		{http.MethodPost, "/api/accounts", false},
+		{http.MethodPut, account, true},
+		{http.MethodDelete, account, true},
+		{http.MethodGet, account + "/balances", true},
+		{http.MethodPost, account + "/balances", true},
+		{http.MethodDelete, account + "/balances/1", true},
+		{http.MethodGet, account + "/reconcile", true},
+		{http.MethodPost, account + "/reconcile", true},
+		{http.MethodGet, "/api/net-worth", false},
+
+		{http.MethodGet, "/api/bills", false},
+		{http.MethodGet, "/api/bills/overdue", false},
+		{http.MethodPost, "/api/bills", false},
+		{http.MethodGet, bill, true},
+		{http.MethodPut, bill, true},
+		{http.MethodDelete, bill, true},
+		{http.MethodPost, bill + "/pay", true},
+
+		{http.MethodGet, "/api/stats/monthly", false},
+		{http.MethodGet, "/api/stats/category?start_date=" + monthAgo + "&end_date=" + today + "&type=expense", false},
+		{http.MethodGet, "/api/stats/trend", false},
+		{http.MethodGet, "/api/forecast", false},
+
+		{http.MethodPost, "/api/upload/pdf", false},
+		{http.MethodPost, "/api/transactions/import", false},
+
+		{http.MethodPost, "/api/budget", false},
+		{http.MethodGet, "/api/budget", false},
+		{http.MethodPut, budget, true},
+		{http.MethodDelete, budget, true},
+		{http.MethodGet, "/api/budget/compare", false},
+		{http.MethodGet, budget + "/history", true},
+
+		{http.MethodGet, "/api/goals", false},
+		{http.MethodPost, "/api/goals", false},
+		{http.MethodGet, goal, true},
+		{http.MethodPut, goal, true},
+		{http.MethodDelete, goal, true},
+		{http.MethodGet, goal + "/contributions", true},
+		{http.MethodPost, goal + "/contributions", true},
+		{http.MethodDelete, goal + "/contributions/1", true},
+
+		{http.MethodGet, "/api/export/csv?start_date=" + monthAgo + "&end_date=" + today, false},
+
+		{http.MethodPost, "/api/invoice/sync", false},
+		{http.MethodGet, "/api/invoice/list", false},
+		{http.MethodPost, "/api/invoice/confirm-duplicate", false},
+		{http.MethodDelete, "/api/invoice/1", false},
+		{http.MethodPut, "/api/invoice/settings", false},
+		{http.MethodGet, "/api/invoice/sync/status", false},
+		{http.MethodPut, "/api/invoice/sync/settings", false},
+		{http.MethodGet, "/api/invoice/sync/history", false},
+
+		{http.MethodGet, "/api/invoice/winning-numbers", false},
+		{http.MethodGet, "/api/invoice/winning-numbers/11501", false},
+		{http.MethodPost, "/api/invoice/winning-numbers/import", false},
+		{http.MethodPost, "/api/invoice/winning-numbers/fetch", false},
+		{http.MethodPost, "/api/invoice/lottery/check", false},
+		{http.MethodGet, "/api/invoice/winnings", false},
+		{http.MethodPost, "/api/invoice/winnings/1/record", false},
+	}
+}
+
+func (f *viewAsFixture) do(method, path, token string, viewAs uint, body interface{}) *httptest.ResponseRecorder {
+	if viewAs != 0 {
+		sep := "?"
+		if strings.Contains(path, "?") {
+			sep = "&"
+		}
+		path = fmt.Sprintf("%s%sview_as=%d", path, sep, viewAs)
+	}
+
+	var reader *bytes.Reader
+	if body != nil {
+		payload, _ := json.Marshal(body)
+		reader = bytes.NewReader(payload)
+	} else {
+		reader = bytes.NewReader(nil)
+	}
+
+	req, _ := http.NewRequest(method, path, reader)
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	f.router.ServeHTTP(w, req)
+	return w
+}
+
+func TestViewAsAPI_StrangerIsForbidden(t *testing.T) {
+	f, cleanup := setupViewAsTestServer(t)
+	if f == nil {
+		return
+	}
+	defer cleanup()
+
+	for _, route := range f.routes() {
+		t.Run(route.method+" "+route.path, func(t *testing.T) {
+			w := f.do(route.method, route.path, f.tokens["stranger"], f.owner.ID, nil)
+			assert.Equal(t, http.StatusForbidden, w.Code)
+			assert.Empty(t, w.Header().Get("X-View-As-User-ID"))
+		})
+	}
+}
+
+func TestViewAsAPI_ViewerReadsOwnerData(t *testing.T) {
+	f, cleanup := setupViewAsTestServer(t)
+	if f == nil {
+		return
+	}
+	defer cleanup()
+
+	ownerID := fmt.Sprintf("%d", f.owner.ID)
+	for _, route := range f.routes() {
+		t.Run(route.method+" "+route.path, func(t *testing.T) {
+			w := f.do(route.method, route.path, f.tokens["viewer"], f.owner.ID, nil)
+
+			if route.method != http.MethodGet {
+				// Viewers are read-only
+				assert.Equal(t, http.StatusForbidden, w.Code)
+				return
+			}
+
+			assert.NotEqual(t, http.StatusForbidden, w.Code)
+			assert.NotEqual(t, http.StatusUnauthorized, w.Code)
+			assert.Equal(t, ownerID, w.Header().Get("X-View-As-User-ID"))
+			assert.Equal(t, models.ShareRoleViewer, w.Header().Get("X-View-As-Role"))
+			if route.owned {
+				assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+				// Without view_as the viewer works on their own data
+				own := f.do(route.method, route.path, f.tokens["viewer"], 0, nil)
+				assert.NotEqual(t, http.StatusOK, own.Code)
+				assert.Empty(t, own.Header().Get("X-View-As-User-ID"))
+			}
+		})
+	}
+}
+
+func TestViewAsAPI_ListsShowOwnerData(t *testing.T) {
+	f, cleanup := setupViewAsTestServer(t)
+	if f == nil {
+		return
+	}
+	defer cleanup()
+
+	lists := map[string]string{
+		"/api/transactions": "Owner groceries",
+		"/api/accounts":     "Owner bank",
+		"/api/bills":        "Owner rent",
+		"/api/goals":        "Owner trip",
+	}
+	for path, marker := range lists {
+		t.Run(path, func(t *testing.T) {
+			w := f.do(http.MethodGet, path, f.tokens["viewer"], f.owner.ID, nil)
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.Contains(t, w.Body.String(), marker)
+
+			own := f.do(http.MethodGet, path, f.tokens["viewer"], 0, nil)
+			assert.NotContains(t, own.Body.String(), marker)
+		})
+	}
+}
+
+func TestViewAsAPI_EditorWritesToOwner(t *testing.T) {
+	f, cleanup := setupViewAsTestServer(t)
+	if f == nil {
+		return
+	}
+	defer cleanup()
+
+	w := f.do(http.MethodPost, "/api/transactions", f.tokens["editor"], f.owner.ID, map[string]interface{}{
+		"amount":           45.5,
+		"type":             "expense",
+		"description":      "Added by editor",
+		"transaction_date": time.Now().Format(time.RFC3339),
+	})
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	assert.Equal(t, models.ShareRoleEditor, w.Header().Get("X-View-As-Role"))
+
+	var created models.Transaction
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+	assert.Equal(t, f.owner.ID, created.UserID)
+
+	w = f.do(http.MethodPost, "/api/goals", f.tokens["editor"], f.owner.ID, map[string]interface{}{
+		"name":          "Editor goal",
+		"target_amount": 800,
+	})
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+
+	var goal models.Goal
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &goal))
+	assert.Equal(t, f.owner.ID, goal.UserID)
+}